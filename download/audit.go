@@ -0,0 +1,116 @@
+package download
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultAuditBufferSize is the default number of audit events buffered for every subscriber.
+const DefaultAuditBufferSize = 100
+
+// AuditFeed fans the audit events of finished downloads out to its subscribers. Every subscriber
+// has a bounded buffer, events for a subscriber whose buffer is full are dropped rather than
+// blocking the downloads.
+type AuditFeed struct {
+	bufferSize  int
+	dropped     int64
+	mu          sync.Mutex
+	subscribers map[chan *pb.AuditEvent]struct{}
+}
+
+// NewAuditFeed returns an AuditFeed buffering up to bufferSize events for every subscriber,
+// a non-positive bufferSize defaults to DefaultAuditBufferSize.
+func NewAuditFeed(bufferSize int) *AuditFeed {
+	if bufferSize <= 0 {
+		bufferSize = DefaultAuditBufferSize
+	}
+
+	return &AuditFeed{bufferSize: bufferSize, subscribers: make(map[chan *pb.AuditEvent]struct{})}
+}
+
+// Dropped returns the number of events dropped for slow subscribers.
+func (f *AuditFeed) Dropped() int64 {
+	return atomic.LoadInt64(&f.dropped)
+}
+
+// subscribe returns a new subscriber's channel of events.
+func (f *AuditFeed) subscribe() chan *pb.AuditEvent {
+	events := make(chan *pb.AuditEvent, f.bufferSize)
+
+	f.mu.Lock()
+	f.subscribers[events] = struct{}{}
+	f.mu.Unlock()
+
+	return events
+}
+
+// unsubscribe stops sending events to the subscriber of events.
+func (f *AuditFeed) unsubscribe(events chan *pb.AuditEvent) {
+	f.mu.Lock()
+	delete(f.subscribers, events)
+	f.mu.Unlock()
+}
+
+// publish sends event to every subscriber with room for it in its buffer, without blocking.
+// A nil AuditFeed publishes nothing.
+func (f *AuditFeed) publish(event *pb.AuditEvent) {
+	if f == nil {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for events := range f.subscribers {
+		select {
+		case events <- event:
+		default:
+			atomic.AddInt64(&f.dropped, 1)
+		}
+	}
+}
+
+// publishAudit publishes the audit event of the download of d, which ended with err, to s.AuditFeed.
+func (s Service) publishAudit(d *partDownload, err error) {
+	if s.AuditFeed == nil {
+		return
+	}
+
+	s.AuditFeed.publish(&pb.AuditEvent{
+		Subject:     SubjectFromContext(d.stream.Context()),
+		Bucket:      d.bucket,
+		Key:         d.key,
+		Bytes:       d.bytesSent,
+		Outcome:     status.Code(err).String(),
+		TimestampMs: time.Now().UnixNano() / int64(time.Millisecond),
+		TraceId:     s.traceID(d.stream.Context()),
+	})
+}
+
+// WatchAuditEvents is the request to subscribe to the live feed of the audit events of finished downloads,
+// which streams every event until the client cancels the call. Events are dropped for clients that fall
+// too far behind. It returns a FailedPrecondition error if the audit feed isn't enabled.
+func (s Service) WatchAuditEvents(req *pb.WatchAuditEventsRequest, stream pb.Admin_WatchAuditEventsServer) error {
+	if s.AuditFeed == nil {
+		return status.Error(codes.FailedPrecondition, "audit feed isn't enabled")
+	}
+
+	events := s.AuditFeed.subscribe()
+	defer s.AuditFeed.unsubscribe(events)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event := <-events:
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package download_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// auditEventStream is a pb.Admin_WatchAuditEventsServer that passes the events sent on it to events,
+// and closes watching once the watch subscribed, when it first waits on the stream's context.
+type auditEventStream struct {
+	grpc.ServerStream
+	ctx       context.Context
+	events    chan *pb.AuditEvent
+	watching  chan struct{}
+	watchOnce sync.Once
+}
+
+func newAuditEventStream(ctx context.Context) *auditEventStream {
+	return &auditEventStream{ctx: ctx, events: make(chan *pb.AuditEvent), watching: make(chan struct{})}
+}
+
+func (s *auditEventStream) Context() context.Context {
+	s.watchOnce.Do(func() { close(s.watching) })
+	return s.ctx
+}
+
+func (s *auditEventStream) Send(event *pb.AuditEvent) error {
+	select {
+	case s.events <- event:
+		return nil
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+}
+
+// watchAuditEvents watches the audit events of service on stream until its context is done,
+// and returns once the watch subscribed, with a channel closed once the watch returned.
+func watchAuditEvents(t *testing.T, service *download.Service, stream *auditEventStream) <-chan struct{} {
+	t.Helper()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		err := service.WatchAuditEvents(&pb.WatchAuditEventsRequest{}, stream)
+		if err != nil && stream.ctx.Err() == nil {
+			t.Errorf("DownloadService.WatchAuditEvents() error = %v", err)
+		}
+	}()
+	<-stream.watching
+
+	return done
+}
+
+func TestDownloadService_WatchAuditEvents(t *testing.T) {
+	service := download.NewService(s3Client, logger)
+	service.AuditFeed = download.NewAuditFeed(10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := newAuditEventStream(ctx)
+	done := watchAuditEvents(t, service, stream)
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	downloadCtx := download.ContextWithSubject(context.Background(), "auditor")
+	downloads := []struct {
+		key         string
+		wantOutcome string
+		wantBytes   int64
+	}{
+		{key: testkey, wantOutcome: codes.OK.String(), wantBytes: int64(len(file))},
+		{key: "missing.txt", wantOutcome: codes.NotFound.String()},
+	}
+
+	for _, d := range downloads {
+		downloadStream := &hashingDownloadStream{ctx: downloadCtx, hash: sha256.New()}
+		_ = service.Download(&pb.DownloadRequest{Key: d.key, Bucket: testbucket}, downloadStream)
+
+		select {
+		case event := <-stream.events:
+			if event.GetSubject() != "auditor" || event.GetBucket() != testbucket || event.GetKey() != d.key {
+				t.Errorf(
+					"DownloadService.WatchAuditEvents() event of %s/%s by %q, want %s/%s by %q",
+					event.GetBucket(), event.GetKey(), event.GetSubject(), testbucket, d.key, "auditor",
+				)
+			}
+
+			if event.GetOutcome() != d.wantOutcome || event.GetBytes() != d.wantBytes {
+				t.Errorf(
+					"DownloadService.WatchAuditEvents() event outcome %s of %d bytes, want %s of %d bytes",
+					event.GetOutcome(), event.GetBytes(), d.wantOutcome, d.wantBytes,
+				)
+			}
+
+			if since := time.Since(time.Unix(0, event.GetTimestampMs()*int64(time.Millisecond))); since > time.Minute {
+				t.Errorf("DownloadService.WatchAuditEvents() event is %s old, want a recent event", since)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("DownloadService.WatchAuditEvents() sent no event of the download of %s", d.key)
+		}
+	}
+}
+
+func TestDownloadService_WatchAuditEventsSlowSubscriber(t *testing.T) {
+	service := download.NewService(s3Client, logger)
+	service.AuditFeed = download.NewAuditFeed(1)
+
+	// The subscriber never receives its events, so its buffer fills up.
+	ctx, cancel := context.WithCancel(context.Background())
+	done := watchAuditEvents(t, service, newAuditEventStream(ctx))
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	for i := 0; i < 3; i++ {
+		downloadStream := &hashingDownloadStream{ctx: context.Background(), hash: sha256.New()}
+		if err := service.Download(&pb.DownloadRequest{Key: testkey, Bucket: testbucket}, downloadStream); err != nil {
+			t.Fatalf("DownloadService.Download() error = %v", err)
+		}
+	}
+
+	if dropped := service.AuditFeed.Dropped(); dropped == 0 {
+		t.Errorf("AuditFeed.Dropped() = %d, want dropped events of the slow subscriber", dropped)
+	}
+}
+
+func TestDownloadService_WatchAuditEventsDisabled(t *testing.T) {
+	service := download.NewService(s3Client, logger)
+	stream := newAuditEventStream(context.Background())
+	err := service.WatchAuditEvents(&pb.WatchAuditEventsRequest{}, stream)
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("DownloadService.WatchAuditEvents() error = %v, want code %v", err, codes.FailedPrecondition)
+	}
+}
@@ -0,0 +1,47 @@
+package download
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// subjectContextKey is the context key under which the authenticated subject is stored.
+type subjectContextKey struct{}
+
+// Authorizer decides whether subject may download the object key from bucket.
+// Returning a non-nil error denies the download. Listing objects is authorized with the listed prefix as key.
+type Authorizer func(ctx context.Context, subject string, bucket string, key string) error
+
+// AllowAll is the default Authorizer, it permits every download.
+func AllowAll(context.Context, string, string, string) error {
+	return nil
+}
+
+// ContextWithSubject returns a copy of ctx holding the authenticated subject,
+// authentication interceptors should use it to pass the subject to the service.
+func ContextWithSubject(ctx context.Context, subject string) context.Context {
+	return context.WithValue(ctx, subjectContextKey{}, subject)
+}
+
+// SubjectFromContext returns the authenticated subject held by ctx,
+// or an empty string if the request was not authenticated.
+func SubjectFromContext(ctx context.Context) string {
+	subject, _ := ctx.Value(subjectContextKey{}).(string)
+
+	return subject
+}
+
+// authorize returns a PermissionDenied error if s.Authorizer denies the subject of ctx access to bucket/key.
+func (s Service) authorize(ctx context.Context, bucket string, key string) error {
+	if s.Authorizer == nil {
+		return nil
+	}
+
+	if err := s.Authorizer(ctx, SubjectFromContext(ctx), bucket, key); err != nil {
+		return status.Errorf(codes.PermissionDenied, "access to object %s/%s denied: %v", bucket, key, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package download
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// autoTuneMinParts is the number of parts below which downloads aren't tuned, since they end
+	// before the tuner could converge, and fetch all of their parts at once up to the maximum instead.
+	autoTuneMinParts = 8
+
+	// autoTuneMinWindowParts is the minimal number of parts the throughput of a concurrency is measured over.
+	autoTuneMinWindowParts = 2
+
+	// autoTuneTolerance is the relative throughput improvement below which a concurrency isn't
+	// considered better than the best one, so measurement noise doesn't move the concurrency.
+	autoTuneTolerance = 0.05
+)
+
+// concurrencyTuner tunes the concurrency of fetching the parts of a download toward its maximal
+// throughput by hill climbing. It starts at a single part and doubles the concurrency while the
+// throughput improves, then probes the concurrencies next to the best one a step at a time, and
+// settles on the best concurrency once neither of them improves it.
+// Each concurrency's throughput is measured over a window of as many parts as the concurrency.
+type concurrencyTuner struct {
+	mu             sync.Mutex
+	max            int
+	concurrency    int
+	best           int
+	bestThroughput float64
+	slowStart      bool
+	step           int
+	climbed        bool
+	settled        bool
+	windowParts    int
+	windowBytes    int64
+	windowStart    time.Time
+}
+
+// newConcurrencyTuner returns a concurrencyTuner tuning the concurrency up to max, starting at now.
+func newConcurrencyTuner(max int, now time.Time) *concurrencyTuner {
+	return &concurrencyTuner{
+		max:         max,
+		concurrency: 1,
+		best:        1,
+		slowStart:   true,
+		step:        1,
+		settled:     max <= 1,
+		windowStart: now,
+	}
+}
+
+// current returns the concurrency to fetch the parts with.
+func (t *concurrencyTuner) current() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.concurrency
+}
+
+// observe records that a part of size bytes was fetched at now, and moves the concurrency
+// once the window of the current concurrency is complete.
+func (t *concurrencyTuner) observe(size int64, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.settled {
+		return
+	}
+
+	t.windowParts++
+	t.windowBytes += size
+	if t.windowParts < t.concurrency || t.windowParts < autoTuneMinWindowParts {
+		return
+	}
+
+	elapsed := now.Sub(t.windowStart).Seconds()
+	if elapsed > 0 {
+		t.move(float64(t.windowBytes) / elapsed)
+	}
+
+	t.windowParts, t.windowBytes, t.windowStart = 0, 0, now
+}
+
+// move moves the concurrency after measuring throughput at the current concurrency, t.mu must be held.
+func (t *concurrencyTuner) move(throughput float64) {
+	improved := throughput > t.bestThroughput*(1+autoTuneTolerance)
+	if improved {
+		t.best, t.bestThroughput = t.concurrency, throughput
+	}
+
+	switch {
+	case t.slowStart && improved && t.concurrency < t.max:
+		t.concurrency *= 2
+		if t.concurrency > t.max {
+			t.concurrency = t.max
+		}
+	case t.slowStart:
+		// Probe the concurrencies next to the best one, above it first.
+		t.slowStart = false
+		t.probe(1)
+	case improved:
+		// Keep climbing in the direction that improved.
+		t.climbed = true
+		t.probe(t.step)
+	case t.step > 0 && !t.climbed:
+		t.probe(-1)
+	default:
+		t.concurrency, t.settled = t.best, true
+	}
+}
+
+// probe moves the concurrency a step from the best one, skipping the concurrencies out of bounds
+// or already measured while doubling, and settles on the best one if there's none left to probe.
+// t.mu must be held.
+func (t *concurrencyTuner) probe(step int) {
+	t.step = step
+	t.concurrency = t.best + step
+	measured := t.concurrency == t.best*2 || t.concurrency*2 == t.best
+	if t.concurrency >= 1 && t.concurrency <= t.max && !measured {
+		return
+	}
+
+	if step > 0 && !t.climbed {
+		t.probe(-1)
+		return
+	}
+
+	t.concurrency, t.settled = t.best, true
+}
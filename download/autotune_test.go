@@ -0,0 +1,75 @@
+package download
+
+import (
+	"testing"
+	"time"
+)
+
+// favoring returns the latency of fetching a part from a simulated backend whose throughput
+// peaks at favored concurrent fetches, since its latency grows steeply with more.
+func favoring(favored int) func(concurrency int) time.Duration {
+	return func(concurrency int) time.Duration {
+		latency := 20 * time.Millisecond
+		for c := concurrency; c > favored; c-- {
+			latency = latency * 5 / 2
+		}
+
+		return latency
+	}
+}
+
+func Test_concurrencyTuner(t *testing.T) {
+	tests := []struct {
+		name         string
+		max          int
+		latency      func(concurrency int) time.Duration
+		want         int
+		wantMaxParts int
+	}{
+		{name: "favors 4", max: 16, latency: favoring(4), want: 4, wantMaxParts: 30},
+		{name: "favors 6", max: 16, latency: favoring(6), want: 6, wantMaxParts: 40},
+		{name: "favors 1", max: 16, latency: favoring(1), want: 1, wantMaxParts: 10},
+		{name: "favors more than max", max: 5, latency: favoring(16), want: 5, wantMaxParts: 30},
+		{name: "max 1", max: 1, latency: favoring(4), want: 1, wantMaxParts: 0},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Unix(0, 0)
+			tuner := newConcurrencyTuner(tt.max, now)
+
+			// Fetch rounds of as many parts as the concurrency, completing together.
+			parts := 0
+			for parts < 200 && !tuner.settled {
+				concurrency := tuner.current()
+				if concurrency < 1 || concurrency > tt.max {
+					t.Fatalf("concurrencyTuner.current() = %d, want between 1 and %d", concurrency, tt.max)
+				}
+
+				now = now.Add(tt.latency(concurrency))
+				for i := 0; i < concurrency; i++ {
+					tuner.observe(5<<20, now)
+					parts++
+				}
+			}
+
+			if got := tuner.current(); got != tt.want {
+				t.Errorf("concurrencyTuner.current() = %d, want %d", got, tt.want)
+			}
+
+			if parts > tt.wantMaxParts {
+				t.Errorf("concurrencyTuner settled after %d parts, want at most %d", parts, tt.wantMaxParts)
+			}
+
+			// Settled tuners don't move anymore.
+			for i := 0; i < 20; i++ {
+				now = now.Add(tt.latency(i%tt.max + 1))
+				tuner.observe(5<<20, now)
+				if got := tuner.current(); got != tt.want {
+					t.Fatalf("concurrencyTuner.current() = %d after settling, want %d", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package download
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/sirupsen/logrus"
+)
+
+// cacheCopyTimeout is the timeout of copying an object to the cache bucket.
+const cacheCopyTimeout = 5 * time.Minute
+
+// cacheCopies tracks the objects being copied to the cache bucket, to coalesce their copies.
+type cacheCopies struct {
+	mu       sync.Mutex
+	inflight map[headCacheKey]struct{}
+}
+
+// start marks the copy of bucket/key as in flight, and returns false if it already was.
+func (c *cacheCopies) start(bucket string, key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	copyKey := headCacheKey{bucket: bucket, key: key}
+	if _, ok := c.inflight[copyKey]; ok {
+		return false
+	}
+
+	c.inflight[copyKey] = struct{}{}
+
+	return true
+}
+
+// done marks the copy of bucket/key as finished.
+func (c *cacheCopies) done(bucket string, key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.inflight, headCacheKey{bucket: bucket, key: key})
+}
+
+// readThroughCache returns the bucket to download key from, s.CacheBucket if the object
+// was copied there, otherwise bucket, copying the object to s.CacheBucket for the next downloads.
+// Requests with delegated credentials and requests of a version are always downloaded from bucket.
+func (s Service) readThroughCache(ctx context.Context, bucket string, key string) string {
+	if s.CacheBucket == "" || bucket == s.CacheBucket || s.isDelegated(ctx) || versionIDFromContext(ctx) != nil {
+		return bucket
+	}
+
+	_, err := s.headObject(ctx, s.CacheBucket, key)
+	if err == nil {
+		return s.CacheBucket
+	}
+
+	if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != "NotFound" {
+		s.logger.WithField("key.prefix", KeyPrefixLabel(key, s.KeyPrefixAllowlist)).Warnf(
+			"failed to check the cache bucket %s for %s, downloading from %s: %v", s.CacheBucket, key, bucket, err,
+		)
+
+		return bucket
+	}
+
+	s.copyToCache(bucket, key)
+
+	return bucket
+}
+
+// copyToCache copies bucket/key to s.CacheBucket asynchronously, unless it's already being copied.
+// A failed copy is logged and never affects the download.
+func (s Service) copyToCache(bucket string, key string) {
+	if !s.cacheCopies.start(bucket, key) {
+		return
+	}
+
+	go func() {
+		defer s.cacheCopies.done(bucket, key)
+
+		ctx, cancel := context.WithTimeout(context.Background(), cacheCopyTimeout)
+		defer cancel()
+
+		fields := logrus.Fields{"s3.bucket": bucket, "key.prefix": KeyPrefixLabel(key, s.KeyPrefixAllowlist)}
+		if _, err := s.s3Client.CopyObjectWithContext(ctx, &s3.CopyObjectInput{
+			Bucket:     aws.String(s.CacheBucket),
+			Key:        aws.String(key),
+			CopySource: aws.String(url.PathEscape(bucket + "/" + key)),
+		}); err != nil {
+			s.logger.WithFields(fields).Errorf(
+				"failed to copy %s/%s to the cache bucket %s: %v", bucket, key, s.CacheBucket, err,
+			)
+			return
+		}
+
+		s.logger.WithFields(fields).Infof("copied %s/%s to the cache bucket %s", bucket, key, s.CacheBucket)
+	}()
+}
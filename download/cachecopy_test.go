@@ -0,0 +1,143 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+)
+
+// blockingCopyS3Client returns an S3 client whose CopyObject calls block until release is closed,
+// and a channel that receives each of its CopyObject calls.
+func blockingCopyS3Client(release <-chan struct{}) (*s3.S3, <-chan struct{}) {
+	copies := make(chan struct{}, 16)
+	client := s3.New(session.Must(session.NewSession(&s3Client.Config)))
+	client.Handlers.Validate.PushBack(func(r *request.Request) {
+		if _, ok := r.Params.(*s3.CopyObjectInput); !ok {
+			return
+		}
+
+		copies <- struct{}{}
+		<-release
+	})
+
+	return client, copies
+}
+
+func TestDownloadService_DownloadCacheBucket(t *testing.T) {
+	const cacheBucket, cacheKey, downloads = "cache", "cached.txt", 4
+
+	if err := emptyAndDeleteBucket(cacheBucket); err != nil {
+		t.Logf("failed to emptyAndDeleteBucket, %v", err)
+	}
+
+	if _, err := s3Client.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String(cacheBucket)}); err != nil {
+		t.Fatalf("failed to create bucket %s, %v", cacheBucket, err)
+	}
+
+	if _, err := s3Client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(testbucket),
+		Key:    aws.String(cacheKey),
+		Body:   bytes.NewReader(file),
+	}); err != nil {
+		t.Fatalf("failed to upload %s, %v", cacheKey, err)
+	}
+
+	release := make(chan struct{})
+	client, copies := blockingCopyS3Client(release)
+	service := download.NewService(client, logger)
+	service.CacheBucket = cacheBucket
+
+	serviceClient, closeClient := newServiceClient(t, service)
+	defer closeClient()
+
+	// downloadCached downloads the key and checks it's the wanted file.
+	downloadCached := func() {
+		stream, err := serviceClient.Download(
+			context.Background(),
+			&pb.DownloadRequest{Key: cacheKey, Bucket: testbucket},
+		)
+		if err != nil {
+			t.Errorf("DownloadService.Download() error = %v", err)
+			return
+		}
+
+		received, err := recvAll(stream)
+		if err != nil {
+			t.Errorf("DownloadService.Download() error = %v", err)
+			return
+		}
+
+		if !bytes.Equal(received, file) {
+			t.Errorf("DownloadService.Download() file downloaded is different from the wanted file")
+		}
+	}
+
+	// Concurrent cache misses complete while the copy is blocked, and issue a single copy.
+	var wg sync.WaitGroup
+	for i := 0; i < downloads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			downloadCached()
+		}()
+	}
+	wg.Wait()
+
+	select {
+	case <-copies:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("DownloadService.Download() didn't copy the object to the cache bucket")
+	}
+
+	if len(copies) != 0 {
+		t.Errorf("DownloadService.Download() copied %d times to the cache bucket, want 1", 1+len(copies))
+	}
+
+	close(release)
+
+	// Wait for the copy to land in the cache bucket.
+	waitForObject(t, cacheBucket, cacheKey)
+
+	// Once copied, downloads are served from the cache bucket even if the source changed.
+	if _, err := s3Client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(testbucket),
+		Key:    aws.String(cacheKey),
+		Body:   bytes.NewReader([]byte("changed")),
+	}); err != nil {
+		t.Fatalf("failed to upload %s, %v", cacheKey, err)
+	}
+
+	downloadCached()
+
+	if len(copies) != 0 {
+		t.Errorf("DownloadService.Download() copied the cached object to the cache bucket again")
+	}
+}
+
+// waitForObject waits until bucket/key exists, failing t if it doesn't within a few seconds.
+func waitForObject(t *testing.T, bucket string, key string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, err := s3Client.HeadObject(&s3.HeadObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+		if err == nil {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("object %s/%s wasn't created, %v", bucket, key, err)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
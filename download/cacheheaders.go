@@ -0,0 +1,59 @@
+package download
+
+import (
+	"net/http"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// CacheControlHeader is the response header of the downloaded object's Cache-Control,
+	// omitted if the object has none.
+	CacheControlHeader = "x-download-cache-control"
+
+	// ExpiresHeader is the response header of the downloaded object's Expires,
+	// omitted if the object has none.
+	ExpiresHeader = "x-download-expires"
+)
+
+// httpHeaderByHeader maps the response headers of downloads to the HTTP headers they originate from.
+var httpHeaderByHeader = map[string]string{
+	CacheControlHeader: "Cache-Control",
+	ExpiresHeader:      "Expires",
+	ContentTypeHeader:  "Content-Type",
+}
+
+// setCacheHeaders sets the CacheControlHeader and ExpiresHeader headers of stream
+// to the Cache-Control and Expires of the object of objectDetails, if it has them.
+func setCacheHeaders(stream grpc.ServerStream, objectDetails *s3.HeadObjectOutput) error {
+	header := metadata.MD{}
+	if cacheControl := aws.StringValue(objectDetails.CacheControl); cacheControl != "" {
+		header.Set(CacheControlHeader, cacheControl)
+	}
+
+	if expires := aws.StringValue(objectDetails.Expires); expires != "" {
+		header.Set(ExpiresHeader, expires)
+	}
+
+	if header.Len() == 0 {
+		return nil
+	}
+
+	return stream.SetHeader(header)
+}
+
+// HTTPHeadersFromHeader returns the HTTP headers of header, the response header of a download,
+// for HTTP adapters of the service serving as a CDN origin. Headers the download omitted are omitted.
+func HTTPHeadersFromHeader(header metadata.MD) http.Header {
+	httpHeader := http.Header{}
+	for key, httpKey := range httpHeaderByHeader {
+		if values := header.Get(key); len(values) > 0 {
+			httpHeader.Set(httpKey, values[0])
+		}
+	}
+
+	return httpHeader
+}
@@ -0,0 +1,117 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+)
+
+// cacheHeadersS3Client returns an S3 client of a store that returns cacheControl and expires
+// as the Cache-Control and Expires of key.
+func cacheHeadersS3Client(key string, cacheControl string, expires string) *s3.S3 {
+	client := s3.New(session.Must(session.NewSession(&s3Client.Config)))
+	client.Handlers.Send.PushBack(func(r *request.Request) {
+		input, ok := r.Params.(*s3.HeadObjectInput)
+		if !ok || r.HTTPResponse == nil || aws.StringValue(input.Key) != key {
+			return
+		}
+
+		r.HTTPResponse.Header.Set("Cache-Control", cacheControl)
+		r.HTTPResponse.Header.Set("Expires", expires)
+	})
+
+	return client
+}
+
+func TestDownloadService_DownloadCacheHeaders(t *testing.T) {
+	const (
+		cachedKey    = "cdn/cached.txt"
+		cacheControl = "public, max-age=3600"
+		expires      = "Wed, 21 Oct 2026 07:28:00 GMT"
+	)
+
+	if _, err := s3Client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(testbucket),
+		Key:    aws.String(cachedKey),
+		Body:   bytes.NewReader(file[:1024]),
+	}); err != nil {
+		t.Fatalf("failed to upload %s, %v", cachedKey, err)
+	}
+
+	tests := []struct {
+		name             string
+		key              string
+		wantCacheControl string
+		wantExpires      string
+	}{
+		{
+			name:             "cache headers - set",
+			key:              cachedKey,
+			wantCacheControl: cacheControl,
+			wantExpires:      expires,
+		},
+		{
+			name: "cache headers - unset",
+			key:  testkey,
+		},
+	}
+
+	service := download.NewService(cacheHeadersS3Client(cachedKey, cacheControl, expires), logger)
+	client, closeClient := newServiceClient(t, service)
+	defer closeClient()
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := client.Download(context.Background(), &pb.DownloadRequest{Key: tt.key, Bucket: testbucket})
+			if err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			if _, err := recvAll(stream); err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			header, err := stream.Header()
+			if err != nil {
+				t.Fatalf("DownloadService.Download() header error = %v", err)
+			}
+
+			httpHeader := download.HTTPHeadersFromHeader(header)
+			if got := httpHeader.Get("Cache-Control"); got != tt.wantCacheControl {
+				t.Errorf("HTTPHeadersFromHeader() Cache-Control = %q, want %q", got, tt.wantCacheControl)
+			}
+
+			if got := httpHeader.Get("Expires"); got != tt.wantExpires {
+				t.Errorf("HTTPHeadersFromHeader() Expires = %q, want %q", got, tt.wantExpires)
+			}
+
+			// Headers of objects without them are omitted rather than empty.
+			if _, ok := httpHeader["Cache-Control"]; ok != (tt.wantCacheControl != "") {
+				t.Errorf("HTTPHeadersFromHeader() has Cache-Control = %v, want %v", ok, tt.wantCacheControl != "")
+			}
+
+			manifest, err := client.GetDownloadManifest(
+				context.Background(),
+				&pb.GetDownloadManifestRequest{Key: tt.key, Bucket: testbucket},
+			)
+			if err != nil {
+				t.Fatalf("DownloadService.GetDownloadManifest() error = %v", err)
+			}
+
+			if manifest.GetCacheControl() != tt.wantCacheControl || manifest.GetExpires() != tt.wantExpires {
+				t.Errorf(
+					"DownloadService.GetDownloadManifest() cache control, expires = %q, %q, want %q, %q",
+					manifest.GetCacheControl(), manifest.GetExpires(), tt.wantCacheControl, tt.wantExpires,
+				)
+			}
+		})
+	}
+}
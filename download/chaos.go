@@ -0,0 +1,112 @@
+package download
+
+import (
+	"context"
+	"math/rand"
+	"strconv"
+	"time"
+
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// ChaosPartDelayHeader is the request header overriding Chaos.PartDelay, in milliseconds.
+	ChaosPartDelayHeader = "x-chaos-part-delay-ms"
+
+	// ChaosErrorProbabilityHeader is the request header overriding Chaos.ErrorProbability.
+	ChaosErrorProbabilityHeader = "x-chaos-error-probability"
+
+	// ChaosSendDelayHeader is the request header overriding Chaos.SendDelay, in milliseconds.
+	ChaosSendDelayHeader = "x-chaos-send-delay-ms"
+)
+
+// Chaos injects faults into downloads to test the resilience of clients.
+// It must never be enabled in production.
+type Chaos struct {
+	// PartDelay delays the download of every part.
+	PartDelay time.Duration
+
+	// ErrorProbability is the probability of failing the download with an Unavailable error before every part.
+	ErrorProbability float64
+
+	// SendDelay delays every chunk sent to the client.
+	SendDelay time.Duration
+}
+
+// forRequest returns c overridden by the chaos headers of the request of ctx.
+// Invalid header values are ignored.
+func (c Chaos) forRequest(ctx context.Context) Chaos {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return c
+	}
+
+	if values := md.Get(ChaosPartDelayHeader); len(values) > 0 {
+		if ms, err := strconv.ParseInt(values[0], 10, 64); err == nil && ms >= 0 {
+			c.PartDelay = time.Duration(ms) * time.Millisecond
+		}
+	}
+
+	if values := md.Get(ChaosErrorProbabilityHeader); len(values) > 0 {
+		if probability, err := strconv.ParseFloat(values[0], 64); err == nil {
+			c.ErrorProbability = probability
+		}
+	}
+
+	if values := md.Get(ChaosSendDelayHeader); len(values) > 0 {
+		if ms, err := strconv.ParseInt(values[0], 10, 64); err == nil && ms >= 0 {
+			c.SendDelay = time.Duration(ms) * time.Millisecond
+		}
+	}
+
+	return c
+}
+
+// beforePart delays the download of the part number currentPart and fails it
+// with an Unavailable error with probability c.ErrorProbability.
+func (c Chaos) beforePart(ctx context.Context, currentPart int64) error {
+	if err := sleepContext(ctx, c.PartDelay); err != nil {
+		return err
+	}
+
+	if c.ErrorProbability > 0 && rand.Float64() < c.ErrorProbability {
+		return status.Errorf(codes.Unavailable, "chaos: injected error before part %d", currentPart)
+	}
+
+	return nil
+}
+
+// chaosDownloadStream is a pb.Download_DownloadServer that delays every chunk sent on it.
+type chaosDownloadStream struct {
+	pb.Download_DownloadServer
+	sendDelay time.Duration
+}
+
+// Send sends res on the underlying stream after the send delay.
+func (s chaosDownloadStream) Send(res *pb.DownloadResponse) error {
+	if err := sleepContext(s.Context(), s.sendDelay); err != nil {
+		return err
+	}
+
+	return s.Download_DownloadServer.Send(res)
+}
+
+// sleepContext sleeps for d, or until ctx is done and returns its error.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
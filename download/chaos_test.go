@@ -0,0 +1,106 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestDownloadService_DownloadChaos(t *testing.T) {
+	const chunkSize = 256 << 10
+	chunks := len(file) / chunkSize
+
+	tests := []struct {
+		name        string
+		chaos       *download.Chaos
+		md          metadata.MD
+		wantCode    codes.Code
+		minDuration time.Duration
+	}{
+		{
+			name:     "chaos - disabled ignores headers",
+			md:       metadata.Pairs(download.ChaosErrorProbabilityHeader, "1"),
+			wantCode: codes.OK,
+		},
+		{
+			name:     "chaos - no faults",
+			chaos:    &download.Chaos{},
+			wantCode: codes.OK,
+		},
+		{
+			name:     "chaos - error",
+			chaos:    &download.Chaos{ErrorProbability: 1},
+			wantCode: codes.Unavailable,
+		},
+		{
+			name:        "chaos - part delay",
+			chaos:       &download.Chaos{PartDelay: 100 * time.Millisecond},
+			wantCode:    codes.OK,
+			minDuration: 100 * time.Millisecond,
+		},
+		{
+			name:        "chaos - slow send",
+			chaos:       &download.Chaos{SendDelay: 10 * time.Millisecond},
+			wantCode:    codes.OK,
+			minDuration: time.Duration(chunks) * 10 * time.Millisecond,
+		},
+		{
+			name:     "chaos - header error",
+			chaos:    &download.Chaos{},
+			md:       metadata.Pairs(download.ChaosErrorProbabilityHeader, "1"),
+			wantCode: codes.Unavailable,
+		},
+		{
+			name:        "chaos - header part delay",
+			chaos:       &download.Chaos{},
+			md:          metadata.Pairs(download.ChaosPartDelayHeader, "100"),
+			wantCode:    codes.OK,
+			minDuration: 100 * time.Millisecond,
+		},
+		{
+			name:     "chaos - header overrides config",
+			chaos:    &download.Chaos{ErrorProbability: 1},
+			md:       metadata.Pairs(download.ChaosErrorProbabilityHeader, "0"),
+			wantCode: codes.OK,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			service := download.NewService(s3Client, logger)
+			service.Chaos = tt.chaos
+			service.MaxBufferSize = chunkSize
+
+			client, closeClient := newServiceClient(t, service)
+			defer closeClient()
+
+			ctx := metadata.NewOutgoingContext(context.Background(), tt.md)
+			startTime := time.Now()
+			stream, err := client.Download(ctx, &pb.DownloadRequest{Key: testkey, Bucket: testbucket})
+			if err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			got, err := recvAll(stream)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("DownloadService.Download() error = %v, wantCode %v", err, tt.wantCode)
+			}
+
+			if duration := time.Since(startTime); duration < tt.minDuration {
+				t.Errorf("DownloadService.Download() took %v, want at least %v", duration, tt.minDuration)
+			}
+
+			if tt.wantCode == codes.OK && !bytes.Equal(got, file) {
+				t.Errorf("DownloadService.Download() file downloaded is different from the wanted file")
+			}
+		})
+	}
+}
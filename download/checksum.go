@@ -0,0 +1,175 @@
+package download
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"hash"
+	"hash/crc32"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// ChecksumAlgorithmCRC32 is the algorithm of objects uploaded with a CRC32 checksum.
+	ChecksumAlgorithmCRC32 = "CRC32"
+
+	// ChecksumAlgorithmCRC32C is the algorithm of objects uploaded with a CRC32C checksum.
+	ChecksumAlgorithmCRC32C = "CRC32C"
+
+	// ChecksumAlgorithmSHA1 is the algorithm of objects uploaded with a SHA1 checksum.
+	ChecksumAlgorithmSHA1 = "SHA1"
+
+	// ChecksumAlgorithmSHA256 is the algorithm of objects uploaded with a SHA256 checksum.
+	ChecksumAlgorithmSHA256 = "SHA256"
+
+	// checksumModeHeader is the request header that makes S3 return the checksum of the object.
+	checksumModeHeader = "x-amz-checksum-mode"
+
+	// checksumHeaderPrefix is the prefix of the response headers of the object's checksum,
+	// followed by the lowercase algorithm.
+	checksumHeaderPrefix = "x-amz-checksum-"
+)
+
+// checksumAlgorithms are the supported checksum algorithms, in the order they're detected.
+var checksumAlgorithms = []string{
+	ChecksumAlgorithmCRC32C,
+	ChecksumAlgorithmCRC32,
+	ChecksumAlgorithmSHA256,
+	ChecksumAlgorithmSHA1,
+}
+
+// objectChecksum is the checksum an object was uploaded with.
+type objectChecksum struct {
+	algorithm string
+	value     string
+}
+
+// withChecksumMode is a request.Option that asks S3 for the checksum of the object in HeadObject,
+// and keeps the checksum headers in the result's Metadata, since the SDK doesn't model them.
+func withChecksumMode(r *request.Request) {
+	r.HTTPRequest.Header.Set(checksumModeHeader, "ENABLED")
+	r.Handlers.Unmarshal.PushBack(func(r *request.Request) {
+		output, ok := r.Data.(*s3.HeadObjectOutput)
+		if !ok || r.HTTPResponse == nil {
+			return
+		}
+
+		for _, algorithm := range checksumAlgorithms {
+			header := http.CanonicalHeaderKey(checksumHeaderPrefix + algorithm)
+			if value := r.HTTPResponse.Header.Get(header); value != "" {
+				if output.Metadata == nil {
+					output.Metadata = make(map[string]*string)
+				}
+
+				output.Metadata[header] = aws.String(value)
+			}
+		}
+	})
+}
+
+// headChecksum returns the checksum of the object of head, detecting its algorithm,
+// and false if the object has none.
+func headChecksum(head *s3.HeadObjectOutput) (objectChecksum, bool) {
+	for _, algorithm := range checksumAlgorithms {
+		header := http.CanonicalHeaderKey(checksumHeaderPrefix + algorithm)
+		if value := aws.StringValue(head.Metadata[header]); value != "" {
+			return objectChecksum{algorithm: algorithm, value: value}, true
+		}
+	}
+
+	return objectChecksum{}, false
+}
+
+// verifiable returns whether c is a checksum of the object's bytes that downloads can be verified
+// against. Checksums of multipart uploads, formatted as "checksum-parts", are checksums of the
+// checksums of the parts.
+func (c objectChecksum) verifiable() bool {
+	return !strings.Contains(c.value, "-")
+}
+
+// newHash returns a hash of c's algorithm.
+func (c objectChecksum) newHash() hash.Hash {
+	switch c.algorithm {
+	case ChecksumAlgorithmCRC32:
+		return crc32.NewIEEE()
+	case ChecksumAlgorithmCRC32C:
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	case ChecksumAlgorithmSHA1:
+		return sha1.New()
+	default:
+		return sha256.New()
+	}
+}
+
+// checksumDownloadStream is a pb.Download_DownloadServer that computes the checksum of the file
+// bytes sent on it, to verify them against the checksum the object was uploaded with.
+type checksumDownloadStream struct {
+	pb.Download_DownloadServer
+	checksum objectChecksum
+	hash     hash.Hash
+}
+
+// newChecksumDownloadStream returns a checksumDownloadStream of stream verifying checksum.
+func newChecksumDownloadStream(
+	stream pb.Download_DownloadServer,
+	checksum objectChecksum,
+) *checksumDownloadStream {
+	return &checksumDownloadStream{
+		Download_DownloadServer: stream,
+		checksum:                checksum,
+		hash:                    checksum.newHash(),
+	}
+}
+
+// Send adds the file bytes of res to the checksum and sends it on the underlying stream.
+func (s *checksumDownloadStream) Send(res *pb.DownloadResponse) error {
+	s.hash.Write(res.GetFile())
+
+	return s.Download_DownloadServer.Send(res)
+}
+
+// verify returns a DataLoss error if the checksum of the bytes sent on s doesn't match
+// the checksum of bucket/key. A nil stream verifies nothing.
+func (s *checksumDownloadStream) verify(bucket string, key string) error {
+	if s == nil {
+		return nil
+	}
+
+	if sum := base64.StdEncoding.EncodeToString(s.hash.Sum(nil)); sum != s.checksum.value {
+		return status.Errorf(
+			codes.DataLoss,
+			"%s checksum of object %s/%s is %s, want %s",
+			s.checksum.algorithm, bucket, key, sum, s.checksum.value,
+		)
+	}
+
+	return nil
+}
+
+// checksumToVerify returns the checksum that the download of objectRange of the object of head is
+// verified against, if s.VerifyChecksums is set, otherwise an empty checksum. Only downloads of the
+// whole object in order are verified, against the checksum of the object's bytes.
+func (s Service) checksumToVerify(head *s3.HeadObjectOutput, objectRange byteRange, reverse bool) objectChecksum {
+	if !s.VerifyChecksums || reverse {
+		return objectChecksum{}
+	}
+
+	if objectRange.start != 0 || objectRange.length() != aws.Int64Value(head.ContentLength) {
+		return objectChecksum{}
+	}
+
+	checksum, ok := headChecksum(head)
+	if !ok || !checksum.verifiable() {
+		return objectChecksum{}
+	}
+
+	return checksum
+}
@@ -0,0 +1,146 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"hash"
+	"hash/crc32"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// checksummingS3Client returns an S3 client whose HeadObject results report that the objects were
+// uploaded with checksum of algorithm, when the checksum mode is enabled like S3 requires.
+func checksummingS3Client(algorithm string, checksum string) *s3.S3 {
+	client := s3.New(session.Must(session.NewSession(&s3Client.Config)))
+	client.Handlers.Send.PushBack(func(r *request.Request) {
+		if _, ok := r.Params.(*s3.HeadObjectInput); !ok || r.HTTPResponse == nil {
+			return
+		}
+
+		if r.HTTPRequest.Header.Get("x-amz-checksum-mode") == "ENABLED" {
+			r.HTTPResponse.Header.Set("x-amz-checksum-"+strings.ToLower(algorithm), checksum)
+		}
+	})
+
+	return client
+}
+
+// base64Checksum returns the base64 checksum of data computed with h, like S3 formats it.
+func base64Checksum(h hash.Hash, data []byte) string {
+	h.Write(data)
+
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func TestDownloadService_DownloadChecksum(t *testing.T) {
+	checksums := map[string]func() hash.Hash{
+		download.ChecksumAlgorithmCRC32:  func() hash.Hash { return crc32.NewIEEE() },
+		download.ChecksumAlgorithmCRC32C: func() hash.Hash { return crc32.New(crc32.MakeTable(crc32.Castagnoli)) },
+		download.ChecksumAlgorithmSHA1:   sha1.New,
+		download.ChecksumAlgorithmSHA256: sha256.New,
+	}
+
+	type test struct {
+		name       string
+		algorithm  string
+		checksum   string
+		verify     bool
+		rangeStart int64
+		wantCode   codes.Code
+	}
+
+	tests := make([]test, 0, 3*len(checksums)+2)
+	for algorithm, newHash := range checksums {
+		tests = append(tests,
+			test{
+				name:      "checksum - " + algorithm,
+				algorithm: algorithm,
+				checksum:  base64Checksum(newHash(), file),
+				verify:    true,
+			},
+			test{
+				name:      "checksum - " + algorithm + " mismatch",
+				algorithm: algorithm,
+				checksum:  base64Checksum(newHash(), file[1:]),
+				verify:    true,
+				wantCode:  codes.DataLoss,
+			},
+			test{
+				name:      "checksum - " + algorithm + " mismatch unverified",
+				algorithm: algorithm,
+				checksum:  base64Checksum(newHash(), file[1:]),
+			},
+		)
+	}
+
+	tests = append(tests,
+		test{
+			name:       "checksum - range unverified",
+			algorithm:  download.ChecksumAlgorithmSHA256,
+			checksum:   base64Checksum(sha256.New(), file[1:]),
+			verify:     true,
+			rangeStart: 1,
+		},
+		test{
+			name:      "checksum - multipart unverified",
+			algorithm: download.ChecksumAlgorithmCRC32C,
+			checksum:  base64Checksum(crc32.New(crc32.MakeTable(crc32.Castagnoli)), file[1:]) + "-2",
+			verify:    true,
+		},
+	)
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			service := download.NewService(checksummingS3Client(tt.algorithm, tt.checksum), logger)
+			service.VerifyChecksums = tt.verify
+
+			client, closeClient := newServiceClient(t, service)
+			defer closeClient()
+
+			manifest, err := client.GetDownloadManifest(
+				context.Background(),
+				&pb.GetDownloadManifestRequest{Key: testkey, Bucket: testbucket},
+			)
+			if err != nil {
+				t.Fatalf("DownloadService.GetDownloadManifest() error = %v", err)
+			}
+
+			if manifest.GetChecksumAlgorithm() != tt.algorithm || manifest.GetChecksum() != tt.checksum {
+				t.Errorf(
+					"DownloadService.GetDownloadManifest() checksum = %q, %q, want %q, %q",
+					manifest.GetChecksumAlgorithm(), manifest.GetChecksum(), tt.algorithm, tt.checksum,
+				)
+			}
+
+			stream, err := client.Download(
+				context.Background(),
+				&pb.DownloadRequest{Key: testkey, Bucket: testbucket, RangeStart: tt.rangeStart},
+			)
+			if err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			fileFromStream, err := recvAll(stream)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("DownloadService.Download() error = %v, wantCode %v", err, tt.wantCode)
+			}
+
+			if tt.wantCode == codes.OK && !bytes.Equal(fileFromStream, file[tt.rangeStart:]) {
+				t.Errorf("DownloadService.Download() file downloaded is different from the wanted file")
+			}
+		})
+	}
+}
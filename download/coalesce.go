@@ -0,0 +1,67 @@
+package download
+
+import (
+	"context"
+	"fmt"
+)
+
+// coalescedRead is a single ranged read of adjacent ranges of an object, split back into them once read.
+type coalescedRead struct {
+	byteRange
+	ranges []byteRange
+}
+
+// coalesceRanges merges the ranges, ordered by their start, that are at most maxGap bytes apart into reads
+// of up to maxSize bytes, the bytes between the merged ranges are read and dropped. Ranges larger than
+// maxSize are read on their own, a non-positive maxSize reads every range on its own.
+func coalesceRanges(ranges []byteRange, maxGap int64, maxSize int64) []coalescedRead {
+	reads := make([]coalescedRead, 0, len(ranges))
+	for _, r := range ranges {
+		if len(reads) > 0 {
+			last := &reads[len(reads)-1]
+			gap := r.start - last.end - 1
+			if gap >= 0 && gap <= maxGap && r.end-last.start+1 <= maxSize {
+				last.end = r.end
+				last.ranges = append(last.ranges, r)
+				continue
+			}
+		}
+
+		reads = append(reads, coalescedRead{byteRange: r, ranges: []byteRange{r}})
+	}
+
+	return reads
+}
+
+// split splits data, the bytes read of c, into the bytes of its ranges.
+// It returns an error if fewer bytes were read than the ranges span.
+func (c coalescedRead) split(data []byte) ([][]byte, error) {
+	parts := make([][]byte, 0, len(c.ranges))
+	for _, r := range c.ranges {
+		start, end := r.start-c.start, r.end-c.start+1
+		if end > int64(len(data)) {
+			return nil, fmt.Errorf("coalesced read of bytes %d-%d returned %d bytes", c.start, c.end, len(data))
+		}
+
+		parts = append(parts, data[start:end])
+	}
+
+	return parts, nil
+}
+
+// fetchCoalesced downloads c, whose first range is the part number firstPart of bucket/key,
+// into memory by a single GetObject call, and returns the bytes of its ranges.
+func (s Service) fetchCoalesced(
+	ctx context.Context,
+	bucket string,
+	key string,
+	c coalescedRead,
+	firstPart int64,
+) ([][]byte, error) {
+	data, err := s.fetchPartBytes(ctx, bucket, key, c.byteRange, firstPart)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.split(data)
+}
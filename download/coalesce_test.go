@@ -0,0 +1,54 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+)
+
+func TestDownloadService_DownloadCoalescedParts(t *testing.T) {
+	tests := []struct {
+		name            string
+		coalesceMaxSize int64
+		wantCalls       int64
+	}{
+		{name: "coalesce - disabled", wantCalls: int64(len(file)) / download.MinPartSize},
+		{
+			name:            "coalesce - smaller than a part",
+			coalesceMaxSize: download.MinPartSize / 2,
+			wantCalls:       int64(len(file)) / download.MinPartSize,
+		},
+		{name: "coalesce - three parts", coalesceMaxSize: 3 * download.MinPartSize, wantCalls: 3},
+		{name: "coalesce - four parts", coalesceMaxSize: 4 * download.MinPartSize, wantCalls: 2},
+		{name: "coalesce - whole object", coalesceMaxSize: int64(len(file)), wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int64
+			service := download.NewService(partFailingS3Client(nil, 0, &calls), logger)
+			service.PartSize = download.MinPartSize
+			service.PartConcurrency = 2
+			service.CoalesceMaxSize = tt.coalesceMaxSize
+
+			stream := &hashingDownloadStream{ctx: context.Background(), hash: sha256.New()}
+			if err := service.Download(&pb.DownloadRequest{Key: testkey, Bucket: testbucket}, stream); err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			wantHash := sha256.Sum256(file)
+			if !bytes.Equal(stream.hash.Sum(nil), wantHash[:]) {
+				t.Errorf("DownloadService.Download() file downloaded is different from the wanted file")
+			}
+
+			if calls != tt.wantCalls {
+				t.Errorf("DownloadService.Download() called GetObject %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	pb "github.com/meateam/download-service/proto"
+	opentracing "github.com/opentracing/opentracing-go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// ConcatenatedSizeHeader is the response header of the combined size of the concatenated objects.
+	ConcatenatedSizeHeader = "x-download-size"
+)
+
+// concatMember is an object of a concatenated download.
+type concatMember struct {
+	bucket string
+	key    string
+	etag   string
+	size   int64
+}
+
+// DownloadConcatenated is the request to download a file that is split across multiple objects,
+// streaming the objects back-to-back as a single stream of bytes, their combined size in the
+// ConcatenatedSizeHeader header. Every key is resolved to its bucket and authorized on its own,
+// and all the objects are looked up before any byte is sent, so a missing object fails the download
+// with NotFound, and an object that changes while the download is in progress fails it.
+func (s Service) DownloadConcatenated(
+	req *pb.DownloadConcatenatedRequest,
+	stream pb.Download_DownloadConcatenatedServer,
+) error {
+	// Reject oversized requests before doing any work for them.
+	keys := req.GetKeys()
+	if err := s.RequestLimits.check(req, keys); err != nil {
+		return err
+	}
+
+	// Shed the download before doing any work for it if the service is overloaded.
+	if err := s.startDownload(); err != nil {
+		return err
+	}
+	defer s.endDownload()
+
+	// Reject the download if the service served its egress quota.
+	if err := s.EgressQuota.check(); err != nil {
+		return err
+	}
+
+	if len(keys) == 0 {
+		return status.Error(codes.InvalidArgument, "keys are required")
+	}
+
+	ctx := stream.Context()
+	members, size, err := s.concatMembers(ctx, req.GetBucket(), keys)
+	if err != nil {
+		return err
+	}
+
+	// Limit the concurrent downloads of the requesting subject.
+	subject := SubjectFromContext(ctx)
+	if err := s.SubjectLimiter.acquire(subject); err != nil {
+		return err
+	}
+	defer s.SubjectLimiter.release(subject)
+
+	// Share the bandwidth budget with the other downloads as a standard download, if limited.
+	qos, err := s.QoSBudget.join(pb.QoSClass_STANDARD)
+	if err != nil {
+		return err
+	}
+	defer qos.leave()
+
+	if err := stream.SetHeader(metadata.Pairs(ConcatenatedSizeHeader, strconv.FormatInt(size, 10))); err != nil {
+		return err
+	}
+
+	sendStream := s.prepareConcatStream(stream, qos)
+	buffer := getSendBuffer(s.bufferSize(s.defaultPartSize()))
+	defer putSendBuffer(buffer)
+
+	for i, member := range members {
+		if err := s.sendConcatMember(ctx, sendStream, member, int64(i+1), buffer); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// prepareConcatStream decorates stream like the stream of a download, counting the bytes sent on it
+// against the egress quota and pacing them to the per-stream rate and to qos, if limited.
+func (s Service) prepareConcatStream(stream pb.Download_DownloadServer, qos *qosShare) pb.Download_DownloadServer {
+	if s.EgressQuota != nil {
+		stream = egressDownloadStream{Download_DownloadServer: stream, quota: s.EgressQuota}
+	}
+
+	if s.PerStreamMaxBytesPerSec > 0 {
+		stream = newPacedDownloadStream(stream, s.PerStreamMaxBytesPerSec)
+	}
+
+	if qos != nil {
+		stream = newSharedPacedDownloadStream(stream, qos.bytesPerSec)
+	}
+
+	return stream
+}
+
+// concatMembers resolves the objects of keys to their buckets, bucket if set, authorizes and looks
+// them up, and returns them with their combined size. It returns a NotFound error if an object
+// doesn't exist.
+func (s Service) concatMembers(ctx context.Context, bucket string, keys []string) ([]concatMember, int64, error) {
+	members := make([]concatMember, 0, len(keys))
+	size := int64(0)
+	for _, key := range keys {
+		if key == "" {
+			return nil, 0, status.Error(codes.InvalidArgument, "keys must not be empty")
+		}
+
+		memberBucket, key, err := s.resolveObject(bucket, key, "")
+		if err != nil {
+			return nil, 0, status.Error(codes.InvalidArgument, err.Error())
+		}
+
+		if err := s.authorize(ctx, memberBucket, key); err != nil {
+			return nil, 0, err
+		}
+
+		objectDetails, err := s.headObject(ctx, memberBucket, key)
+		if err != nil {
+			return nil, 0, s3ErrorToStatus(
+				fmt.Errorf("failed to look up object %s/%s of the concatenation: %w", memberBucket, key, err),
+			)
+		}
+
+		if err := s.checkEncryption(memberBucket, key, objectDetails); err != nil {
+			return nil, 0, err
+		}
+
+		member := concatMember{
+			bucket: memberBucket,
+			key:    key,
+			etag:   aws.StringValue(objectDetails.ETag),
+			size:   aws.Int64Value(objectDetails.ContentLength),
+		}
+		members = append(members, member)
+		size += member.size
+	}
+
+	return members, size, nil
+}
+
+// sendConcatMember sends the bytes of member, the number memberNumber of the concatenation,
+// to stream in chunks of up to len(buffer) bytes. The object must not change since it was looked up,
+// and a DataLoss error is returned if it ends before its size.
+func (s Service) sendConcatMember(
+	ctx context.Context,
+	stream pb.Download_DownloadServer,
+	member concatMember,
+	memberNumber int64,
+	buffer []byte,
+) error {
+	if member.size == 0 {
+		return nil
+	}
+
+	getObjectInput := &s3.GetObjectInput{
+		Bucket: aws.String(member.bucket),
+		Key:    aws.String(member.key),
+	}
+	if member.etag != "" {
+		getObjectInput.IfMatch = aws.String(member.etag)
+	}
+
+	tags := opentracing.Tags{"s3.bucket": member.bucket, "s3.key": member.key}
+	span := s.startClientSpan(ctx, "s3.GetObject", tags)
+	object, err := s.s3ClientFor(ctx, member.bucket).GetObjectWithContext(ctx, getObjectInput)
+	if err != nil {
+		finishSpan(span, err)
+		return s3ErrorToStatus(fmt.Errorf("failed to download object %s/%s: %w", member.bucket, member.key, err))
+	}
+	defer object.Body.Close()
+
+	keyPrefix := KeyPrefixLabel(member.key, s.KeyPrefixAllowlist)
+	sent, err := s.sendPart(stream, object.Body, buffer, memberNumber, 0, nil, keyPrefix)
+	if err == nil && sent != member.size {
+		err = status.Errorf(
+			codes.DataLoss,
+			"object %s/%s ended after %d of its %d bytes",
+			member.bucket,
+			member.key,
+			sent,
+			member.size,
+		)
+	}
+	finishSpan(span, err)
+
+	return s3ErrorToStatus(err)
+}
@@ -0,0 +1,181 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDownloadService_DownloadConcatenated(t *testing.T) {
+	// The file is split across three keys of different sizes, and an empty key.
+	members := map[string][]byte{
+		"split.part0": file[:1<<20],
+		"split.part1": file[1<<20 : 1<<20+12345],
+		"split.part2": file[1<<20+12345:],
+		"split.empty": nil,
+	}
+	for key, body := range members {
+		if _, err := s3Client.PutObject(&s3.PutObjectInput{
+			Bucket: aws.String(testbucket),
+			Key:    aws.String(key),
+			Body:   bytes.NewReader(body),
+		}); err != nil {
+			t.Fatalf("failed to upload %s, %v", key, err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		keys     []string
+		want     []byte
+		wantCode codes.Code
+	}{
+		{
+			name: "concatenated - split file",
+			keys: []string{"split.part0", "split.part1", "split.part2"},
+			want: file,
+		},
+		{
+			name: "concatenated - reordered with an empty key",
+			keys: []string{"split.part2", "split.empty", "split.part0"},
+			want: append(append([]byte(nil), members["split.part2"]...), members["split.part0"]...),
+		},
+		{
+			name:     "concatenated - missing key",
+			keys:     []string{"split.part0", "split.part3", "split.part2"},
+			wantCode: codes.NotFound,
+		},
+		{
+			name:     "concatenated - no keys",
+			wantCode: codes.InvalidArgument,
+		},
+	}
+
+	client, closeClient := newServiceClient(t, download.NewService(s3Client, logger))
+	defer closeClient()
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			req := &pb.DownloadConcatenatedRequest{Keys: tt.keys, Bucket: testbucket}
+			stream, err := client.DownloadConcatenated(context.Background(), req)
+			if err != nil {
+				t.Fatalf("DownloadService.DownloadConcatenated() error = %v", err)
+			}
+
+			got, err := recvAll(stream)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("DownloadService.DownloadConcatenated() error = %v, want code %v", err, tt.wantCode)
+			}
+
+			if tt.wantCode != codes.OK {
+				if len(got) != 0 {
+					t.Errorf("DownloadService.DownloadConcatenated() sent %d bytes before failing", len(got))
+				}
+
+				return
+			}
+
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("DownloadService.DownloadConcatenated() file downloaded is different from the joined objects")
+			}
+
+			header, err := stream.Header()
+			if err != nil {
+				t.Fatalf("DownloadService.DownloadConcatenated() header error = %v", err)
+			}
+
+			wantSize := strconv.Itoa(len(tt.want))
+			if size := header.Get(download.ConcatenatedSizeHeader); len(size) != 1 || size[0] != wantSize {
+				t.Errorf("DownloadService.DownloadConcatenated() size header = %v, want %s", size, wantSize)
+			}
+		})
+	}
+}
+
+func TestDownloadService_DownloadConcatenatedRouted(t *testing.T) {
+	buckets := []string{"concat-0", "concat-1"}
+	for _, bucket := range buckets {
+		if err := emptyAndDeleteBucket(bucket); err != nil {
+			t.Logf("failed to emptyAndDeleteBucket, %v", err)
+		}
+
+		if _, err := s3Client.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String(bucket)}); err != nil {
+			t.Fatalf("failed to create bucket %s, %v", bucket, err)
+		}
+	}
+
+	service := download.NewService(s3Client, logger)
+	service.BucketRouter = func(key string) string {
+		if strings.HasSuffix(key, ".part1") {
+			return buckets[1]
+		}
+
+		return buckets[0]
+	}
+
+	// Every member is stored in the bucket it's routed to, which differ between the members.
+	keys := []string{"routed.part0", "routed.part1", "routed.part2"}
+	members := [][]byte{file[:1<<20], file[1<<20 : 1<<20+12345], file[1<<20+12345:]}
+	for i, key := range keys {
+		if _, err := s3Client.PutObject(&s3.PutObjectInput{
+			Bucket: aws.String(service.BucketRouter(key)),
+			Key:    aws.String(key),
+			Body:   bytes.NewReader(members[i]),
+		}); err != nil {
+			t.Fatalf("failed to upload %s, %v", key, err)
+		}
+	}
+
+	var authorized []string
+	service.Authorizer = func(ctx context.Context, subject string, bucket string, key string) error {
+		authorized = append(authorized, bucket+"/"+key)
+		if key == "routed.part2" {
+			return status.Error(codes.PermissionDenied, "denied")
+		}
+
+		return nil
+	}
+
+	client, closeClient := newServiceClient(t, service)
+	defer closeClient()
+
+	stream, err := client.DownloadConcatenated(context.Background(), &pb.DownloadConcatenatedRequest{Keys: keys[:2]})
+	if err != nil {
+		t.Fatalf("DownloadService.DownloadConcatenated() error = %v", err)
+	}
+
+	got, err := recvAll(stream)
+	if err != nil {
+		t.Fatalf("DownloadService.DownloadConcatenated() error = %v", err)
+	}
+
+	if want := file[:1<<20+12345]; !bytes.Equal(got, want) {
+		t.Errorf("DownloadService.DownloadConcatenated() file downloaded is different from the joined objects")
+	}
+
+	wantAuthorized := []string{buckets[0] + "/routed.part0", buckets[1] + "/routed.part1"}
+	if !reflect.DeepEqual(authorized, wantAuthorized) {
+		t.Errorf("DownloadService.DownloadConcatenated() authorized %v, want %v", authorized, wantAuthorized)
+	}
+
+	// Every member is authorized on its own.
+	stream, err = client.DownloadConcatenated(context.Background(), &pb.DownloadConcatenatedRequest{Keys: keys})
+	if err != nil {
+		t.Fatalf("DownloadService.DownloadConcatenated() error = %v", err)
+	}
+
+	if _, err := recvAll(stream); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("DownloadService.DownloadConcatenated() error = %v, want code %v", err, codes.PermissionDenied)
+	}
+}
@@ -0,0 +1,240 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	opentracing "github.com/opentracing/opentracing-go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fetchedPartResult is a part fetched into memory, or the error fetching it.
+type fetchedPartResult struct {
+	data []byte
+	err  error
+}
+
+// concurrentPrefetcher fetches the parts of a download into memory concurrently, and hands them
+// out in order. The parts fetched ahead of the part being sent are bounded by the maximal concurrency.
+type concurrentPrefetcher struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	parts  chan chan fetchedPartResult
+	tuner  *concurrencyTuner
+	fixed  int
+	done   chan struct{}
+}
+
+// prefetchConcurrently starts fetching the totalParts parts of objectRange of bucket/key with up to
+// s.PartConcurrency concurrent GetObject calls, tuned by the throughput if s.AutoTunePartConcurrency is set.
+// Adjacent parts are coalesced into a single call of up to s.CoalesceMaxSize bytes, if set.
+// The returned prefetcher must be closed to stop fetching.
+func (s Service) prefetchConcurrently(
+	ctx context.Context,
+	bucket string,
+	key string,
+	objectRange byteRange,
+	partSize int64,
+	alignParts bool,
+	totalParts int64,
+) *concurrentPrefetcher {
+	ctx, cancel := context.WithCancel(ctx)
+	p := &concurrentPrefetcher{
+		ctx:    ctx,
+		cancel: cancel,
+		parts:  make(chan chan fetchedPartResult, s.PartConcurrency),
+		fixed:  s.PartConcurrency,
+		done:   make(chan struct{}),
+	}
+
+	// Small downloads end before they could be tuned, so they fetch their parts at once.
+	if s.AutoTunePartConcurrency && totalParts >= autoTuneMinParts {
+		p.tuner = newConcurrencyTuner(s.PartConcurrency, time.Now())
+	}
+
+	// Adjacent parts are fetched by a single read of up to s.CoalesceMaxSize bytes, if coalesced.
+	partRanges := make([]byteRange, totalParts)
+	for currentPart := range partRanges {
+		partRanges[currentPart] = objectRange.part(int64(currentPart), partSize, alignParts)
+	}
+	reads := coalesceRanges(partRanges, s.CoalesceGap, s.CoalesceMaxSize)
+
+	go func() {
+		defer close(p.done)
+		defer close(p.parts)
+
+		// Every read signals released once it's done, which never blocks since at most
+		// s.PartConcurrency reads are fetched at once.
+		released := make(chan struct{}, s.PartConcurrency)
+		inFlight := 0
+		firstPart := int64(0)
+		for _, read := range reads {
+			for inFlight >= p.concurrency() {
+				select {
+				case <-released:
+					inFlight--
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			results := make([]chan fetchedPartResult, len(read.ranges))
+			for i := range results {
+				results[i] = make(chan fetchedPartResult, 1)
+			}
+
+			inFlight++
+			go func(read coalescedRead, firstPart int64) {
+				defer func() { released <- struct{}{} }()
+
+				s.fetchRead(ctx, p, bucket, key, read, firstPart, results)
+			}(read, firstPart)
+
+			// Queue the results of the read's parts in order, waiting for parts to be handed out.
+			for _, result := range results {
+				select {
+				case p.parts <- result:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			firstPart += int64(len(read.ranges))
+		}
+	}()
+
+	return p
+}
+
+// fetchRead fetches read, whose first range is the part number firstPart of bucket/key, for p,
+// and hands the bytes of its parts, or the error fetching them, to their results.
+func (s Service) fetchRead(
+	ctx context.Context,
+	p *concurrentPrefetcher,
+	bucket string,
+	key string,
+	read coalescedRead,
+	firstPart int64,
+	results []chan fetchedPartResult,
+) {
+	parts, err := s.fetchCoalesced(ctx, bucket, key, read, firstPart)
+	if err == nil && p.tuner != nil {
+		p.tuner.observe(read.length(), time.Now())
+	}
+
+	for i, result := range results {
+		if err != nil {
+			result <- fetchedPartResult{err: err}
+			continue
+		}
+
+		result <- fetchedPartResult{data: parts[i]}
+	}
+}
+
+// concurrency returns the number of parts p fetches at once.
+func (p *concurrentPrefetcher) concurrency() int {
+	if p.tuner != nil {
+		return p.tuner.current()
+	}
+
+	return p.fixed
+}
+
+// next returns the next fetched part, waiting for it to be fetched.
+// It returns the error of fetching the part, after which the prefetcher must be closed.
+func (p *concurrentPrefetcher) next() (io.ReadCloser, error) {
+	result, ok := <-p.parts
+	if !ok {
+		if err := p.ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("no more fetched parts")
+	}
+
+	select {
+	case part := <-result:
+		if part.err != nil {
+			return nil, part.err
+		}
+
+		return ioutil.NopCloser(bytes.NewReader(part.data)), nil
+	case <-p.ctx.Done():
+		return nil, p.ctx.Err()
+	}
+}
+
+// close stops fetching, dropping the fetched parts that weren't handed out.
+func (p *concurrentPrefetcher) close() {
+	p.cancel()
+	<-p.done
+}
+
+// fetchPartBytes downloads partRange, the part number currentPart of bucket/key, into memory.
+// It returns a DataLoss error if S3 returned fewer bytes than partRange's length.
+func (s Service) fetchPartBytes(
+	ctx context.Context,
+	bucket string,
+	key string,
+	partRange byteRange,
+	currentPart int64,
+) (data []byte, err error) {
+	getObjectInput := &s3.GetObjectInput{
+		Key:        aws.String(key),
+		Bucket:     aws.String(bucket),
+		PartNumber: aws.Int64(currentPart),
+		Range:      aws.String(fmt.Sprintf("bytes=%d-%d", partRange.start, partRange.end)),
+	}
+
+	span := s.startClientSpan(ctx, "s3.GetObject", opentracing.Tags{
+		"s3.bucket": bucket,
+		"s3.key":    key,
+		"s3.range":  aws.StringValue(getObjectInput.Range),
+	})
+	defer func() {
+		finishSpan(span, err)
+	}()
+
+	objectPartOutput, err := s.getPartObject(ctx, bucket, getObjectInput)
+	if err != nil {
+		return nil, err
+	}
+	defer objectPartOutput.Body.Close()
+
+	data = make([]byte, partRange.length())
+	n, err := io.ReadFull(objectPartOutput.Body, data)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, status.Errorf(
+			codes.DataLoss,
+			"object %s/%s was truncated, read %d of %d bytes of range %d-%d",
+			bucket, key, n, len(data), partRange.start, partRange.end,
+		)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// logPartConcurrency logs the part concurrency that p was tuned to for d, if it was tuned.
+func (s Service) logPartConcurrency(d *partDownload, p *concurrentPrefetcher) {
+	if p.tuner == nil {
+		return
+	}
+
+	concurrency := p.tuner.current()
+	s.downloadLogger(d).WithField("download.part_concurrency", concurrency).Infof(
+		"download of %s/%s fetched %d parts at once",
+		d.bucket, d.key, concurrency,
+	)
+}
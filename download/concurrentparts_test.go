@@ -0,0 +1,256 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// simulatedPattern is the repeating pattern of the bytes of the objects of a simulatedBackend.
+var simulatedPattern = func() []byte {
+	pattern := make([]byte, download.PartSize+251)
+	for i := range pattern {
+		pattern[i] = byte(i % 251)
+	}
+
+	return pattern
+}()
+
+// simulatedObjectRange returns the bytes from start to end, inclusive, of the objects of a simulatedBackend,
+// up to a part's bytes.
+func simulatedObjectRange(start int64, end int64) []byte {
+	offset := start % 251
+
+	return simulatedPattern[offset : offset+end-start+1]
+}
+
+// simulatedBackend is a simulated S3 serving objects of size bytes, whose GetObject calls take
+// the latency of the number of calls in flight, and fail from the byte failAt on, if set.
+type simulatedBackend struct {
+	size    int64
+	failAt  int64
+	latency func(inFlight int) time.Duration
+
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+}
+
+// client returns an S3 client of b, that never sends its requests.
+func (b *simulatedBackend) client() *s3.S3 {
+	client := s3.New(session.Must(session.NewSession(&s3Client.Config)))
+	client.Handlers.Send.Clear()
+	client.Handlers.Send.PushBack(func(r *request.Request) {
+		header := http.Header{}
+		r.HTTPResponse = &http.Response{StatusCode: http.StatusOK, Header: header, Body: http.NoBody}
+		header.Set("ETag", `"simulated"`)
+
+		input, ok := r.Params.(*s3.GetObjectInput)
+		if !ok {
+			header.Set("Content-Length", strconv.FormatInt(b.size, 10))
+			return
+		}
+
+		var start, end int64
+		if _, err := fmt.Sscanf(aws.StringValue(input.Range), "bytes=%d-%d", &start, &end); err != nil {
+			r.Error = awserr.New("InvalidRange", err.Error(), nil)
+			return
+		}
+
+		time.Sleep(b.latency(b.enter()))
+		defer b.exit()
+
+		if b.failAt > 0 && end >= b.failAt {
+			r.Error = awserr.New("InternalError", "injected failure", nil)
+			return
+		}
+
+		data := simulatedObjectRange(start, end)
+		r.HTTPResponse.StatusCode = http.StatusPartialContent
+		r.HTTPResponse.Body = ioutil.NopCloser(bytes.NewReader(data))
+		header.Set("Content-Length", strconv.Itoa(len(data)))
+	})
+
+	return client
+}
+
+// enter records a GetObject call and returns the number of calls in flight with it.
+func (b *simulatedBackend) enter() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.inFlight++
+	if b.inFlight > b.maxInFlight {
+		b.maxInFlight = b.inFlight
+	}
+
+	return b.inFlight
+}
+
+// exit records that a GetObject call ended.
+func (b *simulatedBackend) exit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.inFlight--
+}
+
+// favoringConcurrency returns a latency of a backend whose throughput peaks at favored calls in flight.
+func favoringConcurrency(favored int) func(inFlight int) time.Duration {
+	return func(inFlight int) time.Duration {
+		latency := 100 * time.Millisecond
+		if inFlight > favored {
+			latency *= time.Duration(1 + 2*(inFlight-favored))
+		}
+
+		return latency
+	}
+}
+
+func TestDownloadService_DownloadPartConcurrency(t *testing.T) {
+	tests := []struct {
+		name            string
+		parts           int64
+		concurrency     int
+		minSize         int64
+		autoTune        bool
+		failAt          int64
+		wantErr         bool
+		wantMaxInFlight int
+		wantTuned       int
+	}{
+		{name: "part concurrency - fixed", parts: 6, concurrency: 4, wantMaxInFlight: 4},
+		{
+			name:        "part concurrency - fetch failure",
+			parts:       6,
+			concurrency: 4,
+			failAt:      3 * download.PartSize,
+			wantErr:     true,
+		},
+		{
+			name:            "part concurrency - below min size",
+			parts:           6,
+			concurrency:     4,
+			minSize:         6 * download.PartSize,
+			wantMaxInFlight: 1,
+		},
+		{
+			name:            "part concurrency - above min size",
+			parts:           6,
+			concurrency:     4,
+			minSize:         5 * download.PartSize,
+			wantMaxInFlight: 4,
+		},
+		{name: "part concurrency - auto-tuned", parts: 40, concurrency: 16, autoTune: true, wantTuned: 4},
+		{
+			name:            "part concurrency - small not auto-tuned",
+			parts:           3,
+			concurrency:     16,
+			autoTune:        true,
+			wantMaxInFlight: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			serviceLogger := logrus.New()
+			serviceLogger.SetOutput(ioutil.Discard)
+			hook := test.NewLocal(serviceLogger)
+
+			backend := &simulatedBackend{
+				size:    tt.parts * download.PartSize,
+				failAt:  tt.failAt,
+				latency: favoringConcurrency(4),
+			}
+			service := download.NewService(backend.client(), serviceLogger)
+			service.PartConcurrency = tt.concurrency
+			service.ConcurrencyMinSize = tt.minSize
+			service.AutoTunePartConcurrency = tt.autoTune
+
+			stream := &hashingDownloadStream{ctx: context.Background(), hash: crc32.NewIEEE()}
+			err := service.Download(&pb.DownloadRequest{Key: testkey, Bucket: testbucket}, stream)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DownloadService.Download() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			want := crc32.NewIEEE()
+			for start := int64(0); start < backend.size; start += download.PartSize {
+				want.Write(simulatedObjectRange(start, start+download.PartSize-1))
+			}
+
+			if !bytes.Equal(stream.hash.Sum(nil), want.Sum(nil)) {
+				t.Errorf("DownloadService.Download() file downloaded is different from the wanted file")
+			}
+
+			if tt.wantMaxInFlight > 0 && backend.maxInFlight != tt.wantMaxInFlight {
+				t.Errorf(
+					"DownloadService.Download() fetched %d parts at once, want %d",
+					backend.maxInFlight, tt.wantMaxInFlight,
+				)
+			}
+
+			tuned := 0
+			for _, entry := range hook.AllEntries() {
+				if concurrency, ok := entry.Data["download.part_concurrency"]; ok {
+					tuned = concurrency.(int)
+				}
+			}
+
+			if tuned != tt.wantTuned {
+				t.Errorf("DownloadService.Download() tuned the part concurrency to %d, want %d", tuned, tt.wantTuned)
+			}
+		})
+	}
+}
+
+func BenchmarkDownloadService_DownloadSmallConcurrencyMinSize(b *testing.B) {
+	const smallSize = 64 << 10
+
+	benchmarks := []struct {
+		name    string
+		minSize int64
+	}{
+		{name: "concurrent", minSize: 0},
+		{name: "sequential below min size", minSize: download.PartSize},
+	}
+
+	for _, bm := range benchmarks {
+		bm := bm
+		b.Run(bm.name, func(b *testing.B) {
+			backend := &simulatedBackend{size: smallSize, latency: func(int) time.Duration { return 0 }}
+			service := download.NewService(backend.client(), logger)
+			service.PartConcurrency = 8
+			service.ConcurrencyMinSize = bm.minSize
+
+			b.SetBytes(smallSize)
+			for i := 0; i < b.N; i++ {
+				stream := &hashingDownloadStream{ctx: context.Background(), hash: crc32.NewIEEE()}
+				if err := service.Download(&pb.DownloadRequest{Key: testkey, Bucket: testbucket}, stream); err != nil {
+					b.Fatalf("DownloadService.Download() error = %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package download
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// NotModifiedHeader is the response header set to "true" when the object wasn't modified
+	// according to the request's IfNoneMatch or IfModifiedSince validators, and nothing is sent.
+	// HTTP adapters of the service respond to such downloads with http.StatusNotModified.
+	NotModifiedHeader = "x-download-not-modified"
+
+	// anyETag is the ETag list that matches any ETag of an existing object.
+	anyETag = "*"
+)
+
+// evaluateConditions evaluates the conditional validators of req against the object whose current
+// validators are etag and lastModified, in the precedence of RFC 7232 section 6: IfMatch, or
+// IfUnmodifiedSince without it, then IfNoneMatch, or IfModifiedSince without it.
+// ETag validators take precedence over date validators, and invalid dates are ignored.
+// It returns a FailedPrecondition error if a precondition failed, otherwise whether the object
+// wasn't modified since the client's copy.
+func evaluateConditions(req *pb.DownloadRequest, etag string, lastModified time.Time) (bool, error) {
+	lastModified = lastModified.UTC().Truncate(time.Second)
+
+	if ifMatch := req.GetIfMatch(); ifMatch != "" {
+		if !etagListMatches(ifMatch, etag, false) {
+			return false, status.Errorf(codes.FailedPrecondition, "object's ETag %s doesn't match %s", etag, ifMatch)
+		}
+	} else if date, err := http.ParseTime(req.GetIfUnmodifiedSince()); err == nil && lastModified.After(date) {
+		return false, status.Errorf(
+			codes.FailedPrecondition,
+			"object was modified at %s, after %s",
+			lastModified.Format(http.TimeFormat), req.GetIfUnmodifiedSince(),
+		)
+	}
+
+	if ifNoneMatch := req.GetIfNoneMatch(); ifNoneMatch != "" {
+		return etagListMatches(ifNoneMatch, etag, true), nil
+	}
+
+	date, err := http.ParseTime(req.GetIfModifiedSince())
+
+	return err == nil && !lastModified.After(date), nil
+}
+
+// etagListMatches reports whether etag is in list, a comma-separated list of ETags or "*",
+// using the weak comparison if weak is set, otherwise the strong comparison, which never
+// matches weak ETags.
+func etagListMatches(list string, etag string, weak bool) bool {
+	if etag == "" {
+		return false
+	}
+
+	if strings.TrimSpace(list) == anyETag {
+		return true
+	}
+
+	tag, etagWeak := parseETag(etag)
+	if !weak && etagWeak {
+		return false
+	}
+
+	for _, candidate := range strings.Split(list, ",") {
+		candidateTag, candidateWeak := parseETag(strings.TrimSpace(candidate))
+		if (weak || !candidateWeak) && candidateTag == tag {
+			return true
+		}
+	}
+
+	return false
+}
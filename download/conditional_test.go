@@ -0,0 +1,145 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDownloadService_DownloadConditional(t *testing.T) {
+	head, err := s3Client.HeadObject(&s3.HeadObjectInput{Bucket: aws.String(testbucket), Key: aws.String(testkey)})
+	if err != nil {
+		t.Fatalf("failed to head %s/%s: %v", testbucket, testkey, err)
+	}
+
+	etag := aws.StringValue(head.ETag)
+	lastModified := aws.TimeValue(head.LastModified)
+	before := lastModified.Add(-time.Hour).UTC().Format(http.TimeFormat)
+	after := lastModified.Add(time.Hour).UTC().Format(http.TimeFormat)
+
+	tests := []struct {
+		name            string
+		req             *pb.DownloadRequest
+		wantCode        codes.Code
+		wantNotModified bool
+	}{
+		{name: "conditional - no validators", req: &pb.DownloadRequest{}},
+		{name: "conditional - if match", req: &pb.DownloadRequest{IfMatch: etag}},
+		{name: "conditional - if match list", req: &pb.DownloadRequest{IfMatch: `"other", ` + etag}},
+		{name: "conditional - if match any", req: &pb.DownloadRequest{IfMatch: "*"}},
+		{
+			name:     "conditional - if match changed",
+			req:      &pb.DownloadRequest{IfMatch: `"changed"`},
+			wantCode: codes.FailedPrecondition,
+		},
+		{
+			name:     "conditional - if match weak",
+			req:      &pb.DownloadRequest{IfMatch: "W/" + etag},
+			wantCode: codes.FailedPrecondition,
+		},
+		{name: "conditional - if unmodified since", req: &pb.DownloadRequest{IfUnmodifiedSince: after}},
+		{
+			name:     "conditional - if unmodified since modified",
+			req:      &pb.DownloadRequest{IfUnmodifiedSince: before},
+			wantCode: codes.FailedPrecondition,
+		},
+		{
+			name: "conditional - if match takes precedence over if unmodified since",
+			req:  &pb.DownloadRequest{IfMatch: etag, IfUnmodifiedSince: before},
+		},
+		{
+			name:            "conditional - if none match",
+			req:             &pb.DownloadRequest{IfNoneMatch: etag},
+			wantNotModified: true,
+		},
+		{
+			name:            "conditional - if none match weak",
+			req:             &pb.DownloadRequest{IfNoneMatch: "W/" + etag},
+			wantNotModified: true,
+		},
+		{name: "conditional - if none match any", req: &pb.DownloadRequest{IfNoneMatch: "*"}, wantNotModified: true},
+		{name: "conditional - if none match changed", req: &pb.DownloadRequest{IfNoneMatch: `"changed"`}},
+		{
+			name:            "conditional - if modified since",
+			req:             &pb.DownloadRequest{IfModifiedSince: after},
+			wantNotModified: true,
+		},
+		{name: "conditional - if modified since modified", req: &pb.DownloadRequest{IfModifiedSince: before}},
+		{name: "conditional - invalid date ignored", req: &pb.DownloadRequest{IfModifiedSince: "yesterday"}},
+		{
+			name: "conditional - if none match takes precedence over if modified since",
+			req:  &pb.DownloadRequest{IfNoneMatch: `"changed"`, IfModifiedSince: after},
+		},
+		{
+			name:            "conditional - if none match not modified regardless of if modified since",
+			req:             &pb.DownloadRequest{IfNoneMatch: etag, IfModifiedSince: before},
+			wantNotModified: true,
+		},
+		{
+			name:     "conditional - failed precondition takes precedence over not modified",
+			req:      &pb.DownloadRequest{IfMatch: `"changed"`, IfNoneMatch: etag},
+			wantCode: codes.FailedPrecondition,
+		},
+		{
+			name:            "conditional - if match and if none match",
+			req:             &pb.DownloadRequest{IfMatch: etag, IfNoneMatch: etag},
+			wantNotModified: true,
+		},
+		{
+			name:     "conditional - if unmodified since and if modified since",
+			req:      &pb.DownloadRequest{IfUnmodifiedSince: before, IfModifiedSince: after},
+			wantCode: codes.FailedPrecondition,
+		},
+	}
+
+	client, closeClient := newServiceClient(t, download.NewService(s3Client, logger))
+	defer closeClient()
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.Key, tt.req.Bucket = testkey, testbucket
+			stream, err := client.Download(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			got, err := recvAll(stream)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("DownloadService.Download() error = %v, wantCode %v", err, tt.wantCode)
+			}
+
+			if tt.wantCode != codes.OK {
+				return
+			}
+
+			header, err := stream.Header()
+			if err != nil {
+				t.Fatalf("failed to get the response header: %v", err)
+			}
+
+			notModified := len(header.Get(download.NotModifiedHeader)) > 0
+			if notModified != tt.wantNotModified {
+				t.Errorf("DownloadService.Download() not modified = %v, want %v", notModified, tt.wantNotModified)
+			}
+
+			want := file
+			if tt.wantNotModified {
+				want = nil
+			}
+
+			if !bytes.Equal(got, want) {
+				t.Errorf("DownloadService.Download() downloaded %d bytes, want %d", len(got), len(want))
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package download
+
+import (
+	"context"
+	"crypto/sha256"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// AccessKeyIDHeader is the request header of the access key ID of delegated S3 credentials.
+	AccessKeyIDHeader = "x-s3-access-key-id"
+
+	// SecretAccessKeyHeader is the request header of the secret access key of delegated S3 credentials.
+	SecretAccessKeyHeader = "x-s3-secret-access-key"
+
+	// SessionTokenHeader is the request header of the session token of temporary delegated S3 credentials.
+	SessionTokenHeader = "x-s3-session-token"
+
+	// maxDelegatedClients is the number of cached S3 clients of delegated credentials above which
+	// the cache is cleared, bounding the clients of rotated credentials.
+	maxDelegatedClients = 1024
+)
+
+// delegatedCredentials are the S3 credentials a request delegated to the service to read on its behalf.
+type delegatedCredentials struct {
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+}
+
+// delegatedCredentialsFromContext returns the delegated S3 credentials in the metadata of ctx,
+// and false if it has none.
+func delegatedCredentialsFromContext(ctx context.Context) (delegatedCredentials, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return delegatedCredentials{}, false
+	}
+
+	first := func(header string) string {
+		if values := md.Get(header); len(values) > 0 {
+			return values[0]
+		}
+
+		return ""
+	}
+
+	creds := delegatedCredentials{
+		accessKeyID:     first(AccessKeyIDHeader),
+		secretAccessKey: first(SecretAccessKeyHeader),
+		sessionToken:    first(SessionTokenHeader),
+	}
+
+	return creds, creds.accessKeyID != "" && creds.secretAccessKey != ""
+}
+
+// hash returns the hash the S3 client of c is cached by, so the cache doesn't hold the secrets.
+func (c delegatedCredentials) hash() [sha256.Size]byte {
+	return sha256.Sum256([]byte(c.accessKeyID + "\x00" + c.secretAccessKey + "\x00" + c.sessionToken))
+}
+
+// delegatedClients caches the S3 clients of delegated credentials by the hash of the credentials.
+type delegatedClients struct {
+	mu      sync.Mutex
+	clients map[[sha256.Size]byte]*s3.S3
+}
+
+// newDelegatedClients returns an empty delegatedClients.
+func newDelegatedClients() *delegatedClients {
+	return &delegatedClients{clients: make(map[[sha256.Size]byte]*s3.S3)}
+}
+
+// get returns the S3 client of creds, configured like base.
+func (c *delegatedClients) get(base *s3.S3, creds delegatedCredentials) *s3.S3 {
+	hash := creds.hash()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if client, ok := c.clients[hash]; ok {
+		return client
+	}
+
+	if len(c.clients) >= maxDelegatedClients {
+		c.clients = make(map[[sha256.Size]byte]*s3.S3)
+	}
+
+	config := base.Config.Copy(&aws.Config{
+		Credentials: credentials.NewStaticCredentials(creds.accessKeyID, creds.secretAccessKey, creds.sessionToken),
+	})
+	client := s3.New(session.Must(session.NewSession(config)))
+	c.clients[hash] = client
+
+	return client
+}
+
+// s3ClientFor returns the S3 client to serve the request of ctx to bucket with, the client of
+// the request's delegated credentials if s.AllowDelegatedCredentials is set and it has any,
+// otherwise the service's client, configured for the region of bucket if S3 redirected to it.
+func (s Service) s3ClientFor(ctx context.Context, bucket string) *s3.S3 {
+	if !s.AllowDelegatedCredentials || s.delegatedClients == nil {
+		return s.regionClients.get(s.s3Client, bucket)
+	}
+
+	creds, ok := delegatedCredentialsFromContext(ctx)
+	if !ok {
+		return s.regionClients.get(s.s3Client, bucket)
+	}
+
+	return s.regionClients.get(s.delegatedClients.get(s.s3Client, creds), bucket)
+}
+
+// isDelegated returns whether the request of ctx is served with its delegated credentials.
+func (s Service) isDelegated(ctx context.Context) bool {
+	if !s.AllowDelegatedCredentials {
+		return false
+	}
+
+	_, ok := delegatedCredentialsFromContext(ctx)
+
+	return ok
+}
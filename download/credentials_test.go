@@ -0,0 +1,173 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"google.golang.org/grpc/metadata"
+)
+
+// credentialRe matches the access key ID of a signed request's Authorization header.
+var credentialRe = regexp.MustCompile(`Credential=([^/]+)/`)
+
+// s3Credentials are the credentials a request to S3 was signed with.
+type s3Credentials struct {
+	accessKeyID  string
+	sessionToken string
+}
+
+// credentialsRecordingS3Client returns an S3 client that sends its requests through a proxy
+// to the test S3 server, and a function that returns the credentials of the requests so far.
+func credentialsRecordingS3Client(t *testing.T) (*s3.S3, func() []s3Credentials, func()) {
+	t.Helper()
+
+	target, err := url.Parse(aws.StringValue(s3Client.Config.Endpoint))
+	if err != nil {
+		t.Fatalf("failed to parse the S3 endpoint, %v", err)
+	}
+
+	var mu sync.Mutex
+	var recorded []s3Credentials
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		creds := s3Credentials{sessionToken: r.Header.Get("X-Amz-Security-Token")}
+		if match := credentialRe.FindStringSubmatch(r.Header.Get("Authorization")); match != nil {
+			creds.accessKeyID = match[1]
+		}
+
+		mu.Lock()
+		recorded = append(recorded, creds)
+		mu.Unlock()
+
+		proxy.ServeHTTP(w, r)
+	}))
+
+	client := s3.New(session.Must(session.NewSession(s3Client.Config.Copy(&aws.Config{
+		Endpoint: aws.String(server.URL),
+	}))))
+
+	return client, func() []s3Credentials {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return append([]s3Credentials(nil), recorded...)
+	}, server.Close
+}
+
+func TestDownloadService_DownloadDelegatedCredentials(t *testing.T) {
+	const accessKeyID, secretAccessKey, sessionToken = "delegated-key", "delegated-secret", "delegated-token"
+
+	serviceCreds, err := s3Client.Config.Credentials.Get()
+	if err != nil {
+		t.Fatalf("failed to get the service's credentials, %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		allow     bool
+		md        metadata.MD
+		wantCreds s3Credentials
+	}{
+		{
+			name:      "credentials - default",
+			allow:     true,
+			wantCreds: s3Credentials{accessKeyID: serviceCreds.AccessKeyID},
+		},
+		{
+			name:  "credentials - delegated",
+			allow: true,
+			md: metadata.Pairs(
+				download.AccessKeyIDHeader, accessKeyID,
+				download.SecretAccessKeyHeader, secretAccessKey,
+				download.SessionTokenHeader, sessionToken,
+			),
+			wantCreds: s3Credentials{accessKeyID: accessKeyID, sessionToken: sessionToken},
+		},
+		{
+			name:      "credentials - delegated without secret",
+			allow:     true,
+			md:        metadata.Pairs(download.AccessKeyIDHeader, accessKeyID),
+			wantCreds: s3Credentials{accessKeyID: serviceCreds.AccessKeyID},
+		},
+		{
+			name: "credentials - delegation disabled",
+			md: metadata.Pairs(
+				download.AccessKeyIDHeader, accessKeyID,
+				download.SecretAccessKeyHeader, secretAccessKey,
+			),
+			wantCreds: s3Credentials{accessKeyID: serviceCreds.AccessKeyID},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			client, recorded, closeProxy := credentialsRecordingS3Client(t)
+			defer closeProxy()
+
+			serviceLogger := logrus.New()
+			serviceLogger.SetOutput(ioutil.Discard)
+			serviceLogger.SetLevel(logrus.DebugLevel)
+			logs := test.NewLocal(serviceLogger)
+
+			service := download.NewService(client, serviceLogger)
+			service.AllowDelegatedCredentials = tt.allow
+
+			serviceClient, closeClient := newServiceClient(t, service)
+			defer closeClient()
+
+			ctx := metadata.NewOutgoingContext(context.Background(), tt.md)
+			stream, err := serviceClient.Download(ctx, &pb.DownloadRequest{Key: testkey, Bucket: testbucket})
+			if err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			fileFromStream, err := recvAll(stream)
+			if err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			if !bytes.Equal(fileFromStream, file) {
+				t.Errorf("DownloadService.Download() file downloaded is different from the wanted file")
+			}
+
+			requests := recorded()
+			if len(requests) == 0 {
+				t.Fatalf("DownloadService.Download() sent no requests to S3")
+			}
+
+			for _, creds := range requests {
+				if creds != tt.wantCreds {
+					t.Errorf("DownloadService.Download() signed a request to S3 with %+v, want %+v", creds, tt.wantCreds)
+				}
+			}
+
+			for _, entry := range logs.AllEntries() {
+				line, err := entry.String()
+				if err != nil {
+					t.Fatalf("failed to format log entry, %v", err)
+				}
+
+				if strings.Contains(line, secretAccessKey) || strings.Contains(line, sessionToken) {
+					t.Errorf("DownloadService.Download() logged the delegated secrets: %s", line)
+				}
+			}
+		})
+	}
+}
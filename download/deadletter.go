@@ -0,0 +1,88 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/sirupsen/logrus"
+)
+
+// PermanentFailureMessage is the message of the log entry of a download that failed after
+// the retries of one of its parts were exhausted, for following up on actionable failures
+// separately from the transient failures that retries recovered from.
+const PermanentFailureMessage = "download.failed.permanent"
+
+// retryExhaustionContextKey is the context key of the retry exhaustion of a download.
+type retryExhaustionContextKey struct{}
+
+// retryExhaustion records the last error of the parts of a download whose retries were exhausted.
+type retryExhaustion struct {
+	mu  sync.Mutex
+	err error
+}
+
+// contextWithRetryExhaustion returns ctx with r, which records the parts of its download
+// whose retries are exhausted.
+func contextWithRetryExhaustion(ctx context.Context, r *retryExhaustion) context.Context {
+	return context.WithValue(ctx, retryExhaustionContextKey{}, r)
+}
+
+// recordRetryExhaustion records err as the error of a part of the download of ctx whose retries
+// were exhausted, if the download records them.
+func recordRetryExhaustion(ctx context.Context, err error) {
+	r, ok := ctx.Value(retryExhaustionContextKey{}).(*retryExhaustion)
+	if !ok {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.err = err
+}
+
+// lastError returns the error of the last part whose retries were exhausted, nil if none were.
+// A nil retryExhaustion returns nil.
+func (r *retryExhaustion) lastError() error {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+// logPermanentFailure logs a PermanentFailureMessage entry to s.DeadLetterLogger, or to s.logger if it's nil,
+// if the download of d ended with err after the retries of one of its parts were exhausted.
+// Failures that weren't retried, or that a retry, fallback or failover recovered from, aren't logged.
+func (s Service) logPermanentFailure(d *partDownload, err error) {
+	exhausted := d.exhausted.lastError()
+	if err == nil || exhausted == nil {
+		return
+	}
+
+	var requestID string
+	var reqErr awserr.RequestFailure
+	if errors.As(exhausted, &reqErr) {
+		requestID = reqErr.RequestID()
+	}
+
+	logger := s.DeadLetterLogger
+	if logger == nil {
+		logger = s.logger
+	}
+
+	logger.WithFields(logrus.Fields{
+		"error":         err.Error(),
+		"s3.error":      exhausted.Error(),
+		"s3.request_id": requestID,
+		"s3.bucket":     d.bucket,
+		"s3.key":        d.key,
+		"trace.id":      s.traceID(d.stream.Context()),
+		"key.prefix":    d.keyPrefix,
+	}).Error(PermanentFailureMessage)
+}
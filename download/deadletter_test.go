@@ -0,0 +1,112 @@
+package download_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"google.golang.org/grpc/status"
+)
+
+func TestDownloadService_DownloadPermanentFailure(t *testing.T) {
+	const requestID = "permanent-request"
+
+	serverError := awserr.NewRequestFailure(
+		awserr.New("InternalError", "injected server error", nil),
+		http.StatusInternalServerError,
+		requestID,
+	)
+
+	tests := []struct {
+		name      string
+		err       error
+		failures  int64
+		retries   int
+		wantErr   bool
+		wantEvent bool
+	}{
+		{
+			name:      "permanent failure - retries exhausted",
+			err:       serverError,
+			failures:  4,
+			retries:   3,
+			wantErr:   true,
+			wantEvent: true,
+		},
+		{name: "permanent failure - recovered by retry", err: serverError, failures: 2, retries: 3},
+		{
+			name:     "permanent failure - not retried",
+			err:      awserr.New(s3.ErrCodeNoSuchKey, "injected missing key", nil),
+			failures: 1,
+			retries:  3,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			serviceLogger := logrus.New()
+			serviceLogger.SetOutput(ioutil.Discard)
+			deadLetterLogger := logrus.New()
+			deadLetterLogger.SetOutput(ioutil.Discard)
+			hook := test.NewLocal(deadLetterLogger)
+
+			var calls int64
+			service := download.NewService(partFailingS3Client(tt.err, tt.failures, &calls), serviceLogger)
+			service.MaxBufferSize = int64(len(file))
+			service.PartRetries = tt.retries
+			service.PartRetryBaseDelay = time.Millisecond
+			service.DeadLetterLogger = deadLetterLogger
+
+			stream := &hashingDownloadStream{ctx: context.Background(), hash: sha256.New()}
+			err := service.Download(&pb.DownloadRequest{Key: testkey, Bucket: testbucket}, stream)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DownloadService.Download() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var events []*logrus.Entry
+			for _, entry := range hook.AllEntries() {
+				if entry.Message == download.PermanentFailureMessage {
+					events = append(events, entry)
+				}
+			}
+
+			if !tt.wantEvent {
+				if len(events) != 0 {
+					t.Errorf("DownloadService.Download() logged %d permanent failures, want none", len(events))
+				}
+
+				return
+			}
+
+			if len(events) != 1 {
+				t.Fatalf("DownloadService.Download() logged %d permanent failures, want 1", len(events))
+			}
+
+			for field, want := range map[string]string{
+				"s3.bucket":     testbucket,
+				"s3.key":        testkey,
+				"s3.request_id": requestID,
+				"error":         status.Convert(err).Message(),
+			} {
+				if got := events[0].Data[field]; got != want {
+					t.Errorf("DownloadService.Download() permanent failure %s = %v, want %v", field, got, want)
+				}
+			}
+
+			if _, ok := events[0].Data["trace.id"]; !ok {
+				t.Errorf("DownloadService.Download() permanent failure has no trace.id")
+			}
+		})
+	}
+}
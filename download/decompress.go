@@ -0,0 +1,165 @@
+package download
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/klauspost/compress/zstd"
+	pb "github.com/meateam/download-service/proto"
+	opentracing "github.com/opentracing/opentracing-go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// ContentTypeHeader is the response header of the content type of a decompressed object,
+	// omitted if it isn't inferable from the object's key.
+	ContentTypeHeader = "x-download-content-type"
+)
+
+// decompressor returns a reader of the decompressed bytes of the compressed bytes of r.
+type decompressor func(r io.Reader) (io.ReadCloser, error)
+
+// decompressorsByExtension are the decompressors of the objects by the extension of their keys.
+var decompressorsByExtension = map[string]decompressor{
+	".gz":  newGzipReader,
+	".zst": newZstdReader,
+}
+
+// newGzipReader returns a reader of the decompressed bytes of the gzip stream of r.
+func newGzipReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// newZstdReader returns a reader of the decompressed bytes of the zstd stream of r.
+func newZstdReader(r io.Reader) (io.ReadCloser, error) {
+	decoder, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+
+	return decoder.IOReadCloser(), nil
+}
+
+// decompressorFor returns the decompressor of the object of key by its extension,
+// and the content type of the decompressed object if it's inferable from the key without it.
+// It returns a nil decompressor if the object's extension isn't of a supported compression.
+func decompressorFor(key string) (decompressor, string) {
+	extension := path.Ext(key)
+	decompress, ok := decompressorsByExtension[extension]
+	if !ok {
+		return nil, ""
+	}
+
+	return decompress, mime.TypeByExtension(path.Ext(strings.TrimSuffix(key, extension)))
+}
+
+// prepareDecompress sets the decompressor of d if req requests its object decompressed and the
+// object is compressed, and sets the ContentTypeHeader header to the content type of the
+// decompressed object if it's inferable. It returns an InvalidArgument error if req combines
+// decompression with a range, an offset, reverse, follow or progress, which count the compressed bytes.
+func prepareDecompress(req *pb.DownloadRequest, d *partDownload) error {
+	if !req.GetDecompress() {
+		return nil
+	}
+
+	if req.GetRangeStart() != 0 || req.GetRangeEnd() != 0 || req.GetRangePercent() != nil ||
+		req.GetOffset() != 0 || req.GetReverse() || req.GetFollow() ||
+		req.GetProgressInterval() != 0 || req.GetProgressPercent() != 0 {
+		return status.Error(
+			codes.InvalidArgument,
+			"decompress can't be combined with ranges, offset, reverse, follow or progress",
+		)
+	}
+
+	decompress, contentType := decompressorFor(d.key)
+	if decompress == nil {
+		return nil
+	}
+
+	// The object's checksum is of its compressed bytes, which aren't the bytes sent.
+	d.decompress = decompress
+	d.checksum = objectChecksum{}
+
+	if contentType == "" {
+		return nil
+	}
+
+	return d.stream.SetHeader(metadata.Pairs(ContentTypeHeader, contentType))
+}
+
+// errorReader is an io.Reader that keeps the first error other than io.EOF of its underlying reader.
+type errorReader struct {
+	io.Reader
+	err error
+}
+
+// Read reads from the underlying reader into p and keeps its error.
+func (r *errorReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err != nil && err != io.EOF && r.err == nil {
+		r.err = err
+	}
+
+	return n, err
+}
+
+// sendDecompressed downloads the whole object of d by a single GetObject call, and sends its decompressed
+// bytes in chunks of up to the part size. It returns a DataLoss error if the object's compressed bytes
+// are corrupt or truncated, possibly after sending the bytes decompressed before the corruption.
+func (s Service) sendDecompressed(ctx context.Context, d *partDownload) error {
+	span := s.startClientSpan(ctx, "s3.GetObject", opentracing.Tags{"s3.bucket": d.bucket, "s3.key": d.key})
+	object, err := s.getPartObject(ctx, d.bucket, &s3.GetObjectInput{
+		Bucket:  aws.String(d.bucket),
+		Key:     aws.String(d.key),
+		IfMatch: aws.String(d.etag),
+	})
+	if err != nil {
+		finishSpan(span, err)
+		return s3ErrorToStatus(fmt.Errorf("failed to download object %s/%s: %w", d.bucket, d.key, err))
+	}
+	defer object.Body.Close()
+
+	body := &errorReader{Reader: object.Body}
+	err = s.sendDecompressedBody(d, body)
+	if body.err != nil {
+		err = s3ErrorToStatus(fmt.Errorf("failed to download object %s/%s: %w", d.bucket, d.key, body.err))
+	}
+	finishSpan(span, err)
+
+	return err
+}
+
+// sendDecompressedBody sends the decompressed bytes of body, the compressed object of d.
+func (s Service) sendDecompressedBody(d *partDownload, body io.Reader) error {
+	reader, err := d.decompress(body)
+	if err != nil {
+		return status.Errorf(codes.DataLoss, "object %s/%s is corrupt: %v", d.bucket, d.key, err)
+	}
+	defer reader.Close()
+
+	decompressed := &errorReader{Reader: reader}
+	sent, err := s.sendPart(d.stream, decompressed, make([]byte, s.bufferSize(d.partSize)), 0, 0, nil, d.keyPrefix)
+	d.bytesSent += sent
+
+	// Truncated streams end with io.ErrUnexpectedEOF, which sendPart takes for the end of the part.
+	if decompressed.err != nil {
+		return status.Errorf(codes.DataLoss, "object %s/%s is corrupt: %v", d.bucket, d.key, decompressed.err)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	d.partsSent++
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package download_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/klauspost/compress/zstd"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// gzipBytes returns the gzip compression of b.
+func gzipBytes(t *testing.T, b []byte) []byte {
+	t.Helper()
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(b); err != nil {
+		t.Fatalf("failed to gzip, %v", err)
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatalf("failed to gzip, %v", err)
+	}
+
+	return compressed.Bytes()
+}
+
+// zstdBytes returns the zstd compression of b.
+func zstdBytes(t *testing.T, b []byte) []byte {
+	t.Helper()
+
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatalf("failed to create zstd encoder, %v", err)
+	}
+	defer encoder.Close()
+
+	return encoder.EncodeAll(b, nil)
+}
+
+func TestDownloadService_DownloadDecompress(t *testing.T) {
+	gzipped := gzipBytes(t, file)
+
+	// Flip a byte of the compressed bytes, past the gzip header.
+	corrupt := append([]byte{}, gzipped...)
+	corrupt[len(corrupt)/2] ^= 0xff
+
+	objects := map[string][]byte{
+		"decompress.json.gz":  gzipped,
+		"decompress.html.zst": zstdBytes(t, file),
+		"decompress.gz":       gzipped,
+		"corrupt.txt.gz":      corrupt,
+		"truncated.txt.gz":    gzipped[:len(gzipped)/2],
+		"truncated.txt.zst":   zstdBytes(t, file)[:1024],
+		"uncompressed.txt.gz": file,
+	}
+	for key, object := range objects {
+		if _, err := s3Client.PutObject(&s3.PutObjectInput{
+			Bucket: aws.String(testbucket),
+			Key:    aws.String(key),
+			Body:   bytes.NewReader(object),
+		}); err != nil {
+			t.Fatalf("failed to upload %s, %v", key, err)
+		}
+	}
+
+	tests := []struct {
+		name            string
+		req             *pb.DownloadRequest
+		wantCode        codes.Code
+		want            []byte
+		wantContentType string
+	}{
+		{
+			name:            "decompress - gzip",
+			req:             &pb.DownloadRequest{Key: "decompress.json.gz", Decompress: true},
+			want:            file,
+			wantContentType: "application/json",
+		},
+		{
+			name:            "decompress - zstd",
+			req:             &pb.DownloadRequest{Key: "decompress.html.zst", Decompress: true},
+			want:            file,
+			wantContentType: "text/html; charset=utf-8",
+		},
+		{
+			name: "decompress - no inferable content type",
+			req:  &pb.DownloadRequest{Key: "decompress.gz", Decompress: true},
+			want: file,
+		},
+		{
+			name: "decompress - not requested",
+			req:  &pb.DownloadRequest{Key: "decompress.json.gz"},
+			want: gzipped,
+		},
+		{
+			name: "decompress - not compressed",
+			req:  &pb.DownloadRequest{Key: testkey, Decompress: true},
+			want: file,
+		},
+		{
+			name:     "decompress - corrupt",
+			req:      &pb.DownloadRequest{Key: "corrupt.txt.gz", Decompress: true},
+			wantCode: codes.DataLoss,
+		},
+		{
+			name:     "decompress - truncated gzip",
+			req:      &pb.DownloadRequest{Key: "truncated.txt.gz", Decompress: true},
+			wantCode: codes.DataLoss,
+		},
+		{
+			name:     "decompress - truncated zstd",
+			req:      &pb.DownloadRequest{Key: "truncated.txt.zst", Decompress: true},
+			wantCode: codes.DataLoss,
+		},
+		{
+			name:     "decompress - not gzip",
+			req:      &pb.DownloadRequest{Key: "uncompressed.txt.gz", Decompress: true},
+			wantCode: codes.DataLoss,
+		},
+		{
+			name:     "decompress - with a range",
+			req:      &pb.DownloadRequest{Key: "decompress.json.gz", Decompress: true, RangeEnd: 10},
+			wantCode: codes.InvalidArgument,
+		},
+	}
+
+	service := download.NewService(s3Client, logger)
+	service.MaxBufferSize = 1 << 20
+	client, closeClient := newServiceClient(t, service)
+	defer closeClient()
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.Bucket = testbucket
+			stream, err := client.Download(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			got, err := recvAll(stream)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("DownloadService.Download() error = %v, want code %v", err, tt.wantCode)
+			}
+
+			if tt.wantCode != codes.OK {
+				return
+			}
+
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("DownloadService.Download() file downloaded is different from the wanted file")
+			}
+
+			header, err := stream.Header()
+			if err != nil {
+				t.Fatalf("DownloadService.Download() header error = %v", err)
+			}
+
+			contentType := ""
+			if values := header.Get(download.ContentTypeHeader); len(values) > 0 {
+				contentType = values[0]
+			}
+
+			if contentType != tt.wantContentType {
+				t.Errorf("DownloadService.Download() content type = %q, want %q", contentType, tt.wantContentType)
+			}
+		})
+	}
+}
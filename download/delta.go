@@ -0,0 +1,443 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// DefaultDeltaBlockSize is the default size of the blocks of the base version matched in a delta.
+	DefaultDeltaBlockSize = 4 << 10
+
+	// MinDeltaBlockSize is the minimal size of the blocks of the base version matched in a delta.
+	MinDeltaBlockSize = 64
+
+	// DefaultMaxDeltaSize is the default size of the largest target version a delta is computed for,
+	// since the target version is held in memory while computing it.
+	DefaultMaxDeltaSize = 64 << 20
+
+	// DeltaFullHeader is the response header set to "true" when the delta is the whole target
+	// version, since the versions are too different or too large for a delta to be beneficial.
+	DeltaFullHeader = "x-download-delta-full"
+
+	// deltaMaxLiteralRatio is the ratio of the target version's bytes above which a delta
+	// with more literal bytes isn't beneficial, and the whole target version is sent instead.
+	deltaMaxLiteralRatio = 0.9
+)
+
+// deltaBlock is a block of the base version of a delta.
+type deltaBlock struct {
+	offset int64
+	strong [sha256.Size]byte
+}
+
+// deltaSignature is the signature of the base version of a delta, its blocks by their weak checksum.
+type deltaSignature map[uint32][]deltaBlock
+
+// deltaOp is an operation of a delta, either copying length bytes of the base version from offset,
+// or sending length bytes of the target version from offset.
+type deltaOp struct {
+	copy   bool
+	offset int64
+	length int64
+}
+
+// weakChecksum returns the rsync rolling checksum of block, its sums a and b.
+func weakChecksum(block []byte) (uint32, uint32) {
+	var a, b uint32
+	n := uint32(len(block))
+	for i, c := range block {
+		a += uint32(c)
+		b += (n - uint32(i)) * uint32(c)
+	}
+
+	return a & 0xffff, b & 0xffff
+}
+
+// newDeltaSignature reads base and returns the signature of its whole blocks of blockSize bytes.
+func newDeltaSignature(base io.Reader, blockSize int64) (deltaSignature, error) {
+	signature := make(deltaSignature)
+	block := make([]byte, blockSize)
+	for offset := int64(0); ; offset += blockSize {
+		if _, err := io.ReadFull(base, block); err == io.EOF || err == io.ErrUnexpectedEOF {
+			return signature, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		a, b := weakChecksum(block)
+		weak := a | b<<16
+		signature[weak] = append(signature[weak], deltaBlock{offset: offset, strong: sha256.Sum256(block)})
+	}
+}
+
+// match returns the offset of the block of the base version whose weak checksum is weak
+// and whose bytes are block, and false if there's none.
+func (s deltaSignature) match(weak uint32, block []byte) (int64, bool) {
+	candidates, ok := s[weak]
+	if !ok {
+		return 0, false
+	}
+
+	strong := sha256.Sum256(block)
+	for _, candidate := range candidates {
+		if candidate.strong == strong {
+			return candidate.offset, true
+		}
+	}
+
+	return 0, false
+}
+
+// computeDelta returns the operations that reconstruct target from the base version of signature,
+// matching the blocks of the base version of blockSize bytes at every offset of target with
+// a rolling checksum, and the number of literal bytes of target sent by the operations.
+func computeDelta(signature deltaSignature, target []byte, blockSize int64) ([]deltaOp, int64) {
+	var ops []deltaOp
+	var literal int64
+	addLiteral := func(start int64, end int64) {
+		if end > start {
+			ops = append(ops, deltaOp{offset: start, length: end - start})
+			literal += end - start
+		}
+	}
+
+	length := int64(len(target))
+	literalStart := int64(0)
+	rolling := false
+	var a, b uint32
+	for i := int64(0); i+blockSize <= length; {
+		if !rolling {
+			a, b = weakChecksum(target[i : i+blockSize])
+			rolling = true
+		}
+
+		if offset, ok := signature.match(a|b<<16, target[i:i+blockSize]); ok {
+			addLiteral(literalStart, i)
+			ops = appendCopy(ops, offset, blockSize)
+			i += blockSize
+			literalStart = i
+			rolling = false
+			continue
+		}
+
+		// Roll the checksum one byte forward.
+		if i+blockSize < length {
+			out, in := uint32(target[i]), uint32(target[i+blockSize])
+			a = (a - out + in) & 0xffff
+			b = (b - uint32(blockSize)*out + a) & 0xffff
+		}
+		i++
+	}
+	addLiteral(literalStart, length)
+
+	return ops, literal
+}
+
+// appendCopy appends copying length bytes of the base version from offset to ops,
+// extending the last operation if it copies the bytes right before them.
+func appendCopy(ops []deltaOp, offset int64, length int64) []deltaOp {
+	if last := len(ops) - 1; last >= 0 && ops[last].copy && ops[last].offset+ops[last].length == offset {
+		ops[last].length += length
+		return ops
+	}
+
+	return append(ops, deltaOp{copy: true, offset: offset, length: length})
+}
+
+// deltaBlockSize returns the block size of req, DefaultDeltaBlockSize if it's unset,
+// and an InvalidArgument error if it's below MinDeltaBlockSize or above PartSize.
+func deltaBlockSize(req *pb.DownloadDeltaRequest) (int64, error) {
+	blockSize := req.GetBlockSize()
+	if blockSize == 0 {
+		return DefaultDeltaBlockSize, nil
+	}
+
+	if blockSize < MinDeltaBlockSize || blockSize > PartSize {
+		return 0, status.Errorf(
+			codes.InvalidArgument,
+			"block size must be between %d and %d, got %d",
+			MinDeltaBlockSize,
+			PartSize,
+			blockSize,
+		)
+	}
+
+	return blockSize, nil
+}
+
+// DownloadDelta is the request to download a version of an object as the delta from another
+// version of it, which the client has. The delta is computed by matching the blocks of the base
+// version in the target version with a rolling checksum, like rsync, and sends the target version's
+// bytes that match no block of the base version. The whole target version is sent instead when it's
+// larger than s.MaxDeltaSize or the delta isn't beneficial, setting the DeltaFullHeader header.
+func (s Service) DownloadDelta(req *pb.DownloadDeltaRequest, stream pb.Download_DownloadDeltaServer) error {
+	// Reject oversized requests before doing any work for them.
+	if err := s.RequestLimits.check(req, []string{req.GetKey()}); err != nil {
+		return err
+	}
+
+	// Shed the download before doing any work for it if the service is overloaded.
+	if err := s.startDownload(); err != nil {
+		return err
+	}
+	defer s.endDownload()
+
+	// Reject the download if the service served its egress quota.
+	if err := s.EgressQuota.check(); err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+	bucket, key, err := s.resolveObject(req.GetBucket(), req.GetKey(), "")
+	if err != nil {
+		return err
+	}
+
+	if req.GetBaseVersionId() == "" {
+		return status.Error(codes.InvalidArgument, "base version id is required")
+	}
+
+	blockSize, err := deltaBlockSize(req)
+	if err != nil {
+		return err
+	}
+
+	if err := s.authorize(ctx, bucket, key); err != nil {
+		return err
+	}
+
+	// Limit the concurrent downloads of the requesting subject.
+	subject := SubjectFromContext(ctx)
+	if err := s.SubjectLimiter.acquire(subject); err != nil {
+		return err
+	}
+	defer s.SubjectLimiter.release(subject)
+
+	// Share the bandwidth budget with the other downloads as a standard download, if limited.
+	qos, err := s.QoSBudget.join(pb.QoSClass_STANDARD)
+	if err != nil {
+		return err
+	}
+	defer qos.leave()
+
+	stream = s.prepareDeltaStream(stream, qos)
+
+	target, err := s.getObjectVersion(ctx, bucket, key, req.GetTargetVersionId())
+	if err != nil {
+		return err
+	}
+	defer target.Body.Close()
+
+	// Send the whole target version if it's too large to hold in memory.
+	maxDeltaSize := s.MaxDeltaSize
+	if maxDeltaSize <= 0 {
+		maxDeltaSize = DefaultMaxDeltaSize
+	}
+
+	if aws.Int64Value(target.ContentLength) > maxDeltaSize {
+		return s.sendFullDelta(stream, target.Body, aws.Int64Value(target.ContentLength))
+	}
+
+	targetBytes, err := readObjectVersion(target.Body, bucket, key)
+	if err != nil {
+		return err
+	}
+
+	signature, err := s.deltaSignature(ctx, bucket, key, req.GetBaseVersionId(), blockSize)
+	if err != nil {
+		return err
+	}
+
+	// Send the whole target version if it barely matches the base version.
+	ops, literal := computeDelta(signature, targetBytes, blockSize)
+	if float64(literal) > deltaMaxLiteralRatio*float64(len(targetBytes)) {
+		return s.sendFullDelta(stream, bytes.NewReader(targetBytes), int64(len(targetBytes)))
+	}
+
+	return s.sendDelta(stream, ops, targetBytes)
+}
+
+// prepareDeltaStream decorates stream like the stream of a download, counting the bytes sent on it
+// against the egress quota and pacing them to the per-stream rate and to qos, if limited.
+func (s Service) prepareDeltaStream(
+	stream pb.Download_DownloadDeltaServer,
+	qos *qosShare,
+) pb.Download_DownloadDeltaServer {
+	if s.EgressQuota != nil {
+		stream = egressDeltaStream{Download_DownloadDeltaServer: stream, quota: s.EgressQuota}
+	}
+
+	if rate := s.PerStreamMaxBytesPerSec; rate > 0 {
+		stream = pacedDeltaStream{
+			Download_DownloadDeltaServer: stream,
+			bucket:                       newTokenBucket(func() int64 { return rate }),
+		}
+	}
+
+	if qos != nil {
+		stream = pacedDeltaStream{Download_DownloadDeltaServer: stream, bucket: newTokenBucket(qos.bytesPerSec)}
+	}
+
+	return stream
+}
+
+// egressDeltaStream is a pb.Download_DownloadDeltaServer that counts the literal bytes sent on it
+// against an EgressQuota.
+type egressDeltaStream struct {
+	pb.Download_DownloadDeltaServer
+	quota *EgressQuota
+}
+
+// Send sends chunk on the underlying stream and counts its literal bytes against the quota.
+func (s egressDeltaStream) Send(chunk *pb.DeltaChunk) error {
+	if err := s.Download_DownloadDeltaServer.Send(chunk); err != nil {
+		return err
+	}
+
+	s.quota.add(int64(len(chunk.GetData())))
+
+	return nil
+}
+
+// pacedDeltaStream is a pb.Download_DownloadDeltaServer that paces the literal bytes sent on it
+// with a tokenBucket.
+type pacedDeltaStream struct {
+	pb.Download_DownloadDeltaServer
+	bucket *tokenBucket
+}
+
+// Send sends chunk on the underlying stream once the bucket has the tokens for its literal bytes,
+// or returns the stream context's error if it's done first.
+func (s pacedDeltaStream) Send(chunk *pb.DeltaChunk) error {
+	if err := s.bucket.wait(s.Context(), len(chunk.GetData())); err != nil {
+		return err
+	}
+
+	return s.Download_DownloadDeltaServer.Send(chunk)
+}
+
+// getObjectVersion returns the GetObject output of versionID of bucket/key, of its latest version
+// if versionID is empty.
+func (s Service) getObjectVersion(
+	ctx context.Context,
+	bucket string,
+	key string,
+	versionID string,
+) (*s3.GetObjectOutput, error) {
+	input := &s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)}
+	if versionID != "" {
+		input.VersionId = aws.String(versionID)
+	}
+
+	output, err := s.s3ClientFor(ctx, bucket).GetObjectWithContext(ctx, input)
+	if err != nil {
+		return nil, s3ErrorToStatus(
+			fmt.Errorf("failed to download version %q of object %s/%s: %w", versionID, bucket, key, err),
+		)
+	}
+
+	return output, nil
+}
+
+// readObjectVersion reads the whole body of a version of bucket/key.
+func readObjectVersion(body io.Reader, bucket string, key string) ([]byte, error) {
+	var buffer bytes.Buffer
+	if _, err := buffer.ReadFrom(body); err != nil {
+		return nil, s3ErrorToStatus(fmt.Errorf("failed to download object %s/%s: %w", bucket, key, err))
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// deltaSignature returns the signature of the blocks of blockSize bytes of versionID of bucket/key.
+func (s Service) deltaSignature(
+	ctx context.Context,
+	bucket string,
+	key string,
+	versionID string,
+	blockSize int64,
+) (deltaSignature, error) {
+	base, err := s.getObjectVersion(ctx, bucket, key, versionID)
+	if err != nil {
+		return nil, err
+	}
+	defer base.Body.Close()
+
+	signature, err := newDeltaSignature(base.Body, blockSize)
+	if err != nil {
+		return nil, s3ErrorToStatus(
+			fmt.Errorf("failed to download version %q of object %s/%s: %w", versionID, bucket, key, err),
+		)
+	}
+
+	return signature, nil
+}
+
+// sendDelta sends ops on stream, the literal bytes from target in chunks of up to s.MaxBufferSize.
+func (s Service) sendDelta(stream pb.Download_DownloadDeltaServer, ops []deltaOp, target []byte) error {
+	chunkSize := s.bufferSize(int64(len(target)))
+	for _, op := range ops {
+		if op.copy {
+			copyOp := &pb.DeltaCopy{Offset: op.offset, Length: op.length}
+			if err := stream.Send(&pb.DeltaChunk{Op: &pb.DeltaChunk_Copy{Copy: copyOp}}); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		for start := op.offset; start < op.offset+op.length; start += chunkSize {
+			end := start + chunkSize
+			if end > op.offset+op.length {
+				end = op.offset + op.length
+			}
+
+			if err := stream.Send(&pb.DeltaChunk{Op: &pb.DeltaChunk_Data{Data: target[start:end]}}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// sendFullDelta sends the whole target version of length bytes read from target on stream,
+// in chunks of up to s.MaxBufferSize, setting the DeltaFullHeader header.
+func (s Service) sendFullDelta(stream pb.Download_DownloadDeltaServer, target io.Reader, length int64) error {
+	if err := stream.SetHeader(metadata.Pairs(DeltaFullHeader, "true")); err != nil {
+		return err
+	}
+
+	if length == 0 {
+		return nil
+	}
+
+	chunk := make([]byte, s.bufferSize(length))
+	for {
+		n, err := io.ReadFull(target, chunk)
+		if n > 0 {
+			if sendErr := stream.Send(&pb.DeltaChunk{Op: &pb.DeltaChunk_Data{Data: chunk[:n]}}); sendErr != nil {
+				return sendErr
+			}
+		}
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+
+		if err != nil {
+			return s3ErrorToStatus(fmt.Errorf("failed to download delta: %w", err))
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// versionedS3Client returns an S3 client serving the versions of a single object by their version ids,
+// the latest version for requests without a version id.
+func versionedS3Client(versions map[string][]byte, latest string) *s3.S3 {
+	client := s3.New(session.Must(session.NewSession(&s3Client.Config)))
+	client.Handlers.Send.Clear()
+	client.Handlers.Send.PushBack(func(r *request.Request) {
+		input := r.Params.(*s3.GetObjectInput)
+		versionID := aws.StringValue(input.VersionId)
+		if versionID == "" {
+			versionID = latest
+		}
+
+		version, ok := versions[versionID]
+		if !ok {
+			r.HTTPResponse = &http.Response{StatusCode: http.StatusNotFound, Header: http.Header{}, Body: http.NoBody}
+			r.Error = awserr.New("NoSuchVersion", "the specified version does not exist", nil)
+			return
+		}
+
+		header := http.Header{}
+		header.Set("Content-Length", strconv.Itoa(len(version)))
+		r.HTTPResponse = &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     header,
+			Body:       ioutil.NopCloser(bytes.NewReader(version)),
+		}
+	})
+
+	return client
+}
+
+// recvDelta receives the whole delta stream and reconstructs the target version from base,
+// returning it with the number of literal bytes received.
+func recvDelta(stream pb.Download_DownloadDeltaClient, base []byte) ([]byte, int, error) {
+	var target []byte
+	literal := 0
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			return target, literal, nil
+		}
+
+		if err != nil {
+			return target, literal, err
+		}
+
+		if copyOp := chunk.GetCopy(); copyOp != nil {
+			target = append(target, base[copyOp.GetOffset():copyOp.GetOffset()+copyOp.GetLength()]...)
+			continue
+		}
+
+		target = append(target, chunk.GetData()...)
+		literal += len(chunk.GetData())
+	}
+}
+
+func TestDownloadService_DownloadDelta(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	base := make([]byte, 1<<20)
+	random.Read(base)
+
+	// The target version edits, inserts and removes bytes in the base version and appends to it.
+	edited := append([]byte(nil), base[:100000]...)
+	edited = append(edited, []byte("edited bytes")...)
+	edited = append(edited, base[100012:500000]...)
+	edited = append(edited, []byte("inserted bytes")...)
+	edited = append(edited, base[500000:800000]...)
+	edited = append(edited, base[810000:]...)
+	edited = append(edited, []byte("appended bytes")...)
+
+	unrelated := make([]byte, 1<<20)
+	random.Read(unrelated)
+
+	versions := map[string][]byte{"base": base, "edited": edited, "unrelated": unrelated}
+
+	tests := []struct {
+		name         string
+		req          *pb.DownloadDeltaRequest
+		maxDeltaSize int64
+		want         []byte
+		wantFull     bool
+		wantLiteral  int
+		wantCode     codes.Code
+	}{
+		{
+			name:        "delta - edited version",
+			req:         &pb.DownloadDeltaRequest{BaseVersionId: "base", TargetVersionId: "edited"},
+			want:        edited,
+			wantLiteral: 4 * download.DefaultDeltaBlockSize,
+		},
+		{
+			name:        "delta - latest version",
+			req:         &pb.DownloadDeltaRequest{BaseVersionId: "base", BlockSize: 1024},
+			want:        edited,
+			wantLiteral: 4 * 1024,
+		},
+		{
+			name:        "delta - same version",
+			req:         &pb.DownloadDeltaRequest{BaseVersionId: "base", TargetVersionId: "base"},
+			want:        base,
+			wantLiteral: 0,
+		},
+		{
+			name:        "delta - unrelated version",
+			req:         &pb.DownloadDeltaRequest{BaseVersionId: "base", TargetVersionId: "unrelated"},
+			want:        unrelated,
+			wantFull:    true,
+			wantLiteral: len(unrelated),
+		},
+		{
+			name:         "delta - target too large",
+			req:          &pb.DownloadDeltaRequest{BaseVersionId: "base", TargetVersionId: "edited"},
+			maxDeltaSize: 64 << 10,
+			want:         edited,
+			wantFull:     true,
+			wantLiteral:  len(edited),
+		},
+		{
+			name:     "delta - base version missing",
+			req:      &pb.DownloadDeltaRequest{TargetVersionId: "edited"},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "delta - block size too small",
+			req:      &pb.DownloadDeltaRequest{BaseVersionId: "base", BlockSize: 8},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "delta - base version not found",
+			req:      &pb.DownloadDeltaRequest{BaseVersionId: "deleted", TargetVersionId: "edited"},
+			wantCode: codes.NotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			service := download.NewService(versionedS3Client(versions, "edited"), logger)
+			service.MaxDeltaSize = tt.maxDeltaSize
+			client, closeClient := newServiceClient(t, service)
+			defer closeClient()
+
+			tt.req.Key = testkey
+			tt.req.Bucket = testbucket
+			stream, err := client.DownloadDelta(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("DownloadService.DownloadDelta() error = %v", err)
+			}
+
+			got, literal, err := recvDelta(stream, base)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("DownloadService.DownloadDelta() error = %v, want code %v", err, tt.wantCode)
+			}
+
+			if tt.wantCode != codes.OK {
+				return
+			}
+
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("DownloadService.DownloadDelta() reconstructed version is different from the target version")
+			}
+
+			// Every edit sends at most the bytes of a block around it.
+			if literal > tt.wantLiteral {
+				t.Errorf("DownloadService.DownloadDelta() sent %d literal bytes, want at most %d", literal, tt.wantLiteral)
+			}
+
+			header, err := stream.Header()
+			if err != nil {
+				t.Fatalf("DownloadService.DownloadDelta() header error = %v", err)
+			}
+
+			if full := len(header.Get(download.DeltaFullHeader)) > 0; full != tt.wantFull {
+				t.Errorf("DownloadService.DownloadDelta() full = %v, want %v", full, tt.wantFull)
+			}
+		})
+	}
+}
+
+func TestDownloadService_DownloadDeltaEgressQuota(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	base := make([]byte, 64<<10)
+	random.Read(base)
+	unrelated := make([]byte, 64<<10)
+	random.Read(unrelated)
+
+	versions := map[string][]byte{"base": base, "unrelated": unrelated}
+	service := download.NewService(versionedS3Client(versions, "unrelated"), logger)
+	service.EgressQuota = download.NewEgressQuota(int64(len(unrelated)), time.Hour)
+	client, closeClient := newServiceClient(t, service)
+	defer closeClient()
+
+	req := &pb.DownloadDeltaRequest{Key: testkey, Bucket: testbucket, BaseVersionId: "base"}
+
+	// The literal bytes of the delta are counted against the quota, which the second delta exceeds.
+	for i, wantCode := range []codes.Code{codes.OK, codes.ResourceExhausted} {
+		stream, err := client.DownloadDelta(context.Background(), req)
+		if err != nil {
+			t.Fatalf("DownloadService.DownloadDelta() error = %v", err)
+		}
+
+		if _, _, err := recvDelta(stream, base); status.Code(err) != wantCode {
+			t.Fatalf("DownloadService.DownloadDelta() #%d error = %v, want code %v", i, err, wantCode)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package download
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// digestDownloadStream is a pb.Download_DownloadServer that computes the checksum of the file bytes
+// sent on it by the algorithm the client requested, for the client to verify the bytes it received.
+type digestDownloadStream struct {
+	pb.Download_DownloadServer
+	hash hash.Hash
+}
+
+// newDigestDownloadStream returns a digestDownloadStream of stream computing the checksum of algorithm.
+// It returns an InvalidArgument error if algorithm isn't supported.
+func newDigestDownloadStream(
+	stream pb.Download_DownloadServer,
+	algorithm pb.ChecksumAlgorithm,
+) (*digestDownloadStream, error) {
+	var h hash.Hash
+	switch algorithm {
+	case pb.ChecksumAlgorithm_MD5:
+		h = md5.New()
+	case pb.ChecksumAlgorithm_SHA1:
+		h = sha1.New()
+	case pb.ChecksumAlgorithm_SHA256:
+		h = sha256.New()
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unsupported checksum algorithm %v", algorithm)
+	}
+
+	return &digestDownloadStream{Download_DownloadServer: stream, hash: h}, nil
+}
+
+// Send adds the file bytes of res to the checksum and sends it on the underlying stream.
+func (s *digestDownloadStream) Send(res *pb.DownloadResponse) error {
+	s.hash.Write(res.GetFile())
+
+	return s.Download_DownloadServer.Send(res)
+}
+
+// sendDigest sends the hex checksum of the file bytes sent on d as the last message of its stream,
+// if it was requested.
+func (d *partDownload) sendDigest() error {
+	if d.digest == nil {
+		return nil
+	}
+
+	return d.stream.Send(&pb.DownloadResponse{
+		Payload: &pb.DownloadResponse_Checksum{Checksum: hex.EncodeToString(d.digest.hash.Sum(nil))},
+	})
+}
+
+// prepareDigest decorates the stream of d with the computation of the checksum of the algorithm
+// of req, if it's set. It returns an InvalidArgument error for reversed downloads, whose bytes
+// aren't sent in order.
+func (s Service) prepareDigest(req *pb.DownloadRequest, d *partDownload) error {
+	algorithm := req.GetChecksumAlgorithm()
+	if algorithm == pb.ChecksumAlgorithm_NONE {
+		return nil
+	}
+
+	if d.reverse {
+		return status.Error(codes.InvalidArgument, "checksum_algorithm can't be combined with reverse")
+	}
+
+	digest, err := newDigestDownloadStream(d.stream, algorithm)
+	if err != nil {
+		return err
+	}
+
+	d.digest = digest
+	d.stream = digest
+
+	return nil
+}
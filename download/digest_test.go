@@ -0,0 +1,131 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"testing"
+
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// recvDigest receives the file bytes of stream and the checksums sent after them. It returns an error
+// if any message was sent after a checksum.
+func recvDigest(stream pb.Download_DownloadClient) ([]byte, []string, error) {
+	var fileFromStream []byte
+	var checksums []string
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			return fileFromStream, checksums, nil
+		}
+
+		if err != nil {
+			return fileFromStream, checksums, err
+		}
+
+		if len(checksums) > 0 {
+			return fileFromStream, checksums, fmt.Errorf("%v sent after the checksum", res)
+		}
+
+		if checksum, ok := res.GetPayload().(*pb.DownloadResponse_Checksum); ok {
+			checksums = append(checksums, checksum.Checksum)
+		}
+
+		fileFromStream = append(fileFromStream, res.GetFile()...)
+	}
+}
+
+func TestDownloadService_DownloadDigest(t *testing.T) {
+	tests := []struct {
+		name       string
+		algorithm  pb.ChecksumAlgorithm
+		newHash    func() hash.Hash
+		rangeStart int64
+		rangeEnd   int64
+		reverse    bool
+		wantCode   codes.Code
+	}{
+		{name: "digest - none", algorithm: pb.ChecksumAlgorithm_NONE},
+		{name: "digest - md5", algorithm: pb.ChecksumAlgorithm_MD5, newHash: md5.New},
+		{name: "digest - sha1", algorithm: pb.ChecksumAlgorithm_SHA1, newHash: sha1.New},
+		{name: "digest - sha256", algorithm: pb.ChecksumAlgorithm_SHA256, newHash: sha256.New},
+		{
+			name:       "digest - sha256 of a range",
+			algorithm:  pb.ChecksumAlgorithm_SHA256,
+			newHash:    sha256.New,
+			rangeStart: 1000,
+			rangeEnd:   1<<20 + 999,
+		},
+		{
+			name:      "digest - reversed",
+			algorithm: pb.ChecksumAlgorithm_SHA256,
+			reverse:   true,
+			wantCode:  codes.InvalidArgument,
+		},
+		{name: "digest - unknown algorithm", algorithm: pb.ChecksumAlgorithm(42), wantCode: codes.InvalidArgument},
+	}
+
+	service := download.NewService(s3Client, logger)
+	service.MaxBufferSize = 256 << 10
+	client, closeClient := newServiceClient(t, service)
+	defer closeClient()
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := client.Download(context.Background(), &pb.DownloadRequest{
+				Key:               testkey,
+				Bucket:            testbucket,
+				RangeStart:        tt.rangeStart,
+				RangeEnd:          tt.rangeEnd,
+				Reverse:           tt.reverse,
+				ChecksumAlgorithm: tt.algorithm,
+			})
+			if err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			got, checksums, err := recvDigest(stream)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("DownloadService.Download() error = %v, want code %v", err, tt.wantCode)
+			}
+
+			if tt.wantCode != codes.OK {
+				return
+			}
+
+			want := file
+			if tt.rangeEnd != 0 {
+				want = file[tt.rangeStart : tt.rangeEnd+1]
+			}
+
+			if !bytes.Equal(got, want) {
+				t.Errorf("DownloadService.Download() file downloaded is different from the wanted file")
+			}
+
+			if tt.newHash == nil {
+				if len(checksums) != 0 {
+					t.Errorf("DownloadService.Download() checksums = %v, want none", checksums)
+				}
+
+				return
+			}
+
+			h := tt.newHash()
+			h.Write(want)
+			if wantChecksum := hex.EncodeToString(h.Sum(nil)); len(checksums) != 1 || checksums[0] != wantChecksum {
+				t.Errorf("DownloadService.Download() checksums = %v, want [%s]", checksums, wantChecksum)
+			}
+		})
+	}
+}
@@ -1,65 +1,116 @@
 package download
 
 import (
+	"context"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"strconv"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/logrus/ctxlogrus"
 	pb "github.com/meateam/download-service/proto"
-	ilogger "github.com/meateam/elasticsearch-logger"
+	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 const (
 	// PartSize is the number of bytes that a object part has, currently 5MB per part.
 	PartSize = 5 << 20
+
+	// MinPartSize is the minimum part size of a download, of the service or of a request's chunk size.
+	MinPartSize = 256 << 10
+
+	// MaxPartSize is the maximum part size of a download, of the service or of a request's chunk size.
+	MaxPartSize = 64 << 20
+
+	// BytesSentTrailer is the trailer holding the number of bytes sent by a download.
+	BytesSentTrailer = "x-bytes-sent"
+
+	// PartsSentTrailer is the trailer holding the number of parts fully sent by a download.
+	PartsSentTrailer = "x-parts-sent"
+
+	// PartSizeHeader is the response header of reversed downloads holding the size of their parts.
+	PartSizeHeader = "x-part-size"
+
+	// deadlineCancelSlack is how long before the deadline of a call its cancellation
+	// is considered the client's deadline passing.
+	deadlineCancelSlack = 100 * time.Millisecond
+
+	// EndReasonCanceled is the "download.end_reason" log field of downloads cancelled by the client.
+	EndReasonCanceled = "client_canceled"
+
+	// EndReasonDeadlineExceeded is the "download.end_reason" log field of downloads that exceeded their deadline.
+	EndReasonDeadlineExceeded = "deadline_exceeded"
 )
 
 // ErrBufferLength is the error returned by StreamReadCloser.Read when len(p) <= PartSize.
+//
+// Deprecated: StreamReadCloser.Read reads into buffers of any length.
 var ErrBufferLength error = fmt.Errorf("len(p) is required to be at least %d", PartSize)
 
 // StreamReadCloser is a structure that implements io.Reader to read a object's bytes from stream.
 type StreamReadCloser struct {
 	stream pb.Download_DownloadClient
+
+	// pending are the bytes of the last chunk received that weren't read yet, shared by the copies of the reader.
+	pending *[]byte
 }
 
 // NewStreamReadCloser returns a StreamReadCloser initialized with stream to read the object's bytes from.
 func NewStreamReadCloser(stream pb.Download_DownloadClient) StreamReadCloser {
-	return StreamReadCloser{stream: stream}
+	return StreamReadCloser{stream: stream, pending: new([]byte)}
+}
+
+// NewStreamReadCloserWithPartSize returns a StreamReadCloser initialized with stream to read the object's
+// bytes from, whose chunks are at most partSize bytes, e.g. the chunk size of its request.
+//
+// Deprecated: StreamReadCloser reads chunks of any size into buffers of any length, use NewStreamReadCloser.
+func NewStreamReadCloserWithPartSize(stream pb.Download_DownloadClient, partSize int64) StreamReadCloser {
+	return NewStreamReadCloser(stream)
 }
 
-// Read implements io.Reader to read object's bytes into p,
-// len(p) MUST be >= PartSize, otherwise Read wouldn't read the chunk into p,
-// Read doesn't call r.stream.Recv() unless len(p) >= PartSize.
-// If Read would've read the chunk into p where len(p) < PartSize,
-// it would read incomplete object bytes into p and the reader would
-// miss bytes from the object stream.
+// Read implements io.Reader to read object's bytes into p, of any length.
+// The bytes of a chunk that don't fit p are kept and read by the next calls,
+// before r.stream.Recv() is called for the next chunk.
 // Implementation does not retain p.
 func (r StreamReadCloser) Read(p []byte) (n int, err error) {
-	// Cannot read the whole bytes of a chunk's maximum number of bytes.
-	// Do not call r.steam.Recv unless the whole chunk can be read into p,
-	// otherwise the reader would miss bytes of the stream chunks.
-	if int64(len(p)) < PartSize {
-		return 0, fmt.Errorf("len(p) is required to be at least %d", PartSize)
+	if len(p) == 0 {
+		return 0, nil
 	}
 
-	chunk, err := r.stream.Recv()
-
-	// Return even if err == io.EOF
-	if err != nil {
-		return 0, err
+	// Receive the next chunk only once the previous one was read whole.
+	if len(*r.pending) == 0 {
+		if *r.pending, err = r.recvFile(); err != nil {
+			return 0, err
+		}
 	}
 
-	part := chunk.GetFile()
-	read := 0
+	n = copy(p, *r.pending)
+	*r.pending = (*r.pending)[n:]
 
-	// len(p) is big enough to safely use it without losing bytes.
-	if len(p) >= len(part) {
-		read = copy(p, part)
-	}
+	return n, nil
+}
+
+// recvFile receives the next chunk of the object's bytes from r.stream, skipping the progress messages
+// interleaved between them and empty chunks.
+func (r StreamReadCloser) recvFile() ([]byte, error) {
+	for {
+		chunk, err := r.stream.Recv()
 
-	return read, nil
+		// Return even if err == io.EOF
+		if err != nil {
+			return nil, err
+		}
+
+		if part := chunk.GetFile(); len(part) > 0 {
+			return part, nil
+		}
+	}
 }
 
 // Close closes the send direction of the underlying r.stream.
@@ -71,11 +122,270 @@ func (r StreamReadCloser) Close() error {
 type Service struct {
 	s3Client *s3.S3
 	logger   *logrus.Logger
+
+	// RequestLimits bound the size of the requests, defaults to DefaultRequestLimits.
+	RequestLimits RequestLimits
+
+	// Authorizer is called for every valid request before the object is fetched,
+	// defaults to AllowAll.
+	Authorizer Authorizer
+
+	// BucketRouter resolves the bucket of requests that omit it, defaults to IdentityBucketRouter.
+	BucketRouter BucketRouter
+
+	// KeyTemplate maps the keys of requests to the keys of the objects in S3, after their bucket
+	// is resolved, nil uses the keys as is.
+	KeyTemplate *KeyTemplate
+
+	// MaxBufferSize is the maximum number of bytes buffered by a single download,
+	// parts larger than it are sent in chunks of up to MaxBufferSize bytes.
+	// Zero or a value larger than the part size buffers whole parts.
+	MaxBufferSize int64
+
+	// PartSize is the size of the parts objects are downloaded from S3 in, unless requests set their
+	// chunk size, between MinPartSize and MaxPartSize. Zero defaults to PartSize.
+	PartSize int64
+
+	// AlignToNativeParts downloads multipart objects in ranges aligned to their native parts,
+	// instead of PartSize parts, at the cost of another HeadObject call per download.
+	AlignToNativeParts bool
+
+	// RangeFallbackThreshold is the number of failed ranged GetObject calls of a download after which
+	// the rest of the object is downloaded by a single non-ranged call, zero fails on the first failure.
+	RangeFallbackThreshold int
+
+	// HeadDeniedFallback learns the size and attributes of objects by a ranged GetObject of their
+	// first byte when HeadObject is denied, for stores that allow GetObject but deny HeadObject.
+	HeadDeniedFallback bool
+
+	// TrustClientSize skips the HeadObject call of downloads whose requests set the size of the object,
+	// and validates the size against the first part downloaded instead. The parts of such downloads
+	// aren't prefetched.
+	TrustClientSize bool
+
+	// SpillDir is the directory that the parts of downloads are prefetched into, ahead of
+	// the part being sent, to prefetch without holding the parts in memory.
+	// Empty disables prefetching, reversed downloads are never prefetched.
+	SpillDir string
+
+	// SpillMaxSize is the maximum number of bytes a single download spills to SpillDir at once,
+	// at least a single part is spilled regardless.
+	SpillMaxSize int64
+
+	// FetchBufferDepth is the number of chunks a download fetches from S3 into memory ahead of
+	// the chunk being sent, decoupling the rate of fetching from the rate of sending.
+	// Zero fetches and sends in lockstep. Downloads prefetched to SpillDir or fetching parts
+	// concurrently, and reversed downloads, are never buffered.
+	FetchBufferDepth int
+
+	// PartConcurrency is the number of parts a download fetches from S3 into memory at once, ahead of
+	// the part being sent. Zero and one fetch the parts one at a time. Downloads prefetched to SpillDir
+	// and reversed downloads never fetch parts concurrently.
+	PartConcurrency int
+
+	// ConcurrencyMinSize is the size in bytes up to which downloads fetch their parts one at a time even if
+	// PartConcurrency is above one, since small downloads gain only the overhead of fetching them concurrently.
+	// Zero fetches downloads of every size concurrently.
+	ConcurrencyMinSize int64
+
+	// CoalesceMaxSize is the maximal number of bytes of a single GetObject call that adjacent parts fetched
+	// concurrently are coalesced into, and then split back into the parts, to reduce the calls to S3.
+	// Every call in flight buffers up to CoalesceMaxSize bytes. Zero fetches every part by its own call.
+	CoalesceMaxSize int64
+
+	// CoalesceGap is the maximal number of bytes between ranges coalesced into a single GetObject call,
+	// which are fetched and dropped. Zero coalesces only contiguous ranges.
+	CoalesceGap int64
+
+	// AutoTunePartConcurrency tunes the part concurrency of every download toward its maximal
+	// throughput, up to PartConcurrency, instead of always fetching PartConcurrency parts at once.
+	AutoTunePartConcurrency bool
+
+	// StrictOrderAssert fails a download with an Internal error, instead of sending
+	// a chunk that doesn't directly follow the previous one. Meant for testing.
+	StrictOrderAssert bool
+
+	// Chaos injects faults into downloads, overridable by request headers, nil disables it.
+	// It must never be enabled in production.
+	Chaos *Chaos
+
+	// SubjectLimiter limits the concurrent downloads of every authenticated subject, nil disables it.
+	SubjectLimiter *SubjectLimiter
+
+	// EgressQuota caps the file bytes the service serves per window of time, nil disables it.
+	EgressQuota *EgressQuota
+
+	// ShedHighWater is the number of active downloads at which new downloads are rejected
+	// with an Unavailable error, until they drop below ShedLowWater. Zero disables load shedding.
+	ShedHighWater int64
+
+	// ShedLowWater is the number of active downloads below which load shedding stops,
+	// zero defaults to 80% of ShedHighWater, but at least 1.
+	ShedLowWater int64
+
+	// ShedRetryAfter is the delay that shed downloads are told to retry after, defaults to DefaultShedRetryAfter.
+	ShedRetryAfter time.Duration
+
+	// PartRetries is the number of times the download of a part that failed transiently, by a server
+	// error or a failure to connect, is retried while the stream is live. Zero disables retries.
+	PartRetries int
+
+	// PartRetryBaseDelay is the delay before the first retry of a part, doubled for every further retry
+	// and jittered, defaults to DefaultPartRetryBaseDelay.
+	PartRetryBaseDelay time.Duration
+
+	// PerStreamMaxBytesPerSec is the rate that the file bytes of every download are paced to, 0 disables pacing.
+	// Requests may lower their own rate, or set it when it's 0.
+	PerStreamMaxBytesPerSec int64
+
+	// MaxDeltaSize is the size of the largest target version DownloadDelta computes a delta for,
+	// larger versions are sent whole, defaults to DefaultMaxDeltaSize.
+	MaxDeltaSize int64
+
+	// QoSBudget is the bandwidth budget shared by the downloads by their QoS classes, nil disables it.
+	QoSBudget *QoSBudget
+
+	// VerifyChecksums verifies the downloads of whole objects uploaded with a checksum against it,
+	// failing them with DataLoss after their last chunk on a mismatch.
+	VerifyChecksums bool
+
+	// RequireEncryption refuses to serve objects that aren't encrypted at rest with FailedPrecondition.
+	RequireEncryption bool
+
+	// AllowDelegatedCredentials serves requests that pass their own S3 credentials in the
+	// AccessKeyIDHeader, SecretAccessKeyHeader and SessionTokenHeader headers with them,
+	// instead of with the service's credentials.
+	AllowDelegatedCredentials bool
+
+	// FollowPollInterval is the interval at which objects downloaded with Follow are checked for
+	// appended bytes, defaults to DefaultFollowPollInterval.
+	FollowPollInterval time.Duration
+
+	// FollowIdleTimeout is the time without appended bytes after which downloads with Follow end,
+	// defaults to DefaultFollowIdleTimeout.
+	FollowIdleTimeout time.Duration
+
+	// CacheBucket is the bucket that downloaded objects are copied to asynchronously when they aren't there yet,
+	// and downloaded from once they are. The copies aren't invalidated when the source object changes.
+	// Empty disables the cache bucket.
+	CacheBucket string
+
+	// CompletionHook is notified asynchronously when a download finishes, nil disables it.
+	CompletionHook CompletionHook
+
+	// Metrics observes the parts fetched and the downloads finished, nil disables it.
+	Metrics Metrics
+
+	// DeadLetterLogger logs the downloads that failed after the retries of their parts were exhausted,
+	// the service's logger if nil.
+	DeadLetterLogger *logrus.Logger
+
+	// SequentialPrefetch prefetches the next range of clients reading ranges of an object in order,
+	// nil disables it.
+	SequentialPrefetch *SequentialPrefetcher
+
+	// AuditFeed is published the audit event of every finished download, nil disables it.
+	AuditFeed *AuditFeed
+
+	// LogBuffer is the buffer of the logs shipped asynchronously, reported by GetStats if set.
+	LogBuffer LogBuffer
+
+	// S3Probe is the health probe of S3, reported by GetStats if set.
+	S3Probe Probe
+
+	// HeadCache caches the HeadObject results of downloaded objects, nil disables caching.
+	HeadCache *HeadCache
+
+	// ServeStaleOnError serves the expired HeadObject results of HeadCache, up to its max staleness,
+	// when S3 fails with a server error or a failure to connect, with a stale CacheStatusHeader trailer.
+	// The bytes of objects aren't cached, so downloads still fail unless GetObject succeeds.
+	ServeStaleOnError bool
+
+	// Tracer traces downloads and their calls to S3, nil disables tracing.
+	Tracer opentracing.Tracer
+
+	// TraceExtractors extract the trace ids that logs and spans are tagged with from the requests,
+	// tried in order, nil uses DefaultTraceExtractors.
+	TraceExtractors []TraceExtractor
+
+	// KeyPrefixAllowlist are the top-level key prefixes that logs and metrics are
+	// tagged with, other prefixes are tagged as OtherKeyPrefix.
+	KeyPrefixAllowlist []string
+
+	// FailoverBuckets maps buckets to their replicas in secondary regions, tried in order by the calls
+	// of downloads whose bucket's region fails with server errors, failures to connect or timeouts.
+	// The regions of the replicas are discovered by their GetBucketLocation. Nil disables failover.
+	FailoverBuckets map[string][]string
+
+	downloadLatency  *LatencyStats
+	partLatency      *LatencyStats
+	load             *loadState
+	cacheCopies      *cacheCopies
+	delegatedClients *delegatedClients
+	regionClients    *regionClients
+	failovers        *regionCounts
 }
 
 // NewService creates a Service and returns it.
 func NewService(s3Client *s3.S3, logger *logrus.Logger) *Service {
-	return &Service{s3Client: s3Client, logger: logger}
+	return &Service{
+		s3Client:         s3Client,
+		logger:           logger,
+		RequestLimits:    DefaultRequestLimits,
+		Authorizer:       AllowAll,
+		BucketRouter:     IdentityBucketRouter,
+		downloadLatency:  NewLatencyStats(),
+		partLatency:      NewLatencyStats(),
+		load:             &loadState{},
+		cacheCopies:      &cacheCopies{inflight: make(map[headCacheKey]struct{})},
+		delegatedClients: newDelegatedClients(),
+		regionClients:    newRegionClients(),
+		failovers:        &regionCounts{},
+	}
+}
+
+// defaultPartSize returns the part size of the downloads whose requests don't set their chunk size,
+// s.PartSize if set, otherwise PartSize.
+func (s Service) defaultPartSize() int64 {
+	if s.PartSize > 0 {
+		return s.PartSize
+	}
+
+	return PartSize
+}
+
+// partSize returns the part size of a download whose request's chunk size is chunkSize,
+// the default part size if it's zero. It returns an InvalidArgument error if chunkSize is
+// out of the bounds of MinPartSize and MaxPartSize.
+func (s Service) partSize(chunkSize int64) (int64, error) {
+	if chunkSize == 0 {
+		return s.defaultPartSize(), nil
+	}
+
+	if chunkSize < MinPartSize || chunkSize > MaxPartSize {
+		return 0, status.Errorf(
+			codes.InvalidArgument,
+			"chunk size must be between %d and %d, got %d",
+			MinPartSize, MaxPartSize, chunkSize,
+		)
+	}
+
+	return chunkSize, nil
+}
+
+// bufferSize returns the size of the buffer for downloading rangeLength bytes,
+// which is the smallest of the default part size, s.MaxBufferSize and rangeLength.
+func (s Service) bufferSize(rangeLength int64) int64 {
+	size := s.defaultPartSize()
+	if s.MaxBufferSize > 0 && s.MaxBufferSize < size {
+		size = s.MaxBufferSize
+	}
+
+	if rangeLength < size {
+		size = rangeLength
+	}
+
+	return size
 }
 
 // GetS3Client returns the internal s3 client.
@@ -83,76 +393,838 @@ func (s Service) GetS3Client() *s3.S3 {
 	return s.s3Client
 }
 
+// s3Endpoint returns the endpoint of the S3 client of s, empty for the AWS endpoints.
+func (s Service) s3Endpoint() string {
+	if s.s3Client == nil {
+		return ""
+	}
+
+	return aws.StringValue(s.s3Client.Config.Endpoint)
+}
+
+// resolveObject resolves the bucket and key of the object a request refers to, by its bucket and key,
+// or by its objectURL for the fields that are missing, or by s.BucketRouter for a missing bucket.
+// The key is mapped to the object's key in S3 by s.KeyTemplate.
+func (s Service) resolveObject(bucket string, key string, objectURL string) (string, string, error) {
+	// Fill the fields missing from the request using the object's URL.
+	if objectURL != "" {
+		urlBucket, urlKey, err := s.parseObjectURL(objectURL)
+		if err != nil {
+			return "", "", status.Error(codes.InvalidArgument, err.Error())
+		}
+
+		if key == "" {
+			key = urlKey
+		}
+
+		if bucket == "" {
+			bucket = urlBucket
+		}
+	}
+
+	if key == "" {
+		return "", "", fmt.Errorf("key is required")
+	}
+
+	// Resolve the physical bucket of the key when the request doesn't name one.
+	if bucket == "" && s.BucketRouter != nil {
+		bucket = s.BucketRouter(key)
+	}
+
+	if bucket == "" {
+		return "", "", fmt.Errorf("bucket is required")
+	}
+
+	return bucket, s.KeyTemplate.Render(key), nil
+}
+
+// partDownload is the state of streaming the parts of a range of an object to a client.
+type partDownload struct {
+	stream      pb.Download_DownloadServer
+	bucket      string
+	key         string
+	keyPrefix   string
+	reverse     bool
+	offset      int64
+	knownSize   int64
+	notModified bool
+	etag        string
+	metadata    *pb.DownloadMetadata
+	objectSize  int64
+	objectRange byteRange
+	partSize    int64
+	alignParts  bool
+	totalParts  int64
+	buffer      []byte
+	order       *orderAssertion
+	chaos       Chaos
+	spill       *spillPrefetcher
+	fetch       *fetchBuffer
+	prefetch    *concurrentPrefetcher
+	warm        *rangePrefetch
+	decompress  decompressor
+	checksum    objectChecksum
+	verifier    *checksumDownloadStream
+	digest      *digestDownloadStream
+	tail        *tailDownloadStream
+	qos         *qosShare
+	exhausted   *retryExhaustion
+	bytesSent   int64
+	partsSent   int64
+}
+
+// checkCanceled returns a Canceled error if the client cancelled d, or a DeadlineExceeded error
+// if its deadline was exceeded, and nil while it's live.
+func (d *partDownload) checkCanceled() error {
+	ctx := d.stream.Context()
+	select {
+	case <-ctx.Done():
+	default:
+		return nil
+	}
+
+	code := codes.Canceled
+	if ctx.Err() == context.DeadlineExceeded {
+		code = codes.DeadlineExceeded
+	}
+
+	return status.Errorf(
+		code,
+		"download of %s/%s stopped after %d bytes: %v",
+		d.bucket, d.key, d.bytesSent, ctx.Err(),
+	)
+}
+
+// partNumber returns the number of the i-th part to send, counting from the last part of reversed downloads.
+func (d *partDownload) partNumber(i int64) int64 {
+	if d.reverse {
+		return d.totalParts - 1 - i
+	}
+
+	return i
+}
+
+// closeSpill stops prefetching the parts of d, if they're prefetched.
+func (d *partDownload) closeSpill() {
+	if d.spill != nil {
+		d.spill.close()
+		d.spill = nil
+	}
+}
+
+// closeFetch stops fetching the parts of d ahead of sending them, if they're fetched ahead.
+func (d *partDownload) closeFetch() {
+	if d.fetch != nil {
+		d.fetch.close()
+		d.fetch = nil
+	}
+}
+
+// closePrefetch stops fetching the parts of d concurrently, if they're fetched concurrently.
+func (d *partDownload) closePrefetch() {
+	if d.prefetch != nil {
+		d.prefetch.close()
+		d.prefetch = nil
+	}
+}
+
 // Download is the request to download a object from S3.
 // It receives a request for a object.
 // Responds with a stream of the object bytes in chunks.
-func (s Service) Download(req *pb.DownloadRequest, stream pb.Download_DownloadServer) error {
+func (s Service) Download(req *pb.DownloadRequest, stream pb.Download_DownloadServer) (err error) {
+	startTime := time.Now()
+
+	// Reject oversized requests before doing any work for them.
+	if err := s.RequestLimits.check(req, []string{req.GetKey()}, req.GetUrl()); err != nil {
+		return err
+	}
+
+	// Shed the download before doing any work for it if the service is overloaded.
+	if err := s.startDownload(); err != nil {
+		return err
+	}
+	defer s.endDownload()
+
+	// Reject the download if the service served its egress quota.
+	if err := s.EgressQuota.check(); err != nil {
+		return err
+	}
+
 	// Fetch key and bucket from the request and check it's validity.
-	key := req.GetKey()
-	bucket := req.GetBucket()
-	if key == "" {
-		return fmt.Errorf("key is required")
+	bucket, key, err := s.resolveObject(req.GetBucket(), req.GetKey(), req.GetUrl())
+	if err != nil {
+		return err
 	}
 
-	if bucket == "" {
-		return fmt.Errorf("bucket is required")
+	// Tag the call's logs with the object's top-level prefix.
+	d := &partDownload{
+		stream:    stream,
+		bucket:    bucket,
+		key:       key,
+		keyPrefix: KeyPrefixLabel(key, s.KeyPrefixAllowlist),
+		reverse:   req.GetReverse(),
+		offset:    req.GetOffset(),
+		exhausted: &retryExhaustion{},
+	}
+	ctxlogrus.AddFields(stream.Context(), logrus.Fields{"key.prefix": d.keyPrefix})
+
+	// Trace the download and its calls to S3, if tracing is enabled.
+	span, ctx := s.startServerSpan(stream.Context(), bucket, key)
+	defer func() {
+		err = s.finishDownload(d, span, startTime, err)
+	}()
+
+	// Download the requested version of the object, if any, rather than its latest.
+	ctx = contextWithVersionID(ctx, req.GetVersionId())
+
+	// Fail the calls to the bucket over to its replicas in other regions if its region fails.
+	ctx = s.contextWithFailover(ctx, bucket)
+
+	// Record the parts whose retries are exhausted, to log the download's failure as permanent.
+	ctx = contextWithRetryExhaustion(ctx, d.exhausted)
+
+	// Check that the requesting subject has access to the object.
+	if err := s.authorize(stream.Context(), bucket, key); err != nil {
+		return err
+	}
+
+	// Limit the concurrent downloads of the requesting subject.
+	subject := SubjectFromContext(stream.Context())
+	if err := s.SubjectLimiter.acquire(subject); err != nil {
+		return err
+	}
+	defer s.SubjectLimiter.release(subject)
+
+	// Share the bandwidth budget with the other downloads by the request's QoS class, if limited.
+	if d.qos, err = s.QoSBudget.join(req.GetQosClass()); err != nil {
+		return err
+	}
+	defer d.qos.leave()
+
+	// Download the object from the cache bucket if it was already copied there.
+	d.bucket = s.readThroughCache(ctx, bucket, key)
+
+	// Resolve the requested range of bytes to download.
+	if d.objectRange, err = s.downloadRange(ctx, req, d); err != nil || d.notModified {
+		return err
+	}
+
+	// Split the range into the parts to download.
+	if err := s.splitParts(ctx, req, d); err != nil {
+		return err
+	}
+	defer putSendBuffer(d.buffer)
+
+	// Decorate the stream with the requested and enabled features.
+	if err := s.prepareStream(req, d); err != nil {
+		return err
+	}
+
+	if err := s.sendObject(ctx, d); err != nil {
+		return err
+	}
+
+	s.downloadLatency.ObserveTraced(time.Since(startTime), s.traceID(stream.Context()))
+
+	// Stream the bytes appended to the object, if followed.
+	if req.GetFollow() {
+		if err := s.follow(ctx, d); err != nil {
+			return err
+		}
+	}
+
+	// Send the checksum of the bytes sent after them, if requested.
+	return d.sendDigest()
+}
+
+// sendObject sends the metadata of the object of d, if requested, and then the parts of its range, or
+// the whole object decompressed if it's decompressed, and verifies that the whole range was sent and
+// the bytes sent against its checksum, if verified.
+// It returns a DataLoss error if S3 returned fewer bytes than the range's length.
+func (s Service) sendObject(ctx context.Context, d *partDownload) error {
+	// Send the object's metadata ahead of its bytes, if requested.
+	if err := d.sendMetadata(); err != nil {
+		return err
+	}
+
+	if d.decompress != nil {
+		return s.sendDecompressed(ctx, d)
+	}
+
+	// Serve the range from memory if the client read up to it sequentially, and prefetch the next one.
+	releaseSequential := s.readSequentially(ctx, d)
+	defer releaseSequential()
+
+	// Fetch the parts ahead of the part being sent, if enabled.
+	stopPrefetching := s.prefetchParts(ctx, d)
+	defer stopPrefetching()
+
+	if err := s.sendParts(ctx, d); err != nil {
+		return err
+	}
+
+	// Catch bodies S3 truncated before the client assembles a truncated object.
+	if length := d.objectRange.length(); d.bytesSent != length {
+		return status.Errorf(
+			codes.DataLoss,
+			"object %s/%s was truncated, sent %d of %d bytes",
+			d.bucket, d.key, d.bytesSent, length,
+		)
+	}
+
+	// Verify the bytes sent against the object's checksum, if verified.
+	return d.verifier.verify(d.bucket, d.key)
+}
+
+// finishDownload sets the trailers of d, which started at startTime, finishes its span, logs, observes,
+// notifies and audits its end, and returns err with the bytes sent before it, for the client to resume from.
+func (s Service) finishDownload(d *partDownload, span opentracing.Span, startTime time.Time, err error) error {
+	d.stream.SetTrailer(metadata.Pairs(
+		BytesSentTrailer, strconv.FormatInt(d.bytesSent, 10),
+		PartsSentTrailer, strconv.FormatInt(d.partsSent, 10),
+	))
+	finishSpan(span, err)
+	s.logEarlyEnd(d.stream.Context(), d.bytesSent, d.keyPrefix)
+	s.notifyCompletion(d.bucket, d.key, d.bytesSent, startTime, s.traceID(d.stream.Context()), err)
+	s.publishAudit(d, err)
+	s.observeDownload(d.keyPrefix, d.bytesSent, err)
+	s.logPermanentFailure(d, err)
+
+	if err != nil {
+		return withBytesSent(err, d.bytesSent)
 	}
 
+	return nil
+}
+
+// prefetchParts starts fetching the parts of d ahead of the part being sent, to disk if s.SpillDir is set,
+// otherwise concurrently into memory if s.PartConcurrency is above one and d is larger than
+// s.ConcurrencyMinSize, otherwise into a chunk buffer if s.FetchBufferDepth is set.
+// It returns the function that stops fetching them.
+// The parts of reversed downloads, of downloads of a size that wasn't validated and of ranges prefetched
+// by the client's sequential reads are never fetched ahead.
+func (s Service) prefetchParts(ctx context.Context, d *partDownload) func() {
+	switch {
+	case d.reverse || d.knownSize != 0 || d.warm != nil:
+		return func() {}
+	case s.SpillDir != "":
+		d.spill = s.spillParts(ctx, d.bucket, d.key, d.objectRange, d.partSize, d.alignParts, d.totalParts)
+
+		return d.closeSpill
+	case s.PartConcurrency > 1 && d.objectRange.length() > s.ConcurrencyMinSize:
+		prefetch := s.prefetchConcurrently(
+			ctx,
+			d.bucket,
+			d.key,
+			d.objectRange,
+			d.partSize,
+			d.alignParts,
+			d.totalParts,
+		)
+		d.prefetch = prefetch
+
+		return func() {
+			d.closePrefetch()
+			s.logPartConcurrency(d, prefetch)
+		}
+	case s.FetchBufferDepth > 0:
+		d.fetch = s.fetchParts(
+			ctx,
+			d.bucket,
+			d.key,
+			d.objectRange,
+			d.partSize,
+			d.alignParts,
+			d.totalParts,
+			len(d.buffer),
+			s.FetchBufferDepth,
+		)
+
+		return d.closeFetch
+	default:
+		return func() {}
+	}
+}
+
+// downloadRange returns the range of bytes of d's object that req requests, after evaluating the
+// request's conditional validators, and marks d not modified if the client's copy is up to date.
+// The whole object is downloaded instead of resuming it if it changed since the client's validator.
+func (s Service) downloadRange(ctx context.Context, req *pb.DownloadRequest, d *partDownload) (byteRange, error) {
 	// Get the object's length.
-	objectDetails, err := s.s3Client.HeadObjectWithContext(
-		stream.Context(),
-		&s3.HeadObjectInput{
-			Bucket: aws.String(bucket),
-			Key:    aws.String(key),
-		},
+	objectDetails, err := s.downloadHead(ctx, req, d)
+	if err != nil {
+		return byteRange{}, s3ErrorToStatus(fmt.Errorf("failed to download object %s/%s: %w", d.bucket, d.key, err))
+	}
+
+	if err := s.checkEncryption(d.bucket, d.key, objectDetails); err != nil {
+		return byteRange{}, err
+	}
+
+	// Tell CDNs how to cache the object, also when the client's copy is up to date.
+	if err := setCacheHeaders(d.stream, objectDetails); err != nil {
+		return byteRange{}, err
+	}
+
+	// Send nothing if the client's copy of the object is up to date.
+	d.notModified, err = evaluateConditions(
+		req,
+		aws.StringValue(objectDetails.ETag),
+		aws.TimeValue(objectDetails.LastModified),
 	)
 	if err != nil {
-		return fmt.Errorf("failed to download object %s/%s: %v", bucket, key, err)
+		return byteRange{}, err
 	}
 
-	// Calculate how many parts there are to download.
-	totalParts := *objectDetails.ContentLength / PartSize
-	if *objectDetails.ContentLength%PartSize > 0 {
-		totalParts++
+	if d.notModified {
+		return byteRange{}, d.stream.SetHeader(metadata.Pairs(NotModifiedHeader, "true"))
 	}
 
-	// Iterate over all of the parts, download each part and stream it to the client.
-	for currentPart := int64(0); currentPart < totalParts; currentPart++ {
-		// Calculate current part bytes range to download.
-		rangeStart := currentPart * PartSize
-		rangeEnd := rangeStart + PartSize - 1
-		if rangeEnd > *objectDetails.ContentLength {
-			rangeEnd = *objectDetails.ContentLength - 1
+	rangeStart, rangeEnd, rangePercent := req.GetRangeStart(), req.GetRangeEnd(), req.GetRangePercent()
+	if ifRange := req.GetIfRange(); ifRange != "" && !ifRangeMatches(
+		ifRange,
+		aws.StringValue(objectDetails.ETag),
+		aws.TimeValue(objectDetails.LastModified),
+	) {
+		if req.GetIfRangeFail() {
+			return byteRange{}, status.Errorf(
+				codes.FailedPrecondition,
+				"object %s/%s changed since %s",
+				d.bucket, d.key, ifRange,
+			)
 		}
 
-		getObjectInput := &s3.GetObjectInput{
-			Key:        aws.String(key),
-			Bucket:     aws.String(bucket),
-			PartNumber: aws.Int64(currentPart),
-			Range:      aws.String(fmt.Sprintf("bytes=%d-%d", rangeStart, rangeEnd)),
+		if err := d.stream.SetHeader(metadata.Pairs(RestartedHeader, "true")); err != nil {
+			return byteRange{}, err
 		}
 
-		objectPartOutput, err := s.s3Client.GetObjectWithContext(stream.Context(), getObjectInput)
+		rangeStart, rangeEnd, rangePercent = 0, 0, nil
+	}
+
+	objectRange, err := resolveRequestRange(rangeStart, rangeEnd, rangePercent, *objectDetails.ContentLength)
+	if err != nil {
+		return byteRange{}, err
+	}
 
+	// Resume the download from the offset the client has the bytes up to, if it's resumed.
+	if d.offset != 0 && d.reverse {
+		return byteRange{}, status.Error(codes.InvalidArgument, "offset can't be combined with reverse")
+	}
+
+	if objectRange, err = resumeRange(objectRange, d.offset, *objectDetails.ContentLength); err != nil {
+		return byteRange{}, err
+	}
+
+	d.etag = aws.StringValue(objectDetails.ETag)
+	d.objectSize = *objectDetails.ContentLength
+
+	if req.GetIncludeMetadata() {
+		d.metadata = objectMetadata(objectDetails)
+	}
+
+	d.checksum = s.checksumToVerify(objectDetails, objectRange, d.reverse)
+
+	return objectRange, nil
+}
+
+// splitParts splits the range of d into the parts to download, and gets the buffer they're sent from,
+// which must be returned by putSendBuffer once the download ended.
+// Objects are split into their native parts if they were uploaded as multipart, otherwise in parts of
+// req's chunk size or the default part size. Reversed downloads are always split into such parts from
+// the range's start, and resumed downloads into parts aligned in the object rather than from the range's start.
+func (s Service) splitParts(ctx context.Context, req *pb.DownloadRequest, d *partDownload) (err error) {
+	if d.partSize, err = s.partSize(req.GetChunkSize()); err != nil {
+		return err
+	}
+
+	if s.AlignToNativeParts && !d.reverse {
+		nativePartSize, err := s.nativePartSize(ctx, d.bucket, d.key)
 		if err != nil {
-			return fmt.Errorf("failed to download object %s/%s: %v", bucket, key, err)
+			return s3ErrorToStatus(fmt.Errorf("failed to download object %s/%s: %w", d.bucket, d.key, err))
+		}
+
+		if nativePartSize > 0 {
+			d.partSize = nativePartSize
+			d.alignParts = true
 		}
+	}
+
+	// Resumed downloads start from the part of their offset in the object, shortened to start at it.
+	if d.offset != 0 {
+		d.alignParts = true
+	}
+
+	// Calculate how many parts there are to download.
+	d.totalParts = d.objectRange.parts(d.partSize, d.alignParts)
+
+	// The buffer the parts are read into and sent from, bounding the memory of the download,
+	// pooled across downloads. Every chunk is at most the size of a part, so that clients can read
+	// it into a part-sized buffer.
+	bufferLength := d.objectRange.length()
+	if d.partSize < bufferLength {
+		bufferLength = d.partSize
+	}
+	d.buffer = getSendBuffer(s.bufferSize(bufferLength))
+
+	// Assert the chunks are sent in order, if enabled.
+	if s.StrictOrderAssert {
+		d.order = newOrderAssertion(d.objectRange.start)
+	}
+
+	return nil
+}
 
-		partBytes, err := ioutil.ReadAll(objectPartOutput.Body)
+// prepareStream decorates the stream of d with enveloping, tail keeping, decompression,
+// egress counting, checksum verification, pacing, chaos and progress messages, if enabled, and tells
+// the clients of reversed downloads the size of the parts to reassemble them.
+func (s Service) prepareStream(req *pb.DownloadRequest, d *partDownload) error {
+	// Envelope the file bytes with the client's key, if requested, after the other features handled them.
+	if publicKey := req.GetEnvelopePublicKey(); len(publicKey) > 0 {
+		envelope, err := newEnvelopeDownloadStream(d.stream, publicKey)
 		if err != nil {
-			return fmt.Errorf("failed to download part %d: %v", currentPart, err)
+			return err
 		}
 
-		if err := stream.Send(&pb.DownloadResponse{File: partBytes}); err != nil {
-			s.logger.WithFields(
-				logrus.Fields{
-					"trace.id": ilogger.ExtractTraceParent(stream.Context()),
-				},
-			).Errorf(err.Error())
+		d.stream = envelope
+	}
+
+	// Keep the last bytes sent to tell appends from replacements, if followed.
+	if err := prepareFollow(req, d); err != nil {
+		return err
+	}
 
+	// Send the object decompressed, if requested and compressed.
+	if err := prepareDecompress(req, d); err != nil {
+		return err
+	}
+
+	// Count the bytes sent against the egress quota, if limited.
+	if s.EgressQuota != nil {
+		d.stream = egressDownloadStream{Download_DownloadServer: d.stream, quota: s.EgressQuota}
+	}
+
+	// Compute the checksum of the bytes sent, if they're verified.
+	if d.checksum.algorithm != "" {
+		d.verifier = newChecksumDownloadStream(d.stream, d.checksum)
+		d.stream = d.verifier
+	}
+
+	// Compute the checksum of the bytes sent for the client, if requested.
+	if err := s.prepareDigest(req, d); err != nil {
+		return err
+	}
+
+	// Pace the download to the per-stream rate or the request's rate, if limited.
+	if err := s.paceStream(req, d); err != nil {
+		return err
+	}
+
+	// Pace the download to its share of the QoS budget, if limited.
+	if d.qos != nil {
+		d.stream = newSharedPacedDownloadStream(d.stream, d.qos.bytesPerSec)
+	}
+
+	// Inject faults into the download, if chaos is enabled.
+	if s.Chaos != nil {
+		d.chaos = s.Chaos.forRequest(d.stream.Context())
+		d.stream = chaosDownloadStream{Download_DownloadServer: d.stream, sendDelay: d.chaos.SendDelay}
+	}
+
+	// Interleave progress messages in the stream, if requested.
+	progressInterval, err := progressInterval(req, d.objectRange.length())
+	if err != nil {
+		return err
+	}
+
+	if progressInterval > 0 {
+		d.stream = newProgressDownloadStream(d.stream, progressInterval, d.objectRange.length())
+	}
+
+	if d.reverse {
+		return d.stream.SetHeader(metadata.Pairs(PartSizeHeader, strconv.FormatInt(d.partSize, 10)))
+	}
+
+	return nil
+}
+
+// sendParts iterates over all of the parts of d, downloads each part and streams it to the client.
+func (s Service) sendParts(ctx context.Context, d *partDownload) error {
+	rangeFailures := 0
+	for i := int64(0); i < d.totalParts; i++ {
+		// Stop before fetching a part that the client would never receive.
+		if err := d.checkCanceled(); err != nil {
+			return err
+		}
+
+		currentPart := d.partNumber(i)
+		if err := d.chaos.beforePart(ctx, currentPart); err != nil {
+			return err
+		}
+
+		// Calculate current part bytes range to download.
+		partRange := d.objectRange.part(currentPart, d.partSize, d.alignParts)
+
+		// The parts of reversed downloads are each sent in order.
+		if d.reverse && s.StrictOrderAssert {
+			d.order = newOrderAssertion(partRange.start)
+		}
+
+		partStartTime := time.Now()
+		partBody, partSpan, err := s.getPart(ctx, d, currentPart, partRange)
+		fetchDuration := time.Since(partStartTime)
+		if err != nil {
+			finishSpan(partSpan, err)
+
+			// The size the client claimed didn't match the object's, retrying wouldn't match either.
+			if status.Code(err) == codes.FailedPrecondition {
+				return err
+			}
+
+			// Without range fallbacks, a part that failed, even to prefetch, fails the download.
+			if s.RangeFallbackThreshold <= 0 {
+				return s3ErrorToStatus(fmt.Errorf("failed to download object %s/%s: %w", d.bucket, d.key, err))
+			}
+
+			// Retry the part until the ranged calls failed too many times.
+			rangeFailures++
+			if rangeFailures < s.RangeFallbackThreshold {
+				s.downloadLogger(d).Warnf(
+					"retrying part %d after ranged download failure %d: %v",
+					currentPart, rangeFailures, err,
+				)
+				i--
+				continue
+			}
+
+			if err := s.fallBackToRemainder(ctx, d, currentPart, partRange, rangeFailures, err); err != nil {
+				return err
+			}
+
+			// Reversed downloads fall back part by part, the others sent the rest of the object.
+			if d.reverse {
+				continue
+			}
+
+			break
+		}
+
+		partBytesSent, err := s.sendPart(
+			d.stream,
+			partBody,
+			d.buffer,
+			currentPart,
+			partRange.start,
+			d.order,
+			d.keyPrefix,
+		)
+		d.bytesSent += partBytesSent
+		partBody.Close()
+		finishSpan(partSpan, err)
+		if err != nil {
 			return err
 		}
+		d.partsSent++
+		traceID := s.traceID(d.stream.Context())
+		s.partLatency.ObserveTraced(time.Since(partStartTime), traceID)
+		s.observePart(fetchDuration, partBytesSent, traceID)
 	}
 
 	return nil
 }
+
+// getPart returns the body of the part number currentPart of d, whose bytes are partRange, from memory
+// if the client's sequential reads prefetched its range, from its spill if the parts are prefetched,
+// otherwise from a ranged GetObject call and its span.
+// A failure to prefetch the part stops prefetching and fails the download, unless RangeFallbackThreshold
+// retries the part, which is then downloaded like the rest of the object without prefetching.
+func (s Service) getPart(
+	ctx context.Context,
+	d *partDownload,
+	currentPart int64,
+	partRange byteRange,
+) (io.ReadCloser, opentracing.Span, error) {
+	// The part was prefetched by the client's sequential reads.
+	if d.warm != nil {
+		return d.warm.part(partRange), nil, nil
+	}
+
+	// The spilled part was traced when it was prefetched.
+	if d.spill != nil {
+		partBody, err := d.spill.next()
+		if err != nil {
+			d.closeSpill()
+		}
+
+		return partBody, nil, err
+	}
+
+	// The part was traced when it was fetched concurrently.
+	if d.prefetch != nil {
+		partBody, err := d.prefetch.next()
+		if err != nil {
+			d.closePrefetch()
+		}
+
+		return partBody, nil, err
+	}
+
+	// The fetched part is traced by the fetch buffer.
+	if d.fetch != nil {
+		partBody, err := d.fetch.next()
+		if err != nil {
+			d.closeFetch()
+		}
+
+		return partBody, nil, err
+	}
+
+	getObjectInput := &s3.GetObjectInput{
+		Key:        aws.String(d.key),
+		Bucket:     aws.String(d.bucket),
+		PartNumber: aws.Int64(currentPart),
+		Range:      aws.String(fmt.Sprintf("bytes=%d-%d", partRange.start, partRange.end)),
+	}
+
+	partSpan := s.startClientSpan(ctx, "s3.GetObject", opentracing.Tags{
+		"s3.bucket": d.bucket,
+		"s3.key":    d.key,
+		"s3.range":  aws.StringValue(getObjectInput.Range),
+	})
+	objectPartOutput, err := s.getPartObject(ctx, d.bucket, getObjectInput)
+	if err != nil {
+		return nil, partSpan, err
+	}
+
+	// Validate the size the client claimed before sending any of the object's bytes.
+	if err := d.verifyKnownSize(objectPartOutput.ContentRange); err != nil {
+		objectPartOutput.Body.Close()
+		return nil, partSpan, err
+	}
+
+	return objectPartOutput.Body, partSpan, nil
+}
+
+// fallBackToRemainder streams the rest of the object of d from the part number currentPart, whose bytes
+// are partRange, or only that part of reversed downloads, from a single non-ranged call,
+// after rangeFailures ranged calls failed with the last failure cause.
+func (s Service) fallBackToRemainder(
+	ctx context.Context,
+	d *partDownload,
+	currentPart int64,
+	partRange byteRange,
+	rangeFailures int,
+	cause error,
+) error {
+	remainder := byteRange{start: partRange.start, end: d.objectRange.end}
+	if d.reverse {
+		remainder.end = partRange.end
+	}
+
+	s.downloadLogger(d).Warnf(
+		"downgrading to a non-ranged download of %s/%s from offset %d after %d ranged download failures: %v",
+		d.bucket, d.key, partRange.start, rangeFailures, cause,
+	)
+	remainderBytesSent, err := s.sendRemainder(
+		ctx,
+		d.stream,
+		d.bucket,
+		d.key,
+		remainder,
+		d.buffer,
+		currentPart,
+		d.order,
+		d.keyPrefix,
+	)
+	d.bytesSent += remainderBytesSent
+
+	return err
+}
+
+// downloadLogger returns the logger of d's logs, tagged with its trace id and key prefix.
+func (s Service) downloadLogger(d *partDownload) *logrus.Entry {
+	return s.logger.WithFields(logrus.Fields{
+		"trace.id":   s.traceID(d.stream.Context()),
+		"key.prefix": d.keyPrefix,
+	})
+}
+
+// logEarlyEnd logs why the download ended early, if ctx was cancelled by the client
+// or its deadline was exceeded, with the number of bytes sent until then.
+func (s Service) logEarlyEnd(ctx context.Context, bytesSent int64, keyPrefix string) {
+	var reason string
+	switch ctx.Err() {
+	case context.Canceled:
+		reason = EndReasonCanceled
+
+		// The client cancels the call when its deadline passes, usually just before the server's
+		// deadline fires, since the server computes it from the timeout once the request arrives.
+		if deadline, ok := ctx.Deadline(); ok && !time.Now().Add(deadlineCancelSlack).Before(deadline) {
+			reason = EndReasonDeadlineExceeded
+		}
+	case context.DeadlineExceeded:
+		reason = EndReasonDeadlineExceeded
+	default:
+		return
+	}
+
+	s.logger.WithFields(
+		logrus.Fields{
+			"download.end_reason": reason,
+			"download.bytes_sent": bytesSent,
+			"trace.id":            s.traceID(ctx),
+			"key.prefix":          keyPrefix,
+		},
+	).Warnf("download ended early: %s", reason)
+}
+
+// sendPart reads the bytes of the part number currentPart, which starts at offset partStart
+// of the object, from body into buffer and sends them to stream, in chunks of up to len(buffer) bytes.
+// Every chunk is checked by order before it's sent. It returns the number of bytes sent.
+func (s Service) sendPart(
+	stream pb.Download_DownloadServer,
+	body io.Reader,
+	buffer []byte,
+	currentPart int64,
+	partStart int64,
+	order *orderAssertion,
+	keyPrefix string,
+) (int64, error) {
+	sent := int64(0)
+	for {
+		n, err := io.ReadFull(body, buffer)
+		if n > 0 {
+			if err := order.check(partStart+sent, int64(n)); err != nil {
+				s.logger.WithFields(
+					logrus.Fields{
+						"trace.id":   s.traceID(stream.Context()),
+						"key.prefix": keyPrefix,
+					},
+				).Errorf(err.Error())
+
+				return sent, err
+			}
+
+			// The message is serialized by Send, so buffer can be reused once it returns.
+			chunk := &pb.DownloadResponse{Payload: &pb.DownloadResponse_File{File: buffer[:n]}}
+			if err := stream.Send(chunk); err != nil {
+				s.logger.WithFields(
+					logrus.Fields{
+						"trace.id":   s.traceID(stream.Context()),
+						"key.prefix": keyPrefix,
+					},
+				).Errorf(err.Error())
+
+				return sent, err
+			}
+
+			sent += int64(n)
+		}
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return sent, nil
+		}
+
+		if err != nil {
+			return sent, fmt.Errorf("failed to download part %d: %v", currentPart, err)
+		}
+	}
+}
@@ -4,19 +4,28 @@ import (
 	"bytes"
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"hash"
 	"io"
 	"io/ioutil"
 	"log"
 	"net"
+	"runtime"
 	"testing"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/meateam/download-service/download"
 	pb "github.com/meateam/download-service/proto"
 	"github.com/meateam/download-service/server"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
 )
 
@@ -139,6 +148,141 @@ func TestDownloadService_Download(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "download - url",
+			args: args{
+				ctx: context.Background(),
+				req: &pb.DownloadRequest{
+					Url: "s3://" + testbucket + "/" + testkey,
+				},
+			},
+			wantErr: false,
+			want:    file,
+		},
+		{
+			name: "download - key takes precedence over url",
+			args: args{
+				ctx: context.Background(),
+				req: &pb.DownloadRequest{
+					Key: testkey,
+					Url: aws.StringValue(s3Client.Config.Endpoint) + "/" + testbucket + "/testkey",
+				},
+			},
+			wantErr: false,
+			want:    file,
+		},
+		{
+			name: "download - url of another host",
+			args: args{
+				ctx: context.Background(),
+				req: &pb.DownloadRequest{
+					Url: "https://evil.example/" + testbucket + "/" + testkey,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "download - invalid url",
+			args: args{
+				ctx: context.Background(),
+				req: &pb.DownloadRequest{
+					Url: "ftp://" + testbucket + "/" + testkey,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "download - range",
+			args: args{
+				ctx: context.Background(),
+				req: &pb.DownloadRequest{
+					Key:        testkey,
+					Bucket:     testbucket,
+					RangeStart: 100,
+					RangeEnd:   199,
+				},
+			},
+			wantErr: false,
+			want:    file[100:200],
+		},
+		{
+			name: "download - open-ended range",
+			args: args{
+				ctx: context.Background(),
+				req: &pb.DownloadRequest{
+					Key:        testkey,
+					Bucket:     testbucket,
+					RangeStart: 1 << 10,
+				},
+			},
+			wantErr: false,
+			want:    file[1<<10:],
+		},
+		{
+			name: "download - range end beyond object length",
+			args: args{
+				ctx: context.Background(),
+				req: &pb.DownloadRequest{
+					Key:        testkey,
+					Bucket:     testbucket,
+					RangeStart: 10,
+					RangeEnd:   10 << 20,
+				},
+			},
+			wantErr: false,
+			want:    file[10:],
+		},
+		{
+			name: "download - suffix range",
+			args: args{
+				ctx: context.Background(),
+				req: &pb.DownloadRequest{
+					Key:        testkey,
+					Bucket:     testbucket,
+					RangeStart: -4096,
+				},
+			},
+			wantErr: false,
+			want:    file[len(file)-4096:],
+		},
+		{
+			name: "download - suffix range longer than object",
+			args: args{
+				ctx: context.Background(),
+				req: &pb.DownloadRequest{
+					Key:        testkey,
+					Bucket:     testbucket,
+					RangeStart: -(10 << 20),
+				},
+			},
+			wantErr: false,
+			want:    file,
+		},
+		{
+			name: "download - range start beyond object length",
+			args: args{
+				ctx: context.Background(),
+				req: &pb.DownloadRequest{
+					Key:        testkey,
+					Bucket:     testbucket,
+					RangeStart: 10 << 20,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "download - range start greater than range end",
+			args: args{
+				ctx: context.Background(),
+				req: &pb.DownloadRequest{
+					Key:        testkey,
+					Bucket:     testbucket,
+					RangeStart: 200,
+					RangeEnd:   100,
+				},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -191,6 +335,199 @@ func TestDownloadService_Download(t *testing.T) {
 	}
 }
 
+func TestDownloadService_Authorizer(t *testing.T) {
+	const subject = "subject"
+
+	tests := []struct {
+		name     string
+		allow    bool
+		wantCode codes.Code
+	}{
+		{name: "authorizer - allow", allow: true, wantCode: codes.OK},
+		{name: "authorizer - deny", allow: false, wantCode: codes.PermissionDenied},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var gotSubject, gotBucket, gotKey string
+			service := download.NewService(s3Client, logger)
+			service.Authorizer = func(ctx context.Context, subject string, bucket string, key string) error {
+				gotSubject, gotBucket, gotKey = subject, bucket, key
+				if !tt.allow {
+					return fmt.Errorf("%s may not read %s/%s", subject, bucket, key)
+				}
+
+				return nil
+			}
+
+			// Authenticate every stream as subject.
+			authenticate := grpc.StreamInterceptor(func(
+				srv interface{},
+				ss grpc.ServerStream,
+				info *grpc.StreamServerInfo,
+				handler grpc.StreamHandler,
+			) error {
+				wrapped := grpc_middleware.WrapServerStream(ss)
+				wrapped.WrappedContext = download.ContextWithSubject(ss.Context(), subject)
+				return handler(srv, wrapped)
+			})
+
+			client, closeClient := newServiceClient(t, service, authenticate)
+			defer closeClient()
+
+			stream, err := client.Download(context.Background(), &pb.DownloadRequest{Key: testkey, Bucket: testbucket})
+			if err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			got, err := recvAll(stream)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("DownloadService.Download() error = %v, wantCode %v", err, tt.wantCode)
+			}
+
+			if gotSubject != subject || gotBucket != testbucket || gotKey != testkey {
+				t.Errorf(
+					"Authorizer called with (%q, %q, %q), want (%q, %q, %q)",
+					gotSubject, gotBucket, gotKey, subject, testbucket, testkey,
+				)
+			}
+
+			if tt.wantCode == codes.OK && !bytes.Equal(got, file) {
+				t.Errorf("DownloadService.Download() file downloaded is different from the wanted file")
+			}
+		})
+	}
+}
+
+func TestDownloadService_DownloadMaxBufferSize(t *testing.T) {
+	const maxBufferSize = 64 << 10
+
+	tests := []struct {
+		name          string
+		maxBufferSize int64
+		wantChunkSize int
+	}{
+		{name: "max buffer size - whole parts", maxBufferSize: 0, wantChunkSize: len(file)},
+		{name: "max buffer size - sub-part chunks", maxBufferSize: maxBufferSize, wantChunkSize: maxBufferSize},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			service := download.NewService(s3Client, logger)
+			service.MaxBufferSize = tt.maxBufferSize
+			stream := &hashingDownloadStream{ctx: context.Background(), hash: sha256.New()}
+
+			var before, after runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+
+			if err := service.Download(&pb.DownloadRequest{Key: testkey, Bucket: testbucket}, stream); err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			runtime.ReadMemStats(&after)
+			allocated := after.TotalAlloc - before.TotalAlloc
+
+			wantHash := sha256.Sum256(file)
+			if !bytes.Equal(stream.hash.Sum(nil), wantHash[:]) {
+				t.Errorf("DownloadService.Download() file downloaded is different from the wanted file")
+			}
+
+			if stream.maxChunkSize != tt.wantChunkSize {
+				t.Errorf("DownloadService.Download() max chunk size = %d, want %d", stream.maxChunkSize, tt.wantChunkSize)
+			}
+
+			// Buffering whole parts allocates at least the file, the budget must allocate well below it.
+			if tt.maxBufferSize > 0 && allocated > uint64(len(file)/2) {
+				t.Errorf(
+					"DownloadService.Download() allocated %d bytes with max buffer size %d, want less than %d",
+					allocated, tt.maxBufferSize, len(file)/2,
+				)
+			}
+		})
+	}
+}
+
+// hashingDownloadStream is a pb.Download_DownloadServer that hashes the file bytes
+// sent on it instead of keeping them, and records the size of the largest chunk.
+type hashingDownloadStream struct {
+	grpc.ServerStream
+	ctx          context.Context
+	hash         hash.Hash
+	maxChunkSize int
+}
+
+func (s *hashingDownloadStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *hashingDownloadStream) SetTrailer(metadata.MD) {}
+
+func (s *hashingDownloadStream) Send(res *pb.DownloadResponse) error {
+	if len(res.GetFile()) > s.maxChunkSize {
+		s.maxChunkSize = len(res.GetFile())
+	}
+
+	_, err := s.hash.Write(res.GetFile())
+	return err
+}
+
+// newServiceClient serves service on a new in-memory listener with opts,
+// and returns a client connected to it and a function that closes both.
+func newServiceClient(
+	t *testing.T,
+	service *download.Service,
+	opts ...grpc.ServerOption,
+) (pb.DownloadClient, func()) {
+	t.Helper()
+
+	serviceLis := bufconn.Listen(bufSize)
+	grpcServer := grpc.NewServer(opts...)
+	pb.RegisterDownloadServer(grpcServer, service)
+	go func() {
+		if err := grpcServer.Serve(serviceLis); err != nil {
+			log.Printf("failed to serve: %v", err)
+		}
+	}()
+
+	conn, err := grpc.DialContext(
+		context.Background(),
+		"bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return serviceLis.Dial()
+		}),
+		grpc.WithInsecure(),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial bufnet: %v", err)
+	}
+
+	return pb.NewDownloadClient(conn), func() {
+		conn.Close()
+		grpcServer.Stop()
+	}
+}
+
+// recvAll receives the whole stream and returns the file's bytes,
+// the error is nil when the stream ends with io.EOF.
+func recvAll(stream pb.Download_DownloadClient) ([]byte, error) {
+	fileFromStream := make([]byte, 0, 2<<20)
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			return fileFromStream, nil
+		}
+
+		if err != nil {
+			return fileFromStream, err
+		}
+
+		fileFromStream = append(fileFromStream, chunk.GetFile()...)
+	}
+}
+
 // EmptyBucket empties the Amazon S3 bucket and deletes it.
 func emptyAndDeleteBucket(bucket string) error {
 	log.Print("removing objects from S3 bucket : ", bucket)
@@ -0,0 +1,221 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// DefaultDownloaderConcurrency is the default number of ranges a Downloader downloads concurrently.
+	DefaultDownloaderConcurrency = 5
+
+	// DefaultDownloaderMaxRetries is the default number of times a Downloader retries a failed range.
+	DefaultDownloaderMaxRetries = 3
+)
+
+// ErrObjectChanged is the error returned by Downloader.DownloadToWriterAt when the object
+// changed during the download, so its ranges may belong to different versions of the object.
+var ErrObjectChanged = errors.New("object changed during the download")
+
+// Downloader downloads objects from the download service into an io.WriterAt with concurrent
+// ranged downloads, like s3manager.Downloader does from S3.
+type Downloader struct {
+	client pb.DownloadClient
+
+	// PartSize is the size of the ranges the object is split into, defaults to PartSize.
+	PartSize int64
+
+	// Concurrency is the maximum number of ranges downloaded concurrently,
+	// defaults to DefaultDownloaderConcurrency.
+	Concurrency int
+
+	// MaxRetries is the number of times a failed range is retried, resuming from the bytes of the range
+	// already downloaded, defaults to DefaultDownloaderMaxRetries.
+	MaxRetries int
+}
+
+// NewDownloader creates a Downloader of client and returns it.
+func NewDownloader(client pb.DownloadClient) *Downloader {
+	return &Downloader{
+		client:      client,
+		PartSize:    PartSize,
+		Concurrency: DefaultDownloaderConcurrency,
+		MaxRetries:  DefaultDownloaderMaxRetries,
+	}
+}
+
+// DownloadToWriterAt downloads the whole object of req, identified by its key, bucket or url,
+// into w and returns the number of bytes downloaded. The object is split into ranges of
+// d.PartSize bytes, each downloaded with up to d.Concurrency concurrent downloads, retried
+// up to d.MaxRetries times from the bytes already downloaded, and written at its offset in w.
+// The ranges are validated against the object's ETag, the download fails with ErrObjectChanged
+// if the object changed.
+// The ranges of objects with weak ETags aren't validated.
+func (d *Downloader) DownloadToWriterAt(
+	ctx context.Context,
+	req *pb.DownloadRequest,
+	w io.WriterAt,
+) (int64, error) {
+	manifest, err := d.client.GetDownloadManifest(ctx, &pb.GetDownloadManifestRequest{
+		Key:      req.GetKey(),
+		Bucket:   req.GetBucket(),
+		Url:      req.GetUrl(),
+		PartSize: d.PartSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Weak ETags don't identify the object's bytes, so they can't validate its ranges.
+	etag := manifest.GetEtag()
+	if manifest.GetEtagWeak() {
+		etag = ""
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	concurrency := d.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultDownloaderConcurrency
+	}
+
+	parts := make(chan *pb.ManifestPart)
+	errs := make(chan error, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for part := range parts {
+				if err := d.downloadPartWithRetry(ctx, req, etag, part, w); err != nil {
+					errs <- err
+					cancel()
+					return
+				}
+			}
+		}()
+	}
+
+	// Hand out the parts until they're all handed out or a part failed.
+handOut:
+	for _, part := range manifest.GetParts() {
+		select {
+		case parts <- part:
+		case <-ctx.Done():
+			break handOut
+		}
+	}
+	close(parts)
+	wg.Wait()
+	close(errs)
+
+	if err := <-errs; err != nil {
+		return 0, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return manifest.GetSize(), nil
+}
+
+// downloadPartWithRetry downloads part of req's object into w, retrying up to d.MaxRetries times,
+// unless the object changed since etag or ctx is done. Every retry resumes the part from the bytes
+// already written, since a server can't retry a send that failed once the stream was aborted.
+func (d *Downloader) downloadPartWithRetry(
+	ctx context.Context,
+	req *pb.DownloadRequest,
+	etag string,
+	part *pb.ManifestPart,
+	w io.WriterAt,
+) error {
+	maxRetries := d.MaxRetries
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+
+	var written int64
+	for attempt := 0; ; attempt++ {
+		var err error
+		written, err = d.downloadPart(ctx, req, etag, part, written, w)
+		if err == nil {
+			return nil
+		}
+
+		if status.Code(err) == codes.FailedPrecondition {
+			return fmt.Errorf("failed to download range at %d: %w", part.GetOffset(), ErrObjectChanged)
+		}
+
+		if ctx.Err() != nil || attempt >= maxRetries {
+			return fmt.Errorf("failed to download range at %d after %d attempts: %v", part.GetOffset(), attempt+1, err)
+		}
+	}
+}
+
+// downloadPart downloads part of req's object into w at its offset, resuming after the written bytes
+// of the part that a previous attempt wrote, and returns the bytes of the part written so far.
+// It fails with FailedPrecondition if the object changed since etag.
+func (d *Downloader) downloadPart(
+	ctx context.Context,
+	req *pb.DownloadRequest,
+	etag string,
+	part *pb.ManifestPart,
+	written int64,
+	w io.WriterAt,
+) (int64, error) {
+	partReq := &pb.DownloadRequest{
+		Key:         req.GetKey(),
+		Bucket:      req.GetBucket(),
+		Url:         req.GetUrl(),
+		RangeStart:  part.GetOffset(),
+		RangeEnd:    part.GetOffset() + part.GetLength() - 1,
+		IfRange:     etag,
+		IfRangeFail: etag != "",
+	}
+
+	offset := part.GetOffset() + written
+	if written > 0 {
+		partReq.Offset = offset
+	}
+
+	stream, err := d.client.Download(ctx, partReq)
+	if err != nil {
+		return written, err
+	}
+
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return offset - part.GetOffset(), err
+		}
+
+		n, err := w.WriteAt(chunk.GetFile(), offset)
+		offset += int64(n)
+		if err != nil {
+			return offset - part.GetOffset(), err
+		}
+	}
+
+	written = offset - part.GetOffset()
+	if written != part.GetLength() {
+		return written, fmt.Errorf(
+			"range at %d ended after %d bytes, want %d",
+			part.GetOffset(), written, part.GetLength(),
+		)
+	}
+
+	return written, nil
+}
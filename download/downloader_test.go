@@ -0,0 +1,281 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// rangeFailingOnceS3Client returns an S3 client whose first GetObject call of the range
+// starting at rangeStart fails, and a counter of the GetObject calls of that range.
+func rangeFailingOnceS3Client(rangeStart int64) (*s3.S3, func() int) {
+	var mu sync.Mutex
+	calls := 0
+	prefix := fmt.Sprintf("bytes=%d-", rangeStart)
+	client := s3.New(session.Must(session.NewSession(&s3Client.Config)))
+	client.Handlers.Validate.PushBack(func(r *request.Request) {
+		input, ok := r.Params.(*s3.GetObjectInput)
+		if !ok || !strings.HasPrefix(aws.StringValue(input.Range), prefix) {
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls == 1 {
+			r.Error = awserr.New("InternalError", "injected range failure", nil)
+		}
+	})
+
+	return client, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return calls
+	}
+}
+
+// concurrentDownloadsInterceptor returns a grpc.StreamServerInterceptor that holds every download
+// for a moment, and a function that returns the most downloads that were served concurrently.
+func concurrentDownloadsInterceptor() (grpc.StreamServerInterceptor, func() int) {
+	var mu sync.Mutex
+	active, maxActive := 0, 0
+	interceptor := func(
+		srv interface{},
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		mu.Lock()
+		active++
+		if active > maxActive {
+			maxActive = active
+		}
+		mu.Unlock()
+
+		time.Sleep(50 * time.Millisecond)
+		err := handler(srv, stream)
+
+		mu.Lock()
+		active--
+		mu.Unlock()
+
+		return err
+	}
+
+	return interceptor, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return maxActive
+	}
+}
+
+// interruptingServerStream is a grpc.ServerStream whose sends fail after its first message
+// if it downloads the range starting at rangeStart for the first time.
+type interruptingServerStream struct {
+	grpc.ServerStream
+	rangeStart  int64
+	interrupted *int32
+	offsets     chan<- int64
+	sends       int
+	interrupt   bool
+}
+
+func (s *interruptingServerStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	if req, ok := m.(*pb.DownloadRequest); ok && req.GetRangeStart() == s.rangeStart {
+		s.offsets <- req.GetOffset()
+		s.interrupt = atomic.CompareAndSwapInt32(s.interrupted, 0, 1)
+	}
+
+	return nil
+}
+
+func (s *interruptingServerStream) SendMsg(m interface{}) error {
+	if s.interrupt && s.sends > 0 {
+		return status.Error(codes.Unavailable, "injected network blip")
+	}
+
+	s.sends++
+
+	return s.ServerStream.SendMsg(m)
+}
+
+// interruptingInterceptor returns a grpc.StreamServerInterceptor interrupting the first download
+// of the range starting at rangeStart after its first message, and a channel of the offsets
+// the downloads of that range were requested from.
+func interruptingInterceptor(rangeStart int64) (grpc.StreamServerInterceptor, <-chan int64) {
+	var interrupted int32
+	offsets := make(chan int64, 10)
+	interceptor := func(
+		srv interface{},
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		return handler(srv, &interruptingServerStream{
+			ServerStream: stream,
+			rangeStart:   rangeStart,
+			interrupted:  &interrupted,
+			offsets:      offsets,
+		})
+	}
+
+	return interceptor, offsets
+}
+
+func TestDownloader_DownloadToWriterAt(t *testing.T) {
+	const (
+		downloaderKey = "downloader.txt"
+		partSize      = 1 << 20
+	)
+
+	// Upload a fixture of a few ranges, the last one partial.
+	downloaderFile := make([]byte, 3*partSize+5)
+	if _, err := rand.Read(downloaderFile); err != nil {
+		t.Fatalf("failed to generate file, %v", err)
+	}
+
+	upload := func(body []byte) {
+		if _, err := s3Client.PutObject(&s3.PutObjectInput{
+			Bucket: aws.String(testbucket),
+			Key:    aws.String(downloaderKey),
+			Body:   bytes.NewReader(body),
+		}); err != nil {
+			t.Fatalf("failed to upload %s, %v", downloaderKey, err)
+		}
+	}
+
+	req := &pb.DownloadRequest{Key: downloaderKey, Bucket: testbucket}
+
+	t.Run("downloader - parallel ranges", func(t *testing.T) {
+		upload(downloaderFile)
+		interceptor, maxActive := concurrentDownloadsInterceptor()
+		client, closeClient := newServiceClient(
+			t,
+			download.NewService(s3Client, logger),
+			grpc.StreamInterceptor(interceptor),
+		)
+		defer closeClient()
+
+		downloader := download.NewDownloader(client)
+		downloader.PartSize = partSize
+		downloader.Concurrency = 3
+		checkDownloadToWriterAt(t, downloader, req, downloaderFile)
+
+		if got := maxActive(); got < 2 || got > downloader.Concurrency {
+			t.Errorf(
+				"Downloader.DownloadToWriterAt() ran %d concurrent downloads, want 2 to %d",
+				got, downloader.Concurrency,
+			)
+		}
+	})
+
+	t.Run("downloader - failed range retried", func(t *testing.T) {
+		upload(downloaderFile)
+		failingClient, calls := rangeFailingOnceS3Client(partSize)
+		client, closeClient := newServiceClient(t, download.NewService(failingClient, logger))
+		defer closeClient()
+
+		downloader := download.NewDownloader(client)
+		downloader.PartSize = partSize
+		checkDownloadToWriterAt(t, downloader, req, downloaderFile)
+
+		if got := calls(); got != 2 {
+			t.Errorf("Downloader.DownloadToWriterAt() downloaded the failed range %d times, want 2", got)
+		}
+	})
+
+	t.Run("downloader - interrupted range resumed", func(t *testing.T) {
+		const chunkSize = partSize / 4
+
+		upload(downloaderFile)
+		interceptor, offsets := interruptingInterceptor(partSize)
+		service := download.NewService(s3Client, logger)
+		service.MaxBufferSize = chunkSize
+		client, closeClient := newServiceClient(t, service, grpc.StreamInterceptor(interceptor))
+		defer closeClient()
+
+		downloader := download.NewDownloader(client)
+		downloader.PartSize = partSize
+		checkDownloadToWriterAt(t, downloader, req, downloaderFile)
+
+		if got := []int64{<-offsets, <-offsets}; got[0] != 0 || got[1] != partSize+chunkSize {
+			t.Errorf(
+				"Downloader.DownloadToWriterAt() requested the interrupted range from offsets %v, want [0 %d]",
+				got, partSize+chunkSize,
+			)
+		}
+	})
+
+	t.Run("downloader - object changed", func(t *testing.T) {
+		upload(downloaderFile)
+
+		// Change the object once the ranges start downloading.
+		var once sync.Once
+		changeObject := func(
+			srv interface{},
+			stream grpc.ServerStream,
+			info *grpc.StreamServerInfo,
+			handler grpc.StreamHandler,
+		) error {
+			once.Do(func() { upload(downloaderFile[:partSize]) })
+			return handler(srv, stream)
+		}
+
+		client, closeClient := newServiceClient(
+			t,
+			download.NewService(s3Client, logger),
+			grpc.StreamInterceptor(changeObject),
+		)
+		defer closeClient()
+
+		downloader := download.NewDownloader(client)
+		downloader.PartSize = partSize
+		_, err := downloader.DownloadToWriterAt(context.Background(), req, aws.NewWriteAtBuffer(nil))
+		if !errors.Is(err, download.ErrObjectChanged) {
+			t.Errorf("Downloader.DownloadToWriterAt() error = %v, want %v", err, download.ErrObjectChanged)
+		}
+	})
+}
+
+// checkDownloadToWriterAt checks that downloader downloads req's object, which is want, into a buffer.
+func checkDownloadToWriterAt(t *testing.T, downloader *download.Downloader, req *pb.DownloadRequest, want []byte) {
+	t.Helper()
+
+	buffer := aws.NewWriteAtBuffer(nil)
+	n, err := downloader.DownloadToWriterAt(context.Background(), req, buffer)
+	if err != nil {
+		t.Fatalf("Downloader.DownloadToWriterAt() error = %v", err)
+	}
+
+	if n != int64(len(want)) {
+		t.Errorf("Downloader.DownloadToWriterAt() = %d, want %d", n, len(want))
+	}
+
+	if !bytes.Equal(buffer.Bytes(), want) {
+		t.Errorf("Downloader.DownloadToWriterAt() file downloaded is different from the wanted file")
+	}
+}
@@ -0,0 +1,157 @@
+package download
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultEgressWindow is the default window of an EgressQuota.
+const DefaultEgressWindow = 24 * time.Hour
+
+// EgressQuota caps the bytes a service serves per fixed window of time, for cost control.
+// Once the bytes served in a window reach the limit, new downloads are rejected until the
+// window ends, while the active downloads complete.
+type EgressQuota struct {
+	mu          sync.Mutex
+	limit       int64
+	window      time.Duration
+	used        int64
+	windowStart time.Time
+}
+
+// EgressQuotaSnapshot is a point in time state of an EgressQuota.
+type EgressQuotaSnapshot struct {
+	Limit     int64
+	Used      int64
+	Remaining int64
+	ResetsIn  time.Duration
+}
+
+// NewEgressQuota returns an EgressQuota allowing limit bytes per window, starting now.
+// A non-positive window defaults to DefaultEgressWindow.
+func NewEgressQuota(limit int64, window time.Duration) *EgressQuota {
+	if window <= 0 {
+		window = DefaultEgressWindow
+	}
+
+	return &EgressQuota{limit: limit, window: window, windowStart: time.Now()}
+}
+
+// check returns a ResourceExhausted error with a RetryInfo detail of the time left in the window
+// if the bytes served in the current window reached the limit.
+func (q *EgressQuota) check() error {
+	if q == nil {
+		return nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	q.roll(now)
+	if q.used < q.limit {
+		return nil
+	}
+
+	resetsIn := q.windowStart.Add(q.window).Sub(now)
+	st := status.Newf(
+		codes.ResourceExhausted,
+		"egress limit of %d bytes per %s exceeded, resets in %s",
+		q.limit,
+		q.window,
+		resetsIn,
+	)
+	withDetails, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: ptypes.DurationProto(resetsIn)})
+	if err != nil {
+		return st.Err()
+	}
+
+	return withDetails.Err()
+}
+
+// add counts n bytes served in the current window.
+func (q *EgressQuota) add(n int64) {
+	if q == nil {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.roll(time.Now())
+	q.used += n
+}
+
+// Snapshot returns the current state of the quota.
+func (q *EgressQuota) Snapshot() EgressQuotaSnapshot {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	q.roll(now)
+
+	remaining := q.limit - q.used
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return EgressQuotaSnapshot{
+		Limit:     q.limit,
+		Used:      q.used,
+		Remaining: remaining,
+		ResetsIn:  q.windowStart.Add(q.window).Sub(now),
+	}
+}
+
+// roll starts the window that now is in if the current window ended, q.mu must be held.
+func (q *EgressQuota) roll(now time.Time) {
+	if elapsed := now.Sub(q.windowStart); elapsed >= q.window {
+		q.windowStart = q.windowStart.Add(elapsed - elapsed%q.window)
+		q.used = 0
+	}
+}
+
+// egressDownloadStream is a pb.Download_DownloadServer that counts the file bytes sent on it
+// against an EgressQuota.
+type egressDownloadStream struct {
+	pb.Download_DownloadServer
+	quota *EgressQuota
+}
+
+// Send sends res on the underlying stream and counts its file bytes against the quota.
+func (s egressDownloadStream) Send(res *pb.DownloadResponse) error {
+	if err := s.Download_DownloadServer.Send(res); err != nil {
+		return err
+	}
+
+	s.quota.add(int64(len(res.GetFile())))
+
+	return nil
+}
+
+// GetEgressQuota is the request to get the egress quota of the current window.
+// It returns a FailedPrecondition error if egress isn't limited.
+func (s Service) GetEgressQuota(ctx context.Context, req *pb.GetEgressQuotaRequest) (*pb.EgressQuota, error) {
+	if s.EgressQuota == nil {
+		return nil, status.Error(codes.FailedPrecondition, "egress isn't limited")
+	}
+
+	return egressQuota(s.EgressQuota.Snapshot()), nil
+}
+
+// egressQuota converts snapshot to its protobuf message.
+func egressQuota(snapshot EgressQuotaSnapshot) *pb.EgressQuota {
+	return &pb.EgressQuota{
+		LimitBytes:     snapshot.Limit,
+		UsedBytes:      snapshot.Used,
+		RemainingBytes: snapshot.Remaining,
+		ResetsInMs:     int64(snapshot.ResetsIn / time.Millisecond),
+	}
+}
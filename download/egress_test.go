@@ -0,0 +1,100 @@
+package download_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDownloadService_DownloadEgressQuota(t *testing.T) {
+	const window = 500 * time.Millisecond
+
+	service := download.NewService(s3Client, logger)
+	service.EgressQuota = download.NewEgressQuota(int64(len(file))+1, window)
+
+	downloadFile := func() error {
+		stream := &hashingDownloadStream{ctx: context.Background(), hash: sha256.New()}
+		return service.Download(&pb.DownloadRequest{Key: testkey, Bucket: testbucket}, stream)
+	}
+
+	// The quota isn't exceeded until the second download.
+	for i := 0; i < 2; i++ {
+		if err := downloadFile(); err != nil {
+			t.Fatalf("DownloadService.Download() error = %v", err)
+		}
+	}
+
+	quota, err := service.GetEgressQuota(context.Background(), &pb.GetEgressQuotaRequest{})
+	if err != nil {
+		t.Fatalf("DownloadService.GetEgressQuota() error = %v", err)
+	}
+
+	if quota.GetUsedBytes() != 2*int64(len(file)) || quota.GetRemainingBytes() != 0 {
+		t.Errorf(
+			"DownloadService.GetEgressQuota() used %d bytes with %d remaining, want %d used with none remaining",
+			quota.GetUsedBytes(), quota.GetRemainingBytes(), 2*len(file),
+		)
+	}
+
+	if quota.GetResetsInMs() <= 0 || quota.GetResetsInMs() > int64(window/time.Millisecond) {
+		t.Errorf("DownloadService.GetEgressQuota() resets in %dms, want within %s", quota.GetResetsInMs(), window)
+	}
+
+	stats, err := service.GetStats(context.Background(), &pb.GetStatsRequest{})
+	if err != nil {
+		t.Fatalf("DownloadService.GetStats() error = %v", err)
+	}
+
+	if stats.GetEgressQuota().GetUsedBytes() != quota.GetUsedBytes() {
+		t.Errorf(
+			"DownloadService.GetStats() egress used %d bytes, want %d",
+			stats.GetEgressQuota().GetUsedBytes(), quota.GetUsedBytes(),
+		)
+	}
+
+	if err := downloadFile(); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("DownloadService.Download() error = %v, want %v", err, codes.ResourceExhausted)
+	}
+
+	// The quota resets with the window.
+	time.Sleep(time.Duration(quota.GetResetsInMs())*time.Millisecond + 50*time.Millisecond)
+	if err := downloadFile(); err != nil {
+		t.Fatalf("DownloadService.Download() after the window reset error = %v", err)
+	}
+
+	quota, err = service.GetEgressQuota(context.Background(), &pb.GetEgressQuotaRequest{})
+	if err != nil {
+		t.Fatalf("DownloadService.GetEgressQuota() error = %v", err)
+	}
+
+	if quota.GetUsedBytes() != int64(len(file)) {
+		t.Errorf(
+			"DownloadService.GetEgressQuota() used %d bytes after the reset, want %d",
+			quota.GetUsedBytes(), len(file),
+		)
+	}
+}
+
+func TestDownloadService_GetEgressQuotaUnlimited(t *testing.T) {
+	service := download.NewService(s3Client, logger)
+
+	_, err := service.GetEgressQuota(context.Background(), &pb.GetEgressQuotaRequest{})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("DownloadService.GetEgressQuota() error = %v, want %v", err, codes.FailedPrecondition)
+	}
+
+	stats, err := service.GetStats(context.Background(), &pb.GetStatsRequest{})
+	if err != nil {
+		t.Fatalf("DownloadService.GetStats() error = %v", err)
+	}
+
+	if stats.GetEgressQuota() != nil {
+		t.Errorf("DownloadService.GetStats() egress quota = %v, want none", stats.GetEgressQuota())
+	}
+}
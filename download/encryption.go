@@ -0,0 +1,18 @@
+package download
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// checkEncryption returns a FailedPrecondition error if s.RequireEncryption is set
+// and head reports that bucket/key isn't encrypted at rest.
+func (s Service) checkEncryption(bucket string, key string, head *s3.HeadObjectOutput) error {
+	if !s.RequireEncryption || aws.StringValue(head.ServerSideEncryption) != "" {
+		return nil
+	}
+
+	return status.Errorf(codes.FailedPrecondition, "object %s/%s isn't encrypted at rest", bucket, key)
+}
@@ -0,0 +1,117 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// encryptingS3Client returns an S3 client whose HeadObject results report that
+// encryptedKey is encrypted at rest with SSE-KMS using kmsKeyID.
+func encryptingS3Client(encryptedKey string, kmsKeyID string) *s3.S3 {
+	client := s3.New(session.Must(session.NewSession(&s3Client.Config)))
+	client.Handlers.Unmarshal.PushBack(func(r *request.Request) {
+		input, ok := r.Params.(*s3.HeadObjectInput)
+		if !ok || aws.StringValue(input.Key) != encryptedKey {
+			return
+		}
+
+		if output, ok := r.Data.(*s3.HeadObjectOutput); ok {
+			output.ServerSideEncryption = aws.String(s3.ServerSideEncryptionAwsKms)
+			output.SSEKMSKeyId = aws.String(kmsKeyID)
+		}
+	})
+
+	return client
+}
+
+func TestDownloadService_RequireEncryption(t *testing.T) {
+	const encryptedKey, kmsKeyID = "encrypted.txt", "kms-key"
+
+	if _, err := s3Client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(testbucket),
+		Key:    aws.String(encryptedKey),
+		Body:   bytes.NewReader(file),
+	}); err != nil {
+		t.Fatalf("failed to upload %s, %v", encryptedKey, err)
+	}
+
+	tests := []struct {
+		name              string
+		key               string
+		requireEncryption bool
+		wantSSE           string
+		wantKMSKeyID      string
+		wantCode          codes.Code
+	}{
+		{
+			name:         "encryption - encrypted object",
+			key:          encryptedKey,
+			wantSSE:      s3.ServerSideEncryptionAwsKms,
+			wantKMSKeyID: kmsKeyID,
+		},
+		{name: "encryption - unencrypted object", key: testkey},
+		{
+			name:              "encryption - strict encrypted object",
+			key:               encryptedKey,
+			requireEncryption: true,
+			wantSSE:           s3.ServerSideEncryptionAwsKms,
+			wantKMSKeyID:      kmsKeyID,
+		},
+		{
+			name:              "encryption - strict unencrypted object",
+			key:               testkey,
+			requireEncryption: true,
+			wantCode:          codes.FailedPrecondition,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			service := download.NewService(encryptingS3Client(encryptedKey, kmsKeyID), logger)
+			service.RequireEncryption = tt.requireEncryption
+
+			client, closeClient := newServiceClient(t, service)
+			defer closeClient()
+
+			manifest, err := client.GetDownloadManifest(
+				context.Background(),
+				&pb.GetDownloadManifestRequest{Key: tt.key, Bucket: testbucket},
+			)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("DownloadService.GetDownloadManifest() error = %v, wantCode %v", err, tt.wantCode)
+			}
+
+			if manifest.GetServerSideEncryption() != tt.wantSSE || manifest.GetSseKmsKeyId() != tt.wantKMSKeyID {
+				t.Errorf(
+					"DownloadService.GetDownloadManifest() encryption = %q, %q, want %q, %q",
+					manifest.GetServerSideEncryption(), manifest.GetSseKmsKeyId(), tt.wantSSE, tt.wantKMSKeyID,
+				)
+			}
+
+			stream, err := client.Download(context.Background(), &pb.DownloadRequest{Key: tt.key, Bucket: testbucket})
+			if err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			fileFromStream, err := recvAll(stream)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("DownloadService.Download() error = %v, wantCode %v", err, tt.wantCode)
+			}
+
+			if tt.wantCode == codes.OK && !bytes.Equal(fileFromStream, file) {
+				t.Errorf("DownloadService.Download() file downloaded is different from the wanted file")
+			}
+		})
+	}
+}
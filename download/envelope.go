@@ -0,0 +1,191 @@
+package download
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"fmt"
+
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// EnvelopeKeyHeader is the binary response header of the stream's envelope key,
+	// encrypted with RSA-OAEP SHA-256 by the request's envelope public key.
+	EnvelopeKeyHeader = "x-envelope-key-bin"
+
+	// MinEnvelopeKeyBits is the minimal size of the RSA keys downloads are enveloped with.
+	MinEnvelopeKeyBits = 2048
+
+	// envelopeKeySize is the size of the AES-256 key of an enveloped stream.
+	envelopeKeySize = 32
+)
+
+// envelopeNonce returns the nonce of the nth encrypted chunk of a stream, the chunk's number
+// in the nonce's last 8 bytes, so chunks that were dropped or reordered fail to be opened.
+func envelopeNonce(aead cipher.AEAD, n uint64) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], n)
+
+	return nonce
+}
+
+// newEnvelopeAEAD returns the AES-GCM AEAD of key.
+func newEnvelopeAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// envelopeDownloadStream is a pb.Download_DownloadServer that encrypts the file bytes sent on it.
+type envelopeDownloadStream struct {
+	pb.Download_DownloadServer
+	aead   cipher.AEAD
+	chunks uint64
+}
+
+// newEnvelopeDownloadStream returns an envelopeDownloadStream of stream encrypting with a random key,
+// which it sets in the EnvelopeKeyHeader header encrypted by publicKey, a PKIX DER encoded RSA key.
+// It returns an InvalidArgument error if publicKey isn't an RSA key of at least MinEnvelopeKeyBits.
+func newEnvelopeDownloadStream(
+	stream pb.Download_DownloadServer,
+	publicKey []byte,
+) (*envelopeDownloadStream, error) {
+	parsed, err := x509.ParsePKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid envelope public key: %v", err)
+	}
+
+	rsaKey, ok := parsed.(*rsa.PublicKey)
+	if !ok || rsaKey.N.BitLen() < MinEnvelopeKeyBits {
+		return nil, status.Errorf(
+			codes.InvalidArgument,
+			"envelope public key must be an RSA key of at least %d bits",
+			MinEnvelopeKeyBits,
+		)
+	}
+
+	key := make([]byte, envelopeKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate envelope key: %v", err)
+	}
+
+	aead, err := newEnvelopeAEAD(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create envelope cipher: %v", err)
+	}
+
+	wrappedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, rsaKey, key, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt envelope key: %v", err)
+	}
+
+	if err := stream.SetHeader(metadata.Pairs(EnvelopeKeyHeader, string(wrappedKey))); err != nil {
+		return nil, err
+	}
+
+	return &envelopeDownloadStream{Download_DownloadServer: stream, aead: aead}, nil
+}
+
+// Send encrypts the file bytes of res, if it has any, and sends them with their nonce on the
+// underlying stream. Other messages are sent as is.
+func (s *envelopeDownloadStream) Send(res *pb.DownloadResponse) error {
+	file := res.GetFile()
+	if file == nil {
+		return s.Download_DownloadServer.Send(res)
+	}
+
+	nonce := envelopeNonce(s.aead, s.chunks)
+	s.chunks++
+
+	return s.Download_DownloadServer.Send(&pb.DownloadResponse{
+		Payload: &pb.DownloadResponse_File{File: s.aead.Seal(nil, nonce, file, nil)},
+		Nonce:   nonce,
+	})
+}
+
+// GenerateEnvelopeKey generates an RSA key of bits to envelope downloads with, and returns it
+// with its PKIX DER encoded public key, which is the envelope_public_key of the requests.
+func GenerateEnvelopeKey(bits int) (*rsa.PrivateKey, []byte, error) {
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return key, publicKey, nil
+}
+
+// EnvelopeDownloadClient is a pb.Download_DownloadClient that decrypts the file bytes of
+// a download enveloped by the public key of its private key.
+type EnvelopeDownloadClient struct {
+	pb.Download_DownloadClient
+	key    *rsa.PrivateKey
+	aead   cipher.AEAD
+	chunks uint64
+}
+
+// NewEnvelopeDownloadClient returns an EnvelopeDownloadClient of stream, a download requested
+// with the public key of key as its envelope_public_key.
+func NewEnvelopeDownloadClient(stream pb.Download_DownloadClient, key *rsa.PrivateKey) *EnvelopeDownloadClient {
+	return &EnvelopeDownloadClient{Download_DownloadClient: stream, key: key}
+}
+
+// Recv receives the next message of the stream and decrypts its file bytes, if it has any.
+// It returns a DataLoss error if the file bytes fail to be decrypted, e.g. since they were
+// tampered with, dropped or reordered.
+func (c *EnvelopeDownloadClient) Recv() (*pb.DownloadResponse, error) {
+	res, err := c.Download_DownloadClient.Recv()
+	if err != nil || res.GetFile() == nil {
+		return res, err
+	}
+
+	if c.aead == nil {
+		if c.aead, err = c.openKey(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Open the chunk with the expected nonce rather than the sent one, to detect reordering.
+	file, err := c.aead.Open(nil, envelopeNonce(c.aead, c.chunks), res.GetFile(), nil)
+	if err != nil {
+		return nil, status.Errorf(codes.DataLoss, "failed to decrypt chunk %d: %v", c.chunks, err)
+	}
+	c.chunks++
+
+	return &pb.DownloadResponse{Payload: &pb.DownloadResponse_File{File: file}}, nil
+}
+
+// openKey decrypts the stream's envelope key in its header and returns its AEAD.
+func (c *EnvelopeDownloadClient) openKey() (cipher.AEAD, error) {
+	header, err := c.Header()
+	if err != nil {
+		return nil, err
+	}
+
+	wrappedKeys := header.Get(EnvelopeKeyHeader)
+	if len(wrappedKeys) != 1 {
+		return nil, status.Error(codes.DataLoss, "download isn't enveloped")
+	}
+
+	key, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, c.key, []byte(wrappedKeys[0]), nil)
+	if err != nil {
+		return nil, status.Errorf(codes.DataLoss, "failed to decrypt envelope key: %v", err)
+	}
+
+	return newEnvelopeAEAD(key)
+}
@@ -0,0 +1,120 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"io"
+	"testing"
+
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDownloadService_DownloadEnvelope(t *testing.T) {
+	key, publicKey, err := download.GenerateEnvelopeKey(download.MinEnvelopeKeyBits)
+	if err != nil {
+		t.Fatalf("GenerateEnvelopeKey() error = %v", err)
+	}
+
+	client, closeClient := newServiceClient(t, download.NewService(s3Client, logger))
+	defer closeClient()
+
+	req := &pb.DownloadRequest{Key: testkey, Bucket: testbucket, EnvelopePublicKey: publicKey}
+
+	// The raw chunks are encrypted, each with its own nonce.
+	raw, err := client.Download(context.Background(), req)
+	if err != nil {
+		t.Fatalf("DownloadService.Download() error = %v", err)
+	}
+
+	nonces := make(map[string]bool)
+	var encrypted []byte
+	for {
+		chunk, err := raw.Recv()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Fatalf("DownloadService.Download() error = %v", err)
+		}
+
+		if nonces[string(chunk.GetNonce())] {
+			t.Fatalf("DownloadService.Download() reused nonce %x", chunk.GetNonce())
+		}
+		nonces[string(chunk.GetNonce())] = true
+		encrypted = append(encrypted, chunk.GetFile()...)
+	}
+
+	if bytes.Contains(encrypted, file[:1024]) {
+		t.Errorf("DownloadService.Download() sent the file's bytes in plaintext")
+	}
+
+	// The client decrypts them with the private key.
+	stream, err := client.Download(context.Background(), req)
+	if err != nil {
+		t.Fatalf("DownloadService.Download() error = %v", err)
+	}
+
+	got, err := recvAll(download.NewEnvelopeDownloadClient(stream, key))
+	if err != nil {
+		t.Fatalf("EnvelopeDownloadClient.Recv() error = %v", err)
+	}
+
+	if !bytes.Equal(got, file) {
+		t.Errorf("EnvelopeDownloadClient.Recv() file decrypted is different from the wanted file")
+	}
+
+	// Another key fails to decrypt them.
+	otherKey, _, err := download.GenerateEnvelopeKey(download.MinEnvelopeKeyBits)
+	if err != nil {
+		t.Fatalf("GenerateEnvelopeKey() error = %v", err)
+	}
+
+	stream, err = client.Download(context.Background(), req)
+	if err != nil {
+		t.Fatalf("DownloadService.Download() error = %v", err)
+	}
+
+	if _, err := recvAll(download.NewEnvelopeDownloadClient(stream, otherKey)); status.Code(err) != codes.DataLoss {
+		t.Errorf("EnvelopeDownloadClient.Recv() error = %v, want code %v", err, codes.DataLoss)
+	}
+}
+
+func TestDownloadService_DownloadEnvelopeInvalidKey(t *testing.T) {
+	weakKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+
+	weakPublicKey, err := x509.MarshalPKIXPublicKey(&weakKey.PublicKey)
+	if err != nil {
+		t.Fatalf("x509.MarshalPKIXPublicKey() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		publicKey []byte
+	}{
+		{name: "envelope - malformed key", publicKey: []byte("not a key")},
+		{name: "envelope - weak key", publicKey: weakPublicKey},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			service := download.NewService(s3Client, logger)
+			stream := &hashingDownloadStream{ctx: context.Background(), hash: sha256.New()}
+			req := &pb.DownloadRequest{Key: testkey, Bucket: testbucket, EnvelopePublicKey: tt.publicKey}
+			if err := service.Download(req, stream); status.Code(err) != codes.InvalidArgument {
+				t.Errorf("DownloadService.Download() error = %v, want code %v", err, codes.InvalidArgument)
+			}
+		})
+	}
+}
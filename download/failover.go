@@ -0,0 +1,175 @@
+package download
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// failoverContextKey is the context key of the failover of a download's bucket.
+type failoverContextKey struct{}
+
+// failover is the state of the failover of a download's calls to its bucket to the replicas of the bucket.
+type failover struct {
+	bucket   string
+	replicas []string
+
+	mu      sync.Mutex
+	replica string
+}
+
+// current returns the replica serving the calls to the bucket of f since its region failed,
+// empty while the bucket serves them.
+func (f *failover) current() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.replica
+}
+
+// failOver sets replica as the replica serving the calls to the bucket of f.
+func (f *failover) failOver(replica string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.replica = replica
+}
+
+// contextWithFailover returns ctx with the failover of the calls to bucket to its replicas in
+// s.FailoverBuckets, ctx as is if bucket has no replicas.
+func (s Service) contextWithFailover(ctx context.Context, bucket string) context.Context {
+	replicas := s.FailoverBuckets[bucket]
+	if len(replicas) == 0 {
+		return ctx
+	}
+
+	return context.WithValue(ctx, failoverContextKey{}, &failover{bucket: bucket, replicas: replicas})
+}
+
+// failoverFromContext returns the failover of the calls to bucket of the download of ctx,
+// nil if they don't fail over, e.g. the calls to a download's cache bucket.
+func failoverFromContext(ctx context.Context, bucket string) *failover {
+	f, ok := ctx.Value(failoverContextKey{}).(*failover)
+	if !ok || f.bucket != bucket {
+		return nil
+	}
+
+	return f
+}
+
+// callWithFailover calls call with bucket, or, if the region of bucket failed the call with
+// a server error, a failure to connect or a timeout, with the replicas of bucket in the failover
+// of ctx in order, until one of them serves it. The first replica that serves a call serves
+// the rest of the calls to bucket of the download of ctx. It returns the error of bucket
+// if none of its replicas serve the call.
+func (s Service) callWithFailover(ctx context.Context, bucket string, call func(bucket string) error) error {
+	f := failoverFromContext(ctx, bucket)
+	if f == nil {
+		return call(bucket)
+	}
+
+	if replica := f.current(); replica != "" {
+		return call(replica)
+	}
+
+	err := call(bucket)
+	if err == nil || !isTransientS3Error(err) {
+		return err
+	}
+
+	region := aws.StringValue(s.s3ClientFor(ctx, bucket).Config.Region)
+	for _, replica := range f.replicas {
+		if replicaErr := s.discoverRegion(ctx, replica); replicaErr != nil {
+			s.logger.Warnf("failed to discover the region of replica bucket %s of %s: %v", replica, bucket, replicaErr)
+			continue
+		}
+
+		replicaRegion := aws.StringValue(s.s3ClientFor(ctx, replica).Config.Region)
+		if replicaErr := call(replica); replicaErr != nil {
+			s.logger.Warnf("replica bucket %s of %s in region %s failed: %v", replica, bucket, replicaRegion, replicaErr)
+			continue
+		}
+
+		f.failOver(replica)
+		s.failovers.add(replicaRegion)
+		s.observeFailover(replicaRegion)
+		s.logger.Infof(
+			"bucket %s failed in region %s, served by replica bucket %s in region %s: %v",
+			bucket, region, replica, replicaRegion, err,
+		)
+
+		return nil
+	}
+
+	return err
+}
+
+// discoverRegion caches the region of bucket, by its GetBucketLocation, if it isn't known yet,
+// for s3ClientFor to return the S3 client of its region.
+func (s Service) discoverRegion(ctx context.Context, bucket string) error {
+	if s.regionClients == nil {
+		return nil
+	}
+
+	if _, ok := s.regionClients.region(bucket); ok {
+		return nil
+	}
+
+	location, err := s.s3ClientFor(ctx, bucket).GetBucketLocationWithContext(
+		ctx,
+		&s3.GetBucketLocationInput{Bucket: aws.String(bucket)},
+	)
+	if err != nil {
+		return err
+	}
+
+	region := s3.NormalizeBucketLocation(aws.StringValue(location.LocationConstraint))
+	s.regionClients.setRegion(bucket, region)
+
+	return nil
+}
+
+// regionCounts counts events by the region of S3 they occurred in.
+type regionCounts struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+// add counts an event in region. A nil regionCounts ignores it.
+func (c *regionCounts) add(region string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.counts == nil {
+		c.counts = make(map[string]int64)
+	}
+
+	c.counts[region]++
+}
+
+// snapshot returns the counts by region, and empties them if reset is true.
+func (c *regionCounts) snapshot(reset bool) map[string]int64 {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counts := make(map[string]int64, len(c.counts))
+	for region, count := range c.counts {
+		counts[region] = count
+	}
+
+	if reset {
+		c.counts = nil
+	}
+
+	return counts
+}
@@ -0,0 +1,151 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/corehandlers"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// regionOutageS3Client returns an S3 client whose calls to bucket fail with a server error, counting them,
+// simulating an outage of its region, and that locates every other bucket in replicaRegion.
+// Clients configured for other regions call S3 as is.
+func regionOutageS3Client(bucket string, replicaRegion string, calls *int64) *s3.S3 {
+	client := s3.New(session.Must(session.NewSession(&s3Client.Config)))
+	client.Handlers.Validate.PushBack(func(r *request.Request) {
+		if input, ok := r.Params.(*s3.HeadObjectInput); ok && aws.StringValue(input.Bucket) == bucket {
+			atomic.AddInt64(calls, 1)
+			r.Error = awserr.NewRequestFailure(
+				awserr.New("ServiceUnavailable", "injected region outage", nil),
+				http.StatusServiceUnavailable,
+				"",
+			)
+		}
+
+		if input, ok := r.Params.(*s3.GetObjectInput); ok && aws.StringValue(input.Bucket) == bucket {
+			atomic.AddInt64(calls, 1)
+			r.Error = awserr.New("RequestError", "injected region outage", nil)
+		}
+	})
+	client.Handlers.Send.Clear()
+	client.Handlers.Send.PushBack(func(r *request.Request) {
+		if _, ok := r.Params.(*s3.GetBucketLocationInput); !ok {
+			corehandlers.SendHandler.Fn(r)
+			return
+		}
+
+		location := "<LocationConstraint>" + replicaRegion + "</LocationConstraint>"
+		r.HTTPResponse = &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(strings.NewReader(location)),
+		}
+	})
+
+	return client
+}
+
+func TestDownloadService_DownloadRegionFailover(t *testing.T) {
+	const replicaBucket, replicaRegion = "replica", "eu-west-1"
+
+	if err := emptyAndDeleteBucket(replicaBucket); err != nil {
+		t.Logf("failed to emptyAndDeleteBucket, %v", err)
+	}
+
+	if _, err := s3Client.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String(replicaBucket)}); err != nil {
+		t.Fatalf("failed to create bucket %s, %v", replicaBucket, err)
+	}
+
+	if _, err := s3Client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(replicaBucket),
+		Key:    aws.String(testkey),
+		Body:   bytes.NewReader(file),
+	}); err != nil {
+		t.Fatalf("failed to upload %s, %v", testkey, err)
+	}
+
+	tests := []struct {
+		name          string
+		replicas      []string
+		wantErr       bool
+		wantFailovers int64
+	}{
+		{name: "failover - no replicas", wantErr: true},
+		{name: "failover - secondary region", replicas: []string{replicaBucket}, wantFailovers: 1},
+		{
+			name:          "failover - secondary region missing the object",
+			replicas:      []string{"missing-replica", replicaBucket},
+			wantFailovers: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			serviceLogger := logrus.New()
+			serviceLogger.SetOutput(ioutil.Discard)
+			hook := test.NewLocal(serviceLogger)
+
+			var primaryCalls int64
+			service := download.NewService(regionOutageS3Client(testbucket, replicaRegion, &primaryCalls), serviceLogger)
+			service.FailoverBuckets = map[string][]string{testbucket: tt.replicas}
+			service.PartSize = download.MinPartSize
+			metrics := &recordingMetrics{}
+			service.Metrics = metrics
+
+			stream := &hashingDownloadStream{ctx: context.Background(), hash: sha256.New()}
+			err := service.Download(&pb.DownloadRequest{Key: testkey, Bucket: testbucket}, stream)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DownloadService.Download() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			wantHash := sha256.Sum256(file)
+			if !tt.wantErr && !bytes.Equal(stream.hash.Sum(nil), wantHash[:]) {
+				t.Errorf("DownloadService.Download() file downloaded is different from the wanted file")
+			}
+
+			// The parts are downloaded from the replica once the download failed over to it.
+			if primaryCalls != 1 {
+				t.Errorf("DownloadService.Download() called the failed region %d times, want 1", primaryCalls)
+			}
+
+			stats, err := service.GetStats(context.Background(), &pb.GetStatsRequest{})
+			if err != nil {
+				t.Fatalf("DownloadService.GetStats() error = %v", err)
+			}
+
+			if got := stats.GetFailoversByRegion()[replicaRegion]; got != tt.wantFailovers {
+				t.Errorf("DownloadService.GetStats() failovers in %s = %d, want %d", replicaRegion, got, tt.wantFailovers)
+			}
+
+			if got := int64(len(metrics.failovers)); got != tt.wantFailovers {
+				t.Errorf("DownloadService.Download() observed %d failovers, want %d", got, tt.wantFailovers)
+			}
+
+			served := false
+			for _, entry := range hook.AllEntries() {
+				served = served || strings.Contains(entry.Message, "served by replica bucket "+replicaBucket+
+					" in region "+replicaRegion)
+			}
+
+			if served != (tt.wantFailovers > 0) {
+				t.Errorf("DownloadService.Download() logged the serving region = %v, want %v", served, !served)
+			}
+		})
+	}
+}
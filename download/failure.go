@@ -0,0 +1,30 @@
+package download
+
+import (
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/status"
+)
+
+// withBytesSent returns err as a status error, keeping its code, whose details hold
+// the number of bytes sent before the download failed, so the client can resume from it.
+func withBytesSent(err error, bytesSent int64) error {
+	st := status.Convert(err)
+	withDetails, detailsErr := st.WithDetails(&pb.DownloadFailure{BytesSent: bytesSent})
+	if detailsErr != nil {
+		return err
+	}
+
+	return withDetails.Err()
+}
+
+// BytesSentFromError returns the number of bytes a failed download sent before it failed,
+// which the client received and may resume from, and whether err reports it.
+func BytesSentFromError(err error) (int64, bool) {
+	for _, detail := range status.Convert(err).Details() {
+		if failure, ok := detail.(*pb.DownloadFailure); ok {
+			return failure.GetBytesSent(), true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,129 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// midStreamFailingS3Client returns an S3 client whose GetObject calls fail after the first succeeded calls.
+func midStreamFailingS3Client(succeeded int) *s3.S3 {
+	var mu sync.Mutex
+	calls := 0
+	client := s3.New(session.Must(session.NewSession(&s3Client.Config)))
+	client.Handlers.Validate.PushBack(func(r *request.Request) {
+		if _, ok := r.Params.(*s3.GetObjectInput); !ok {
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls > succeeded {
+			r.Error = awserr.New("InternalError", "injected mid-stream failure", nil)
+		}
+	})
+
+	return client
+}
+
+func TestDownloadService_DownloadFailureBytesSent(t *testing.T) {
+	const failureKey = "failure.txt"
+
+	// Upload a fixture of a few parts.
+	failureFile := make([]byte, 2*download.PartSize+(1<<20))
+	if _, err := rand.Read(failureFile); err != nil {
+		t.Fatalf("failed to generate file, %v", err)
+	}
+
+	if _, err := s3Client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(testbucket),
+		Key:    aws.String(failureKey),
+		Body:   bytes.NewReader(failureFile),
+	}); err != nil {
+		t.Fatalf("failed to upload %s, %v", failureKey, err)
+	}
+
+	tests := []struct {
+		name          string
+		succeeded     int
+		rangeStart    int64
+		wantBytesSent int64
+	}{
+		{name: "failure bytes sent - first part", succeeded: 0, wantBytesSent: 0},
+		{name: "failure bytes sent - mid-stream", succeeded: 2, wantBytesSent: 2 * download.PartSize},
+		{name: "failure bytes sent - range", succeeded: 1, rangeStart: 100, wantBytesSent: download.PartSize},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			service := download.NewService(midStreamFailingS3Client(tt.succeeded), logger)
+			service.MaxBufferSize = 1 << 20
+
+			client, closeClient := newServiceClient(t, service)
+			defer closeClient()
+
+			stream, err := client.Download(
+				context.Background(),
+				&pb.DownloadRequest{Key: failureKey, Bucket: testbucket, RangeStart: tt.rangeStart},
+			)
+			if err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			// Read the stream until it fails, keeping every byte received.
+			reader := download.NewStreamReadCloser(stream)
+			defer reader.Close()
+
+			var received []byte
+			p := make([]byte, download.PartSize)
+			for {
+				n, err := reader.Read(p)
+				received = append(received, p[:n]...)
+				if err == io.EOF {
+					t.Fatalf("DownloadService.Download() succeeded, want a mid-stream failure")
+				}
+
+				if err != nil {
+					if status.Code(err) != codes.Unknown {
+						t.Errorf("DownloadService.Download() error = %v, wantCode %v", err, codes.Unknown)
+					}
+
+					bytesSent, ok := download.BytesSentFromError(err)
+					if !ok {
+						t.Fatalf("BytesSentFromError() didn't find the bytes sent in %v", err)
+					}
+
+					if bytesSent != tt.wantBytesSent {
+						t.Errorf("BytesSentFromError() = %d, want %d", bytesSent, tt.wantBytesSent)
+					}
+
+					break
+				}
+			}
+
+			// The bytes received before the failure are valid to resume from.
+			if int64(len(received)) != tt.wantBytesSent {
+				t.Fatalf("received %d bytes before the failure, want %d", len(received), tt.wantBytesSent)
+			}
+
+			if !bytes.Equal(received, failureFile[tt.rangeStart:tt.rangeStart+tt.wantBytesSent]) {
+				t.Errorf("bytes received before the failure are different from the wanted file")
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	pb "github.com/meateam/download-service/proto"
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// sendRemainder sends the bytes of remainder of bucket/key to stream from a single
+// non-ranged GetObject, for S3 servers that mishandle ranged GetObject calls.
+// The bytes of the object before remainder are read and discarded.
+// It returns the number of bytes sent.
+func (s Service) sendRemainder(
+	ctx context.Context,
+	stream pb.Download_DownloadServer,
+	bucket string,
+	key string,
+	remainder byteRange,
+	buffer []byte,
+	currentPart int64,
+	order *orderAssertion,
+	keyPrefix string,
+) (int64, error) {
+	span := s.startClientSpan(ctx, "s3.GetObject", opentracing.Tags{"s3.bucket": bucket, "s3.key": key})
+	object, err := s.s3ClientFor(ctx, bucket).GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket:    aws.String(bucket),
+		Key:       aws.String(key),
+		VersionId: versionIDFromContext(ctx),
+	})
+	if err != nil {
+		finishSpan(span, err)
+		return 0, fmt.Errorf("failed to download object %s/%s: %v", bucket, key, err)
+	}
+	defer object.Body.Close()
+
+	if _, err := io.CopyN(ioutil.Discard, object.Body, remainder.start); err != nil {
+		err = fmt.Errorf("failed to skip to offset %d of object %s/%s: %v", remainder.start, bucket, key, err)
+		finishSpan(span, err)
+		return 0, err
+	}
+
+	sent, err := s.sendPart(
+		stream,
+		io.LimitReader(object.Body, remainder.length()),
+		buffer,
+		currentPart,
+		remainder.start,
+		order,
+		keyPrefix,
+	)
+	if err == nil && sent < remainder.length() {
+		err = fmt.Errorf("object %s/%s ended after %d of %d bytes", bucket, key, remainder.start+sent, remainder.end+1)
+	}
+	finishSpan(span, err)
+
+	return sent, err
+}
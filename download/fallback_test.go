@@ -0,0 +1,134 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// rangeFailingS3Client returns an S3 client whose first failures ranged GetObject calls fail,
+// and counters of its ranged and non-ranged GetObject calls.
+func rangeFailingS3Client(t *testing.T, failures int) (*s3.S3, func() (ranged int, nonRanged int)) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var ranged, nonRanged int
+	client := s3.New(session.Must(session.NewSession(&s3Client.Config)))
+	client.Handlers.Validate.PushBack(func(r *request.Request) {
+		input, ok := r.Params.(*s3.GetObjectInput)
+		if !ok {
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if input.Range == nil {
+			nonRanged++
+			return
+		}
+
+		ranged++
+		if ranged <= failures {
+			r.Error = awserr.New("InvalidRange", "injected range failure", nil)
+		}
+	})
+
+	return client, func() (int, int) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return ranged, nonRanged
+	}
+}
+
+func TestDownloadService_DownloadRangeFallback(t *testing.T) {
+	tests := []struct {
+		name          string
+		threshold     int
+		failures      int
+		rangeStart    int64
+		wantCode      codes.Code
+		wantRanged    int
+		wantNonRanged int
+	}{
+		{
+			name:       "range fallback - disabled",
+			threshold:  0,
+			failures:   1,
+			wantCode:   codes.Unknown,
+			wantRanged: 1,
+		},
+		{
+			name:       "range fallback - retried below threshold",
+			threshold:  3,
+			failures:   2,
+			wantCode:   codes.OK,
+			wantRanged: 3,
+		},
+		{
+			name:          "range fallback - downgraded at threshold",
+			threshold:     3,
+			failures:      3,
+			wantCode:      codes.OK,
+			wantRanged:    3,
+			wantNonRanged: 1,
+		},
+		{
+			name:          "range fallback - downgraded from offset",
+			threshold:     1,
+			failures:      1,
+			rangeStart:    1<<20 + 7,
+			wantCode:      codes.OK,
+			wantRanged:    1,
+			wantNonRanged: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			failingClient, calls := rangeFailingS3Client(t, tt.failures)
+			service := download.NewService(failingClient, logger)
+			service.RangeFallbackThreshold = tt.threshold
+			service.StrictOrderAssert = true
+
+			client, closeClient := newServiceClient(t, service)
+			defer closeClient()
+
+			stream, err := client.Download(context.Background(), &pb.DownloadRequest{
+				Key:        testkey,
+				Bucket:     testbucket,
+				RangeStart: tt.rangeStart,
+			})
+			if err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			got, err := recvAll(stream)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("DownloadService.Download() error = %v, wantCode %v", err, tt.wantCode)
+			}
+
+			if tt.wantCode == codes.OK && !bytes.Equal(got, file[tt.rangeStart:]) {
+				t.Errorf("DownloadService.Download() file downloaded is different from the wanted file")
+			}
+
+			if ranged, nonRanged := calls(); ranged != tt.wantRanged || nonRanged != tt.wantNonRanged {
+				t.Errorf(
+					"GetObject calls = %d ranged, %d non-ranged, want %d, %d",
+					ranged, nonRanged, tt.wantRanged, tt.wantNonRanged,
+				)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// fetchedChunk is a chunk of a part fetched from S3, or the error fetching it.
+type fetchedChunk struct {
+	data []byte
+	last bool
+	err  error
+}
+
+// fetchBuffer fetches the parts of a download from S3, in order, in chunks, up to a bounded number
+// of chunks ahead of the chunk being sent, decoupling the rate of fetching from the rate of sending.
+type fetchBuffer struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	chunks chan fetchedChunk
+	free   chan []byte
+	done   chan struct{}
+}
+
+// fetchParts starts fetching the totalParts parts of objectRange of bucket/key in chunks of
+// chunkSize bytes, up to depth chunks ahead of the chunk being sent.
+// The returned buffer must be closed to stop fetching.
+func (s Service) fetchParts(
+	ctx context.Context,
+	bucket string,
+	key string,
+	objectRange byteRange,
+	partSize int64,
+	alignParts bool,
+	totalParts int64,
+	chunkSize int,
+	depth int,
+) *fetchBuffer {
+	ctx, cancel := context.WithCancel(ctx)
+	b := &fetchBuffer{
+		ctx:    ctx,
+		cancel: cancel,
+		chunks: make(chan fetchedChunk, depth),
+		free:   make(chan []byte, depth+2),
+		done:   make(chan struct{}),
+	}
+
+	// A chunk is either buffered, being fetched or being sent.
+	for i := 0; i < depth+2; i++ {
+		b.free <- make([]byte, chunkSize)
+	}
+
+	go func() {
+		defer close(b.done)
+		defer close(b.chunks)
+
+		for currentPart := int64(0); currentPart < totalParts; currentPart++ {
+			partRange := objectRange.part(currentPart, partSize, alignParts)
+			if err := s.fetchPart(ctx, b, bucket, key, partRange, currentPart); err != nil {
+				b.push(fetchedChunk{err: err})
+				return
+			}
+		}
+	}()
+
+	return b
+}
+
+// fetchPart fetches partRange, the part number currentPart of bucket/key, into b's chunks.
+func (s Service) fetchPart(
+	ctx context.Context,
+	b *fetchBuffer,
+	bucket string,
+	key string,
+	partRange byteRange,
+	currentPart int64,
+) (err error) {
+	getObjectInput := &s3.GetObjectInput{
+		Key:        aws.String(key),
+		Bucket:     aws.String(bucket),
+		PartNumber: aws.Int64(currentPart),
+		Range:      aws.String(fmt.Sprintf("bytes=%d-%d", partRange.start, partRange.end)),
+	}
+
+	span := s.startClientSpan(ctx, "s3.GetObject", opentracing.Tags{
+		"s3.bucket": bucket,
+		"s3.key":    key,
+		"s3.range":  aws.StringValue(getObjectInput.Range),
+	})
+	defer func() {
+		finishSpan(span, err)
+	}()
+
+	objectPartOutput, err := s.getPartObject(ctx, bucket, getObjectInput)
+	if err != nil {
+		return err
+	}
+	defer objectPartOutput.Body.Close()
+
+	for {
+		var buffer []byte
+		select {
+		case buffer = <-b.free:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		n, readErr := io.ReadFull(objectPartOutput.Body, buffer)
+		last := readErr == io.EOF || readErr == io.ErrUnexpectedEOF
+		if readErr != nil && !last {
+			return readErr
+		}
+
+		if !b.push(fetchedChunk{data: buffer[:n], last: last}) {
+			return ctx.Err()
+		}
+
+		if last {
+			return nil
+		}
+	}
+}
+
+// push buffers chunk to be sent, and returns false if b was closed first.
+func (b *fetchBuffer) push(chunk fetchedChunk) bool {
+	select {
+	case b.chunks <- chunk:
+		return true
+	case <-b.ctx.Done():
+		return false
+	}
+}
+
+// next returns a reader of the next fetched part.
+// It returns the error of fetching the part, after which the buffer must be closed.
+func (b *fetchBuffer) next() (io.ReadCloser, error) {
+	chunk, err := b.pull()
+	if err != nil {
+		return nil, err
+	}
+
+	return &fetchedPart{buffer: b, chunk: chunk}, nil
+}
+
+// pull returns the next fetched chunk, or the error of fetching it.
+func (b *fetchBuffer) pull() (fetchedChunk, error) {
+	chunk, ok := <-b.chunks
+	if !ok {
+		if err := b.ctx.Err(); err != nil {
+			return fetchedChunk{}, err
+		}
+
+		return fetchedChunk{}, fmt.Errorf("no more fetched parts")
+	}
+
+	return chunk, chunk.err
+}
+
+// close stops fetching, dropping the fetched chunks that weren't read.
+func (b *fetchBuffer) close() {
+	b.cancel()
+	<-b.done
+}
+
+// fetchedPart is an io.ReadCloser of the chunks of a part in a fetchBuffer.
+type fetchedPart struct {
+	buffer *fetchBuffer
+	chunk  fetchedChunk
+	read   int
+}
+
+// Read reads the part's chunks into p, freeing each chunk once it's read.
+func (p *fetchedPart) Read(b []byte) (int, error) {
+	for p.read == len(p.chunk.data) {
+		if p.chunk.last {
+			return 0, io.EOF
+		}
+
+		p.release()
+
+		chunk, err := p.buffer.pull()
+		if err != nil {
+			return 0, err
+		}
+
+		p.chunk, p.read = chunk, 0
+	}
+
+	n := copy(b, p.chunk.data[p.read:])
+	p.read += n
+
+	return n, nil
+}
+
+// Close frees the chunk being read.
+func (p *fetchedPart) Close() error {
+	p.release()
+
+	return nil
+}
+
+// release returns the chunk being read to the free chunks.
+func (p *fetchedPart) release() {
+	if p.chunk.data != nil {
+		p.buffer.free <- p.chunk.data[:cap(p.chunk.data)]
+		p.chunk.data = nil
+	}
+}
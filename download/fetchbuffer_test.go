@@ -0,0 +1,170 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const fetchBufferKey = "fetchbuffer.txt"
+
+// slowDownloadStream is a pb.Download_DownloadServer that keeps the bytes sent on it,
+// delaying every send by sendDelay and failing the send number failAt, if set.
+type slowDownloadStream struct {
+	grpc.ServerStream
+	sendDelay time.Duration
+	failAt    int
+	sends     int
+	received  bytes.Buffer
+}
+
+func (s *slowDownloadStream) Context() context.Context {
+	return context.Background()
+}
+
+func (s *slowDownloadStream) SetTrailer(metadata.MD) {}
+
+func (s *slowDownloadStream) Send(res *pb.DownloadResponse) error {
+	time.Sleep(s.sendDelay)
+
+	s.sends++
+	if s.sends == s.failAt {
+		return fmt.Errorf("send failed")
+	}
+
+	_, err := s.received.Write(res.GetFile())
+	return err
+}
+
+// slowGetObjectS3Client returns an S3 client whose GetObject calls are delayed by delay.
+func slowGetObjectS3Client(delay time.Duration) *s3.S3 {
+	client := s3.New(session.Must(session.NewSession(&s3Client.Config)))
+	client.Handlers.Validate.PushBack(func(r *request.Request) {
+		if _, ok := r.Params.(*s3.GetObjectInput); ok {
+			time.Sleep(delay)
+		}
+	})
+
+	return client
+}
+
+// uploadFetchBufferFile uploads a fixture of a few parts, the last one partial, and returns it.
+func uploadFetchBufferFile(tb testing.TB) []byte {
+	tb.Helper()
+
+	fetchBufferFile := make([]byte, 3*download.PartSize+(1<<20))
+	if _, err := rand.Read(fetchBufferFile); err != nil {
+		tb.Fatalf("failed to generate file, %v", err)
+	}
+
+	if _, err := s3Client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(testbucket),
+		Key:    aws.String(fetchBufferKey),
+		Body:   bytes.NewReader(fetchBufferFile),
+	}); err != nil {
+		tb.Fatalf("failed to upload %s, %v", fetchBufferKey, err)
+	}
+
+	return fetchBufferFile
+}
+
+func TestDownloadService_DownloadFetchBuffer(t *testing.T) {
+	fetchBufferFile := uploadFetchBufferFile(t)
+
+	tests := []struct {
+		name          string
+		depth         int
+		s3Client      *s3.S3
+		failAt        int
+		wantErr       bool
+		wantBytesSent int
+	}{
+		{name: "fetch buffer - single chunk", depth: 1, s3Client: s3Client, wantBytesSent: len(fetchBufferFile)},
+		{name: "fetch buffer - many chunks", depth: 16, s3Client: s3Client, wantBytesSent: len(fetchBufferFile)},
+		{
+			name:          "fetch buffer - fetch failure",
+			depth:         16,
+			s3Client:      midStreamFailingS3Client(2),
+			wantErr:       true,
+			wantBytesSent: 2 * download.PartSize,
+		},
+		{
+			name:          "fetch buffer - send failure",
+			depth:         16,
+			s3Client:      s3Client,
+			failAt:        3,
+			wantErr:       true,
+			wantBytesSent: 2 << 20,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			service := download.NewService(tt.s3Client, logger)
+			service.MaxBufferSize = 1 << 20
+			service.FetchBufferDepth = tt.depth
+			stream := &slowDownloadStream{failAt: tt.failAt}
+
+			err := service.Download(&pb.DownloadRequest{Key: fetchBufferKey, Bucket: testbucket}, stream)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DownloadService.Download() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if stream.received.Len() != tt.wantBytesSent {
+				t.Errorf("DownloadService.Download() sent %d bytes, want %d", stream.received.Len(), tt.wantBytesSent)
+			}
+
+			if !bytes.Equal(stream.received.Bytes(), fetchBufferFile[:stream.received.Len()]) {
+				t.Errorf("DownloadService.Download() file downloaded is different from the wanted file")
+			}
+		})
+	}
+}
+
+func BenchmarkDownloadService_DownloadFetchBuffer(b *testing.B) {
+	const (
+		getObjectDelay = 20 * time.Millisecond
+		sendDelay      = time.Millisecond
+	)
+
+	fetchBufferFile := uploadFetchBufferFile(b)
+
+	benchmarks := []struct {
+		name  string
+		depth int
+	}{
+		{name: "lockstep", depth: 0},
+		{name: "buffered", depth: 32},
+	}
+
+	for _, bm := range benchmarks {
+		bm := bm
+		b.Run(bm.name, func(b *testing.B) {
+			service := download.NewService(slowGetObjectS3Client(getObjectDelay), logger)
+			service.MaxBufferSize = 256 << 10
+			service.FetchBufferDepth = bm.depth
+
+			b.SetBytes(int64(len(fetchBufferFile)))
+			for i := 0; i < b.N; i++ {
+				stream := &slowDownloadStream{sendDelay: sendDelay}
+				req := &pb.DownloadRequest{Key: fetchBufferKey, Bucket: testbucket}
+				if err := service.Download(req, stream); err != nil {
+					b.Fatalf("DownloadService.Download() error = %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// DefaultFollowPollInterval is the default interval at which followed objects are checked for new bytes.
+	DefaultFollowPollInterval = time.Second
+
+	// DefaultFollowIdleTimeout is the default time without new bytes after which following an object ends.
+	DefaultFollowIdleTimeout = time.Minute
+
+	// followTailSize is the number of the last bytes sent of a followed object that are compared
+	// with the object once its ETag changed, to tell an append from a replacement.
+	followTailSize = 4 << 10
+)
+
+// prepareFollow keeps the last bytes sent on the stream of d if req follows its object. It returns
+// an InvalidArgument error if req follows its object with a range end, a range percent, in reverse
+// or at a version, since following appends the bytes after the object's current end.
+func prepareFollow(req *pb.DownloadRequest, d *partDownload) error {
+	if !req.GetFollow() {
+		return nil
+	}
+
+	if req.GetRangeEnd() != 0 || req.GetRangePercent() != nil || req.GetReverse() || req.GetVersionId() != "" {
+		return status.Error(
+			codes.InvalidArgument,
+			"follow can't be combined with range end, range percent, reverse or version id",
+		)
+	}
+
+	d.tail = &tailDownloadStream{Download_DownloadServer: d.stream}
+	d.stream = d.tail
+
+	return nil
+}
+
+// tailDownloadStream is a pb.Download_DownloadServer that keeps the last followTailSize
+// file bytes sent on it.
+type tailDownloadStream struct {
+	pb.Download_DownloadServer
+	tail []byte
+}
+
+// Send sends res on the underlying stream and keeps the last of its file bytes.
+func (s *tailDownloadStream) Send(res *pb.DownloadResponse) error {
+	if err := s.Download_DownloadServer.Send(res); err != nil {
+		return err
+	}
+
+	s.tail = append(s.tail, res.GetFile()...)
+	if excess := len(s.tail) - followTailSize; excess > 0 {
+		s.tail = append(s.tail[:0], s.tail[excess:]...)
+	}
+
+	return nil
+}
+
+// follow streams the bytes appended to the object of d after the bytes already sent, polling its
+// size every s.FollowPollInterval, until no bytes were appended for s.FollowIdleTimeout or ctx is done.
+// It returns a FailedPrecondition error if the object was replaced rather than appended to, either
+// shrinking or changing the bytes already sent.
+func (s Service) follow(ctx context.Context, d *partDownload) error {
+	pollInterval := s.FollowPollInterval
+	if pollInterval <= 0 {
+		pollInterval = DefaultFollowPollInterval
+	}
+
+	idleTimeout := s.FollowIdleTimeout
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultFollowIdleTimeout
+	}
+
+	// The appended bytes are fetched directly, the parts fetched ahead were all sent.
+	d.closeSpill()
+	d.closePrefetch()
+	d.closeFetch()
+	d.alignParts = false
+
+	lastAppend := time.Now()
+	for time.Since(lastAppend) < idleTimeout {
+		if err := sleepContext(ctx, pollInterval); err != nil {
+			return err
+		}
+
+		head, err := s.fetchHead(ctx, d.bucket, d.key)
+		if err != nil {
+			return fmt.Errorf("failed to follow object %s/%s: %v", d.bucket, d.key, err)
+		}
+
+		appended, err := s.checkAppended(ctx, d, head)
+		if err != nil {
+			return err
+		}
+
+		if !appended {
+			continue
+		}
+
+		d.objectRange = byteRange{start: d.objectRange.end + 1, end: aws.Int64Value(head.ContentLength) - 1}
+		d.totalParts = d.objectRange.parts(d.partSize, false)
+		if err := s.sendParts(ctx, d); err != nil {
+			return err
+		}
+
+		lastAppend = time.Now()
+	}
+
+	return nil
+}
+
+// checkAppended returns whether bytes were appended to the object of d, whose current state is head,
+// after the bytes already sent, and a FailedPrecondition error if the object was replaced instead.
+func (s Service) checkAppended(ctx context.Context, d *partDownload, head *s3.HeadObjectOutput) (bool, error) {
+	size := aws.Int64Value(head.ContentLength)
+	sent := d.objectRange.end + 1
+	if size < sent {
+		return false, status.Errorf(codes.FailedPrecondition, "followed object %s/%s shrank", d.bucket, d.key)
+	}
+
+	etag := aws.StringValue(head.ETag)
+	if etag == d.etag || len(d.tail.tail) == 0 {
+		d.etag = etag
+		return size > sent, nil
+	}
+
+	// Every write changes the ETag, so compare the last bytes sent to tell an append from a replacement.
+	tailStart := sent - int64(len(d.tail.tail))
+	output, err := s.s3ClientFor(ctx, d.bucket).GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(d.bucket),
+		Key:    aws.String(d.key),
+		Range:  aws.String(fmt.Sprintf("bytes=%d-%d", tailStart, sent-1)),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to follow object %s/%s: %v", d.bucket, d.key, err)
+	}
+	defer output.Body.Close()
+
+	tail, err := ioutil.ReadAll(output.Body)
+	if err != nil {
+		return false, fmt.Errorf("failed to follow object %s/%s: %v", d.bucket, d.key, err)
+	}
+
+	if !bytes.Equal(tail, d.tail.tail) {
+		return false, status.Errorf(codes.FailedPrecondition, "followed object %s/%s was replaced", d.bucket, d.key)
+	}
+
+	d.etag = etag
+
+	return size > sent, nil
+}
@@ -0,0 +1,137 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// putFollowedObject uploads data as key to testbucket.
+func putFollowedObject(t *testing.T, key string, data []byte) {
+	t.Helper()
+
+	if _, err := s3Client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(testbucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(data),
+	}); err != nil {
+		t.Fatalf("failed to upload %s, %v", key, err)
+	}
+}
+
+// recvAtLeast receives chunks from stream until it received at least n bytes, and returns them.
+func recvAtLeast(stream pb.Download_DownloadClient, n int) ([]byte, error) {
+	var received []byte
+	for len(received) < n {
+		chunk, err := stream.Recv()
+		if err != nil {
+			return received, err
+		}
+
+		received = append(received, chunk.GetFile()...)
+	}
+
+	return received, nil
+}
+
+func TestDownloadService_DownloadFollow(t *testing.T) {
+	const followKey = "follow.log"
+
+	initial := make([]byte, 1<<20)
+	if _, err := rand.Read(initial); err != nil {
+		t.Fatalf("failed to generate file, %v", err)
+	}
+
+	appended := append(append([]byte{}, initial...), bytes.Repeat([]byte("appended line\n"), 1<<15)...)
+	replaced := append(bytes.Repeat([]byte{'x'}, len(initial)), appended[len(initial):]...)
+
+	tests := []struct {
+		name     string
+		req      *pb.DownloadRequest
+		next     []byte
+		want     []byte
+		wantCode codes.Code
+	}{
+		{name: "follow - appended", req: &pb.DownloadRequest{Follow: true}, next: appended, want: appended},
+		{
+			name: "follow - appended after range start",
+			req:  &pb.DownloadRequest{Follow: true, RangeStart: 1 << 19},
+			next: appended,
+			want: appended[1<<19:],
+		},
+		{name: "follow - idle", req: &pb.DownloadRequest{Follow: true}, want: initial},
+		{
+			name:     "follow - replaced",
+			req:      &pb.DownloadRequest{Follow: true},
+			next:     replaced,
+			want:     initial,
+			wantCode: codes.FailedPrecondition,
+		},
+		{
+			name:     "follow - shrank",
+			req:      &pb.DownloadRequest{Follow: true},
+			next:     initial[:1<<10],
+			want:     initial,
+			wantCode: codes.FailedPrecondition,
+		},
+		{
+			name:     "follow - reverse",
+			req:      &pb.DownloadRequest{Follow: true, Reverse: true},
+			wantCode: codes.InvalidArgument,
+		},
+	}
+
+	service := download.NewService(s3Client, logger)
+	service.FollowPollInterval = 20 * time.Millisecond
+	service.FollowIdleTimeout = 300 * time.Millisecond
+
+	client, closeClient := newServiceClient(t, service)
+	defer closeClient()
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			putFollowedObject(t, followKey, initial)
+
+			tt.req.Key, tt.req.Bucket = followKey, testbucket
+			stream, err := client.Download(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			// Change the object once its current bytes were received.
+			got, err := recvAtLeast(stream, len(initial)-int(tt.req.GetRangeStart()))
+			if err == nil && tt.next != nil {
+				putFollowedObject(t, followKey, tt.next)
+			}
+
+			if err == nil {
+				var rest []byte
+				rest, err = recvAll(stream)
+				got = append(got, rest...)
+			}
+
+			if err == io.EOF {
+				err = nil
+			}
+
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("DownloadService.Download() error = %v, wantCode %v", err, tt.wantCode)
+			}
+
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("DownloadService.Download() downloaded %d bytes, want %d", len(got), len(tt.want))
+			}
+		})
+	}
+}
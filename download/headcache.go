@@ -0,0 +1,358 @@
+package download
+
+import (
+	"container/list"
+	"context"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultWarmConcurrency is the default number of HeadObject calls made concurrently
+	// while warming a HeadCache.
+	DefaultWarmConcurrency = 8
+)
+
+// headCacheKey is the key of an object's entry in a HeadCache.
+type headCacheKey struct {
+	bucket string
+	key    string
+}
+
+// headCacheEntry is a cached HeadObject result and the time it expires at.
+type headCacheEntry struct {
+	cacheKey  headCacheKey
+	head      *s3.HeadObjectOutput
+	expiresAt time.Time
+}
+
+// headCacheShard is a shard of a HeadCache, holding its entries from the most to the least recently used.
+type headCacheShard struct {
+	mu         sync.Mutex
+	maxEntries int
+	recency    *list.List
+	entries    map[headCacheKey]*list.Element
+}
+
+// HeadCache caches the HeadObject results of objects for a TTL,
+// sparing the HeadObject call of repeated downloads of the same object.
+// An object that changes within the TTL is downloaded with its stale length and validators.
+// The TTL can be overridden per bucket, for buckets whose objects change more or less often.
+// The entries are split across shards by the hash of their bucket and key, each with its own
+// lock, and each shard evicts its least recently used entries once it's full.
+// Expired entries can be kept for a max staleness, to be served while S3 fails.
+type HeadCache struct {
+	ttl        time.Duration
+	bucketTTLs map[string]time.Duration
+	maxStale   time.Duration
+	shards     []*headCacheShard
+	hits       int64
+	misses     int64
+}
+
+// NewHeadCache creates a single-shard unbounded HeadCache whose entries expire ttl after they're set.
+func NewHeadCache(ttl time.Duration) *HeadCache {
+	return NewShardedHeadCache(ttl, 1, 0)
+}
+
+// NewShardedHeadCache creates a HeadCache of shards shards whose entries expire ttl after they're set.
+// The cache holds up to maxEntries entries, split evenly across the shards, zero leaves it unbounded.
+func NewShardedHeadCache(ttl time.Duration, shards int, maxEntries int) *HeadCache {
+	if shards < 1 {
+		shards = 1
+	}
+
+	// Round up so that every shard holds at least a single entry.
+	maxShardEntries := 0
+	if maxEntries > 0 {
+		maxShardEntries = (maxEntries + shards - 1) / shards
+	}
+
+	c := &HeadCache{ttl: ttl, shards: make([]*headCacheShard, shards)}
+	for i := range c.shards {
+		c.shards[i] = &headCacheShard{
+			maxEntries: maxShardEntries,
+			recency:    list.New(),
+			entries:    make(map[headCacheKey]*list.Element),
+		}
+	}
+
+	return c
+}
+
+// SetBucketTTLs overrides the TTL of the entries of the buckets of ttls, the entries of other buckets
+// expire after the TTL c was created with. A non-positive TTL disables caching the bucket's entries.
+// It must be called before c is used.
+func (c *HeadCache) SetBucketTTLs(ttls map[string]time.Duration) {
+	c.bucketTTLs = make(map[string]time.Duration, len(ttls))
+	for bucket, ttl := range ttls {
+		c.bucketTTLs[bucket] = ttl
+	}
+}
+
+// SetMaxStaleness keeps the entries of c for maxStale after they expire, to be returned by GetStale.
+// Get never returns expired entries. It must be called before c is used.
+func (c *HeadCache) SetMaxStaleness(maxStale time.Duration) {
+	c.maxStale = maxStale
+}
+
+// bucketTTL returns the TTL of the entries of bucket.
+func (c *HeadCache) bucketTTL(bucket string) time.Duration {
+	if ttl, ok := c.bucketTTLs[bucket]; ok {
+		return ttl
+	}
+
+	return c.ttl
+}
+
+// shard returns the shard holding the entry of cacheKey, by the FNV-1a hash of its bucket and key.
+func (c *HeadCache) shard(cacheKey headCacheKey) *headCacheShard {
+	if len(c.shards) == 1 {
+		return c.shards[0]
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(cacheKey.bucket))
+	h.Write([]byte{'/'})
+	h.Write([]byte(cacheKey.key))
+
+	return c.shards[h.Sum32()%uint32(len(c.shards))]
+}
+
+// Get returns the cached HeadObject result of bucket/key, and whether it was found and not expired.
+func (c *HeadCache) Get(bucket string, key string) (*s3.HeadObjectOutput, bool) {
+	cacheKey := headCacheKey{bucket: bucket, key: key}
+	shard := c.shard(cacheKey)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	element, ok := shard.entries[cacheKey]
+	if ok && time.Now().After(element.Value.(headCacheEntry).expiresAt) {
+		// Keep the expired entry until its max staleness passes.
+		if time.Now().After(element.Value.(headCacheEntry).expiresAt.Add(c.maxStale)) {
+			shard.remove(element)
+		}
+		ok = false
+	}
+
+	if !ok {
+		atomic.AddInt64(&c.misses, 1)
+		return nil, false
+	}
+
+	atomic.AddInt64(&c.hits, 1)
+	shard.recency.MoveToFront(element)
+
+	return element.Value.(headCacheEntry).head, true
+}
+
+// GetStale returns the cached HeadObject result of bucket/key, and whether it was found and expired
+// up to the max staleness of c ago, for serving it while S3 fails. Fresh entries are returned by Get.
+func (c *HeadCache) GetStale(bucket string, key string) (*s3.HeadObjectOutput, bool) {
+	cacheKey := headCacheKey{bucket: bucket, key: key}
+	shard := c.shard(cacheKey)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	element, ok := shard.entries[cacheKey]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(headCacheEntry)
+	if now := time.Now(); !now.After(entry.expiresAt) || now.After(entry.expiresAt.Add(c.maxStale)) {
+		return nil, false
+	}
+
+	return entry.head, true
+}
+
+// Set caches head as the HeadObject result of bucket/key for the TTL of bucket,
+// evicting the least recently used entry of its shard if it's full.
+// Nothing is cached if the TTL of bucket isn't positive.
+func (c *HeadCache) Set(bucket string, key string, head *s3.HeadObjectOutput) {
+	ttl := c.bucketTTL(bucket)
+	if ttl <= 0 {
+		return
+	}
+
+	cacheKey := headCacheKey{bucket: bucket, key: key}
+	shard := c.shard(cacheKey)
+	entry := headCacheEntry{
+		cacheKey:  cacheKey,
+		head:      head,
+		expiresAt: time.Now().Add(ttl),
+	}
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if element, ok := shard.entries[cacheKey]; ok {
+		element.Value = entry
+		shard.recency.MoveToFront(element)
+		return
+	}
+
+	if shard.maxEntries > 0 && shard.recency.Len() >= shard.maxEntries {
+		shard.remove(shard.recency.Back())
+	}
+
+	shard.entries[cacheKey] = shard.recency.PushFront(entry)
+}
+
+// Len returns the number of entries in c, including expired entries that weren't evicted yet.
+func (c *HeadCache) Len() int {
+	length := 0
+	for _, shard := range c.shards {
+		shard.mu.Lock()
+		length += shard.recency.Len()
+		shard.mu.Unlock()
+	}
+
+	return length
+}
+
+// Stats returns the number of cache hits and misses of c.
+func (c *HeadCache) Stats() (hits int64, misses int64) {
+	return atomic.LoadInt64(&c.hits), atomic.LoadInt64(&c.misses)
+}
+
+// remove removes element from s, s.mu must be held.
+func (s *headCacheShard) remove(element *list.Element) {
+	delete(s.entries, element.Value.(headCacheEntry).cacheKey)
+	s.recency.Remove(element)
+}
+
+// headObject returns the HeadObject result of bucket/key, from s.HeadCache if it's cached there.
+// Requests with delegated credentials bypass the cache, which is filled with the service's credentials,
+// and so do requests of a version, since the cache holds the latest versions.
+// If S3 fails transiently and s.ServeStaleOnError is set, the expired result is returned if it's
+// cached up to the cache's max staleness, and the call is marked with a stale CacheStatusHeader trailer.
+func (s Service) headObject(ctx context.Context, bucket string, key string) (*s3.HeadObjectOutput, error) {
+	useCache := s.HeadCache != nil && !s.isDelegated(ctx) && versionIDFromContext(ctx) == nil
+	if useCache {
+		if head, ok := s.HeadCache.Get(bucket, key); ok {
+			return head, nil
+		}
+	}
+
+	head, err := s.fetchHead(ctx, bucket, key)
+	if err != nil && useCache && s.ServeStaleOnError && isTransientS3Error(err) {
+		return s.staleHead(ctx, bucket, key, err)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if useCache {
+		s.HeadCache.Set(bucket, key, head)
+	}
+
+	return head, nil
+}
+
+// fetchHead returns the HeadObject result of bucket/key from S3, bypassing s.HeadCache,
+// or learns it by a ranged GetObject when HeadObject is denied, if s.HeadDeniedFallback is set.
+func (s Service) fetchHead(ctx context.Context, bucket string, key string) (*s3.HeadObjectOutput, error) {
+	headSpan := s.startClientSpan(ctx, "s3.HeadObject", opentracing.Tags{"s3.bucket": bucket, "s3.key": key})
+	headInput := &s3.HeadObjectInput{
+		Bucket:    aws.String(bucket),
+		Key:       aws.String(key),
+		VersionId: versionIDFromContext(ctx),
+	}
+
+	var head *s3.HeadObjectOutput
+	err := s.callWithFailover(ctx, bucket, func(bucket string) error {
+		var region string
+		var err error
+		headInput.Bucket = aws.String(bucket)
+		head, err = s.s3ClientFor(ctx, bucket).HeadObjectWithContext(
+			ctx,
+			headInput,
+			withChecksumMode,
+			withRedirectRegion(&region),
+		)
+
+		// Retry once in the bucket's region if S3 redirected to it, the later calls use its region too.
+		if err != nil && s.resolveRedirect(ctx, bucket, region) {
+			head, err = s.s3ClientFor(ctx, bucket).HeadObjectWithContext(ctx, headInput, withChecksumMode)
+		}
+
+		return err
+	})
+	finishSpan(headSpan, err)
+
+	// Learn the object by its first byte if the store denies HeadObject but may allow GetObject.
+	if err != nil && s.HeadDeniedFallback && isHeadDenied(err) {
+		s.logger.WithField("s3.bucket", bucket).Debugf("HeadObject denied, falling back to GetObject: %v", err)
+		return s.fetchHeadByRange(ctx, bucket, key)
+	}
+
+	return head, err
+}
+
+// WarmHeadCache fetches the HeadObject results of objects, each formatted as "bucket/key",
+// into s.HeadCache with up to concurrency concurrent calls. Objects that fail are logged and skipped.
+// It returns the number of objects warmed.
+func (s Service) WarmHeadCache(ctx context.Context, objects []string, concurrency int) int {
+	if s.HeadCache == nil || len(objects) == 0 {
+		return 0
+	}
+
+	if concurrency <= 0 {
+		concurrency = DefaultWarmConcurrency
+	}
+
+	var warmed int64
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for _, object := range objects {
+		object := strings.TrimSpace(object)
+		separator := strings.Index(object, "/")
+		if separator <= 0 || separator == len(object)-1 {
+			s.logger.Warnf("skipping invalid warm key %q, expected bucket/key", object)
+			continue
+		}
+
+		bucket, key := object[:separator], object[separator+1:]
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			fields := logrus.Fields{"s3.bucket": bucket, "key.prefix": KeyPrefixLabel(key, s.KeyPrefixAllowlist)}
+			if _, err := s.headObject(ctx, bucket, key); err != nil {
+				if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "NotFound" {
+					s.logger.WithFields(fields).Warnf("skipping missing warm key %s/%s", bucket, key)
+					return
+				}
+
+				s.logger.WithFields(fields).Errorf("failed to warm key %s/%s: %v", bucket, key, err)
+				return
+			}
+
+			atomic.AddInt64(&warmed, 1)
+			s.logger.WithFields(fields).Infof("warmed key %s/%s", bucket, key)
+		}()
+	}
+
+	wg.Wait()
+
+	return int(warmed)
+}
@@ -0,0 +1,209 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+)
+
+func TestHeadCache(t *testing.T) {
+	cache := download.NewHeadCache(50 * time.Millisecond)
+	head := &s3.HeadObjectOutput{ContentLength: aws.Int64(10)}
+
+	if _, ok := cache.Get(testbucket, testkey); ok {
+		t.Fatalf("HeadCache.Get() found an object that wasn't set")
+	}
+
+	cache.Set(testbucket, testkey, head)
+	if got, ok := cache.Get(testbucket, testkey); !ok || got != head {
+		t.Fatalf("HeadCache.Get() = %v, %v, want %v, true", got, ok, head)
+	}
+
+	if _, ok := cache.Get("otherbucket", testkey); ok {
+		t.Errorf("HeadCache.Get() found an object of another bucket")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if _, ok := cache.Get(testbucket, testkey); ok {
+		t.Errorf("HeadCache.Get() found an expired object")
+	}
+
+	if hits, misses := cache.Stats(); hits != 1 || misses != 3 {
+		t.Errorf("HeadCache.Stats() = %d, %d, want 1, 3", hits, misses)
+	}
+}
+
+func TestHeadCache_BucketTTLs(t *testing.T) {
+	const staticBucket, workingBucket, uncachedBucket = "static", "working", "uncached"
+
+	cache := download.NewHeadCache(time.Minute)
+	cache.SetBucketTTLs(map[string]time.Duration{
+		staticBucket:   time.Hour,
+		workingBucket:  50 * time.Millisecond,
+		uncachedBucket: 0,
+	})
+
+	head := &s3.HeadObjectOutput{ContentLength: aws.Int64(10)}
+	for _, bucket := range []string{testbucket, staticBucket, workingBucket, uncachedBucket} {
+		cache.Set(bucket, testkey, head)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	tests := []struct {
+		name   string
+		bucket string
+		want   bool
+	}{
+		{name: "bucket ttl - default ttl", bucket: testbucket, want: true},
+		{name: "bucket ttl - long ttl serves from cache", bucket: staticBucket, want: true},
+		{name: "bucket ttl - short ttl revalidates", bucket: workingBucket, want: false},
+		{name: "bucket ttl - zero ttl isn't cached", bucket: uncachedBucket, want: false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := cache.Get(tt.bucket, testkey); ok != tt.want {
+				t.Errorf("HeadCache.Get() found = %v, want %v", ok, tt.want)
+			}
+		})
+	}
+
+	// The expired entry was evicted when it was got, and the uncached one was never set.
+	if got := cache.Len(); got != 2 {
+		t.Errorf("HeadCache.Len() = %d, want 2", got)
+	}
+}
+
+func TestHeadCache_LRU(t *testing.T) {
+	tests := []struct {
+		name       string
+		shards     int
+		maxEntries int
+		keys       int
+		wantLen    int
+	}{
+		{name: "lru - single shard", shards: 1, maxEntries: 4, keys: 10, wantLen: 4},
+		{name: "lru - sharded", shards: 4, maxEntries: 8, keys: 100, wantLen: 8},
+		{name: "lru - unbounded", shards: 4, maxEntries: 0, keys: 100, wantLen: 100},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			cache := download.NewShardedHeadCache(time.Minute, tt.shards, tt.maxEntries)
+			head := &s3.HeadObjectOutput{ContentLength: aws.Int64(10)}
+			for i := 0; i < tt.keys; i++ {
+				cache.Set(testbucket, strconv.Itoa(i), head)
+			}
+
+			if got := cache.Len(); got > tt.wantLen || (tt.maxEntries == 0 && got != tt.wantLen) {
+				t.Errorf("HeadCache.Len() = %d, want at most %d", got, tt.wantLen)
+			}
+
+			// The most recently set key is never the one evicted.
+			if _, ok := cache.Get(testbucket, strconv.Itoa(tt.keys-1)); !ok {
+				t.Errorf("HeadCache.Get() didn't find the most recently set object")
+			}
+		})
+	}
+
+	// Getting an entry makes it the most recently used, so the other one is evicted.
+	cache := download.NewShardedHeadCache(time.Minute, 1, 2)
+	head := &s3.HeadObjectOutput{ContentLength: aws.Int64(10)}
+	cache.Set(testbucket, "a", head)
+	cache.Set(testbucket, "b", head)
+	cache.Get(testbucket, "a")
+	cache.Set(testbucket, "c", head)
+
+	if _, ok := cache.Get(testbucket, "b"); ok {
+		t.Errorf("HeadCache.Get() found the least recently used object, want it evicted")
+	}
+
+	for _, key := range []string{"a", "c"} {
+		if _, ok := cache.Get(testbucket, key); !ok {
+			t.Errorf("HeadCache.Get() didn't find recently used object %s", key)
+		}
+	}
+}
+
+func TestDownloadService_WarmHeadCache(t *testing.T) {
+	service := download.NewService(s3Client, logger)
+	service.HeadCache = download.NewHeadCache(time.Minute)
+
+	objects := []string{
+		testbucket + "/" + testkey,
+		testbucket + "/missing.txt",
+		"invalid",
+	}
+
+	if warmed := service.WarmHeadCache(context.Background(), objects, 2); warmed != 1 {
+		t.Fatalf("Service.WarmHeadCache() = %d, want 1", warmed)
+	}
+
+	// The warm key is served from the cache on its first request.
+	client, closeClient := newServiceClient(t, service)
+	defer closeClient()
+
+	stream, err := client.Download(context.Background(), &pb.DownloadRequest{Key: testkey, Bucket: testbucket})
+	if err != nil {
+		t.Fatalf("DownloadService.Download() error = %v", err)
+	}
+
+	got, err := recvAll(stream)
+	if err != nil {
+		t.Fatalf("DownloadService.Download() error = %v", err)
+	}
+
+	if !bytes.Equal(got, file) {
+		t.Errorf("DownloadService.Download() file downloaded is different from the wanted file")
+	}
+
+	if hits, misses := service.HeadCache.Stats(); hits != 1 || misses != 2 {
+		t.Errorf("HeadCache.Stats() = %d, %d, want 1 hit and the 2 misses of warming", hits, misses)
+	}
+}
+
+func BenchmarkHeadCache(b *testing.B) {
+	const keys = 1 << 14
+
+	benchmarks := []struct {
+		name   string
+		shards int
+	}{
+		{name: "single lock", shards: 1},
+		{name: "sharded", shards: 16},
+	}
+
+	head := &s3.HeadObjectOutput{ContentLength: aws.Int64(10)}
+	keyNames := make([]string, keys)
+	for i := range keyNames {
+		keyNames[i] = strconv.Itoa(i)
+	}
+
+	for _, bm := range benchmarks {
+		bm := bm
+		b.Run(bm.name, func(b *testing.B) {
+			cache := download.NewShardedHeadCache(time.Minute, bm.shards, keys/2)
+			b.RunParallel(func(pb *testing.PB) {
+				i := rand.Int()
+				for pb.Next() {
+					key := keyNames[i%keys]
+					if _, ok := cache.Get(testbucket, key); !ok {
+						cache.Set(testbucket, key, head)
+					}
+					i++
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// isHeadDenied returns true if err is a HeadObject call that the store denied, rather than failed.
+// Responses to HeadObject have no body, so the error's code is derived from its status code.
+func isHeadDenied(err error) bool {
+	if reqErr, ok := err.(awserr.RequestFailure); ok {
+		statusCode := reqErr.StatusCode()
+		if statusCode == http.StatusForbidden || statusCode == http.StatusMethodNotAllowed {
+			return true
+		}
+	}
+
+	aerr, ok := err.(awserr.Error)
+
+	return ok && (aerr.Code() == "AccessDenied" || aerr.Code() == "MethodNotAllowed")
+}
+
+// parseContentRangeSize returns the object's size of contentRange, a Content-Range header
+// of the form "bytes first-last/size".
+func parseContentRangeSize(contentRange string) (int64, error) {
+	separator := strings.LastIndex(contentRange, "/")
+	if !strings.HasPrefix(contentRange, "bytes ") || separator < 0 {
+		return 0, fmt.Errorf("invalid content range %q", contentRange)
+	}
+
+	size, err := strconv.ParseInt(contentRange[separator+1:], 10, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid content range %q", contentRange)
+	}
+
+	return size, nil
+}
+
+// fetchHeadByRange returns the HeadObject result of bucket/key learned by a ranged GetObject of
+// its first byte, its size from the response's Content-Range, for stores that deny HeadObject.
+// Empty objects have no first byte, so they're learned by a non-ranged GetObject instead.
+func (s Service) fetchHeadByRange(ctx context.Context, bucket string, key string) (*s3.HeadObjectOutput, error) {
+	getSpan := s.startClientSpan(ctx, "s3.GetObject", opentracing.Tags{"s3.bucket": bucket, "s3.key": key})
+	getInput := &s3.GetObjectInput{
+		Bucket:    aws.String(bucket),
+		Key:       aws.String(key),
+		Range:     aws.String("bytes=0-0"),
+		VersionId: versionIDFromContext(ctx),
+	}
+
+	object, err := s.s3ClientFor(ctx, bucket).GetObjectWithContext(ctx, getInput)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "InvalidRange" {
+		getInput.Range = nil
+		object, err = s.s3ClientFor(ctx, bucket).GetObjectWithContext(ctx, getInput)
+	}
+	finishSpan(getSpan, err)
+
+	if err != nil {
+		return nil, err
+	}
+	defer object.Body.Close()
+
+	size := aws.Int64Value(object.ContentLength)
+	if getInput.Range != nil {
+		if size, err = parseContentRangeSize(aws.StringValue(object.ContentRange)); err != nil {
+			return nil, fmt.Errorf("failed to learn the size of object %s/%s: %v", bucket, key, err)
+		}
+	}
+
+	return &s3.HeadObjectOutput{
+		AcceptRanges:         object.AcceptRanges,
+		CacheControl:         object.CacheControl,
+		ContentDisposition:   object.ContentDisposition,
+		ContentEncoding:      object.ContentEncoding,
+		ContentLanguage:      object.ContentLanguage,
+		ContentLength:        aws.Int64(size),
+		ContentType:          object.ContentType,
+		ETag:                 object.ETag,
+		Expires:              object.Expires,
+		LastModified:         object.LastModified,
+		Metadata:             object.Metadata,
+		PartsCount:           object.PartsCount,
+		SSEKMSKeyId:          object.SSEKMSKeyId,
+		ServerSideEncryption: object.ServerSideEncryption,
+		StorageClass:         object.StorageClass,
+		VersionId:            object.VersionId,
+	}, nil
+}
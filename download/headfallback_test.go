@@ -0,0 +1,84 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+)
+
+// headDenyingS3Client returns an S3 client of a store that denies HeadObject with statusCode,
+// with the code S3 derives from it, but allows GetObject.
+func headDenyingS3Client(statusCode int) *s3.S3 {
+	client := s3.New(session.Must(session.NewSession(&s3Client.Config)))
+	client.Handlers.Validate.PushBack(func(r *request.Request) {
+		if _, ok := r.Params.(*s3.HeadObjectInput); ok {
+			code := http.StatusText(statusCode)
+			r.Error = awserr.NewRequestFailure(awserr.New(code, code, nil), statusCode, "")
+		}
+	})
+
+	return client
+}
+
+func TestDownloadService_DownloadHeadDeniedFallback(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		fallback   bool
+		wantErr    bool
+	}{
+		{
+			name:       "head denied - fallback on forbidden",
+			statusCode: http.StatusForbidden,
+			fallback:   true,
+		},
+		{
+			name:       "head denied - fallback on method not allowed",
+			statusCode: http.StatusMethodNotAllowed,
+			fallback:   true,
+		},
+		{
+			name:       "head denied - fallback disabled",
+			statusCode: http.StatusForbidden,
+			wantErr:    true,
+		},
+		{
+			name:       "head denied - other failures not retried",
+			statusCode: http.StatusInternalServerError,
+			fallback:   true,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			service := download.NewService(headDenyingS3Client(tt.statusCode), logger)
+			service.HeadDeniedFallback = tt.fallback
+			client, closeClient := newServiceClient(t, service)
+			defer closeClient()
+
+			stream, err := client.Download(context.Background(), &pb.DownloadRequest{Key: testkey, Bucket: testbucket})
+			if err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			got, err := recvAll(stream)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DownloadService.Download() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && !bytes.Equal(got, file) {
+				t.Errorf("DownloadService.Download() file downloaded is different from the wanted file")
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package download
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DownloadEvent describes a finished download.
+type DownloadEvent struct {
+	Bucket     string  `json:"bucket"`
+	Key        string  `json:"key"`
+	Bytes      int64   `json:"bytes"`
+	DurationMs float64 `json:"durationMs"`
+	Success    bool    `json:"success"`
+	Error      string  `json:"error,omitempty"`
+	TraceID    string  `json:"traceId"`
+}
+
+// CompletionHook is called asynchronously with the event of every finished download,
+// its error is logged and never affects the download.
+type CompletionHook func(event DownloadEvent) error
+
+// WebhookCompletionHook returns a CompletionHook that POSTs the event as JSON to url using client.
+func WebhookCompletionHook(url string, client *http.Client) CompletionHook {
+	return func(event DownloadEvent) error {
+		body, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to marshal download event: %v", err)
+		}
+
+		res, err := client.Post(url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("failed to post download event: %v", err)
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+			return fmt.Errorf("download event webhook responded with status %s", res.Status)
+		}
+
+		return nil
+	}
+}
+
+// notifyCompletion fires s.CompletionHook, if there's one, with the event of the
+// download of bucket/key that started at startTime, sent bytesSent and ended with err.
+func (s Service) notifyCompletion(
+	bucket string,
+	key string,
+	bytesSent int64,
+	startTime time.Time,
+	traceID string,
+	err error,
+) {
+	if s.CompletionHook == nil {
+		return
+	}
+
+	event := DownloadEvent{
+		Bucket:     bucket,
+		Key:        key,
+		Bytes:      bytesSent,
+		DurationMs: float64(time.Since(startTime)) / float64(time.Millisecond),
+		Success:    err == nil,
+		TraceID:    traceID,
+	}
+
+	if err != nil {
+		event.Error = err.Error()
+	}
+
+	go func() {
+		if err := s.CompletionHook(event); err != nil {
+			s.logger.WithField("trace.id", traceID).Errorf("failed to notify download completion: %v", err)
+		}
+	}()
+}
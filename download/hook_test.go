@@ -0,0 +1,128 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDownloadService_CompletionHook(t *testing.T) {
+	tests := []struct {
+		name        string
+		key         string
+		hookStatus  int
+		wantCode    codes.Code
+		wantBytes   int64
+		wantSuccess bool
+	}{
+		{
+			name:        "completion hook - success",
+			key:         testkey,
+			hookStatus:  http.StatusOK,
+			wantCode:    codes.OK,
+			wantBytes:   int64(len(file)),
+			wantSuccess: true,
+		},
+		{
+			name:        "completion hook - failed download",
+			key:         "nonexistent.txt",
+			hookStatus:  http.StatusOK,
+			wantCode:    codes.NotFound,
+			wantBytes:   0,
+			wantSuccess: false,
+		},
+		{
+			name:        "completion hook - webhook fails",
+			key:         testkey,
+			hookStatus:  http.StatusInternalServerError,
+			wantCode:    codes.OK,
+			wantBytes:   int64(len(file)),
+			wantSuccess: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			events := make(chan download.DownloadEvent, 1)
+			webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var event download.DownloadEvent
+				if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+					t.Errorf("failed to decode download event: %v", err)
+				}
+
+				w.WriteHeader(tt.hookStatus)
+				events <- event
+			}))
+			defer webhook.Close()
+
+			service := download.NewService(s3Client, logger)
+			service.CompletionHook = download.WebhookCompletionHook(webhook.URL, webhook.Client())
+
+			client, closeClient := newServiceClient(t, service)
+			defer closeClient()
+
+			stream, err := client.Download(context.Background(), &pb.DownloadRequest{Key: tt.key, Bucket: testbucket})
+			if err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			got, err := recvAll(stream)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("DownloadService.Download() error = %v, wantCode %v", err, tt.wantCode)
+			}
+
+			if tt.wantCode == codes.OK && !bytes.Equal(got, file) {
+				t.Errorf("DownloadService.Download() file downloaded is different from the wanted file")
+			}
+
+			select {
+			case event := <-events:
+				checkDownloadEvent(t, event, tt.key, tt.wantBytes, tt.wantSuccess)
+			case <-time.After(5 * time.Second):
+				t.Fatalf("webhook was not notified of the download")
+			}
+		})
+	}
+}
+
+// checkDownloadEvent checks that event is of a download of key from testbucket
+// that sent wantBytes bytes and succeeded if wantSuccess is true.
+func checkDownloadEvent(
+	t *testing.T,
+	event download.DownloadEvent,
+	key string,
+	wantBytes int64,
+	wantSuccess bool,
+) {
+	t.Helper()
+
+	if event.Bucket != testbucket || event.Key != key {
+		t.Errorf("DownloadEvent of %s/%s, want %s/%s", event.Bucket, event.Key, testbucket, key)
+	}
+
+	if event.Bytes != wantBytes {
+		t.Errorf("DownloadEvent.Bytes = %d, want %d", event.Bytes, wantBytes)
+	}
+
+	if event.Success != wantSuccess {
+		t.Errorf("DownloadEvent.Success = %v, want %v", event.Success, wantSuccess)
+	}
+
+	if !event.Success && event.Error == "" {
+		t.Errorf("DownloadEvent.Error is empty for a failed download")
+	}
+
+	if event.DurationMs <= 0 {
+		t.Errorf("DownloadEvent.DurationMs = %v, want positive", event.DurationMs)
+	}
+}
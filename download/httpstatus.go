@@ -0,0 +1,43 @@
+package download
+
+import (
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// StatusClientClosedRequest is the non-standard HTTP status of requests cancelled by the client.
+const StatusClientClosedRequest = 499
+
+// httpStatusByCode maps the gRPC codes of download errors to HTTP statuses.
+var httpStatusByCode = map[codes.Code]int{
+	codes.OK:                 http.StatusOK,
+	codes.Canceled:           StatusClientClosedRequest,
+	codes.Unknown:            http.StatusInternalServerError,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.FailedPrecondition: http.StatusPreconditionFailed,
+	codes.Aborted:            http.StatusConflict,
+	codes.OutOfRange:         http.StatusRequestedRangeNotSatisfiable,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Internal:           http.StatusBadGateway,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DataLoss:           http.StatusBadGateway,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+}
+
+// HTTPStatusFromError returns the HTTP status of err, as returned by the service's RPCs,
+// for HTTP adapters of the service. A nil err is http.StatusOK, and errors without
+// a gRPC status are http.StatusInternalServerError.
+func HTTPStatusFromError(err error) int {
+	if httpStatus, ok := httpStatusByCode[status.Code(err)]; ok {
+		return httpStatus
+	}
+
+	return http.StatusInternalServerError
+}
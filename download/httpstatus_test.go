@@ -0,0 +1,56 @@
+package download_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/meateam/download-service/download"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestHTTPStatusFromError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "nil", err: nil, want: http.StatusOK},
+		{name: "non-status error", err: fmt.Errorf("failed"), want: http.StatusInternalServerError},
+		{name: "Canceled", err: status.Error(codes.Canceled, ""), want: download.StatusClientClosedRequest},
+		{name: "Unknown", err: status.Error(codes.Unknown, ""), want: http.StatusInternalServerError},
+		{name: "InvalidArgument", err: status.Error(codes.InvalidArgument, ""), want: http.StatusBadRequest},
+		{name: "DeadlineExceeded", err: status.Error(codes.DeadlineExceeded, ""), want: http.StatusGatewayTimeout},
+		{name: "NotFound", err: status.Error(codes.NotFound, ""), want: http.StatusNotFound},
+		{name: "AlreadyExists", err: status.Error(codes.AlreadyExists, ""), want: http.StatusConflict},
+		{name: "PermissionDenied", err: status.Error(codes.PermissionDenied, ""), want: http.StatusForbidden},
+		{name: "ResourceExhausted", err: status.Error(codes.ResourceExhausted, ""), want: http.StatusTooManyRequests},
+		{
+			name: "FailedPrecondition",
+			err:  status.Error(codes.FailedPrecondition, ""),
+			want: http.StatusPreconditionFailed,
+		},
+		{name: "Aborted", err: status.Error(codes.Aborted, ""), want: http.StatusConflict},
+		{
+			name: "OutOfRange",
+			err:  status.Error(codes.OutOfRange, ""),
+			want: http.StatusRequestedRangeNotSatisfiable,
+		},
+		{name: "Unimplemented", err: status.Error(codes.Unimplemented, ""), want: http.StatusNotImplemented},
+		{name: "Internal", err: status.Error(codes.Internal, ""), want: http.StatusBadGateway},
+		{name: "Unavailable", err: status.Error(codes.Unavailable, ""), want: http.StatusServiceUnavailable},
+		{name: "DataLoss", err: status.Error(codes.DataLoss, ""), want: http.StatusBadGateway},
+		{name: "Unauthenticated", err: status.Error(codes.Unauthenticated, ""), want: http.StatusUnauthorized},
+		{name: "undefined code", err: status.Error(codes.Code(100), ""), want: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := download.HTTPStatusFromError(tt.err); got != tt.want {
+				t.Errorf("HTTPStatusFromError() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
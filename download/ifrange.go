@@ -0,0 +1,42 @@
+package download
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// RestartedHeader is the response header set to "true" when the object changed
+	// since the request's IfRange validator and is downloaded from its first byte.
+	RestartedHeader = "x-download-restarted"
+
+	// weakETagPrefix is the prefix of a weak ETag, which never matches If-Range.
+	weakETagPrefix = "W/"
+)
+
+// ifRangeMatches reports whether the object whose current validators are etag and lastModified
+// is unchanged since ifRange, either an ETag or an HTTP-date, using the strong comparison
+// of the HTTP If-Range header.
+func ifRangeMatches(ifRange string, etag string, lastModified time.Time) bool {
+	if date, err := http.ParseTime(ifRange); err == nil {
+		return !lastModified.IsZero() && lastModified.UTC().Truncate(time.Second).Equal(date)
+	}
+
+	ifRangeTag, ifRangeWeak := parseETag(ifRange)
+	tag, weak := parseETag(etag)
+	if ifRangeWeak || weak {
+		return false
+	}
+
+	return tag != "" && ifRangeTag == tag
+}
+
+// parseETag returns the opaque tag of etag without its quotes, and whether etag is weak.
+// Weak ETags, prefixed with "W/", only identify semantically equivalent objects, not
+// byte-identical ones, so they never match with the strong comparison.
+func parseETag(etag string) (string, bool) {
+	weak := strings.HasPrefix(etag, weakETagPrefix)
+
+	return strings.Trim(strings.TrimPrefix(etag, weakETagPrefix), `"`), weak
+}
@@ -0,0 +1,104 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDownloadService_DownloadIfRange(t *testing.T) {
+	head, err := s3Client.HeadObject(&s3.HeadObjectInput{Bucket: aws.String(testbucket), Key: aws.String(testkey)})
+	if err != nil {
+		t.Fatalf("failed to head %s/%s: %v", testbucket, testkey, err)
+	}
+
+	etag := aws.StringValue(head.ETag)
+	lastModified := aws.TimeValue(head.LastModified)
+	const rangeStart = 1 << 20
+
+	tests := []struct {
+		name          string
+		ifRange       string
+		ifRangeFail   bool
+		wantCode      codes.Code
+		wantRestarted bool
+	}{
+		{name: "if range - unchanged etag resumes", ifRange: etag, wantCode: codes.OK},
+		{name: "if range - unquoted etag resumes", ifRange: etag[1 : len(etag)-1], wantCode: codes.OK},
+		{
+			name:     "if range - unchanged date resumes",
+			ifRange:  lastModified.UTC().Format(http.TimeFormat),
+			wantCode: codes.OK,
+		},
+		{name: "if range - changed etag restarts", ifRange: `"changed"`, wantCode: codes.OK, wantRestarted: true},
+		{name: "if range - weak etag restarts", ifRange: "W/" + etag, wantCode: codes.OK, wantRestarted: true},
+		{
+			name:          "if range - changed date restarts",
+			ifRange:       lastModified.Add(-time.Hour).UTC().Format(http.TimeFormat),
+			wantCode:      codes.OK,
+			wantRestarted: true,
+		},
+		{
+			name:        "if range - changed etag fails",
+			ifRange:     `"changed"`,
+			ifRangeFail: true,
+			wantCode:    codes.FailedPrecondition,
+		},
+	}
+
+	client, closeClient := newServiceClient(t, download.NewService(s3Client, logger))
+	defer closeClient()
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := client.Download(context.Background(), &pb.DownloadRequest{
+				Key:         testkey,
+				Bucket:      testbucket,
+				RangeStart:  rangeStart,
+				IfRange:     tt.ifRange,
+				IfRangeFail: tt.ifRangeFail,
+			})
+			if err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			got, err := recvAll(stream)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("DownloadService.Download() error = %v, wantCode %v", err, tt.wantCode)
+			}
+
+			if tt.wantCode != codes.OK {
+				return
+			}
+
+			header, err := stream.Header()
+			if err != nil {
+				t.Fatalf("failed to get the response header: %v", err)
+			}
+
+			restarted := len(header.Get(download.RestartedHeader)) > 0
+			if restarted != tt.wantRestarted {
+				t.Errorf("DownloadService.Download() restarted = %v, want %v", restarted, tt.wantRestarted)
+			}
+
+			want := file[rangeStart:]
+			if tt.wantRestarted {
+				want = file
+			}
+
+			if !bytes.Equal(got, want) {
+				t.Errorf("DownloadService.Download() downloaded %d bytes, want %d", len(got), len(want))
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package download
+
+import (
+	"fmt"
+	"strings"
+)
+
+// KeyTemplateKeyVariable is the variable of a key template that is replaced by the request's key.
+const KeyTemplateKeyVariable = "key"
+
+// KeyTemplate maps the keys of requests to the keys of the objects in S3, e.g. "{env}/{key}" prefixes
+// them by the environment. The variables other than KeyTemplateKeyVariable are resolved once,
+// when the template is parsed.
+type KeyTemplate struct {
+	// literals are the parts of the template between the request's keys, resolved.
+	literals []string
+}
+
+// ParseKeyTemplate parses template, whose variables are names in braces resolved from vars,
+// besides KeyTemplateKeyVariable. It returns an error if template references a variable missing
+// from vars, has unbalanced braces, or doesn't reference KeyTemplateKeyVariable.
+func ParseKeyTemplate(template string, vars map[string]string) (*KeyTemplate, error) {
+	var literals []string
+	var literal strings.Builder
+	for rest := template; rest != ""; {
+		open := strings.IndexAny(rest, "{}")
+		if open < 0 {
+			literal.WriteString(rest)
+			break
+		}
+
+		literal.WriteString(rest[:open])
+		closing := strings.Index(rest[open:], "}")
+		if rest[open] == '}' || closing < 0 {
+			return nil, fmt.Errorf("key template %q has unbalanced braces", template)
+		}
+
+		name := rest[open+1 : open+closing]
+		if strings.Contains(name, "{") {
+			return nil, fmt.Errorf("key template %q has unbalanced braces", template)
+		}
+
+		rest = rest[open+closing+1:]
+		if name == KeyTemplateKeyVariable {
+			literals = append(literals, literal.String())
+			literal.Reset()
+			continue
+		}
+
+		value, ok := vars[name]
+		if !ok {
+			return nil, fmt.Errorf("key template %q references undefined variable %q", template, name)
+		}
+
+		literal.WriteString(value)
+	}
+
+	if len(literals) == 0 {
+		return nil, fmt.Errorf("key template %q doesn't reference {%s}", template, KeyTemplateKeyVariable)
+	}
+
+	return &KeyTemplate{literals: append(literals, literal.String())}, nil
+}
+
+// Render returns the key of the object in S3 of key, the key of a request.
+// A nil KeyTemplate returns key as is.
+func (t *KeyTemplate) Render(key string) string {
+	if t == nil {
+		return key
+	}
+
+	return strings.Join(t.literals, key)
+}
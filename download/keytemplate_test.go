@@ -0,0 +1,122 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+)
+
+func TestParseKeyTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		vars     map[string]string
+		key      string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "key template - key only",
+			template: "{key}",
+			key:      "a/b.txt",
+			want:     "a/b.txt",
+		},
+		{
+			name:     "key template - dev environment",
+			template: "{env}/{key}",
+			vars:     map[string]string{"env": "dev"},
+			key:      "a/b.txt",
+			want:     "dev/a/b.txt",
+		},
+		{
+			name:     "key template - prod environment and region",
+			template: "{env}-{region}/data/{key}.v1",
+			vars:     map[string]string{"env": "prod", "region": "eu", "unused": "x"},
+			key:      "a/b.txt",
+			want:     "prod-eu/data/a/b.txt.v1",
+		},
+		{
+			name:     "key template - repeated key",
+			template: "{key}/{env}/{key}",
+			vars:     map[string]string{"env": "staging"},
+			key:      "k",
+			want:     "k/staging/k",
+		},
+		{
+			name:     "key template - undefined variable",
+			template: "{env}/{key}",
+			vars:     map[string]string{"region": "eu"},
+			wantErr:  true,
+		},
+		{
+			name:     "key template - missing key",
+			template: "{env}/static",
+			vars:     map[string]string{"env": "dev"},
+			wantErr:  true,
+		},
+		{
+			name:     "key template - unclosed brace",
+			template: "{env/{key}",
+			vars:     map[string]string{"env": "dev"},
+			wantErr:  true,
+		},
+		{
+			name:     "key template - unopened brace",
+			template: "env}/{key}",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			template, err := download.ParseKeyTemplate(tt.template, tt.vars)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseKeyTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if got := template.Render(tt.key); got != tt.want {
+				t.Errorf("KeyTemplate.Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDownloadService_DownloadKeyTemplate(t *testing.T) {
+	staged := file[:4096]
+	if _, err := s3Client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(testbucket),
+		Key:    aws.String("staging/" + testkey),
+		Body:   bytes.NewReader(staged),
+	}); err != nil {
+		t.Fatalf("failed to upload staging/%s, %v", testkey, err)
+	}
+
+	template, err := download.ParseKeyTemplate("{env}/{key}", map[string]string{"env": "staging"})
+	if err != nil {
+		t.Fatalf("ParseKeyTemplate() error = %v", err)
+	}
+
+	service := download.NewService(s3Client, logger)
+	service.KeyTemplate = template
+
+	stream := &hashingDownloadStream{ctx: context.Background(), hash: sha256.New()}
+	if err := service.Download(&pb.DownloadRequest{Key: testkey, Bucket: testbucket}, stream); err != nil {
+		t.Fatalf("DownloadService.Download() error = %v", err)
+	}
+
+	wantHash := sha256.Sum256(staged)
+	if !bytes.Equal(stream.hash.Sum(nil), wantHash[:]) {
+		t.Errorf("DownloadService.Download() file downloaded is different from the staging file")
+	}
+}
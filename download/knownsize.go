@@ -0,0 +1,73 @@
+package download
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// trustsKnownSize returns whether the download of req skips HeadObject and trusts the size
+// the client knows, when s.TrustClientSize is set and the download needs only the object's size.
+func (s Service) trustsKnownSize(req *pb.DownloadRequest) bool {
+	if !s.TrustClientSize || req.GetKnownSize() <= 0 || s.RequireEncryption {
+		return false
+	}
+
+	// The conditions, metadata and follow need the object's validators and attributes.
+	return req.GetIfRange() == "" &&
+		req.GetIfMatch() == "" &&
+		req.GetIfNoneMatch() == "" &&
+		req.GetIfModifiedSince() == "" &&
+		req.GetIfUnmodifiedSince() == "" &&
+		!req.GetIncludeMetadata() &&
+		!req.GetFollow()
+}
+
+// downloadHead returns the HeadObject result of the object of d, or only its size if the size
+// the client of req knows is trusted, which the first part of d validates.
+func (s Service) downloadHead(
+	ctx context.Context,
+	req *pb.DownloadRequest,
+	d *partDownload,
+) (*s3.HeadObjectOutput, error) {
+	if !s.trustsKnownSize(req) {
+		return s.headObject(ctx, d.bucket, d.key)
+	}
+
+	d.knownSize = req.GetKnownSize()
+
+	return &s3.HeadObjectOutput{ContentLength: aws.Int64(d.knownSize)}, nil
+}
+
+// verifyKnownSize validates the size the client of d claimed against contentRange, the Content-Range
+// of its first part downloaded, once. It returns a FailedPrecondition error if they don't match.
+func (d *partDownload) verifyKnownSize(contentRange *string) error {
+	if d.knownSize == 0 {
+		return nil
+	}
+
+	size, err := parseContentRangeSize(aws.StringValue(contentRange))
+	if err != nil {
+		return status.Errorf(
+			codes.FailedPrecondition,
+			"failed to validate the size of object %s/%s: %v",
+			d.bucket, d.key, err,
+		)
+	}
+
+	if size != d.knownSize {
+		return status.Errorf(
+			codes.FailedPrecondition,
+			"object %s/%s is %d bytes, not the known size of %d bytes",
+			d.bucket, d.key, size, d.knownSize,
+		)
+	}
+
+	d.knownSize = 0
+
+	return nil
+}
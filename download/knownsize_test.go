@@ -0,0 +1,106 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// headCountingS3Client returns an S3 client that counts its HeadObject calls in heads.
+func headCountingS3Client(heads *int64) *s3.S3 {
+	client := s3.New(session.Must(session.NewSession(&s3Client.Config)))
+	client.Handlers.Send.PushBack(func(r *request.Request) {
+		if _, ok := r.Params.(*s3.HeadObjectInput); ok {
+			atomic.AddInt64(heads, 1)
+		}
+	})
+
+	return client
+}
+
+func TestDownloadService_DownloadKnownSize(t *testing.T) {
+	size := int64(len(file))
+	tests := []struct {
+		name            string
+		trustClientSize bool
+		knownSize       int64
+		rangeStart      int64
+		rangeEnd        int64
+		ifMatch         string
+		wantCode        codes.Code
+		wantHeads       int64
+	}{
+		{name: "known size - trusted", trustClientSize: true, knownSize: size},
+		{name: "known size - trusted range", trustClientSize: true, knownSize: size, rangeStart: 10, rangeEnd: 99},
+		{
+			name:            "known size - smaller than the object",
+			trustClientSize: true,
+			knownSize:       size - 1,
+			wantCode:        codes.FailedPrecondition,
+		},
+		{
+			name:            "known size - larger than the object",
+			trustClientSize: true,
+			knownSize:       size + 1,
+			wantCode:        codes.FailedPrecondition,
+		},
+		{name: "known size - untrusted", knownSize: size - 1, wantHeads: 1},
+		{name: "known size - unset", trustClientSize: true, wantHeads: 1},
+		{name: "known size - conditional", trustClientSize: true, knownSize: size - 1, ifMatch: "*", wantHeads: 1},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var heads int64
+			service := download.NewService(headCountingS3Client(&heads), logger)
+			service.MaxBufferSize = 256 << 10
+			service.TrustClientSize = tt.trustClientSize
+			client, closeClient := newServiceClient(t, service)
+			defer closeClient()
+
+			stream, err := client.Download(context.Background(), &pb.DownloadRequest{
+				Key:        testkey,
+				Bucket:     testbucket,
+				RangeStart: tt.rangeStart,
+				RangeEnd:   tt.rangeEnd,
+				IfMatch:    tt.ifMatch,
+				KnownSize:  tt.knownSize,
+			})
+			if err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			got, err := recvAll(stream)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("DownloadService.Download() error = %v, want code %v", err, tt.wantCode)
+			}
+
+			if heads := atomic.LoadInt64(&heads); heads != tt.wantHeads {
+				t.Errorf("DownloadService.Download() made %d HeadObject calls, want %d", heads, tt.wantHeads)
+			}
+
+			// A mismatching size is detected before any of the object's bytes are sent.
+			want := file
+			switch {
+			case tt.wantCode != codes.OK:
+				want = nil
+			case tt.rangeEnd != 0:
+				want = file[tt.rangeStart : tt.rangeEnd+1]
+			}
+
+			if !bytes.Equal(got, want) {
+				t.Errorf("DownloadService.Download() received %d bytes, want %d bytes", len(got), len(want))
+			}
+		})
+	}
+}
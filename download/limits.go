@@ -0,0 +1,80 @@
+package download
+
+import (
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// DefaultMaxKeyLength is the default maximum length in bytes of the keys of a request,
+	// which is the maximum length of S3 keys.
+	DefaultMaxKeyLength = 1024
+
+	// DefaultMaxRequestKeys is the default maximum number of keys of a request.
+	DefaultMaxRequestKeys = 1000
+
+	// DefaultMaxRequestSize is the default maximum size in bytes of a serialized request.
+	DefaultMaxRequestSize = 1 << 20
+)
+
+// RequestLimits bound the size of the requests, which are rejected with an InvalidArgument error
+// before they're processed when they exceed them. A zero limit disables it.
+type RequestLimits struct {
+	// MaxKeyLength is the maximum length in bytes of every key, prefix and URL of a request.
+	MaxKeyLength int
+
+	// MaxKeys is the maximum number of keys of a request, the number of concatenated keys.
+	MaxKeys int
+
+	// MaxRequestSize is the maximum size in bytes of a serialized request.
+	MaxRequestSize int
+}
+
+// DefaultRequestLimits are the request limits of a new Service.
+var DefaultRequestLimits = RequestLimits{
+	MaxKeyLength:   DefaultMaxKeyLength,
+	MaxKeys:        DefaultMaxRequestKeys,
+	MaxRequestSize: DefaultMaxRequestSize,
+}
+
+// check returns an InvalidArgument error if req, whose keys are keys and whose other
+// key-like fields, such as prefixes and URLs, are fields, exceeds the limits of l.
+func (l RequestLimits) check(req proto.Message, keys []string, fields ...string) error {
+	if l.MaxRequestSize > 0 {
+		if size := proto.Size(req); size > l.MaxRequestSize {
+			return status.Errorf(
+				codes.InvalidArgument,
+				"request of %d bytes exceeds the limit of %d bytes",
+				size,
+				l.MaxRequestSize,
+			)
+		}
+	}
+
+	if l.MaxKeys > 0 && len(keys) > l.MaxKeys {
+		return status.Errorf(
+			codes.InvalidArgument,
+			"request of %d keys exceeds the limit of %d keys",
+			len(keys),
+			l.MaxKeys,
+		)
+	}
+
+	if l.MaxKeyLength <= 0 {
+		return nil
+	}
+
+	for _, key := range append(fields, keys...) {
+		if len(key) > l.MaxKeyLength {
+			return status.Errorf(
+				codes.InvalidArgument,
+				"key of %d bytes exceeds the limit of %d bytes",
+				len(key),
+				l.MaxKeyLength,
+			)
+		}
+	}
+
+	return nil
+}
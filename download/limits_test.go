@@ -0,0 +1,109 @@
+package download_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"strings"
+	"testing"
+
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDownloadService_RequestLimits(t *testing.T) {
+	longKey := strings.Repeat("k", download.DefaultMaxKeyLength+1)
+	manyKeys := make([]string, download.DefaultMaxRequestKeys+1)
+	for i := range manyKeys {
+		manyKeys[i] = testkey
+	}
+
+	tests := []struct {
+		name     string
+		limits   download.RequestLimits
+		call     func(service *download.Service) error
+		wantCode codes.Code
+	}{
+		{
+			name:   "limits - over-long key",
+			limits: download.DefaultRequestLimits,
+			call: func(service *download.Service) error {
+				req := &pb.DownloadRequest{Key: longKey, Bucket: testbucket}
+				return service.Download(req, &hashingDownloadStream{ctx: context.Background(), hash: sha256.New()})
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:   "limits - over-long url",
+			limits: download.DefaultRequestLimits,
+			call: func(service *download.Service) error {
+				req := &pb.DownloadRequest{Url: "s3://" + testbucket + "/" + longKey}
+				return service.Download(req, &hashingDownloadStream{ctx: context.Background(), hash: sha256.New()})
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:   "limits - over-long prefix",
+			limits: download.DefaultRequestLimits,
+			call: func(service *download.Service) error {
+				_, err := service.ListObjects(context.Background(), &pb.ListObjectsRequest{
+					Bucket: testbucket,
+					Prefix: longKey,
+				})
+				return err
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:   "limits - excessive key list",
+			limits: download.DefaultRequestLimits,
+			call: func(service *download.Service) error {
+				req := &pb.DownloadConcatenatedRequest{Keys: manyKeys, Bucket: testbucket}
+				return service.DownloadConcatenated(
+					req,
+					&hashingDownloadStream{ctx: context.Background(), hash: sha256.New()},
+				)
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:   "limits - oversized request",
+			limits: download.RequestLimits{MaxRequestSize: 1 << 10},
+			call: func(service *download.Service) error {
+				req := &pb.DownloadRequest{Key: testkey, Bucket: testbucket, EnvelopePublicKey: make([]byte, 1<<10)}
+				return service.Download(req, &hashingDownloadStream{ctx: context.Background(), hash: sha256.New()})
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:   "limits - within the limits",
+			limits: download.DefaultRequestLimits,
+			call: func(service *download.Service) error {
+				req := &pb.DownloadRequest{Key: testkey, Bucket: testbucket}
+				return service.Download(req, &hashingDownloadStream{ctx: context.Background(), hash: sha256.New()})
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name:   "limits - disabled",
+			limits: download.RequestLimits{},
+			call: func(service *download.Service) error {
+				req := &pb.DownloadRequest{Key: longKey, Bucket: testbucket}
+				return service.Download(req, &hashingDownloadStream{ctx: context.Background(), hash: sha256.New()})
+			},
+			wantCode: codes.NotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			service := download.NewService(s3Client, logger)
+			service.RequestLimits = tt.limits
+			if err := tt.call(service); status.Code(err) != tt.wantCode {
+				t.Errorf("DownloadService request error = %v, want code %v", err, tt.wantCode)
+			}
+		})
+	}
+}
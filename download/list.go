@@ -0,0 +1,125 @@
+package download
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// DefaultPageSize is the page size of ListObjects when the request doesn't specify one.
+	DefaultPageSize = 100
+
+	// MaxPageSize is the maximum page size of ListObjects, which is the maximum keys S3 returns.
+	MaxPageSize = 1000
+)
+
+// ListObjects is the request to list a single page of the objects in a bucket.
+// It responds with up to req.PageSize objects and the token of the next page,
+// which is the S3 continuation token encoded opaquely.
+// Listing is authorized by s.Authorizer with req.Prefix as the key.
+func (s Service) ListObjects(ctx context.Context, req *pb.ListObjectsRequest) (*pb.ListObjectsResponse, error) {
+	if err := s.RequestLimits.check(req, nil, req.GetPrefix(), req.GetPageToken()); err != nil {
+		return nil, err
+	}
+
+	bucket := req.GetBucket()
+	if bucket == "" {
+		return nil, status.Error(codes.InvalidArgument, "bucket is required")
+	}
+
+	if err := s.authorize(ctx, bucket, req.GetPrefix()); err != nil {
+		return nil, err
+	}
+
+	pageSize := req.GetPageSize()
+	if pageSize == 0 {
+		pageSize = DefaultPageSize
+	}
+
+	if pageSize < 0 || pageSize > MaxPageSize {
+		return nil, status.Errorf(codes.InvalidArgument, "page size must be between 1 and %d", MaxPageSize)
+	}
+
+	listInput := &s3.ListObjectsV2Input{
+		Bucket:  aws.String(bucket),
+		MaxKeys: aws.Int64(pageSize),
+	}
+
+	if prefix := req.GetPrefix(); prefix != "" {
+		listInput.Prefix = aws.String(prefix)
+	}
+
+	if delimiter := req.GetDelimiter(); delimiter != "" {
+		listInput.Delimiter = aws.String(delimiter)
+	}
+
+	if pageToken := req.GetPageToken(); pageToken != "" {
+		continuationToken, err := base64.RawURLEncoding.DecodeString(pageToken)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid page token")
+		}
+
+		listInput.ContinuationToken = aws.String(string(continuationToken))
+	}
+
+	listOutput, err := s.listObjectsV2(ctx, bucket, listInput)
+	if err != nil {
+		return nil, s3ErrorToStatus(fmt.Errorf("failed to list objects of %s: %w", bucket, err))
+	}
+
+	response := &pb.ListObjectsResponse{
+		Objects:        make([]*pb.ObjectInfo, 0, len(listOutput.Contents)),
+		CommonPrefixes: make([]string, 0, len(listOutput.CommonPrefixes)),
+	}
+
+	for _, object := range listOutput.Contents {
+		objectInfo := &pb.ObjectInfo{
+			Key:  aws.StringValue(object.Key),
+			Size: aws.Int64Value(object.Size),
+			Etag: aws.StringValue(object.ETag),
+		}
+
+		if object.LastModified != nil {
+			objectInfo.LastModified = object.LastModified.UnixNano() / int64(time.Millisecond)
+		}
+
+		response.Objects = append(response.Objects, objectInfo)
+	}
+
+	for _, commonPrefix := range listOutput.CommonPrefixes {
+		response.CommonPrefixes = append(response.CommonPrefixes, aws.StringValue(commonPrefix.Prefix))
+	}
+
+	if aws.BoolValue(listOutput.IsTruncated) {
+		response.NextPageToken = base64.RawURLEncoding.EncodeToString(
+			[]byte(aws.StringValue(listOutput.NextContinuationToken)),
+		)
+	}
+
+	return response, nil
+}
+
+// listObjectsV2 lists the objects of bucket by listInput, retrying once in the bucket's region
+// if S3 redirected to it.
+func (s Service) listObjectsV2(
+	ctx context.Context,
+	bucket string,
+	listInput *s3.ListObjectsV2Input,
+) (*s3.ListObjectsV2Output, error) {
+	var region string
+	client := s.s3ClientFor(ctx, bucket)
+	listOutput, err := client.ListObjectsV2WithContext(ctx, listInput, withRedirectRegion(&region))
+	if err != nil && s.resolveRedirect(ctx, bucket, region) {
+		return s.s3ClientFor(ctx, bucket).ListObjectsV2WithContext(ctx, listInput)
+	}
+
+	return listOutput, err
+}
@@ -0,0 +1,156 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDownloadService_ListObjects(t *testing.T) {
+	const listPrefix = "list/"
+
+	wantKeys := make([]string, 0, 5)
+	for i := 0; i < 5; i++ {
+		key := fmt.Sprintf("%sobject-%d.txt", listPrefix, i)
+		if _, err := s3Client.PutObject(&s3.PutObjectInput{
+			Bucket: aws.String(testbucket),
+			Key:    aws.String(key),
+			Body:   bytes.NewReader([]byte(key)),
+		}); err != nil {
+			t.Fatalf("failed to put object %s: %v", key, err)
+		}
+
+		wantKeys = append(wantKeys, key)
+	}
+
+	conn, err := grpc.DialContext(
+		context.Background(),
+		"bufnet",
+		grpc.WithContextDialer(bufDialer),
+		grpc.WithInsecure(),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial bufnet: %v", err)
+	}
+	defer conn.Close()
+
+	client := pb.NewDownloadClient(conn)
+
+	t.Run("list objects - pages", func(t *testing.T) {
+		gotKeys := make([]string, 0, len(wantKeys))
+		pages := 0
+		req := &pb.ListObjectsRequest{Bucket: testbucket, Prefix: listPrefix, PageSize: 2}
+		for {
+			res, err := client.ListObjects(context.Background(), req)
+			if err != nil {
+				t.Fatalf("DownloadService.ListObjects() error = %v", err)
+			}
+
+			pages++
+			if len(res.GetObjects()) > int(req.GetPageSize()) {
+				t.Fatalf(
+					"DownloadService.ListObjects() returned %d objects, page size %d",
+					len(res.GetObjects()), req.GetPageSize(),
+				)
+			}
+
+			for _, object := range res.GetObjects() {
+				gotKeys = append(gotKeys, object.GetKey())
+			}
+
+			if res.GetNextPageToken() == "" {
+				break
+			}
+
+			req.PageToken = res.GetNextPageToken()
+		}
+
+		if !reflect.DeepEqual(gotKeys, wantKeys) {
+			t.Errorf("DownloadService.ListObjects() keys = %v, want %v", gotKeys, wantKeys)
+		}
+
+		if pages != 3 {
+			t.Errorf("DownloadService.ListObjects() pages = %d, want 3", pages)
+		}
+	})
+
+	invalidRequests := []struct {
+		name string
+		req  *pb.ListObjectsRequest
+	}{
+		{name: "list objects - bucket is nil", req: &pb.ListObjectsRequest{}},
+		{name: "list objects - negative page size", req: &pb.ListObjectsRequest{Bucket: testbucket, PageSize: -1}},
+		{name: "list objects - page size too big", req: &pb.ListObjectsRequest{Bucket: testbucket, PageSize: 1001}},
+		{name: "list objects - invalid page token", req: &pb.ListObjectsRequest{Bucket: testbucket, PageToken: "!"}},
+	}
+
+	for _, tt := range invalidRequests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := client.ListObjects(context.Background(), tt.req); status.Code(err) != codes.InvalidArgument {
+				t.Errorf("DownloadService.ListObjects() error = %v, want %v", err, codes.InvalidArgument)
+			}
+		})
+	}
+
+	t.Run("list objects - bucket does not exist", func(t *testing.T) {
+		req := &pb.ListObjectsRequest{Bucket: "nosuchbucket"}
+		if _, err := client.ListObjects(context.Background(), req); status.Code(err) != codes.NotFound {
+			t.Errorf("DownloadService.ListObjects() error = %v, want %v", err, codes.NotFound)
+		}
+	})
+}
+
+func TestDownloadService_ListObjectsAuthorizer(t *testing.T) {
+	const allowedPrefix = "list/"
+
+	service := download.NewService(s3Client, logger)
+	service.Authorizer = func(ctx context.Context, subject string, bucket string, key string) error {
+		if bucket != testbucket || key != allowedPrefix {
+			return fmt.Errorf("listing %s/%s is not allowed", bucket, key)
+		}
+
+		return nil
+	}
+
+	tests := []struct {
+		name     string
+		req      *pb.ListObjectsRequest
+		wantCode codes.Code
+	}{
+		{
+			name:     "list objects - allowed prefix",
+			req:      &pb.ListObjectsRequest{Bucket: testbucket, Prefix: allowedPrefix},
+			wantCode: codes.OK,
+		},
+		{
+			name:     "list objects - denied prefix",
+			req:      &pb.ListObjectsRequest{Bucket: testbucket, Prefix: "secret/"},
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:     "list objects - denied whole bucket",
+			req:      &pb.ListObjectsRequest{Bucket: testbucket},
+			wantCode: codes.PermissionDenied,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.ListObjects(context.Background(), tt.req); status.Code(err) != tt.wantCode {
+				t.Errorf("DownloadService.ListObjects() error = %v, want %v", err, tt.wantCode)
+			}
+		})
+	}
+}
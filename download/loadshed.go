@@ -0,0 +1,155 @@
+package download
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultShedRetryAfter is the default delay that shed downloads are told to retry after.
+const DefaultShedRetryAfter = time.Second
+
+// loadState counts the active downloads of a Service and whether it's shedding load or draining.
+type loadState struct {
+	active   int64
+	mu       sync.Mutex
+	shedding bool
+	draining bool
+}
+
+// ActiveDownloads returns the number of downloads the service is currently serving.
+func (s Service) ActiveDownloads() int64 {
+	if s.load == nil {
+		return 0
+	}
+
+	return atomic.LoadInt64(&s.load.active)
+}
+
+// Shedding returns whether the service is currently rejecting new downloads to shed load.
+func (s Service) Shedding() bool {
+	if s.load == nil {
+		return false
+	}
+
+	s.load.mu.Lock()
+	defer s.load.mu.Unlock()
+
+	return s.load.shedding
+}
+
+// SetDraining sets whether the service is draining. A draining service rejects new downloads
+// with Unavailable, while the active downloads complete.
+func (s Service) SetDraining(draining bool) {
+	if s.load == nil {
+		return
+	}
+
+	s.load.mu.Lock()
+	defer s.load.mu.Unlock()
+
+	if s.load.draining != draining {
+		s.logger.Infof("set draining to %v with %d active downloads", draining, atomic.LoadInt64(&s.load.active))
+	}
+
+	s.load.draining = draining
+}
+
+// Draining returns whether the service is draining.
+func (s Service) Draining() bool {
+	if s.load == nil {
+		return false
+	}
+
+	s.load.mu.Lock()
+	defer s.load.mu.Unlock()
+
+	return s.load.draining
+}
+
+// startDownload counts a new download as active, or returns an Unavailable error if the service is
+// draining, or with a RetryInfo detail if it's shedding load. Shedding starts once s.ShedHighWater
+// downloads are active, and stops once they drop below s.shedLowWater().
+// Every successful startDownload must be followed by s.endDownload.
+func (s Service) startDownload() error {
+	if s.load == nil {
+		return nil
+	}
+
+	s.load.mu.Lock()
+	defer s.load.mu.Unlock()
+
+	if s.load.draining {
+		return status.Error(codes.Unavailable, "draining, not accepting new downloads")
+	}
+
+	if s.ShedHighWater > 0 {
+		active := atomic.LoadInt64(&s.load.active)
+		if s.load.shedding && active < s.shedLowWater() {
+			s.load.shedding = false
+			s.logger.Infof("stopped shedding load with %d active downloads", active)
+		} else if !s.load.shedding && active >= s.ShedHighWater {
+			s.load.shedding = true
+			s.logger.Warnf("started shedding load with %d active downloads", active)
+		}
+
+		if s.load.shedding {
+			return s.shedError(active)
+		}
+	}
+
+	atomic.AddInt64(&s.load.active, 1)
+
+	return nil
+}
+
+// shedLowWater returns the number of active downloads below which load shedding stops,
+// s.ShedLowWater or 80% of s.ShedHighWater if it's unset, but at least 1.
+func (s Service) shedLowWater() int64 {
+	lowWater := s.ShedLowWater
+	if lowWater <= 0 {
+		lowWater = s.ShedHighWater * 8 / 10
+	}
+
+	if lowWater < 1 {
+		lowWater = 1
+	}
+
+	return lowWater
+}
+
+// endDownload counts a download started by s.startDownload as no longer active.
+func (s Service) endDownload() {
+	if s.load == nil {
+		return
+	}
+
+	atomic.AddInt64(&s.load.active, -1)
+}
+
+// shedError returns the Unavailable error of a download shed with active downloads,
+// telling the client to retry after s.ShedRetryAfter.
+func (s Service) shedError(active int64) error {
+	retryAfter := s.ShedRetryAfter
+	if retryAfter <= 0 {
+		retryAfter = DefaultShedRetryAfter
+	}
+
+	st := status.Newf(
+		codes.Unavailable,
+		"shedding load with %d active downloads, retry after %s",
+		active,
+		retryAfter,
+	)
+	withDetails, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: ptypes.DurationProto(retryAfter)})
+	if err != nil {
+		return st.Err()
+	}
+
+	return withDetails.Err()
+}
@@ -0,0 +1,187 @@
+package download_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDownloadService_DownloadLoadShedding(t *testing.T) {
+	const highWater, lowWater = 3, 1
+
+	service := download.NewService(s3Client, logger)
+	service.ShedHighWater = highWater
+	service.ShedLowWater = lowWater
+	service.ShedRetryAfter = 2 * time.Second
+	req := &pb.DownloadRequest{Key: testkey, Bucket: testbucket}
+
+	// tryDownload runs a download that doesn't block and returns its error.
+	tryDownload := func() error {
+		stream := newBlockingDownloadStream("")
+		close(stream.release)
+
+		return service.Download(req, stream)
+	}
+
+	// Drive the active downloads up to the high-water mark.
+	held := make([]*blockingDownloadStream, highWater)
+	errs := make(chan error, highWater)
+	for i := range held {
+		held[i] = newBlockingDownloadStream("")
+		go func(stream *blockingDownloadStream) {
+			errs <- service.Download(req, stream)
+		}(held[i])
+		<-held[i].started
+	}
+
+	if active := service.ActiveDownloads(); active != highWater {
+		t.Fatalf("Service.ActiveDownloads() = %d, want %d", active, highWater)
+	}
+
+	err := tryDownload()
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("DownloadService.Download() at the high-water mark error = %v, want %v", err, codes.Unavailable)
+	}
+
+	if retryAfter := retryDelay(err); retryAfter != service.ShedRetryAfter {
+		t.Errorf("DownloadService.Download() retry after = %s, want %s", retryAfter, service.ShedRetryAfter)
+	}
+
+	checkLoadStats(t, service, highWater, true)
+
+	// Downloads are shed until the active downloads drop below the low-water mark.
+	for i, stream := range held {
+		close(stream.release)
+		if err := <-errs; err != nil {
+			t.Fatalf("DownloadService.Download() held download error = %v", err)
+		}
+
+		active := highWater - int64(i+1)
+		err := tryDownload()
+		if active >= lowWater && status.Code(err) != codes.Unavailable {
+			t.Errorf(
+				"DownloadService.Download() with %d active downloads error = %v, want %v",
+				active, err, codes.Unavailable,
+			)
+		}
+
+		if active < lowWater && err != nil {
+			t.Errorf("DownloadService.Download() with %d active downloads error = %v, want nil", active, err)
+		}
+	}
+
+	checkLoadStats(t, service, 0, false)
+}
+
+func TestDownloadService_DownloadLoadSheddingDefaultLowWater(t *testing.T) {
+	const highWater = 3
+
+	service := download.NewService(s3Client, logger)
+	service.ShedHighWater = highWater
+	req := &pb.DownloadRequest{Key: testkey, Bucket: testbucket}
+
+	held := make([]*blockingDownloadStream, highWater)
+	errs := make(chan error, highWater)
+	for i := range held {
+		held[i] = newBlockingDownloadStream("")
+		go func(stream *blockingDownloadStream) {
+			errs <- service.Download(req, stream)
+		}(held[i])
+		<-held[i].started
+	}
+
+	stream := newBlockingDownloadStream("")
+	close(stream.release)
+	if err := service.Download(req, stream); status.Code(err) != codes.Unavailable {
+		t.Fatalf("DownloadService.Download() at the high-water mark error = %v, want %v", err, codes.Unavailable)
+	}
+
+	// Shedding stops once the active downloads drop below the default low-water mark.
+	for _, stream := range held {
+		close(stream.release)
+		if err := <-errs; err != nil {
+			t.Fatalf("DownloadService.Download() held download error = %v", err)
+		}
+	}
+
+	stream = newBlockingDownloadStream("")
+	close(stream.release)
+	if err := service.Download(req, stream); err != nil {
+		t.Errorf("DownloadService.Download() after the active downloads dropped error = %v, want nil", err)
+	}
+
+	checkLoadStats(t, service, 0, false)
+}
+
+func TestDownloadService_DownloadDraining(t *testing.T) {
+	service := download.NewService(s3Client, logger)
+	req := &pb.DownloadRequest{Key: testkey, Bucket: testbucket}
+
+	held := newBlockingDownloadStream("")
+	errs := make(chan error, 1)
+	go func() {
+		errs <- service.Download(req, held)
+	}()
+	<-held.started
+
+	service.SetDraining(true)
+	if !service.Draining() {
+		t.Fatalf("Service.Draining() = false after SetDraining(true)")
+	}
+
+	// New downloads are rejected while draining.
+	stream := newBlockingDownloadStream("")
+	close(stream.release)
+	if err := service.Download(req, stream); status.Code(err) != codes.Unavailable {
+		t.Errorf("DownloadService.Download() while draining error = %v, want %v", err, codes.Unavailable)
+	}
+
+	// The active download completes.
+	close(held.release)
+	if err := <-errs; err != nil {
+		t.Errorf("DownloadService.Download() active download error = %v, want nil", err)
+	}
+
+	service.SetDraining(false)
+	stream = newBlockingDownloadStream("")
+	close(stream.release)
+	if err := service.Download(req, stream); err != nil {
+		t.Errorf("DownloadService.Download() after draining error = %v, want nil", err)
+	}
+}
+
+// checkLoadStats checks that the stats of service report wantActive active downloads and wantShedding.
+func checkLoadStats(t *testing.T, service *download.Service, wantActive int64, wantShedding bool) {
+	t.Helper()
+
+	stats, err := service.GetStats(context.Background(), &pb.GetStatsRequest{})
+	if err != nil {
+		t.Fatalf("Service.GetStats() error = %v", err)
+	}
+
+	if stats.GetActiveDownloads() != wantActive || stats.GetShedding() != wantShedding {
+		t.Errorf(
+			"Service.GetStats() active downloads = %d, shedding = %v, want %d, %v",
+			stats.GetActiveDownloads(), stats.GetShedding(), wantActive, wantShedding,
+		)
+	}
+}
+
+// retryDelay returns the delay of the RetryInfo detail of err, or zero if it has none.
+func retryDelay(err error) time.Duration {
+	for _, detail := range status.Convert(err).Details() {
+		if retryInfo, ok := detail.(*errdetails.RetryInfo); ok {
+			delay, _ := ptypes.Duration(retryInfo.GetRetryDelay())
+			return delay
+		}
+	}
+
+	return 0
+}
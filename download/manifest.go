@@ -0,0 +1,110 @@
+package download
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// MaxManifestParts is the maximum number of parts in a download manifest.
+	MaxManifestParts = 10000
+
+	// MinManifestPartSize is the minimum size of the parts of a download manifest.
+	MinManifestPartSize = 64 << 10
+)
+
+// GetDownloadManifest is the request to get the manifest of the parts of an object,
+// for clients that download the parts concurrently with ranged downloads.
+// It returns a NotFound error if the object or its bucket doesn't exist.
+func (s Service) GetDownloadManifest(
+	ctx context.Context,
+	req *pb.GetDownloadManifestRequest,
+) (*pb.DownloadManifest, error) {
+	if err := s.RequestLimits.check(req, []string{req.GetKey()}, req.GetUrl()); err != nil {
+		return nil, err
+	}
+
+	bucket, key, err := s.resolveObject(req.GetBucket(), req.GetKey(), req.GetUrl())
+	if err != nil {
+		return nil, err
+	}
+
+	partSize := req.GetPartSize()
+	if partSize == 0 {
+		partSize = s.defaultPartSize()
+	}
+
+	if partSize < MinManifestPartSize {
+		return nil, status.Errorf(
+			codes.InvalidArgument,
+			"part size must be at least %d, got %d",
+			MinManifestPartSize,
+			partSize,
+		)
+	}
+
+	if err := s.authorize(ctx, bucket, key); err != nil {
+		return nil, err
+	}
+
+	objectDetails, err := s.headObject(ctx, bucket, key)
+	if err != nil {
+		return nil, s3ErrorToStatus(fmt.Errorf("failed to get manifest of object %s/%s: %w", bucket, key, err))
+	}
+
+	if err := s.checkEncryption(bucket, key, objectDetails); err != nil {
+		return nil, err
+	}
+
+	size := aws.Int64Value(objectDetails.ContentLength)
+	parts, err := manifestParts(size, partSize)
+	if err != nil {
+		return nil, err
+	}
+
+	checksum, _ := headChecksum(objectDetails)
+	etag := aws.StringValue(objectDetails.ETag)
+	_, etagWeak := parseETag(etag)
+
+	return &pb.DownloadManifest{
+		Size:                 size,
+		Etag:                 etag,
+		EtagWeak:             etagWeak,
+		Parts:                parts,
+		ServerSideEncryption: aws.StringValue(objectDetails.ServerSideEncryption),
+		SseKmsKeyId:          aws.StringValue(objectDetails.SSEKMSKeyId),
+		ChecksumAlgorithm:    checksum.algorithm,
+		Checksum:             checksum.value,
+		CacheControl:         aws.StringValue(objectDetails.CacheControl),
+		Expires:              aws.StringValue(objectDetails.Expires),
+	}, nil
+}
+
+// manifestParts splits an object of size bytes into parts of partSize bytes.
+// It returns an InvalidArgument error if there would be more than MaxManifestParts parts.
+func manifestParts(size int64, partSize int64) ([]*pb.ManifestPart, error) {
+	object := byteRange{start: 0, end: size - 1}
+	totalParts := object.parts(partSize, false)
+	if totalParts > MaxManifestParts {
+		return nil, status.Errorf(
+			codes.InvalidArgument,
+			"part size %d splits the object into %d parts, more than %d",
+			partSize,
+			totalParts,
+			MaxManifestParts,
+		)
+	}
+
+	parts := make([]*pb.ManifestPart, 0, totalParts)
+	for n := int64(0); n < totalParts; n++ {
+		part := object.part(n, partSize, false)
+		parts = append(parts, &pb.ManifestPart{Offset: part.start, Length: part.length()})
+	}
+
+	return parts, nil
+}
@@ -0,0 +1,172 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDownloadService_GetDownloadManifest(t *testing.T) {
+	const emptyKey = "empty.txt"
+	if _, err := s3Client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(testbucket),
+		Key:    aws.String(emptyKey),
+		Body:   bytes.NewReader(nil),
+	}); err != nil {
+		t.Fatalf("failed to upload %s, %v", emptyKey, err)
+	}
+
+	size := int64(len(file))
+	tests := []struct {
+		name       string
+		req        *pb.GetDownloadManifestRequest
+		wantCode   codes.Code
+		wantSize   int64
+		wantParts  []*pb.ManifestPart
+		reassemble bool
+	}{
+		{
+			name:       "manifest - default part size",
+			req:        &pb.GetDownloadManifestRequest{Key: testkey, Bucket: testbucket},
+			wantSize:   size,
+			wantParts:  []*pb.ManifestPart{{Offset: 0, Length: size}},
+			reassemble: true,
+		},
+		{
+			name:       "manifest - dividing part size",
+			req:        &pb.GetDownloadManifestRequest{Key: testkey, Bucket: testbucket, PartSize: size / 2},
+			wantSize:   size,
+			wantParts:  []*pb.ManifestPart{{Offset: 0, Length: size / 2}, {Offset: size / 2, Length: size / 2}},
+			reassemble: true,
+		},
+		{
+			name:     "manifest - non-dividing part size",
+			req:      &pb.GetDownloadManifestRequest{Key: testkey, Bucket: testbucket, PartSize: 768 << 10},
+			wantSize: size,
+			wantParts: []*pb.ManifestPart{
+				{Offset: 0, Length: 768 << 10},
+				{Offset: 768 << 10, Length: 768 << 10},
+				{Offset: 2 * 768 << 10, Length: size - 2*768<<10},
+			},
+			reassemble: true,
+		},
+		{
+			name:       "manifest - url",
+			req:        &pb.GetDownloadManifestRequest{Url: "s3://" + testbucket + "/" + testkey},
+			wantSize:   size,
+			wantParts:  []*pb.ManifestPart{{Offset: 0, Length: size}},
+			reassemble: true,
+		},
+		{
+			name:      "manifest - empty object",
+			req:       &pb.GetDownloadManifestRequest{Key: emptyKey, Bucket: testbucket},
+			wantSize:  0,
+			wantParts: []*pb.ManifestPart{},
+		},
+		{
+			name:     "manifest - part size too small",
+			req:      &pb.GetDownloadManifestRequest{Key: testkey, Bucket: testbucket, PartSize: 1},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "manifest - negative part size",
+			req:      &pb.GetDownloadManifestRequest{Key: testkey, Bucket: testbucket, PartSize: -1},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "manifest - empty key",
+			req:      &pb.GetDownloadManifestRequest{Bucket: testbucket},
+			wantCode: codes.Unknown,
+		},
+		{
+			name:     "manifest - missing key",
+			req:      &pb.GetDownloadManifestRequest{Key: "missing.txt", Bucket: testbucket},
+			wantCode: codes.NotFound,
+		},
+		{
+			name:     "manifest - missing bucket",
+			req:      &pb.GetDownloadManifestRequest{Key: testkey, Bucket: "missing-bucket"},
+			wantCode: codes.NotFound,
+		},
+	}
+
+	client, closeClient := newServiceClient(t, download.NewService(s3Client, logger))
+	defer closeClient()
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			manifest, err := client.GetDownloadManifest(context.Background(), tt.req)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("DownloadService.GetDownloadManifest() error = %v, wantCode %v", err, tt.wantCode)
+			}
+
+			if tt.wantCode != codes.OK {
+				return
+			}
+
+			if manifest.GetSize() != tt.wantSize {
+				t.Errorf("DownloadManifest.Size = %d, want %d", manifest.GetSize(), tt.wantSize)
+			}
+
+			if manifest.GetEtag() == "" {
+				t.Errorf("DownloadManifest.Etag is empty")
+			}
+
+			if len(manifest.GetParts()) != len(tt.wantParts) {
+				t.Fatalf("DownloadManifest.Parts = %v, want %v", manifest.GetParts(), tt.wantParts)
+			}
+
+			for i, part := range manifest.GetParts() {
+				if part.GetOffset() != tt.wantParts[i].GetOffset() || part.GetLength() != tt.wantParts[i].GetLength() {
+					t.Errorf("DownloadManifest.Parts[%d] = %v, want %v", i, part, tt.wantParts[i])
+				}
+			}
+
+			if !tt.reassemble {
+				return
+			}
+
+			if got := reassembleManifest(t, client, manifest); !bytes.Equal(got, file) {
+				t.Errorf("reassembled file is different from the wanted file")
+			}
+		})
+	}
+}
+
+// reassembleManifest downloads every part of the manifest of testkey with a ranged download,
+// and returns the file reassembled from them.
+func reassembleManifest(t *testing.T, client pb.DownloadClient, manifest *pb.DownloadManifest) []byte {
+	t.Helper()
+
+	var got []byte
+	for _, part := range manifest.GetParts() {
+		stream, err := client.Download(context.Background(), &pb.DownloadRequest{
+			Key:         testkey,
+			Bucket:      testbucket,
+			RangeStart:  part.GetOffset(),
+			RangeEnd:    part.GetOffset() + part.GetLength() - 1,
+			IfRange:     manifest.GetEtag(),
+			IfRangeFail: true,
+		})
+		if err != nil {
+			t.Fatalf("DownloadService.Download() error = %v", err)
+		}
+
+		partBytes, err := recvAll(stream)
+		if err != nil {
+			t.Fatalf("DownloadService.Download() error = %v", err)
+		}
+
+		got = append(got, partBytes...)
+	}
+
+	return got
+}
@@ -0,0 +1,105 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	pb "github.com/meateam/download-service/proto"
+)
+
+// objectMetadata returns the metadata of the object of objectDetails, sent to clients before its bytes,
+// with the same encryption, checksum, ETag and caching details as its manifest.
+// S3 omits the storage class of STANDARD objects.
+func objectMetadata(objectDetails *s3.HeadObjectOutput) *pb.DownloadMetadata {
+	checksum, _ := headChecksum(objectDetails)
+	etag := aws.StringValue(objectDetails.ETag)
+	_, etagWeak := parseETag(etag)
+
+	objectMetadata := &pb.DownloadMetadata{
+		ContentType:          aws.StringValue(objectDetails.ContentType),
+		Size:                 aws.Int64Value(objectDetails.ContentLength),
+		Etag:                 etag,
+		EtagWeak:             etagWeak,
+		StorageClass:         aws.StringValue(objectDetails.StorageClass),
+		VersionId:            aws.StringValue(objectDetails.VersionId),
+		ServerSideEncryption: aws.StringValue(objectDetails.ServerSideEncryption),
+		SseKmsKeyId:          aws.StringValue(objectDetails.SSEKMSKeyId),
+		ChecksumAlgorithm:    checksum.algorithm,
+		Checksum:             checksum.value,
+		CacheControl:         aws.StringValue(objectDetails.CacheControl),
+		Expires:              aws.StringValue(objectDetails.Expires),
+	}
+
+	if objectMetadata.StorageClass == "" {
+		objectMetadata.StorageClass = s3.StorageClassStandard
+	}
+
+	if objectDetails.LastModified != nil {
+		objectMetadata.LastModified = objectDetails.LastModified.UnixNano() / int64(time.Millisecond)
+	}
+
+	return objectMetadata
+}
+
+// sendMetadata sends the metadata of the object of d as the first message of its stream,
+// if it was requested. The message has no file bytes, for clients that only read them.
+func (d *partDownload) sendMetadata() error {
+	if d.metadata == nil {
+		return nil
+	}
+
+	return d.stream.Send(&pb.DownloadResponse{Payload: &pb.DownloadResponse_Metadata{Metadata: d.metadata}})
+}
+
+// GetMetadata is the request to get the metadata of an object without downloading it.
+// It returns a NotFound error if the object or its bucket doesn't exist.
+func (s Service) GetMetadata(ctx context.Context, req *pb.GetMetadataRequest) (*pb.DownloadMetadata, error) {
+	if err := s.RequestLimits.check(req, []string{req.GetKey()}, req.GetUrl()); err != nil {
+		return nil, err
+	}
+
+	bucket, key, err := s.resolveObject(req.GetBucket(), req.GetKey(), req.GetUrl())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authorize(ctx, bucket, key); err != nil {
+		return nil, err
+	}
+
+	ctx = contextWithVersionID(ctx, req.GetVersionId())
+	objectDetails, err := s.headObject(ctx, bucket, key)
+	if err != nil {
+		return nil, s3ErrorToStatus(fmt.Errorf("failed to get metadata of object %s/%s: %w", bucket, key, err))
+	}
+
+	metadata := objectMetadata(objectDetails)
+	if req.GetRangeHints() {
+		if metadata.RangeHints, err = s.rangeHints(ctx, bucket, key); err != nil {
+			return nil, s3ErrorToStatus(fmt.Errorf("failed to get native parts of object %s/%s: %w", bucket, key, err))
+		}
+	}
+
+	return metadata, nil
+}
+
+// rangeHints returns the hints for aligning parallel range requests of bucket/key: the part size
+// the service downloads it in and the number of its native parts. The sizes of the native parts
+// aren't hinted, since they may differ and only the first part's size is known without a HeadObject
+// call per part.
+func (s Service) rangeHints(ctx context.Context, bucket string, key string) (*pb.RangeHints, error) {
+	partSize, partsCount, err := s.nativeParts(ctx, bucket, key)
+	if err != nil {
+		return nil, err
+	}
+
+	hints := &pb.RangeHints{SuggestedPartSize: s.defaultPartSize(), NativePartsCount: partsCount}
+	if partsCount > 1 && s.AlignToNativeParts {
+		hints.SuggestedPartSize = partSize
+	}
+
+	return hints, nil
+}
@@ -0,0 +1,337 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDownloadService_DownloadIncludeMetadata(t *testing.T) {
+	const metadataKey = "metadata.json"
+
+	object := file[:10000]
+	if _, err := s3Client.PutObject(&s3.PutObjectInput{
+		Bucket:      aws.String(testbucket),
+		Key:         aws.String(metadataKey),
+		Body:        bytes.NewReader(object),
+		ContentType: aws.String("application/json"),
+	}); err != nil {
+		t.Fatalf("failed to upload %s, %v", metadataKey, err)
+	}
+
+	head, err := s3Client.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(testbucket),
+		Key:    aws.String(metadataKey),
+	})
+	if err != nil {
+		t.Fatalf("failed to head %s, %v", metadataKey, err)
+	}
+
+	tests := []struct {
+		name            string
+		includeMetadata bool
+		rangeStart      int64
+		rangeEnd        int64
+	}{
+		{name: "metadata - excluded"},
+		{name: "metadata - included", includeMetadata: true},
+		{name: "metadata - included with a range", includeMetadata: true, rangeStart: 100, rangeEnd: 199},
+	}
+
+	service := download.NewService(s3Client, logger)
+	client, closeClient := newServiceClient(t, service)
+	defer closeClient()
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := client.Download(context.Background(), &pb.DownloadRequest{
+				Key:             metadataKey,
+				Bucket:          testbucket,
+				RangeStart:      tt.rangeStart,
+				RangeEnd:        tt.rangeEnd,
+				IncludeMetadata: tt.includeMetadata,
+			})
+			if err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			first, err := stream.Recv()
+			if err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			// Clients that only read the file bytes read none from the metadata message.
+			metadata := first.GetMetadata()
+			if (metadata != nil) != tt.includeMetadata || (metadata != nil && first.GetFile() != nil) {
+				t.Fatalf("DownloadService.Download() first message = %v, want metadata %v", first, tt.includeMetadata)
+			}
+
+			if metadata != nil {
+				wantMetadata := &pb.DownloadMetadata{
+					ContentType:  "application/json",
+					Size:         int64(len(object)),
+					Etag:         aws.StringValue(head.ETag),
+					LastModified: head.LastModified.UnixNano() / int64(time.Millisecond),
+					StorageClass: s3.StorageClassStandard,
+					VersionId:    aws.StringValue(head.VersionId),
+				}
+				if metadata.String() != wantMetadata.String() {
+					t.Errorf("DownloadService.Download() metadata = %v, want %v", metadata, wantMetadata)
+				}
+			}
+
+			rest, err := recvAll(stream)
+			if err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			want := object
+			if tt.rangeEnd != 0 {
+				want = object[tt.rangeStart : tt.rangeEnd+1]
+			}
+
+			if got := append(append([]byte(nil), first.GetFile()...), rest...); !bytes.Equal(got, want) {
+				t.Errorf("DownloadService.Download() file downloaded is different from the wanted file")
+			}
+		})
+	}
+}
+
+func TestDownloadService_GetMetadata(t *testing.T) {
+	head, err := s3Client.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(testbucket),
+		Key:    aws.String(testkey),
+	})
+	if err != nil {
+		t.Fatalf("failed to head %s, %v", testkey, err)
+	}
+
+	tests := []struct {
+		name     string
+		req      *pb.GetMetadataRequest
+		want     *pb.DownloadMetadata
+		wantCode codes.Code
+	}{
+		{
+			name: "metadata - existing object",
+			req:  &pb.GetMetadataRequest{Key: testkey, Bucket: testbucket},
+			want: &pb.DownloadMetadata{
+				ContentType:  aws.StringValue(head.ContentType),
+				Size:         int64(len(file)),
+				Etag:         aws.StringValue(head.ETag),
+				LastModified: head.LastModified.UnixNano() / int64(time.Millisecond),
+				StorageClass: s3.StorageClassStandard,
+				VersionId:    aws.StringValue(head.VersionId),
+			},
+		},
+		{
+			name: "metadata - object url",
+			req:  &pb.GetMetadataRequest{Url: "s3://" + testbucket + "/" + testkey},
+			want: &pb.DownloadMetadata{
+				ContentType:  aws.StringValue(head.ContentType),
+				Size:         int64(len(file)),
+				Etag:         aws.StringValue(head.ETag),
+				LastModified: head.LastModified.UnixNano() / int64(time.Millisecond),
+				StorageClass: s3.StorageClassStandard,
+				VersionId:    aws.StringValue(head.VersionId),
+			},
+		},
+		{
+			name:     "metadata - missing key",
+			req:      &pb.GetMetadataRequest{Key: "missing.txt", Bucket: testbucket},
+			wantCode: codes.NotFound,
+		},
+		{
+			name:     "metadata - missing bucket",
+			req:      &pb.GetMetadataRequest{Key: testkey, Bucket: "missing-bucket"},
+			wantCode: codes.NotFound,
+		},
+	}
+
+	service := download.NewService(s3Client, logger)
+	client, closeClient := newServiceClient(t, service)
+	defer closeClient()
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := client.GetMetadata(context.Background(), tt.req)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("DownloadService.GetMetadata() error = %v, want code %v", err, tt.wantCode)
+			}
+
+			if tt.want != nil && got.String() != tt.want.String() {
+				t.Errorf("DownloadService.GetMetadata() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDownloadService_GetMetadataMatchesManifest(t *testing.T) {
+	const (
+		cacheControl = "public, max-age=3600"
+		expires      = "Wed, 21 Oct 2026 07:28:00 GMT"
+	)
+
+	// The object was uploaded with a checksum and has caching headers.
+	client := checksummingS3Client(download.ChecksumAlgorithmSHA256, base64Checksum(sha256.New(), file))
+	client.Handlers.Send.PushBack(func(r *request.Request) {
+		if _, ok := r.Params.(*s3.HeadObjectInput); ok && r.HTTPResponse != nil {
+			r.HTTPResponse.Header.Set("Cache-Control", cacheControl)
+			r.HTTPResponse.Header.Set("Expires", expires)
+		}
+	})
+
+	serviceClient, closeClient := newServiceClient(t, download.NewService(client, logger))
+	defer closeClient()
+
+	metadata, err := serviceClient.GetMetadata(
+		context.Background(),
+		&pb.GetMetadataRequest{Key: testkey, Bucket: testbucket},
+	)
+	if err != nil {
+		t.Fatalf("DownloadService.GetMetadata() error = %v", err)
+	}
+
+	manifest, err := serviceClient.GetDownloadManifest(
+		context.Background(),
+		&pb.GetDownloadManifestRequest{Key: testkey, Bucket: testbucket},
+	)
+	if err != nil {
+		t.Fatalf("DownloadService.GetDownloadManifest() error = %v", err)
+	}
+
+	want := &pb.DownloadMetadata{
+		Etag:                 manifest.GetEtag(),
+		EtagWeak:             manifest.GetEtagWeak(),
+		ServerSideEncryption: manifest.GetServerSideEncryption(),
+		SseKmsKeyId:          manifest.GetSseKmsKeyId(),
+		ChecksumAlgorithm:    manifest.GetChecksumAlgorithm(),
+		Checksum:             manifest.GetChecksum(),
+		CacheControl:         manifest.GetCacheControl(),
+		Expires:              manifest.GetExpires(),
+	}
+	got := &pb.DownloadMetadata{
+		Etag:                 metadata.GetEtag(),
+		EtagWeak:             metadata.GetEtagWeak(),
+		ServerSideEncryption: metadata.GetServerSideEncryption(),
+		SseKmsKeyId:          metadata.GetSseKmsKeyId(),
+		ChecksumAlgorithm:    metadata.GetChecksumAlgorithm(),
+		Checksum:             metadata.GetChecksum(),
+		CacheControl:         metadata.GetCacheControl(),
+		Expires:              metadata.GetExpires(),
+	}
+	if got.String() != want.String() {
+		t.Errorf("DownloadService.GetMetadata() = %v, want the details of the manifest %v", got, want)
+	}
+
+	if got.GetChecksumAlgorithm() != download.ChecksumAlgorithmSHA256 || got.GetCacheControl() != cacheControl {
+		t.Errorf("DownloadService.GetMetadata() = %v, want the checksum and caching headers of the object", got)
+	}
+}
+
+// multipartHeadS3Client returns an S3 client whose HeadObject calls describe an object of size bytes
+// uploaded in parts of partSize bytes, or in a single part if partSize is zero.
+func multipartHeadS3Client(size int64, partSize int64) *s3.S3 {
+	client := s3.New(session.Must(session.NewSession(&s3Client.Config)))
+	client.Handlers.Send.Clear()
+	client.Handlers.Send.PushBack(func(r *request.Request) {
+		header := http.Header{}
+		header.Set("Content-Length", strconv.FormatInt(size, 10))
+		header.Set("ETag", `"multipart"`)
+		if input, ok := r.Params.(*s3.HeadObjectInput); ok && input.PartNumber != nil && partSize > 0 {
+			header.Set("Content-Length", strconv.FormatInt(partSize, 10))
+			header.Set("X-Amz-Mp-Parts-Count", strconv.FormatInt((size+partSize-1)/partSize, 10))
+		}
+
+		r.HTTPResponse = &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     header,
+			Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+		}
+	})
+
+	return client
+}
+
+func TestDownloadService_GetMetadataRangeHints(t *testing.T) {
+	const nativePartSize = 6 << 20
+
+	tests := []struct {
+		name       string
+		size       int64
+		partSize   int64
+		rangeHints bool
+		align      bool
+		want       *pb.RangeHints
+	}{
+		{name: "range hints - not requested", size: 2*nativePartSize + 1, partSize: nativePartSize},
+		{
+			name:       "range hints - single-part",
+			size:       1 << 20,
+			rangeHints: true,
+			want:       &pb.RangeHints{SuggestedPartSize: download.PartSize, NativePartsCount: 1},
+		},
+		{
+			name:       "range hints - multipart",
+			size:       2*nativePartSize + 1,
+			partSize:   nativePartSize,
+			rangeHints: true,
+			want: &pb.RangeHints{
+				SuggestedPartSize: download.PartSize,
+				NativePartsCount:  3,
+			},
+		},
+		{
+			name:       "range hints - multipart aligned to native parts",
+			size:       2 * nativePartSize,
+			partSize:   nativePartSize,
+			rangeHints: true,
+			align:      true,
+			want: &pb.RangeHints{
+				SuggestedPartSize: nativePartSize,
+				NativePartsCount:  2,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			service := download.NewService(multipartHeadS3Client(tt.size, tt.partSize), logger)
+			service.AlignToNativeParts = tt.align
+
+			metadata, err := service.GetMetadata(
+				context.Background(),
+				&pb.GetMetadataRequest{Key: testkey, Bucket: testbucket, RangeHints: tt.rangeHints},
+			)
+			if err != nil {
+				t.Fatalf("DownloadService.GetMetadata() error = %v", err)
+			}
+
+			if metadata.GetSize() != tt.size {
+				t.Errorf("DownloadService.GetMetadata() size = %d, want %d", metadata.GetSize(), tt.size)
+			}
+
+			if got := metadata.GetRangeHints(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DownloadService.GetMetadata() range hints = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
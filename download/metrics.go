@@ -0,0 +1,52 @@
+package download
+
+import (
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Metrics receives the measurements of downloads, to export them to a monitoring system such as Prometheus.
+// Its methods are called synchronously by the downloads, so they must be fast and concurrency-safe.
+type Metrics interface {
+	// ObservePart observes a part of bytes bytes that took duration to fetch from S3, of the download
+	// whose trace id is traceID, empty if the download isn't traced.
+	ObservePart(duration time.Duration, bytes int64, traceID string)
+
+	// ObserveDownload observes a finished download of a key whose prefix label is keyPrefix,
+	// as returned by KeyPrefixLabel, that streamed bytes bytes and ended with code.
+	ObserveDownload(code codes.Code, keyPrefix string, bytes int64)
+
+	// ObserveFailover observes a bucket failed over to its replica in region.
+	ObserveFailover(region string)
+}
+
+// observePart reports a part of bytes bytes fetched in duration, of the download whose trace id is traceID,
+// to s.Metrics, if set.
+func (s Service) observePart(duration time.Duration, bytes int64, traceID string) {
+	if s.Metrics == nil {
+		return
+	}
+
+	s.Metrics.ObservePart(duration, bytes, traceID)
+}
+
+// observeDownload reports a download of a key whose prefix label is keyPrefix, that streamed bytesSent bytes
+// and ended with err, to s.Metrics, if set.
+func (s Service) observeDownload(keyPrefix string, bytesSent int64, err error) {
+	if s.Metrics == nil {
+		return
+	}
+
+	s.Metrics.ObserveDownload(status.Code(err), keyPrefix, bytesSent)
+}
+
+// observeFailover reports a bucket failed over to its replica in region to s.Metrics, if set.
+func (s Service) observeFailover(region string) {
+	if s.Metrics == nil {
+		return
+	}
+
+	s.Metrics.ObserveFailover(region)
+}
@@ -0,0 +1,106 @@
+package download_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// recordingMetrics is a download.Metrics that records its observations.
+type recordingMetrics struct {
+	mu        sync.Mutex
+	parts     int64
+	partBytes int64
+	codes     []codes.Code
+	prefixes  []string
+	bytes     int64
+	failovers []string
+}
+
+func (m *recordingMetrics) ObservePart(_ time.Duration, bytes int64, _ string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.parts++
+	m.partBytes += bytes
+}
+
+func (m *recordingMetrics) ObserveDownload(code codes.Code, keyPrefix string, bytes int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.codes = append(m.codes, code)
+	m.prefixes = append(m.prefixes, keyPrefix)
+	m.bytes += bytes
+}
+
+func (m *recordingMetrics) ObserveFailover(region string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.failovers = append(m.failovers, region)
+}
+
+func TestDownloadService_DownloadMetrics(t *testing.T) {
+	tests := []struct {
+		name      string
+		key       string
+		wantCode  codes.Code
+		wantParts int64
+		wantBytes int64
+	}{
+		{
+			name:      "metrics - downloaded",
+			key:       testkey,
+			wantCode:  codes.OK,
+			wantParts: int64(len(file) / download.MinPartSize),
+			wantBytes: int64(len(file)),
+		},
+		{name: "metrics - not found", key: "missing", wantCode: codes.NotFound},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			metrics := &recordingMetrics{}
+			service := download.NewService(s3Client, logger)
+			service.PartSize = download.MinPartSize
+			service.Metrics = metrics
+
+			stream := &hashingDownloadStream{ctx: context.Background(), hash: sha256.New()}
+			err := service.Download(&pb.DownloadRequest{Key: tt.key, Bucket: testbucket}, stream)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("DownloadService.Download() error = %v, want code %v", err, tt.wantCode)
+			}
+
+			if len(metrics.codes) != 1 || metrics.codes[0] != tt.wantCode {
+				t.Errorf("DownloadService.Download() observed downloads %v, want a single %v", metrics.codes, tt.wantCode)
+			}
+
+			if len(metrics.prefixes) != 1 || metrics.prefixes[0] != download.OtherKeyPrefix {
+				t.Errorf(
+					"DownloadService.Download() observed key prefixes %v, want a single %q",
+					metrics.prefixes, download.OtherKeyPrefix,
+				)
+			}
+
+			if metrics.bytes != tt.wantBytes || metrics.partBytes != tt.wantBytes {
+				t.Errorf(
+					"DownloadService.Download() observed %d bytes streamed and %d bytes of parts, want %d",
+					metrics.bytes, metrics.partBytes, tt.wantBytes,
+				)
+			}
+
+			if metrics.parts != tt.wantParts {
+				t.Errorf("DownloadService.Download() observed %d parts, want %d", metrics.parts, tt.wantParts)
+			}
+		})
+	}
+}
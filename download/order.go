@@ -0,0 +1,34 @@
+package download
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// orderAssertion asserts that the chunks of a download are sent in order,
+// catching reassembly bugs before they corrupt the client's file.
+type orderAssertion struct {
+	next int64
+}
+
+// newOrderAssertion creates an orderAssertion of a download whose first chunk starts at offset start.
+func newOrderAssertion(start int64) *orderAssertion {
+	return &orderAssertion{next: start}
+}
+
+// check returns an Internal error if the chunk of length bytes at offset doesn't
+// directly follow the last chunk checked, otherwise it advances past the chunk.
+// A nil orderAssertion accepts every chunk.
+func (a *orderAssertion) check(offset int64, length int64) error {
+	if a == nil {
+		return nil
+	}
+
+	if offset != a.next {
+		return status.Errorf(codes.Internal, "out of order chunk at offset %d, expected offset %d", offset, a.next)
+	}
+
+	a.next += length
+
+	return nil
+}
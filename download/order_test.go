@@ -0,0 +1,105 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+
+	pb "github.com/meateam/download-service/proto"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// recordingDownloadStream is a pb.Download_DownloadServer that records the chunks sent on it.
+type recordingDownloadStream struct {
+	grpc.ServerStream
+	chunks [][]byte
+}
+
+func (s *recordingDownloadStream) Context() context.Context {
+	return context.Background()
+}
+
+func (s *recordingDownloadStream) Send(res *pb.DownloadResponse) error {
+	s.chunks = append(s.chunks, append([]byte(nil), res.GetFile()...))
+	return nil
+}
+
+func TestOrderAssertion_check(t *testing.T) {
+	tests := []struct {
+		name     string
+		start    int64
+		chunks   [][2]int64
+		wantCode codes.Code
+	}{
+		{name: "in order", start: 0, chunks: [][2]int64{{0, 10}, {10, 10}, {20, 5}}, wantCode: codes.OK},
+		{name: "in order from offset", start: 100, chunks: [][2]int64{{100, 10}, {110, 10}}, wantCode: codes.OK},
+		{name: "reordered", start: 0, chunks: [][2]int64{{10, 10}, {0, 10}}, wantCode: codes.Internal},
+		{name: "repeated", start: 0, chunks: [][2]int64{{0, 10}, {0, 10}}, wantCode: codes.Internal},
+		{name: "gap", start: 0, chunks: [][2]int64{{0, 10}, {11, 10}}, wantCode: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			order := newOrderAssertion(tt.start)
+
+			var err error
+			for _, chunk := range tt.chunks {
+				if err = order.check(chunk[0], chunk[1]); err != nil {
+					break
+				}
+			}
+
+			if status.Code(err) != tt.wantCode {
+				t.Errorf("orderAssertion.check() error = %v, wantCode %v", err, tt.wantCode)
+			}
+		})
+	}
+
+	// A nil orderAssertion is disabled.
+	var disabled *orderAssertion
+	if err := disabled.check(10, 10); err != nil {
+		t.Errorf("nil orderAssertion.check() error = %v, want nil", err)
+	}
+}
+
+func TestService_sendPartStrictOrderAssert(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	s := NewService(nil, logger)
+
+	parts := [][]byte{[]byte("first part"), []byte("second part")}
+	stream := &recordingDownloadStream{}
+	order := newOrderAssertion(0)
+	buffer := make([]byte, 4)
+
+	// Simulate a reassembly bug which sends the second part before the first.
+	_, err := s.sendPart(stream, bytes.NewReader(parts[1]), buffer, 1, int64(len(parts[0])), order, "")
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("Service.sendPart() error = %v, wantCode %v", err, codes.Internal)
+	}
+
+	if len(stream.chunks) != 0 {
+		t.Errorf("Service.sendPart() sent %d out of order chunks, want 0", len(stream.chunks))
+	}
+
+	// Sending the parts in order succeeds.
+	order = newOrderAssertion(0)
+	offset := int64(0)
+	for i, part := range parts {
+		sent, err := s.sendPart(stream, bytes.NewReader(part), buffer, int64(i), offset, order, "")
+		if err != nil {
+			t.Fatalf("Service.sendPart() error = %v", err)
+		}
+
+		offset += sent
+	}
+
+	if got := bytes.Join(stream.chunks, nil); !bytes.Equal(got, bytes.Join(parts, nil)) {
+		t.Errorf("Service.sendPart() sent %q, want %q", got, bytes.Join(parts, nil))
+	}
+}
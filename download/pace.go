@@ -0,0 +1,112 @@
+package download
+
+import (
+	"context"
+	"time"
+
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// tokenBucket paces bytes to the rate returned by bytesPerSec, which may change between sends.
+// The bucket starts empty and holds up to a second of bytes, so a stream that fell behind,
+// e.g. waiting for S3, may catch up in a burst of up to a second.
+type tokenBucket struct {
+	bytesPerSec func() int64
+	rate        float64
+	tokens      float64
+	last        time.Time
+}
+
+// newTokenBucket returns an empty tokenBucket of the current rate returned by bytesPerSec.
+func newTokenBucket(bytesPerSec func() int64) *tokenBucket {
+	return &tokenBucket{bytesPerSec: bytesPerSec, rate: float64(bytesPerSec()), last: time.Now()}
+}
+
+// wait waits until the bucket has the tokens for n bytes, or returns ctx's error if it's done first.
+func (b *tokenBucket) wait(ctx context.Context, n int) error {
+	now := time.Now()
+	elapsed := now.Sub(b.last)
+	if elapsed > time.Second {
+		elapsed = time.Second
+	}
+	b.last = now
+
+	// Refill at the rate the elapsed time was paced at, a higher rate applies only from now on.
+	b.tokens += elapsed.Seconds() * b.rate
+	b.rate = float64(b.bytesPerSec())
+	if b.tokens > b.rate {
+		b.tokens = b.rate
+	}
+
+	// Go into debt for the bytes and wait until it's paid off, the wait counts towards the next refill.
+	b.tokens -= float64(n)
+	if b.tokens < 0 {
+		wait := time.Duration(-b.tokens / b.rate * float64(time.Second))
+		if err := sleepContext(ctx, wait); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pacedDownloadStream is a pb.Download_DownloadServer that paces the file bytes sent on it
+// with a tokenBucket.
+type pacedDownloadStream struct {
+	pb.Download_DownloadServer
+	bucket *tokenBucket
+}
+
+// newPacedDownloadStream returns a pacedDownloadStream pacing stream to bytesPerSec.
+func newPacedDownloadStream(stream pb.Download_DownloadServer, bytesPerSec int64) *pacedDownloadStream {
+	return newSharedPacedDownloadStream(stream, func() int64 { return bytesPerSec })
+}
+
+// newSharedPacedDownloadStream returns a pacedDownloadStream pacing stream to the current rate
+// returned by bytesPerSec.
+func newSharedPacedDownloadStream(
+	stream pb.Download_DownloadServer,
+	bytesPerSec func() int64,
+) *pacedDownloadStream {
+	return &pacedDownloadStream{Download_DownloadServer: stream, bucket: newTokenBucket(bytesPerSec)}
+}
+
+// Send sends res on the underlying stream once the bucket has the tokens for its bytes,
+// or returns the stream context's error if it's done first.
+func (s *pacedDownloadStream) Send(res *pb.DownloadResponse) error {
+	if err := s.bucket.wait(s.Context(), len(res.GetFile())); err != nil {
+		return err
+	}
+
+	return s.Download_DownloadServer.Send(res)
+}
+
+// streamRate returns the rate in bytes per second that the download of req is paced to, the rate
+// of req if it's lower than s.PerStreamMaxBytesPerSec or the latter is 0, otherwise the latter.
+// Zero disables pacing. It returns an InvalidArgument error if the rate of req is negative.
+func (s Service) streamRate(req *pb.DownloadRequest) (int64, error) {
+	rate := req.GetMaxBytesPerSec()
+	if rate < 0 {
+		return 0, status.Errorf(codes.InvalidArgument, "max bytes per sec must not be negative, got %d", rate)
+	}
+
+	if rate == 0 || (s.PerStreamMaxBytesPerSec > 0 && rate > s.PerStreamMaxBytesPerSec) {
+		rate = s.PerStreamMaxBytesPerSec
+	}
+
+	return rate, nil
+}
+
+// paceStream paces the stream of d to the rate of req, if limited.
+func (s Service) paceStream(req *pb.DownloadRequest, d *partDownload) error {
+	rate, err := s.streamRate(req)
+	if err != nil || rate <= 0 {
+		return err
+	}
+
+	d.stream = newPacedDownloadStream(d.stream, rate)
+
+	return nil
+}
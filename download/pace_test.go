@@ -0,0 +1,125 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDownloadService_DownloadPerStreamPacing(t *testing.T) {
+	const bytesPerSec = 4 << 20
+
+	service := download.NewService(s3Client, logger)
+	service.MaxBufferSize = 256 << 10
+	service.PerStreamMaxBytesPerSec = bytesPerSec
+	stream := &hashingDownloadStream{ctx: context.Background(), hash: sha256.New()}
+
+	start := time.Now()
+	if err := service.Download(&pb.DownloadRequest{Key: testkey, Bucket: testbucket}, stream); err != nil {
+		t.Fatalf("DownloadService.Download() error = %v", err)
+	}
+	elapsed := time.Since(start)
+
+	wantHash := sha256.Sum256(file)
+	if !bytes.Equal(stream.hash.Sum(nil), wantHash[:]) {
+		t.Errorf("DownloadService.Download() file downloaded is different from the wanted file")
+	}
+
+	if throughput := float64(len(file)) / elapsed.Seconds(); throughput > bytesPerSec {
+		t.Errorf("DownloadService.Download() throughput = %.0f bytes/sec, want at most %d", throughput, bytesPerSec)
+	}
+}
+
+func TestDownloadService_DownloadPerStreamPacingCancel(t *testing.T) {
+	service := download.NewService(s3Client, logger)
+	service.MaxBufferSize = 256 << 10
+	service.PerStreamMaxBytesPerSec = 64 << 10
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	stream := &hashingDownloadStream{ctx: ctx, hash: sha256.New()}
+
+	// Pacing the whole file would take half a minute, the download must end with its context.
+	start := time.Now()
+	err := service.Download(&pb.DownloadRequest{Key: testkey, Bucket: testbucket}, stream)
+	if err == nil {
+		t.Fatalf("DownloadService.Download() error = nil, want the context's error")
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("DownloadService.Download() took %s after its context was done", elapsed)
+	}
+}
+
+func TestDownloadService_DownloadRequestPacing(t *testing.T) {
+	const bytesPerSec = 4 << 20
+
+	tests := []struct {
+		name        string
+		serviceRate int64
+		requestRate int64
+		wantCode    codes.Code
+		wantMaxRate float64
+	}{
+		{
+			name:        "request pacing - lower than the service's rate",
+			serviceRate: 1 << 30,
+			requestRate: bytesPerSec,
+			wantMaxRate: bytesPerSec,
+		},
+		{
+			name:        "request pacing - higher than the service's rate",
+			serviceRate: bytesPerSec,
+			requestRate: 1 << 30,
+			wantMaxRate: bytesPerSec,
+		},
+		{
+			name:        "request pacing - service not paced",
+			requestRate: bytesPerSec,
+			wantMaxRate: bytesPerSec,
+		},
+		{
+			name:        "request pacing - negative rate",
+			requestRate: -1,
+			wantCode:    codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			service := download.NewService(s3Client, logger)
+			service.MaxBufferSize = 256 << 10
+			service.PerStreamMaxBytesPerSec = tt.serviceRate
+			stream := &hashingDownloadStream{ctx: context.Background(), hash: sha256.New()}
+
+			start := time.Now()
+			err := service.Download(
+				&pb.DownloadRequest{Key: testkey, Bucket: testbucket, MaxBytesPerSec: tt.requestRate},
+				stream,
+			)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("DownloadService.Download() error = %v, want code %v", err, tt.wantCode)
+			}
+
+			if tt.wantCode != codes.OK {
+				return
+			}
+
+			throughput := float64(len(file)) / time.Since(start).Seconds()
+			if throughput > tt.wantMaxRate {
+				t.Errorf(
+					"DownloadService.Download() throughput = %.0f bytes/sec, want at most %.0f",
+					throughput, tt.wantMaxRate,
+				)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const (
+	// DefaultPartRetryBaseDelay is the default delay before the first retry of a part that failed transiently.
+	DefaultPartRetryBaseDelay = 100 * time.Millisecond
+
+	// maxPartRetryDelay caps the backoff between the retries of a part.
+	maxPartRetryDelay = 30 * time.Second
+
+	// s3RequestErrorCode is the code of the SDK's errors of requests that failed to be sent,
+	// e.g. by a refused or reset connection.
+	s3RequestErrorCode = "RequestError"
+)
+
+// isTransientS3Error returns true if err is a failure of an S3 call that may succeed when retried,
+// a server error, e.g. while MinIO is rebalancing, or a failure to connect.
+// Errors of the request itself, such as a missing object or denied access, aren't transient.
+func isTransientS3Error(err error) bool {
+	var reqErr awserr.RequestFailure
+	if errors.As(err, &reqErr) && reqErr.StatusCode() >= http.StatusInternalServerError {
+		return true
+	}
+
+	var aerr awserr.Error
+
+	return errors.As(err, &aerr) && aerr.Code() == s3RequestErrorCode
+}
+
+// partRetryDelay returns the delay before the retry number retry, counted from zero, of a part,
+// a random delay between half and all of s.PartRetryBaseDelay doubled for every retry so far,
+// at most maxPartRetryDelay.
+func (s Service) partRetryDelay(retry int) time.Duration {
+	baseDelay := s.PartRetryBaseDelay
+	if baseDelay <= 0 {
+		baseDelay = DefaultPartRetryBaseDelay
+	}
+
+	delay := maxPartRetryDelay
+	if retry < 62 && baseDelay < maxPartRetryDelay>>uint(retry) {
+		delay = baseDelay << uint(retry)
+	}
+
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// getPartObject gets the part of getObjectInput from bucket, retrying it up to s.PartRetries times
+// after transient failures with a jittered exponential backoff, and then failing it over to the replicas
+// of bucket, if the download fails over. It stops retrying as soon as ctx is done, returning ctx's error.
+func (s Service) getPartObject(
+	ctx context.Context,
+	bucket string,
+	getObjectInput *s3.GetObjectInput,
+) (*s3.GetObjectOutput, error) {
+	getObjectInput.VersionId = versionIDFromContext(ctx)
+
+	var output *s3.GetObjectOutput
+	err := s.callWithFailover(ctx, bucket, func(bucket string) error {
+		var err error
+		getObjectInput.Bucket = aws.String(bucket)
+		output, err = s.retryPartObject(ctx, bucket, getObjectInput)
+
+		return err
+	})
+
+	return output, err
+}
+
+// retryPartObject gets the part of getObjectInput from bucket, retrying it up to s.PartRetries times
+// after transient failures, and records the exhaustion of its retries in ctx. It stops retrying as soon
+// as ctx is done, returning ctx's error.
+func (s Service) retryPartObject(
+	ctx context.Context,
+	bucket string,
+	getObjectInput *s3.GetObjectInput,
+) (*s3.GetObjectOutput, error) {
+	client := s.s3ClientFor(ctx, bucket)
+	output, err := client.GetObjectWithContext(ctx, getObjectInput)
+	for retry := 0; retry < s.PartRetries && err != nil && isTransientS3Error(err); retry++ {
+		timer := time.NewTimer(s.partRetryDelay(retry))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		output, err = client.GetObjectWithContext(ctx, getObjectInput)
+	}
+
+	// The part failed though it may succeed later, after all of its retries.
+	if err != nil && isTransientS3Error(err) {
+		recordRetryExhaustion(ctx, err)
+	}
+
+	return output, err
+}
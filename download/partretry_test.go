@@ -0,0 +1,161 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// partFailingS3Client returns an S3 client whose first failures ranged GetObject calls fail with err,
+// counting its ranged GetObject calls in calls.
+func partFailingS3Client(err error, failures int64, calls *int64) *s3.S3 {
+	client := s3.New(session.Must(session.NewSession(&s3Client.Config)))
+	client.Handlers.Validate.PushBack(func(r *request.Request) {
+		if input, ok := r.Params.(*s3.GetObjectInput); !ok || input.Range == nil {
+			return
+		}
+
+		if atomic.AddInt64(calls, 1) <= failures {
+			r.Error = err
+		}
+	})
+
+	return client
+}
+
+func TestDownloadService_DownloadPartRetry(t *testing.T) {
+	serverError := awserr.NewRequestFailure(
+		awserr.New("InternalError", "injected server error", nil),
+		http.StatusInternalServerError,
+		"",
+	)
+
+	tests := []struct {
+		name      string
+		err       error
+		failures  int64
+		retries   int
+		wantCode  codes.Code
+		wantCalls int64
+	}{
+		{
+			name:      "part retry - transient server error",
+			err:       serverError,
+			failures:  2,
+			retries:   3,
+			wantCode:  codes.OK,
+			wantCalls: 3,
+		},
+		{
+			name:      "part retry - transient connection error",
+			err:       awserr.New("RequestError", "injected connection reset", nil),
+			failures:  1,
+			retries:   1,
+			wantCode:  codes.OK,
+			wantCalls: 2,
+		},
+		{
+			name:      "part retry - retries exhausted",
+			err:       serverError,
+			failures:  4,
+			retries:   3,
+			wantCode:  codes.Unknown,
+			wantCalls: 4,
+		},
+		{
+			name:      "part retry - disabled",
+			err:       serverError,
+			failures:  1,
+			wantCode:  codes.Unknown,
+			wantCalls: 1,
+		},
+		{
+			name:      "part retry - no such key",
+			err:       awserr.New(s3.ErrCodeNoSuchKey, "injected missing key", nil),
+			failures:  1,
+			retries:   3,
+			wantCode:  codes.NotFound,
+			wantCalls: 1,
+		},
+		{
+			name:      "part retry - access denied",
+			err:       awserr.New("AccessDenied", "injected denied access", nil),
+			failures:  1,
+			retries:   3,
+			wantCode:  codes.PermissionDenied,
+			wantCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int64
+			service := download.NewService(partFailingS3Client(tt.err, tt.failures, &calls), logger)
+			service.MaxBufferSize = int64(len(file))
+			service.PartRetries = tt.retries
+			service.PartRetryBaseDelay = time.Millisecond
+
+			stream := &hashingDownloadStream{ctx: context.Background(), hash: sha256.New()}
+			err := service.Download(&pb.DownloadRequest{Key: testkey, Bucket: testbucket}, stream)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("DownloadService.Download() error = %v, want code %v", err, tt.wantCode)
+			}
+
+			if got := atomic.LoadInt64(&calls); got != tt.wantCalls {
+				t.Errorf("DownloadService.Download() called GetObject %d times, want %d", got, tt.wantCalls)
+			}
+
+			wantHash := sha256.Sum256(file)
+			if tt.wantCode == codes.OK && !bytes.Equal(stream.hash.Sum(nil), wantHash[:]) {
+				t.Errorf("DownloadService.Download() file downloaded is different from the wanted file")
+			}
+		})
+	}
+}
+
+func TestDownloadService_DownloadPartRetryCancelled(t *testing.T) {
+	serverError := awserr.NewRequestFailure(
+		awserr.New("InternalError", "injected server error", nil),
+		http.StatusInternalServerError,
+		"",
+	)
+
+	var calls int64
+	service := download.NewService(partFailingS3Client(serverError, 1, &calls), logger)
+	service.MaxBufferSize = int64(len(file))
+	service.PartRetries = 3
+	service.PartRetryBaseDelay = time.Hour
+
+	// Cancel the download while it backs off before retrying the part.
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	stream := &hashingDownloadStream{ctx: ctx, hash: sha256.New()}
+	err := service.Download(&pb.DownloadRequest{Key: testkey, Bucket: testbucket}, stream)
+	if status.Code(err) != codes.Canceled {
+		t.Errorf("DownloadService.Download() error = %v, want code %v", err, codes.Canceled)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("DownloadService.Download() returned %v after the cancellation, want it to stop retrying", elapsed)
+	}
+
+	if got := atomic.LoadInt64(&calls); got != 1 {
+		t.Errorf("DownloadService.Download() called GetObject %d times, want 1", got)
+	}
+}
@@ -0,0 +1,48 @@
+package download
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// nativePartSize returns the size of the native parts of bucket/key if it was uploaded
+// as multipart, the size of its first part, or zero for single-part objects.
+// Multipart uploads have equally sized parts, except for the last one.
+func (s Service) nativePartSize(ctx context.Context, bucket string, key string) (int64, error) {
+	partSize, _, err := s.nativeParts(ctx, bucket, key)
+
+	return partSize, err
+}
+
+// nativeParts returns the size of the first native part of bucket/key and the number of its native parts
+// if it was uploaded as multipart, or zero and one for single-part objects.
+func (s Service) nativeParts(ctx context.Context, bucket string, key string) (int64, int64, error) {
+	headSpan := s.startClientSpan(ctx, "s3.HeadObject", opentracing.Tags{
+		"s3.bucket":      bucket,
+		"s3.key":         key,
+		"s3.part_number": 1,
+	})
+	firstPart, err := s.s3ClientFor(ctx, bucket).HeadObjectWithContext(
+		ctx,
+		&s3.HeadObjectInput{
+			Bucket:     aws.String(bucket),
+			Key:        aws.String(key),
+			PartNumber: aws.Int64(1),
+			VersionId:  versionIDFromContext(ctx),
+		},
+	)
+	finishSpan(headSpan, err)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	partsCount := aws.Int64Value(firstPart.PartsCount)
+	if partsCount <= 1 {
+		return 0, 1, nil
+	}
+
+	return aws.Int64Value(firstPart.ContentLength), partsCount, nil
+}
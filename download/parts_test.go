@@ -0,0 +1,112 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+)
+
+func TestDownloadService_DownloadAlignToNativeParts(t *testing.T) {
+	const (
+		multipartKey      = "multipart.txt"
+		multipartPartSize = 6 << 20
+		rangeStart        = 3 << 20
+	)
+
+	// Upload a multipart fixture whose native parts are larger than download.PartSize.
+	multipartFile := make([]byte, 2*multipartPartSize+(1<<20))
+	if _, err := rand.Read(multipartFile); err != nil {
+		t.Fatalf("failed to generate file, %v", err)
+	}
+
+	uploader := s3manager.NewUploaderWithClient(s3Client, func(u *s3manager.Uploader) {
+		u.PartSize = multipartPartSize
+	})
+	if _, err := uploader.Upload(&s3manager.UploadInput{
+		Bucket: aws.String(testbucket),
+		Key:    aws.String(multipartKey),
+		Body:   bytes.NewReader(multipartFile),
+	}); err != nil {
+		t.Fatalf("failed to upload file, %v", err)
+	}
+
+	// Expect ranges aligned to the native parts if the S3 server reports them,
+	// otherwise fixed download.PartSize ranges from the range start.
+	var wantRanges []string
+	firstPart, err := s3Client.HeadObject(&s3.HeadObjectInput{
+		Bucket:     aws.String(testbucket),
+		Key:        aws.String(multipartKey),
+		PartNumber: aws.Int64(1),
+	})
+	if err == nil && aws.Int64Value(firstPart.PartsCount) > 1 {
+		wantRanges = []string{
+			fmt.Sprintf("bytes=%d-%d", rangeStart, multipartPartSize-1),
+			fmt.Sprintf("bytes=%d-%d", multipartPartSize, 2*multipartPartSize-1),
+			fmt.Sprintf("bytes=%d-%d", 2*multipartPartSize, len(multipartFile)-1),
+		}
+	} else {
+		t.Logf("S3 server doesn't report native parts, expecting fixed part ranges")
+		for start := int64(rangeStart); start < int64(len(multipartFile)); start += download.PartSize {
+			end := start + download.PartSize - 1
+			if end >= int64(len(multipartFile)) {
+				end = int64(len(multipartFile)) - 1
+			}
+
+			wantRanges = append(wantRanges, fmt.Sprintf("bytes=%d-%d", start, end))
+		}
+	}
+
+	// Record the ranges of the GetObject calls.
+	var mu sync.Mutex
+	var gotRanges []string
+	recordingClient := s3.New(session.Must(session.NewSession(&s3Client.Config)))
+	recordingClient.Handlers.Send.PushFront(func(r *request.Request) {
+		if r.Operation.Name == "GetObject" {
+			mu.Lock()
+			gotRanges = append(gotRanges, r.HTTPRequest.Header.Get("Range"))
+			mu.Unlock()
+		}
+	})
+
+	service := download.NewService(recordingClient, logger)
+	service.AlignToNativeParts = true
+	service.MaxBufferSize = 1 << 20
+
+	client, closeClient := newServiceClient(t, service)
+	defer closeClient()
+
+	stream, err := client.Download(context.Background(), &pb.DownloadRequest{
+		Key:        multipartKey,
+		Bucket:     testbucket,
+		RangeStart: rangeStart,
+	})
+	if err != nil {
+		t.Fatalf("DownloadService.Download() error = %v", err)
+	}
+
+	got, err := recvAll(stream)
+	if err != nil {
+		t.Fatalf("DownloadService.Download() error = %v", err)
+	}
+
+	if !bytes.Equal(got, multipartFile[rangeStart:]) {
+		t.Errorf("DownloadService.Download() file downloaded is different from the wanted file")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if fmt.Sprint(gotRanges) != fmt.Sprint(wantRanges) {
+		t.Errorf("DownloadService.Download() GetObject ranges = %v, want %v", gotRanges, wantRanges)
+	}
+}
@@ -0,0 +1,164 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDownloadService_DownloadChunkSize(t *testing.T) {
+	const chunksKey = "chunks.bin"
+
+	object := make([]byte, 3<<20+777)
+	rand.New(rand.NewSource(2)).Read(object)
+	if _, err := s3Client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(testbucket),
+		Key:    aws.String(chunksKey),
+		Body:   bytes.NewReader(object),
+	}); err != nil {
+		t.Fatalf("failed to upload %s, %v", chunksKey, err)
+	}
+
+	tests := []struct {
+		name         string
+		partSize     int64
+		chunkSize    int64
+		wantPartSize int64
+		wantCode     codes.Code
+	}{
+		{name: "chunk size - default", wantPartSize: download.PartSize},
+		{name: "chunk size - minimum", chunkSize: download.MinPartSize, wantPartSize: download.MinPartSize},
+		{name: "chunk size - 1MiB", chunkSize: 1 << 20, wantPartSize: 1 << 20},
+		{name: "chunk size - unaligned", chunkSize: 1<<20 + 1, wantPartSize: 1<<20 + 1},
+		{name: "chunk size - maximum", chunkSize: download.MaxPartSize, wantPartSize: download.PartSize},
+		{name: "chunk size - service part size", partSize: 512 << 10, wantPartSize: 512 << 10},
+		{
+			name:         "chunk size - overrides service part size",
+			partSize:     512 << 10,
+			chunkSize:    download.MinPartSize,
+			wantPartSize: download.MinPartSize,
+		},
+		{
+			name:         "chunk size - too small",
+			chunkSize:    download.MinPartSize - 1,
+			wantPartSize: download.PartSize,
+			wantCode:     codes.InvalidArgument,
+		},
+		{
+			name:         "chunk size - too large",
+			chunkSize:    download.MaxPartSize + 1,
+			wantPartSize: download.PartSize,
+			wantCode:     codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			service := download.NewService(s3Client, logger)
+			service.PartSize = tt.partSize
+			client, closeClient := newServiceClient(t, service)
+			defer closeClient()
+
+			stream, err := client.Download(context.Background(), &pb.DownloadRequest{
+				Key:       chunksKey,
+				Bucket:    testbucket,
+				ChunkSize: tt.chunkSize,
+			})
+			if err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			// Every chunk fits a buffer of the stream's part size.
+			var got []byte
+			for {
+				chunk, err := stream.Recv()
+				if err == io.EOF {
+					break
+				}
+
+				if status.Code(err) != tt.wantCode {
+					t.Fatalf("Download_DownloadClient.Recv() error = %v, want code %v", err, tt.wantCode)
+				}
+
+				if err != nil {
+					return
+				}
+
+				if n := int64(len(chunk.GetFile())); n > tt.wantPartSize {
+					t.Fatalf("Download_DownloadClient.Recv() chunk of %d bytes is larger than %d", n, tt.wantPartSize)
+				}
+
+				got = append(got, chunk.GetFile()...)
+			}
+
+			if tt.wantCode != codes.OK {
+				t.Fatalf("DownloadService.Download() error = nil, want code %v", tt.wantCode)
+			}
+
+			if !bytes.Equal(got, object) {
+				t.Errorf("DownloadService.Download() file downloaded is different from the wanted file")
+			}
+		})
+	}
+}
+
+func TestStreamReadCloser_ReadSmallBuffer(t *testing.T) {
+	tests := []struct {
+		name       string
+		bufferSize int
+	}{
+		{name: "read - single byte", bufferSize: 1},
+		{name: "read - smaller than a chunk", bufferSize: download.MinPartSize - 1},
+		{name: "read - chunk", bufferSize: download.MinPartSize},
+		{name: "read - larger than a chunk", bufferSize: download.MinPartSize + 1},
+	}
+
+	service := download.NewService(s3Client, logger)
+	client, closeClient := newServiceClient(t, service)
+	defer closeClient()
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := client.Download(context.Background(), &pb.DownloadRequest{
+				Key:       testkey,
+				Bucket:    testbucket,
+				ChunkSize: download.MinPartSize,
+			})
+			if err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			// Every byte of a chunk is read, also when it doesn't fit the buffer.
+			reader := download.NewStreamReadCloser(stream)
+			p := make([]byte, tt.bufferSize)
+			var got []byte
+			for {
+				n, err := reader.Read(p)
+				if err == io.EOF {
+					break
+				}
+
+				if err != nil {
+					t.Fatalf("StreamReadCloser.Read() error = %v", err)
+				}
+
+				got = append(got, p[:n]...)
+			}
+
+			if !bytes.Equal(got, file) {
+				t.Errorf("StreamReadCloser.Read() read %d bytes different from the wanted file", len(got))
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package download
+
+import (
+	"strings"
+)
+
+const (
+	// OtherKeyPrefix is the label of key prefixes that are not in the allowlist.
+	OtherKeyPrefix = "other"
+)
+
+// KeyPrefix returns the top-level prefix of key, which is its first path segment
+// including the trailing "/", or an empty string if key has no prefix.
+func KeyPrefix(key string) string {
+	i := strings.Index(key, "/")
+	if i < 0 {
+		return ""
+	}
+
+	return key[:i+1]
+}
+
+// KeyPrefixLabel returns the top-level prefix of key if it's in allowlist,
+// otherwise it returns OtherKeyPrefix, which bounds the cardinality of the label
+// to len(allowlist)+1. Allowlist entries may omit the trailing "/".
+func KeyPrefixLabel(key string, allowlist []string) string {
+	prefix := KeyPrefix(key)
+	if prefix == "" {
+		return OtherKeyPrefix
+	}
+
+	for _, allowed := range allowlist {
+		if strings.TrimSuffix(allowed, "/")+"/" == prefix {
+			return prefix
+		}
+	}
+
+	return OtherKeyPrefix
+}
@@ -0,0 +1,80 @@
+package download_test
+
+import (
+	"testing"
+
+	"github.com/meateam/download-service/download"
+)
+
+func TestKeyPrefix(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		want string
+	}{
+		{name: "key prefix - nested key", key: "photos/2019/cat.png", want: "photos/"},
+		{name: "key prefix - single level", key: "videos/dog.mp4", want: "videos/"},
+		{name: "key prefix - root key", key: "test.txt", want: ""},
+		{name: "key prefix - leading slash", key: "/test.txt", want: "/"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := download.KeyPrefix(tt.key); got != tt.want {
+				t.Errorf("KeyPrefix(%q) = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyPrefixLabel(t *testing.T) {
+	allowlist := []string{"photos/", "videos"}
+
+	tests := []struct {
+		name      string
+		key       string
+		allowlist []string
+		want      string
+	}{
+		{name: "key prefix label - allowed", key: "photos/cat.png", allowlist: allowlist, want: "photos/"},
+		{
+			name:      "key prefix label - allowed without slash",
+			key:       "videos/dog.mp4",
+			allowlist: allowlist,
+			want:      "videos/",
+		},
+		{
+			name:      "key prefix label - not allowed",
+			key:       "docs/cv.pdf",
+			allowlist: allowlist,
+			want:      download.OtherKeyPrefix,
+		},
+		{name: "key prefix label - root key", key: "test.txt", allowlist: allowlist, want: download.OtherKeyPrefix},
+		{
+			name:      "key prefix label - empty allowlist",
+			key:       "photos/cat.png",
+			allowlist: nil,
+			want:      download.OtherKeyPrefix,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := download.KeyPrefixLabel(tt.key, tt.allowlist); got != tt.want {
+				t.Errorf("KeyPrefixLabel(%q) = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+
+	// Any number of distinct prefixes yields at most len(allowlist)+1 labels.
+	labels := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		labels[download.KeyPrefixLabel(string(rune('a'+i%26))+"/"+string(rune(i))+"/key", allowlist)] = true
+	}
+
+	if len(labels) > len(allowlist)+1 {
+		t.Errorf("KeyPrefixLabel() produced %d labels, want at most %d", len(labels), len(allowlist)+1)
+	}
+}
@@ -0,0 +1,85 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// DefaultPresignExpiry is the default time a presigned URL is valid for.
+	DefaultPresignExpiry = 15 * time.Minute
+
+	// MaxPresignExpiry is the longest time a presigned URL can be valid for, S3's limit.
+	MaxPresignExpiry = 7 * 24 * time.Hour
+)
+
+// presignExpiry returns the time the URL of a request whose expiry is expirySeconds is valid for,
+// DefaultPresignExpiry if it's zero. It returns an InvalidArgument error if expirySeconds is negative
+// or exceeds MaxPresignExpiry.
+func presignExpiry(expirySeconds int64) (time.Duration, error) {
+	if expirySeconds == 0 {
+		return DefaultPresignExpiry, nil
+	}
+
+	if expirySeconds < 0 || expirySeconds > int64(MaxPresignExpiry/time.Second) {
+		return 0, status.Errorf(
+			codes.InvalidArgument,
+			"expiry of %d seconds is out of bounds, must be between 1 and %d",
+			expirySeconds, int64(MaxPresignExpiry/time.Second),
+		)
+	}
+
+	return time.Duration(expirySeconds) * time.Second, nil
+}
+
+// GetPresignedURL is the request to get a time-limited URL to download an object from S3 over HTTP,
+// for clients that can't stream the object over gRPC.
+// It returns a NotFound error if the object or its bucket doesn't exist.
+func (s Service) GetPresignedURL(ctx context.Context, req *pb.GetPresignedURLRequest) (*pb.PresignedURL, error) {
+	if err := s.RequestLimits.check(req, []string{req.GetKey()}); err != nil {
+		return nil, err
+	}
+
+	expiry, err := presignExpiry(req.GetExpirySeconds())
+	if err != nil {
+		return nil, err
+	}
+
+	bucket, key, err := s.resolveObject(req.GetBucket(), req.GetKey(), "")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authorize(ctx, bucket, key); err != nil {
+		return nil, err
+	}
+
+	// Check the object exists, rather than presigning a URL that can't be downloaded.
+	if _, err := s.headObject(ctx, bucket, key); err != nil {
+		return nil, s3ErrorToStatus(fmt.Errorf("failed to presign object %s/%s: %w", bucket, key, err))
+	}
+
+	getObjectRequest, _ := s.s3ClientFor(ctx, bucket).GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	getObjectRequest.SetContext(ctx)
+
+	signedAt := time.Now()
+	url, err := getObjectRequest.Presign(expiry)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to presign object %s/%s: %v", bucket, key, err)
+	}
+
+	return &pb.PresignedURL{
+		Url:       url,
+		ExpiresAt: signedAt.Add(expiry).UnixNano() / int64(time.Millisecond),
+	}, nil
+}
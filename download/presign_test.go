@@ -0,0 +1,117 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDownloadService_GetPresignedURL(t *testing.T) {
+	tests := []struct {
+		name       string
+		req        *pb.GetPresignedURLRequest
+		wantExpiry time.Duration
+		wantCode   codes.Code
+	}{
+		{
+			name:       "presign - default expiry",
+			req:        &pb.GetPresignedURLRequest{Key: testkey, Bucket: testbucket},
+			wantExpiry: download.DefaultPresignExpiry,
+		},
+		{
+			name:       "presign - expiry",
+			req:        &pb.GetPresignedURLRequest{Key: testkey, Bucket: testbucket, ExpirySeconds: 60},
+			wantExpiry: time.Minute,
+		},
+		{
+			name:       "presign - max expiry",
+			req:        &pb.GetPresignedURLRequest{Key: testkey, Bucket: testbucket, ExpirySeconds: 604800},
+			wantExpiry: download.MaxPresignExpiry,
+		},
+		{
+			name:     "presign - expiry exceeds max",
+			req:      &pb.GetPresignedURLRequest{Key: testkey, Bucket: testbucket, ExpirySeconds: 604801},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "presign - negative expiry",
+			req:      &pb.GetPresignedURLRequest{Key: testkey, Bucket: testbucket, ExpirySeconds: -1},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "presign - missing key",
+			req:      &pb.GetPresignedURLRequest{Key: "missing.txt", Bucket: testbucket},
+			wantCode: codes.NotFound,
+		},
+		{
+			name:     "presign - missing bucket",
+			req:      &pb.GetPresignedURLRequest{Key: testkey, Bucket: "missing-bucket"},
+			wantCode: codes.NotFound,
+		},
+		{
+			name:     "presign - no key",
+			req:      &pb.GetPresignedURLRequest{Bucket: testbucket},
+			wantCode: codes.Unknown,
+		},
+	}
+
+	service := download.NewService(s3Client, logger)
+	client, closeClient := newServiceClient(t, service)
+	defer closeClient()
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			requestedAt := time.Now()
+			got, err := client.GetPresignedURL(context.Background(), tt.req)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("DownloadService.GetPresignedURL() error = %v, want code %v", err, tt.wantCode)
+			}
+
+			if tt.wantCode != codes.OK {
+				return
+			}
+
+			presigned, err := url.Parse(got.GetUrl())
+			if err != nil {
+				t.Fatalf("DownloadService.GetPresignedURL() url = %q is invalid, %v", got.GetUrl(), err)
+			}
+
+			wantExpires := strconv.FormatInt(int64(tt.wantExpiry/time.Second), 10)
+			if expires := presigned.Query().Get("X-Amz-Expires"); expires != wantExpires {
+				t.Errorf("DownloadService.GetPresignedURL() url expires in %s seconds, want %s", expires, wantExpires)
+			}
+
+			expiresAt := time.Unix(0, got.GetExpiresAt()*int64(time.Millisecond))
+			wantExpiresAt := requestedAt.Add(tt.wantExpiry)
+			if d := expiresAt.Sub(wantExpiresAt); d < -time.Second || d > time.Minute {
+				t.Errorf("DownloadService.GetPresignedURL() expires at %v, want %v", expiresAt, wantExpiresAt)
+			}
+
+			res, err := http.Get(got.GetUrl())
+			if err != nil {
+				t.Fatalf("failed to get presigned url %s, %v", got.GetUrl(), err)
+			}
+			defer res.Body.Close()
+
+			body, err := ioutil.ReadAll(res.Body)
+			if err != nil {
+				t.Fatalf("failed to read presigned url %s, %v", got.GetUrl(), err)
+			}
+
+			if res.StatusCode != http.StatusOK || !bytes.Equal(body, file) {
+				t.Errorf("presigned url responded with status %d, want the wanted file", res.StatusCode)
+			}
+		})
+	}
+}
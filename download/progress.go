@@ -0,0 +1,78 @@
+package download
+
+import (
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// progressInterval returns the number of bytes between the progress messages of downloading
+// totalBytes bytes for req, the smaller of its byte and percent intervals, or zero if it has neither.
+func progressInterval(req *pb.DownloadRequest, totalBytes int64) (int64, error) {
+	interval, percent := req.GetProgressInterval(), req.GetProgressPercent()
+	if interval < 0 {
+		return 0, status.Errorf(codes.InvalidArgument, "progress interval %d is negative", interval)
+	}
+
+	if percent < 0 || percent > 100 {
+		return 0, status.Errorf(codes.InvalidArgument, "progress percent %d is not between 0 and 100", percent)
+	}
+
+	if percent > 0 {
+		percentInterval := totalBytes * int64(percent) / 100
+		if percentInterval < 1 {
+			percentInterval = 1
+		}
+
+		if interval == 0 || percentInterval < interval {
+			interval = percentInterval
+		}
+	}
+
+	return interval, nil
+}
+
+// progressDownloadStream is a pb.Download_DownloadServer that sends a progress message after
+// every chunk of the file that crosses a multiple of interval bytes, and after the last chunk.
+type progressDownloadStream struct {
+	pb.Download_DownloadServer
+	interval   int64
+	totalBytes int64
+	bytesSent  int64
+	next       int64
+}
+
+// newProgressDownloadStream returns a progressDownloadStream sending on stream the progress
+// of downloading totalBytes bytes every interval bytes.
+func newProgressDownloadStream(
+	stream pb.Download_DownloadServer,
+	interval int64,
+	totalBytes int64,
+) *progressDownloadStream {
+	return &progressDownloadStream{
+		Download_DownloadServer: stream,
+		interval:                interval,
+		totalBytes:              totalBytes,
+		next:                    interval,
+	}
+}
+
+// Send sends res on the underlying stream, followed by a progress message if due.
+func (s *progressDownloadStream) Send(res *pb.DownloadResponse) error {
+	if err := s.Download_DownloadServer.Send(res); err != nil {
+		return err
+	}
+
+	s.bytesSent += int64(len(res.GetFile()))
+	if s.bytesSent < s.next && s.bytesSent < s.totalBytes {
+		return nil
+	}
+
+	s.next = (s.bytesSent/s.interval + 1) * s.interval
+
+	return s.Download_DownloadServer.Send(&pb.DownloadResponse{
+		Payload: &pb.DownloadResponse_Progress{
+			Progress: &pb.DownloadProgress{BytesSent: s.bytesSent, TotalBytes: s.totalBytes},
+		},
+	})
+}
@@ -0,0 +1,196 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDownloadService_DownloadProgress(t *testing.T) {
+	const chunkSize = 64 << 10
+
+	tests := []struct {
+		name          string
+		req           *pb.DownloadRequest
+		wantCode      codes.Code
+		wantFile      []byte
+		wantBytesSent []int64
+	}{
+		{
+			name:          "progress - disabled",
+			req:           &pb.DownloadRequest{Key: testkey, Bucket: testbucket},
+			wantFile:      file,
+			wantBytesSent: nil,
+		},
+		{
+			name:          "progress - byte interval",
+			req:           &pb.DownloadRequest{Key: testkey, Bucket: testbucket, ProgressInterval: 512 << 10},
+			wantFile:      file,
+			wantBytesSent: []int64{512 << 10, 1 << 20, 1536 << 10, 2 << 20},
+		},
+		{
+			name:     "progress - unaligned byte interval",
+			req:      &pb.DownloadRequest{Key: testkey, Bucket: testbucket, ProgressInterval: 3 * chunkSize / 2},
+			wantFile: file,
+			wantBytesSent: chunkMultiples(
+				chunkSize,
+				2, 3, 5, 6, 8, 9, 11, 12, 14, 15, 17, 18, 20, 21, 23, 24, 26, 27, 29, 30, 32,
+			),
+		},
+		{
+			name:          "progress - percent interval",
+			req:           &pb.DownloadRequest{Key: testkey, Bucket: testbucket, ProgressPercent: 25},
+			wantFile:      file,
+			wantBytesSent: []int64{512 << 10, 1 << 20, 1536 << 10, 2 << 20},
+		},
+		{
+			name: "progress - smaller of both intervals",
+			req: &pb.DownloadRequest{
+				Key:              testkey,
+				Bucket:           testbucket,
+				ProgressInterval: 1 << 20,
+				ProgressPercent:  25,
+			},
+			wantFile:      file,
+			wantBytesSent: []int64{512 << 10, 1 << 20, 1536 << 10, 2 << 20},
+		},
+		{
+			name: "progress - range shorter than the interval",
+			req: &pb.DownloadRequest{
+				Key:              testkey,
+				Bucket:           testbucket,
+				RangeStart:       10,
+				RangeEnd:         19,
+				ProgressInterval: 1 << 20,
+			},
+			wantFile:      file[10:20],
+			wantBytesSent: []int64{10},
+		},
+		{
+			name:     "progress - negative interval",
+			req:      &pb.DownloadRequest{Key: testkey, Bucket: testbucket, ProgressInterval: -1},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "progress - percent above 100",
+			req:      &pb.DownloadRequest{Key: testkey, Bucket: testbucket, ProgressPercent: 101},
+			wantCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			service := download.NewService(s3Client, logger)
+			service.MaxBufferSize = chunkSize
+
+			client, closeClient := newServiceClient(t, service)
+			defer closeClient()
+
+			stream, err := client.Download(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			var received []byte
+			var bytesSent []int64
+			for {
+				chunk, err := stream.Recv()
+				if err == io.EOF {
+					break
+				}
+
+				if err != nil {
+					if status.Code(err) != tt.wantCode {
+						t.Fatalf("DownloadService.Download() error = %v, wantCode %v", err, tt.wantCode)
+					}
+
+					return
+				}
+
+				progress := chunk.GetProgress()
+				if progress == nil {
+					received = append(received, chunk.GetFile()...)
+					continue
+				}
+
+				if progress.GetBytesSent() != int64(len(received)) {
+					t.Errorf("progress bytes sent = %d, want the %d bytes received", progress.GetBytesSent(), len(received))
+				}
+
+				if progress.GetTotalBytes() != int64(len(tt.wantFile)) {
+					t.Errorf("progress total bytes = %d, want %d", progress.GetTotalBytes(), len(tt.wantFile))
+				}
+
+				bytesSent = append(bytesSent, progress.GetBytesSent())
+			}
+
+			if tt.wantCode != codes.OK {
+				t.Fatalf("DownloadService.Download() error = nil, wantCode %v", tt.wantCode)
+			}
+
+			if !bytes.Equal(received, tt.wantFile) {
+				t.Errorf("DownloadService.Download() file downloaded is different from the wanted file")
+			}
+
+			if !reflect.DeepEqual(bytesSent, tt.wantBytesSent) {
+				t.Errorf("DownloadService.Download() progress bytes sent = %v, want %v", bytesSent, tt.wantBytesSent)
+			}
+		})
+	}
+}
+
+func TestStreamReadCloser_ReadSkipsProgress(t *testing.T) {
+	service := download.NewService(s3Client, logger)
+	service.MaxBufferSize = 64 << 10
+
+	client, closeClient := newServiceClient(t, service)
+	defer closeClient()
+
+	stream, err := client.Download(
+		context.Background(),
+		&pb.DownloadRequest{Key: testkey, Bucket: testbucket, ProgressInterval: 1},
+	)
+	if err != nil {
+		t.Fatalf("DownloadService.Download() error = %v", err)
+	}
+
+	reader := download.NewStreamReadCloser(stream)
+	defer reader.Close()
+
+	var received []byte
+	p := make([]byte, download.PartSize)
+	for {
+		n, err := reader.Read(p)
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Fatalf("StreamReadCloser.Read() error = %v", err)
+		}
+
+		received = append(received, p[:n]...)
+	}
+
+	if !bytes.Equal(received, file) {
+		t.Errorf("StreamReadCloser.Read() file read is different from the wanted file")
+	}
+}
+
+// chunkMultiples returns the given multiples of chunkSize.
+func chunkMultiples(chunkSize int64, multiples ...int64) []int64 {
+	bytesSent := make([]int64, len(multiples))
+	for i, multiple := range multiples {
+		bytesSent[i] = multiple * chunkSize
+	}
+
+	return bytesSent
+}
@@ -0,0 +1,106 @@
+package download
+
+import (
+	"sync"
+
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultQoSWeights are the default weights of the QoS classes in a QoSBudget.
+var DefaultQoSWeights = map[pb.QoSClass]int64{
+	pb.QoSClass_PREMIUM:  4,
+	pb.QoSClass_STANDARD: 2,
+	pb.QoSClass_BULK:     1,
+}
+
+// QoSBudget is a bandwidth budget shared by the active downloads by the weights of their QoS classes.
+// Every download is paced to the budget's share of its class's weight out of the weights of all
+// of the active downloads, so under contention premium downloads get more of the budget and bulk
+// downloads are throttled first, while a download without contention gets the whole budget.
+// A download holds its share until it ends, also while it waits for S3.
+type QoSBudget struct {
+	bytesPerSec int64
+	weights     map[pb.QoSClass]int64
+
+	mu           sync.Mutex
+	activeWeight int64
+}
+
+// qosShare is the share of a download of a QoSBudget.
+type qosShare struct {
+	budget *QoSBudget
+	weight int64
+}
+
+// NewQoSBudget returns a QoSBudget of bytesPerSec shared by weights, which default to
+// DefaultQoSWeights. Classes missing from weights have the weight of the standard class,
+// and non-positive weights default to one.
+func NewQoSBudget(bytesPerSec int64, weights map[pb.QoSClass]int64) *QoSBudget {
+	if weights == nil {
+		weights = DefaultQoSWeights
+	}
+
+	budgetWeights := make(map[pb.QoSClass]int64, len(pb.QoSClass_name))
+	for value := range pb.QoSClass_name {
+		class := pb.QoSClass(value)
+		weight, ok := weights[class]
+		if !ok {
+			weight = weights[pb.QoSClass_STANDARD]
+		}
+
+		if weight <= 0 {
+			weight = 1
+		}
+
+		budgetWeights[class] = weight
+	}
+
+	return &QoSBudget{bytesPerSec: bytesPerSec, weights: budgetWeights}
+}
+
+// join adds a download of class to the downloads sharing b and returns its share, which
+// must be left once the download ends. It returns an InvalidArgument error if class is unknown.
+// A nil budget returns a nil share.
+func (b *QoSBudget) join(class pb.QoSClass) (*qosShare, error) {
+	if b == nil {
+		return nil, nil
+	}
+
+	weight, ok := b.weights[class]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown qos class %d", class)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.activeWeight += weight
+
+	return &qosShare{budget: b, weight: weight}, nil
+}
+
+// bytesPerSec returns the current rate of the share, at least a byte per second.
+func (s *qosShare) bytesPerSec() int64 {
+	s.budget.mu.Lock()
+	defer s.budget.mu.Unlock()
+
+	if rate := s.budget.bytesPerSec * s.weight / s.budget.activeWeight; rate > 0 {
+		return rate
+	}
+
+	return 1
+}
+
+// leave removes the share from its budget, a nil share is ignored.
+func (s *qosShare) leave() {
+	if s == nil {
+		return
+	}
+
+	s.budget.mu.Lock()
+	defer s.budget.mu.Unlock()
+
+	s.budget.activeWeight -= s.weight
+}
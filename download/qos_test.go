@@ -0,0 +1,77 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDownloadService_DownloadQoSClasses(t *testing.T) {
+	const budget = 4 << 20
+
+	service := download.NewService(s3Client, logger)
+	service.MaxBufferSize = 256 << 10
+	service.QoSBudget = download.NewQoSBudget(budget, nil)
+
+	// Download the file concurrently as premium and as bulk, contending for the budget.
+	classes := []pb.QoSClass{pb.QoSClass_PREMIUM, pb.QoSClass_BULK}
+	elapsed := make([]time.Duration, len(classes))
+	errs := make([]error, len(classes))
+	streams := make([]*hashingDownloadStream, len(classes))
+
+	var wg sync.WaitGroup
+	for i, class := range classes {
+		i, class := i, class
+		streams[i] = &hashingDownloadStream{ctx: context.Background(), hash: sha256.New()}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			start := time.Now()
+			req := &pb.DownloadRequest{Key: testkey, Bucket: testbucket, QosClass: class}
+			errs[i] = service.Download(req, streams[i])
+			elapsed[i] = time.Since(start)
+		}()
+	}
+	wg.Wait()
+
+	wantHash := sha256.Sum256(file)
+	for i, class := range classes {
+		if errs[i] != nil {
+			t.Fatalf("DownloadService.Download() of class %s error = %v", class, errs[i])
+		}
+
+		if !bytes.Equal(streams[i].hash.Sum(nil), wantHash[:]) {
+			t.Errorf("DownloadService.Download() of class %s file downloaded is different from the wanted file", class)
+		}
+	}
+
+	// Premium gets 4/5 of the budget while bulk downloads, then bulk gets all of it.
+	if premium, bulk := elapsed[0], elapsed[1]; premium >= bulk {
+		t.Errorf("DownloadService.Download() premium took %s, want less than bulk's %s", premium, bulk)
+	}
+
+	// Together the downloads never exceed the budget.
+	if throughput := float64(2*len(file)) / elapsed[1].Seconds(); throughput > budget {
+		t.Errorf("DownloadService.Download() throughput = %.0f bytes/sec, want at most %d", throughput, budget)
+	}
+}
+
+func TestDownloadService_DownloadQoSUnknownClass(t *testing.T) {
+	service := download.NewService(s3Client, logger)
+	service.QoSBudget = download.NewQoSBudget(4<<20, nil)
+
+	stream := &hashingDownloadStream{ctx: context.Background(), hash: sha256.New()}
+	req := &pb.DownloadRequest{Key: testkey, Bucket: testbucket, QosClass: pb.QoSClass(42)}
+	if err := service.Download(req, stream); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("DownloadService.Download() error = %v, want code %v", err, codes.InvalidArgument)
+	}
+}
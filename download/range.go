@@ -0,0 +1,195 @@
+package download
+
+import (
+	"math"
+
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// byteRange is an inclusive range of bytes of an object.
+// An empty range has end < start.
+type byteRange struct {
+	start int64
+	end   int64
+}
+
+// length returns the number of bytes in r.
+func (r byteRange) length() int64 {
+	return r.end - r.start + 1
+}
+
+// resolveRange resolves the requested rangeStart and rangeEnd against an object
+// of contentLength bytes, following the semantics of HTTP byte ranges:
+// a zero rangeEnd means "to the end of the object", a rangeEnd beyond the object
+// is clamped to its last byte and a negative rangeStart requests the last
+// -rangeStart bytes of the object. When both are zero the whole object is returned.
+func resolveRange(rangeStart int64, rangeEnd int64, contentLength int64) (byteRange, error) {
+	whole := byteRange{start: 0, end: contentLength - 1}
+
+	// Suffix range, "bytes=-N".
+	if rangeStart < 0 {
+		if rangeEnd != 0 {
+			return byteRange{}, status.Errorf(
+				codes.InvalidArgument,
+				"range end must be unset for a suffix range, got %d",
+				rangeEnd,
+			)
+		}
+
+		suffixLength := -rangeStart
+		if suffixLength >= contentLength {
+			return whole, nil
+		}
+
+		return byteRange{start: contentLength - suffixLength, end: contentLength - 1}, nil
+	}
+
+	if rangeStart == 0 && rangeEnd == 0 {
+		return whole, nil
+	}
+
+	if rangeEnd != 0 && rangeEnd < rangeStart {
+		return byteRange{}, status.Errorf(
+			codes.InvalidArgument,
+			"range start %d is greater than range end %d",
+			rangeStart,
+			rangeEnd,
+		)
+	}
+
+	if rangeStart >= contentLength {
+		return byteRange{}, status.Errorf(
+			codes.OutOfRange,
+			"range start %d is beyond the object's length %d",
+			rangeStart,
+			contentLength,
+		)
+	}
+
+	// Open-ended "bytes=N-" or over-long ranges reach the end of the object.
+	if rangeEnd == 0 || rangeEnd >= contentLength {
+		rangeEnd = contentLength - 1
+	}
+
+	return byteRange{start: rangeStart, end: rangeEnd}, nil
+}
+
+// resolveRequestRange resolves the range of a request against an object of contentLength bytes, by its
+// rangePercent if set, otherwise by its rangeStart and rangeEnd like resolveRange. It returns an
+// InvalidArgument error if rangePercent is combined with rangeStart or rangeEnd.
+func resolveRequestRange(
+	rangeStart int64,
+	rangeEnd int64,
+	rangePercent *pb.RangePercent,
+	contentLength int64,
+) (byteRange, error) {
+	if rangePercent == nil {
+		return resolveRange(rangeStart, rangeEnd, contentLength)
+	}
+
+	if rangeStart != 0 || rangeEnd != 0 {
+		return byteRange{}, status.Error(
+			codes.InvalidArgument,
+			"range percent can't be combined with range start or range end",
+		)
+	}
+
+	return resolvePercentRange(rangePercent, contentLength)
+}
+
+// resolvePercentRange resolves rangePercent against an object of contentLength bytes.
+// The range starts at the byte that its start percentage falls in and ends right before the byte that
+// its end percentage falls in, both rounded down, so adjacent percentage ranges split the object without
+// overlapping or skipping bytes. A range narrower than a byte is widened to the byte its start falls in.
+// It returns an InvalidArgument error unless 0 <= start < end <= 100.
+func resolvePercentRange(rangePercent *pb.RangePercent, contentLength int64) (byteRange, error) {
+	start, end := rangePercent.GetStart(), rangePercent.GetEnd()
+	if !(start >= 0 && start < end && end <= 100) {
+		return byteRange{}, status.Errorf(
+			codes.InvalidArgument,
+			"range percent must satisfy 0 <= start < end <= 100, got %g-%g",
+			start,
+			end,
+		)
+	}
+
+	rangeStart := int64(math.Floor(start / 100 * float64(contentLength)))
+	rangeEnd := int64(math.Floor(end/100*float64(contentLength))) - 1
+	if rangeEnd < rangeStart && rangeStart < contentLength {
+		rangeEnd = rangeStart
+	}
+
+	return byteRange{start: rangeStart, end: rangeEnd}, nil
+}
+
+// resumeRange returns the rest of r from offset, the first byte to send of a download of r, which was
+// interrupted, of an object of contentLength bytes. A zero offset returns r as is, and an offset
+// right after r's last byte returns an empty range. It returns an InvalidArgument error if offset
+// is negative, beyond the object's length or outside of r.
+func resumeRange(r byteRange, offset int64, contentLength int64) (byteRange, error) {
+	if offset == 0 {
+		return r, nil
+	}
+
+	if offset < 0 || offset > contentLength {
+		return byteRange{}, status.Errorf(
+			codes.InvalidArgument,
+			"offset %d is outside of the object's length %d",
+			offset,
+			contentLength,
+		)
+	}
+
+	if offset < r.start || offset > r.end+1 {
+		return byteRange{}, status.Errorf(
+			codes.InvalidArgument,
+			"offset %d is outside of the range %d-%d",
+			offset,
+			r.start,
+			r.end,
+		)
+	}
+
+	return byteRange{start: offset, end: r.end}, nil
+}
+
+// parts returns the number of parts r is split into, when split into parts of partSize bytes.
+// Aligned parts start at multiples of partSize in the object rather than at multiples of partSize from r.start.
+func (r byteRange) parts(partSize int64, aligned bool) int64 {
+	if r.length() <= 0 {
+		return 0
+	}
+
+	if aligned {
+		return r.end/partSize - r.start/partSize + 1
+	}
+
+	parts := r.length() / partSize
+	if r.length()%partSize > 0 {
+		parts++
+	}
+
+	return parts
+}
+
+// part returns the range of the part number n of r, when split into parts of partSize bytes.
+// See parts for aligned parts.
+func (r byteRange) part(n int64, partSize int64, aligned bool) byteRange {
+	first := r.start
+	if aligned {
+		first = r.start / partSize * partSize
+	}
+
+	part := byteRange{start: first + n*partSize, end: first + (n+1)*partSize - 1}
+	if part.start < r.start {
+		part.start = r.start
+	}
+
+	if part.end > r.end {
+		part.end = r.end
+	}
+
+	return part
+}
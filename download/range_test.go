@@ -0,0 +1,223 @@
+package download
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestByteRange_parts(t *testing.T) {
+	tests := []struct {
+		name      string
+		r         byteRange
+		partSize  int64
+		aligned   bool
+		wantParts []byteRange
+	}{
+		{
+			name:      "fixed from zero",
+			r:         byteRange{start: 0, end: 24},
+			partSize:  10,
+			wantParts: []byteRange{{0, 9}, {10, 19}, {20, 24}},
+		},
+		{
+			name:      "fixed from offset",
+			r:         byteRange{start: 3, end: 24},
+			partSize:  10,
+			wantParts: []byteRange{{3, 12}, {13, 22}, {23, 24}},
+		},
+		{
+			name:      "aligned from offset",
+			r:         byteRange{start: 3, end: 24},
+			partSize:  10,
+			aligned:   true,
+			wantParts: []byteRange{{3, 9}, {10, 19}, {20, 24}},
+		},
+		{
+			name:      "aligned within a part",
+			r:         byteRange{start: 12, end: 15},
+			partSize:  10,
+			aligned:   true,
+			wantParts: []byteRange{{12, 15}},
+		},
+		{
+			name:      "aligned on boundaries",
+			r:         byteRange{start: 10, end: 29},
+			partSize:  10,
+			aligned:   true,
+			wantParts: []byteRange{{10, 19}, {20, 29}},
+		},
+		{
+			name:     "empty",
+			r:        byteRange{start: 0, end: -1},
+			partSize: 10,
+			aligned:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var gotParts []byteRange
+			for n := int64(0); n < tt.r.parts(tt.partSize, tt.aligned); n++ {
+				gotParts = append(gotParts, tt.r.part(n, tt.partSize, tt.aligned))
+			}
+
+			if fmt.Sprint(gotParts) != fmt.Sprint(tt.wantParts) {
+				t.Errorf("byteRange parts = %v, want %v", gotParts, tt.wantParts)
+			}
+		})
+	}
+}
+
+func TestResolvePercentRange(t *testing.T) {
+	tests := []struct {
+		name          string
+		start         float64
+		end           float64
+		contentLength int64
+		want          byteRange
+		wantCode      codes.Code
+	}{
+		{name: "whole", start: 0, end: 100, contentLength: 1000, want: byteRange{0, 999}},
+		{name: "first half", start: 0, end: 50, contentLength: 1000, want: byteRange{0, 499}},
+		{name: "second half", start: 50, end: 100, contentLength: 1000, want: byteRange{500, 999}},
+		{name: "middle", start: 12.5, end: 37.5, contentLength: 1000, want: byteRange{125, 374}},
+		{name: "rounded down", start: 33.3, end: 66.6, contentLength: 10, want: byteRange{3, 5}},
+		{name: "narrower than a byte", start: 50, end: 50.1, contentLength: 10, want: byteRange{5, 5}},
+		{name: "empty object", start: 0, end: 100, contentLength: 0, want: byteRange{0, -1}},
+		{name: "negative start", start: -1, end: 50, contentLength: 1000, wantCode: codes.InvalidArgument},
+		{name: "end beyond 100", start: 0, end: 100.5, contentLength: 1000, wantCode: codes.InvalidArgument},
+		{name: "start after end", start: 60, end: 40, contentLength: 1000, wantCode: codes.InvalidArgument},
+		{name: "empty range", start: 40, end: 40, contentLength: 1000, wantCode: codes.InvalidArgument},
+		{name: "NaN", start: math.NaN(), end: 50, contentLength: 1000, wantCode: codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolvePercentRange(&pb.RangePercent{Start: tt.start, End: tt.end}, tt.contentLength)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("resolvePercentRange() error = %v, want code %v", err, tt.wantCode)
+			}
+
+			if got != tt.want {
+				t.Errorf("resolvePercentRange() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolvePercentRange_adjacent(t *testing.T) {
+	// Adjacent windows of at least a byte each split the object into every byte exactly once.
+	windows := []float64{0, 10, 25, 33.3, 50, 66.6, 99.9, 100}
+	for _, contentLength := range []int64{999, 1000, 4096, 1 << 20} {
+		next := int64(0)
+		for i := 1; i < len(windows); i++ {
+			r, err := resolvePercentRange(&pb.RangePercent{Start: windows[i-1], End: windows[i]}, contentLength)
+			if err != nil {
+				t.Fatalf("resolvePercentRange() error = %v", err)
+			}
+
+			if r.start != next {
+				t.Errorf("range %v of %d bytes starts at %d, want %d", r, contentLength, r.start, next)
+			}
+
+			next = r.end + 1
+		}
+
+		if next != contentLength {
+			t.Errorf("ranges of %d bytes end at %d, want %d", contentLength, next, contentLength)
+		}
+	}
+}
+
+func TestCoalesceRanges(t *testing.T) {
+	tests := []struct {
+		name      string
+		ranges    []byteRange
+		maxGap    int64
+		maxSize   int64
+		wantReads []byteRange
+		wantData  string
+	}{
+		{
+			name:      "disabled",
+			ranges:    []byteRange{{0, 1}, {2, 3}, {4, 5}},
+			wantReads: []byteRange{{0, 1}, {2, 3}, {4, 5}},
+			wantData:  "[[01] [23] [45]]",
+		},
+		{
+			name:      "contiguous",
+			ranges:    []byteRange{{0, 1}, {2, 3}, {4, 5}},
+			maxSize:   4,
+			wantReads: []byteRange{{0, 3}, {4, 5}},
+			wantData:  "[[01 23] [45]]",
+		},
+		{
+			name:      "within the gap",
+			ranges:    []byteRange{{0, 1}, {3, 4}, {7, 8}},
+			maxGap:    1,
+			maxSize:   10,
+			wantReads: []byteRange{{0, 4}, {7, 8}},
+			wantData:  "[[01 34] [78]]",
+		},
+		{
+			name:      "larger than the max size",
+			ranges:    []byteRange{{0, 5}, {6, 7}},
+			maxSize:   4,
+			wantReads: []byteRange{{0, 5}, {6, 7}},
+			wantData:  "[[012345] [67]]",
+		},
+		{
+			name:      "overlapping",
+			ranges:    []byteRange{{0, 3}, {2, 5}},
+			maxGap:    1,
+			maxSize:   10,
+			wantReads: []byteRange{{0, 3}, {2, 5}},
+			wantData:  "[[0123] [2345]]",
+		},
+	}
+
+	const object = "0123456789"
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			reads := coalesceRanges(tt.ranges, tt.maxGap, tt.maxSize)
+
+			var gotReads []byteRange
+			var gotData [][]string
+			for _, read := range reads {
+				gotReads = append(gotReads, read.byteRange)
+
+				parts, err := read.split([]byte(object[read.start : read.end+1]))
+				if err != nil {
+					t.Fatalf("coalescedRead.split() error = %v", err)
+				}
+
+				var data []string
+				for _, part := range parts {
+					data = append(data, string(part))
+				}
+				gotData = append(gotData, data)
+			}
+
+			if fmt.Sprint(gotReads) != fmt.Sprint(tt.wantReads) {
+				t.Errorf("coalesceRanges() = %v, want %v", gotReads, tt.wantReads)
+			}
+
+			if fmt.Sprint(gotData) != tt.wantData {
+				t.Errorf("coalescedRead.split() = %v, want %v", gotData, tt.wantData)
+			}
+		})
+	}
+
+	shortRead := coalescedRead{byteRange: byteRange{0, 3}, ranges: []byteRange{{0, 3}}}
+	if _, err := shortRead.split([]byte("01")); err == nil {
+		t.Errorf("coalescedRead.split() of a short read error = nil, want an error")
+	}
+}
@@ -0,0 +1,92 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDownloadService_DownloadRangePercent(t *testing.T) {
+	size := len(file)
+	tests := []struct {
+		name      string
+		req       *pb.DownloadRequest
+		wantCode  codes.Code
+		wantStart int
+		wantEnd   int
+	}{
+		{
+			name:      "range percent - whole",
+			req:       &pb.DownloadRequest{RangePercent: &pb.RangePercent{Start: 0, End: 100}},
+			wantStart: 0,
+			wantEnd:   size,
+		},
+		{
+			name:      "range percent - first quarter",
+			req:       &pb.DownloadRequest{RangePercent: &pb.RangePercent{Start: 0, End: 25}},
+			wantStart: 0,
+			wantEnd:   size / 4,
+		},
+		{
+			name:      "range percent - middle",
+			req:       &pb.DownloadRequest{RangePercent: &pb.RangePercent{Start: 25, End: 75}},
+			wantStart: size / 4,
+			wantEnd:   size * 3 / 4,
+		},
+		{
+			name:      "range percent - last tenth",
+			req:       &pb.DownloadRequest{RangePercent: &pb.RangePercent{Start: 90, End: 100}},
+			wantStart: size * 9 / 10,
+			wantEnd:   size,
+		},
+		{
+			name:     "range percent - reversed bounds",
+			req:      &pb.DownloadRequest{RangePercent: &pb.RangePercent{Start: 75, End: 25}},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "range percent - beyond 100",
+			req:      &pb.DownloadRequest{RangePercent: &pb.RangePercent{Start: 50, End: 150}},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "range percent - with range start",
+			req:      &pb.DownloadRequest{RangeStart: 10, RangePercent: &pb.RangePercent{Start: 0, End: 50}},
+			wantCode: codes.InvalidArgument,
+		},
+	}
+
+	service := download.NewService(s3Client, logger)
+	service.MaxBufferSize = 1 << 20
+	client, closeClient := newServiceClient(t, service)
+	defer closeClient()
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.Key = testkey
+			tt.req.Bucket = testbucket
+			stream, err := client.Download(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			got, err := recvAll(stream)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("DownloadService.Download() error = %v, want code %v", err, tt.wantCode)
+			}
+
+			if tt.wantCode == codes.OK && !bytes.Equal(got, file[tt.wantStart:tt.wantEnd]) {
+				t.Errorf(
+					"DownloadService.Download() downloaded %d bytes, want bytes %d-%d",
+					len(got), tt.wantStart, tt.wantEnd,
+				)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package download
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const (
+	// bucketRegionHeader is the response header of the region of the bucket of an S3 call,
+	// set when S3 redirects calls to a bucket in another region than the client's.
+	bucketRegionHeader = "X-Amz-Bucket-Region"
+
+	// maxRegionClients is the number of cached region-specific S3 clients above which
+	// the cache is cleared, bounding the clients of delegated credentials.
+	maxRegionClients = 1024
+)
+
+// regionClientKey is the key of a cached region-specific S3 client, configured like base.
+type regionClientKey struct {
+	base   *s3.S3
+	region string
+}
+
+// regionClients caches the regions of the buckets S3 redirected calls for to another region,
+// and the region-specific S3 clients of those calls.
+type regionClients struct {
+	mu      sync.Mutex
+	regions map[string]string
+	clients map[regionClientKey]*s3.S3
+}
+
+// newRegionClients returns an empty regionClients.
+func newRegionClients() *regionClients {
+	return &regionClients{
+		regions: make(map[string]string),
+		clients: make(map[regionClientKey]*s3.S3),
+	}
+}
+
+// get returns the S3 client configured like base for the region of bucket,
+// base itself if the region of bucket isn't known or is the region of base.
+// A nil regionClients returns base.
+func (c *regionClients) get(base *s3.S3, bucket string) *s3.S3 {
+	if c == nil {
+		return base
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	region, ok := c.regions[bucket]
+	if !ok || region == aws.StringValue(base.Config.Region) {
+		return base
+	}
+
+	key := regionClientKey{base: base, region: region}
+	if client, ok := c.clients[key]; ok {
+		return client
+	}
+
+	if len(c.clients) >= maxRegionClients {
+		c.clients = make(map[regionClientKey]*s3.S3)
+	}
+
+	client := s3.New(session.Must(session.NewSession(base.Config.Copy(&aws.Config{Region: aws.String(region)}))))
+	c.clients[key] = client
+
+	return client
+}
+
+// region returns the region of bucket, and whether it's known.
+func (c *regionClients) region(bucket string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	region, ok := c.regions[bucket]
+
+	return region, ok
+}
+
+// setRegion sets the region of bucket, which the S3 clients returned for bucket are configured for.
+func (c *regionClients) setRegion(bucket string, region string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.regions[bucket] = region
+}
+
+// withRedirectRegion returns a request.Option that stores the region of the bucket in region
+// when S3 redirects the call to the bucket's region.
+func withRedirectRegion(region *string) request.Option {
+	return func(r *request.Request) {
+		r.Handlers.UnmarshalError.PushFront(func(r *request.Request) {
+			if r.HTTPResponse == nil {
+				return
+			}
+
+			switch r.HTTPResponse.StatusCode {
+			case http.StatusMovedPermanently, http.StatusTemporaryRedirect:
+				*region = r.HTTPResponse.Header.Get(bucketRegionHeader)
+			}
+		})
+	}
+}
+
+// resolveRedirect caches region as the region of bucket if S3 redirected a call of the request
+// of ctx to it, region is empty otherwise. It returns whether the call should be retried once
+// with the region-specific client of s3ClientFor.
+func (s Service) resolveRedirect(ctx context.Context, bucket string, region string) bool {
+	if region == "" || s.regionClients == nil {
+		return false
+	}
+
+	previous := aws.StringValue(s.s3ClientFor(ctx, bucket).Config.Region)
+	if region == previous {
+		return false
+	}
+
+	s.regionClients.setRegion(bucket, region)
+	s.logger.Infof("resolved redirect of bucket %s from region %s to region %s", bucket, previous, region)
+
+	return true
+}
@@ -0,0 +1,108 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/corehandlers"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// permanentRedirectBody is the body of S3's redirect of calls to a bucket in another region.
+const permanentRedirectBody = `<?xml version="1.0" encoding="UTF-8"?>
+<Error><Code>PermanentRedirect</Code><Message>The bucket you are attempting to access must be addressed ` +
+	`using the specified endpoint.</Message></Error>`
+
+// redirectingS3Client returns an S3 client whose calls are redirected to bucketRegion unless it's the
+// client's region, counting the redirects. Clients configured for bucketRegion call S3 as is.
+func redirectingS3Client(bucketRegion string, redirects *int64) *s3.S3 {
+	client := s3.New(session.Must(session.NewSession(&s3Client.Config)))
+	client.Handlers.Send.Clear()
+	client.Handlers.Send.PushBack(func(r *request.Request) {
+		if aws.StringValue(r.Config.Region) == bucketRegion {
+			corehandlers.SendHandler.Fn(r)
+			return
+		}
+
+		atomic.AddInt64(redirects, 1)
+		header := http.Header{}
+		header.Set("X-Amz-Bucket-Region", bucketRegion)
+		r.HTTPResponse = &http.Response{
+			StatusCode: http.StatusMovedPermanently,
+			Header:     header,
+			Body:       ioutil.NopCloser(strings.NewReader(permanentRedirectBody)),
+		}
+	})
+
+	return client
+}
+
+func TestDownloadService_DownloadRegionRedirect(t *testing.T) {
+	const bucketRegion = "eu-west-1"
+
+	serviceLogger := logrus.New()
+	serviceLogger.SetOutput(ioutil.Discard)
+	hook := test.NewLocal(serviceLogger)
+
+	var redirects int64
+	service := download.NewService(redirectingS3Client(bucketRegion, &redirects), serviceLogger)
+
+	// The first download resolves the redirect, the second uses the cached region.
+	for i := 0; i < 2; i++ {
+		stream := &hashingDownloadStream{ctx: context.Background(), hash: sha256.New()}
+		if err := service.Download(&pb.DownloadRequest{Key: testkey, Bucket: testbucket}, stream); err != nil {
+			t.Fatalf("DownloadService.Download() error = %v", err)
+		}
+
+		wantHash := sha256.Sum256(file)
+		if !bytes.Equal(stream.hash.Sum(nil), wantHash[:]) {
+			t.Errorf("DownloadService.Download() file downloaded is different from the wanted file")
+		}
+	}
+
+	if redirects != 1 {
+		t.Errorf("DownloadService.Download() was redirected %d times, want 1", redirects)
+	}
+
+	resolved := 0
+	for _, entry := range hook.AllEntries() {
+		if strings.Contains(entry.Message, "resolved redirect") && strings.Contains(entry.Message, bucketRegion) {
+			resolved++
+		}
+	}
+
+	if resolved != 1 {
+		t.Errorf("DownloadService.Download() logged %d redirect resolutions, want 1", resolved)
+	}
+}
+
+func TestDownloadService_ListObjectsRegionRedirect(t *testing.T) {
+	var redirects int64
+	service := download.NewService(redirectingS3Client("eu-west-1", &redirects), logger)
+
+	res, err := service.ListObjects(context.Background(), &pb.ListObjectsRequest{Bucket: testbucket})
+	if err != nil {
+		t.Fatalf("DownloadService.ListObjects() error = %v", err)
+	}
+
+	if len(res.GetObjects()) == 0 {
+		t.Errorf("DownloadService.ListObjects() listed no objects, want the bucket's objects")
+	}
+
+	if redirects != 1 {
+		t.Errorf("DownloadService.ListObjects() was redirected %d times, want 1", redirects)
+	}
+}
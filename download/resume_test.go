@@ -0,0 +1,138 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDownloadService_DownloadResumeOffset(t *testing.T) {
+	const resumeKey = "resume.bin"
+
+	// The object spans a few parts, so resuming skips whole parts.
+	object := make([]byte, 2*download.PartSize+12345)
+	rand.New(rand.NewSource(1)).Read(object)
+	if _, err := s3Client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(testbucket),
+		Key:    aws.String(resumeKey),
+		Body:   bytes.NewReader(object),
+	}); err != nil {
+		t.Fatalf("failed to upload %s, %v", resumeKey, err)
+	}
+
+	size := int64(len(object))
+	tests := []struct {
+		name       string
+		offset     int64
+		rangeStart int64
+		rangeEnd   int64
+		reverse    bool
+		wantCode   codes.Code
+	}{
+		{name: "resume - from the start", offset: 0},
+		{name: "resume - within the first part", offset: 12345},
+		{name: "resume - at a part boundary", offset: download.PartSize},
+		{name: "resume - within a later part", offset: download.PartSize + 54321},
+		{name: "resume - at the last byte", offset: size - 1},
+		{name: "resume - after the last byte", offset: size},
+		{name: "resume - within a range", offset: download.PartSize + 1, rangeStart: 100, rangeEnd: size - 100},
+		{name: "resume - negative offset", offset: -1, wantCode: codes.InvalidArgument},
+		{name: "resume - beyond the object", offset: size + 1, wantCode: codes.InvalidArgument},
+		{name: "resume - before the range", offset: 10, rangeStart: 100, wantCode: codes.InvalidArgument},
+		{name: "resume - reversed", offset: 10, reverse: true, wantCode: codes.InvalidArgument},
+	}
+
+	service := download.NewService(s3Client, logger)
+	service.MaxBufferSize = 1 << 20
+	client, closeClient := newServiceClient(t, service)
+	defer closeClient()
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := client.Download(context.Background(), &pb.DownloadRequest{
+				Key:        resumeKey,
+				Bucket:     testbucket,
+				RangeStart: tt.rangeStart,
+				RangeEnd:   tt.rangeEnd,
+				Reverse:    tt.reverse,
+				Offset:     tt.offset,
+			})
+			if err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			got, err := recvAll(stream)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("DownloadService.Download() error = %v, want code %v", err, tt.wantCode)
+			}
+
+			if tt.wantCode != codes.OK {
+				return
+			}
+
+			// The bytes the client had before the offset and the resumed bytes reassemble the range.
+			rangeEnd := size
+			if tt.rangeEnd != 0 {
+				rangeEnd = tt.rangeEnd + 1
+			}
+
+			start := tt.offset
+			if start == 0 {
+				start = tt.rangeStart
+			}
+
+			resumed := append(append([]byte(nil), object[tt.rangeStart:start]...), got...)
+			if !bytes.Equal(resumed, object[tt.rangeStart:rangeEnd]) {
+				t.Errorf("DownloadService.Download() resumed file is different from the wanted file")
+			}
+		})
+	}
+}
+
+func TestDownloadService_DownloadResumeInterrupted(t *testing.T) {
+	service := download.NewService(s3Client, logger)
+	service.MaxBufferSize = 256 << 10
+	client, closeClient := newServiceClient(t, service)
+	defer closeClient()
+
+	// Interrupt the download after its first chunk.
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := client.Download(ctx, &pb.DownloadRequest{Key: testkey, Bucket: testbucket})
+	if err != nil {
+		t.Fatalf("DownloadService.Download() error = %v", err)
+	}
+
+	chunk, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("DownloadService.Download() error = %v", err)
+	}
+	cancel()
+
+	received := append([]byte(nil), chunk.GetFile()...)
+	stream, err = client.Download(context.Background(), &pb.DownloadRequest{
+		Key:    testkey,
+		Bucket: testbucket,
+		Offset: int64(len(received)),
+	})
+	if err != nil {
+		t.Fatalf("DownloadService.Download() error = %v", err)
+	}
+
+	rest, err := recvAll(stream)
+	if err != nil {
+		t.Fatalf("DownloadService.Download() error = %v", err)
+	}
+
+	if !bytes.Equal(append(received, rest...), file) {
+		t.Errorf("DownloadService.Download() resumed file is different from the wanted file")
+	}
+}
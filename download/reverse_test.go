@@ -0,0 +1,109 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+)
+
+func TestDownloadService_DownloadReverse(t *testing.T) {
+	const reverseKey = "reverse.txt"
+
+	// Upload a fixture of a few parts, the last one partial.
+	reverseFile := make([]byte, 2*download.PartSize+(1<<20))
+	if _, err := rand.Read(reverseFile); err != nil {
+		t.Fatalf("failed to generate file, %v", err)
+	}
+
+	if _, err := s3Client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(testbucket),
+		Key:    aws.String(reverseKey),
+		Body:   bytes.NewReader(reverseFile),
+	}); err != nil {
+		t.Fatalf("failed to upload %s, %v", reverseKey, err)
+	}
+
+	tests := []struct {
+		name       string
+		rangeStart int64
+		rangeEnd   int64
+		wantParts  [][2]int64
+	}{
+		{
+			name: "reverse - whole object",
+			wantParts: [][2]int64{
+				{2 * download.PartSize, int64(len(reverseFile))},
+				{download.PartSize, 2 * download.PartSize},
+				{0, download.PartSize},
+			},
+		},
+		{
+			name:       "reverse - range",
+			rangeStart: 1<<20 + 3,
+			rangeEnd:   1<<20 + 3 + download.PartSize + 9,
+			wantParts: [][2]int64{
+				{1<<20 + 3 + download.PartSize, 1<<20 + 3 + download.PartSize + 10},
+				{1<<20 + 3, 1<<20 + 3 + download.PartSize},
+			},
+		},
+		{
+			name:       "reverse - single part",
+			rangeStart: 10,
+			rangeEnd:   19,
+			wantParts:  [][2]int64{{10, 20}},
+		},
+	}
+
+	service := download.NewService(s3Client, logger)
+	service.MaxBufferSize = 1 << 20
+	service.StrictOrderAssert = true
+
+	client, closeClient := newServiceClient(t, service)
+	defer closeClient()
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := client.Download(context.Background(), &pb.DownloadRequest{
+				Key:        reverseKey,
+				Bucket:     testbucket,
+				RangeStart: tt.rangeStart,
+				RangeEnd:   tt.rangeEnd,
+				Reverse:    true,
+			})
+			if err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			got, err := recvAll(stream)
+			if err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			var want []byte
+			for _, part := range tt.wantParts {
+				want = append(want, reverseFile[part[0]:part[1]]...)
+			}
+
+			if !bytes.Equal(got, want) {
+				t.Errorf("DownloadService.Download() downloaded parts out of the wanted order")
+			}
+
+			header, err := stream.Header()
+			if err != nil {
+				t.Fatalf("failed to get the response header: %v", err)
+			}
+
+			if got := header.Get(download.PartSizeHeader); len(got) != 1 || got[0] != strconv.Itoa(download.PartSize) {
+				t.Errorf("header %s = %v, want %d", download.PartSizeHeader, got, download.PartSize)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package download
+
+import (
+	"hash/fnv"
+)
+
+// BucketRouter resolves the physical bucket that stores key, for requests that omit the bucket.
+// An empty bucket leaves the request without one.
+type BucketRouter func(key string) (bucket string)
+
+// IdentityBucketRouter is the default BucketRouter, it routes no keys so requests must name their bucket.
+func IdentityBucketRouter(string) string {
+	return ""
+}
+
+// HashBucketRouter returns a BucketRouter that shards keys across buckets by the FNV-1a hash of the key.
+// It routes no keys when buckets is empty.
+func HashBucketRouter(buckets []string) BucketRouter {
+	if len(buckets) == 0 {
+		return IdentityBucketRouter
+	}
+
+	return func(key string) string {
+		h := fnv.New32a()
+		h.Write([]byte(key))
+
+		return buckets[h.Sum32()%uint32(len(buckets))]
+	}
+}
@@ -0,0 +1,98 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+)
+
+func TestHashBucketRouter(t *testing.T) {
+	buckets := []string{"shard-0", "shard-1", "shard-2"}
+	router := download.HashBucketRouter(buckets)
+
+	routed := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		bucket := router(key)
+		if bucket != router(key) {
+			t.Fatalf("HashBucketRouter() routed %q to different buckets", key)
+		}
+
+		routed[bucket] = true
+	}
+
+	for _, bucket := range buckets {
+		if !routed[bucket] {
+			t.Errorf("HashBucketRouter() routed no keys to %q", bucket)
+		}
+	}
+
+	if bucket := download.HashBucketRouter(nil)("key"); bucket != "" {
+		t.Errorf("HashBucketRouter(nil) routed to %q, want no bucket", bucket)
+	}
+}
+
+func TestDownloadService_DownloadBucketRouter(t *testing.T) {
+	buckets := []string{"shard-0", "shard-1"}
+	for _, bucket := range buckets {
+		if err := emptyAndDeleteBucket(bucket); err != nil {
+			t.Logf("failed to emptyAndDeleteBucket, %v", err)
+		}
+
+		if _, err := s3Client.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String(bucket)}); err != nil {
+			t.Fatalf("failed to create bucket %s, %v", bucket, err)
+		}
+	}
+
+	service := download.NewService(s3Client, logger)
+	service.BucketRouter = download.HashBucketRouter(buckets)
+
+	// Store every object in the bucket it's routed to.
+	objects := make(map[string][]byte)
+	for i := 0; i < 4; i++ {
+		key := fmt.Sprintf("routed/object-%d.txt", i)
+		objects[key] = []byte(key)
+		if _, err := s3Client.PutObject(&s3.PutObjectInput{
+			Bucket: aws.String(service.BucketRouter(key)),
+			Key:    aws.String(key),
+			Body:   bytes.NewReader(objects[key]),
+		}); err != nil {
+			t.Fatalf("failed to upload %s, %v", key, err)
+		}
+	}
+
+	client, closeClient := newServiceClient(t, service)
+	defer closeClient()
+
+	for key, want := range objects {
+		stream, err := client.Download(context.Background(), &pb.DownloadRequest{Key: key})
+		if err != nil {
+			t.Fatalf("DownloadService.Download() error = %v", err)
+		}
+
+		got, err := recvAll(stream)
+		if err != nil {
+			t.Fatalf("DownloadService.Download(%q) error = %v", key, err)
+		}
+
+		if !bytes.Equal(got, want) {
+			t.Errorf("DownloadService.Download(%q) = %q, want %q", key, got, want)
+		}
+	}
+
+	// An explicit bucket takes precedence over the router.
+	stream, err := client.Download(context.Background(), &pb.DownloadRequest{Key: testkey, Bucket: testbucket})
+	if err != nil {
+		t.Fatalf("DownloadService.Download() error = %v", err)
+	}
+
+	if got, err := recvAll(stream); err != nil || !bytes.Equal(got, file) {
+		t.Errorf("DownloadService.Download() with an explicit bucket error = %v", err)
+	}
+}
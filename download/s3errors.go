@@ -0,0 +1,75 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// s3ErrorToStatus returns err, an error of a call to S3 possibly wrapped with %w, as a status error
+// of the code its S3 error maps to, so clients can handle it by its code: missing objects and buckets
+// and versions map to NotFound, denied access to PermissionDenied and cancelled calls to Canceled.
+// Status errors and errors that don't map to a code are returned as is, and wrapped status errors
+// keep their code.
+func s3ErrorToStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	code := s3ErrorCode(err)
+	if code == codes.Unknown {
+		return err
+	}
+
+	return status.Error(code, err.Error())
+}
+
+// s3ErrorCode returns the code that err, an error of a call to S3, maps to, Unknown if none.
+func s3ErrorCode(err error) codes.Code {
+	// Errors of the service wrapped with the call that failed keep their code, e.g. of truncated bodies.
+	var statusErr interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &statusErr) {
+		return statusErr.GRPCStatus().Code()
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return codes.Canceled
+	}
+
+	// Responses to HeadObject have no body, so their errors are known only by their status code.
+	var reqErr awserr.RequestFailure
+	if errors.As(err, &reqErr) {
+		switch reqErr.StatusCode() {
+		case http.StatusNotFound:
+			return codes.NotFound
+		case http.StatusForbidden:
+			return codes.PermissionDenied
+		}
+	}
+
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) {
+		return codes.Unknown
+	}
+
+	switch aerr.Code() {
+	case "NotFound", "NoSuchVersion", s3.ErrCodeNoSuchKey, s3.ErrCodeNoSuchBucket:
+		return codes.NotFound
+	case "AccessDenied", "Forbidden":
+		return codes.PermissionDenied
+	case request.CanceledErrorCode:
+		return codes.Canceled
+	}
+
+	return codes.Unknown
+}
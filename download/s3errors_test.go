@@ -0,0 +1,72 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestS3ErrorToStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode codes.Code
+	}{
+		{name: "nil", err: nil, wantCode: codes.OK},
+		{name: "NoSuchKey", err: awserr.New(s3.ErrCodeNoSuchKey, "", nil), wantCode: codes.NotFound},
+		{name: "NoSuchBucket", err: awserr.New(s3.ErrCodeNoSuchBucket, "", nil), wantCode: codes.NotFound},
+		{name: "NoSuchVersion", err: awserr.New("NoSuchVersion", "", nil), wantCode: codes.NotFound},
+		{
+			name:     "HeadObject not found",
+			err:      awserr.NewRequestFailure(awserr.New("NotFound", "", nil), http.StatusNotFound, ""),
+			wantCode: codes.NotFound,
+		},
+		{name: "AccessDenied", err: awserr.New("AccessDenied", "", nil), wantCode: codes.PermissionDenied},
+		{
+			name:     "HeadObject forbidden",
+			err:      awserr.NewRequestFailure(awserr.New("Forbidden", "", nil), http.StatusForbidden, ""),
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:     "request canceled",
+			err:      awserr.New(request.CanceledErrorCode, "", context.Canceled),
+			wantCode: codes.Canceled,
+		},
+		{name: "context canceled", err: context.Canceled, wantCode: codes.Canceled},
+		{
+			name:     "wrapped",
+			err:      fmt.Errorf("failed to download object: %w", awserr.New(s3.ErrCodeNoSuchKey, "", nil)),
+			wantCode: codes.NotFound,
+		},
+		{name: "other S3 error", err: awserr.New("InternalError", "", nil), wantCode: codes.Unknown},
+		{name: "non-S3 error", err: errors.New("failed"), wantCode: codes.Unknown},
+		{name: "status error", err: status.Error(codes.FailedPrecondition, ""), wantCode: codes.FailedPrecondition},
+		{
+			name:     "wrapped status error",
+			err:      fmt.Errorf("failed to download object: %w", status.Error(codes.DataLoss, "truncated")),
+			wantCode: codes.DataLoss,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got := s3ErrorToStatus(tt.err)
+			if code := status.Code(got); code != tt.wantCode {
+				t.Errorf("s3ErrorToStatus() code = %v, want %v", code, tt.wantCode)
+			}
+
+			if tt.err != nil && got.Error() != tt.err.Error() && status.Convert(got).Message() != tt.err.Error() {
+				t.Errorf("s3ErrorToStatus() = %v, want the message of %v", got, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package download
+
+import "sync"
+
+// sendBuffers pools the buffers that downloads read the bytes of their parts into and send them from,
+// reused across downloads instead of allocating up to a part's bytes for every download.
+var sendBuffers = sync.Pool{
+	New: func() interface{} {
+		return new([]byte)
+	},
+}
+
+// getSendBuffer returns a buffer of size bytes from sendBuffers, allocated if the pooled buffer is smaller.
+// It must be returned by putSendBuffer once the download ended, and not used after.
+func getSendBuffer(size int64) []byte {
+	buffer := sendBuffers.Get().(*[]byte)
+	if int64(cap(*buffer)) < size {
+		return make([]byte, size)
+	}
+
+	return (*buffer)[:size]
+}
+
+// putSendBuffer returns buffer to sendBuffers for the next downloads.
+// Messages are serialized by the stream's Send, so no message references buffer once its download ended.
+func putSendBuffer(buffer []byte) {
+	if cap(buffer) == 0 {
+		return
+	}
+
+	sendBuffers.Put(&buffer)
+}
@@ -0,0 +1,90 @@
+package download_test
+
+import (
+	"bytes"
+	"io"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+)
+
+// closeCountingBody is an object body that counts its closes.
+type closeCountingBody struct {
+	io.ReadCloser
+	closed *int64
+}
+
+func (b closeCountingBody) Close() error {
+	atomic.AddInt64(b.closed, 1)
+
+	return b.ReadCloser.Close()
+}
+
+// closeCountingS3Client returns an S3 client that counts the bodies of the objects it gets, and their closes.
+func closeCountingS3Client(opened *int64, closed *int64) *s3.S3 {
+	client := s3.New(session.Must(session.NewSession(&s3Client.Config)))
+	client.Handlers.Send.PushBack(func(r *request.Request) {
+		if _, ok := r.Params.(*s3.GetObjectInput); !ok || r.Error != nil {
+			return
+		}
+
+		atomic.AddInt64(opened, 1)
+		r.HTTPResponse.Body = closeCountingBody{ReadCloser: r.HTTPResponse.Body, closed: closed}
+	})
+
+	return client
+}
+
+func TestDownloadService_DownloadPooledBuffers(t *testing.T) {
+	tests := []struct {
+		name       string
+		rangeStart int64
+		rangeEnd   int64
+		failAt     int
+		wantErr    bool
+	}{
+		{name: "pooled buffers - whole object", rangeEnd: int64(len(file)) - 1},
+		{name: "pooled buffers - range smaller than a chunk", rangeStart: 10, rangeEnd: 1000},
+		{name: "pooled buffers - whole object again", rangeEnd: int64(len(file)) - 1},
+		{name: "pooled buffers - unaligned range", rangeStart: 1<<20 + 3, rangeEnd: int64(len(file)) - 1},
+		{name: "pooled buffers - send error", rangeEnd: int64(len(file)) - 1, failAt: 6, wantErr: true},
+	}
+
+	var opened, closed int64
+	service := download.NewService(closeCountingS3Client(&opened, &closed), logger)
+	service.PartSize = download.MinPartSize
+	service.MaxBufferSize = 64 << 10
+
+	// The downloads run one after the other, reusing the buffers of the previous downloads.
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			stream := &slowDownloadStream{failAt: tt.failAt}
+			err := service.Download(&pb.DownloadRequest{
+				Key:        testkey,
+				Bucket:     testbucket,
+				RangeStart: tt.rangeStart,
+				RangeEnd:   tt.rangeEnd,
+			}, stream)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DownloadService.Download() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && !bytes.Equal(stream.received.Bytes(), file[tt.rangeStart:tt.rangeEnd+1]) {
+				t.Errorf(
+					"DownloadService.Download() sent %d bytes different from the wanted %d bytes",
+					stream.received.Len(), tt.rangeEnd-tt.rangeStart+1,
+				)
+			}
+
+			if opened != closed {
+				t.Errorf("DownloadService.Download() closed %d of the %d bodies it got", closed, opened)
+			}
+		})
+	}
+}
@@ -0,0 +1,339 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	// DefaultSequentialPrefetchTTL is the default time a prefetched range is kept for the client
+	// to request it before it's dropped.
+	DefaultSequentialPrefetchTTL = 30 * time.Second
+
+	// maxSequentialReaders bounds the clients whose reads are tracked for sequential access.
+	maxSequentialReaders = 10000
+)
+
+// sequentialKey identifies a client reading a version of an object, with the access key id of
+// its delegated credentials, if delegated.
+type sequentialKey struct {
+	client      string
+	accessKeyID string
+	bucket      string
+	key         string
+	versionID   string
+}
+
+// sequentialReader is the reading of an object by a client.
+type sequentialReader struct {
+	// next is the first byte after the last range the client read.
+	next     int64
+	lastRead time.Time
+	prefetch *rangePrefetch
+}
+
+// rangePrefetch is a range of an object fetched into memory ahead of the client requesting it.
+// Its fields other than r and cancel are set once done is closed.
+type rangePrefetch struct {
+	r       byteRange
+	cancel  context.CancelFunc
+	done    chan struct{}
+	data    []byte
+	etag    string
+	err     error
+	expire  *time.Timer
+	release sync.Once
+}
+
+// part returns the body of the bytes of partRange, which is within the prefetched range.
+func (f *rangePrefetch) part(partRange byteRange) io.ReadCloser {
+	return ioutil.NopCloser(bytes.NewReader(f.data[partRange.start-f.r.start : partRange.end-f.r.start+1]))
+}
+
+// SequentialPrefetcher detects clients reading consecutive ranges of an object, and prefetches the
+// range after the last range they read, of the same length, from S3 before they request it.
+// The prefetched ranges are bounded by a total size, and dropped once the client diverges from
+// the pattern or doesn't request them within a TTL.
+type SequentialPrefetcher struct {
+	maxSize int64
+	ttl     time.Duration
+	hits    int64
+	misses  int64
+
+	mu      sync.Mutex
+	size    int64
+	readers map[sequentialKey]*sequentialReader
+}
+
+// NewSequentialPrefetcher returns a SequentialPrefetcher whose prefetched ranges take up to maxSize bytes,
+// kept for up to ttl, a non-positive ttl defaults to DefaultSequentialPrefetchTTL.
+func NewSequentialPrefetcher(maxSize int64, ttl time.Duration) *SequentialPrefetcher {
+	if ttl <= 0 {
+		ttl = DefaultSequentialPrefetchTTL
+	}
+
+	return &SequentialPrefetcher{maxSize: maxSize, ttl: ttl, readers: make(map[sequentialKey]*sequentialReader)}
+}
+
+// Hits returns the number of downloads served from a prefetched range.
+func (p *SequentialPrefetcher) Hits() int64 {
+	return atomic.LoadInt64(&p.hits)
+}
+
+// Misses returns the number of prefetched ranges that were dropped unused, since the client requested
+// another range, the object changed, or fetching them failed.
+func (p *SequentialPrefetcher) Misses() int64 {
+	return atomic.LoadInt64(&p.misses)
+}
+
+// read records that the client of k reads r, and returns the range prefetched for it, if it starts at r,
+// and whether r follows the last range the client read. A prefetched range starting elsewhere is dropped.
+func (p *SequentialPrefetcher) read(k sequentialKey, r byteRange, now time.Time) (*rangePrefetch, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	reader, ok := p.readers[k]
+	if !ok {
+		if len(p.readers) >= maxSequentialReaders {
+			p.evictIdle(now)
+		}
+
+		if len(p.readers) < maxSequentialReaders {
+			p.readers[k] = &sequentialReader{next: r.end + 1, lastRead: now}
+		}
+
+		return nil, false
+	}
+
+	sequential := r.start == reader.next
+	prefetch := reader.prefetch
+	reader.prefetch = nil
+	reader.next = r.end + 1
+	reader.lastRead = now
+
+	if prefetch != nil && prefetch.r.start != r.start {
+		p.drop(prefetch)
+		prefetch = nil
+	}
+
+	return prefetch, sequential
+}
+
+// evictIdle stops tracking the readers that read nothing within the TTL, dropping their prefetched ranges.
+// It must be called with p.mu held.
+func (p *SequentialPrefetcher) evictIdle(now time.Time) {
+	for k, reader := range p.readers {
+		if now.Sub(reader.lastRead) < p.ttl {
+			continue
+		}
+
+		if reader.prefetch != nil {
+			p.drop(reader.prefetch)
+		}
+		delete(p.readers, k)
+	}
+}
+
+// reserve reserves size bytes for a prefetched range, and returns false if they exceed the max size.
+func (p *SequentialPrefetcher) reserve(size int64) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.size+size > p.maxSize {
+		return false
+	}
+
+	p.size += size
+
+	return true
+}
+
+// release releases the bytes reserved for prefetch, once.
+func (p *SequentialPrefetcher) release(prefetch *rangePrefetch) {
+	prefetch.release.Do(func() {
+		prefetch.expire.Stop()
+		prefetch.cancel()
+
+		p.mu.Lock()
+		p.size -= prefetch.r.length()
+		p.mu.Unlock()
+	})
+}
+
+// drop stops fetching prefetch, if it's still fetched, and releases it unused.
+func (p *SequentialPrefetcher) drop(prefetch *rangePrefetch) {
+	atomic.AddInt64(&p.misses, 1)
+	go p.release(prefetch)
+}
+
+// store keeps prefetch for the client of k to request, dropping the range prefetched before it, if any.
+// It returns false if the client isn't tracked anymore.
+func (p *SequentialPrefetcher) store(k sequentialKey, prefetch *rangePrefetch) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	reader, ok := p.readers[k]
+	if !ok {
+		return false
+	}
+
+	if reader.prefetch != nil {
+		p.drop(reader.prefetch)
+	}
+	reader.prefetch = prefetch
+
+	return true
+}
+
+// expireRange drops prefetch if it's still kept for the client of k once its TTL passed.
+func (p *SequentialPrefetcher) expireRange(k sequentialKey, prefetch *rangePrefetch) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if reader, ok := p.readers[k]; ok && reader.prefetch == prefetch {
+		reader.prefetch = nil
+		p.drop(prefetch)
+	}
+}
+
+// sequentialClient returns the identity of the client of ctx whose reads are tracked,
+// its subject and its address.
+func sequentialClient(ctx context.Context) string {
+	client := SubjectFromContext(ctx)
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		client += "@" + p.Addr.String()
+	}
+
+	return client
+}
+
+// readSequentially serves the range of d from its prefetch if the client prefetched it by reading the object
+// sequentially, and prefetches the range after it if the client reads the object sequentially. The returned
+// function releases the prefetched range once d was sent. Reversed downloads and downloads of a known size
+// aren't tracked.
+func (s Service) readSequentially(ctx context.Context, d *partDownload) func() {
+	p := s.SequentialPrefetch
+	if p == nil || d.reverse || d.knownSize != 0 || d.objectRange.length() <= 0 {
+		return func() {}
+	}
+
+	k := sequentialKey{
+		client:    sequentialClient(d.stream.Context()),
+		bucket:    d.bucket,
+		key:       d.key,
+		versionID: aws.StringValue(versionIDFromContext(ctx)),
+	}
+	if s.isDelegated(ctx) {
+		creds, _ := delegatedCredentialsFromContext(ctx)
+		k.accessKeyID = creds.accessKeyID
+	}
+	prefetch, sequential := p.read(k, d.objectRange, time.Now())
+	if sequential {
+		next := byteRange{start: d.objectRange.end + 1, end: d.objectRange.end + d.objectRange.length()}
+		if next.end >= d.objectSize {
+			next.end = d.objectSize - 1
+		}
+
+		s.prefetchRange(ctx, k, d.bucket, d.key, next)
+	}
+
+	if prefetch == nil {
+		return func() {}
+	}
+
+	select {
+	case <-prefetch.done:
+	case <-ctx.Done():
+		p.drop(prefetch)
+		return func() {}
+	}
+
+	if prefetch.err != nil || prefetch.etag != d.etag || prefetch.r.end < d.objectRange.end {
+		p.drop(prefetch)
+		return func() {}
+	}
+
+	atomic.AddInt64(&p.hits, 1)
+	d.warm = prefetch
+
+	return func() {
+		d.warm = nil
+		p.release(prefetch)
+	}
+}
+
+// prefetchRange starts fetching r of bucket/key into memory for the client of k, if r isn't empty
+// and fits within the prefetcher's max size. It's fetched with the credentials, failover and version
+// of the download of ctx, but isn't cancelled with it.
+func (s Service) prefetchRange(ctx context.Context, k sequentialKey, bucket string, key string, r byteRange) {
+	p := s.SequentialPrefetch
+	if r.length() <= 0 || !p.reserve(r.length()) {
+		return
+	}
+
+	// The prefetch outlives the download that started it.
+	ctx, cancel := context.WithCancel(contextWithVersionID(detachedContext{ctx}, k.versionID))
+	prefetch := &rangePrefetch{r: r, cancel: cancel, done: make(chan struct{})}
+	prefetch.expire = time.AfterFunc(p.ttl, func() { p.expireRange(k, prefetch) })
+	if !p.store(k, prefetch) {
+		p.release(prefetch)
+		return
+	}
+
+	go func() {
+		defer close(prefetch.done)
+
+		object, err := s.getPartObject(ctx, bucket, &s3.GetObjectInput{
+			Key:    aws.String(key),
+			Bucket: aws.String(bucket),
+			Range:  aws.String(fmt.Sprintf("bytes=%d-%d", r.start, r.end)),
+		})
+		if err != nil {
+			prefetch.err = err
+			return
+		}
+		defer object.Body.Close()
+
+		prefetch.etag = aws.StringValue(object.ETag)
+		prefetch.data = make([]byte, r.length())
+		if _, err := io.ReadFull(object.Body, prefetch.data); err != nil {
+			prefetch.err = err
+		}
+	}()
+}
+
+// detachedContext is a context that holds the values of its parent, such as the incoming metadata
+// of its request, but is never cancelled and has no deadline.
+type detachedContext struct {
+	parent context.Context
+}
+
+// Deadline returns no deadline.
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// Done returns nil, the context is never done.
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+// Err returns nil, the context is never done.
+func (detachedContext) Err() error {
+	return nil
+}
+
+// Value returns the value of the parent associated with key.
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
@@ -0,0 +1,126 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/metadata"
+)
+
+// recvRange downloads the bytes start to end, inclusive, of the test file with client in ctx,
+// failing t if they're different from the file's.
+func recvRange(ctx context.Context, t *testing.T, client pb.DownloadClient, start int64, end int64) {
+	t.Helper()
+
+	stream, err := client.Download(ctx, &pb.DownloadRequest{
+		Key:        testkey,
+		Bucket:     testbucket,
+		RangeStart: start,
+		RangeEnd:   end,
+	})
+	if err != nil {
+		t.Fatalf("DownloadService.Download() error = %v", err)
+	}
+
+	got, err := recvAll(stream)
+	if err != nil {
+		t.Fatalf("DownloadService.Download() error = %v", err)
+	}
+
+	if !bytes.Equal(got, file[start:end+1]) {
+		t.Errorf("DownloadService.Download() bytes %d-%d are different from the file's", start, end)
+	}
+}
+
+func TestDownloadService_DownloadSequentialPrefetch(t *testing.T) {
+	// The range being served from memory and the range prefetched after it both take up the max size.
+	const window = 256 << 10
+
+	tests := []struct {
+		name       string
+		maxSize    int64
+		starts     []int64
+		wantHits   int64
+		wantMisses int64
+	}{
+		{
+			name:     "sequential prefetch - sequential reads",
+			maxSize:  2 * window,
+			starts:   []int64{0, window, 2 * window, 3 * window, 4 * window, 5 * window, 6 * window, 7 * window},
+			wantHits: 6,
+		},
+		{
+			name:       "sequential prefetch - divergence",
+			maxSize:    2 * window,
+			starts:     []int64{0, window, 5 * window, 6 * window, 7 * window},
+			wantHits:   1,
+			wantMisses: 1,
+		},
+		{
+			name:    "sequential prefetch - range above max size",
+			maxSize: window - 1,
+			starts:  []int64{0, window, 2 * window, 3 * window},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			service := download.NewService(s3Client, logger)
+			service.MaxBufferSize = 1 << 20
+			service.SequentialPrefetch = download.NewSequentialPrefetcher(tt.maxSize, time.Minute)
+			client, closeClient := newServiceClient(t, service)
+			defer closeClient()
+
+			for _, start := range tt.starts {
+				recvRange(context.Background(), t, client, start, start+window-1)
+			}
+
+			if hits := service.SequentialPrefetch.Hits(); hits != tt.wantHits {
+				t.Errorf("SequentialPrefetcher.Hits() = %d, want %d", hits, tt.wantHits)
+			}
+
+			if misses := service.SequentialPrefetch.Misses(); misses != tt.wantMisses {
+				t.Errorf("SequentialPrefetcher.Misses() = %d, want %d", misses, tt.wantMisses)
+			}
+		})
+	}
+}
+
+func TestDownloadService_DownloadSequentialPrefetchDelegatedCredentials(t *testing.T) {
+	const window = 256 << 10
+	const accessKeyID, secretAccessKey = "delegated-key", "delegated-secret"
+
+	recordingClient, recorded, closeProxy := credentialsRecordingS3Client(t)
+	defer closeProxy()
+
+	service := download.NewService(recordingClient, logger)
+	service.MaxBufferSize = 1 << 20
+	service.AllowDelegatedCredentials = true
+	service.SequentialPrefetch = download.NewSequentialPrefetcher(2*window, time.Minute)
+	client, closeClient := newServiceClient(t, service)
+	defer closeClient()
+
+	ctx := metadata.NewOutgoingContext(context.Background(), metadata.Pairs(
+		download.AccessKeyIDHeader, accessKeyID,
+		download.SecretAccessKeyHeader, secretAccessKey,
+	))
+	for _, start := range []int64{0, window, 2 * window} {
+		recvRange(ctx, t, client, start, start+window-1)
+	}
+
+	if hits := service.SequentialPrefetch.Hits(); hits == 0 {
+		t.Fatalf("SequentialPrefetcher.Hits() = 0, want the sequential reads prefetched")
+	}
+
+	// The prefetched ranges are fetched with the delegated credentials of the reads that prefetched them.
+	for _, creds := range recorded() {
+		if creds.accessKeyID != accessKeyID {
+			t.Errorf("DownloadService.Download() signed a request to S3 with %+v, want %s", creds, accessKeyID)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// spillFilePattern is the pattern of the names of the temporary files parts are spilled to.
+const spillFilePattern = "download-spill-*"
+
+// spilledPart is a part prefetched into a temporary file, or the error prefetching it.
+type spilledPart struct {
+	file *os.File
+	err  error
+}
+
+// spillPrefetcher prefetches the parts of a download, in order, into temporary files,
+// bounding the number of parts spilled to disk at once.
+type spillPrefetcher struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	parts  chan spilledPart
+	slots  chan struct{}
+	done   chan struct{}
+}
+
+// spillParts starts prefetching the totalParts parts of objectRange of bucket/key into temporary
+// files in s.SpillDir, up to s.SpillMaxSize bytes at once, and at least a single part.
+// The returned prefetcher must be closed to remove the files that weren't read.
+func (s Service) spillParts(
+	ctx context.Context,
+	bucket string,
+	key string,
+	objectRange byteRange,
+	partSize int64,
+	alignParts bool,
+	totalParts int64,
+) *spillPrefetcher {
+	maxParts := s.SpillMaxSize / partSize
+	if maxParts < 1 {
+		maxParts = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	p := &spillPrefetcher{
+		ctx:    ctx,
+		cancel: cancel,
+		parts:  make(chan spilledPart, maxParts),
+		slots:  make(chan struct{}, maxParts),
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		defer close(p.done)
+		defer close(p.parts)
+
+		for currentPart := int64(0); currentPart < totalParts; currentPart++ {
+			// Wait for a slot to spill the part into.
+			select {
+			case p.slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			file, err := s.spillPart(ctx, bucket, key, objectRange.part(currentPart, partSize, alignParts), currentPart)
+
+			// Every part holds a slot, so parts never blocks.
+			p.parts <- spilledPart{file: file, err: err}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	return p
+}
+
+// next returns the next prefetched part, reading it removes it from disk once it's closed.
+// It returns the error of prefetching the part, after which the prefetcher must be closed.
+func (p *spillPrefetcher) next() (io.ReadCloser, error) {
+	part, ok := <-p.parts
+	if !ok {
+		if err := p.ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("no more spilled parts")
+	}
+
+	if part.err != nil {
+		return nil, part.err
+	}
+
+	return spilledFile{File: part.file, slots: p.slots}, nil
+}
+
+// close stops prefetching and removes the spilled parts that weren't read.
+func (p *spillPrefetcher) close() {
+	p.cancel()
+	<-p.done
+
+	for part := range p.parts {
+		if part.file != nil {
+			removeSpill(part.file)
+		}
+	}
+}
+
+// spilledFile is the temporary file of a spilled part, which is removed and frees
+// its slot when it's closed.
+type spilledFile struct {
+	*os.File
+	slots chan struct{}
+}
+
+// Close removes the file and frees its slot for the next part.
+func (f spilledFile) Close() error {
+	err := removeSpill(f.File)
+	<-f.slots
+
+	return err
+}
+
+// spillPart downloads partRange, the part number currentPart of bucket/key, into a temporary file in s.SpillDir,
+// and returns the file positioned at its start.
+func (s Service) spillPart(
+	ctx context.Context,
+	bucket string,
+	key string,
+	partRange byteRange,
+	currentPart int64,
+) (*os.File, error) {
+	getObjectInput := &s3.GetObjectInput{
+		Key:        aws.String(key),
+		Bucket:     aws.String(bucket),
+		PartNumber: aws.Int64(currentPart),
+		Range:      aws.String(fmt.Sprintf("bytes=%d-%d", partRange.start, partRange.end)),
+	}
+
+	span := s.startClientSpan(ctx, "s3.GetObject", opentracing.Tags{
+		"s3.bucket": bucket,
+		"s3.key":    key,
+		"s3.range":  aws.StringValue(getObjectInput.Range),
+	})
+	objectPartOutput, err := s.getPartObject(ctx, bucket, getObjectInput)
+	if err != nil {
+		finishSpan(span, err)
+		return nil, err
+	}
+	defer objectPartOutput.Body.Close()
+
+	file, err := ioutil.TempFile(s.SpillDir, spillFilePattern)
+	if err != nil {
+		finishSpan(span, err)
+		return nil, fmt.Errorf("failed to create spill of part %d: %v", currentPart, err)
+	}
+
+	_, err = io.Copy(file, objectPartOutput.Body)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+
+	finishSpan(span, err)
+	if err != nil {
+		removeSpill(file)
+		return nil, fmt.Errorf("failed to spill part %d: %v", currentPart, err)
+	}
+
+	return file, nil
+}
+
+// removeSpill closes and removes the spill file.
+func removeSpill(file *os.File) error {
+	file.Close()
+
+	return os.Remove(file.Name())
+}
@@ -0,0 +1,132 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// spillDownloadStream is a pb.Download_DownloadServer that records the bytes sent on it
+// and the most files it saw in dir while sending, and fails the send number failAt, if set.
+type spillDownloadStream struct {
+	grpc.ServerStream
+	t        *testing.T
+	dir      string
+	failAt   int
+	sends    int
+	maxFiles int
+	received bytes.Buffer
+}
+
+func (s *spillDownloadStream) Context() context.Context {
+	return context.Background()
+}
+
+func (s *spillDownloadStream) SetTrailer(metadata.MD) {}
+
+func (s *spillDownloadStream) Send(res *pb.DownloadResponse) error {
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		s.t.Fatalf("failed to read spill dir, %v", err)
+	}
+
+	if len(files) > s.maxFiles {
+		s.maxFiles = len(files)
+	}
+
+	s.sends++
+	if s.sends == s.failAt {
+		return fmt.Errorf("send failed")
+	}
+
+	_, err = s.received.Write(res.GetFile())
+	return err
+}
+
+func TestDownloadService_DownloadSpill(t *testing.T) {
+	const spillKey = "spill.txt"
+
+	// Upload a fixture of a few parts, the last one partial.
+	spillFile := make([]byte, 3*download.PartSize+(1<<20))
+	if _, err := rand.Read(spillFile); err != nil {
+		t.Fatalf("failed to generate file, %v", err)
+	}
+
+	if _, err := s3Client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(testbucket),
+		Key:    aws.String(spillKey),
+		Body:   bytes.NewReader(spillFile),
+	}); err != nil {
+		t.Fatalf("failed to upload %s, %v", spillKey, err)
+	}
+
+	tests := []struct {
+		name         string
+		spillMaxSize int64
+		failAt       int
+		wantErr      bool
+		wantMaxFiles int
+	}{
+		{name: "spill - single part at a time", spillMaxSize: 0, wantMaxFiles: 1},
+		{name: "spill - bounded read-ahead", spillMaxSize: 2 * download.PartSize, wantMaxFiles: 2},
+		{name: "spill - send failure", spillMaxSize: 2 * download.PartSize, failAt: 2, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "spill-test")
+			if err != nil {
+				t.Fatalf("failed to create spill dir, %v", err)
+			}
+			defer os.RemoveAll(dir)
+
+			service := download.NewService(s3Client, logger)
+			service.MaxBufferSize = 1 << 20
+			service.SpillDir = dir
+			service.SpillMaxSize = tt.spillMaxSize
+			stream := &spillDownloadStream{t: t, dir: dir, failAt: tt.failAt}
+
+			err = service.Download(&pb.DownloadRequest{Key: spillKey, Bucket: testbucket}, stream)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DownloadService.Download() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if stream.maxFiles == 0 {
+				t.Errorf("DownloadService.Download() spilled no files to %s", dir)
+			}
+
+			if tt.wantMaxFiles > 0 && stream.maxFiles > tt.wantMaxFiles {
+				t.Errorf(
+					"DownloadService.Download() spilled %d files at once, want at most %d",
+					stream.maxFiles, tt.wantMaxFiles,
+				)
+			}
+
+			if !tt.wantErr && !bytes.Equal(stream.received.Bytes(), spillFile) {
+				t.Errorf("DownloadService.Download() file downloaded is different from the wanted file")
+			}
+
+			// The spill files must be removed once the download returns.
+			files, err := ioutil.ReadDir(dir)
+			if err != nil {
+				t.Fatalf("failed to read spill dir, %v", err)
+			}
+
+			if len(files) != 0 {
+				t.Errorf("DownloadService.Download() left %d spill files in %s", len(files), dir)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package download
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// CacheStatusHeader is the trailer key that marks calls served from the cache.
+	CacheStatusHeader = "x-cache-status"
+
+	// CacheStatusStale is the CacheStatusHeader of calls served expired cache entries while S3 failed.
+	CacheStatusStale = "stale"
+)
+
+// staleHead returns the expired HeadObject result of bucket/key cached in s.HeadCache up to its max
+// staleness, after fetching it failed with fetchErr, and marks the call of ctx with a stale
+// CacheStatusHeader trailer. It returns fetchErr if no such result is cached.
+func (s Service) staleHead(
+	ctx context.Context,
+	bucket string,
+	key string,
+	fetchErr error,
+) (*s3.HeadObjectOutput, error) {
+	head, ok := s.HeadCache.GetStale(bucket, key)
+	if !ok {
+		return nil, fetchErr
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"s3.bucket":  bucket,
+		"key.prefix": KeyPrefixLabel(key, s.KeyPrefixAllowlist),
+		"trace.id":   s.traceID(ctx),
+	}).Warnf("serving stale head of %s/%s after S3 failed: %v", bucket, key, fetchErr)
+
+	// Calls outside of a gRPC call, e.g. warming the cache, have no trailer to set.
+	if err := grpc.SetTrailer(ctx, metadata.Pairs(CacheStatusHeader, CacheStatusStale)); err != nil {
+		s.logger.Debugf("failed to mark the stale head of %s/%s: %v", bucket, key, err)
+	}
+
+	return head, nil
+}
@@ -0,0 +1,104 @@
+package download_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestDownloadService_GetMetadataServeStale(t *testing.T) {
+	const ttl = 10 * time.Millisecond
+
+	tests := []struct {
+		name              string
+		serveStaleOnError bool
+		maxStaleness      time.Duration
+		age               time.Duration
+		wantCode          codes.Code
+		wantCacheStatus   string
+	}{
+		{
+			name:              "serve stale - fresh entry",
+			serveStaleOnError: true,
+			maxStaleness:      time.Minute,
+		},
+		{
+			name:              "serve stale - stale entry",
+			serveStaleOnError: true,
+			maxStaleness:      time.Minute,
+			age:               2 * ttl,
+			wantCacheStatus:   download.CacheStatusStale,
+		},
+		{
+			name:         "serve stale - disabled",
+			maxStaleness: time.Minute,
+			age:          2 * ttl,
+			wantCode:     codes.Unknown,
+		},
+		{
+			name:              "serve stale - beyond max staleness",
+			serveStaleOnError: true,
+			maxStaleness:      ttl,
+			age:               3 * ttl,
+			wantCode:          codes.Unknown,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			cache := download.NewHeadCache(ttl)
+			cache.SetMaxStaleness(tt.maxStaleness)
+
+			// Cache the object's head while S3 is available.
+			head, err := s3Client.HeadObject(&s3.HeadObjectInput{
+				Bucket: aws.String(testbucket),
+				Key:    aws.String(testkey),
+			})
+			if err != nil {
+				t.Fatalf("failed to head %s, %v", testkey, err)
+			}
+			cache.Set(testbucket, testkey, head)
+			time.Sleep(tt.age)
+
+			var calls int64
+			service := download.NewService(regionOutageS3Client(testbucket, "", &calls), logger)
+			service.HeadCache = cache
+			service.ServeStaleOnError = tt.serveStaleOnError
+			client, closeClient := newServiceClient(t, service)
+			defer closeClient()
+
+			var trailer metadata.MD
+			got, err := client.GetMetadata(
+				context.Background(),
+				&pb.GetMetadataRequest{Key: testkey, Bucket: testbucket},
+				grpc.Trailer(&trailer),
+			)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("DownloadService.GetMetadata() error = %v, want code %v", err, tt.wantCode)
+			}
+
+			if tt.wantCode == codes.OK && got.GetEtag() != aws.StringValue(head.ETag) {
+				t.Errorf("DownloadService.GetMetadata() etag = %s, want %s", got.GetEtag(), aws.StringValue(head.ETag))
+			}
+
+			var cacheStatus string
+			if values := trailer.Get(download.CacheStatusHeader); len(values) > 0 {
+				cacheStatus = values[0]
+			}
+
+			if cacheStatus != tt.wantCacheStatus {
+				t.Errorf("DownloadService.GetMetadata() cache status = %q, want %q", cacheStatus, tt.wantCacheStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,316 @@
+package download
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	pb "github.com/meateam/download-service/proto"
+)
+
+// LatencySnapshot is a point in time summary of observed latencies.
+type LatencySnapshot struct {
+	Count int64
+	P50   time.Duration
+	P95   time.Duration
+	P99   time.Duration
+
+	// SlowestTraced is the slowest observation with a trace id, zero if none.
+	SlowestTraced TracedLatency
+}
+
+// TracedLatency is an observed latency and the trace id of the request it was observed in,
+// linking the summary to the trace of its outlier.
+type TracedLatency struct {
+	Latency   time.Duration
+	TraceID   string
+	Timestamp time.Time
+}
+
+// LogBufferStats is a point in time summary of the buffer of the logs shipped asynchronously.
+type LogBufferStats struct {
+	Depth        int
+	Capacity     int
+	Dropped      int64
+	FlushLatency LatencySnapshot
+}
+
+// LogBuffer is the buffer of the logs shipped asynchronously, whose stats GetStats reports.
+type LogBuffer interface {
+	// Stats returns the buffer's stats, resetting its flush latency if reset is true.
+	Stats(reset bool) LogBufferStats
+}
+
+// ProbeStats is a point in time summary of the latency of the health probe of a dependency.
+type ProbeStats struct {
+	Latency       LatencySnapshot
+	RecentLatency time.Duration
+	Degraded      bool
+}
+
+// Probe is the health probe of a dependency, whose stats GetStats reports.
+type Probe interface {
+	// Stats returns the probe's stats, resetting its latency summary if reset is true.
+	Stats(reset bool) ProbeStats
+}
+
+// LatencyStats is a concurrency-safe summary of latencies that estimates
+// their 50th, 95th and 99th percentiles in constant memory.
+type LatencyStats struct {
+	mu            sync.Mutex
+	count         int64
+	p50           *p2Quantile
+	p95           *p2Quantile
+	p99           *p2Quantile
+	slowestTraced TracedLatency
+}
+
+// NewLatencyStats creates an empty LatencyStats and returns it.
+func NewLatencyStats() *LatencyStats {
+	l := &LatencyStats{}
+	l.reset()
+
+	return l
+}
+
+// Observe adds the latency d to the summary.
+func (l *LatencyStats) Observe(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.observe(d)
+}
+
+// ObserveTraced adds the latency d, observed in the request of traceID, to the summary,
+// and keeps it as the summary's slowest traced latency if it's the slowest observation with a trace id.
+// An empty traceID only adds d.
+func (l *LatencyStats) ObserveTraced(d time.Duration, traceID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.observe(d)
+	if traceID != "" && (l.slowestTraced.TraceID == "" || d >= l.slowestTraced.Latency) {
+		l.slowestTraced = TracedLatency{Latency: d, TraceID: traceID, Timestamp: time.Now()}
+	}
+}
+
+// observe adds the latency d to the summary, l.mu must be held.
+func (l *LatencyStats) observe(d time.Duration) {
+	l.count++
+	l.p50.add(float64(d))
+	l.p95.add(float64(d))
+	l.p99.add(float64(d))
+}
+
+// Snapshot returns the current summary of the observed latencies,
+// and empties the summary if reset is true.
+func (l *LatencyStats) Snapshot(reset bool) LatencySnapshot {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	snapshot := LatencySnapshot{
+		Count: l.count,
+		P50:   time.Duration(l.p50.value()),
+		P95:   time.Duration(l.p95.value()),
+		P99:   time.Duration(l.p99.value()),
+
+		SlowestTraced: l.slowestTraced,
+	}
+
+	if reset {
+		l.reset()
+	}
+
+	return snapshot
+}
+
+// reset empties the summary, l.mu must be held.
+func (l *LatencyStats) reset() {
+	l.count = 0
+	l.p50 = newP2Quantile(0.5)
+	l.p95 = newP2Quantile(0.95)
+	l.p99 = newP2Quantile(0.99)
+	l.slowestTraced = TracedLatency{}
+}
+
+// p2Quantile estimates a single quantile of a stream of observations using the
+// P² algorithm by Jain and Chlamtac, which keeps five markers instead of the observations.
+type p2Quantile struct {
+	p       float64
+	count   int
+	heights [5]float64
+	pos     [5]float64
+	desired [5]float64
+	incr    [5]float64
+}
+
+// newP2Quantile creates a p2Quantile that estimates the p quantile, 0 < p < 1.
+func newP2Quantile(p float64) *p2Quantile {
+	return &p2Quantile{
+		p:       p,
+		pos:     [5]float64{1, 2, 3, 4, 5},
+		desired: [5]float64{1, 1 + 2*p, 1 + 4*p, 3 + 2*p, 5},
+		incr:    [5]float64{0, p / 2, p, (1 + p) / 2, 1},
+	}
+}
+
+// add adds the observation x to the estimation.
+func (q *p2Quantile) add(x float64) {
+	// The first five observations initialize the markers.
+	if q.count < len(q.heights) {
+		q.heights[q.count] = x
+		q.count++
+		if q.count == len(q.heights) {
+			sort.Float64s(q.heights[:])
+		}
+
+		return
+	}
+
+	q.count++
+
+	// Find the cell k such that heights[k] <= x < heights[k+1], extending the extremes.
+	var k int
+	switch {
+	case x < q.heights[0]:
+		q.heights[0] = x
+		k = 0
+	case x >= q.heights[4]:
+		q.heights[4] = x
+		k = 3
+	default:
+		for k = 0; k < 3; k++ {
+			if x < q.heights[k+1] {
+				break
+			}
+		}
+	}
+
+	for i := k + 1; i < len(q.pos); i++ {
+		q.pos[i]++
+	}
+
+	for i := range q.desired {
+		q.desired[i] += q.incr[i]
+	}
+
+	// Adjust the heights of the middle markers if they're off their desired positions.
+	for i := 1; i < 4; i++ {
+		q.adjust(i)
+	}
+}
+
+// adjust moves the middle marker i by a single position towards its desired position,
+// if it's off by at least one position and its neighbors leave room for it.
+func (q *p2Quantile) adjust(i int) {
+	d := q.desired[i] - q.pos[i]
+	if (d < 1 || q.pos[i+1]-q.pos[i] <= 1) && (d > -1 || q.pos[i-1]-q.pos[i] >= -1) {
+		return
+	}
+
+	sign := 1.0
+	if d < 0 {
+		sign = -1.0
+	}
+
+	height := q.parabolic(i, sign)
+	if q.heights[i-1] >= height || height >= q.heights[i+1] {
+		height = q.linear(i, sign)
+	}
+
+	q.heights[i] = height
+	q.pos[i] += sign
+}
+
+// parabolic returns the piecewise-parabolic prediction of marker i's height moved by sign.
+func (q *p2Quantile) parabolic(i int, sign float64) float64 {
+	return q.heights[i] + sign/(q.pos[i+1]-q.pos[i-1])*
+		((q.pos[i]-q.pos[i-1]+sign)*(q.heights[i+1]-q.heights[i])/(q.pos[i+1]-q.pos[i])+
+			(q.pos[i+1]-q.pos[i]-sign)*(q.heights[i]-q.heights[i-1])/(q.pos[i]-q.pos[i-1]))
+}
+
+// linear returns the linear prediction of marker i's height moved by sign.
+func (q *p2Quantile) linear(i int, sign float64) float64 {
+	neighbor := i + int(sign)
+
+	return q.heights[i] + sign*(q.heights[neighbor]-q.heights[i])/(q.pos[neighbor]-q.pos[i])
+}
+
+// value returns the estimated quantile, which is exact for up to five observations.
+func (q *p2Quantile) value() float64 {
+	if q.count == 0 {
+		return 0
+	}
+
+	if q.count < len(q.heights) {
+		observed := make([]float64, q.count)
+		copy(observed, q.heights[:q.count])
+		sort.Float64s(observed)
+
+		return observed[int(q.p*float64(q.count-1)+0.5)]
+	}
+
+	return q.heights[2]
+}
+
+// GetStats is the request to get the latency statistics of the service's downloads.
+// It responds with the estimated percentiles of the total download time and
+// of the time to fetch a single part, resetting them if req.ResetOnRead is true,
+// with the number of active downloads and whether load is being shed, with the egress quota,
+// and with the stats of the log buffer and of the health probe of S3.
+func (s Service) GetStats(ctx context.Context, req *pb.GetStatsRequest) (*pb.GetStatsResponse, error) {
+	stats := &pb.GetStatsResponse{
+		DownloadLatency: latencySummary(s.downloadLatency.Snapshot(req.GetResetOnRead())),
+		PartLatency:     latencySummary(s.partLatency.Snapshot(req.GetResetOnRead())),
+		ActiveDownloads: s.ActiveDownloads(),
+		Shedding:        s.Shedding(),
+
+		FailoversByRegion: s.failovers.snapshot(req.GetResetOnRead()),
+	}
+
+	if s.EgressQuota != nil {
+		stats.EgressQuota = egressQuota(s.EgressQuota.Snapshot())
+	}
+
+	if s.LogBuffer != nil {
+		logBuffer := s.LogBuffer.Stats(req.GetResetOnRead())
+		stats.LogBuffer = &pb.LogBufferStats{
+			Depth:        int64(logBuffer.Depth),
+			Capacity:     int64(logBuffer.Capacity),
+			Dropped:      logBuffer.Dropped,
+			FlushLatency: latencySummary(logBuffer.FlushLatency),
+		}
+	}
+
+	if s.S3Probe != nil {
+		s3Probe := s.S3Probe.Stats(req.GetResetOnRead())
+		stats.S3Probe = &pb.ProbeStats{
+			Latency:         latencySummary(s3Probe.Latency),
+			RecentLatencyMs: float64(s3Probe.RecentLatency) / float64(time.Millisecond),
+			Degraded:        s3Probe.Degraded,
+		}
+	}
+
+	return stats, nil
+}
+
+// latencySummary converts snapshot to its protobuf message.
+func latencySummary(snapshot LatencySnapshot) *pb.LatencySummary {
+	summary := &pb.LatencySummary{
+		Count: snapshot.Count,
+		P50Ms: float64(snapshot.P50) / float64(time.Millisecond),
+		P95Ms: float64(snapshot.P95) / float64(time.Millisecond),
+		P99Ms: float64(snapshot.P99) / float64(time.Millisecond),
+	}
+
+	if slowest := snapshot.SlowestTraced; slowest.TraceID != "" {
+		summary.SlowestTraced = &pb.TracedLatency{
+			LatencyMs:   float64(slowest.Latency) / float64(time.Millisecond),
+			TraceId:     slowest.TraceID,
+			TimestampMs: slowest.Timestamp.UnixNano() / int64(time.Millisecond),
+		}
+	}
+
+	return summary
+}
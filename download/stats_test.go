@@ -0,0 +1,239 @@
+package download_test
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"go.elastic.co/apm/module/apmhttp"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestLatencyStats_Snapshot(t *testing.T) {
+	tests := []struct {
+		name      string
+		latencies []time.Duration
+		want      download.LatencySnapshot
+		tolerance float64
+	}{
+		{
+			name:      "latency stats - no observations",
+			latencies: nil,
+			want:      download.LatencySnapshot{},
+		},
+		{
+			name:      "latency stats - few observations are exact",
+			latencies: []time.Duration{3 * time.Millisecond, 1 * time.Millisecond, 2 * time.Millisecond},
+			want: download.LatencySnapshot{
+				Count: 3,
+				P50:   2 * time.Millisecond,
+				P95:   3 * time.Millisecond,
+				P99:   3 * time.Millisecond,
+			},
+		},
+		{
+			name:      "latency stats - uniform observations",
+			latencies: shuffledLatencies(10000),
+			want: download.LatencySnapshot{
+				Count: 10000,
+				P50:   5000 * time.Millisecond,
+				P95:   9500 * time.Millisecond,
+				P99:   9900 * time.Millisecond,
+			},
+			tolerance: 0.02,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			stats := download.NewLatencyStats()
+			for _, latency := range tt.latencies {
+				stats.Observe(latency)
+			}
+
+			got := stats.Snapshot(false)
+			if got.Count != tt.want.Count {
+				t.Errorf("LatencyStats.Snapshot() Count = %d, want %d", got.Count, tt.want.Count)
+			}
+
+			for _, percentile := range []struct {
+				name      string
+				got, want time.Duration
+			}{
+				{"P50", got.P50, tt.want.P50},
+				{"P95", got.P95, tt.want.P95},
+				{"P99", got.P99, tt.want.P99},
+			} {
+				if math.Abs(float64(percentile.got-percentile.want)) > tt.tolerance*float64(percentile.want) {
+					t.Errorf(
+						"LatencyStats.Snapshot() %s = %v, want %v ± %.0f%%",
+						percentile.name, percentile.got, percentile.want, tt.tolerance*100,
+					)
+				}
+			}
+		})
+	}
+}
+
+func TestLatencyStats_SnapshotReset(t *testing.T) {
+	stats := download.NewLatencyStats()
+	stats.Observe(time.Second)
+
+	if got := stats.Snapshot(true); got.Count != 1 || got.P50 != time.Second {
+		t.Errorf("LatencyStats.Snapshot(true) = %+v, want a single second", got)
+	}
+
+	if got := stats.Snapshot(false); got != (download.LatencySnapshot{}) {
+		t.Errorf("LatencyStats.Snapshot() after reset = %+v, want empty", got)
+	}
+}
+
+func TestLatencyStats_ObserveTraced(t *testing.T) {
+	type observation struct {
+		latency time.Duration
+		traceID string
+	}
+
+	tests := []struct {
+		name         string
+		observations []observation
+		want         download.TracedLatency
+	}{
+		{
+			name:         "slowest traced - untraced observations",
+			observations: []observation{{latency: time.Second}, {latency: time.Millisecond}},
+		},
+		{
+			name: "slowest traced - slowest traced observation",
+			observations: []observation{
+				{latency: 2 * time.Millisecond, traceID: "a"},
+				{latency: 5 * time.Millisecond, traceID: "b"},
+				{latency: 3 * time.Millisecond, traceID: "c"},
+			},
+			want: download.TracedLatency{Latency: 5 * time.Millisecond, TraceID: "b"},
+		},
+		{
+			name: "slowest traced - slower untraced observation",
+			observations: []observation{
+				{latency: 2 * time.Millisecond, traceID: "a"},
+				{latency: time.Second},
+			},
+			want: download.TracedLatency{Latency: 2 * time.Millisecond, TraceID: "a"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			stats := download.NewLatencyStats()
+			for _, o := range tt.observations {
+				stats.ObserveTraced(o.latency, o.traceID)
+			}
+
+			got := stats.Snapshot(true)
+			if got.Count != int64(len(tt.observations)) {
+				t.Errorf("LatencyStats.Snapshot() Count = %d, want %d", got.Count, len(tt.observations))
+			}
+
+			if got.SlowestTraced.Latency != tt.want.Latency || got.SlowestTraced.TraceID != tt.want.TraceID {
+				t.Errorf("LatencyStats.Snapshot() SlowestTraced = %+v, want %+v", got.SlowestTraced, tt.want)
+			}
+
+			if age := time.Since(got.SlowestTraced.Timestamp); tt.want.TraceID != "" && age > time.Minute {
+				t.Errorf("LatencyStats.Snapshot() SlowestTraced is %s old, want a recent observation", age)
+			}
+
+			if got := stats.Snapshot(false); got.SlowestTraced != (download.TracedLatency{}) {
+				t.Errorf("LatencyStats.Snapshot() SlowestTraced after reset = %+v, want empty", got.SlowestTraced)
+			}
+		})
+	}
+}
+
+func TestDownloadService_GetStatsSlowestTraced(t *testing.T) {
+	const traceID = "0af7651916cd43dd8448eb211c80319c"
+
+	service := download.NewService(s3Client, logger)
+	client, closeClient := newServiceClient(t, service)
+	defer closeClient()
+
+	ctx := metadata.AppendToOutgoingContext(
+		context.Background(),
+		apmhttp.TraceparentHeader, "00-"+traceID+"-b7ad6b7169203331-01",
+	)
+	stream, err := client.Download(ctx, &pb.DownloadRequest{Key: testkey, Bucket: testbucket})
+	if err != nil {
+		t.Fatalf("DownloadService.Download() error = %v", err)
+	}
+
+	if _, err := recvAll(stream); err != nil {
+		t.Fatalf("DownloadService.Download() error = %v", err)
+	}
+
+	stats, err := service.GetStats(context.Background(), &pb.GetStatsRequest{})
+	if err != nil {
+		t.Fatalf("DownloadService.GetStats() error = %v", err)
+	}
+
+	for _, summary := range []*pb.LatencySummary{stats.GetDownloadLatency(), stats.GetPartLatency()} {
+		slowest := summary.GetSlowestTraced()
+		if slowest.GetTraceId() != traceID || slowest.GetLatencyMs() <= 0 || slowest.GetTimestampMs() <= 0 {
+			t.Errorf("DownloadService.GetStats() slowest traced = %v, want the download's trace %s", slowest, traceID)
+		}
+	}
+}
+
+func TestDownloadService_GetStats(t *testing.T) {
+	service := download.NewService(s3Client, logger)
+	client, closeClient := newServiceClient(t, service)
+	defer closeClient()
+
+	stream, err := client.Download(context.Background(), &pb.DownloadRequest{Key: testkey, Bucket: testbucket})
+	if err != nil {
+		t.Fatalf("DownloadService.Download() error = %v", err)
+	}
+
+	if _, err := recvAll(stream); err != nil {
+		t.Fatalf("DownloadService.Download() error = %v", err)
+	}
+
+	stats, err := service.GetStats(context.Background(), &pb.GetStatsRequest{ResetOnRead: true})
+	if err != nil {
+		t.Fatalf("DownloadService.GetStats() error = %v", err)
+	}
+
+	if stats.GetDownloadLatency().GetCount() != 1 || stats.GetDownloadLatency().GetP50Ms() <= 0 {
+		t.Errorf(
+			"DownloadService.GetStats() download latency = %v, want a single download",
+			stats.GetDownloadLatency(),
+		)
+	}
+
+	if stats.GetPartLatency().GetCount() != 1 {
+		t.Errorf("DownloadService.GetStats() part latency = %v, want a single part", stats.GetPartLatency())
+	}
+
+	stats, err = service.GetStats(context.Background(), &pb.GetStatsRequest{})
+	if err != nil {
+		t.Fatalf("DownloadService.GetStats() error = %v", err)
+	}
+
+	if stats.GetDownloadLatency().GetCount() != 0 {
+		t.Errorf("DownloadService.GetStats() after reset count = %d, want 0", stats.GetDownloadLatency().GetCount())
+	}
+}
+
+// shuffledLatencies returns the latencies 1ms to n ms in random order.
+func shuffledLatencies(n int) []time.Duration {
+	latencies := make([]time.Duration, n)
+	for i, j := range rand.New(rand.NewSource(1)).Perm(n) {
+		latencies[i] = time.Duration(j+1) * time.Millisecond
+	}
+
+	return latencies
+}
@@ -0,0 +1,64 @@
+package download
+
+import (
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// SubjectLimiter limits the number of concurrent downloads of every authenticated subject,
+// so users sharing a source IP each get their share of downloads.
+type SubjectLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	active map[string]int
+}
+
+// NewSubjectLimiter returns a SubjectLimiter allowing every subject up to limit concurrent downloads.
+func NewSubjectLimiter(limit int) *SubjectLimiter {
+	return &SubjectLimiter{limit: limit, active: make(map[string]int)}
+}
+
+// acquire reserves a download of subject, and returns a ResourceExhausted error if subject
+// has reached the limit. Unauthenticated requests, with an empty subject, aren't limited.
+// Every successful acquire must be followed by a release of the same subject.
+func (l *SubjectLimiter) acquire(subject string) error {
+	if l == nil || subject == "" {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.active[subject] >= l.limit {
+		return status.Errorf(
+			codes.ResourceExhausted,
+			"subject %s reached the limit of %d concurrent downloads",
+			subject,
+			l.limit,
+		)
+	}
+
+	l.active[subject]++
+
+	return nil
+}
+
+// release frees a download of subject reserved by acquire.
+func (l *SubjectLimiter) release(subject string) {
+	if l == nil || subject == "" {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Drop idle subjects so the map doesn't grow with every subject ever seen.
+	if l.active[subject] <= 1 {
+		delete(l.active, subject)
+		return
+	}
+
+	l.active[subject]--
+}
@@ -0,0 +1,103 @@
+package download_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// blockingDownloadStream is a pb.Download_DownloadServer of subject's download,
+// which signals started on its first send and blocks every send until release is closed.
+type blockingDownloadStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	started chan struct{}
+	release chan struct{}
+	sends   int
+}
+
+func newBlockingDownloadStream(subject string) *blockingDownloadStream {
+	return &blockingDownloadStream{
+		ctx:     download.ContextWithSubject(context.Background(), subject),
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+}
+
+func (s *blockingDownloadStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *blockingDownloadStream) SetTrailer(metadata.MD) {}
+
+func (s *blockingDownloadStream) Send(*pb.DownloadResponse) error {
+	s.sends++
+	if s.sends == 1 {
+		close(s.started)
+	}
+
+	<-s.release
+
+	return nil
+}
+
+func TestDownloadService_DownloadSubjectLimiter(t *testing.T) {
+	const limit = 2
+
+	service := download.NewService(s3Client, logger)
+	service.SubjectLimiter = download.NewSubjectLimiter(limit)
+	req := &pb.DownloadRequest{Key: testkey, Bucket: testbucket}
+
+	// downloadAs runs a download of subject and returns its error.
+	downloadAs := func(subject string) error {
+		stream := newBlockingDownloadStream(subject)
+		close(stream.release)
+
+		return service.Download(req, stream)
+	}
+
+	// Hold the limit of alice's downloads in flight.
+	held := make([]*blockingDownloadStream, limit)
+	errs := make(chan error, limit)
+	for i := range held {
+		held[i] = newBlockingDownloadStream("alice")
+		go func(stream *blockingDownloadStream) {
+			errs <- service.Download(req, stream)
+		}(held[i])
+		<-held[i].started
+	}
+
+	if err := downloadAs("alice"); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf(
+			"DownloadService.Download() of a subject at its limit error = %v, want %v",
+			err, codes.ResourceExhausted,
+		)
+	}
+
+	for _, subject := range []string{"bob", ""} {
+		if err := downloadAs(subject); err != nil {
+			t.Errorf("DownloadService.Download() of subject %q error = %v, want nil", subject, err)
+		}
+	}
+
+	// Finishing the held downloads frees alice's slots.
+	for _, stream := range held {
+		close(stream.release)
+	}
+
+	for range held {
+		if err := <-errs; err != nil {
+			t.Fatalf("DownloadService.Download() held download error = %v", err)
+		}
+	}
+
+	if err := downloadAs("alice"); err != nil {
+		t.Errorf("DownloadService.Download() of a subject below its limit error = %v, want nil", err)
+	}
+}
@@ -0,0 +1,132 @@
+package download_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// cancellingDownloadStream is a hashingDownloadStream that cancels its context once it sent a chunk,
+// like a client that cancels the download after receiving its first chunk.
+type cancellingDownloadStream struct {
+	hashingDownloadStream
+	cancel context.CancelFunc
+}
+
+func (s *cancellingDownloadStream) Send(res *pb.DownloadResponse) error {
+	defer s.cancel()
+
+	return s.hashingDownloadStream.Send(res)
+}
+
+func TestDownloadService_DownloadCancelledBetweenParts(t *testing.T) {
+	var calls int64
+	service := download.NewService(partFailingS3Client(nil, 0, &calls), logger)
+	service.PartSize = download.MinPartSize
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream := &cancellingDownloadStream{
+		hashingDownloadStream: hashingDownloadStream{ctx: ctx, hash: sha256.New()},
+		cancel:                cancel,
+	}
+	err := service.Download(&pb.DownloadRequest{Key: testkey, Bucket: testbucket}, stream)
+	if status.Code(err) != codes.Canceled {
+		t.Fatalf("DownloadService.Download() error = %v, want code %v", err, codes.Canceled)
+	}
+
+	// The parts after the cancellation aren't fetched from S3.
+	if calls != 1 {
+		t.Errorf("DownloadService.Download() called GetObject %d times, want 1", calls)
+	}
+}
+
+func TestDownloadService_DownloadEarlyEndLogs(t *testing.T) {
+	tests := []struct {
+		name       string
+		timeout    time.Duration
+		wantReason string
+	}{
+		{name: "early end - client cancelled", wantReason: download.EndReasonCanceled},
+		{
+			name:       "early end - deadline exceeded",
+			timeout:    200 * time.Millisecond,
+			wantReason: download.EndReasonDeadlineExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			serviceLogger := logrus.New()
+			serviceLogger.SetOutput(ioutil.Discard)
+			hook := test.NewLocal(serviceLogger)
+
+			// Send small chunks so the download blocks on flow control once the client stops reading.
+			service := download.NewService(s3Client, serviceLogger)
+			service.MaxBufferSize = 1 << 10
+
+			client, closeClient := newServiceClient(t, service)
+			defer closeClient()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.timeout > 0 {
+				ctx, cancel = context.WithTimeout(context.Background(), tt.timeout)
+			}
+			defer cancel()
+
+			stream, err := client.Download(ctx, &pb.DownloadRequest{Key: testkey, Bucket: testbucket})
+			if err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			if _, err := stream.Recv(); err != nil {
+				t.Fatalf("failed to receive the first chunk: %v", err)
+			}
+
+			if tt.timeout == 0 {
+				cancel()
+			}
+
+			// Wait for the download to end and log its reason.
+			deadline := time.Now().Add(5 * time.Second)
+			for time.Now().Before(deadline) {
+				for _, entry := range hook.AllEntries() {
+					reason, ok := entry.Data["download.end_reason"]
+					if !ok {
+						continue
+					}
+
+					if reason != tt.wantReason {
+						t.Fatalf("download.end_reason = %v, want %v", reason, tt.wantReason)
+					}
+
+					bytesSent, _ := entry.Data["download.bytes_sent"].(int64)
+					if bytesSent <= 0 || bytesSent >= int64(len(file)) {
+						t.Errorf("download.bytes_sent = %d, want partial download", bytesSent)
+					}
+
+					if _, ok := entry.Data["trace.id"]; !ok {
+						t.Errorf("early end log has no trace.id")
+					}
+
+					return
+				}
+
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			t.Fatalf("no early end log with download.end_reason %q", tt.wantReason)
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package download
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+
+	"go.elastic.co/apm"
+	"go.elastic.co/apm/module/apmhttp"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// W3CTraceparentHeader is the header of the W3C Trace Context traceparent.
+	W3CTraceparentHeader = "traceparent"
+
+	// B3Header is the header of the B3 single-header propagation format,
+	// "{TraceId}-{SpanId}-{SamplingState}-{ParentSpanId}".
+	B3Header = "b3"
+
+	// B3TraceIDHeader is the trace id header of the B3 multi-header propagation format.
+	B3TraceIDHeader = "x-b3-traceid"
+)
+
+// TraceExtractor extracts the trace id of a request from its metadata,
+// returning an empty string if the request carries no valid trace id.
+type TraceExtractor func(md metadata.MD) string
+
+// DefaultTraceExtractors are the trace extractors used when a service has none configured.
+var DefaultTraceExtractors = []TraceExtractor{ElasticAPMTraceExtractor}
+
+// ElasticAPMTraceExtractor extracts the trace id of the Elastic APM traceparent header.
+func ElasticAPMTraceExtractor(md metadata.MD) string {
+	return traceparentTraceID(md, apmhttp.TraceparentHeader)
+}
+
+// W3CTraceExtractor extracts the trace id of the W3C Trace Context traceparent header.
+func W3CTraceExtractor(md metadata.MD) string {
+	return traceparentTraceID(md, W3CTraceparentHeader)
+}
+
+// B3TraceExtractor extracts the trace id of the B3 single header.
+func B3TraceExtractor(md metadata.MD) string {
+	values := md.Get(B3Header)
+	if len(values) != 1 {
+		return ""
+	}
+
+	// A single sampling state, such as "0", propagates no trace id.
+	traceID := strings.SplitN(values[0], "-", 2)[0]
+
+	return b3TraceID(traceID)
+}
+
+// B3MultiTraceExtractor extracts the trace id of the B3 multi headers.
+func B3MultiTraceExtractor(md metadata.MD) string {
+	values := md.Get(B3TraceIDHeader)
+	if len(values) != 1 {
+		return ""
+	}
+
+	return b3TraceID(values[0])
+}
+
+// traceparentTraceID returns the trace id of the traceparent in the header of md,
+// or an empty string if it's missing or invalid.
+func traceparentTraceID(md metadata.MD, header string) string {
+	values := md.Get(header)
+	if len(values) != 1 {
+		return ""
+	}
+
+	traceCtx, err := apmhttp.ParseTraceparentHeader(values[0])
+	if err != nil {
+		return ""
+	}
+
+	return traceCtx.Trace.String()
+}
+
+// b3TraceID returns the B3 trace id traceID, either 64 or 128 bits, as a 128 bits trace id
+// like the ids of traceparents, or an empty string if it's invalid.
+func b3TraceID(traceID string) string {
+	if len(traceID) != 16 && len(traceID) != 32 {
+		return ""
+	}
+
+	var id apm.TraceID
+	if _, err := hex.Decode(id[len(id)-len(traceID)/2:], []byte(traceID)); err != nil {
+		return ""
+	}
+
+	if err := id.Validate(); err != nil {
+		return ""
+	}
+
+	return id.String()
+}
+
+// RequestTraceID returns the trace id of the request of ctx found by the first of extractors
+// that finds one, or an empty string if none does. No extractors uses DefaultTraceExtractors.
+func RequestTraceID(ctx context.Context, extractors []TraceExtractor) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	if len(extractors) == 0 {
+		extractors = DefaultTraceExtractors
+	}
+
+	for _, extract := range extractors {
+		if traceID := extract(md); traceID != "" {
+			return traceID
+		}
+	}
+
+	return ""
+}
+
+// ExtractTraceID returns the trace id of the request of ctx like RequestTraceID, falling back
+// to the trace id of the server's APM transaction of the request, if there's one.
+func ExtractTraceID(ctx context.Context, extractors []TraceExtractor) string {
+	if traceID := RequestTraceID(ctx, extractors); traceID != "" {
+		return traceID
+	}
+
+	if tx := apm.TransactionFromContext(ctx); tx != nil {
+		return tx.TraceContext().Trace.String()
+	}
+
+	return ""
+}
+
+// traceID returns the trace id of the request of ctx extracted by s.TraceExtractors.
+func (s Service) traceID(ctx context.Context) string {
+	return ExtractTraceID(ctx, s.TraceExtractors)
+}
@@ -0,0 +1,111 @@
+package download_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/meateam/download-service/download"
+	"go.elastic.co/apm/module/apmhttp"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestExtractTraceID(t *testing.T) {
+	const (
+		traceID     = "0af7651916cd43dd8448eb211c80319c"
+		traceparent = "00-" + traceID + "-b7ad6b7169203331-01"
+		otherID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	)
+
+	allExtractors := []download.TraceExtractor{
+		download.ElasticAPMTraceExtractor,
+		download.W3CTraceExtractor,
+		download.B3TraceExtractor,
+		download.B3MultiTraceExtractor,
+	}
+
+	tests := []struct {
+		name       string
+		md         metadata.MD
+		extractors []download.TraceExtractor
+		want       string
+	}{
+		{
+			name: "elastic apm",
+			md:   metadata.Pairs(apmhttp.TraceparentHeader, traceparent),
+			want: traceID,
+		},
+		{
+			name:       "w3c traceparent",
+			md:         metadata.Pairs(download.W3CTraceparentHeader, traceparent),
+			extractors: allExtractors,
+			want:       traceID,
+		},
+		{
+			name:       "b3 single",
+			md:         metadata.Pairs(download.B3Header, traceID+"-b7ad6b7169203331-1-05e3ac9a4f6e3b90"),
+			extractors: allExtractors,
+			want:       traceID,
+		},
+		{
+			name:       "b3 single 64 bits",
+			md:         metadata.Pairs(download.B3Header, "8448eb211c80319c-b7ad6b7169203331"),
+			extractors: allExtractors,
+			want:       "00000000000000008448eb211c80319c",
+		},
+		{
+			name:       "b3 single sampling state only",
+			md:         metadata.Pairs(download.B3Header, "0"),
+			extractors: allExtractors,
+			want:       "",
+		},
+		{
+			name:       "b3 multi",
+			md:         metadata.Pairs(download.B3TraceIDHeader, traceID, "x-b3-spanid", "b7ad6b7169203331"),
+			extractors: allExtractors,
+			want:       traceID,
+		},
+		{
+			name:       "b3 multi invalid",
+			md:         metadata.Pairs(download.B3TraceIDHeader, "not-a-trace-id"),
+			extractors: allExtractors,
+			want:       "",
+		},
+		{
+			name: "first valid in order",
+			md: metadata.Pairs(
+				apmhttp.TraceparentHeader, "invalid",
+				download.W3CTraceparentHeader, "00-"+otherID+"-b7ad6b7169203331-01",
+				download.B3TraceIDHeader, traceID,
+			),
+			extractors: allExtractors,
+			want:       otherID,
+		},
+		{
+			name:       "extractor not enabled",
+			md:         metadata.Pairs(download.W3CTraceparentHeader, traceparent),
+			extractors: []download.TraceExtractor{download.B3TraceExtractor},
+			want:       "",
+		},
+		{
+			name: "default extractors ignore w3c",
+			md:   metadata.Pairs(download.W3CTraceparentHeader, traceparent),
+			want: "",
+		},
+		{
+			name:       "no trace context",
+			md:         metadata.MD{},
+			extractors: allExtractors,
+			want:       "",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+			if got := download.ExtractTraceID(ctx, tt.extractors); got != tt.want {
+				t.Errorf("ExtractTraceID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package download
+
+import (
+	"context"
+	"net/http"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	otlog "github.com/opentracing/opentracing-go/log"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// downloadOperationName is the operation name of the server span of a download.
+	downloadOperationName = "/download.Download/Download"
+
+	// apmTraceIDTag is the span tag holding the trace id of the request,
+	// which stitches the span to the request's logs and APM transaction.
+	apmTraceIDTag = "trace.id"
+)
+
+// startServerSpan starts the server span of a download, as a child of the span
+// propagated in the request's metadata, if there's one.
+// When s.Tracer is nil it returns a nil span and ctx unchanged, without any allocations.
+func (s Service) startServerSpan(
+	ctx context.Context,
+	bucket string,
+	key string,
+) (opentracing.Span, context.Context) {
+	if s.Tracer == nil {
+		return nil, ctx
+	}
+
+	opts := []opentracing.StartSpanOption{
+		ext.SpanKindRPCServer,
+		opentracing.Tags{
+			apmTraceIDTag: s.traceID(ctx),
+			"s3.bucket":   bucket,
+			"s3.key":      key,
+		},
+	}
+
+	// gRPC metadata keys are lower-cased HTTP headers.
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		parent, err := s.Tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(http.Header(md)))
+		if err == nil {
+			opts = append(opts, opentracing.ChildOf(parent))
+		}
+	}
+
+	span := s.Tracer.StartSpan(downloadOperationName, opts...)
+
+	return span, opentracing.ContextWithSpan(ctx, span)
+}
+
+// startClientSpan starts a span of an S3 call named operationName, as a child of the span in ctx.
+// It returns a nil span when tracing is disabled or ctx holds no span.
+func (s Service) startClientSpan(
+	ctx context.Context,
+	operationName string,
+	tags opentracing.Tags,
+) opentracing.Span {
+	if s.Tracer == nil {
+		return nil
+	}
+
+	parent := opentracing.SpanFromContext(ctx)
+	if parent == nil {
+		return nil
+	}
+
+	return s.Tracer.StartSpan(
+		operationName,
+		opentracing.ChildOf(parent.Context()),
+		ext.SpanKindRPCClient,
+		tags,
+	)
+}
+
+// finishSpan marks span as failed if err isn't nil and finishes it, a nil span is ignored.
+func finishSpan(span opentracing.Span, err error) {
+	if span == nil {
+		return
+	}
+
+	if err != nil {
+		ext.Error.Set(span, true)
+		span.LogFields(otlog.Error(err))
+	}
+
+	span.Finish()
+}
@@ -0,0 +1,107 @@
+package download_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestDownloadService_DownloadTracing(t *testing.T) {
+	tracer := mocktracer.New()
+	service := download.NewService(s3Client, logger)
+	service.Tracer = tracer
+
+	client, closeClient := newServiceClient(t, service)
+	defer closeClient()
+
+	// Propagate a client span in the request's metadata.
+	clientSpan := tracer.StartSpan("client")
+	headers := http.Header{}
+	if err := tracer.Inject(
+		clientSpan.Context(),
+		opentracing.HTTPHeaders,
+		opentracing.HTTPHeadersCarrier(headers),
+	); err != nil {
+		t.Fatalf("failed to inject span context: %v", err)
+	}
+	clientSpan.Finish()
+
+	md := metadata.MD{}
+	for header, values := range headers {
+		md.Append(header, values...)
+	}
+
+	ctx := metadata.NewOutgoingContext(context.Background(), md)
+	stream, err := client.Download(ctx, &pb.DownloadRequest{Key: testkey, Bucket: testbucket})
+	if err != nil {
+		t.Fatalf("DownloadService.Download() error = %v", err)
+	}
+
+	if _, err := recvAll(stream); err != nil {
+		t.Fatalf("DownloadService.Download() error = %v", err)
+	}
+
+	spans := tracer.FinishedSpans()
+	if len(spans) != 4 {
+		t.Fatalf("finished spans = %d, want client, server, HeadObject and GetObject spans", len(spans))
+	}
+
+	clientContext := spans[0].Context().(mocktracer.MockSpanContext)
+	serverSpan := spans[len(spans)-1]
+	if serverSpan.OperationName != "/download.Download/Download" {
+		t.Fatalf("last finished span = %q, want the download server span", serverSpan.OperationName)
+	}
+
+	if serverSpan.ParentID != clientContext.SpanID || serverSpan.SpanContext.TraceID != clientContext.TraceID {
+		t.Errorf("server span isn't a child of the propagated client span")
+	}
+
+	if serverSpan.Tag(string(ext.SpanKind)) != ext.SpanKindRPCServerEnum {
+		t.Errorf("server span kind = %v, want %v", serverSpan.Tag(string(ext.SpanKind)), ext.SpanKindRPCServerEnum)
+	}
+
+	if serverSpan.Tag("trace.id") == nil {
+		t.Errorf("server span has no trace.id tag to stitch it with the APM trace")
+	}
+
+	for _, s3Span := range spans[1 : len(spans)-1] {
+		if s3Span.ParentID != serverSpan.SpanContext.SpanID {
+			t.Errorf("span %q isn't a child of the server span", s3Span.OperationName)
+		}
+	}
+
+	if spans[1].OperationName != "s3.HeadObject" || spans[2].OperationName != "s3.GetObject" {
+		t.Errorf("S3 spans = %q, %q, want s3.HeadObject, s3.GetObject", spans[1].OperationName, spans[2].OperationName)
+	}
+}
+
+func TestDownloadService_DownloadTracingError(t *testing.T) {
+	tracer := mocktracer.New()
+	service := download.NewService(s3Client, logger)
+	service.Tracer = tracer
+
+	client, closeClient := newServiceClient(t, service)
+	defer closeClient()
+
+	stream, err := client.Download(context.Background(), &pb.DownloadRequest{Key: "testkey", Bucket: testbucket})
+	if err != nil {
+		t.Fatalf("DownloadService.Download() error = %v", err)
+	}
+
+	if _, err := recvAll(stream); err == nil {
+		t.Fatalf("DownloadService.Download() error = nil, wantErr true")
+	}
+
+	for _, span := range tracer.FinishedSpans() {
+		if span.Tag(string(ext.Error)) != true {
+			t.Errorf("span %q of a failed download isn't marked as an error", span.OperationName)
+		}
+	}
+}
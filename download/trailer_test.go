@@ -0,0 +1,81 @@
+package download_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDownloadService_DownloadTrailer(t *testing.T) {
+	tests := []struct {
+		name          string
+		req           *pb.DownloadRequest
+		chaos         *download.Chaos
+		wantCode      codes.Code
+		wantBytesSent int64
+		wantPartsSent int64
+	}{
+		{
+			name:          "trailer - whole object",
+			req:           &pb.DownloadRequest{Key: testkey, Bucket: testbucket},
+			wantCode:      codes.OK,
+			wantBytesSent: int64(len(file)),
+			wantPartsSent: 1,
+		},
+		{
+			name:          "trailer - range",
+			req:           &pb.DownloadRequest{Key: testkey, Bucket: testbucket, RangeStart: 10, RangeEnd: 19},
+			wantCode:      codes.OK,
+			wantBytesSent: 10,
+			wantPartsSent: 1,
+		},
+		{
+			name:          "trailer - failed download",
+			req:           &pb.DownloadRequest{Key: testkey, Bucket: testbucket},
+			chaos:         &download.Chaos{ErrorProbability: 1},
+			wantCode:      codes.Unavailable,
+			wantBytesSent: 0,
+			wantPartsSent: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			service := download.NewService(s3Client, logger)
+			service.Chaos = tt.chaos
+
+			client, closeClient := newServiceClient(t, service)
+			defer closeClient()
+
+			stream, err := client.Download(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			if _, err := recvAll(stream); status.Code(err) != tt.wantCode {
+				t.Fatalf("DownloadService.Download() error = %v, wantCode %v", err, tt.wantCode)
+			}
+
+			trailer := stream.Trailer()
+			for name, want := range map[string]int64{
+				download.BytesSentTrailer: tt.wantBytesSent,
+				download.PartsSentTrailer: tt.wantPartsSent,
+			} {
+				values := trailer.Get(name)
+				if len(values) != 1 {
+					t.Fatalf("trailer %s = %v, want a single value", name, values)
+				}
+
+				if got, err := strconv.ParseInt(values[0], 10, 64); err != nil || got != want {
+					t.Errorf("trailer %s = %q, want %d", name, values[0], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// truncatingS3Client returns an S3 client of a store that returns up to limit bytes
+// of the bodies of the GetObject calls of key.
+func truncatingS3Client(key string, limit int64) *s3.S3 {
+	client := s3.New(session.Must(session.NewSession(&s3Client.Config)))
+	client.Handlers.Send.PushBack(func(r *request.Request) {
+		input, ok := r.Params.(*s3.GetObjectInput)
+		if !ok || r.HTTPResponse == nil || aws.StringValue(input.Key) != key {
+			return
+		}
+
+		body := r.HTTPResponse.Body
+		r.HTTPResponse.Body = struct {
+			io.Reader
+			io.Closer
+		}{Reader: io.LimitReader(body, limit), Closer: body}
+	})
+
+	return client
+}
+
+func TestDownloadService_DownloadTruncated(t *testing.T) {
+	tests := []struct {
+		name       string
+		limit      int64
+		rangeStart int64
+		rangeEnd   int64
+		wantCode   codes.Code
+		wantBytes  int64
+	}{
+		{name: "truncated - whole body", limit: int64(len(file)), wantBytes: int64(len(file))},
+		{name: "truncated - short body", limit: 1000, wantCode: codes.DataLoss, wantBytes: 1000},
+		{name: "truncated - empty body", limit: 0, wantCode: codes.DataLoss},
+		{name: "truncated - whole range", limit: 1000, rangeStart: 5000, rangeEnd: 5999, wantBytes: 1000},
+		{
+			name:       "truncated - short range",
+			limit:      999,
+			rangeStart: 5000,
+			rangeEnd:   5999,
+			wantCode:   codes.DataLoss,
+			wantBytes:  999,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			service := download.NewService(truncatingS3Client(testkey, tt.limit), logger)
+			service.MaxBufferSize = int64(len(file))
+			client, closeClient := newServiceClient(t, service)
+			defer closeClient()
+
+			stream, err := client.Download(context.Background(), &pb.DownloadRequest{
+				Key:        testkey,
+				Bucket:     testbucket,
+				RangeStart: tt.rangeStart,
+				RangeEnd:   tt.rangeEnd,
+			})
+			if err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			got, err := recvAll(stream)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("DownloadService.Download() error = %v, want code %v", err, tt.wantCode)
+			}
+
+			want := file[tt.rangeStart : tt.rangeStart+tt.wantBytes]
+			if !bytes.Equal(got, want) {
+				t.Errorf("DownloadService.Download() received %d bytes, want the first %d bytes", len(got), len(want))
+			}
+		})
+	}
+}
+
+func TestDownloadService_DownloadTruncatedConcurrentParts(t *testing.T) {
+	tests := []struct {
+		name            string
+		limit           int64
+		coalesceMaxSize int64
+		wantCode        codes.Code
+	}{
+		{name: "truncated concurrent - whole parts", limit: download.MinPartSize},
+		{name: "truncated concurrent - short parts", limit: 1000, wantCode: codes.DataLoss},
+		{name: "truncated concurrent - empty parts", limit: 0, wantCode: codes.DataLoss},
+		{
+			name:            "truncated concurrent - short coalesced read",
+			limit:           download.MinPartSize,
+			coalesceMaxSize: int64(len(file)),
+			wantCode:        codes.DataLoss,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			service := download.NewService(truncatingS3Client(testkey, tt.limit), logger)
+			service.PartSize = download.MinPartSize
+			service.PartConcurrency = 4
+			service.CoalesceMaxSize = tt.coalesceMaxSize
+			client, closeClient := newServiceClient(t, service)
+			defer closeClient()
+
+			stream, err := client.Download(context.Background(), &pb.DownloadRequest{Key: testkey, Bucket: testbucket})
+			if err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			got, err := recvAll(stream)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("DownloadService.Download() error = %v, want code %v", err, tt.wantCode)
+			}
+
+			if tt.wantCode == codes.OK && !bytes.Equal(got, file) {
+				t.Errorf("DownloadService.Download() file downloaded is different from the wanted file")
+			}
+		})
+	}
+}
+
+func TestDownloadService_DownloadConcatenatedTruncated(t *testing.T) {
+	service := download.NewService(truncatingS3Client(testkey, 1000), logger)
+	client, closeClient := newServiceClient(t, service)
+	defer closeClient()
+
+	stream, err := client.DownloadConcatenated(context.Background(), &pb.DownloadConcatenatedRequest{
+		Keys:   []string{testkey},
+		Bucket: testbucket,
+	})
+	if err != nil {
+		t.Fatalf("DownloadService.DownloadConcatenated() error = %v", err)
+	}
+
+	if _, err := recvAll(stream); status.Code(err) != codes.DataLoss {
+		t.Errorf("DownloadService.DownloadConcatenated() error = %v, want code %v", err, codes.DataLoss)
+	}
+}
@@ -0,0 +1,122 @@
+package download
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	// awsHostSuffix is the host suffix of AWS S3 endpoints.
+	awsHostSuffix = ".amazonaws.com"
+)
+
+// ParseObjectURL parses an object URL and returns the bucket and key it points to.
+// Supported URLs are "s3://bucket/key", path-style "https://endpoint/bucket/key"
+// and AWS virtual-hosted-style "https://bucket.s3.region.amazonaws.com/key".
+// Hosts other than AWS S3 endpoints, such as MinIO, are parsed as path-style.
+func ParseObjectURL(objectURL string) (bucket string, key string, err error) {
+	u, err := url.Parse(objectURL)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse object url: %v", err)
+	}
+
+	if u.Host == "" {
+		return "", "", fmt.Errorf("object url %q has no host", objectURL)
+	}
+
+	path := strings.TrimPrefix(u.Path, "/")
+
+	switch u.Scheme {
+	case "s3":
+		bucket, key = u.Host, path
+	case "http", "https":
+		if virtualHostBucket := bucketFromVirtualHost(u.Hostname()); virtualHostBucket != "" {
+			bucket, key = virtualHostBucket, path
+			break
+		}
+
+		pathParts := strings.SplitN(path, "/", 2)
+		bucket = pathParts[0]
+		if len(pathParts) == 2 {
+			key = pathParts[1]
+		}
+	default:
+		return "", "", fmt.Errorf("unsupported object url scheme %q", u.Scheme)
+	}
+
+	if bucket == "" || key == "" {
+		return "", "", fmt.Errorf("object url %q must contain both bucket and key", objectURL)
+	}
+
+	return bucket, key, nil
+}
+
+// parseObjectURL parses an object URL like ParseObjectURL, and rejects path-style URLs of hosts
+// other than the S3 endpoint of s and AWS S3 endpoints, whose buckets would otherwise be resolved
+// to the buckets of the endpoint of s.
+func (s Service) parseObjectURL(objectURL string) (string, string, error) {
+	bucket, key, err := ParseObjectURL(objectURL)
+	if err != nil {
+		return "", "", err
+	}
+
+	// The url was already parsed by ParseObjectURL.
+	u, _ := url.Parse(objectURL)
+	if u.Scheme == "s3" || bucketFromVirtualHost(u.Hostname()) != "" {
+		return bucket, key, nil
+	}
+
+	if !isEndpointHost(u.Host, s.s3Endpoint()) && !isAWSPathStyleHost(u.Hostname()) {
+		return "", "", fmt.Errorf("object url host %q is not an S3 endpoint", u.Host)
+	}
+
+	return bucket, key, nil
+}
+
+// bucketFromVirtualHost returns the bucket of an AWS virtual-hosted-style host,
+// such as "bucket.s3.amazonaws.com" or "bucket.s3.us-east-1.amazonaws.com",
+// or an empty string if host isn't virtual-hosted-style.
+func bucketFromVirtualHost(host string) string {
+	if !strings.HasSuffix(host, awsHostSuffix) {
+		return ""
+	}
+
+	// Find the "s3" label of the service endpoint, everything before it is the bucket.
+	// Search from the right since bucket names may contain dots.
+	labels := strings.Split(strings.TrimSuffix(host, awsHostSuffix), ".")
+	for i := len(labels) - 1; i > 0; i-- {
+		if labels[i] == "s3" || strings.HasPrefix(labels[i], "s3-") {
+			return strings.Join(labels[:i], ".")
+		}
+	}
+
+	return ""
+}
+
+// isEndpointHost returns whether host, with its port if any, is the host of endpoint.
+// Endpoints may omit their scheme.
+func isEndpointHost(host string, endpoint string) bool {
+	if endpoint == "" {
+		return false
+	}
+
+	endpointHost := endpoint
+	if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+		endpointHost = u.Host
+	}
+
+	return strings.EqualFold(host, endpointHost)
+}
+
+// isAWSPathStyleHost returns whether host is an AWS S3 path-style endpoint,
+// such as "s3.amazonaws.com", "s3.us-east-1.amazonaws.com" or "s3-us-east-1.amazonaws.com".
+func isAWSPathStyleHost(host string) bool {
+	if !strings.HasSuffix(host, awsHostSuffix) {
+		return false
+	}
+
+	firstLabel := strings.SplitN(host, ".", 2)[0]
+
+	return firstLabel == "s3" || strings.HasPrefix(firstLabel, "s3-")
+}
@@ -0,0 +1,180 @@
+package download_test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseObjectURL(t *testing.T) {
+	tests := []struct {
+		name       string
+		objectURL  string
+		wantBucket string
+		wantKey    string
+		wantErr    bool
+	}{
+		{
+			name:       "s3 url",
+			objectURL:  "s3://testbucket/dir/test.txt",
+			wantBucket: "testbucket",
+			wantKey:    "dir/test.txt",
+		},
+		{
+			name:       "path-style url",
+			objectURL:  "http://localhost:9000/testbucket/dir/test.txt",
+			wantBucket: "testbucket",
+			wantKey:    "dir/test.txt",
+		},
+		{
+			name:       "path-style aws url",
+			objectURL:  "https://s3.eu-west-1.amazonaws.com/testbucket/test.txt",
+			wantBucket: "testbucket",
+			wantKey:    "test.txt",
+		},
+		{
+			name:       "virtual-host url",
+			objectURL:  "https://testbucket.s3.amazonaws.com/dir/test.txt",
+			wantBucket: "testbucket",
+			wantKey:    "dir/test.txt",
+		},
+		{
+			name:       "virtual-host url with region and dotted bucket",
+			objectURL:  "https://test.bucket.s3.eu-west-1.amazonaws.com/test.txt",
+			wantBucket: "test.bucket",
+			wantKey:    "test.txt",
+		},
+		{
+			name:       "legacy virtual-host url",
+			objectURL:  "https://testbucket.s3-eu-west-1.amazonaws.com/test.txt",
+			wantBucket: "testbucket",
+			wantKey:    "test.txt",
+		},
+		{
+			name:       "escaped key",
+			objectURL:  "s3://testbucket/my%20file.txt",
+			wantBucket: "testbucket",
+			wantKey:    "my file.txt",
+		},
+		{
+			name:      "unsupported scheme",
+			objectURL: "ftp://localhost/testbucket/test.txt",
+			wantErr:   true,
+		},
+		{
+			name:      "missing host",
+			objectURL: "s3:///test.txt",
+			wantErr:   true,
+		},
+		{
+			name:      "missing key",
+			objectURL: "http://localhost:9000/testbucket",
+			wantErr:   true,
+		},
+		{
+			name:      "missing key in virtual-host url",
+			objectURL: "https://testbucket.s3.amazonaws.com/",
+			wantErr:   true,
+		},
+		{
+			name:      "empty s3 url",
+			objectURL: "s3://",
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			gotBucket, gotKey, err := download.ParseObjectURL(tt.objectURL)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseObjectURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if gotBucket != tt.wantBucket || gotKey != tt.wantKey {
+				t.Errorf(
+					"ParseObjectURL() = (%q, %q), want (%q, %q)",
+					gotBucket, gotKey, tt.wantBucket, tt.wantKey,
+				)
+			}
+		})
+	}
+}
+
+func TestDownloadService_GetMetadataObjectURLHost(t *testing.T) {
+	endpoint := aws.StringValue(s3Client.Config.Endpoint)
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		t.Fatalf("failed to parse endpoint %q, %v", endpoint, err)
+	}
+
+	// The client has no endpoint like clients of AWS S3.
+	awsClient := s3.New(session.Must(session.NewSession(&s3Client.Config)), &aws.Config{Endpoint: aws.String("")})
+
+	tests := []struct {
+		name      string
+		s3Client  *s3.S3
+		objectURL string
+		wantCode  codes.Code
+	}{
+		{
+			name:      "object url host - endpoint",
+			s3Client:  s3Client,
+			objectURL: endpoint + "/" + testbucket + "/" + testkey,
+		},
+		{
+			name:      "object url host - s3 url",
+			s3Client:  s3Client,
+			objectURL: "s3://" + testbucket + "/" + testkey,
+		},
+		{
+			name:      "object url host - another host",
+			s3Client:  s3Client,
+			objectURL: "https://evil.example/" + testbucket + "/" + testkey,
+			wantCode:  codes.InvalidArgument,
+		},
+		{
+			name:      "object url host - another port",
+			s3Client:  s3Client,
+			objectURL: endpointURL.Scheme + "://" + endpointURL.Hostname() + ":1/" + testbucket + "/" + testkey,
+			wantCode:  codes.InvalidArgument,
+		},
+		{
+			name:      "object url host - non-S3 aws host",
+			s3Client:  s3Client,
+			objectURL: "https://ec2.amazonaws.com/" + testbucket + "/" + testkey,
+			wantCode:  codes.InvalidArgument,
+		},
+		{
+			name:      "object url host - host ending like aws",
+			s3Client:  s3Client,
+			objectURL: "https://s3.amazonaws.com.evil.example/" + testbucket + "/" + testkey,
+			wantCode:  codes.InvalidArgument,
+		},
+		{
+			name:      "object url host - path-style url without an endpoint",
+			s3Client:  awsClient,
+			objectURL: endpoint + "/" + testbucket + "/" + testkey,
+			wantCode:  codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			service := download.NewService(tt.s3Client, logger)
+			_, err := service.GetMetadata(context.Background(), &pb.GetMetadataRequest{Url: tt.objectURL})
+			if status.Code(err) != tt.wantCode {
+				t.Errorf("DownloadService.GetMetadata(%q) error = %v, want code %v", tt.objectURL, err, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package download
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// versionIDKey is the context key of the version of the object a request downloads.
+type versionIDKey struct{}
+
+// contextWithVersionID returns a copy of ctx whose S3 calls get the version versionID of their objects,
+// ctx itself if versionID is empty, which gets their latest versions.
+func contextWithVersionID(ctx context.Context, versionID string) context.Context {
+	if versionID == "" {
+		return ctx
+	}
+
+	return context.WithValue(ctx, versionIDKey{}, versionID)
+}
+
+// versionIDFromContext returns the version of the objects that the S3 calls of ctx get,
+// nil for their latest versions.
+func versionIDFromContext(ctx context.Context) *string {
+	versionID, ok := ctx.Value(versionIDKey{}).(string)
+	if !ok {
+		return nil
+	}
+
+	return aws.String(versionID)
+}
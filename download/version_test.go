@@ -0,0 +1,156 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// versionedObjectS3Client returns an S3 client serving HeadObject and ranged GetObject calls of the versions
+// of a single object by their version ids, the latest version for calls without a version id.
+func versionedObjectS3Client(versions map[string][]byte, latest string) *s3.S3 {
+	client := s3.New(session.Must(session.NewSession(&s3Client.Config)))
+	client.Handlers.Send.Clear()
+	client.Handlers.Send.PushBack(func(r *request.Request) {
+		var versionID, byteRange *string
+		switch input := r.Params.(type) {
+		case *s3.HeadObjectInput:
+			versionID, byteRange = input.VersionId, input.Range
+		case *s3.GetObjectInput:
+			versionID, byteRange = input.VersionId, input.Range
+		}
+
+		if aws.StringValue(versionID) == "" {
+			versionID = aws.String(latest)
+		}
+
+		version, ok := versions[*versionID]
+		if !ok {
+			r.HTTPResponse = &http.Response{StatusCode: http.StatusNotFound, Header: http.Header{}, Body: http.NoBody}
+			r.Error = awserr.NewRequestFailure(
+				awserr.New("NotFound", "the specified version does not exist", nil),
+				http.StatusNotFound,
+				"",
+			)
+			return
+		}
+
+		start, end := 0, len(version)-1
+		if byteRange != nil {
+			fmt.Sscanf(*byteRange, "bytes=%d-%d", &start, &end)
+		}
+
+		header := http.Header{}
+		header.Set("ETag", strconv.Quote(*versionID))
+		header.Set("X-Amz-Version-Id", *versionID)
+		header.Set("Content-Length", strconv.Itoa(end-start+1))
+		header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(version)))
+		r.HTTPResponse = &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     header,
+			Body:       ioutil.NopCloser(bytes.NewReader(version[start : end+1])),
+		}
+	})
+
+	return client
+}
+
+func TestDownloadService_DownloadVersion(t *testing.T) {
+	versions := map[string][]byte{
+		"first":  []byte("the first version"),
+		"second": []byte("the second, latest version"),
+	}
+
+	tests := []struct {
+		name          string
+		versionID     string
+		wantCode      codes.Code
+		wantVersionID string
+	}{
+		{name: "version - latest", wantVersionID: "second"},
+		{name: "version - first", versionID: "first", wantVersionID: "first"},
+		{name: "version - second", versionID: "second", wantVersionID: "second"},
+		{name: "version - missing", versionID: "missing", wantCode: codes.NotFound},
+	}
+
+	service := download.NewService(versionedObjectS3Client(versions, "second"), logger)
+	client, closeClient := newServiceClient(t, service)
+	defer closeClient()
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := client.Download(context.Background(), &pb.DownloadRequest{
+				Key:             testkey,
+				Bucket:          testbucket,
+				VersionId:       tt.versionID,
+				IncludeMetadata: true,
+			})
+			if err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			first, err := stream.Recv()
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("DownloadService.Download() error = %v, want code %v", err, tt.wantCode)
+			}
+
+			if tt.wantCode != codes.OK {
+				return
+			}
+
+			if versionID := first.GetMetadata().GetVersionId(); versionID != tt.wantVersionID {
+				t.Errorf("DownloadService.Download() metadata version id = %q, want %q", versionID, tt.wantVersionID)
+			}
+
+			got, err := recvAll(stream)
+			if err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			if want := versions[tt.wantVersionID]; !bytes.Equal(got, want) {
+				t.Errorf("DownloadService.Download() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestDownloadService_GetMetadataVersion(t *testing.T) {
+	versions := map[string][]byte{
+		"first":  []byte("the first version"),
+		"second": []byte("the second, latest version"),
+	}
+
+	service := download.NewService(versionedObjectS3Client(versions, "second"), logger)
+	for versionID, version := range versions {
+		metadata, err := service.GetMetadata(context.Background(), &pb.GetMetadataRequest{
+			Key:       testkey,
+			Bucket:    testbucket,
+			VersionId: versionID,
+		})
+		if err != nil {
+			t.Fatalf("DownloadService.GetMetadata() error = %v", err)
+		}
+
+		if metadata.GetVersionId() != versionID || metadata.GetSize() != int64(len(version)) {
+			t.Errorf(
+				"DownloadService.GetMetadata() = %v, want version id %q of size %d",
+				metadata, versionID, len(version),
+			)
+		}
+	}
+}
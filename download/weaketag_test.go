@@ -0,0 +1,122 @@
+package download_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// weakETagS3Client returns an S3 client whose HeadObject results have weak ETags,
+// like some S3-compatible stores return.
+func weakETagS3Client() *s3.S3 {
+	client := s3.New(session.Must(session.NewSession(&s3Client.Config)))
+	client.Handlers.Unmarshal.PushBack(func(r *request.Request) {
+		if output, ok := r.Data.(*s3.HeadObjectOutput); ok && output.ETag != nil {
+			output.ETag = aws.String("W/" + aws.StringValue(output.ETag))
+		}
+	})
+
+	return client
+}
+
+func TestDownloadService_WeakETag(t *testing.T) {
+	head, err := s3Client.HeadObject(&s3.HeadObjectInput{Bucket: aws.String(testbucket), Key: aws.String(testkey)})
+	if err != nil {
+		t.Fatalf("failed to head %s/%s: %v", testbucket, testkey, err)
+	}
+
+	etag := aws.StringValue(head.ETag)
+	const rangeStart = 1 << 20
+
+	tests := []struct {
+		name              string
+		s3Client          *s3.S3
+		wantWeak          bool
+		wantIfMatchCode   codes.Code
+		wantRangeRestarts bool
+	}{
+		{name: "etag - strong", s3Client: s3Client},
+		{
+			name:              "etag - weak",
+			s3Client:          weakETagS3Client(),
+			wantWeak:          true,
+			wantIfMatchCode:   codes.FailedPrecondition,
+			wantRangeRestarts: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			client, closeClient := newServiceClient(t, download.NewService(tt.s3Client, logger))
+			defer closeClient()
+
+			manifest, err := client.GetDownloadManifest(
+				context.Background(),
+				&pb.GetDownloadManifestRequest{Key: testkey, Bucket: testbucket},
+			)
+			if err != nil {
+				t.Fatalf("DownloadService.GetDownloadManifest() error = %v", err)
+			}
+
+			if manifest.GetEtagWeak() != tt.wantWeak {
+				t.Errorf(
+					"DownloadService.GetDownloadManifest() etag weak = %v, want %v",
+					manifest.GetEtagWeak(), tt.wantWeak,
+				)
+			}
+
+			// If-Match uses the strong comparison.
+			_, err = downloadWithHeader(client, &pb.DownloadRequest{IfMatch: etag})
+			if status.Code(err) != tt.wantIfMatchCode {
+				t.Errorf("DownloadService.Download() if match error = %v, wantCode %v", err, tt.wantIfMatchCode)
+			}
+
+			// If-None-Match uses the weak comparison.
+			header, err := downloadWithHeader(client, &pb.DownloadRequest{IfNoneMatch: etag})
+			if err != nil || len(header.Get(download.NotModifiedHeader)) == 0 {
+				t.Errorf("DownloadService.Download() if none match error = %v, want not modified", err)
+			}
+
+			// If-Range uses the strong comparison, so ranges of weak ETags restart.
+			header, err = downloadWithHeader(client, &pb.DownloadRequest{RangeStart: rangeStart, IfRange: etag})
+			if err != nil {
+				t.Fatalf("DownloadService.Download() if range error = %v", err)
+			}
+
+			if restarted := len(header.Get(download.RestartedHeader)) > 0; restarted != tt.wantRangeRestarts {
+				t.Errorf("DownloadService.Download() restarted = %v, want %v", restarted, tt.wantRangeRestarts)
+			}
+
+			// Ranges of weak ETags can't be validated, so they're downloaded unvalidated.
+			downloader := download.NewDownloader(client)
+			downloader.PartSize = download.MinManifestPartSize
+			checkDownloadToWriterAt(t, downloader, &pb.DownloadRequest{Key: testkey, Bucket: testbucket}, file)
+		})
+	}
+}
+
+// downloadWithHeader downloads testkey with the validators of req from client,
+// and returns the download's response header.
+func downloadWithHeader(client pb.DownloadClient, req *pb.DownloadRequest) (metadata.MD, error) {
+	req.Key, req.Bucket = testkey, testbucket
+	stream, err := client.Download(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := recvAll(stream); err != nil {
+		return nil, err
+	}
+
+	return stream.Header()
+}
@@ -3,15 +3,22 @@ module github.com/meateam/download-service
 go 1.13
 
 require (
-	github.com/aws/aws-sdk-go v1.23.21
-	github.com/golang/protobuf v1.3.2
+	github.com/aws/aws-sdk-go v1.27.0
+	github.com/golang/protobuf v1.4.3
 	github.com/grpc-ecosystem/go-grpc-middleware v1.1.0
+	github.com/klauspost/compress v1.10.5
 	github.com/meateam/elasticsearch-logger v1.1.3-0.20190901111807-4e8b84fb9fda
-	github.com/sirupsen/logrus v1.4.2
+	github.com/opentracing/opentracing-go v1.2.0
+	github.com/prometheus/client_golang v1.9.0
+	github.com/sirupsen/logrus v1.6.0
 	github.com/spf13/viper v1.4.0
+	github.com/uber/jaeger-client-go v2.25.0+incompatible
+	github.com/uber/jaeger-lib v2.4.0+incompatible // indirect
+	go.elastic.co/apm v1.5.0
 	go.elastic.co/apm/module/apmhttp v1.5.0
-	golang.org/x/net v0.0.0-20190912160710-24e19bdeb0f2
-	google.golang.org/grpc v1.23.1
+	golang.org/x/net v0.0.0-20200625001655-4c5254603344
+	google.golang.org/genproto v0.0.0-20190819201941-24fa4b261c55
+	google.golang.org/grpc v1.28.1
 )
 
 replace github.com/meateam/download-service/proto => ./proto
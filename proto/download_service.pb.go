@@ -23,12 +23,181 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
+// ChecksumAlgorithm is the algorithm of the checksum of a download's bytes.
+type ChecksumAlgorithm int32
+
+const (
+	// No checksum
+	ChecksumAlgorithm_NONE ChecksumAlgorithm = 0
+	// MD5 checksum
+	ChecksumAlgorithm_MD5 ChecksumAlgorithm = 1
+	// SHA-1 checksum
+	ChecksumAlgorithm_SHA1 ChecksumAlgorithm = 2
+	// SHA-256 checksum
+	ChecksumAlgorithm_SHA256 ChecksumAlgorithm = 3
+)
+
+var ChecksumAlgorithm_name = map[int32]string{
+	0: "NONE",
+	1: "MD5",
+	2: "SHA1",
+	3: "SHA256",
+}
+var ChecksumAlgorithm_value = map[string]int32{
+	"NONE":   0,
+	"MD5":    1,
+	"SHA1":   2,
+	"SHA256": 3,
+}
+
+func (x ChecksumAlgorithm) String() string {
+	return proto.EnumName(ChecksumAlgorithm_name, int32(x))
+}
+func (ChecksumAlgorithm) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_download_service_1710258a4f1cb257, []int{0}
+}
+
+// QoSClass is the bandwidth class of a download.
+type QoSClass int32
+
+const (
+	// Default class, for interactive downloads
+	QoSClass_STANDARD QoSClass = 0
+	// Class with a larger share of the bandwidth, for latency sensitive downloads
+	QoSClass_PREMIUM QoSClass = 1
+	// Class with a smaller share of the bandwidth, for background downloads
+	QoSClass_BULK QoSClass = 2
+)
+
+var QoSClass_name = map[int32]string{
+	0: "STANDARD",
+	1: "PREMIUM",
+	2: "BULK",
+}
+var QoSClass_value = map[string]int32{
+	"STANDARD": 0,
+	"PREMIUM":  1,
+	"BULK":     2,
+}
+
+func (x QoSClass) String() string {
+	return proto.EnumName(QoSClass_name, int32(x))
+}
+func (QoSClass) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_download_service_1710258a4f1cb257, []int{1}
+}
+
 // DownloadRequest is the request type of the download.
 type DownloadRequest struct {
 	// File key to download from S3
 	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
 	// The bucket to download file from
-	Bucket               string   `protobuf:"bytes,2,opt,name=bucket,proto3" json:"bucket,omitempty"`
+	Bucket string `protobuf:"bytes,2,opt,name=bucket,proto3" json:"bucket,omitempty"`
+	// First byte of the range to download, inclusive.
+	// A negative value requests the last -range_start bytes of the file,
+	// like the HTTP suffix range "bytes=-N".
+	RangeStart int64 `protobuf:"varint,3,opt,name=range_start,json=rangeStart,proto3" json:"range_start,omitempty"`
+	// Last byte of the range to download, inclusive.
+	// Zero leaves the range open-ended, up to the end of the file,
+	// a value beyond the file's length is clamped to its last byte.
+	RangeEnd int64 `protobuf:"varint,4,opt,name=range_end,json=rangeEnd,proto3" json:"range_end,omitempty"`
+	// URL of the file to download, either "s3://bucket/key",
+	// path-style or virtual-hosted-style URL.
+	// The key and bucket fields take precedence over the URL's.
+	Url string `protobuf:"bytes,5,opt,name=url,proto3" json:"url,omitempty"`
+	// Validator of the file the client resumes, either its ETag or its
+	// Last-Modified HTTP-date, like the HTTP If-Range header.
+	// When the file changed since, the whole file is downloaded instead of
+	// the requested range, and the "x-download-restarted" header is set.
+	IfRange string `protobuf:"bytes,6,opt,name=if_range,json=ifRange,proto3" json:"if_range,omitempty"`
+	// Fail with FAILED_PRECONDITION instead of downloading the whole file
+	// when the file changed since if_range.
+	IfRangeFail bool `protobuf:"varint,7,opt,name=if_range_fail,json=ifRangeFail,proto3" json:"if_range_fail,omitempty"`
+	// Send the parts of the range from the last to the first, for reading
+	// the tail of the file first. The range is split into parts from its
+	// first byte, each the size in the "x-part-size" header except for the
+	// last one, and each part's bytes are still sent in order.
+	Reverse bool `protobuf:"varint,8,opt,name=reverse,proto3" json:"reverse,omitempty"`
+	// Interleave a progress message in the stream every progress_interval
+	// bytes sent, zero disables it unless progress_percent is set.
+	ProgressInterval int64 `protobuf:"varint,9,opt,name=progress_interval,json=progressInterval,proto3" json:"progress_interval,omitempty"`
+	// Interleave a progress message in the stream every progress_percent
+	// percent of the range sent, between 1 and 100, zero disables it.
+	// When both intervals are set, the smaller one is used.
+	ProgressPercent int32 `protobuf:"varint,10,opt,name=progress_percent,json=progressPercent,proto3" json:"progress_percent,omitempty"`
+	// Comma-separated ETags of which the file must have one, or "*" for any,
+	// like the HTTP If-Match header. Fails with FAILED_PRECONDITION otherwise.
+	IfMatch string `protobuf:"bytes,11,opt,name=if_match,json=ifMatch,proto3" json:"if_match,omitempty"`
+	// Comma-separated ETags of which the file must have none, or "*" for any,
+	// like the HTTP If-None-Match header. Otherwise nothing is downloaded and
+	// the "x-download-not-modified" header is set.
+	IfNoneMatch string `protobuf:"bytes,12,opt,name=if_none_match,json=ifNoneMatch,proto3" json:"if_none_match,omitempty"`
+	// HTTP-date the file must have been modified after, like the HTTP
+	// If-Modified-Since header, ignored when if_none_match is set.
+	// Otherwise nothing is downloaded and the "x-download-not-modified" header is set.
+	IfModifiedSince string `protobuf:"bytes,13,opt,name=if_modified_since,json=ifModifiedSince,proto3" json:"if_modified_since,omitempty"`
+	// HTTP-date the file must not have been modified after, like the HTTP
+	// If-Unmodified-Since header, ignored when if_match is set.
+	// Fails with FAILED_PRECONDITION otherwise.
+	IfUnmodifiedSince string `protobuf:"bytes,14,opt,name=if_unmodified_since,json=ifUnmodifiedSince,proto3" json:"if_unmodified_since,omitempty"`
+	// Keep the stream open after sending the file's current end, and send the
+	// bytes appended to it as it grows, until no bytes were appended for the
+	// server's idle timeout or the client cancels. Fails with FAILED_PRECONDITION
+	// if the file is replaced rather than appended to. Can't be combined with
+	// range_end or reverse.
+	Follow bool `protobuf:"varint,15,opt,name=follow,proto3" json:"follow,omitempty"`
+	// Class of the server's shared bandwidth budget the download is paced by,
+	// when the server limits it. Under contention each download gets a share
+	// of the budget by its class's weight, so bulk downloads are throttled first.
+	QosClass QoSClass `protobuf:"varint,16,opt,name=qos_class,json=qosClass,proto3,enum=download.QoSClass" json:"qos_class,omitempty"`
+	// PKIX DER encoded RSA public key of at least 2048 bits to envelope the
+	// file bytes with, for transports that aren't trusted. Every file chunk is
+	// encrypted with AES-256-GCM by a random key of the stream, which is sent
+	// encrypted with RSA-OAEP SHA-256 by this key in the "x-envelope-key-bin"
+	// header, and the chunk's nonce is set in the response's nonce.
+	EnvelopePublicKey []byte `protobuf:"bytes,17,opt,name=envelope_public_key,json=envelopePublicKey,proto3" json:"envelope_public_key,omitempty"`
+	// Offset in the file to resume an interrupted download from, the first
+	// byte to send, within the requested range. The download's parts stay
+	// aligned to the part size in the file, so the first part is shortened to
+	// start at the offset. Zero starts at the range's start, and reversed
+	// downloads can't be resumed
+	Offset int64 `protobuf:"varint,18,opt,name=offset,proto3" json:"offset,omitempty"`
+	// Send the file's metadata in the first message of the stream, before
+	// any file bytes. The metadata message has no file bytes
+	IncludeMetadata bool `protobuf:"varint,19,opt,name=include_metadata,json=includeMetadata,proto3" json:"include_metadata,omitempty"`
+	// Algorithm of the checksum of the file bytes sent, computed by the server
+	// and sent in the last message of the stream, after any file bytes, as a
+	// lowercase hex digest. NONE, the default, computes no checksum. The bytes
+	// of a range are checksummed as sent, and reversed downloads can't be
+	// checksummed
+	ChecksumAlgorithm ChecksumAlgorithm `protobuf:"varint,20,opt,name=checksum_algorithm,json=checksumAlgorithm,proto3,enum=download.ChecksumAlgorithm" json:"checksum_algorithm,omitempty"`
+	// Size of the file the client already knows, e.g. from a listing, to skip
+	// the HeadObject call of the download when the server trusts clients'
+	// sizes. The size is validated against the first part downloaded, and the
+	// download fails with FAILED_PRECONDITION if it doesn't match. Ignored when
+	// the download needs the file's other attributes, e.g. for its conditions
+	KnownSize int64 `protobuf:"varint,21,opt,name=known_size,json=knownSize,proto3" json:"known_size,omitempty"`
+	// Size of the parts the file is downloaded from S3 in, between 256KiB and
+	// 64MiB, zero uses the server's part size. The file bytes of every message
+	// are at most the smaller of it and the server's part size
+	ChunkSize int64 `protobuf:"varint,22,opt,name=chunk_size,json=chunkSize,proto3" json:"chunk_size,omitempty"`
+	// Range to download as percentages of the file's size, for adaptive players
+	// that don't know the file's size upfront. Can't be combined with range_start
+	// and range_end
+	RangePercent *RangePercent `protobuf:"bytes,23,opt,name=range_percent,json=rangePercent,proto3" json:"range_percent,omitempty"`
+	// Version of the file to download, for buckets with versioning enabled.
+	// Empty downloads the latest version. Can't be combined with follow
+	VersionId string `protobuf:"bytes,24,opt,name=version_id,json=versionId,proto3" json:"version_id,omitempty"`
+	// Send the file decompressed if its key ends in .gz or .zst, and as is
+	// otherwise. The "x-download-content-type" header is set to the content
+	// type of the decompressed file when it's inferable from its key. Fails
+	// with DATA_LOSS if the compressed file is corrupt. Can't be combined with
+	// ranges, offset, reverse, follow or progress
+	Decompress bool `protobuf:"varint,25,opt,name=decompress,proto3" json:"decompress,omitempty"`
+	// Rate in bytes per second the file bytes are sent at most, for clients
+	// that share a link with others. Zero uses the server's rate, and a rate
+	// above the server's rate is lowered to it
+	MaxBytesPerSec       int64    `protobuf:"varint,26,opt,name=max_bytes_per_sec,json=maxBytesPerSec,proto3" json:"max_bytes_per_sec,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -38,7 +207,7 @@ func (m *DownloadRequest) Reset()         { *m = DownloadRequest{} }
 func (m *DownloadRequest) String() string { return proto.CompactTextString(m) }
 func (*DownloadRequest) ProtoMessage()    {}
 func (*DownloadRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_download_service_27a97b505d50aaec, []int{0}
+	return fileDescriptor_download_service_1710258a4f1cb257, []int{0}
 }
 func (m *DownloadRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DownloadRequest.Unmarshal(m, b)
@@ -72,10 +241,237 @@ func (m *DownloadRequest) GetBucket() string {
 	return ""
 }
 
+func (m *DownloadRequest) GetRangeStart() int64 {
+	if m != nil {
+		return m.RangeStart
+	}
+	return 0
+}
+
+func (m *DownloadRequest) GetRangeEnd() int64 {
+	if m != nil {
+		return m.RangeEnd
+	}
+	return 0
+}
+
+func (m *DownloadRequest) GetUrl() string {
+	if m != nil {
+		return m.Url
+	}
+	return ""
+}
+
+func (m *DownloadRequest) GetIfRange() string {
+	if m != nil {
+		return m.IfRange
+	}
+	return ""
+}
+
+func (m *DownloadRequest) GetIfRangeFail() bool {
+	if m != nil {
+		return m.IfRangeFail
+	}
+	return false
+}
+
+func (m *DownloadRequest) GetReverse() bool {
+	if m != nil {
+		return m.Reverse
+	}
+	return false
+}
+
+func (m *DownloadRequest) GetProgressInterval() int64 {
+	if m != nil {
+		return m.ProgressInterval
+	}
+	return 0
+}
+
+func (m *DownloadRequest) GetProgressPercent() int32 {
+	if m != nil {
+		return m.ProgressPercent
+	}
+	return 0
+}
+
+func (m *DownloadRequest) GetIfMatch() string {
+	if m != nil {
+		return m.IfMatch
+	}
+	return ""
+}
+
+func (m *DownloadRequest) GetIfNoneMatch() string {
+	if m != nil {
+		return m.IfNoneMatch
+	}
+	return ""
+}
+
+func (m *DownloadRequest) GetIfModifiedSince() string {
+	if m != nil {
+		return m.IfModifiedSince
+	}
+	return ""
+}
+
+func (m *DownloadRequest) GetIfUnmodifiedSince() string {
+	if m != nil {
+		return m.IfUnmodifiedSince
+	}
+	return ""
+}
+
+func (m *DownloadRequest) GetFollow() bool {
+	if m != nil {
+		return m.Follow
+	}
+	return false
+}
+
+func (m *DownloadRequest) GetQosClass() QoSClass {
+	if m != nil {
+		return m.QosClass
+	}
+	return QoSClass_STANDARD
+}
+
+func (m *DownloadRequest) GetEnvelopePublicKey() []byte {
+	if m != nil {
+		return m.EnvelopePublicKey
+	}
+	return nil
+}
+
+func (m *DownloadRequest) GetOffset() int64 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+func (m *DownloadRequest) GetIncludeMetadata() bool {
+	if m != nil {
+		return m.IncludeMetadata
+	}
+	return false
+}
+
+func (m *DownloadRequest) GetChecksumAlgorithm() ChecksumAlgorithm {
+	if m != nil {
+		return m.ChecksumAlgorithm
+	}
+	return ChecksumAlgorithm_NONE
+}
+
+func (m *DownloadRequest) GetKnownSize() int64 {
+	if m != nil {
+		return m.KnownSize
+	}
+	return 0
+}
+
+func (m *DownloadRequest) GetChunkSize() int64 {
+	if m != nil {
+		return m.ChunkSize
+	}
+	return 0
+}
+
+func (m *DownloadRequest) GetRangePercent() *RangePercent {
+	if m != nil {
+		return m.RangePercent
+	}
+	return nil
+}
+
+func (m *DownloadRequest) GetVersionId() string {
+	if m != nil {
+		return m.VersionId
+	}
+	return ""
+}
+
+func (m *DownloadRequest) GetDecompress() bool {
+	if m != nil {
+		return m.Decompress
+	}
+	return false
+}
+
+func (m *DownloadRequest) GetMaxBytesPerSec() int64 {
+	if m != nil {
+		return m.MaxBytesPerSec
+	}
+	return 0
+}
+
+// RangePercent is a range of a file as percentages of its size, between 0 and 100.
+// The range starts at the byte the start percentage falls in and ends right before
+// the byte the end percentage falls in, so adjacent ranges, such as 0-50 and 50-100,
+// split the file without overlapping or skipping bytes. A range narrower than a byte
+// is widened to the byte its start falls in.
+type RangePercent struct {
+	// Percentage of the file's size the range starts at, inclusive
+	Start float64 `protobuf:"fixed64,1,opt,name=start,proto3" json:"start,omitempty"`
+	// Percentage of the file's size the range ends at, exclusive, greater than start
+	End                  float64  `protobuf:"fixed64,2,opt,name=end,proto3" json:"end,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RangePercent) Reset()         { *m = RangePercent{} }
+func (m *RangePercent) String() string { return proto.CompactTextString(m) }
+func (*RangePercent) ProtoMessage()    {}
+func (*RangePercent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_download_service_1710258a4f1cb257, []int{1}
+}
+func (m *RangePercent) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RangePercent.Unmarshal(m, b)
+}
+func (m *RangePercent) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RangePercent.Marshal(b, m, deterministic)
+}
+func (dst *RangePercent) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RangePercent.Merge(dst, src)
+}
+func (m *RangePercent) XXX_Size() int {
+	return xxx_messageInfo_RangePercent.Size(m)
+}
+func (m *RangePercent) XXX_DiscardUnknown() {
+	xxx_messageInfo_RangePercent.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RangePercent proto.InternalMessageInfo
+
+func (m *RangePercent) GetStart() float64 {
+	if m != nil {
+		return m.Start
+	}
+	return 0
+}
+
+func (m *RangePercent) GetEnd() float64 {
+	if m != nil {
+		return m.End
+	}
+	return 0
+}
+
 // DownloadResponse is the response type of the download.
 type DownloadResponse struct {
-	// Raw File bytes
-	File                 []byte   `protobuf:"bytes,1,opt,name=file,proto3" json:"file,omitempty"`
+	// Types that are valid to be assigned to Payload:
+	//	*DownloadResponse_File
+	//	*DownloadResponse_Progress
+	//	*DownloadResponse_Metadata
+	//	*DownloadResponse_Checksum
+	Payload isDownloadResponse_Payload `protobuf_oneof:"payload"`
+	// Nonce of the encrypted file bytes, when the request's envelope_public_key is set
+	Nonce                []byte   `protobuf:"bytes,3,opt,name=nonce,proto3" json:"nonce,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -85,7 +481,7 @@ func (m *DownloadResponse) Reset()         { *m = DownloadResponse{} }
 func (m *DownloadResponse) String() string { return proto.CompactTextString(m) }
 func (*DownloadResponse) ProtoMessage()    {}
 func (*DownloadResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_download_service_27a97b505d50aaec, []int{1}
+	return fileDescriptor_download_service_1710258a4f1cb257, []int{2}
 }
 func (m *DownloadResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DownloadResponse.Unmarshal(m, b)
@@ -105,131 +501,2764 @@ func (m *DownloadResponse) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_DownloadResponse proto.InternalMessageInfo
 
-func (m *DownloadResponse) GetFile() []byte {
-	if m != nil {
-		return m.File
-	}
-	return nil
+type isDownloadResponse_Payload interface {
+	isDownloadResponse_Payload()
 }
 
-func init() {
-	proto.RegisterType((*DownloadRequest)(nil), "download.DownloadRequest")
-	proto.RegisterType((*DownloadResponse)(nil), "download.DownloadResponse")
+type DownloadResponse_File struct {
+	File []byte `protobuf:"bytes,1,opt,name=file,proto3,oneof"`
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
-
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
+type DownloadResponse_Progress struct {
+	Progress *DownloadProgress `protobuf:"bytes,2,opt,name=progress,proto3,oneof"`
+}
 
-// DownloadClient is the client API for Download service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
-type DownloadClient interface {
-	Download(ctx context.Context, in *DownloadRequest, opts ...grpc.CallOption) (Download_DownloadClient, error)
+type DownloadResponse_Metadata struct {
+	Metadata *DownloadMetadata `protobuf:"bytes,4,opt,name=metadata,proto3,oneof"`
 }
 
-type downloadClient struct {
-	cc *grpc.ClientConn
+type DownloadResponse_Checksum struct {
+	Checksum string `protobuf:"bytes,5,opt,name=checksum,proto3,oneof"`
 }
 
-func NewDownloadClient(cc *grpc.ClientConn) DownloadClient {
-	return &downloadClient{cc}
+func (*DownloadResponse_File) isDownloadResponse_Payload() {}
+
+func (*DownloadResponse_Progress) isDownloadResponse_Payload() {}
+
+func (*DownloadResponse_Metadata) isDownloadResponse_Payload() {}
+
+func (*DownloadResponse_Checksum) isDownloadResponse_Payload() {}
+
+func (m *DownloadResponse) GetPayload() isDownloadResponse_Payload {
+	if m != nil {
+		return m.Payload
+	}
+	return nil
 }
 
-func (c *downloadClient) Download(ctx context.Context, in *DownloadRequest, opts ...grpc.CallOption) (Download_DownloadClient, error) {
-	stream, err := c.cc.NewStream(ctx, &_Download_serviceDesc.Streams[0], "/download.Download/Download", opts...)
-	if err != nil {
-		return nil, err
+func (m *DownloadResponse) GetFile() []byte {
+	if x, ok := m.GetPayload().(*DownloadResponse_File); ok {
+		return x.File
 	}
-	x := &downloadDownloadClient{stream}
-	if err := x.ClientStream.SendMsg(in); err != nil {
-		return nil, err
+	return nil
+}
+
+func (m *DownloadResponse) GetProgress() *DownloadProgress {
+	if x, ok := m.GetPayload().(*DownloadResponse_Progress); ok {
+		return x.Progress
 	}
-	if err := x.ClientStream.CloseSend(); err != nil {
-		return nil, err
+	return nil
+}
+
+func (m *DownloadResponse) GetMetadata() *DownloadMetadata {
+	if x, ok := m.GetPayload().(*DownloadResponse_Metadata); ok {
+		return x.Metadata
 	}
-	return x, nil
+	return nil
 }
 
-type Download_DownloadClient interface {
-	Recv() (*DownloadResponse, error)
-	grpc.ClientStream
+func (m *DownloadResponse) GetChecksum() string {
+	if x, ok := m.GetPayload().(*DownloadResponse_Checksum); ok {
+		return x.Checksum
+	}
+	return ""
 }
 
-type downloadDownloadClient struct {
-	grpc.ClientStream
+func (m *DownloadResponse) GetNonce() []byte {
+	if m != nil {
+		return m.Nonce
+	}
+	return nil
 }
 
-func (x *downloadDownloadClient) Recv() (*DownloadResponse, error) {
-	m := new(DownloadResponse)
-	if err := x.ClientStream.RecvMsg(m); err != nil {
-		return nil, err
+// XXX_OneofFuncs is for the internal use of the proto package.
+func (*DownloadResponse) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
+	return _DownloadResponse_OneofMarshaler, _DownloadResponse_OneofUnmarshaler, _DownloadResponse_OneofSizer, []interface{}{
+		(*DownloadResponse_File)(nil),
+		(*DownloadResponse_Progress)(nil),
+		(*DownloadResponse_Metadata)(nil),
+		(*DownloadResponse_Checksum)(nil),
 	}
-	return m, nil
 }
 
-// DownloadServer is the server API for Download service.
-type DownloadServer interface {
-	Download(*DownloadRequest, Download_DownloadServer) error
+func _DownloadResponse_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
+	m := msg.(*DownloadResponse)
+	// payload
+	switch x := m.Payload.(type) {
+	case *DownloadResponse_File:
+		b.EncodeVarint(1<<3 | proto.WireBytes)
+		b.EncodeRawBytes(x.File)
+	case *DownloadResponse_Progress:
+		b.EncodeVarint(2<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Progress); err != nil {
+			return err
+		}
+	case *DownloadResponse_Metadata:
+		b.EncodeVarint(4<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Metadata); err != nil {
+			return err
+		}
+	case *DownloadResponse_Checksum:
+		b.EncodeVarint(5<<3 | proto.WireBytes)
+		b.EncodeStringBytes(x.Checksum)
+	case nil:
+	default:
+		return fmt.Errorf("DownloadResponse.Payload has unexpected type %T", x)
+	}
+	return nil
 }
 
-func RegisterDownloadServer(s *grpc.Server, srv DownloadServer) {
-	s.RegisterService(&_Download_serviceDesc, srv)
+func _DownloadResponse_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error) {
+	m := msg.(*DownloadResponse)
+	switch tag {
+	case 1: // payload.file
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeRawBytes(true)
+		m.Payload = &DownloadResponse_File{x}
+		return true, err
+	case 2: // payload.progress
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(DownloadProgress)
+		err := b.DecodeMessage(msg)
+		m.Payload = &DownloadResponse_Progress{msg}
+		return true, err
+	case 4: // payload.metadata
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(DownloadMetadata)
+		err := b.DecodeMessage(msg)
+		m.Payload = &DownloadResponse_Metadata{msg}
+		return true, err
+	case 5: // payload.checksum
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeStringBytes()
+		m.Payload = &DownloadResponse_Checksum{x}
+		return true, err
+	default:
+		return false, nil
+	}
 }
 
-func _Download_Download_Handler(srv interface{}, stream grpc.ServerStream) error {
-	m := new(DownloadRequest)
-	if err := stream.RecvMsg(m); err != nil {
-		return err
+func _DownloadResponse_OneofSizer(msg proto.Message) (n int) {
+	m := msg.(*DownloadResponse)
+	// payload
+	switch x := m.Payload.(type) {
+	case *DownloadResponse_File:
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(len(x.File)))
+		n += len(x.File)
+	case *DownloadResponse_Progress:
+		s := proto.Size(x.Progress)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *DownloadResponse_Metadata:
+		s := proto.Size(x.Metadata)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *DownloadResponse_Checksum:
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(len(x.Checksum)))
+		n += len(x.Checksum)
+	case nil:
+	default:
+		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
 	}
-	return srv.(DownloadServer).Download(m, &downloadDownloadServer{stream})
+	return n
 }
 
-type Download_DownloadServer interface {
-	Send(*DownloadResponse) error
-	grpc.ServerStream
+// DownloadProgress is the progress of a download.
+type DownloadProgress struct {
+	// Number of the range's bytes sent so far
+	BytesSent int64 `protobuf:"varint,1,opt,name=bytes_sent,json=bytesSent,proto3" json:"bytes_sent,omitempty"`
+	// Total number of bytes of the range
+	TotalBytes           int64    `protobuf:"varint,2,opt,name=total_bytes,json=totalBytes,proto3" json:"total_bytes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-type downloadDownloadServer struct {
-	grpc.ServerStream
+func (m *DownloadProgress) Reset()         { *m = DownloadProgress{} }
+func (m *DownloadProgress) String() string { return proto.CompactTextString(m) }
+func (*DownloadProgress) ProtoMessage()    {}
+func (*DownloadProgress) Descriptor() ([]byte, []int) {
+	return fileDescriptor_download_service_1710258a4f1cb257, []int{3}
+}
+func (m *DownloadProgress) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DownloadProgress.Unmarshal(m, b)
+}
+func (m *DownloadProgress) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DownloadProgress.Marshal(b, m, deterministic)
+}
+func (dst *DownloadProgress) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DownloadProgress.Merge(dst, src)
+}
+func (m *DownloadProgress) XXX_Size() int {
+	return xxx_messageInfo_DownloadProgress.Size(m)
+}
+func (m *DownloadProgress) XXX_DiscardUnknown() {
+	xxx_messageInfo_DownloadProgress.DiscardUnknown(m)
 }
 
-func (x *downloadDownloadServer) Send(m *DownloadResponse) error {
-	return x.ServerStream.SendMsg(m)
+var xxx_messageInfo_DownloadProgress proto.InternalMessageInfo
+
+func (m *DownloadProgress) GetBytesSent() int64 {
+	if m != nil {
+		return m.BytesSent
+	}
+	return 0
 }
 
-var _Download_serviceDesc = grpc.ServiceDesc{
-	ServiceName: "download.Download",
-	HandlerType: (*DownloadServer)(nil),
-	Methods:     []grpc.MethodDesc{},
-	Streams: []grpc.StreamDesc{
-		{
-			StreamName:    "Download",
-			Handler:       _Download_Download_Handler,
-			ServerStreams: true,
-		},
-	},
-	Metadata: "download_service.proto",
+func (m *DownloadProgress) GetTotalBytes() int64 {
+	if m != nil {
+		return m.TotalBytes
+	}
+	return 0
 }
 
-func init() {
-	proto.RegisterFile("download_service.proto", fileDescriptor_download_service_27a97b505d50aaec)
-}
-
-var fileDescriptor_download_service_27a97b505d50aaec = []byte{
-	// 156 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xe2, 0x12, 0x4b, 0xc9, 0x2f, 0xcf,
-	0xcb, 0xc9, 0x4f, 0x4c, 0x89, 0x2f, 0x4e, 0x2d, 0x2a, 0xcb, 0x4c, 0x4e, 0xd5, 0x2b, 0x28, 0xca,
-	0x2f, 0xc9, 0x17, 0xe2, 0x80, 0x89, 0x2b, 0x59, 0x73, 0xf1, 0xbb, 0x40, 0xd9, 0x41, 0xa9, 0x85,
-	0xa5, 0xa9, 0xc5, 0x25, 0x42, 0x02, 0x5c, 0xcc, 0xd9, 0xa9, 0x95, 0x12, 0x8c, 0x0a, 0x8c, 0x1a,
-	0x9c, 0x41, 0x20, 0xa6, 0x90, 0x18, 0x17, 0x5b, 0x52, 0x69, 0x72, 0x76, 0x6a, 0x89, 0x04, 0x13,
-	0x58, 0x10, 0xca, 0x53, 0x52, 0xe3, 0x12, 0x40, 0x68, 0x2e, 0x2e, 0xc8, 0xcf, 0x2b, 0x4e, 0x15,
-	0x12, 0xe2, 0x62, 0x49, 0xcb, 0xcc, 0x49, 0x05, 0x6b, 0xe7, 0x09, 0x02, 0xb3, 0x8d, 0x02, 0xb9,
-	0x38, 0x60, 0xea, 0x84, 0x5c, 0x91, 0xd8, 0x92, 0x7a, 0x30, 0x77, 0xe8, 0xa1, 0x39, 0x42, 0x4a,
-	0x0a, 0x9b, 0x14, 0xc4, 0x0a, 0x25, 0x06, 0x03, 0xc6, 0x24, 0x36, 0xb0, 0x47, 0x8c, 0x01, 0x01,
-	0x00, 0x00, 0xff, 0xff, 0xce, 0x84, 0x74, 0xdb, 0xe2, 0x00, 0x00, 0x00,
+// DownloadMetadata is the metadata of a downloaded file.
+type DownloadMetadata struct {
+	// The file's MIME type
+	ContentType string `protobuf:"bytes,1,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	// The file's size in bytes, the whole file's rather than the range's
+	Size int64 `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	// The file's ETag
+	Etag string `protobuf:"bytes,3,opt,name=etag,proto3" json:"etag,omitempty"`
+	// The file's last modification time, in Unix milliseconds
+	LastModified int64 `protobuf:"varint,4,opt,name=last_modified,json=lastModified,proto3" json:"last_modified,omitempty"`
+	// The file's storage class, e.g. STANDARD or GLACIER
+	StorageClass string `protobuf:"bytes,5,opt,name=storage_class,json=storageClass,proto3" json:"storage_class,omitempty"`
+	// The version of the file, empty if its bucket isn't versioned
+	VersionId string `protobuf:"bytes,6,opt,name=version_id,json=versionId,proto3" json:"version_id,omitempty"`
+	// Hints for aligning parallel range requests of the file, set only by
+	// GetMetadata when the request's range_hints is set
+	RangeHints *RangeHints `protobuf:"bytes,7,opt,name=range_hints,json=rangeHints,proto3" json:"range_hints,omitempty"`
+	// The server-side encryption algorithm the file is encrypted at rest with,
+	// empty if it isn't encrypted
+	ServerSideEncryption string `protobuf:"bytes,8,opt,name=server_side_encryption,json=serverSideEncryption,proto3" json:"server_side_encryption,omitempty"`
+	// The ID of the KMS key the file is encrypted with, if encrypted with SSE-KMS
+	SseKmsKeyId string `protobuf:"bytes,9,opt,name=sse_kms_key_id,json=sseKmsKeyId,proto3" json:"sse_kms_key_id,omitempty"`
+	// The algorithm of the checksum the file was uploaded with, CRC32, CRC32C, SHA1 or SHA256,
+	// empty if it wasn't uploaded with a checksum
+	ChecksumAlgorithm string `protobuf:"bytes,10,opt,name=checksum_algorithm,json=checksumAlgorithm,proto3" json:"checksum_algorithm,omitempty"`
+	// The base64 checksum the file was uploaded with, suffixed with "-" and the number of parts
+	// if it's the checksum of the checksums of the parts of a multipart upload
+	Checksum string `protobuf:"bytes,11,opt,name=checksum,proto3" json:"checksum,omitempty"`
+	// Whether the file's ETag is weak, prefixed with "W/", as returned by some
+	// S3-compatible stores
+	EtagWeak bool `protobuf:"varint,12,opt,name=etag_weak,json=etagWeak,proto3" json:"etag_weak,omitempty"`
+	// The file's Cache-Control, for CDNs caching it, empty if it isn't set
+	CacheControl string `protobuf:"bytes,13,opt,name=cache_control,json=cacheControl,proto3" json:"cache_control,omitempty"`
+	// The file's Expires as an HTTP date, for CDNs caching it, empty if it isn't set
+	Expires              string   `protobuf:"bytes,14,opt,name=expires,proto3" json:"expires,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DownloadMetadata) Reset()         { *m = DownloadMetadata{} }
+func (m *DownloadMetadata) String() string { return proto.CompactTextString(m) }
+func (*DownloadMetadata) ProtoMessage()    {}
+func (*DownloadMetadata) Descriptor() ([]byte, []int) {
+	return fileDescriptor_download_service_1710258a4f1cb257, []int{4}
+}
+func (m *DownloadMetadata) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DownloadMetadata.Unmarshal(m, b)
+}
+func (m *DownloadMetadata) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DownloadMetadata.Marshal(b, m, deterministic)
+}
+func (dst *DownloadMetadata) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DownloadMetadata.Merge(dst, src)
+}
+func (m *DownloadMetadata) XXX_Size() int {
+	return xxx_messageInfo_DownloadMetadata.Size(m)
+}
+func (m *DownloadMetadata) XXX_DiscardUnknown() {
+	xxx_messageInfo_DownloadMetadata.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DownloadMetadata proto.InternalMessageInfo
+
+func (m *DownloadMetadata) GetContentType() string {
+	if m != nil {
+		return m.ContentType
+	}
+	return ""
+}
+
+func (m *DownloadMetadata) GetSize() int64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *DownloadMetadata) GetEtag() string {
+	if m != nil {
+		return m.Etag
+	}
+	return ""
+}
+
+func (m *DownloadMetadata) GetLastModified() int64 {
+	if m != nil {
+		return m.LastModified
+	}
+	return 0
+}
+
+func (m *DownloadMetadata) GetStorageClass() string {
+	if m != nil {
+		return m.StorageClass
+	}
+	return ""
+}
+
+func (m *DownloadMetadata) GetVersionId() string {
+	if m != nil {
+		return m.VersionId
+	}
+	return ""
+}
+
+func (m *DownloadMetadata) GetRangeHints() *RangeHints {
+	if m != nil {
+		return m.RangeHints
+	}
+	return nil
+}
+
+func (m *DownloadMetadata) GetServerSideEncryption() string {
+	if m != nil {
+		return m.ServerSideEncryption
+	}
+	return ""
+}
+
+func (m *DownloadMetadata) GetSseKmsKeyId() string {
+	if m != nil {
+		return m.SseKmsKeyId
+	}
+	return ""
+}
+
+func (m *DownloadMetadata) GetChecksumAlgorithm() string {
+	if m != nil {
+		return m.ChecksumAlgorithm
+	}
+	return ""
+}
+
+func (m *DownloadMetadata) GetChecksum() string {
+	if m != nil {
+		return m.Checksum
+	}
+	return ""
+}
+
+func (m *DownloadMetadata) GetEtagWeak() bool {
+	if m != nil {
+		return m.EtagWeak
+	}
+	return false
+}
+
+func (m *DownloadMetadata) GetCacheControl() string {
+	if m != nil {
+		return m.CacheControl
+	}
+	return ""
+}
+
+func (m *DownloadMetadata) GetExpires() string {
+	if m != nil {
+		return m.Expires
+	}
+	return ""
+}
+
+// RangeHints describes the boundaries that parallel range requests of a file are best aligned to.
+type RangeHints struct {
+	// The size of the parts the server downloads the file in, the size of its
+	// native parts if the server aligns downloads to them
+	SuggestedPartSize int64 `protobuf:"varint,1,opt,name=suggested_part_size,json=suggestedPartSize,proto3" json:"suggested_part_size,omitempty"`
+	// The number of parts the file was uploaded in, 1 for single-part files
+	NativePartsCount     int64    `protobuf:"varint,2,opt,name=native_parts_count,json=nativePartsCount,proto3" json:"native_parts_count,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RangeHints) Reset()         { *m = RangeHints{} }
+func (m *RangeHints) String() string { return proto.CompactTextString(m) }
+func (*RangeHints) ProtoMessage()    {}
+func (*RangeHints) Descriptor() ([]byte, []int) {
+	return fileDescriptor_download_service_1710258a4f1cb257, []int{5}
+}
+func (m *RangeHints) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RangeHints.Unmarshal(m, b)
+}
+func (m *RangeHints) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RangeHints.Marshal(b, m, deterministic)
+}
+func (dst *RangeHints) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RangeHints.Merge(dst, src)
+}
+func (m *RangeHints) XXX_Size() int {
+	return xxx_messageInfo_RangeHints.Size(m)
+}
+func (m *RangeHints) XXX_DiscardUnknown() {
+	xxx_messageInfo_RangeHints.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RangeHints proto.InternalMessageInfo
+
+func (m *RangeHints) GetSuggestedPartSize() int64 {
+	if m != nil {
+		return m.SuggestedPartSize
+	}
+	return 0
+}
+
+func (m *RangeHints) GetNativePartsCount() int64 {
+	if m != nil {
+		return m.NativePartsCount
+	}
+	return 0
+}
+
+// GetMetadataRequest is the request type of a file's metadata, without downloading it.
+type GetMetadataRequest struct {
+	// File key to get the metadata of from S3
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// The bucket of the file
+	Bucket string `protobuf:"bytes,2,opt,name=bucket,proto3" json:"bucket,omitempty"`
+	// URL of the file, like DownloadRequest's url
+	Url string `protobuf:"bytes,3,opt,name=url,proto3" json:"url,omitempty"`
+	// Version of the file, like DownloadRequest's version_id
+	VersionId string `protobuf:"bytes,4,opt,name=version_id,json=versionId,proto3" json:"version_id,omitempty"`
+	// Return hints for aligning parallel range requests of the file,
+	// at the cost of another call to S3
+	RangeHints           bool     `protobuf:"varint,5,opt,name=range_hints,json=rangeHints,proto3" json:"range_hints,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetMetadataRequest) Reset()         { *m = GetMetadataRequest{} }
+func (m *GetMetadataRequest) String() string { return proto.CompactTextString(m) }
+func (*GetMetadataRequest) ProtoMessage()    {}
+func (*GetMetadataRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_download_service_1710258a4f1cb257, []int{6}
+}
+func (m *GetMetadataRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetMetadataRequest.Unmarshal(m, b)
+}
+func (m *GetMetadataRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetMetadataRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetMetadataRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetMetadataRequest.Merge(dst, src)
+}
+func (m *GetMetadataRequest) XXX_Size() int {
+	return xxx_messageInfo_GetMetadataRequest.Size(m)
+}
+func (m *GetMetadataRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetMetadataRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetMetadataRequest proto.InternalMessageInfo
+
+func (m *GetMetadataRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *GetMetadataRequest) GetBucket() string {
+	if m != nil {
+		return m.Bucket
+	}
+	return ""
+}
+
+func (m *GetMetadataRequest) GetUrl() string {
+	if m != nil {
+		return m.Url
+	}
+	return ""
+}
+
+func (m *GetMetadataRequest) GetVersionId() string {
+	if m != nil {
+		return m.VersionId
+	}
+	return ""
+}
+
+func (m *GetMetadataRequest) GetRangeHints() bool {
+	if m != nil {
+		return m.RangeHints
+	}
+	return false
+}
+
+// GetPresignedURLRequest is the request type of a time-limited URL to download a file from S3 over HTTP.
+type GetPresignedURLRequest struct {
+	// File key to download from S3
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// The bucket of the file
+	Bucket string `protobuf:"bytes,2,opt,name=bucket,proto3" json:"bucket,omitempty"`
+	// Seconds the URL is valid for, defaults to 900 and is limited to 604800
+	ExpirySeconds        int64    `protobuf:"varint,3,opt,name=expiry_seconds,json=expirySeconds,proto3" json:"expiry_seconds,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetPresignedURLRequest) Reset()         { *m = GetPresignedURLRequest{} }
+func (m *GetPresignedURLRequest) String() string { return proto.CompactTextString(m) }
+func (*GetPresignedURLRequest) ProtoMessage()    {}
+func (*GetPresignedURLRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_download_service_1710258a4f1cb257, []int{7}
+}
+func (m *GetPresignedURLRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetPresignedURLRequest.Unmarshal(m, b)
+}
+func (m *GetPresignedURLRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetPresignedURLRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetPresignedURLRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetPresignedURLRequest.Merge(dst, src)
+}
+func (m *GetPresignedURLRequest) XXX_Size() int {
+	return xxx_messageInfo_GetPresignedURLRequest.Size(m)
+}
+func (m *GetPresignedURLRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetPresignedURLRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetPresignedURLRequest proto.InternalMessageInfo
+
+func (m *GetPresignedURLRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *GetPresignedURLRequest) GetBucket() string {
+	if m != nil {
+		return m.Bucket
+	}
+	return ""
+}
+
+func (m *GetPresignedURLRequest) GetExpirySeconds() int64 {
+	if m != nil {
+		return m.ExpirySeconds
+	}
+	return 0
+}
+
+// PresignedURL is a time-limited URL to download a file from S3 over HTTP.
+type PresignedURL struct {
+	// The URL to GET the file from
+	Url string `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	// The time the URL expires at, in Unix milliseconds
+	ExpiresAt            int64    `protobuf:"varint,2,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PresignedURL) Reset()         { *m = PresignedURL{} }
+func (m *PresignedURL) String() string { return proto.CompactTextString(m) }
+func (*PresignedURL) ProtoMessage()    {}
+func (*PresignedURL) Descriptor() ([]byte, []int) {
+	return fileDescriptor_download_service_1710258a4f1cb257, []int{8}
+}
+func (m *PresignedURL) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PresignedURL.Unmarshal(m, b)
+}
+func (m *PresignedURL) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PresignedURL.Marshal(b, m, deterministic)
+}
+func (dst *PresignedURL) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PresignedURL.Merge(dst, src)
+}
+func (m *PresignedURL) XXX_Size() int {
+	return xxx_messageInfo_PresignedURL.Size(m)
+}
+func (m *PresignedURL) XXX_DiscardUnknown() {
+	xxx_messageInfo_PresignedURL.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PresignedURL proto.InternalMessageInfo
+
+func (m *PresignedURL) GetUrl() string {
+	if m != nil {
+		return m.Url
+	}
+	return ""
+}
+
+func (m *PresignedURL) GetExpiresAt() int64 {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return 0
+}
+
+// DownloadFailure is the status detail of a failed download.
+type DownloadFailure struct {
+	// Number of the range's bytes sent before the download failed,
+	// which are valid to resume the download from
+	BytesSent            int64    `protobuf:"varint,1,opt,name=bytes_sent,json=bytesSent,proto3" json:"bytes_sent,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DownloadFailure) Reset()         { *m = DownloadFailure{} }
+func (m *DownloadFailure) String() string { return proto.CompactTextString(m) }
+func (*DownloadFailure) ProtoMessage()    {}
+func (*DownloadFailure) Descriptor() ([]byte, []int) {
+	return fileDescriptor_download_service_1710258a4f1cb257, []int{9}
+}
+func (m *DownloadFailure) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DownloadFailure.Unmarshal(m, b)
+}
+func (m *DownloadFailure) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DownloadFailure.Marshal(b, m, deterministic)
+}
+func (dst *DownloadFailure) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DownloadFailure.Merge(dst, src)
+}
+func (m *DownloadFailure) XXX_Size() int {
+	return xxx_messageInfo_DownloadFailure.Size(m)
+}
+func (m *DownloadFailure) XXX_DiscardUnknown() {
+	xxx_messageInfo_DownloadFailure.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DownloadFailure proto.InternalMessageInfo
+
+func (m *DownloadFailure) GetBytesSent() int64 {
+	if m != nil {
+		return m.BytesSent
+	}
+	return 0
+}
+
+// ListObjectsRequest is the request type of a page of objects listing.
+type ListObjectsRequest struct {
+	// The bucket to list objects of
+	Bucket string `protobuf:"bytes,1,opt,name=bucket,proto3" json:"bucket,omitempty"`
+	// List only keys that begin with prefix
+	Prefix string `protobuf:"bytes,2,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	// Group keys that contain delimiter after the prefix into common prefixes,
+	// "/" lists a single directory level
+	Delimiter string `protobuf:"bytes,3,opt,name=delimiter,proto3" json:"delimiter,omitempty"`
+	// Maximum number of objects and common prefixes in the page,
+	// defaults to 100 and is limited to 1000
+	PageSize int64 `protobuf:"varint,4,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// The next_page_token of the previous page, empty for the first page
+	PageToken            string   `protobuf:"bytes,5,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListObjectsRequest) Reset()         { *m = ListObjectsRequest{} }
+func (m *ListObjectsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListObjectsRequest) ProtoMessage()    {}
+func (*ListObjectsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_download_service_1710258a4f1cb257, []int{10}
+}
+func (m *ListObjectsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListObjectsRequest.Unmarshal(m, b)
+}
+func (m *ListObjectsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListObjectsRequest.Marshal(b, m, deterministic)
+}
+func (dst *ListObjectsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListObjectsRequest.Merge(dst, src)
+}
+func (m *ListObjectsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListObjectsRequest.Size(m)
+}
+func (m *ListObjectsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListObjectsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListObjectsRequest proto.InternalMessageInfo
+
+func (m *ListObjectsRequest) GetBucket() string {
+	if m != nil {
+		return m.Bucket
+	}
+	return ""
+}
+
+func (m *ListObjectsRequest) GetPrefix() string {
+	if m != nil {
+		return m.Prefix
+	}
+	return ""
+}
+
+func (m *ListObjectsRequest) GetDelimiter() string {
+	if m != nil {
+		return m.Delimiter
+	}
+	return ""
+}
+
+func (m *ListObjectsRequest) GetPageSize() int64 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+func (m *ListObjectsRequest) GetPageToken() string {
+	if m != nil {
+		return m.PageToken
+	}
+	return ""
+}
+
+// ObjectInfo describes a listed object.
+type ObjectInfo struct {
+	// The object's key
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// The object's size in bytes
+	Size int64 `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	// The object's ETag
+	Etag string `protobuf:"bytes,3,opt,name=etag,proto3" json:"etag,omitempty"`
+	// The object's last modification time, in Unix milliseconds
+	LastModified         int64    `protobuf:"varint,4,opt,name=last_modified,json=lastModified,proto3" json:"last_modified,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ObjectInfo) Reset()         { *m = ObjectInfo{} }
+func (m *ObjectInfo) String() string { return proto.CompactTextString(m) }
+func (*ObjectInfo) ProtoMessage()    {}
+func (*ObjectInfo) Descriptor() ([]byte, []int) {
+	return fileDescriptor_download_service_1710258a4f1cb257, []int{11}
+}
+func (m *ObjectInfo) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ObjectInfo.Unmarshal(m, b)
+}
+func (m *ObjectInfo) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ObjectInfo.Marshal(b, m, deterministic)
+}
+func (dst *ObjectInfo) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ObjectInfo.Merge(dst, src)
+}
+func (m *ObjectInfo) XXX_Size() int {
+	return xxx_messageInfo_ObjectInfo.Size(m)
+}
+func (m *ObjectInfo) XXX_DiscardUnknown() {
+	xxx_messageInfo_ObjectInfo.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ObjectInfo proto.InternalMessageInfo
+
+func (m *ObjectInfo) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *ObjectInfo) GetSize() int64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *ObjectInfo) GetEtag() string {
+	if m != nil {
+		return m.Etag
+	}
+	return ""
+}
+
+func (m *ObjectInfo) GetLastModified() int64 {
+	if m != nil {
+		return m.LastModified
+	}
+	return 0
+}
+
+// ListObjectsResponse is the response type of a page of objects listing.
+type ListObjectsResponse struct {
+	// The objects of the page
+	Objects []*ObjectInfo `protobuf:"bytes,1,rep,name=objects,proto3" json:"objects,omitempty"`
+	// The common prefixes of the page, when a delimiter is requested
+	CommonPrefixes []string `protobuf:"bytes,2,rep,name=common_prefixes,json=commonPrefixes,proto3" json:"common_prefixes,omitempty"`
+	// Token of the next page, empty when this is the last page
+	NextPageToken        string   `protobuf:"bytes,3,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListObjectsResponse) Reset()         { *m = ListObjectsResponse{} }
+func (m *ListObjectsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListObjectsResponse) ProtoMessage()    {}
+func (*ListObjectsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_download_service_1710258a4f1cb257, []int{12}
+}
+func (m *ListObjectsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListObjectsResponse.Unmarshal(m, b)
+}
+func (m *ListObjectsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListObjectsResponse.Marshal(b, m, deterministic)
+}
+func (dst *ListObjectsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListObjectsResponse.Merge(dst, src)
+}
+func (m *ListObjectsResponse) XXX_Size() int {
+	return xxx_messageInfo_ListObjectsResponse.Size(m)
+}
+func (m *ListObjectsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListObjectsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListObjectsResponse proto.InternalMessageInfo
+
+func (m *ListObjectsResponse) GetObjects() []*ObjectInfo {
+	if m != nil {
+		return m.Objects
+	}
+	return nil
+}
+
+func (m *ListObjectsResponse) GetCommonPrefixes() []string {
+	if m != nil {
+		return m.CommonPrefixes
+	}
+	return nil
+}
+
+func (m *ListObjectsResponse) GetNextPageToken() string {
+	if m != nil {
+		return m.NextPageToken
+	}
+	return ""
+}
+
+// GetDownloadManifestRequest is the request type of a manifest of parts to
+// download concurrently with ranged downloads.
+type GetDownloadManifestRequest struct {
+	// File key to download from S3
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// The bucket to download file from
+	Bucket string `protobuf:"bytes,2,opt,name=bucket,proto3" json:"bucket,omitempty"`
+	// URL of the file to download, like DownloadRequest's url
+	Url string `protobuf:"bytes,3,opt,name=url,proto3" json:"url,omitempty"`
+	// Size of the parts in bytes, at least 64KiB, defaults to the service's part size
+	PartSize             int64    `protobuf:"varint,4,opt,name=part_size,json=partSize,proto3" json:"part_size,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetDownloadManifestRequest) Reset()         { *m = GetDownloadManifestRequest{} }
+func (m *GetDownloadManifestRequest) String() string { return proto.CompactTextString(m) }
+func (*GetDownloadManifestRequest) ProtoMessage()    {}
+func (*GetDownloadManifestRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_download_service_1710258a4f1cb257, []int{13}
+}
+func (m *GetDownloadManifestRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetDownloadManifestRequest.Unmarshal(m, b)
+}
+func (m *GetDownloadManifestRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetDownloadManifestRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetDownloadManifestRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetDownloadManifestRequest.Merge(dst, src)
+}
+func (m *GetDownloadManifestRequest) XXX_Size() int {
+	return xxx_messageInfo_GetDownloadManifestRequest.Size(m)
+}
+func (m *GetDownloadManifestRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetDownloadManifestRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetDownloadManifestRequest proto.InternalMessageInfo
+
+func (m *GetDownloadManifestRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *GetDownloadManifestRequest) GetBucket() string {
+	if m != nil {
+		return m.Bucket
+	}
+	return ""
+}
+
+func (m *GetDownloadManifestRequest) GetUrl() string {
+	if m != nil {
+		return m.Url
+	}
+	return ""
+}
+
+func (m *GetDownloadManifestRequest) GetPartSize() int64 {
+	if m != nil {
+		return m.PartSize
+	}
+	return 0
+}
+
+// ManifestPart is a range of bytes of a file to download.
+type ManifestPart struct {
+	// Offset of the part's first byte in the file
+	Offset int64 `protobuf:"varint,1,opt,name=offset,proto3" json:"offset,omitempty"`
+	// Number of bytes in the part
+	Length               int64    `protobuf:"varint,2,opt,name=length,proto3" json:"length,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ManifestPart) Reset()         { *m = ManifestPart{} }
+func (m *ManifestPart) String() string { return proto.CompactTextString(m) }
+func (*ManifestPart) ProtoMessage()    {}
+func (*ManifestPart) Descriptor() ([]byte, []int) {
+	return fileDescriptor_download_service_1710258a4f1cb257, []int{14}
+}
+func (m *ManifestPart) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ManifestPart.Unmarshal(m, b)
+}
+func (m *ManifestPart) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ManifestPart.Marshal(b, m, deterministic)
+}
+func (dst *ManifestPart) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ManifestPart.Merge(dst, src)
+}
+func (m *ManifestPart) XXX_Size() int {
+	return xxx_messageInfo_ManifestPart.Size(m)
+}
+func (m *ManifestPart) XXX_DiscardUnknown() {
+	xxx_messageInfo_ManifestPart.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ManifestPart proto.InternalMessageInfo
+
+func (m *ManifestPart) GetOffset() int64 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+func (m *ManifestPart) GetLength() int64 {
+	if m != nil {
+		return m.Length
+	}
+	return 0
+}
+
+// DownloadManifest lists the parts of a file, to download each with a ranged
+// download from offset to offset+length-1.
+type DownloadManifest struct {
+	// The file's size in bytes
+	Size int64 `protobuf:"varint,1,opt,name=size,proto3" json:"size,omitempty"`
+	// The file's ETag, pass it as the if_range of the ranged downloads with
+	// if_range_fail to detect changes of the file during the download
+	Etag string `protobuf:"bytes,2,opt,name=etag,proto3" json:"etag,omitempty"`
+	// The parts of the file, in order
+	Parts []*ManifestPart `protobuf:"bytes,3,rep,name=parts,proto3" json:"parts,omitempty"`
+	// The server-side encryption algorithm the file is encrypted at rest with,
+	// empty if it isn't encrypted
+	ServerSideEncryption string `protobuf:"bytes,4,opt,name=server_side_encryption,json=serverSideEncryption,proto3" json:"server_side_encryption,omitempty"`
+	// The ID of the KMS key the file is encrypted with, if encrypted with SSE-KMS
+	SseKmsKeyId string `protobuf:"bytes,5,opt,name=sse_kms_key_id,json=sseKmsKeyId,proto3" json:"sse_kms_key_id,omitempty"`
+	// The algorithm of the checksum the file was uploaded with, CRC32, CRC32C, SHA1 or SHA256,
+	// empty if it wasn't uploaded with a checksum
+	ChecksumAlgorithm string `protobuf:"bytes,6,opt,name=checksum_algorithm,json=checksumAlgorithm,proto3" json:"checksum_algorithm,omitempty"`
+	// The base64 checksum the file was uploaded with, suffixed with "-" and the number of parts
+	// if it's the checksum of the checksums of the parts of a multipart upload
+	Checksum string `protobuf:"bytes,7,opt,name=checksum,proto3" json:"checksum,omitempty"`
+	// Whether the file's ETag is weak, prefixed with "W/", as returned by some
+	// S3-compatible stores. Weak ETags never match if_range or if_match, since
+	// they don't identify the file's bytes, only if_none_match
+	EtagWeak bool `protobuf:"varint,8,opt,name=etag_weak,json=etagWeak,proto3" json:"etag_weak,omitempty"`
+	// The file's Cache-Control, for CDNs caching it, empty if it isn't set
+	CacheControl string `protobuf:"bytes,9,opt,name=cache_control,json=cacheControl,proto3" json:"cache_control,omitempty"`
+	// The file's Expires as an HTTP date, for CDNs caching it, empty if it isn't set
+	Expires              string   `protobuf:"bytes,10,opt,name=expires,proto3" json:"expires,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DownloadManifest) Reset()         { *m = DownloadManifest{} }
+func (m *DownloadManifest) String() string { return proto.CompactTextString(m) }
+func (*DownloadManifest) ProtoMessage()    {}
+func (*DownloadManifest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_download_service_1710258a4f1cb257, []int{15}
+}
+func (m *DownloadManifest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DownloadManifest.Unmarshal(m, b)
+}
+func (m *DownloadManifest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DownloadManifest.Marshal(b, m, deterministic)
+}
+func (dst *DownloadManifest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DownloadManifest.Merge(dst, src)
+}
+func (m *DownloadManifest) XXX_Size() int {
+	return xxx_messageInfo_DownloadManifest.Size(m)
+}
+func (m *DownloadManifest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DownloadManifest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DownloadManifest proto.InternalMessageInfo
+
+func (m *DownloadManifest) GetSize() int64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *DownloadManifest) GetEtag() string {
+	if m != nil {
+		return m.Etag
+	}
+	return ""
+}
+
+func (m *DownloadManifest) GetParts() []*ManifestPart {
+	if m != nil {
+		return m.Parts
+	}
+	return nil
+}
+
+func (m *DownloadManifest) GetServerSideEncryption() string {
+	if m != nil {
+		return m.ServerSideEncryption
+	}
+	return ""
+}
+
+func (m *DownloadManifest) GetSseKmsKeyId() string {
+	if m != nil {
+		return m.SseKmsKeyId
+	}
+	return ""
+}
+
+func (m *DownloadManifest) GetChecksumAlgorithm() string {
+	if m != nil {
+		return m.ChecksumAlgorithm
+	}
+	return ""
+}
+
+func (m *DownloadManifest) GetChecksum() string {
+	if m != nil {
+		return m.Checksum
+	}
+	return ""
+}
+
+func (m *DownloadManifest) GetEtagWeak() bool {
+	if m != nil {
+		return m.EtagWeak
+	}
+	return false
+}
+
+func (m *DownloadManifest) GetCacheControl() string {
+	if m != nil {
+		return m.CacheControl
+	}
+	return ""
+}
+
+func (m *DownloadManifest) GetExpires() string {
+	if m != nil {
+		return m.Expires
+	}
+	return ""
+}
+
+// DownloadDeltaRequest is the request type of the delta between two versions of a file.
+type DownloadDeltaRequest struct {
+	// File key to download from S3
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// The bucket to download file from
+	Bucket string `protobuf:"bytes,2,opt,name=bucket,proto3" json:"bucket,omitempty"`
+	// Version of the file the client has, which the delta is computed against
+	BaseVersionId string `protobuf:"bytes,3,opt,name=base_version_id,json=baseVersionId,proto3" json:"base_version_id,omitempty"`
+	// Version of the file to download, empty for the latest version
+	TargetVersionId string `protobuf:"bytes,4,opt,name=target_version_id,json=targetVersionId,proto3" json:"target_version_id,omitempty"`
+	// Size in bytes of the blocks of the base version that are matched in the
+	// target version, defaults to 4KiB
+	BlockSize            int64    `protobuf:"varint,5,opt,name=block_size,json=blockSize,proto3" json:"block_size,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DownloadDeltaRequest) Reset()         { *m = DownloadDeltaRequest{} }
+func (m *DownloadDeltaRequest) String() string { return proto.CompactTextString(m) }
+func (*DownloadDeltaRequest) ProtoMessage()    {}
+func (*DownloadDeltaRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_download_service_1710258a4f1cb257, []int{16}
+}
+func (m *DownloadDeltaRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DownloadDeltaRequest.Unmarshal(m, b)
+}
+func (m *DownloadDeltaRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DownloadDeltaRequest.Marshal(b, m, deterministic)
+}
+func (dst *DownloadDeltaRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DownloadDeltaRequest.Merge(dst, src)
+}
+func (m *DownloadDeltaRequest) XXX_Size() int {
+	return xxx_messageInfo_DownloadDeltaRequest.Size(m)
+}
+func (m *DownloadDeltaRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DownloadDeltaRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DownloadDeltaRequest proto.InternalMessageInfo
+
+func (m *DownloadDeltaRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *DownloadDeltaRequest) GetBucket() string {
+	if m != nil {
+		return m.Bucket
+	}
+	return ""
+}
+
+func (m *DownloadDeltaRequest) GetBaseVersionId() string {
+	if m != nil {
+		return m.BaseVersionId
+	}
+	return ""
+}
+
+func (m *DownloadDeltaRequest) GetTargetVersionId() string {
+	if m != nil {
+		return m.TargetVersionId
+	}
+	return ""
+}
+
+func (m *DownloadDeltaRequest) GetBlockSize() int64 {
+	if m != nil {
+		return m.BlockSize
+	}
+	return 0
+}
+
+// DeltaCopy is a range of bytes of the base version to copy to the target version.
+type DeltaCopy struct {
+	// Offset of the range's first byte in the base version
+	Offset int64 `protobuf:"varint,1,opt,name=offset,proto3" json:"offset,omitempty"`
+	// Number of bytes in the range
+	Length               int64    `protobuf:"varint,2,opt,name=length,proto3" json:"length,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeltaCopy) Reset()         { *m = DeltaCopy{} }
+func (m *DeltaCopy) String() string { return proto.CompactTextString(m) }
+func (*DeltaCopy) ProtoMessage()    {}
+func (*DeltaCopy) Descriptor() ([]byte, []int) {
+	return fileDescriptor_download_service_1710258a4f1cb257, []int{17}
+}
+func (m *DeltaCopy) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeltaCopy.Unmarshal(m, b)
+}
+func (m *DeltaCopy) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeltaCopy.Marshal(b, m, deterministic)
+}
+func (dst *DeltaCopy) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeltaCopy.Merge(dst, src)
+}
+func (m *DeltaCopy) XXX_Size() int {
+	return xxx_messageInfo_DeltaCopy.Size(m)
+}
+func (m *DeltaCopy) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeltaCopy.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeltaCopy proto.InternalMessageInfo
+
+func (m *DeltaCopy) GetOffset() int64 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+func (m *DeltaCopy) GetLength() int64 {
+	if m != nil {
+		return m.Length
+	}
+	return 0
+}
+
+// DeltaChunk is the response type of the delta between two versions of a file.
+// The target version is the concatenation of the chunks, in order.
+type DeltaChunk struct {
+	// Types that are valid to be assigned to Op:
+	//	*DeltaChunk_Copy
+	//	*DeltaChunk_Data
+	Op                   isDeltaChunk_Op `protobuf_oneof:"op"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *DeltaChunk) Reset()         { *m = DeltaChunk{} }
+func (m *DeltaChunk) String() string { return proto.CompactTextString(m) }
+func (*DeltaChunk) ProtoMessage()    {}
+func (*DeltaChunk) Descriptor() ([]byte, []int) {
+	return fileDescriptor_download_service_1710258a4f1cb257, []int{18}
+}
+func (m *DeltaChunk) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeltaChunk.Unmarshal(m, b)
+}
+func (m *DeltaChunk) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeltaChunk.Marshal(b, m, deterministic)
+}
+func (dst *DeltaChunk) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeltaChunk.Merge(dst, src)
+}
+func (m *DeltaChunk) XXX_Size() int {
+	return xxx_messageInfo_DeltaChunk.Size(m)
+}
+func (m *DeltaChunk) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeltaChunk.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeltaChunk proto.InternalMessageInfo
+
+type isDeltaChunk_Op interface {
+	isDeltaChunk_Op()
+}
+
+type DeltaChunk_Copy struct {
+	Copy *DeltaCopy `protobuf:"bytes,1,opt,name=copy,proto3,oneof"`
+}
+
+type DeltaChunk_Data struct {
+	Data []byte `protobuf:"bytes,2,opt,name=data,proto3,oneof"`
+}
+
+func (*DeltaChunk_Copy) isDeltaChunk_Op() {}
+
+func (*DeltaChunk_Data) isDeltaChunk_Op() {}
+
+func (m *DeltaChunk) GetOp() isDeltaChunk_Op {
+	if m != nil {
+		return m.Op
+	}
+	return nil
+}
+
+func (m *DeltaChunk) GetCopy() *DeltaCopy {
+	if x, ok := m.GetOp().(*DeltaChunk_Copy); ok {
+		return x.Copy
+	}
+	return nil
+}
+
+func (m *DeltaChunk) GetData() []byte {
+	if x, ok := m.GetOp().(*DeltaChunk_Data); ok {
+		return x.Data
+	}
+	return nil
+}
+
+// XXX_OneofFuncs is for the internal use of the proto package.
+func (*DeltaChunk) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
+	return _DeltaChunk_OneofMarshaler, _DeltaChunk_OneofUnmarshaler, _DeltaChunk_OneofSizer, []interface{}{
+		(*DeltaChunk_Copy)(nil),
+		(*DeltaChunk_Data)(nil),
+	}
+}
+
+func _DeltaChunk_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
+	m := msg.(*DeltaChunk)
+	// op
+	switch x := m.Op.(type) {
+	case *DeltaChunk_Copy:
+		b.EncodeVarint(1<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Copy); err != nil {
+			return err
+		}
+	case *DeltaChunk_Data:
+		b.EncodeVarint(2<<3 | proto.WireBytes)
+		b.EncodeRawBytes(x.Data)
+	case nil:
+	default:
+		return fmt.Errorf("DeltaChunk.Op has unexpected type %T", x)
+	}
+	return nil
+}
+
+func _DeltaChunk_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error) {
+	m := msg.(*DeltaChunk)
+	switch tag {
+	case 1: // op.copy
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(DeltaCopy)
+		err := b.DecodeMessage(msg)
+		m.Op = &DeltaChunk_Copy{msg}
+		return true, err
+	case 2: // op.data
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeRawBytes(true)
+		m.Op = &DeltaChunk_Data{x}
+		return true, err
+	default:
+		return false, nil
+	}
+}
+
+func _DeltaChunk_OneofSizer(msg proto.Message) (n int) {
+	m := msg.(*DeltaChunk)
+	// op
+	switch x := m.Op.(type) {
+	case *DeltaChunk_Copy:
+		s := proto.Size(x.Copy)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *DeltaChunk_Data:
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(len(x.Data)))
+		n += len(x.Data)
+	case nil:
+	default:
+		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
+	}
+	return n
+}
+
+// DownloadConcatenatedRequest is the request type of the download of a file
+// that is split across multiple objects, as a single stream of its bytes.
+type DownloadConcatenatedRequest struct {
+	// File keys to download from S3, in the order of their bytes in the file
+	Keys []string `protobuf:"bytes,1,rep,name=keys,proto3" json:"keys,omitempty"`
+	// The bucket to download the files from
+	Bucket               string   `protobuf:"bytes,2,opt,name=bucket,proto3" json:"bucket,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DownloadConcatenatedRequest) Reset()         { *m = DownloadConcatenatedRequest{} }
+func (m *DownloadConcatenatedRequest) String() string { return proto.CompactTextString(m) }
+func (*DownloadConcatenatedRequest) ProtoMessage()    {}
+func (*DownloadConcatenatedRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_download_service_1710258a4f1cb257, []int{19}
+}
+func (m *DownloadConcatenatedRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DownloadConcatenatedRequest.Unmarshal(m, b)
+}
+func (m *DownloadConcatenatedRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DownloadConcatenatedRequest.Marshal(b, m, deterministic)
+}
+func (dst *DownloadConcatenatedRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DownloadConcatenatedRequest.Merge(dst, src)
+}
+func (m *DownloadConcatenatedRequest) XXX_Size() int {
+	return xxx_messageInfo_DownloadConcatenatedRequest.Size(m)
+}
+func (m *DownloadConcatenatedRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DownloadConcatenatedRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DownloadConcatenatedRequest proto.InternalMessageInfo
+
+func (m *DownloadConcatenatedRequest) GetKeys() []string {
+	if m != nil {
+		return m.Keys
+	}
+	return nil
+}
+
+func (m *DownloadConcatenatedRequest) GetBucket() string {
+	if m != nil {
+		return m.Bucket
+	}
+	return ""
+}
+
+// GetStatsRequest is the request type of the download statistics.
+type GetStatsRequest struct {
+	// Reset the statistics after reading them
+	ResetOnRead          bool     `protobuf:"varint,1,opt,name=reset_on_read,json=resetOnRead,proto3" json:"reset_on_read,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetStatsRequest) Reset()         { *m = GetStatsRequest{} }
+func (m *GetStatsRequest) String() string { return proto.CompactTextString(m) }
+func (*GetStatsRequest) ProtoMessage()    {}
+func (*GetStatsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_download_service_1710258a4f1cb257, []int{20}
+}
+func (m *GetStatsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetStatsRequest.Unmarshal(m, b)
+}
+func (m *GetStatsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetStatsRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetStatsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetStatsRequest.Merge(dst, src)
+}
+func (m *GetStatsRequest) XXX_Size() int {
+	return xxx_messageInfo_GetStatsRequest.Size(m)
+}
+func (m *GetStatsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetStatsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetStatsRequest proto.InternalMessageInfo
+
+func (m *GetStatsRequest) GetResetOnRead() bool {
+	if m != nil {
+		return m.ResetOnRead
+	}
+	return false
+}
+
+// LatencySummary holds estimated percentiles of observed latencies.
+type LatencySummary struct {
+	// Number of observed latencies
+	Count int64 `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	// Estimated 50th percentile in milliseconds
+	P50Ms float64 `protobuf:"fixed64,2,opt,name=p50_ms,json=p50Ms,proto3" json:"p50_ms,omitempty"`
+	// Estimated 95th percentile in milliseconds
+	P95Ms float64 `protobuf:"fixed64,3,opt,name=p95_ms,json=p95Ms,proto3" json:"p95_ms,omitempty"`
+	// Estimated 99th percentile in milliseconds
+	P99Ms float64 `protobuf:"fixed64,4,opt,name=p99_ms,json=p99Ms,proto3" json:"p99_ms,omitempty"`
+	// The slowest observed latency with a trace id, to jump from the latency
+	// to the trace of its request, unset if none was traced
+	SlowestTraced        *TracedLatency `protobuf:"bytes,5,opt,name=slowest_traced,json=slowestTraced,proto3" json:"slowest_traced,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *LatencySummary) Reset()         { *m = LatencySummary{} }
+func (m *LatencySummary) String() string { return proto.CompactTextString(m) }
+func (*LatencySummary) ProtoMessage()    {}
+func (*LatencySummary) Descriptor() ([]byte, []int) {
+	return fileDescriptor_download_service_1710258a4f1cb257, []int{21}
+}
+func (m *LatencySummary) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LatencySummary.Unmarshal(m, b)
+}
+func (m *LatencySummary) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LatencySummary.Marshal(b, m, deterministic)
+}
+func (dst *LatencySummary) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LatencySummary.Merge(dst, src)
+}
+func (m *LatencySummary) XXX_Size() int {
+	return xxx_messageInfo_LatencySummary.Size(m)
+}
+func (m *LatencySummary) XXX_DiscardUnknown() {
+	xxx_messageInfo_LatencySummary.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LatencySummary proto.InternalMessageInfo
+
+func (m *LatencySummary) GetCount() int64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *LatencySummary) GetP50Ms() float64 {
+	if m != nil {
+		return m.P50Ms
+	}
+	return 0
+}
+
+func (m *LatencySummary) GetP95Ms() float64 {
+	if m != nil {
+		return m.P95Ms
+	}
+	return 0
+}
+
+func (m *LatencySummary) GetP99Ms() float64 {
+	if m != nil {
+		return m.P99Ms
+	}
+	return 0
+}
+
+func (m *LatencySummary) GetSlowestTraced() *TracedLatency {
+	if m != nil {
+		return m.SlowestTraced
+	}
+	return nil
+}
+
+// TracedLatency is an observed latency and the trace of its request.
+type TracedLatency struct {
+	// The observed latency in milliseconds
+	LatencyMs float64 `protobuf:"fixed64,1,opt,name=latency_ms,json=latencyMs,proto3" json:"latency_ms,omitempty"`
+	// Trace id of the request the latency was observed in
+	TraceId string `protobuf:"bytes,2,opt,name=trace_id,json=traceId,proto3" json:"trace_id,omitempty"`
+	// Time the latency was observed at, in Unix milliseconds
+	TimestampMs          int64    `protobuf:"varint,3,opt,name=timestamp_ms,json=timestampMs,proto3" json:"timestamp_ms,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TracedLatency) Reset()         { *m = TracedLatency{} }
+func (m *TracedLatency) String() string { return proto.CompactTextString(m) }
+func (*TracedLatency) ProtoMessage()    {}
+func (*TracedLatency) Descriptor() ([]byte, []int) {
+	return fileDescriptor_download_service_1710258a4f1cb257, []int{22}
+}
+func (m *TracedLatency) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TracedLatency.Unmarshal(m, b)
+}
+func (m *TracedLatency) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TracedLatency.Marshal(b, m, deterministic)
+}
+func (dst *TracedLatency) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TracedLatency.Merge(dst, src)
+}
+func (m *TracedLatency) XXX_Size() int {
+	return xxx_messageInfo_TracedLatency.Size(m)
+}
+func (m *TracedLatency) XXX_DiscardUnknown() {
+	xxx_messageInfo_TracedLatency.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TracedLatency proto.InternalMessageInfo
+
+func (m *TracedLatency) GetLatencyMs() float64 {
+	if m != nil {
+		return m.LatencyMs
+	}
+	return 0
+}
+
+func (m *TracedLatency) GetTraceId() string {
+	if m != nil {
+		return m.TraceId
+	}
+	return ""
+}
+
+func (m *TracedLatency) GetTimestampMs() int64 {
+	if m != nil {
+		return m.TimestampMs
+	}
+	return 0
+}
+
+// GetStatsResponse is the response type of the download statistics.
+type GetStatsResponse struct {
+	// Latency of whole downloads
+	DownloadLatency *LatencySummary `protobuf:"bytes,1,opt,name=download_latency,json=downloadLatency,proto3" json:"download_latency,omitempty"`
+	// Latency of fetching a single part from S3
+	PartLatency *LatencySummary `protobuf:"bytes,2,opt,name=part_latency,json=partLatency,proto3" json:"part_latency,omitempty"`
+	// Number of downloads currently being served
+	ActiveDownloads int64 `protobuf:"varint,3,opt,name=active_downloads,json=activeDownloads,proto3" json:"active_downloads,omitempty"`
+	// Whether new downloads are being rejected to shed load
+	Shedding bool `protobuf:"varint,4,opt,name=shedding,proto3" json:"shedding,omitempty"`
+	// The egress quota of the current window, unset if egress isn't limited
+	EgressQuota *EgressQuota `protobuf:"bytes,5,opt,name=egress_quota,json=egressQuota,proto3" json:"egress_quota,omitempty"`
+	// The buffer of the logs shipped asynchronously, unset if logs are shipped synchronously
+	LogBuffer *LogBufferStats `protobuf:"bytes,6,opt,name=log_buffer,json=logBuffer,proto3" json:"log_buffer,omitempty"`
+	// The health probe of S3, unset if the server doesn't probe S3
+	S3Probe *ProbeStats `protobuf:"bytes,7,opt,name=s3_probe,json=s3Probe,proto3" json:"s3_probe,omitempty"`
+	// Number of downloads failed over to a replica of their bucket, by the replica's region
+	FailoversByRegion    map[string]int64 `protobuf:"bytes,8,rep,name=failovers_by_region,json=failoversByRegion,proto3" json:"failovers_by_region,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *GetStatsResponse) Reset()         { *m = GetStatsResponse{} }
+func (m *GetStatsResponse) String() string { return proto.CompactTextString(m) }
+func (*GetStatsResponse) ProtoMessage()    {}
+func (*GetStatsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_download_service_1710258a4f1cb257, []int{23}
+}
+func (m *GetStatsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetStatsResponse.Unmarshal(m, b)
+}
+func (m *GetStatsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetStatsResponse.Marshal(b, m, deterministic)
+}
+func (dst *GetStatsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetStatsResponse.Merge(dst, src)
+}
+func (m *GetStatsResponse) XXX_Size() int {
+	return xxx_messageInfo_GetStatsResponse.Size(m)
+}
+func (m *GetStatsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetStatsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetStatsResponse proto.InternalMessageInfo
+
+func (m *GetStatsResponse) GetDownloadLatency() *LatencySummary {
+	if m != nil {
+		return m.DownloadLatency
+	}
+	return nil
+}
+
+func (m *GetStatsResponse) GetPartLatency() *LatencySummary {
+	if m != nil {
+		return m.PartLatency
+	}
+	return nil
+}
+
+func (m *GetStatsResponse) GetActiveDownloads() int64 {
+	if m != nil {
+		return m.ActiveDownloads
+	}
+	return 0
+}
+
+func (m *GetStatsResponse) GetShedding() bool {
+	if m != nil {
+		return m.Shedding
+	}
+	return false
+}
+
+func (m *GetStatsResponse) GetEgressQuota() *EgressQuota {
+	if m != nil {
+		return m.EgressQuota
+	}
+	return nil
+}
+
+func (m *GetStatsResponse) GetLogBuffer() *LogBufferStats {
+	if m != nil {
+		return m.LogBuffer
+	}
+	return nil
+}
+
+func (m *GetStatsResponse) GetS3Probe() *ProbeStats {
+	if m != nil {
+		return m.S3Probe
+	}
+	return nil
+}
+
+func (m *GetStatsResponse) GetFailoversByRegion() map[string]int64 {
+	if m != nil {
+		return m.FailoversByRegion
+	}
+	return nil
+}
+
+// ProbeStats describes the latency of the health probe of a dependency.
+type ProbeStats struct {
+	// Latency of a single probe
+	Latency *LatencySummary `protobuf:"bytes,1,opt,name=latency,proto3" json:"latency,omitempty"`
+	// Average latency of the latest probes in milliseconds
+	RecentLatencyMs float64 `protobuf:"fixed64,2,opt,name=recent_latency_ms,json=recentLatencyMs,proto3" json:"recent_latency_ms,omitempty"`
+	// Whether the average latency of the latest probes exceeds the degraded threshold,
+	// though the probes succeed
+	Degraded             bool     `protobuf:"varint,3,opt,name=degraded,proto3" json:"degraded,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ProbeStats) Reset()         { *m = ProbeStats{} }
+func (m *ProbeStats) String() string { return proto.CompactTextString(m) }
+func (*ProbeStats) ProtoMessage()    {}
+func (*ProbeStats) Descriptor() ([]byte, []int) {
+	return fileDescriptor_download_service_1710258a4f1cb257, []int{24}
+}
+func (m *ProbeStats) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ProbeStats.Unmarshal(m, b)
+}
+func (m *ProbeStats) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ProbeStats.Marshal(b, m, deterministic)
+}
+func (dst *ProbeStats) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ProbeStats.Merge(dst, src)
+}
+func (m *ProbeStats) XXX_Size() int {
+	return xxx_messageInfo_ProbeStats.Size(m)
+}
+func (m *ProbeStats) XXX_DiscardUnknown() {
+	xxx_messageInfo_ProbeStats.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ProbeStats proto.InternalMessageInfo
+
+func (m *ProbeStats) GetLatency() *LatencySummary {
+	if m != nil {
+		return m.Latency
+	}
+	return nil
+}
+
+func (m *ProbeStats) GetRecentLatencyMs() float64 {
+	if m != nil {
+		return m.RecentLatencyMs
+	}
+	return 0
+}
+
+func (m *ProbeStats) GetDegraded() bool {
+	if m != nil {
+		return m.Degraded
+	}
+	return false
+}
+
+// LogBufferStats describes the buffer of the logs shipped asynchronously to Elasticsearch.
+type LogBufferStats struct {
+	// Log entries waiting in the buffer to be shipped
+	Depth int64 `protobuf:"varint,1,opt,name=depth,proto3" json:"depth,omitempty"`
+	// Log entries the buffer holds at most
+	Capacity int64 `protobuf:"varint,2,opt,name=capacity,proto3" json:"capacity,omitempty"`
+	// Log entries dropped since the buffer was full
+	Dropped int64 `protobuf:"varint,3,opt,name=dropped,proto3" json:"dropped,omitempty"`
+	// Latency of shipping a single log entry
+	FlushLatency         *LatencySummary `protobuf:"bytes,4,opt,name=flush_latency,json=flushLatency,proto3" json:"flush_latency,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *LogBufferStats) Reset()         { *m = LogBufferStats{} }
+func (m *LogBufferStats) String() string { return proto.CompactTextString(m) }
+func (*LogBufferStats) ProtoMessage()    {}
+func (*LogBufferStats) Descriptor() ([]byte, []int) {
+	return fileDescriptor_download_service_1710258a4f1cb257, []int{25}
+}
+func (m *LogBufferStats) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LogBufferStats.Unmarshal(m, b)
+}
+func (m *LogBufferStats) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LogBufferStats.Marshal(b, m, deterministic)
+}
+func (dst *LogBufferStats) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LogBufferStats.Merge(dst, src)
+}
+func (m *LogBufferStats) XXX_Size() int {
+	return xxx_messageInfo_LogBufferStats.Size(m)
+}
+func (m *LogBufferStats) XXX_DiscardUnknown() {
+	xxx_messageInfo_LogBufferStats.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LogBufferStats proto.InternalMessageInfo
+
+func (m *LogBufferStats) GetDepth() int64 {
+	if m != nil {
+		return m.Depth
+	}
+	return 0
+}
+
+func (m *LogBufferStats) GetCapacity() int64 {
+	if m != nil {
+		return m.Capacity
+	}
+	return 0
+}
+
+func (m *LogBufferStats) GetDropped() int64 {
+	if m != nil {
+		return m.Dropped
+	}
+	return 0
+}
+
+func (m *LogBufferStats) GetFlushLatency() *LatencySummary {
+	if m != nil {
+		return m.FlushLatency
+	}
+	return nil
+}
+
+// GetEgressQuotaRequest is the request type of the egress quota.
+type GetEgressQuotaRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetEgressQuotaRequest) Reset()         { *m = GetEgressQuotaRequest{} }
+func (m *GetEgressQuotaRequest) String() string { return proto.CompactTextString(m) }
+func (*GetEgressQuotaRequest) ProtoMessage()    {}
+func (*GetEgressQuotaRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_download_service_1710258a4f1cb257, []int{26}
+}
+func (m *GetEgressQuotaRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetEgressQuotaRequest.Unmarshal(m, b)
+}
+func (m *GetEgressQuotaRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetEgressQuotaRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetEgressQuotaRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetEgressQuotaRequest.Merge(dst, src)
+}
+func (m *GetEgressQuotaRequest) XXX_Size() int {
+	return xxx_messageInfo_GetEgressQuotaRequest.Size(m)
+}
+func (m *GetEgressQuotaRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetEgressQuotaRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetEgressQuotaRequest proto.InternalMessageInfo
+
+// EgressQuota is the quota of bytes the server may serve in the current window.
+type EgressQuota struct {
+	// Bytes the server may serve per window
+	LimitBytes int64 `protobuf:"varint,1,opt,name=limit_bytes,json=limitBytes,proto3" json:"limit_bytes,omitempty"`
+	// Bytes served in the current window
+	UsedBytes int64 `protobuf:"varint,2,opt,name=used_bytes,json=usedBytes,proto3" json:"used_bytes,omitempty"`
+	// Bytes left to serve in the current window, new downloads are rejected
+	// with RESOURCE_EXHAUSTED once none are left
+	RemainingBytes int64 `protobuf:"varint,3,opt,name=remaining_bytes,json=remainingBytes,proto3" json:"remaining_bytes,omitempty"`
+	// Milliseconds until the current window ends and the quota resets
+	ResetsInMs           int64    `protobuf:"varint,4,opt,name=resets_in_ms,json=resetsInMs,proto3" json:"resets_in_ms,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *EgressQuota) Reset()         { *m = EgressQuota{} }
+func (m *EgressQuota) String() string { return proto.CompactTextString(m) }
+func (*EgressQuota) ProtoMessage()    {}
+func (*EgressQuota) Descriptor() ([]byte, []int) {
+	return fileDescriptor_download_service_1710258a4f1cb257, []int{27}
+}
+func (m *EgressQuota) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_EgressQuota.Unmarshal(m, b)
+}
+func (m *EgressQuota) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_EgressQuota.Marshal(b, m, deterministic)
+}
+func (dst *EgressQuota) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EgressQuota.Merge(dst, src)
+}
+func (m *EgressQuota) XXX_Size() int {
+	return xxx_messageInfo_EgressQuota.Size(m)
+}
+func (m *EgressQuota) XXX_DiscardUnknown() {
+	xxx_messageInfo_EgressQuota.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EgressQuota proto.InternalMessageInfo
+
+func (m *EgressQuota) GetLimitBytes() int64 {
+	if m != nil {
+		return m.LimitBytes
+	}
+	return 0
+}
+
+func (m *EgressQuota) GetUsedBytes() int64 {
+	if m != nil {
+		return m.UsedBytes
+	}
+	return 0
+}
+
+func (m *EgressQuota) GetRemainingBytes() int64 {
+	if m != nil {
+		return m.RemainingBytes
+	}
+	return 0
+}
+
+func (m *EgressQuota) GetResetsInMs() int64 {
+	if m != nil {
+		return m.ResetsInMs
+	}
+	return 0
+}
+
+// WatchAuditEventsRequest is the request type of the live feed of download audit events.
+type WatchAuditEventsRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WatchAuditEventsRequest) Reset()         { *m = WatchAuditEventsRequest{} }
+func (m *WatchAuditEventsRequest) String() string { return proto.CompactTextString(m) }
+func (*WatchAuditEventsRequest) ProtoMessage()    {}
+func (*WatchAuditEventsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_download_service_1710258a4f1cb257, []int{28}
+}
+func (m *WatchAuditEventsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WatchAuditEventsRequest.Unmarshal(m, b)
+}
+func (m *WatchAuditEventsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WatchAuditEventsRequest.Marshal(b, m, deterministic)
+}
+func (dst *WatchAuditEventsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WatchAuditEventsRequest.Merge(dst, src)
+}
+func (m *WatchAuditEventsRequest) XXX_Size() int {
+	return xxx_messageInfo_WatchAuditEventsRequest.Size(m)
+}
+func (m *WatchAuditEventsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_WatchAuditEventsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WatchAuditEventsRequest proto.InternalMessageInfo
+
+// AuditEvent describes a finished download, for security tooling.
+type AuditEvent struct {
+	// The authenticated subject that downloaded the file, empty if unauthenticated
+	Subject string `protobuf:"bytes,1,opt,name=subject,proto3" json:"subject,omitempty"`
+	// The bucket the file was downloaded from
+	Bucket string `protobuf:"bytes,2,opt,name=bucket,proto3" json:"bucket,omitempty"`
+	// The downloaded file's key
+	Key string `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	// Number of file bytes sent
+	Bytes int64 `protobuf:"varint,4,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	// The gRPC code the download ended with, e.g. "OK" or "PermissionDenied"
+	Outcome string `protobuf:"bytes,5,opt,name=outcome,proto3" json:"outcome,omitempty"`
+	// Unix time in milliseconds at which the download finished
+	TimestampMs int64 `protobuf:"varint,6,opt,name=timestamp_ms,json=timestampMs,proto3" json:"timestamp_ms,omitempty"`
+	// The download's trace id, empty if it wasn't traced
+	TraceId              string   `protobuf:"bytes,7,opt,name=trace_id,json=traceId,proto3" json:"trace_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AuditEvent) Reset()         { *m = AuditEvent{} }
+func (m *AuditEvent) String() string { return proto.CompactTextString(m) }
+func (*AuditEvent) ProtoMessage()    {}
+func (*AuditEvent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_download_service_1710258a4f1cb257, []int{29}
+}
+func (m *AuditEvent) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AuditEvent.Unmarshal(m, b)
+}
+func (m *AuditEvent) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AuditEvent.Marshal(b, m, deterministic)
+}
+func (dst *AuditEvent) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AuditEvent.Merge(dst, src)
+}
+func (m *AuditEvent) XXX_Size() int {
+	return xxx_messageInfo_AuditEvent.Size(m)
+}
+func (m *AuditEvent) XXX_DiscardUnknown() {
+	xxx_messageInfo_AuditEvent.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AuditEvent proto.InternalMessageInfo
+
+func (m *AuditEvent) GetSubject() string {
+	if m != nil {
+		return m.Subject
+	}
+	return ""
+}
+
+func (m *AuditEvent) GetBucket() string {
+	if m != nil {
+		return m.Bucket
+	}
+	return ""
+}
+
+func (m *AuditEvent) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *AuditEvent) GetBytes() int64 {
+	if m != nil {
+		return m.Bytes
+	}
+	return 0
+}
+
+func (m *AuditEvent) GetOutcome() string {
+	if m != nil {
+		return m.Outcome
+	}
+	return ""
+}
+
+func (m *AuditEvent) GetTimestampMs() int64 {
+	if m != nil {
+		return m.TimestampMs
+	}
+	return 0
+}
+
+func (m *AuditEvent) GetTraceId() string {
+	if m != nil {
+		return m.TraceId
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterType((*DownloadRequest)(nil), "download.DownloadRequest")
+	proto.RegisterType((*RangePercent)(nil), "download.RangePercent")
+	proto.RegisterType((*DownloadResponse)(nil), "download.DownloadResponse")
+	proto.RegisterType((*DownloadProgress)(nil), "download.DownloadProgress")
+	proto.RegisterType((*DownloadMetadata)(nil), "download.DownloadMetadata")
+	proto.RegisterType((*RangeHints)(nil), "download.RangeHints")
+	proto.RegisterType((*GetMetadataRequest)(nil), "download.GetMetadataRequest")
+	proto.RegisterType((*GetPresignedURLRequest)(nil), "download.GetPresignedURLRequest")
+	proto.RegisterType((*PresignedURL)(nil), "download.PresignedURL")
+	proto.RegisterType((*DownloadFailure)(nil), "download.DownloadFailure")
+	proto.RegisterType((*ListObjectsRequest)(nil), "download.ListObjectsRequest")
+	proto.RegisterType((*ObjectInfo)(nil), "download.ObjectInfo")
+	proto.RegisterType((*ListObjectsResponse)(nil), "download.ListObjectsResponse")
+	proto.RegisterType((*GetDownloadManifestRequest)(nil), "download.GetDownloadManifestRequest")
+	proto.RegisterType((*ManifestPart)(nil), "download.ManifestPart")
+	proto.RegisterType((*DownloadManifest)(nil), "download.DownloadManifest")
+	proto.RegisterType((*DownloadDeltaRequest)(nil), "download.DownloadDeltaRequest")
+	proto.RegisterType((*DeltaCopy)(nil), "download.DeltaCopy")
+	proto.RegisterType((*DeltaChunk)(nil), "download.DeltaChunk")
+	proto.RegisterType((*DownloadConcatenatedRequest)(nil), "download.DownloadConcatenatedRequest")
+	proto.RegisterType((*GetStatsRequest)(nil), "download.GetStatsRequest")
+	proto.RegisterType((*LatencySummary)(nil), "download.LatencySummary")
+	proto.RegisterType((*TracedLatency)(nil), "download.TracedLatency")
+	proto.RegisterType((*GetStatsResponse)(nil), "download.GetStatsResponse")
+	proto.RegisterMapType((map[string]int64)(nil), "download.GetStatsResponse.FailoversByRegionEntry")
+	proto.RegisterType((*ProbeStats)(nil), "download.ProbeStats")
+	proto.RegisterType((*LogBufferStats)(nil), "download.LogBufferStats")
+	proto.RegisterType((*GetEgressQuotaRequest)(nil), "download.GetEgressQuotaRequest")
+	proto.RegisterType((*EgressQuota)(nil), "download.EgressQuota")
+	proto.RegisterType((*WatchAuditEventsRequest)(nil), "download.WatchAuditEventsRequest")
+	proto.RegisterType((*AuditEvent)(nil), "download.AuditEvent")
+	proto.RegisterEnum("download.ChecksumAlgorithm", ChecksumAlgorithm_name, ChecksumAlgorithm_value)
+	proto.RegisterEnum("download.QoSClass", QoSClass_name, QoSClass_value)
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// DownloadClient is the client API for Download service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type DownloadClient interface {
+	Download(ctx context.Context, in *DownloadRequest, opts ...grpc.CallOption) (Download_DownloadClient, error)
+	ListObjects(ctx context.Context, in *ListObjectsRequest, opts ...grpc.CallOption) (*ListObjectsResponse, error)
+	GetDownloadManifest(ctx context.Context, in *GetDownloadManifestRequest, opts ...grpc.CallOption) (*DownloadManifest, error)
+	DownloadDelta(ctx context.Context, in *DownloadDeltaRequest, opts ...grpc.CallOption) (Download_DownloadDeltaClient, error)
+	DownloadConcatenated(ctx context.Context, in *DownloadConcatenatedRequest, opts ...grpc.CallOption) (Download_DownloadConcatenatedClient, error)
+	GetMetadata(ctx context.Context, in *GetMetadataRequest, opts ...grpc.CallOption) (*DownloadMetadata, error)
+	GetPresignedURL(ctx context.Context, in *GetPresignedURLRequest, opts ...grpc.CallOption) (*PresignedURL, error)
+}
+
+type downloadClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewDownloadClient(cc *grpc.ClientConn) DownloadClient {
+	return &downloadClient{cc}
+}
+
+func (c *downloadClient) Download(ctx context.Context, in *DownloadRequest, opts ...grpc.CallOption) (Download_DownloadClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Download_serviceDesc.Streams[0], "/download.Download/Download", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &downloadDownloadClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Download_DownloadClient interface {
+	Recv() (*DownloadResponse, error)
+	grpc.ClientStream
+}
+
+type downloadDownloadClient struct {
+	grpc.ClientStream
+}
+
+func (x *downloadDownloadClient) Recv() (*DownloadResponse, error) {
+	m := new(DownloadResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *downloadClient) ListObjects(ctx context.Context, in *ListObjectsRequest, opts ...grpc.CallOption) (*ListObjectsResponse, error) {
+	out := new(ListObjectsResponse)
+	err := c.cc.Invoke(ctx, "/download.Download/ListObjects", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *downloadClient) GetDownloadManifest(ctx context.Context, in *GetDownloadManifestRequest, opts ...grpc.CallOption) (*DownloadManifest, error) {
+	out := new(DownloadManifest)
+	err := c.cc.Invoke(ctx, "/download.Download/GetDownloadManifest", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *downloadClient) DownloadDelta(ctx context.Context, in *DownloadDeltaRequest, opts ...grpc.CallOption) (Download_DownloadDeltaClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Download_serviceDesc.Streams[1], "/download.Download/DownloadDelta", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &downloadDownloadDeltaClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Download_DownloadDeltaClient interface {
+	Recv() (*DeltaChunk, error)
+	grpc.ClientStream
+}
+
+type downloadDownloadDeltaClient struct {
+	grpc.ClientStream
+}
+
+func (x *downloadDownloadDeltaClient) Recv() (*DeltaChunk, error) {
+	m := new(DeltaChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *downloadClient) DownloadConcatenated(ctx context.Context, in *DownloadConcatenatedRequest, opts ...grpc.CallOption) (Download_DownloadConcatenatedClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Download_serviceDesc.Streams[2], "/download.Download/DownloadConcatenated", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &downloadDownloadConcatenatedClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Download_DownloadConcatenatedClient interface {
+	Recv() (*DownloadResponse, error)
+	grpc.ClientStream
+}
+
+type downloadDownloadConcatenatedClient struct {
+	grpc.ClientStream
+}
+
+func (x *downloadDownloadConcatenatedClient) Recv() (*DownloadResponse, error) {
+	m := new(DownloadResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *downloadClient) GetMetadata(ctx context.Context, in *GetMetadataRequest, opts ...grpc.CallOption) (*DownloadMetadata, error) {
+	out := new(DownloadMetadata)
+	err := c.cc.Invoke(ctx, "/download.Download/GetMetadata", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *downloadClient) GetPresignedURL(ctx context.Context, in *GetPresignedURLRequest, opts ...grpc.CallOption) (*PresignedURL, error) {
+	out := new(PresignedURL)
+	err := c.cc.Invoke(ctx, "/download.Download/GetPresignedURL", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DownloadServer is the server API for Download service.
+type DownloadServer interface {
+	Download(*DownloadRequest, Download_DownloadServer) error
+	ListObjects(context.Context, *ListObjectsRequest) (*ListObjectsResponse, error)
+	GetDownloadManifest(context.Context, *GetDownloadManifestRequest) (*DownloadManifest, error)
+	DownloadDelta(*DownloadDeltaRequest, Download_DownloadDeltaServer) error
+	DownloadConcatenated(*DownloadConcatenatedRequest, Download_DownloadConcatenatedServer) error
+	GetMetadata(context.Context, *GetMetadataRequest) (*DownloadMetadata, error)
+	GetPresignedURL(context.Context, *GetPresignedURLRequest) (*PresignedURL, error)
+}
+
+func RegisterDownloadServer(s *grpc.Server, srv DownloadServer) {
+	s.RegisterService(&_Download_serviceDesc, srv)
+}
+
+func _Download_Download_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DownloadRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DownloadServer).Download(m, &downloadDownloadServer{stream})
+}
+
+type Download_DownloadServer interface {
+	Send(*DownloadResponse) error
+	grpc.ServerStream
+}
+
+type downloadDownloadServer struct {
+	grpc.ServerStream
+}
+
+func (x *downloadDownloadServer) Send(m *DownloadResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Download_ListObjects_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListObjectsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DownloadServer).ListObjects(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/download.Download/ListObjects",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DownloadServer).ListObjects(ctx, req.(*ListObjectsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Download_GetDownloadManifest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDownloadManifestRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DownloadServer).GetDownloadManifest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/download.Download/GetDownloadManifest",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DownloadServer).GetDownloadManifest(ctx, req.(*GetDownloadManifestRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Download_DownloadDelta_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DownloadDeltaRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DownloadServer).DownloadDelta(m, &downloadDownloadDeltaServer{stream})
+}
+
+type Download_DownloadDeltaServer interface {
+	Send(*DeltaChunk) error
+	grpc.ServerStream
+}
+
+type downloadDownloadDeltaServer struct {
+	grpc.ServerStream
+}
+
+func (x *downloadDownloadDeltaServer) Send(m *DeltaChunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Download_DownloadConcatenated_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DownloadConcatenatedRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DownloadServer).DownloadConcatenated(m, &downloadDownloadConcatenatedServer{stream})
+}
+
+type Download_DownloadConcatenatedServer interface {
+	Send(*DownloadResponse) error
+	grpc.ServerStream
+}
+
+type downloadDownloadConcatenatedServer struct {
+	grpc.ServerStream
+}
+
+func (x *downloadDownloadConcatenatedServer) Send(m *DownloadResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Download_GetMetadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMetadataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DownloadServer).GetMetadata(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/download.Download/GetMetadata",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DownloadServer).GetMetadata(ctx, req.(*GetMetadataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Download_GetPresignedURL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPresignedURLRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DownloadServer).GetPresignedURL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/download.Download/GetPresignedURL",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DownloadServer).GetPresignedURL(ctx, req.(*GetPresignedURLRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Download_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "download.Download",
+	HandlerType: (*DownloadServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListObjects",
+			Handler:    _Download_ListObjects_Handler,
+		},
+		{
+			MethodName: "GetDownloadManifest",
+			Handler:    _Download_GetDownloadManifest_Handler,
+		},
+		{
+			MethodName: "GetMetadata",
+			Handler:    _Download_GetMetadata_Handler,
+		},
+		{
+			MethodName: "GetPresignedURL",
+			Handler:    _Download_GetPresignedURL_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Download",
+			Handler:       _Download_Download_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "DownloadDelta",
+			Handler:       _Download_DownloadDelta_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "DownloadConcatenated",
+			Handler:       _Download_DownloadConcatenated_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "download_service.proto",
+}
+
+// AdminClient is the client API for Admin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type AdminClient interface {
+	GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*GetStatsResponse, error)
+	GetEgressQuota(ctx context.Context, in *GetEgressQuotaRequest, opts ...grpc.CallOption) (*EgressQuota, error)
+	WatchAuditEvents(ctx context.Context, in *WatchAuditEventsRequest, opts ...grpc.CallOption) (Admin_WatchAuditEventsClient, error)
+}
+
+type adminClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewAdminClient(cc *grpc.ClientConn) AdminClient {
+	return &adminClient{cc}
+}
+
+func (c *adminClient) GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*GetStatsResponse, error) {
+	out := new(GetStatsResponse)
+	err := c.cc.Invoke(ctx, "/download.Admin/GetStats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) GetEgressQuota(ctx context.Context, in *GetEgressQuotaRequest, opts ...grpc.CallOption) (*EgressQuota, error) {
+	out := new(EgressQuota)
+	err := c.cc.Invoke(ctx, "/download.Admin/GetEgressQuota", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) WatchAuditEvents(ctx context.Context, in *WatchAuditEventsRequest, opts ...grpc.CallOption) (Admin_WatchAuditEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Admin_serviceDesc.Streams[0], "/download.Admin/WatchAuditEvents", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &adminWatchAuditEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Admin_WatchAuditEventsClient interface {
+	Recv() (*AuditEvent, error)
+	grpc.ClientStream
+}
+
+type adminWatchAuditEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *adminWatchAuditEventsClient) Recv() (*AuditEvent, error) {
+	m := new(AuditEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// AdminServer is the server API for Admin service.
+type AdminServer interface {
+	GetStats(context.Context, *GetStatsRequest) (*GetStatsResponse, error)
+	GetEgressQuota(context.Context, *GetEgressQuotaRequest) (*EgressQuota, error)
+	WatchAuditEvents(*WatchAuditEventsRequest, Admin_WatchAuditEventsServer) error
+}
+
+func RegisterAdminServer(s *grpc.Server, srv AdminServer) {
+	s.RegisterService(&_Admin_serviceDesc, srv)
+}
+
+func _Admin_GetStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).GetStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/download.Admin/GetStats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).GetStats(ctx, req.(*GetStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_GetEgressQuota_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetEgressQuotaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).GetEgressQuota(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/download.Admin/GetEgressQuota",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).GetEgressQuota(ctx, req.(*GetEgressQuotaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_WatchAuditEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchAuditEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AdminServer).WatchAuditEvents(m, &adminWatchAuditEventsServer{stream})
+}
+
+type Admin_WatchAuditEventsServer interface {
+	Send(*AuditEvent) error
+	grpc.ServerStream
+}
+
+type adminWatchAuditEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *adminWatchAuditEventsServer) Send(m *AuditEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _Admin_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "download.Admin",
+	HandlerType: (*AdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetStats",
+			Handler:    _Admin_GetStats_Handler,
+		},
+		{
+			MethodName: "GetEgressQuota",
+			Handler:    _Admin_GetEgressQuota_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchAuditEvents",
+			Handler:       _Admin_WatchAuditEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "download_service.proto",
+}
+
+func init() {
+	proto.RegisterFile("download_service.proto", fileDescriptor_download_service_1710258a4f1cb257)
+}
+
+var fileDescriptor_download_service_1710258a4f1cb257 = []byte{
+	// 2435 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x59, 0x4f, 0x73, 0xdb, 0xc6,
+	0x15, 0x17, 0x44, 0x4a, 0x24, 0x1f, 0x29, 0x91, 0x5a, 0xcb, 0x32, 0xcc, 0xfc, 0xb1, 0x82, 0x34,
+	0x89, 0xec, 0xa6, 0x8e, 0xa3, 0x54, 0x69, 0xdc, 0xb4, 0xe9, 0xc8, 0xb2, 0x62, 0x2b, 0x16, 0x6d,
+	0x05, 0xb4, 0x9b, 0xe9, 0xa1, 0x83, 0x81, 0x80, 0x25, 0x85, 0x0a, 0xc4, 0xc2, 0xd8, 0xa5, 0x6c,
+	0xe6, 0xda, 0x4b, 0x8f, 0x3d, 0x75, 0x3a, 0xd3, 0x99, 0x9e, 0x3b, 0xd3, 0x63, 0xef, 0x39, 0xf5,
+	0x5b, 0xf4, 0x13, 0xb4, 0x5f, 0xa2, 0x9d, 0xb7, 0x7f, 0x08, 0x80, 0xa4, 0xed, 0xb8, 0x93, 0x1b,
+	0xdf, 0xef, 0xbd, 0x5d, 0xbc, 0x7d, 0x7f, 0x7e, 0xfb, 0x00, 0xc2, 0x56, 0xc8, 0x9e, 0x25, 0x31,
+	0xf3, 0x43, 0x8f, 0xd3, 0xec, 0x22, 0x0a, 0xe8, 0xcd, 0x34, 0x63, 0x82, 0x91, 0xba, 0xc1, 0x9d,
+	0x7f, 0xd6, 0xa0, 0x7d, 0x57, 0x0b, 0x2e, 0x7d, 0x3a, 0xa6, 0x5c, 0x90, 0x0e, 0x54, 0xce, 0xe9,
+	0xc4, 0xb6, 0xb6, 0xad, 0x9d, 0x86, 0x8b, 0x3f, 0xc9, 0x16, 0xac, 0x9e, 0x8e, 0x83, 0x73, 0x2a,
+	0xec, 0x65, 0x09, 0x6a, 0x89, 0x5c, 0x83, 0x66, 0xe6, 0x27, 0x43, 0xea, 0x71, 0xe1, 0x67, 0xc2,
+	0xae, 0x6c, 0x5b, 0x3b, 0x15, 0x17, 0x24, 0xd4, 0x47, 0x84, 0xbc, 0x01, 0x0d, 0x65, 0x40, 0x93,
+	0xd0, 0xae, 0x4a, 0x75, 0x5d, 0x02, 0x87, 0x49, 0x88, 0xcf, 0x19, 0x67, 0xb1, 0xbd, 0xa2, 0x9e,
+	0x33, 0xce, 0x62, 0x72, 0x15, 0xea, 0xd1, 0xc0, 0x93, 0x06, 0xf6, 0xaa, 0x84, 0x6b, 0xd1, 0xc0,
+	0x45, 0x91, 0x38, 0xb0, 0x66, 0x54, 0xde, 0xc0, 0x8f, 0x62, 0xbb, 0xb6, 0x6d, 0xed, 0xd4, 0xdd,
+	0xa6, 0xd6, 0x7f, 0xe9, 0x47, 0x31, 0xb1, 0xa1, 0x96, 0xd1, 0x0b, 0x9a, 0x71, 0x6a, 0xd7, 0xa5,
+	0xd6, 0x88, 0xe4, 0xc7, 0xb0, 0x91, 0x66, 0x6c, 0x98, 0x51, 0xce, 0xbd, 0x28, 0x11, 0x34, 0xbb,
+	0xf0, 0x63, 0xbb, 0x21, 0xfd, 0xe9, 0x18, 0xc5, 0x91, 0xc6, 0xc9, 0x75, 0x98, 0x62, 0x5e, 0x4a,
+	0xb3, 0x80, 0x26, 0xc2, 0x86, 0x6d, 0x6b, 0x67, 0xc5, 0x6d, 0x1b, 0xfc, 0x44, 0xc1, 0xda, 0xe1,
+	0x91, 0x2f, 0x82, 0x33, 0xbb, 0x69, 0x1c, 0xee, 0xa1, 0xa8, 0x1d, 0x4e, 0x58, 0x42, 0xb5, 0xbe,
+	0x25, 0xf5, 0xcd, 0x68, 0xf0, 0x90, 0x25, 0x54, 0xd9, 0xdc, 0x80, 0x0d, 0x5c, 0xce, 0xc2, 0x68,
+	0x10, 0xd1, 0xd0, 0xe3, 0x51, 0x12, 0x50, 0x7b, 0x4d, 0xda, 0xb5, 0xa3, 0x41, 0x4f, 0xe3, 0x7d,
+	0x84, 0xc9, 0x4d, 0xb8, 0x14, 0x0d, 0xbc, 0x71, 0x32, 0x63, 0xbd, 0x2e, 0xad, 0x37, 0xa2, 0xc1,
+	0x93, 0x64, 0x54, 0xb2, 0xdf, 0x82, 0xd5, 0x01, 0x8b, 0x63, 0xf6, 0xcc, 0x6e, 0xcb, 0x58, 0x68,
+	0x89, 0x7c, 0x04, 0x8d, 0xa7, 0x8c, 0x7b, 0x41, 0xec, 0x73, 0x6e, 0x77, 0xb6, 0xad, 0x9d, 0xf5,
+	0x5d, 0x72, 0xd3, 0xd4, 0xc3, 0xcd, 0xaf, 0x59, 0xff, 0x00, 0x35, 0x6e, 0xfd, 0x29, 0xe3, 0xf2,
+	0x17, 0x3e, 0x98, 0x26, 0x17, 0x34, 0x66, 0x29, 0xf5, 0xd2, 0xf1, 0x69, 0x1c, 0x05, 0x1e, 0x96,
+	0xc7, 0xc6, 0xb6, 0xb5, 0xd3, 0x72, 0x37, 0x8c, 0xea, 0x44, 0x6a, 0x1e, 0xa8, 0x62, 0x61, 0x83,
+	0x01, 0xa7, 0xc2, 0x26, 0x32, 0xc0, 0x5a, 0xc2, 0xb0, 0x46, 0x49, 0x10, 0x8f, 0x43, 0xea, 0x8d,
+	0xa8, 0xf0, 0x43, 0x5f, 0xf8, 0xf6, 0x25, 0xe9, 0x5a, 0x5b, 0xe3, 0x3d, 0x0d, 0x93, 0xaf, 0x80,
+	0x04, 0x67, 0x34, 0x38, 0xe7, 0xe3, 0x91, 0xe7, 0xc7, 0x43, 0x96, 0x45, 0xe2, 0x6c, 0x64, 0x6f,
+	0x4a, 0x67, 0xdf, 0xc8, 0x9d, 0x3d, 0xd0, 0x36, 0xfb, 0xc6, 0xc4, 0xdd, 0x08, 0x66, 0x21, 0xf2,
+	0x16, 0xc0, 0x79, 0xc2, 0x9e, 0x25, 0x1e, 0x8f, 0xbe, 0xa5, 0xf6, 0x65, 0xe9, 0x52, 0x43, 0x22,
+	0xfd, 0xe8, 0x5b, 0x8a, 0xea, 0xe0, 0x6c, 0x9c, 0x9c, 0x2b, 0xf5, 0x96, 0x52, 0x4b, 0x44, 0xaa,
+	0x3f, 0x87, 0x35, 0x55, 0x73, 0xa6, 0x10, 0xae, 0x6c, 0x5b, 0x3b, 0xcd, 0xdd, 0xad, 0xdc, 0x09,
+	0x59, 0x7e, 0xba, 0x1e, 0xdc, 0x56, 0x56, 0x90, 0x70, 0x6f, 0x2c, 0xbf, 0x88, 0x25, 0x5e, 0x14,
+	0xda, 0xb6, 0xcc, 0x54, 0x43, 0x23, 0x47, 0x21, 0x79, 0x1b, 0x20, 0xa4, 0x01, 0x1b, 0xa5, 0x58,
+	0x51, 0xf6, 0x55, 0x19, 0x8a, 0x02, 0x42, 0xae, 0xc3, 0xc6, 0xc8, 0x7f, 0xee, 0x9d, 0x4e, 0x04,
+	0x95, 0x85, 0xe8, 0x71, 0x1a, 0xd8, 0x5d, 0xe9, 0xe1, 0xfa, 0xc8, 0x7f, 0x7e, 0x07, 0xf1, 0x13,
+	0x9a, 0xf5, 0x69, 0xe0, 0x7c, 0x0a, 0xad, 0xa2, 0x1f, 0x64, 0x13, 0x56, 0x54, 0x4b, 0x62, 0x13,
+	0x5b, 0xae, 0x12, 0xb0, 0xe1, 0xb0, 0x0f, 0x97, 0x25, 0x86, 0x3f, 0x9d, 0x7f, 0x59, 0xd0, 0xc9,
+	0xdb, 0x9f, 0xa7, 0x2c, 0xe1, 0x94, 0x6c, 0x42, 0x75, 0x10, 0xc5, 0x54, 0xae, 0x6d, 0xdd, 0x5f,
+	0x72, 0xa5, 0x44, 0x3e, 0x83, 0xba, 0xa9, 0x7e, 0xb9, 0x43, 0x73, 0xb7, 0x9b, 0x07, 0xc1, 0xec,
+	0x71, 0xa2, 0x2d, 0xee, 0x2f, 0xb9, 0x53, 0x6b, 0x5c, 0x39, 0x4d, 0x78, 0xf5, 0x45, 0x2b, 0x4d,
+	0xee, 0x71, 0xa5, 0xb1, 0x26, 0x6f, 0x42, 0xdd, 0x24, 0x54, 0xd1, 0x04, 0x6a, 0x0d, 0x82, 0x87,
+	0x4c, 0x18, 0xf6, 0x40, 0x45, 0x96, 0xa2, 0x12, 0xee, 0x34, 0xa0, 0x96, 0xfa, 0x13, 0x49, 0x6e,
+	0x2e, 0x74, 0x66, 0x1d, 0xc3, 0x9c, 0xa8, 0x80, 0x72, 0xcc, 0xa6, 0xa5, 0xf2, 0x2d, 0x91, 0x3e,
+	0x06, 0xee, 0x1a, 0x34, 0x05, 0x13, 0x7e, 0xac, 0xa2, 0x2e, 0x0f, 0x5a, 0x71, 0x41, 0x42, 0x32,
+	0xde, 0xce, 0x7f, 0x2b, 0xd0, 0x99, 0xf5, 0x99, 0xbc, 0x03, 0xad, 0x80, 0x25, 0x82, 0x26, 0xc2,
+	0x13, 0x93, 0x94, 0x6a, 0xea, 0x6c, 0x6a, 0xec, 0xf1, 0x24, 0xa5, 0x84, 0x40, 0x55, 0x56, 0x98,
+	0xda, 0x51, 0xfe, 0x46, 0x8c, 0x0a, 0x7f, 0x28, 0xfd, 0x6f, 0xb8, 0xf2, 0x37, 0x79, 0x17, 0xd6,
+	0x62, 0x9f, 0x8b, 0x29, 0x29, 0x68, 0xd6, 0x6c, 0x21, 0x68, 0x08, 0x01, 0x8d, 0xb8, 0x60, 0x99,
+	0x3f, 0xa4, 0xba, 0x8f, 0x15, 0x87, 0xb6, 0x34, 0xa8, 0xfa, 0xb6, 0x5c, 0x7d, 0xab, 0xb3, 0xd5,
+	0xb7, 0x67, 0xb8, 0xfb, 0x2c, 0x4a, 0x04, 0x97, 0x74, 0xda, 0xdc, 0xdd, 0x9c, 0xa9, 0xeb, 0xfb,
+	0xa8, 0xd3, 0x8c, 0x2e, 0x7f, 0x93, 0x9f, 0xc2, 0x16, 0xde, 0x25, 0x58, 0x8d, 0x51, 0x88, 0xbc,
+	0x1e, 0x64, 0x93, 0x54, 0x44, 0x2c, 0x91, 0x94, 0xdb, 0x70, 0x37, 0x95, 0xb6, 0x1f, 0x85, 0xf4,
+	0x70, 0xaa, 0x23, 0xef, 0xc2, 0x3a, 0xe7, 0xd4, 0x3b, 0x1f, 0x71, 0xe4, 0x0e, 0xf4, 0xa7, 0xa1,
+	0x42, 0xc4, 0x39, 0x7d, 0x30, 0xe2, 0x0f, 0xe8, 0xe4, 0x28, 0x24, 0x3f, 0x59, 0xd8, 0xf5, 0xa0,
+	0x08, 0x6e, 0xbe, 0xb1, 0xbb, 0x85, 0xe2, 0x50, 0xdc, 0x3b, 0x95, 0xf1, 0xde, 0xc1, 0x68, 0x7a,
+	0xcf, 0xa8, 0x7f, 0x2e, 0x89, 0xb7, 0xee, 0xd6, 0x11, 0xf8, 0x86, 0xfa, 0xe7, 0x18, 0xbd, 0xc0,
+	0x0f, 0xce, 0xa8, 0x87, 0xf9, 0xc9, 0x58, 0xac, 0x19, 0xb7, 0x25, 0xc1, 0x03, 0x85, 0xe1, 0x5d,
+	0x42, 0x9f, 0xa7, 0x51, 0x46, 0xb9, 0xa6, 0x58, 0x23, 0x3a, 0xbf, 0xb7, 0x00, 0xf2, 0xe0, 0x20,
+	0x3d, 0xf2, 0xf1, 0x70, 0x48, 0xb9, 0xa0, 0xa1, 0x97, 0xfa, 0x99, 0x50, 0x4c, 0xa2, 0x2a, 0x6b,
+	0x63, 0xaa, 0x3a, 0xf1, 0x33, 0x21, 0x19, 0xe5, 0x43, 0x20, 0x89, 0x2f, 0xa2, 0x0b, 0x2a, 0x8d,
+	0xb9, 0x17, 0xb0, 0x71, 0x22, 0x74, 0x59, 0x74, 0x94, 0x06, 0x6d, 0xf9, 0x01, 0xe2, 0x5f, 0x55,
+	0xeb, 0x95, 0x4e, 0xd5, 0xdd, 0x28, 0xac, 0x90, 0xdb, 0x73, 0xe7, 0x8f, 0x16, 0x90, 0x7b, 0x54,
+	0x98, 0x12, 0x7c, 0xfd, 0xbb, 0x5b, 0xdf, 0xbe, 0x95, 0xfc, 0xf6, 0x2d, 0x17, 0x4c, 0x75, 0xb6,
+	0x60, 0xae, 0x95, 0x0b, 0x66, 0x45, 0xf1, 0x55, 0x5e, 0x1a, 0x4e, 0x04, 0x5b, 0xf7, 0xa8, 0x38,
+	0xc9, 0x28, 0x8f, 0x86, 0x09, 0x0d, 0x9f, 0xb8, 0xc7, 0xaf, 0xef, 0xd5, 0x7b, 0xb0, 0x2e, 0xe3,
+	0x3c, 0x41, 0xb2, 0x63, 0x49, 0xc8, 0xf5, 0x50, 0xb1, 0xa6, 0xd0, 0xbe, 0x02, 0x9d, 0x5f, 0x41,
+	0xab, 0xf8, 0x1c, 0x73, 0x18, 0xab, 0x74, 0x18, 0x9d, 0x30, 0xcf, 0x37, 0xe1, 0x6d, 0x68, 0x64,
+	0x5f, 0x38, 0xb7, 0xf2, 0xb1, 0x07, 0x47, 0x87, 0x71, 0x46, 0x5f, 0xc1, 0x0c, 0xce, 0x5f, 0x2d,
+	0x20, 0xc7, 0x11, 0x17, 0x8f, 0x4e, 0x7f, 0x47, 0x03, 0xc1, 0xcd, 0xd1, 0xf2, 0x83, 0x58, 0xa5,
+	0x83, 0x6c, 0xc1, 0x6a, 0x9a, 0xd1, 0x41, 0xf4, 0xdc, 0x1c, 0x50, 0x49, 0xe4, 0x4d, 0x68, 0x84,
+	0x34, 0x8e, 0x46, 0x91, 0xa0, 0x99, 0x0e, 0x7e, 0x0e, 0x60, 0xdd, 0xa6, 0xd8, 0xd5, 0xb2, 0x84,
+	0xf4, 0xbc, 0x84, 0x80, 0xb9, 0xaa, 0xa4, 0x52, 0xb0, 0x73, 0x9a, 0xe8, 0x96, 0x97, 0xe6, 0x8f,
+	0x11, 0x70, 0xce, 0x01, 0x94, 0x6f, 0x47, 0xc9, 0x80, 0x2d, 0x08, 0xf9, 0x0f, 0xc9, 0x40, 0xce,
+	0x9f, 0x2c, 0xb8, 0x54, 0x8a, 0x86, 0xbe, 0x3b, 0x6e, 0x42, 0x8d, 0x29, 0xc8, 0xb6, 0xb6, 0x2b,
+	0x65, 0x46, 0xc9, 0xbd, 0x73, 0x8d, 0x11, 0xf9, 0x00, 0xda, 0x01, 0x1b, 0x8d, 0x58, 0xe2, 0xa9,
+	0xf8, 0x48, 0xce, 0xad, 0xec, 0x34, 0xdc, 0x75, 0x05, 0x9f, 0x68, 0x94, 0xbc, 0x0f, 0xed, 0x84,
+	0x3e, 0x17, 0x5e, 0x21, 0x02, 0xca, 0xe9, 0x35, 0x84, 0x4f, 0xa6, 0x51, 0x18, 0x43, 0xf7, 0x1e,
+	0x15, 0x53, 0x86, 0xf6, 0x93, 0x68, 0x40, 0xb9, 0xf8, 0x21, 0xda, 0x43, 0xe6, 0xc6, 0xb4, 0xf7,
+	0x34, 0x37, 0xaa, 0xab, 0x9d, 0x2f, 0xa0, 0x65, 0x9e, 0x85, 0xdd, 0x5b, 0x18, 0x82, 0xac, 0xd2,
+	0x10, 0xb4, 0x05, 0xab, 0x31, 0x4d, 0x86, 0xe2, 0x4c, 0xa7, 0x41, 0x4b, 0xce, 0x7f, 0x96, 0x0b,
+	0xd7, 0x8a, 0xde, 0x68, 0x9a, 0x31, 0x6b, 0x41, 0xc6, 0x96, 0x0b, 0x19, 0xfb, 0x10, 0x56, 0x24,
+	0x97, 0xd8, 0x95, 0xed, 0x4a, 0x79, 0x38, 0x29, 0xfa, 0xe4, 0x2a, 0xa3, 0x97, 0x30, 0x78, 0xf5,
+	0xb5, 0x18, 0x7c, 0xe5, 0xfb, 0x32, 0xf8, 0xea, 0xf7, 0x61, 0xf0, 0xda, 0xcb, 0x18, 0xbc, 0xfe,
+	0x2a, 0x06, 0x6f, 0xbc, 0x9c, 0xc1, 0xa1, 0xcc, 0xe0, 0xff, 0xb0, 0x60, 0xd3, 0x04, 0xfb, 0x2e,
+	0x8d, 0xff, 0x1f, 0xf6, 0x7c, 0x1f, 0xda, 0xa7, 0x3e, 0xa7, 0x5e, 0x81, 0x30, 0x75, 0x39, 0x22,
+	0xfc, 0xeb, 0x29, 0x69, 0xde, 0x80, 0x0d, 0xe1, 0x67, 0x43, 0x2a, 0xbc, 0x39, 0x6a, 0x6d, 0x2b,
+	0x45, 0x6e, 0x8b, 0x04, 0x14, 0xb3, 0x40, 0x8f, 0xa2, 0x2b, 0x9a, 0x80, 0x10, 0x91, 0x25, 0xf6,
+	0x39, 0x34, 0xa4, 0xb3, 0x07, 0x2c, 0x9d, 0xbc, 0x76, 0x7d, 0xf5, 0x01, 0xd4, 0x62, 0x9c, 0x6c,
+	0xc9, 0x75, 0xa8, 0x06, 0x2c, 0x55, 0x07, 0x6d, 0xee, 0x5e, 0x2a, 0x4c, 0x63, 0xe6, 0x01, 0x38,
+	0xf6, 0xa1, 0x09, 0x0e, 0x83, 0x72, 0x70, 0x5b, 0x36, 0xc3, 0x20, 0x4a, 0x77, 0xaa, 0xb0, 0xcc,
+	0x52, 0xe7, 0x08, 0xde, 0x30, 0x61, 0x3c, 0x60, 0x49, 0xe0, 0x0b, 0x9a, 0xf8, 0x82, 0x4e, 0xdf,
+	0x23, 0x09, 0x54, 0xcf, 0xe9, 0x44, 0x11, 0x41, 0xc3, 0x95, 0xbf, 0x5f, 0x14, 0x4f, 0x67, 0x0f,
+	0xda, 0xf7, 0xa8, 0xe8, 0x0b, 0x3f, 0x67, 0x56, 0x07, 0xd6, 0x32, 0xca, 0xa9, 0xf0, 0x58, 0xe2,
+	0x65, 0xd4, 0x0f, 0xa5, 0xb7, 0x75, 0xb7, 0x29, 0xc1, 0x47, 0x89, 0x4b, 0xfd, 0xd0, 0xf9, 0xbb,
+	0x05, 0xeb, 0xc7, 0xf8, 0xdc, 0x60, 0xd2, 0x1f, 0x8f, 0x46, 0x7e, 0x86, 0x0e, 0xaf, 0xa8, 0x2b,
+	0x55, 0x05, 0x46, 0x09, 0xe4, 0x32, 0xac, 0xa6, 0x7b, 0xb7, 0xbc, 0x11, 0xd7, 0xd3, 0xef, 0x4a,
+	0xba, 0x77, 0xab, 0xc7, 0x25, 0x7c, 0x7b, 0x0f, 0xe1, 0x8a, 0x86, 0x6f, 0xef, 0x19, 0xf8, 0x36,
+	0xc2, 0x55, 0x03, 0xdf, 0xee, 0x71, 0xf2, 0x05, 0xac, 0xf3, 0x98, 0x3d, 0xa3, 0x5c, 0x78, 0x22,
+	0xf3, 0x03, 0xaa, 0x7a, 0xa0, 0xb9, 0x7b, 0x25, 0x0f, 0xe0, 0x63, 0x89, 0x6b, 0x97, 0xdc, 0x35,
+	0x6d, 0xae, 0x50, 0x27, 0x86, 0xb5, 0x92, 0x1e, 0x33, 0x1e, 0xab, 0x9f, 0xf8, 0x2c, 0x35, 0xab,
+	0x37, 0x34, 0xd2, 0xe3, 0xf8, 0x76, 0x29, 0x9f, 0x83, 0x35, 0xa3, 0xc2, 0x55, 0x93, 0xf2, 0x51,
+	0x88, 0x13, 0xa7, 0x88, 0x46, 0x94, 0x0b, 0x7f, 0x94, 0x1a, 0xf7, 0x2b, 0x6e, 0x73, 0x8a, 0xf5,
+	0xb8, 0xf3, 0xb7, 0x2a, 0x74, 0xf2, 0x98, 0x6a, 0x7e, 0x3e, 0x80, 0xce, 0xf4, 0x9b, 0x80, 0x7e,
+	0x90, 0xae, 0x02, 0x3b, 0x3f, 0x44, 0x39, 0xa2, 0x6e, 0xdb, 0x28, 0x8c, 0xdb, 0x9f, 0x43, 0x4b,
+	0x32, 0xa1, 0xd9, 0x60, 0xf9, 0x15, 0x1b, 0x34, 0xd1, 0xda, 0x2c, 0xbe, 0x0e, 0x1d, 0x3f, 0x90,
+	0xd3, 0x8c, 0x31, 0x37, 0xde, 0xb7, 0x15, 0x6e, 0x4a, 0x8a, 0x23, 0x3f, 0xf0, 0x33, 0x1a, 0x86,
+	0x51, 0x32, 0x94, 0x89, 0xa8, 0xbb, 0x53, 0x99, 0x7c, 0x06, 0x2d, 0xaa, 0x5e, 0xd1, 0x9f, 0x8e,
+	0x99, 0xf0, 0x75, 0x26, 0x2e, 0xe7, 0x3e, 0x1c, 0x4a, 0xed, 0xd7, 0xa8, 0x74, 0x9b, 0x34, 0x17,
+	0xc8, 0xcf, 0x00, 0x62, 0x36, 0xf4, 0x4e, 0xc7, 0x83, 0x01, 0xcd, 0xec, 0xd5, 0x39, 0xdf, 0xd9,
+	0xf0, 0x8e, 0x54, 0xa9, 0xc0, 0x35, 0x62, 0x23, 0x93, 0x8f, 0xa0, 0xce, 0x3f, 0xf1, 0xd2, 0x8c,
+	0x9d, 0xd2, 0xf9, 0x71, 0xf9, 0x04, 0x61, 0xb5, 0xa4, 0xc6, 0x3f, 0x91, 0x12, 0xf1, 0xe1, 0x12,
+	0x7e, 0xaa, 0x60, 0xd8, 0xfa, 0xde, 0xe9, 0xc4, 0xcb, 0xe8, 0x50, 0x0d, 0xca, 0xc8, 0xd2, 0x1f,
+	0xe7, 0x6b, 0x67, 0xb3, 0x74, 0xf3, 0x4b, 0xb3, 0xea, 0xce, 0xc4, 0x95, 0x6b, 0x0e, 0x13, 0x91,
+	0x4d, 0xdc, 0x8d, 0xc1, 0x2c, 0xde, 0xbd, 0x0b, 0x5b, 0x8b, 0x8d, 0x17, 0x70, 0xd9, 0x26, 0xac,
+	0x5c, 0xf8, 0xf1, 0xd8, 0x4c, 0x00, 0x4a, 0xf8, 0xf9, 0xf2, 0x67, 0x96, 0xf3, 0x07, 0x0b, 0x20,
+	0x3f, 0x00, 0xd9, 0x85, 0xda, 0xf7, 0xad, 0x0d, 0x63, 0x88, 0x44, 0x97, 0x51, 0x7c, 0xf7, 0xf4,
+	0x0a, 0x15, 0xad, 0x7a, 0xad, 0xad, 0x14, 0xc7, 0xd3, 0xba, 0xee, 0x42, 0x3d, 0xa4, 0xc3, 0xcc,
+	0x0f, 0xa9, 0x62, 0xcd, 0xba, 0x3b, 0x95, 0x9d, 0xbf, 0x60, 0x47, 0x97, 0x52, 0x80, 0x7e, 0x87,
+	0x34, 0x15, 0x67, 0xa6, 0xa3, 0xa5, 0x20, 0x2f, 0x0f, 0x3f, 0xf5, 0x83, 0x48, 0x4c, 0xf4, 0x81,
+	0xa6, 0x32, 0x52, 0x7f, 0x98, 0xb1, 0x34, 0xd5, 0xfb, 0x57, 0x5c, 0x23, 0x92, 0x5f, 0xc2, 0xda,
+	0x20, 0x1e, 0xf3, 0xb3, 0x69, 0xed, 0x56, 0x5f, 0x71, 0xc0, 0x96, 0x34, 0xd7, 0xa0, 0x73, 0x05,
+	0x2e, 0xdf, 0xa3, 0xa2, 0x58, 0x5a, 0x8a, 0xac, 0x9c, 0x3f, 0x5b, 0xd0, 0x2c, 0xc0, 0x38, 0x2c,
+	0xcb, 0x99, 0x4e, 0xbf, 0x47, 0x2a, 0xcf, 0x41, 0x42, 0xf2, 0x3d, 0x12, 0x5b, 0x7f, 0xcc, 0x69,
+	0x58, 0x7a, 0xcf, 0x6c, 0x20, 0xa2, 0xd4, 0x1f, 0x40, 0x3b, 0xa3, 0x23, 0x3f, 0x4a, 0xa2, 0x64,
+	0xa8, 0x6d, 0xd4, 0x49, 0xd6, 0xa7, 0xb0, 0x32, 0xdc, 0x86, 0x96, 0x24, 0x44, 0xfc, 0xae, 0x65,
+	0x08, 0x0b, 0xbf, 0xc1, 0x49, 0xec, 0x28, 0xe9, 0x71, 0xe7, 0x2a, 0x5c, 0xf9, 0x06, 0xbf, 0x36,
+	0xed, 0x8f, 0xc3, 0x48, 0x1c, 0x5e, 0xd0, 0x64, 0x4a, 0xb1, 0xce, 0x77, 0x16, 0x40, 0x0e, 0x63,
+	0xd8, 0xf8, 0x58, 0x0e, 0x66, 0xba, 0x6c, 0x8c, 0xf8, 0xb2, 0x29, 0x09, 0x8b, 0xac, 0x52, 0x2a,
+	0x32, 0xe5, 0xae, 0x72, 0x44, 0x09, 0xb8, 0x33, 0x1b, 0x8b, 0x80, 0x8d, 0xa8, 0x1e, 0x1b, 0x8c,
+	0x38, 0x47, 0x64, 0xab, 0x73, 0x44, 0x56, 0xa2, 0xc1, 0x5a, 0x89, 0x06, 0x6f, 0xfc, 0x02, 0x36,
+	0xe6, 0x3e, 0x02, 0x91, 0x3a, 0x54, 0x1f, 0x3e, 0x7a, 0x78, 0xd8, 0x59, 0x22, 0x35, 0xa8, 0xf4,
+	0xee, 0xee, 0x75, 0x2c, 0x84, 0xfa, 0xf7, 0xf7, 0x3f, 0xee, 0x2c, 0x13, 0x80, 0xd5, 0xfe, 0xfd,
+	0xfd, 0xdd, 0xbd, 0x4f, 0x3b, 0x95, 0x1b, 0x1f, 0x41, 0xdd, 0x7c, 0xef, 0x22, 0x2d, 0xa8, 0xf7,
+	0x1f, 0xef, 0x3f, 0xbc, 0xbb, 0xef, 0xde, 0xed, 0x2c, 0x91, 0x26, 0xd4, 0x4e, 0xdc, 0xc3, 0xde,
+	0xd1, 0x93, 0x9e, 0x5a, 0x7c, 0xe7, 0xc9, 0xf1, 0x83, 0xce, 0xf2, 0xee, 0x77, 0x55, 0xa8, 0x1b,
+	0x7a, 0x22, 0x87, 0x85, 0xdf, 0x57, 0xe7, 0x3f, 0x68, 0xe8, 0x18, 0x77, 0xbb, 0x8b, 0x54, 0xaa,
+	0xcf, 0x9d, 0xa5, 0x5b, 0x16, 0x39, 0x86, 0x66, 0x61, 0x90, 0x26, 0x6f, 0x16, 0x2a, 0x71, 0xee,
+	0x6d, 0xa3, 0xfb, 0xd6, 0x0b, 0xb4, 0x66, 0x3f, 0xf2, 0x1b, 0xb8, 0xb4, 0x60, 0xfc, 0x25, 0x3f,
+	0x2a, 0x91, 0xcd, 0x0b, 0xa6, 0xe3, 0x45, 0xae, 0x1a, 0x13, 0x67, 0x89, 0x1c, 0xc1, 0x5a, 0x69,
+	0x68, 0x22, 0x6f, 0xcf, 0x9b, 0x17, 0xa7, 0xa9, 0xee, 0xe6, 0xec, 0x5c, 0x81, 0xb3, 0x87, 0x3c,
+	0xf3, 0x6f, 0x61, 0x73, 0xd1, 0xe0, 0x40, 0xde, 0x9b, 0xdf, 0x71, 0xc1, 0x60, 0xf1, 0xca, 0x90,
+	0x1e, 0x41, 0xb3, 0xf0, 0x6a, 0x5c, 0x0c, 0xe9, 0xfc, 0x1b, 0x73, 0xf7, 0x25, 0xdf, 0xa2, 0x9c,
+	0x25, 0xd2, 0x93, 0x73, 0x49, 0xe9, 0x5d, 0x73, 0xbb, 0xb4, 0xdd, 0x82, 0xd7, 0xdd, 0xee, 0x56,
+	0xf1, 0x5a, 0xc8, 0xd5, 0xce, 0xd2, 0xee, 0xbf, 0x2d, 0x58, 0xd9, 0x0f, 0x47, 0x51, 0x42, 0x0e,
+	0xa0, 0x6e, 0x68, 0xbf, 0x58, 0x3d, 0x33, 0x43, 0x50, 0xb7, 0xbb, 0x48, 0x35, 0xcd, 0xf6, 0x57,
+	0xb0, 0x5e, 0xa6, 0x23, 0x72, 0xad, 0x64, 0x3f, 0x4f, 0x54, 0xdd, 0xc5, 0x37, 0xa4, 0xb3, 0x44,
+	0x1e, 0x41, 0x67, 0x96, 0x26, 0xc8, 0x3b, 0xb9, 0xf1, 0x0b, 0x28, 0xa4, 0x98, 0xe4, 0x5c, 0x8b,
+	0x59, 0x38, 0x5d, 0x95, 0xff, 0x35, 0x7c, 0xf2, 0xbf, 0x01, 0x00, 0x72, 0xbc, 0x91, 0x29, 0x85,
+	0x18, 0x00, 0x00,
 }
@@ -0,0 +1,191 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultAccessLogFlushInterval is the default interval of flushing the access logs to S3.
+	defaultAccessLogFlushInterval = time.Minute
+
+	// accessLogMaxBuffered is the size of the access logs above which logs that failed
+	// to be flushed are dropped instead of retried on the next flush.
+	accessLogMaxBuffered = 64 << 20
+
+	// accessLogKeyTimeFormat is the format of the time in the keys of the access log objects,
+	// which sorts them by the time they were flushed at.
+	accessLogKeyTimeFormat = "2006/01/02/150405.000000000"
+)
+
+// s3AccessLogHook is a logrus.Hook that batches the "rpc.finished" entries as JSON lines and
+// flushes them every interval as a gzip-compressed object to an S3 bucket, for durable access
+// logs without Elasticsearch. Close flushes the remaining logs.
+type s3AccessLogHook struct {
+	client    *s3.S3
+	bucket    string
+	prefix    string
+	host      string
+	logger    *logrus.Logger
+	formatter logrus.Formatter
+
+	mu    sync.Mutex
+	lines []byte
+
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+// newS3AccessLogHook creates an s3AccessLogHook that flushes to objects of bucket under prefix
+// every interval, a non-positive interval defaults to defaultAccessLogFlushInterval.
+// Failed flushes are logged to logger.
+func newS3AccessLogHook(
+	client *s3.S3,
+	bucket string,
+	prefix string,
+	interval time.Duration,
+	logger *logrus.Logger,
+) *s3AccessLogHook {
+	if interval <= 0 {
+		interval = defaultAccessLogFlushInterval
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+
+	h := &s3AccessLogHook{
+		client:    client,
+		bucket:    bucket,
+		prefix:    prefix,
+		host:      host,
+		logger:    logger,
+		formatter: &logrus.JSONFormatter{},
+		done:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+
+	go h.run(interval)
+
+	return h
+}
+
+// Levels returns all levels, the rpc logger picks the level of the access log entries.
+func (h *s3AccessLogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire buffers entry as a JSON line if it's an access log entry.
+func (h *s3AccessLogHook) Fire(entry *logrus.Entry) error {
+	if entry.Message != rpcFinishedMessage {
+		return nil
+	}
+
+	line, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lines = append(h.lines, line...)
+
+	return nil
+}
+
+// run flushes the buffered logs every interval until the hook is closed.
+func (h *s3AccessLogHook) run(interval time.Duration) {
+	defer close(h.stopped)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := h.flush(); err != nil {
+				h.logger.Errorf("failed to flush access logs: %v", err)
+			}
+		case <-h.done:
+			return
+		}
+	}
+}
+
+// flush uploads the buffered logs as a single gzip-compressed object keyed by the current time
+// and the host's name. Logs that failed to be uploaded are kept for the next flush, unless the
+// buffered logs exceed accessLogMaxBuffered.
+func (h *s3AccessLogHook) flush() error {
+	h.mu.Lock()
+	lines := h.lines
+	h.lines = nil
+	h.mu.Unlock()
+
+	if len(lines) == 0 {
+		return nil
+	}
+
+	err := h.upload(lines)
+	if err == nil {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(lines)+len(h.lines) > accessLogMaxBuffered {
+		return fmt.Errorf("%v, dropped %d bytes of access logs", err, len(lines))
+	}
+
+	h.lines = append(lines, h.lines...)
+
+	return err
+}
+
+// upload uploads lines as a gzip-compressed object.
+func (h *s3AccessLogHook) upload(lines []byte) error {
+	var compressed bytes.Buffer
+	gzipWriter := gzip.NewWriter(&compressed)
+	if _, err := gzipWriter.Write(lines); err != nil {
+		return fmt.Errorf("failed to compress access logs: %v", err)
+	}
+
+	if err := gzipWriter.Close(); err != nil {
+		return fmt.Errorf("failed to compress access logs: %v", err)
+	}
+
+	key := fmt.Sprintf("%s%s-%s.log.gz", h.prefix, time.Now().UTC().Format(accessLogKeyTimeFormat), h.host)
+	_, err := h.client.PutObjectWithContext(context.Background(), &s3.PutObjectInput{
+		Bucket:      aws.String(h.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(compressed.Bytes()),
+		ContentType: aws.String("application/gzip"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload access logs to s3://%s/%s: %v", h.bucket, key, err)
+	}
+
+	return nil
+}
+
+// Close stops flushing periodically and flushes the remaining logs.
+func (h *s3AccessLogHook) Close() error {
+	h.closeOnce.Do(func() {
+		close(h.done)
+	})
+	<-h.stopped
+
+	return h.flush()
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/sirupsen/logrus"
+)
+
+// capturingS3 is a simulated S3 that keeps the objects put to it, failing the first failPuts puts.
+type capturingS3 struct {
+	mu       sync.Mutex
+	failPuts int
+	objects  map[string][]byte
+}
+
+// client returns an S3 client of c, that never sends its requests.
+func (c *capturingS3) client() *s3.S3 {
+	client := s3.New(session.Must(session.NewSession(&aws.Config{
+		Region:           aws.String("us-east-1"),
+		Endpoint:         aws.String("http://s3.test"),
+		Credentials:      credentials.NewStaticCredentials("id", "secret", ""),
+		S3ForcePathStyle: aws.Bool(true),
+		MaxRetries:       aws.Int(0),
+	})))
+	client.Handlers.Send.Clear()
+	client.Handlers.Send.PushBack(func(r *request.Request) {
+		r.HTTPResponse = &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if c.failPuts > 0 {
+			c.failPuts--
+			r.Error = awserr.New("InternalError", "injected failure", nil)
+			return
+		}
+
+		input := r.Params.(*s3.PutObjectInput)
+		body, err := ioutil.ReadAll(input.Body)
+		if err != nil {
+			r.Error = err
+			return
+		}
+
+		c.objects[aws.StringValue(input.Key)] = body
+	})
+
+	return client
+}
+
+func TestS3AccessLogHook(t *testing.T) {
+	tests := []struct {
+		name        string
+		failPuts    int
+		wantObjects int
+	}{
+		{name: "flushed periodically and on close", wantObjects: 2},
+		{name: "failed flush retried", failPuts: 1, wantObjects: 1},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &capturingS3{failPuts: tt.failPuts, objects: make(map[string][]byte)}
+			logger := logrus.New()
+			logger.SetOutput(ioutil.Discard)
+
+			hook := newS3AccessLogHook(backend.client(), "logs", "access/", time.Hour, logger)
+			logger.AddHook(hook)
+
+			logger.WithField("grpc.method", "/download.Download/Download").Info(rpcFinishedMessage)
+			logger.Info("not an access log")
+			if err := hook.flush(); (err != nil) != (tt.failPuts > 0) {
+				t.Fatalf("s3AccessLogHook.flush() error = %v, want error %v", err, tt.failPuts > 0)
+			}
+
+			logger.WithField("grpc.method", "/download.Download/ListObjects").Warn(rpcFinishedMessage)
+			if err := hook.Close(); err != nil {
+				t.Fatalf("s3AccessLogHook.Close() error = %v", err)
+			}
+
+			if len(backend.objects) != tt.wantObjects {
+				t.Fatalf("s3AccessLogHook flushed %d objects, want %d", len(backend.objects), tt.wantObjects)
+			}
+
+			var methods []string
+			for key, object := range backend.objects {
+				if !strings.HasPrefix(key, "access/") || !strings.HasSuffix(key, ".log.gz") {
+					t.Errorf("s3AccessLogHook flushed object key = %s, want access/*.log.gz", key)
+				}
+
+				methods = append(methods, accessLogMethods(t, object)...)
+			}
+
+			if len(methods) != 2 {
+				t.Fatalf("s3AccessLogHook flushed methods = %v, want 2 access log entries", methods)
+			}
+
+			for _, method := range methods {
+				if !strings.HasPrefix(method, "/download.Download/") {
+					t.Errorf("s3AccessLogHook flushed entry of method %q, want only access log entries", method)
+				}
+			}
+		})
+	}
+}
+
+// accessLogMethods returns the "grpc.method" of every entry of the gzip-compressed access log object.
+func accessLogMethods(t *testing.T, object []byte) []string {
+	reader, err := gzip.NewReader(bytes.NewReader(object))
+	if err != nil {
+		t.Fatalf("gzip.NewReader() error = %v", err)
+	}
+
+	var methods []string
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		var entry map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("json.Unmarshal() error = %v", err)
+		}
+
+		if entry["msg"] != rpcFinishedMessage {
+			t.Errorf("access log entry message = %v, want %s", entry["msg"], rpcFinishedMessage)
+		}
+
+		methods = append(methods, entry["grpc.method"].(string))
+	}
+
+	return methods
+}
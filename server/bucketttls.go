@@ -0,0 +1,33 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// parseBucketTTLs parses ttls formatted as "bucket=seconds,bucket=seconds" into the TTLs of the
+// head cache entries of the buckets, zero disables caching a bucket's entries.
+// Invalid entries are logged to logger and skipped.
+func parseBucketTTLs(logger *logrus.Logger, ttls string) map[string]time.Duration {
+	bucketTTLs := make(map[string]time.Duration)
+	for _, entry := range strings.Split(ttls, ",") {
+		if entry == "" {
+			continue
+		}
+
+		bucket, secondsValue := splitMethodValue(entry)
+		bucket = strings.TrimSpace(bucket)
+		seconds, err := strconv.Atoi(strings.TrimSpace(secondsValue))
+		if bucket == "" || err != nil || seconds < 0 {
+			logger.Warnf("ignoring invalid head cache bucket ttl %q, want bucket=seconds", entry)
+			continue
+		}
+
+		bucketTTLs[bucket] = time.Duration(seconds) * time.Second
+	}
+
+	return bucketTTLs
+}
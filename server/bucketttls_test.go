@@ -0,0 +1,46 @@
+package server
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestParseBucketTTLs(t *testing.T) {
+	tests := []struct {
+		name string
+		ttls string
+		want map[string]time.Duration
+	}{
+		{
+			name: "bucket ttls - empty",
+			ttls: "",
+			want: map[string]time.Duration{},
+		},
+		{
+			name: "bucket ttls - overrides",
+			ttls: "static=600, working = 0",
+			want: map[string]time.Duration{"static": 10 * time.Minute, "working": 0},
+		},
+		{
+			name: "bucket ttls - invalid entries skipped",
+			ttls: "static,=60,working=-1,archive=soon,logs=5",
+			want: map[string]time.Duration{"logs": 5 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logrus.New()
+			logger.SetOutput(ioutil.Discard)
+
+			if got := parseBucketTTLs(logger, tt.ttls); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseBucketTTLs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
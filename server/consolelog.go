@@ -0,0 +1,50 @@
+package server
+
+import (
+	"io"
+	"io/ioutil"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// consoleFormatJSON logs to the console in the logger's own format, JSON.
+	consoleFormatJSON = "json"
+
+	// consoleFormatText logs to the console as text, while the other hooks keep their format.
+	consoleFormatText = "text"
+)
+
+// consoleHook is a logrus.Hook that writes every entry to writer in its own format,
+// so the console can be formatted for humans while hooks such as the Elasticsearch
+// hook keep formatting for machines.
+type consoleHook struct {
+	writer    io.Writer
+	formatter logrus.Formatter
+}
+
+// Levels returns all levels, the logger's level already filters the entries.
+func (h consoleHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire formats entry and writes it to the hook's writer.
+func (h consoleHook) Fire(entry *logrus.Entry) error {
+	line, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.writer.Write(line)
+	return err
+}
+
+// setConsoleText makes logger write its entries to console as text, instead of
+// in its formatter's format, without changing the format of its hooks.
+func setConsoleText(logger *logrus.Logger, console io.Writer) {
+	logger.SetOutput(ioutil.Discard)
+	logger.AddHook(consoleHook{
+		writer:    console,
+		formatter: &logrus.TextFormatter{DisableColors: true, FullTimestamp: true},
+	})
+}
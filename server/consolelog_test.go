@@ -0,0 +1,47 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestSetConsoleText(t *testing.T) {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+
+	// The recording hook stands in for the Elasticsearch hook, which formats entries as JSON.
+	esHook := test.NewLocal(logger)
+	var console bytes.Buffer
+	setConsoleText(logger, &console)
+
+	logger.WithField("key.prefix", "docs").Info("download finished")
+
+	if got := console.String(); !strings.Contains(got, `msg="download finished"`) ||
+		!strings.Contains(got, "key.prefix=docs") {
+		t.Errorf("console = %q, want the entry as text", got)
+	}
+
+	entry := esHook.LastEntry()
+	if entry == nil {
+		t.Fatalf("Elasticsearch hook received no entry")
+	}
+
+	line, err := logger.Formatter.Format(entry)
+	if err != nil {
+		t.Fatalf("failed to format the Elasticsearch entry: %v", err)
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(line, &fields); err != nil {
+		t.Fatalf("Elasticsearch entry %q isn't JSON: %v", line, err)
+	}
+
+	if fields["msg"] != "download finished" || fields["key.prefix"] != "docs" {
+		t.Errorf("Elasticsearch entry = %v, want the logged entry", fields)
+	}
+}
@@ -0,0 +1,27 @@
+package server
+
+import (
+	ilogger "github.com/meateam/elasticsearch-logger"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// configLogIndex is the configuration of the Elasticsearch index that ilogger.NewLogger ships logs to.
+const configLogIndex = "log_index"
+
+// newDeadLetterLogger returns a logger like ilogger.NewLogger's that ships the permanent failures
+// of downloads to the Elasticsearch index of DEAD_LETTER_LOG_INDEX, separately from the rest of
+// the logs, or nil if it isn't set.
+func newDeadLetterLogger() *logrus.Logger {
+	index := viper.GetString(configDeadLetterIndex)
+	if index == "" {
+		return nil
+	}
+
+	// ilogger.NewLogger reads the index from the configuration when it creates the logger.
+	logIndex := viper.GetString(configLogIndex)
+	viper.Set(configLogIndex, index)
+	defer viper.Set(configLogIndex, logIndex)
+
+	return ilogger.NewLogger()
+}
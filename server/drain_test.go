@@ -0,0 +1,40 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/meateam/download-service/download"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestDownloadServer_SetDraining(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
+	server := DownloadServer{
+		logger:          logger,
+		downloadService: download.NewService(nil, logger),
+		healthServer:    healthServer,
+	}
+
+	server.SetDraining(true)
+
+	res, err := healthServer.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("Health.Check() error = %v", err)
+	}
+
+	if want := grpc_health_v1.HealthCheckResponse_NOT_SERVING; res.GetStatus() != want {
+		t.Errorf("Health.Check() status = %v, want %v", res.GetStatus(), want)
+	}
+
+	if service := server.GetService(); !service.Draining() {
+		t.Errorf("DownloadServer.SetDraining(true) didn't drain the download service")
+	}
+}
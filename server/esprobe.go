@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// s3HealthService is the health service whose status is whether S3 is reachable.
+	s3HealthService = "s3"
+
+	// elasticsearchHealthService is the health service whose status is whether the Elasticsearch
+	// cluster that logs are shipped to is reachable.
+	elasticsearchHealthService = "elasticsearch"
+
+	// defaultElasticsearchProbeTimeout is the default time to wait for Elasticsearch to respond to a probe.
+	defaultElasticsearchProbeTimeout = 2 * time.Second
+)
+
+// elasticsearchProbe probes that the Elasticsearch cluster that logs are shipped to is reachable,
+// by pinging its nodes.
+type elasticsearchProbe struct {
+	urls     []string
+	user     string
+	password string
+	timeout  time.Duration
+	client   *http.Client
+}
+
+// newElasticsearchProbe returns an elasticsearchProbe of the comma-separated node urls, authenticated
+// by user and password if both are set, that waits up to timeout for a node to respond, a non-positive
+// timeout defaults to defaultElasticsearchProbeTimeout. The certificates of the nodes are verified
+// unless skipVerify is true, like the logger's.
+func newElasticsearchProbe(
+	urls string,
+	user string,
+	password string,
+	timeout time.Duration,
+	skipVerify bool,
+) *elasticsearchProbe {
+	if timeout <= 0 {
+		timeout = defaultElasticsearchProbeTimeout
+	}
+
+	return &elasticsearchProbe{
+		urls:     strings.Split(urls, ","),
+		user:     user,
+		password: password,
+		timeout:  timeout,
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: skipVerify},
+			},
+		},
+	}
+}
+
+// check returns nil if any node of the cluster responds to a ping within the probe's timeout,
+// and the error of the last node otherwise. A nil elasticsearchProbe never fails.
+func (p *elasticsearchProbe) check(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	err := fmt.Errorf("no elasticsearch urls to probe")
+	for _, url := range p.urls {
+		if err = p.ping(ctx, strings.TrimSpace(url)); err == nil {
+			return nil
+		}
+	}
+
+	return err
+}
+
+// ping sends a HEAD request to the node of url, failing unless it responds with a success status.
+func (p *elasticsearchProbe) ping(ctx context.Context, url string) error {
+	req, err := http.NewRequest(http.MethodHead, url, nil)
+	if err != nil {
+		return fmt.Errorf("invalid elasticsearch url %q: %v", url, err)
+	}
+
+	if p.user != "" && p.password != "" {
+		req.SetBasicAuth(p.user, p.password)
+	}
+
+	res, err := p.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to ping elasticsearch %s: %v", url, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("elasticsearch %s responded to ping with status %s", url, res.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/meateam/download-service/download"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// newElasticsearchTestServer returns a server that responds to pings with statusCode after delay,
+// and with 401 to pings without the basic auth of user and password, if set.
+func newElasticsearchTestServer(
+	statusCode int,
+	delay time.Duration,
+	user string,
+	password string,
+) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if gotUser, gotPassword, _ := r.BasicAuth(); gotUser != user || gotPassword != password {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+
+		w.WriteHeader(statusCode)
+	}))
+}
+
+func TestElasticsearchProbe_check(t *testing.T) {
+	up := newElasticsearchTestServer(http.StatusOK, 0, "", "")
+	defer up.Close()
+	down := newElasticsearchTestServer(http.StatusServiceUnavailable, 0, "", "")
+	defer down.Close()
+	hung := newElasticsearchTestServer(http.StatusOK, time.Minute, "", "")
+	defer hung.Close()
+	authenticated := newElasticsearchTestServer(http.StatusOK, 0, "elastic", "changeme")
+	defer authenticated.Close()
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	tests := []struct {
+		name     string
+		urls     string
+		user     string
+		password string
+		wantErr  bool
+	}{
+		{name: "elasticsearch probe - up", urls: up.URL},
+		{name: "elasticsearch probe - down", urls: down.URL, wantErr: true},
+		{name: "elasticsearch probe - hung", urls: hung.URL, wantErr: true},
+		{name: "elasticsearch probe - unreachable", urls: unreachable.URL, wantErr: true},
+		{name: "elasticsearch probe - a node is up", urls: unreachable.URL + "," + up.URL},
+		{
+			name:     "elasticsearch probe - authenticated",
+			urls:     authenticated.URL,
+			user:     "elastic",
+			password: "changeme",
+		},
+		{name: "elasticsearch probe - unauthenticated", urls: authenticated.URL, wantErr: true},
+		{name: "elasticsearch probe - invalid url", urls: "http://[::1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			const timeout = 100 * time.Millisecond
+			probe := newElasticsearchProbe(tt.urls, tt.user, tt.password, timeout, false)
+
+			start := time.Now()
+			err := probe.check(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("elasticsearchProbe.check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			// A hung cluster doesn't block the health checks past the timeout.
+			if elapsed := time.Since(start); elapsed > 10*timeout {
+				t.Errorf("elasticsearchProbe.check() took %v, want at most about %v", elapsed, timeout)
+			}
+		})
+	}
+}
+
+func TestDownloadServer_checkHealthElasticsearch(t *testing.T) {
+	up := newElasticsearchTestServer(http.StatusOK, 0, "", "")
+	defer up.Close()
+	down := newElasticsearchTestServer(http.StatusServiceUnavailable, 0, "", "")
+	defer down.Close()
+
+	const notProbed = grpc_health_v1.HealthCheckResponse_UNKNOWN
+	tests := []struct {
+		name       string
+		esURLs     string
+		wantHealth grpc_health_v1.HealthCheckResponse_ServingStatus
+		wantES     grpc_health_v1.HealthCheckResponse_ServingStatus
+	}{
+		{
+			name:       "elasticsearch health - not probed",
+			wantHealth: grpc_health_v1.HealthCheckResponse_SERVING,
+			wantES:     notProbed,
+		},
+		{
+			name:       "elasticsearch health - up",
+			esURLs:     up.URL,
+			wantHealth: grpc_health_v1.HealthCheckResponse_SERVING,
+			wantES:     grpc_health_v1.HealthCheckResponse_SERVING,
+		},
+		{
+			name:       "elasticsearch health - down",
+			esURLs:     down.URL,
+			wantHealth: grpc_health_v1.HealthCheckResponse_NOT_SERVING,
+			wantES:     grpc_health_v1.HealthCheckResponse_NOT_SERVING,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logrus.New()
+			logger.SetOutput(ioutil.Discard)
+
+			server := DownloadServer{
+				logger:          logger,
+				downloadService: download.NewService(slowS3Client(0), logger),
+				healthServer:    health.NewServer(),
+			}
+			if tt.esURLs != "" {
+				server.esProbe = newElasticsearchProbe(tt.esURLs, "", "", time.Second, false)
+			}
+
+			server.checkHealth()
+
+			for service, want := range map[string]grpc_health_v1.HealthCheckResponse_ServingStatus{
+				"":                         tt.wantHealth,
+				readinessService:           tt.wantHealth,
+				s3HealthService:            grpc_health_v1.HealthCheckResponse_SERVING,
+				elasticsearchHealthService: tt.wantES,
+			} {
+				res, err := server.healthServer.Check(
+					context.Background(),
+					&grpc_health_v1.HealthCheckRequest{Service: service},
+				)
+
+				// The status of dependencies that aren't probed is unknown.
+				if want == notProbed {
+					if status.Code(err) != codes.NotFound {
+						t.Errorf("Health.Check(%q) error = %v, want NotFound", service, err)
+					}
+
+					continue
+				}
+
+				if err != nil {
+					t.Fatalf("Health.Check(%q) error = %v", service, err)
+				}
+
+				if res.GetStatus() != want {
+					t.Errorf("Health.Check(%q) status = %v, want %v", service, res.GetStatus(), want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// parseFailoverBuckets parses buckets formatted as "bucket=replica|replica,bucket=replica" into the
+// replicas of the buckets, in the order they're failed over to.
+// Invalid entries are logged to logger and skipped.
+func parseFailoverBuckets(logger *logrus.Logger, buckets string) map[string][]string {
+	failoverBuckets := make(map[string][]string)
+	for _, entry := range strings.Split(buckets, ",") {
+		if entry == "" {
+			continue
+		}
+
+		bucket, replicasValue := splitMethodValue(entry)
+		bucket = strings.TrimSpace(bucket)
+		var replicas []string
+		for _, replica := range strings.Split(replicasValue, "|") {
+			if replica = strings.TrimSpace(replica); replica != "" && replica != bucket {
+				replicas = append(replicas, replica)
+			}
+		}
+
+		if bucket == "" || len(replicas) == 0 {
+			logger.Warnf("ignoring invalid failover buckets %q, want bucket=replica|replica", entry)
+			continue
+		}
+
+		failoverBuckets[bucket] = replicas
+	}
+
+	return failoverBuckets
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestParseFailoverBuckets(t *testing.T) {
+	tests := []struct {
+		name    string
+		buckets string
+		want    map[string][]string
+	}{
+		{
+			name:    "failover buckets - empty",
+			buckets: "",
+			want:    map[string][]string{},
+		},
+		{
+			name:    "failover buckets - replicas in order",
+			buckets: "files=files-eu|files-ap, logs = logs-eu",
+			want:    map[string][]string{"files": {"files-eu", "files-ap"}, "logs": {"logs-eu"}},
+		},
+		{
+			name:    "failover buckets - invalid entries skipped",
+			buckets: "files,=files-eu,logs=,media=media|,static=static-eu||",
+			want:    map[string][]string{"static": {"static-eu"}},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logrus.New()
+			logger.SetOutput(ioutil.Discard)
+
+			if got := parseFailoverBuckets(logger, tt.buckets); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseFailoverBuckets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
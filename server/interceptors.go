@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"runtime/debug"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/logrus/ctxlogrus"
+	grpc_recovery "github.com/grpc-ecosystem/go-grpc-middleware/recovery"
+	"github.com/meateam/download-service/download"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Interceptor is a pair of interceptors of the unary and the streaming calls of the server,
+// e.g. to authenticate requests by OAuth, API keys or the subject of a client certificate.
+// Either may be nil to intercept only one kind of call.
+type Interceptor struct {
+	Unary  grpc.UnaryServerInterceptor
+	Stream grpc.StreamServerInterceptor
+}
+
+// Config configures a DownloadServer beyond the environment, for deployments that embed it.
+type Config struct {
+	// Interceptors intercept the calls of the server in order, inside the logging, recovery and
+	// trace interceptors, so that the calls they reject are logged and traced like any other call,
+	// and their panics fail the calls with an Internal error.
+	Interceptors []Interceptor
+}
+
+// chainInterceptors returns the server options of the interceptor chains of the server, rpcLogger's
+// interceptors first, then the recovery of the streaming calls, then the interceptors rejecting
+// untraced requests if requireTrace is set, and then interceptors in order, closest to the handlers.
+// The unary calls are recovered by the logger interceptor of the server, outside of these chains.
+func chainInterceptors(
+	rpcLogger *rpcLogger,
+	traceExtractors []download.TraceExtractor,
+	requireTrace bool,
+	interceptors []Interceptor,
+) []grpc.ServerOption {
+	streamInterceptors := []grpc.StreamServerInterceptor{
+		rpcLogger.StreamServerInterceptor(),
+		recoveryStreamServerInterceptor(rpcLogger.logger),
+	}
+	unaryInterceptors := []grpc.UnaryServerInterceptor{rpcLogger.UnaryServerInterceptor()}
+
+	// In strict mode reject untraced requests, after the rpc logger so they're logged.
+	if requireTrace {
+		streamInterceptors = append(streamInterceptors, requireTraceStreamServerInterceptor(traceExtractors))
+		unaryInterceptors = append(unaryInterceptors, requireTraceUnaryServerInterceptor(traceExtractors))
+	}
+
+	for _, interceptor := range interceptors {
+		if interceptor.Stream != nil {
+			streamInterceptors = append(streamInterceptors, interceptor.Stream)
+		}
+
+		if interceptor.Unary != nil {
+			unaryInterceptors = append(unaryInterceptors, interceptor.Unary)
+		}
+	}
+
+	return []grpc.ServerOption{
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+	}
+}
+
+// recoveryStreamServerInterceptor returns a stream server interceptor that recovers the panics of
+// the streaming calls, logs them with their stack to logger and fails the calls with an Internal error.
+func recoveryStreamServerInterceptor(logger *logrus.Logger) grpc.StreamServerInterceptor {
+	return grpc_recovery.StreamServerInterceptor(grpc_recovery.WithRecoveryHandlerContext(
+		func(ctx context.Context, p interface{}) error {
+			logger.WithFields(ctxlogrus.Extract(ctx).Data).
+				WithField("panic.stack", string(debug.Stack())).
+				Errorf("recovered from panic: %v", p)
+
+			return status.Errorf(codes.Internal, "recovered from panic: %v", p)
+		},
+	))
+}
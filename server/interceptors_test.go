@@ -0,0 +1,239 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"go.elastic.co/apm"
+	"go.elastic.co/apm/module/apmhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// recordingInterceptor returns an Interceptor that appends name to calls, and then rejects the call
+// with an Unauthenticated error if reject is set.
+func recordingInterceptor(name string, reject bool, calls *[]string) Interceptor {
+	intercept := func() error {
+		*calls = append(*calls, name)
+		if reject {
+			return status.Errorf(codes.Unauthenticated, "%s rejected the call", name)
+		}
+
+		return nil
+	}
+
+	return Interceptor{
+		Unary: func(
+			ctx context.Context,
+			req interface{},
+			info *grpc.UnaryServerInfo,
+			handler grpc.UnaryHandler,
+		) (interface{}, error) {
+			if err := intercept(); err != nil {
+				return nil, err
+			}
+
+			return handler(ctx, req)
+		},
+		Stream: func(
+			srv interface{},
+			stream grpc.ServerStream,
+			info *grpc.StreamServerInfo,
+			handler grpc.StreamHandler,
+		) error {
+			if err := intercept(); err != nil {
+				return err
+			}
+
+			return handler(srv, stream)
+		},
+	}
+}
+
+func TestChainInterceptors(t *testing.T) {
+	traceparent := apmhttp.FormatTraceparentHeader(apm.TraceContext{
+		Trace: apm.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		Span:  apm.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+	})
+
+	tests := []struct {
+		name      string
+		md        metadata.MD
+		reject    bool
+		wantCode  codes.Code
+		wantCalls []string
+	}{
+		{
+			name:      "interceptors - pass",
+			md:        metadata.Pairs(apmhttp.TraceparentHeader, traceparent),
+			wantCode:  codes.Unknown,
+			wantCalls: []string{"first", "second"},
+		},
+		{
+			name:      "interceptors - reject",
+			md:        metadata.Pairs(apmhttp.TraceparentHeader, traceparent),
+			reject:    true,
+			wantCode:  codes.Unauthenticated,
+			wantCalls: []string{"first", "second"},
+		},
+		{
+			name:     "interceptors - untraced rejected first",
+			md:       metadata.MD{},
+			wantCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logrus.New()
+			logger.SetOutput(ioutil.Discard)
+			hook := test.NewLocal(logger)
+
+			var calls []string
+			rpcLogger := newRPCLogger(logger, "info", "error", nil)
+			grpcServer := grpc.NewServer(chainInterceptors(rpcLogger, nil, true, []Interceptor{
+				recordingInterceptor("first", false, &calls),
+				recordingInterceptor("second", tt.reject, &calls),
+			})...)
+			defer grpcServer.Stop()
+
+			// Requests without a key fail before S3 is called.
+			pb.RegisterDownloadServer(grpcServer, download.NewService(nil, logger))
+
+			lis := bufconn.Listen(1 << 20)
+			go func() {
+				if err := grpcServer.Serve(lis); err != nil {
+					t.Errorf("failed to serve: %v", err)
+				}
+			}()
+
+			conn, err := grpc.DialContext(
+				context.Background(),
+				"bufnet",
+				grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+					return lis.Dial()
+				}),
+				grpc.WithInsecure(),
+			)
+			if err != nil {
+				t.Fatalf("failed to dial bufnet: %v", err)
+			}
+			defer conn.Close()
+
+			client := pb.NewDownloadClient(conn)
+			ctx := metadata.NewOutgoingContext(context.Background(), tt.md)
+
+			_, err = client.GetMetadata(ctx, &pb.GetMetadataRequest{})
+			if status.Code(err) != tt.wantCode {
+				t.Errorf("GetMetadata() error = %v, want code %v", err, tt.wantCode)
+			}
+
+			stream, err := client.Download(ctx, &pb.DownloadRequest{})
+			if err != nil {
+				t.Fatalf("Download() error = %v", err)
+			}
+
+			if _, err := stream.Recv(); status.Code(err) != tt.wantCode {
+				t.Errorf("Download() error = %v, want code %v", err, tt.wantCode)
+			}
+
+			// The interceptors ran in order for both calls, inside the strict trace interceptors.
+			wantCalls := append(append([]string{}, tt.wantCalls...), tt.wantCalls...)
+			if len(wantCalls) == 0 {
+				wantCalls = nil
+			}
+
+			if !reflect.DeepEqual(calls, wantCalls) {
+				t.Errorf("interceptors called = %v, want %v", calls, wantCalls)
+			}
+
+			// The rpc logger logged both calls, including those the interceptors rejected.
+			logged := 0
+			for _, entry := range hook.AllEntries() {
+				if entry.Message == rpcFinishedMessage && entry.Data["grpc.code"] == tt.wantCode.String() {
+					logged++
+				}
+			}
+
+			if logged != 2 {
+				t.Errorf("rpc logger logged %d calls with code %v, want 2", logged, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestChainInterceptorsStreamRecovery(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	hook := test.NewLocal(logger)
+
+	rpcLogger := newRPCLogger(logger, "info", "error", nil)
+	grpcServer := grpc.NewServer(chainInterceptors(rpcLogger, nil, false, []Interceptor{{
+		Stream: func(interface{}, grpc.ServerStream, *grpc.StreamServerInfo, grpc.StreamHandler) error {
+			panic("interceptor panicked")
+		},
+	}})...)
+	defer grpcServer.Stop()
+
+	pb.RegisterDownloadServer(grpcServer, download.NewService(nil, logger))
+
+	lis := bufconn.Listen(1 << 20)
+	go func() {
+		if err := grpcServer.Serve(lis); err != nil {
+			t.Errorf("failed to serve: %v", err)
+		}
+	}()
+
+	conn, err := grpc.DialContext(
+		context.Background(),
+		"bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		}),
+		grpc.WithInsecure(),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial bufnet: %v", err)
+	}
+	defer conn.Close()
+
+	stream, err := pb.NewDownloadClient(conn).Download(context.Background(), &pb.DownloadRequest{})
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+
+	if _, err := stream.Recv(); status.Code(err) != codes.Internal {
+		t.Errorf("Download() error = %v, want code %v", err, codes.Internal)
+	}
+
+	// The panic was logged with its stack, and the failed call by the rpc logger.
+	var recovered, logged bool
+	for _, entry := range hook.AllEntries() {
+		if _, ok := entry.Data["panic.stack"]; ok {
+			recovered = true
+		}
+
+		if entry.Message == rpcFinishedMessage && entry.Data["grpc.code"] == codes.Internal.String() {
+			logged = true
+		}
+	}
+
+	if !recovered {
+		t.Error("recovered panic not logged")
+	}
+
+	if !logged {
+		t.Errorf("rpc logger did not log the call with code %v", codes.Internal)
+	}
+}
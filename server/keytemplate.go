@@ -0,0 +1,28 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// parseKeyTemplateVars parses vars formatted as "name=value,name=value" into the variables of
+// a key template. Invalid entries are logged to logger and skipped.
+func parseKeyTemplateVars(logger *logrus.Logger, vars string) map[string]string {
+	templateVars := make(map[string]string)
+	for _, entry := range strings.Split(vars, ",") {
+		if entry == "" {
+			continue
+		}
+
+		i := strings.Index(entry, "=")
+		if i < 0 || strings.TrimSpace(entry[:i]) == "" {
+			logger.Warnf("ignoring invalid key template variable %q, want name=value", entry)
+			continue
+		}
+
+		templateVars[strings.TrimSpace(entry[:i])] = strings.TrimSpace(entry[i+1:])
+	}
+
+	return templateVars
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestParseKeyTemplateVars(t *testing.T) {
+	tests := []struct {
+		name string
+		vars string
+		want map[string]string
+	}{
+		{
+			name: "key template vars - empty",
+			vars: "",
+			want: map[string]string{},
+		},
+		{
+			name: "key template vars - variables",
+			vars: "env=prod, region = eu ,query=a=b",
+			want: map[string]string{"env": "prod", "region": "eu", "query": "a=b"},
+		},
+		{
+			name: "key template vars - invalid entries skipped",
+			vars: "env,=prod,region=eu",
+			want: map[string]string{"region": "eu"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logrus.New()
+			logger.SetOutput(ioutil.Discard)
+
+			if got := parseKeyTemplateVars(logger, tt.vars); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseKeyTemplateVars() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
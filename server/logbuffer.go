@@ -0,0 +1,206 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/meateam/download-service/download"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultLogBufferWarnInterval is the default interval between the warnings that the log buffer is full.
+const defaultLogBufferWarnInterval = time.Minute
+
+// logBufferHook is a logrus.Hook that buffers the entries logged and fires the hooks it wraps,
+// such as the Elasticsearch hook, on them in the background, so logging doesn't wait for them.
+// When the buffer is full, entries are dropped, or logging blocks until there's room if block
+// is set, and a warning is written to warnings at most once per warnInterval.
+// Fatal and panic entries are fired synchronously, since the process exits right after them.
+type logBufferHook struct {
+	hooks        logrus.LevelHooks
+	entries      chan *logrus.Entry
+	block        bool
+	dropped      int64
+	flushLatency *download.LatencyStats
+	warnings     io.Writer
+	warnInterval time.Duration
+
+	warnMu      sync.Mutex
+	lastWarning time.Time
+	unwarned    int64
+
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+// newLogBufferHook creates a logBufferHook that buffers up to size entries for hooks,
+// writing its warnings to warnings.
+func newLogBufferHook(hooks logrus.LevelHooks, size int, block bool, warnings io.Writer) *logBufferHook {
+	h := &logBufferHook{
+		hooks:        hooks,
+		entries:      make(chan *logrus.Entry, size),
+		block:        block,
+		flushLatency: download.NewLatencyStats(),
+		warnings:     warnings,
+		warnInterval: defaultLogBufferWarnInterval,
+		done:         make(chan struct{}),
+		stopped:      make(chan struct{}),
+	}
+
+	go h.run()
+
+	return h
+}
+
+// bufferLogHooks replaces the hooks of logger by a logBufferHook of size entries wrapping them,
+// and returns it. Hooks added to logger afterwards are fired synchronously.
+func bufferLogHooks(logger *logrus.Logger, size int, block bool, warnings io.Writer) *logBufferHook {
+	h := newLogBufferHook(logger.Hooks, size, block, warnings)
+	logger.ReplaceHooks(logrus.LevelHooks{})
+	logger.AddHook(h)
+
+	return h
+}
+
+// Levels returns all levels, the wrapped hooks pick the levels they fire on.
+func (h *logBufferHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire buffers a copy of entry to be fired on the wrapped hooks.
+func (h *logBufferHook) Fire(entry *logrus.Entry) error {
+	if entry.Level <= logrus.FatalLevel {
+		return h.hooks.Fire(entry.Level, entry)
+	}
+
+	select {
+	case <-h.done:
+		return h.hooks.Fire(entry.Level, entry)
+	default:
+	}
+
+	// The logger reuses its entries once they're logged.
+	buffered := copyEntry(entry)
+	select {
+	case h.entries <- buffered:
+		return nil
+	default:
+	}
+
+	if !h.block {
+		atomic.AddInt64(&h.dropped, 1)
+		h.warnFull("dropped")
+
+		return nil
+	}
+
+	h.warnFull("delayed")
+	select {
+	case h.entries <- buffered:
+		return nil
+	case <-h.done:
+		return h.hooks.Fire(buffered.Level, buffered)
+	}
+}
+
+// warnFull writes a warning that the buffer is full to h.warnings, unless one was written
+// in the last h.warnInterval, counting the entries that were dropped or delayed, by action,
+// since the last warning.
+func (h *logBufferHook) warnFull(action string) {
+	h.warnMu.Lock()
+	defer h.warnMu.Unlock()
+
+	h.unwarned++
+	now := time.Now()
+	if !h.lastWarning.IsZero() && now.Sub(h.lastWarning) < h.warnInterval {
+		return
+	}
+
+	fmt.Fprintf(
+		h.warnings,
+		"log buffer of %d entries is full, %s %d log entries since the last warning\n",
+		cap(h.entries), action, h.unwarned,
+	)
+	h.lastWarning = now
+	h.unwarned = 0
+}
+
+// run fires the wrapped hooks on the buffered entries until the hook is closed,
+// and then on the entries left in the buffer.
+func (h *logBufferHook) run() {
+	defer close(h.stopped)
+
+	for {
+		select {
+		case entry := <-h.entries:
+			h.fire(entry)
+		case <-h.done:
+			for {
+				select {
+				case entry := <-h.entries:
+					h.fire(entry)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// fire fires the wrapped hooks on entry, observing how long they took.
+func (h *logBufferHook) fire(entry *logrus.Entry) {
+	start := time.Now()
+	err := h.hooks.Fire(entry.Level, entry)
+	h.flushLatency.Observe(time.Since(start))
+	if err != nil {
+		fmt.Fprintf(h.warnings, "Failed to fire hook: %v\n", err)
+	}
+}
+
+// Dropped returns the number of entries dropped since the buffer was full.
+func (h *logBufferHook) Dropped() int64 {
+	return atomic.LoadInt64(&h.dropped)
+}
+
+// Stats returns the stats of the buffer, resetting its flush latency if reset is true.
+func (h *logBufferHook) Stats(reset bool) download.LogBufferStats {
+	return download.LogBufferStats{
+		Depth:        len(h.entries),
+		Capacity:     cap(h.entries),
+		Dropped:      h.Dropped(),
+		FlushLatency: h.flushLatency.Snapshot(reset),
+	}
+}
+
+// Close fires the wrapped hooks on the entries left in the buffer, and fires them synchronously
+// on the entries logged afterwards.
+func (h *logBufferHook) Close() error {
+	h.closeOnce.Do(func() {
+		close(h.done)
+	})
+	<-h.stopped
+
+	return nil
+}
+
+// copyEntry returns a copy of entry that doesn't share its fields.
+func copyEntry(entry *logrus.Entry) *logrus.Entry {
+	data := make(logrus.Fields, len(entry.Data))
+	for key, value := range entry.Data {
+		data[key] = value
+	}
+
+	return &logrus.Entry{
+		Logger:  entry.Logger,
+		Data:    data,
+		Time:    entry.Time,
+		Level:   entry.Level,
+		Caller:  entry.Caller,
+		Message: entry.Message,
+		Context: entry.Context,
+	}
+}
@@ -0,0 +1,172 @@
+package server
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// stalledHook is a logrus.Hook that stalls firing until release is closed, signaling fired
+// on every entry it fires on.
+type stalledHook struct {
+	fired   chan *logrus.Entry
+	release chan struct{}
+}
+
+func (h *stalledHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *stalledHook) Fire(entry *logrus.Entry) error {
+	h.fired <- entry
+	<-h.release
+
+	return nil
+}
+
+// lockedBuffer is a bytes.Buffer safe for concurrent use.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+// newStalledLogger returns a logger whose hooks are buffered by a logBufferHook of size entries
+// in front of a stalledHook, which stalls on the first entry logged.
+func newStalledLogger(t *testing.T, size int, block bool, warnings *lockedBuffer) (
+	*logrus.Logger, *logBufferHook, *stalledHook) {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	stalled := &stalledHook{fired: make(chan *logrus.Entry, 100), release: make(chan struct{})}
+	logger.AddHook(stalled)
+	logBuffer := bufferLogHooks(logger, size, block, warnings)
+
+	logger.Info("stalling")
+	select {
+	case <-stalled.fired:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("logBufferHook didn't fire the first entry")
+	}
+
+	return logger, logBuffer, stalled
+}
+
+func TestLogBufferHook_Drop(t *testing.T) {
+	const size = 4
+	const overflow = 10
+
+	var warnings lockedBuffer
+	logger, logBuffer, stalled := newStalledLogger(t, size, false, &warnings)
+	logBuffer.warnInterval = time.Hour
+
+	for i := 0; i < size+overflow; i++ {
+		logger.WithField("i", i).Info("buffered")
+	}
+
+	if dropped := logBuffer.Dropped(); dropped != overflow {
+		t.Errorf("logBufferHook.Dropped() = %d, want %d", dropped, overflow)
+	}
+
+	stats := logBuffer.Stats(false)
+	if stats.Depth != size || stats.Capacity != size || stats.Dropped != overflow {
+		t.Errorf(
+			"logBufferHook.Stats() = depth %d of %d, %d dropped, want depth %d of %d, %d dropped",
+			stats.Depth, stats.Capacity, stats.Dropped, size, size, overflow,
+		)
+	}
+
+	// The warning is written once per interval, however many entries were dropped.
+	if got := strings.Count(warnings.String(), "log buffer of 4 entries is full"); got != 1 {
+		t.Errorf("logBufferHook wrote %d warnings, want 1: %q", got, warnings.String())
+	}
+
+	logBuffer.warnInterval = 0
+	logger.Info("dropped")
+	if got := strings.Count(warnings.String(), "dropped 10 log entries since the last warning"); got != 1 {
+		t.Errorf("logBufferHook warnings = %q, want the entries dropped since the last warning", warnings.String())
+	}
+
+	close(stalled.release)
+	if err := logBuffer.Close(); err != nil {
+		t.Fatalf("logBufferHook.Close() error = %v", err)
+	}
+
+	// The buffered entries are fired in order, the dropped ones aren't.
+	for i := 0; i < size; i++ {
+		if entry := <-stalled.fired; entry.Data["i"] != i {
+			t.Errorf("logBufferHook fired entry %v, want %d", entry.Data["i"], i)
+		}
+	}
+
+	if len(stalled.fired) != 0 {
+		t.Errorf("logBufferHook fired %d dropped entries, want none", len(stalled.fired))
+	}
+}
+
+func TestLogBufferHook_Block(t *testing.T) {
+	const size = 2
+
+	var warnings lockedBuffer
+	logger, logBuffer, stalled := newStalledLogger(t, size, true, &warnings)
+
+	logged := make(chan struct{})
+	go func() {
+		defer close(logged)
+		for i := 0; i < size+1; i++ {
+			logger.WithField("i", i).Info("buffered")
+		}
+	}()
+
+	select {
+	case <-logged:
+		t.Fatalf("logging didn't block while the log buffer was full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(stalled.release)
+	select {
+	case <-logged:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("logging stayed blocked after the log buffer was released")
+	}
+
+	if err := logBuffer.Close(); err != nil {
+		t.Fatalf("logBufferHook.Close() error = %v", err)
+	}
+
+	if dropped := logBuffer.Dropped(); dropped != 0 {
+		t.Errorf("logBufferHook.Dropped() = %d, want 0", dropped)
+	}
+
+	if !strings.Contains(warnings.String(), "delayed 1 log entries") {
+		t.Errorf("logBufferHook warnings = %q, want a warning of the delayed entry", warnings.String())
+	}
+
+	if fired := len(stalled.fired); fired != size+1 {
+		t.Errorf("logBufferHook fired %d entries, want %d", fired, size+1)
+	}
+
+	if count := logBuffer.Stats(false).FlushLatency.Count; count != size+2 {
+		t.Errorf("logBufferHook flush latency count = %d, want %d", count, size+2)
+	}
+}
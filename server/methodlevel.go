@@ -0,0 +1,49 @@
+package server
+
+import (
+	"strings"
+
+	ilogger "github.com/meateam/elasticsearch-logger"
+	"github.com/sirupsen/logrus"
+)
+
+// methodLogLevels maps full method names to the least severe level of their logged entries,
+// overriding the logger's level for noisy or chatty methods.
+type methodLogLevels map[string]logrus.Level
+
+// parseMethodLogLevels parses levels formatted as "method=level,method=level" into methodLogLevels.
+// Invalid entries are logged to logger and skipped.
+func parseMethodLogLevels(logger *logrus.Logger, levels string) methodLogLevels {
+	methodLevels := make(methodLogLevels)
+	for _, entry := range strings.Split(levels, ",") {
+		if entry == "" {
+			continue
+		}
+
+		method, levelName := splitMethodValue(entry)
+		level, err := logrus.ParseLevel(levelName)
+		if err != nil || method == "" {
+			logger.Warnf("ignoring invalid method log level %q, want method=level", entry)
+			continue
+		}
+
+		methodLevels[method] = level
+	}
+
+	return methodLevels
+}
+
+// enabled returns whether an entry of fullMethod at level is logged.
+func (l methodLogLevels) enabled(fullMethod string, level logrus.Level) bool {
+	minLevel, ok := l[fullMethod]
+
+	return !ok || level <= minLevel
+}
+
+// payloadDecider returns an ilogger.DeciderFunc that drops the payloads of the methods whose
+// level is above info, which payloads are logged at, and lets decider decide the rest.
+func (l methodLogLevels) payloadDecider(decider ilogger.DeciderFunc) ilogger.DeciderFunc {
+	return func(fullMethodName string) bool {
+		return l.enabled(fullMethodName, logrus.InfoLevel) && decider(fullMethodName)
+	}
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+
+	ilogger "github.com/meateam/elasticsearch-logger"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	healthCheckMethod = "/grpc.health.v1.Health/Check"
+	downloadMethod    = "/download.Download/Download"
+)
+
+func TestParseMethodLogLevels(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	got := parseMethodLogLevels(logger, healthCheckMethod+"=warn,"+downloadMethod+"=info,=debug,/a.B/C=loud")
+	want := methodLogLevels{healthCheckMethod: logrus.WarnLevel, downloadMethod: logrus.InfoLevel}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseMethodLogLevels() = %v, want %v", got, want)
+	}
+}
+
+func TestRPCLogger_methodLevels(t *testing.T) {
+	methodLevels := methodLogLevels{healthCheckMethod: logrus.WarnLevel, downloadMethod: logrus.InfoLevel}
+
+	tests := []struct {
+		name    string
+		method  string
+		err     error
+		wantLog bool
+	}{
+		{name: "method levels - health check ok", method: healthCheckMethod},
+		{
+			name:    "method levels - health check failed",
+			method:  healthCheckMethod,
+			err:     status.Error(codes.Unavailable, "unavailable"),
+			wantLog: true,
+		},
+		{name: "method levels - download ok", method: downloadMethod, wantLog: true},
+		{name: "method levels - method without level", method: "/download.Download/ListObjects", wantLog: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logrus.New()
+			logger.SetOutput(ioutil.Discard)
+			logger.SetLevel(logrus.DebugLevel)
+			logs := test.NewLocal(logger)
+
+			l := newRPCLogger(logger, "info", "error", methodLevels)
+			l.log(context.Background(), tt.method, time.Now(), tt.err, logrus.Fields{})
+
+			if logged := len(logs.AllEntries()) > 0; logged != tt.wantLog {
+				t.Errorf("rpcLogger.log() logged = %v, want %v", logged, tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestMethodLogLevels_payloadDecider(t *testing.T) {
+	methodLevels := methodLogLevels{healthCheckMethod: logrus.WarnLevel, downloadMethod: logrus.DebugLevel}
+	decider := methodLevels.payloadDecider(ilogger.IgnoreServerMethodsDecider(downloadMethod))
+
+	tests := []struct {
+		method string
+		want   bool
+	}{
+		{method: healthCheckMethod, want: false},
+		{method: downloadMethod, want: false},
+		{method: "/download.Download/ListObjects", want: true},
+	}
+
+	for _, tt := range tests {
+		if got := decider(tt.method); got != tt.want {
+			t.Errorf("methodLogLevels.payloadDecider()(%q) = %v, want %v", tt.method, got, tt.want)
+		}
+	}
+}
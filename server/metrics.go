@@ -0,0 +1,208 @@
+package server
+
+import (
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/meateam/download-service/download"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	// metricsNamespace is the namespace of the Prometheus metrics of the server.
+	metricsNamespace = "download_service"
+
+	// metricsPath is the HTTP path the Prometheus metrics are served on.
+	metricsPath = "/metrics"
+
+	// traceIDExemplarLabel is the label of the trace id in the exemplars of the part latency histogram.
+	traceIDExemplarLabel = "trace_id"
+)
+
+// promMetrics is a download.Metrics that exports the measurements of downloads as Prometheus metrics.
+type promMetrics struct {
+	bytesStreamed *prometheus.CounterVec
+	downloads     *prometheus.CounterVec
+	partLatency   prometheus.Histogram
+	partBytes     prometheus.Counter
+	failovers     *prometheus.CounterVec
+}
+
+// newPromMetrics creates the Prometheus metrics of downloads, and of the number of active downloads
+// returned by activeDownloads, registers them in registerer and returns them.
+func newPromMetrics(registerer prometheus.Registerer, activeDownloads func() int64) *promMetrics {
+	m := &promMetrics{
+		bytesStreamed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "bytes_streamed_total",
+			Help:      "Bytes of objects streamed to clients by the top-level prefix of their keys.",
+		}, []string{"key_prefix"}),
+		downloads: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "downloads_total",
+			Help:      "Finished downloads by their gRPC status code and the top-level prefix of their keys.",
+		}, []string{"code", "key_prefix"}),
+		partLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "part_fetch_duration_seconds",
+			Help:      "Duration of fetching a single part of an object from S3.",
+			Buckets:   prometheus.ExponentialBuckets(0.005, 2, 12),
+		}),
+		partBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "part_fetch_bytes_total",
+			Help:      "Bytes of the parts of objects fetched from S3.",
+		}),
+		failovers: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "failovers_total",
+			Help:      "Buckets failed over to their replicas by the region of the replica.",
+		}, []string{"region"}),
+	}
+
+	registerer.MustRegister(
+		m.bytesStreamed,
+		m.downloads,
+		m.partLatency,
+		m.partBytes,
+		m.failovers,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "active_streams",
+			Help:      "Downloads currently being streamed.",
+		}, func() float64 {
+			return float64(activeDownloads())
+		}),
+	)
+
+	return m
+}
+
+// ObservePart observes a part of bytes bytes that took duration to fetch from S3, of the download
+// whose trace id is traceID, empty if the download isn't traced. The latency of a traced part is
+// observed with the trace id as its exemplar, unless it's too long for an exemplar.
+func (m *promMetrics) ObservePart(duration time.Duration, bytes int64, traceID string) {
+	m.partBytes.Add(float64(bytes))
+	if traceID == "" || utf8.RuneCountInString(traceIDExemplarLabel+traceID) > prometheus.ExemplarMaxRunes {
+		m.partLatency.Observe(duration.Seconds())
+		return
+	}
+
+	m.partLatency.(prometheus.ExemplarObserver).ObserveWithExemplar(
+		duration.Seconds(),
+		prometheus.Labels{traceIDExemplarLabel: traceID},
+	)
+}
+
+// ObserveDownload observes a finished download of a key whose prefix label is keyPrefix,
+// that streamed bytes bytes and ended with code.
+func (m *promMetrics) ObserveDownload(code codes.Code, keyPrefix string, bytes int64) {
+	m.downloads.WithLabelValues(code.String(), keyPrefix).Inc()
+	m.bytesStreamed.WithLabelValues(keyPrefix).Add(float64(bytes))
+}
+
+// ObserveFailover observes a bucket failed over to its replica in region.
+func (m *promMetrics) ObserveFailover(region string) {
+	m.failovers.WithLabelValues(region).Inc()
+}
+
+// registerServiceMetrics registers in registerer the Prometheus metrics of the state of downloadService:
+// whether it's shedding load, and the remaining egress quota and the log buffer, if set.
+func registerServiceMetrics(registerer prometheus.Registerer, downloadService *download.Service) {
+	registerer.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "shedding",
+		Help:      "Whether new downloads are rejected to shed load, 1 if they are.",
+	}, func() float64 {
+		if downloadService.Shedding() {
+			return 1
+		}
+
+		return 0
+	}))
+
+	if egressQuota := downloadService.EgressQuota; egressQuota != nil {
+		registerer.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "egress_remaining_bytes",
+			Help:      "Bytes left to serve in the current window of the egress quota.",
+		}, func() float64 {
+			return float64(egressQuota.Snapshot().Remaining)
+		}))
+	}
+
+	if logBuffer := downloadService.LogBuffer; logBuffer != nil {
+		registerer.MustRegister(
+			prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+				Namespace: metricsNamespace,
+				Name:      "log_buffer_depth",
+				Help:      "Log entries waiting in the buffer to be shipped.",
+			}, func() float64 {
+				return float64(logBuffer.Stats(false).Depth)
+			}),
+			prometheus.NewCounterFunc(prometheus.CounterOpts{
+				Namespace: metricsNamespace,
+				Name:      "log_buffer_dropped_total",
+				Help:      "Log entries dropped since the buffer was full.",
+			}, func() float64 {
+				return float64(logBuffer.Stats(false).Dropped)
+			}),
+		)
+	}
+}
+
+// configureMetrics sets the Prometheus metrics of downloadService, along with the metrics of its state,
+// and returns the HTTP server that serves them on port, separately from the gRPC server.
+// It returns nil if port is empty, disabling the metrics.
+func configureMetrics(downloadService *download.Service, port string) *http.Server {
+	if port == "" {
+		return nil
+	}
+
+	registry := prometheus.NewRegistry()
+	downloadService.Metrics = newPromMetrics(registry, downloadService.ActiveDownloads)
+	registerServiceMetrics(registry, downloadService)
+
+	return newMetricsServer(port, registry)
+}
+
+// newMetricsServer returns an HTTP server that serves the Prometheus metrics of gatherer
+// on metricsPath of port, separately from the gRPC server.
+func newMetricsServer(port string, gatherer prometheus.Gatherer) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle(metricsPath, promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}))
+
+	return &http.Server{
+		Addr:    ":" + port,
+		Handler: mux,
+	}
+}
+
+// serveMetrics serves the Prometheus metrics in the background if METRICS_PORT is set.
+// Failing to serve them is logged and never stops the gRPC server.
+func (s DownloadServer) serveMetrics() {
+	if s.metricsServer == nil {
+		return
+	}
+
+	go func() {
+		s.logger.Infof("serving metrics on %s%s", s.metricsServer.Addr, metricsPath)
+		if err := s.metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			s.logger.Errorf("failed to serve metrics: %v", err)
+		}
+	}()
+}
+
+// closeMetrics stops serving the Prometheus metrics, if served.
+func (s DownloadServer) closeMetrics() {
+	if s.metricsServer == nil {
+		return
+	}
+
+	if err := s.metricsServer.Close(); err != nil {
+		s.logger.Errorf("failed to close metrics server: %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/meateam/download-service/download"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+)
+
+func TestConfigureMetrics(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	tests := []struct {
+		name        string
+		port        string
+		wantMetrics []string
+	}{
+		{name: "metrics - disabled"},
+		{
+			name: "metrics - enabled",
+			port: "9100",
+			wantMetrics: []string{
+				`download_service_bytes_streamed_total{key_prefix="photos/"} 3072`,
+				`download_service_downloads_total{code="OK",key_prefix="photos/"} 1`,
+				`download_service_downloads_total{code="NotFound",key_prefix="other"} 1`,
+				"download_service_part_fetch_duration_seconds_count 2",
+				`# {trace_id="4bf92f3577b34da6a3ce929d0e0e4736"} 0.02`,
+				"download_service_part_fetch_bytes_total 3072",
+				"download_service_active_streams 0",
+				`download_service_failovers_total{region="eu-west-1"} 1`,
+				"download_service_shedding 0",
+				"download_service_egress_remaining_bytes 1000",
+				"download_service_log_buffer_depth 0",
+				"download_service_log_buffer_dropped_total 0",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			downloadService := download.NewService(nil, logger)
+			downloadService.EgressQuota = download.NewEgressQuota(1000, time.Hour)
+			logBuffer := newLogBufferHook(logrus.LevelHooks{}, 10, false, ioutil.Discard)
+			defer logBuffer.Close()
+			downloadService.LogBuffer = logBuffer
+			metricsServer := configureMetrics(downloadService, tt.port)
+			if (metricsServer != nil) != (tt.port != "") || (downloadService.Metrics != nil) != (tt.port != "") {
+				t.Fatalf("configureMetrics() = %v, want metrics served %v", metricsServer, tt.port != "")
+			}
+
+			if metricsServer == nil {
+				return
+			}
+
+			downloadService.Metrics.ObservePart(10*time.Millisecond, 1024, "")
+			downloadService.Metrics.ObservePart(20*time.Millisecond, 2048, "4bf92f3577b34da6a3ce929d0e0e4736")
+			downloadService.Metrics.ObserveDownload(codes.OK, "photos/", 3072)
+			downloadService.Metrics.ObserveDownload(codes.NotFound, download.OtherKeyPrefix, 0)
+			downloadService.Metrics.ObserveFailover("eu-west-1")
+
+			server := httptest.NewServer(metricsServer.Handler)
+			defer server.Close()
+
+			// Exemplars are exposed only in the OpenMetrics format.
+			req, err := http.NewRequest(http.MethodGet, server.URL+metricsPath, nil)
+			if err != nil {
+				t.Fatalf("failed to create request, %v", err)
+			}
+			req.Header.Set("Accept", "application/openmetrics-text; version=0.0.1")
+
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("GET %s error = %v", metricsPath, err)
+			}
+			defer res.Body.Close()
+
+			body, err := ioutil.ReadAll(res.Body)
+			if err != nil {
+				t.Fatalf("failed to read metrics, %v", err)
+			}
+
+			for _, metric := range tt.wantMetrics {
+				if !strings.Contains(string(body), metric) {
+					t.Errorf("GET %s = %s, want it to contain %q", metricsPath, body, metric)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"encoding/json"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+// payloadFields are the log fields the payload interceptors log request and response payloads in.
+var payloadFields = []string{"grpc.request.content", "grpc.response.content"}
+
+// payloadTruncationHook is a logrus.Hook that replaces payloads larger than maxSize bytes
+// of JSON with a summary of their first truncatedSize bytes and their total size,
+// protecting Elasticsearch from oversized documents.
+type payloadTruncationHook struct {
+	maxSize       int
+	truncatedSize int
+}
+
+// Levels returns all levels, payloads are truncated at every level.
+func (h payloadTruncationHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire truncates the oversized payloads of entry.
+func (h payloadTruncationHook) Fire(entry *logrus.Entry) error {
+	var data logrus.Fields
+	for _, field := range payloadFields {
+		value, ok := entry.Data[field]
+		if !ok {
+			continue
+		}
+
+		payload, err := json.Marshal(value)
+		if err != nil || len(payload) <= h.maxSize {
+			continue
+		}
+
+		// Copy the entry's fields on the first change, they may be shared with other entries.
+		if data == nil {
+			data = make(logrus.Fields, len(entry.Data))
+			for k, v := range entry.Data {
+				data[k] = v
+			}
+		}
+
+		data[field] = map[string]interface{}{
+			"truncated": truncateUTF8(payload, h.truncatedSize),
+			"size":      len(payload),
+		}
+	}
+
+	if data != nil {
+		entry.Data = data
+	}
+
+	return nil
+}
+
+// truncateUTF8 returns the first size bytes of b as a string, without splitting a rune.
+func truncateUTF8(b []byte, size int) string {
+	if size >= len(b) {
+		return string(b)
+	}
+
+	for size > 0 && !utf8.RuneStart(b[size]) {
+		size--
+	}
+
+	return string(b[:size])
+}
+
+// addFirstHook adds hook to logger before its existing hooks,
+// so they see the entries as modified by hook.
+func addFirstHook(logger *logrus.Logger, hook logrus.Hook) {
+	hooks := make(logrus.LevelHooks)
+	hooks.Add(hook)
+	for level, levelHooks := range logger.Hooks {
+		hooks[level] = append(hooks[level], levelHooks...)
+	}
+
+	logger.ReplaceHooks(hooks)
+}
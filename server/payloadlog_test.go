@@ -0,0 +1,87 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	pb "github.com/meateam/download-service/proto"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestPayloadTruncationHook(t *testing.T) {
+	const (
+		maxSize       = 128
+		truncatedSize = 16
+	)
+
+	small := &pb.DownloadRequest{Key: "key", Bucket: "bucket"}
+	oversized := &pb.DownloadRequest{Key: strings.Repeat("k", 1024), Bucket: "bucket"}
+	oversizedPayload, err := json.Marshal(oversized)
+	if err != nil {
+		t.Fatalf("failed to marshal payload: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		payload       interface{}
+		wantTruncated bool
+	}{
+		{name: "small payload", payload: small},
+		{name: "oversized payload", payload: oversized, wantTruncated: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logrus.New()
+			logger.SetOutput(ioutil.Discard)
+
+			// The recording hook is added first, but must see the truncated entry.
+			recorder := test.NewLocal(logger)
+			addFirstHook(logger, payloadTruncationHook{maxSize: maxSize, truncatedSize: truncatedSize})
+
+			entry := logger.WithField("grpc.request.content", tt.payload)
+			entry.Info("server request payload logged as grpc.request.content field")
+
+			got := recorder.LastEntry().Data["grpc.request.content"]
+			if !tt.wantTruncated {
+				if got != tt.payload {
+					t.Errorf("payload = %v, want it untouched", got)
+				}
+
+				return
+			}
+
+			summary, ok := got.(map[string]interface{})
+			if !ok {
+				t.Fatalf("payload = %v, want a truncated summary", got)
+			}
+
+			if summary["size"] != len(oversizedPayload) {
+				t.Errorf("truncated payload size = %v, want %d", summary["size"], len(oversizedPayload))
+			}
+
+			if summary["truncated"] != string(oversizedPayload[:truncatedSize]) {
+				t.Errorf("truncated payload = %q, want %q", summary["truncated"], oversizedPayload[:truncatedSize])
+			}
+
+			// The entry the payload was logged with is left untouched.
+			if entry.Data["grpc.request.content"] != tt.payload {
+				t.Errorf("original entry payload was modified")
+			}
+		})
+	}
+}
+
+func TestTruncateUTF8(t *testing.T) {
+	if got := truncateUTF8([]byte("aé"), 2); got != "a" {
+		t.Errorf("truncateUTF8() = %q, want %q", got, "a")
+	}
+
+	if got := truncateUTF8([]byte("abc"), 10); got != "abc" {
+		t.Errorf("truncateUTF8() = %q, want %q", got, "abc")
+	}
+}
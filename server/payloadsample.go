@@ -0,0 +1,59 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+
+	ilogger "github.com/meateam/elasticsearch-logger"
+	"github.com/sirupsen/logrus"
+)
+
+// parsePayloadSampleRates parses rates formatted as "method=rate,method=rate" into a map of
+// full method names to the rate of their payloads that are logged, between 0 and 1.
+// Invalid entries are logged to logger and skipped.
+func parsePayloadSampleRates(logger *logrus.Logger, rates string) map[string]float64 {
+	sampleRates := make(map[string]float64)
+	for _, entry := range strings.Split(rates, ",") {
+		if entry == "" {
+			continue
+		}
+
+		method, rateValue := splitMethodValue(entry)
+		rate, err := strconv.ParseFloat(rateValue, 64)
+		if err != nil || method == "" || rate < 0 || rate > 1 {
+			logger.Warnf("ignoring invalid payload log sample rate %q, want method=rate with a rate in [0, 1]", entry)
+			continue
+		}
+
+		sampleRates[method] = rate
+	}
+
+	return sampleRates
+}
+
+// splitMethodValue splits entry formatted as "method=value" into the method and the value.
+func splitMethodValue(entry string) (string, string) {
+	i := strings.LastIndex(entry, "=")
+	if i < 0 {
+		return entry, ""
+	}
+
+	return entry[:i], entry[i+1:]
+}
+
+// sampledPayloadDecider returns an ilogger.DeciderFunc that logs the payloads of the methods
+// in rates at their rate, sampled with random, overriding decider, which decides the rest.
+func sampledPayloadDecider(
+	decider ilogger.DeciderFunc,
+	rates map[string]float64,
+	random func() float64,
+) ilogger.DeciderFunc {
+	return func(fullMethodName string) bool {
+		rate, ok := rates[fullMethodName]
+		if !ok {
+			return decider(fullMethodName)
+		}
+
+		return rate > 0 && random() < rate
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	ilogger "github.com/meateam/elasticsearch-logger"
+	"github.com/sirupsen/logrus"
+)
+
+func TestParsePayloadSampleRates(t *testing.T) {
+	tests := []struct {
+		name  string
+		rates string
+		want  map[string]float64
+	}{
+		{name: "sample rates - empty", rates: "", want: map[string]float64{}},
+		{
+			name:  "sample rates - methods",
+			rates: "/download.Download/Download=0.01,/download.Download/GetDownloadManifest=1",
+			want: map[string]float64{
+				"/download.Download/Download":            0.01,
+				"/download.Download/GetDownloadManifest": 1,
+			},
+		},
+		{
+			name:  "sample rates - invalid entries skipped",
+			rates: "/download.Download/Download=2,=0.5,/download.Download/ListObjects,/download.Admin/GetStats=0",
+			want:  map[string]float64{"/download.Admin/GetStats": 0},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logrus.New()
+			logger.SetOutput(ioutil.Discard)
+
+			if got := parsePayloadSampleRates(logger, tt.rates); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePayloadSampleRates() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSampledPayloadDecider(t *testing.T) {
+	const samples = 10000
+
+	ignored := "/grpc.health.v1.Health/Check"
+	decider := sampledPayloadDecider(
+		ilogger.IgnoreServerMethodsDecider(ignored, "/download.Download/Download"),
+		map[string]float64{
+			"/download.Download/Download":            0.1,
+			"/download.Download/GetDownloadManifest": 1,
+			"/download.Download/ListObjects":         0,
+		},
+		rand.New(rand.NewSource(1)).Float64,
+	)
+
+	// Interleave the methods, every rate applies to its own method's payloads only.
+	wantRates := map[string]float64{
+		"/download.Download/Download":            0.1,
+		"/download.Download/GetDownloadManifest": 1,
+		"/download.Download/ListObjects":         0,
+		"/download.Admin/GetStats":               1,
+		ignored:                                  0,
+	}
+	logged := make(map[string]int)
+	for i := 0; i < samples; i++ {
+		for method := range wantRates {
+			if decider(method) {
+				logged[method]++
+			}
+		}
+	}
+
+	for method, wantRate := range wantRates {
+		if rate := float64(logged[method]) / samples; math.Abs(rate-wantRate) > 0.02 {
+			t.Errorf("sampledPayloadDecider() logged %.3f of %s payloads, want %.3f", rate, method, wantRate)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/meateam/download-service/download"
+)
+
+const (
+	// readinessService is the health service whose status is NOT_SERVING while S3 is unhealthy
+	// or degraded, or the server is draining, for load balancers that route by readiness.
+	// The status of the server's health, "", is NOT_SERVING only while S3 is unhealthy or draining.
+	readinessService = "readiness"
+
+	// defaultProbeLatencyWindow is the default number of latest probes whose average latency
+	// is compared against the degraded threshold.
+	defaultProbeLatencyWindow = 10
+)
+
+// probeLatency tracks the latency of the successful health probes of a dependency, in a summary
+// over time and as the average of the latest probes. The dependency is degraded while the average
+// exceeds a threshold.
+type probeLatency struct {
+	threshold time.Duration
+	summary   *download.LatencyStats
+
+	mu     sync.Mutex
+	recent []time.Duration
+	next   int
+}
+
+// newProbeLatency returns a probeLatency of the average of the latest window probes, a non-positive
+// window defaults to defaultProbeLatencyWindow, degraded while the average exceeds threshold.
+// A non-positive threshold never degrades.
+func newProbeLatency(threshold time.Duration, window int) *probeLatency {
+	if window <= 0 {
+		window = defaultProbeLatencyWindow
+	}
+
+	return &probeLatency{
+		threshold: threshold,
+		summary:   download.NewLatencyStats(),
+		recent:    make([]time.Duration, 0, window),
+	}
+}
+
+// observe adds the latency d of a successful probe. A nil probeLatency ignores it.
+func (p *probeLatency) observe(d time.Duration) {
+	if p == nil {
+		return
+	}
+
+	p.summary.Observe(d)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.recent) < cap(p.recent) {
+		p.recent = append(p.recent, d)
+		return
+	}
+
+	p.recent[p.next] = d
+	p.next = (p.next + 1) % len(p.recent)
+}
+
+// average returns the average latency of the latest probes. It must be called with p.mu held.
+func (p *probeLatency) average() time.Duration {
+	if len(p.recent) == 0 {
+		return 0
+	}
+
+	var sum time.Duration
+	for _, d := range p.recent {
+		sum += d
+	}
+
+	return sum / time.Duration(len(p.recent))
+}
+
+// degraded returns whether the average latency of the latest probes exceeds the threshold.
+// A nil probeLatency is never degraded.
+func (p *probeLatency) degraded() bool {
+	if p == nil || p.threshold <= 0 {
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.average() > p.threshold
+}
+
+// Stats returns the stats of the probe, resetting its latency summary if reset is true.
+func (p *probeLatency) Stats(reset bool) download.ProbeStats {
+	p.mu.Lock()
+	recent := p.average()
+	p.mu.Unlock()
+
+	return download.ProbeStats{
+		Latency:       p.summary.Snapshot(reset),
+		RecentLatency: recent,
+		Degraded:      p.degraded(),
+	}
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// slowS3Client returns an S3 client whose calls succeed after delay, listing no buckets.
+func slowS3Client(delay time.Duration) *s3.S3 {
+	client := s3.New(session.Must(session.NewSession(&aws.Config{
+		Region:           aws.String("us-east-1"),
+		Endpoint:         aws.String("http://s3.test"),
+		Credentials:      credentials.NewStaticCredentials("key", "secret", ""),
+		S3ForcePathStyle: aws.Bool(true),
+	})))
+	client.Handlers.Send.Clear()
+	client.Handlers.Send.PushBack(func(r *request.Request) {
+		time.Sleep(delay)
+		r.HTTPResponse = &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(strings.NewReader("<ListAllMyBucketsResult></ListAllMyBucketsResult>")),
+		}
+	})
+
+	return client
+}
+
+func TestProbeLatency(t *testing.T) {
+	tests := []struct {
+		name         string
+		threshold    time.Duration
+		observed     []time.Duration
+		wantRecent   time.Duration
+		wantDegraded bool
+	}{
+		{name: "probe latency - no probes", threshold: time.Second},
+		{
+			name:       "probe latency - below threshold",
+			threshold:  time.Second,
+			observed:   []time.Duration{100 * time.Millisecond, 300 * time.Millisecond},
+			wantRecent: 200 * time.Millisecond,
+		},
+		{
+			name:         "probe latency - above threshold",
+			threshold:    time.Second,
+			observed:     []time.Duration{time.Second, 2 * time.Second},
+			wantRecent:   1500 * time.Millisecond,
+			wantDegraded: true,
+		},
+		{
+			name:      "probe latency - recovered",
+			threshold: time.Second,
+			observed: []time.Duration{
+				5 * time.Second, 5 * time.Second, 5 * time.Second,
+				100 * time.Millisecond, 100 * time.Millisecond, 100 * time.Millisecond,
+			},
+			wantRecent: 100 * time.Millisecond,
+		},
+		{
+			name:       "probe latency - disabled threshold",
+			observed:   []time.Duration{5 * time.Second},
+			wantRecent: 5 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			p := newProbeLatency(tt.threshold, 3)
+			for _, d := range tt.observed {
+				p.observe(d)
+			}
+
+			stats := p.Stats(false)
+			if stats.RecentLatency != tt.wantRecent {
+				t.Errorf("probeLatency.Stats() recent latency = %v, want %v", stats.RecentLatency, tt.wantRecent)
+			}
+
+			if stats.Degraded != tt.wantDegraded {
+				t.Errorf("probeLatency.Stats() degraded = %v, want %v", stats.Degraded, tt.wantDegraded)
+			}
+
+			if stats.Latency.Count != int64(len(tt.observed)) {
+				t.Errorf("probeLatency.Stats() count = %d, want %d", stats.Latency.Count, len(tt.observed))
+			}
+		})
+	}
+}
+
+func TestDownloadServer_checkHealthDegraded(t *testing.T) {
+	tests := []struct {
+		name          string
+		delay         time.Duration
+		wantReadiness grpc_health_v1.HealthCheckResponse_ServingStatus
+	}{
+		{
+			name:          "degraded - fast s3",
+			wantReadiness: grpc_health_v1.HealthCheckResponse_SERVING,
+		},
+		{
+			name:          "degraded - slow but successful s3",
+			delay:         50 * time.Millisecond,
+			wantReadiness: grpc_health_v1.HealthCheckResponse_NOT_SERVING,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logrus.New()
+			logger.SetOutput(ioutil.Discard)
+
+			s3Latency := newProbeLatency(20*time.Millisecond, 3)
+			downloadService := download.NewService(slowS3Client(tt.delay), logger)
+			downloadService.S3Probe = s3Latency
+			server := DownloadServer{
+				logger:          logger,
+				downloadService: downloadService,
+				healthServer:    health.NewServer(),
+				s3Latency:       s3Latency,
+			}
+
+			server.checkHealth()
+
+			// S3 is healthy regardless of its latency.
+			for service, want := range map[string]grpc_health_v1.HealthCheckResponse_ServingStatus{
+				"":               grpc_health_v1.HealthCheckResponse_SERVING,
+				readinessService: tt.wantReadiness,
+			} {
+				res, err := server.healthServer.Check(
+					context.Background(),
+					&grpc_health_v1.HealthCheckRequest{Service: service},
+				)
+				if err != nil {
+					t.Fatalf("Health.Check(%q) error = %v", service, err)
+				}
+
+				if res.GetStatus() != want {
+					t.Errorf("Health.Check(%q) status = %v, want %v", service, res.GetStatus(), want)
+				}
+			}
+
+			stats, err := downloadService.GetStats(context.Background(), &pb.GetStatsRequest{})
+			if err != nil {
+				t.Fatalf("DownloadService.GetStats() error = %v", err)
+			}
+
+			wantDegraded := tt.wantReadiness == grpc_health_v1.HealthCheckResponse_NOT_SERVING
+			if probe := stats.GetS3Probe(); probe.GetLatency().GetCount() != 1 || probe.GetDegraded() != wantDegraded {
+				t.Errorf("DownloadService.GetStats() s3 probe = %v, want a single probe, degraded %v", probe, wantDegraded)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"github.com/sirupsen/logrus"
+)
+
+// parseQoSWeights parses weights formatted as "class=weight,class=weight" into the weights of the
+// QoS classes, with case-insensitive class names, starting from download.DefaultQoSWeights.
+// Invalid entries are logged to logger and skipped.
+func parseQoSWeights(logger *logrus.Logger, weights string) map[pb.QoSClass]int64 {
+	qosWeights := make(map[pb.QoSClass]int64, len(download.DefaultQoSWeights))
+	for class, weight := range download.DefaultQoSWeights {
+		qosWeights[class] = weight
+	}
+
+	for _, entry := range strings.Split(weights, ",") {
+		if entry == "" {
+			continue
+		}
+
+		className, weightValue := splitMethodValue(entry)
+		class, ok := pb.QoSClass_value[strings.ToUpper(strings.TrimSpace(className))]
+		weight, err := strconv.ParseInt(weightValue, 10, 64)
+		if !ok || err != nil || weight <= 0 {
+			logger.Warnf("ignoring invalid qos weight %q, want class=weight with a positive weight", entry)
+			continue
+		}
+
+		qosWeights[pb.QoSClass(class)] = weight
+	}
+
+	return qosWeights
+}
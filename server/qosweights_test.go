@@ -0,0 +1,46 @@
+package server
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	pb "github.com/meateam/download-service/proto"
+	"github.com/sirupsen/logrus"
+)
+
+func TestParseQoSWeights(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights string
+		want    map[pb.QoSClass]int64
+	}{
+		{
+			name:    "qos weights - empty",
+			weights: "",
+			want:    map[pb.QoSClass]int64{pb.QoSClass_PREMIUM: 4, pb.QoSClass_STANDARD: 2, pb.QoSClass_BULK: 1},
+		},
+		{
+			name:    "qos weights - classes",
+			weights: "premium=10,BULK=3",
+			want:    map[pb.QoSClass]int64{pb.QoSClass_PREMIUM: 10, pb.QoSClass_STANDARD: 2, pb.QoSClass_BULK: 3},
+		},
+		{
+			name:    "qos weights - invalid entries skipped",
+			weights: "gold=5,premium=0,standard,bulk=x,standard=7",
+			want:    map[pb.QoSClass]int64{pb.QoSClass_PREMIUM: 4, pb.QoSClass_STANDARD: 7, pb.QoSClass_BULK: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logrus.New()
+			logger.SetOutput(ioutil.Discard)
+
+			if got := parseQoSWeights(logger, tt.weights); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseQoSWeights() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
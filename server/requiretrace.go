@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"strings"
+
+	"github.com/meateam/download-service/download"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// traceExemptMethodPrefixes are the prefixes of the methods served without a trace context in strict mode.
+var traceExemptMethodPrefixes = []string{
+	"/grpc.health.v1.Health/",
+	"/grpc.reflection.",
+}
+
+// checkTraceParent returns an InvalidArgument error if the request of ctx to fullMethod
+// carries no trace id valid for any of extractors and fullMethod isn't exempt.
+// It doesn't use download.ExtractTraceID, which falls back to the trace id
+// of the server's own APM transaction when the request carries none.
+func checkTraceParent(ctx context.Context, fullMethod string, extractors []download.TraceExtractor) error {
+	for _, prefix := range traceExemptMethodPrefixes {
+		if strings.HasPrefix(fullMethod, prefix) {
+			return nil
+		}
+	}
+
+	if download.RequestTraceID(ctx, extractors) == "" {
+		return status.Error(codes.InvalidArgument, "request is missing a valid trace context header")
+	}
+
+	return nil
+}
+
+// requireTraceStreamServerInterceptor returns a grpc.StreamServerInterceptor
+// that rejects streams without a trace id valid for any of extractors.
+func requireTraceStreamServerInterceptor(extractors []download.TraceExtractor) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		if err := checkTraceParent(stream.Context(), info.FullMethod, extractors); err != nil {
+			return err
+		}
+
+		return handler(srv, stream)
+	}
+}
+
+// requireTraceUnaryServerInterceptor returns a grpc.UnaryServerInterceptor
+// that rejects calls without a trace id valid for any of extractors.
+func requireTraceUnaryServerInterceptor(extractors []download.TraceExtractor) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if err := checkTraceParent(ctx, info.FullMethod, extractors); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/meateam/download-service/download"
+	"go.elastic.co/apm"
+	"go.elastic.co/apm/module/apmhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// contextServerStream is a grpc.ServerStream of ctx.
+type contextServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s contextServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestRequireTraceInterceptors(t *testing.T) {
+	traceparent := apmhttp.FormatTraceparentHeader(apm.TraceContext{
+		Trace: apm.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		Span:  apm.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		md         metadata.MD
+		extractors []download.TraceExtractor
+		wantCalled bool
+		wantCode   codes.Code
+	}{
+		{
+			name:       "traceparent present",
+			method:     "/download.Download/Download",
+			md:         metadata.Pairs(apmhttp.TraceparentHeader, traceparent),
+			wantCalled: true,
+			wantCode:   codes.OK,
+		},
+		{
+			name:     "traceparent absent",
+			method:   "/download.Download/Download",
+			md:       metadata.MD{},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "traceparent invalid",
+			method:   "/download.Download/Download",
+			md:       metadata.Pairs(apmhttp.TraceparentHeader, "invalid"),
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:       "w3c traceparent enabled",
+			method:     "/download.Download/Download",
+			md:         metadata.Pairs(download.W3CTraceparentHeader, traceparent),
+			extractors: []download.TraceExtractor{download.ElasticAPMTraceExtractor, download.W3CTraceExtractor},
+			wantCalled: true,
+			wantCode:   codes.OK,
+		},
+		{
+			name:     "w3c traceparent not enabled",
+			method:   "/download.Download/Download",
+			md:       metadata.Pairs(download.W3CTraceparentHeader, traceparent),
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:       "health check exempt",
+			method:     "/grpc.health.v1.Health/Check",
+			md:         metadata.MD{},
+			wantCalled: true,
+			wantCode:   codes.OK,
+		},
+		{
+			name:       "reflection exempt",
+			method:     "/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo",
+			md:         metadata.MD{},
+			wantCalled: true,
+			wantCode:   codes.OK,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+
+			streamCalled := false
+			err := requireTraceStreamServerInterceptor(tt.extractors)(
+				nil,
+				contextServerStream{ctx: ctx},
+				&grpc.StreamServerInfo{FullMethod: tt.method},
+				func(interface{}, grpc.ServerStream) error {
+					streamCalled = true
+					return nil
+				},
+			)
+			if status.Code(err) != tt.wantCode || streamCalled != tt.wantCalled {
+				t.Errorf(
+					"stream interceptor error = %v, called = %v, want code %v, called %v",
+					err, streamCalled, tt.wantCode, tt.wantCalled,
+				)
+			}
+
+			unaryCalled := false
+			_, err = requireTraceUnaryServerInterceptor(tt.extractors)(
+				ctx,
+				nil,
+				&grpc.UnaryServerInfo{FullMethod: tt.method},
+				func(context.Context, interface{}) (interface{}, error) {
+					unaryCalled = true
+					return nil, nil
+				},
+			)
+			if status.Code(err) != tt.wantCode || unaryCalled != tt.wantCalled {
+				t.Errorf(
+					"unary interceptor error = %v, called = %v, want code %v, called %v",
+					err, unaryCalled, tt.wantCode, tt.wantCalled,
+				)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+const (
+	// defaultS3MaxRetries is the SDK's default number of retries of S3 calls.
+	defaultS3MaxRetries = 3
+
+	// maxDuration is the longest time.Duration, the max delay of an uncapped backoff.
+	maxDuration = time.Duration(1<<63 - 1)
+)
+
+// s3Retryer is a request.Retryer that retries like client.DefaultRetryer,
+// with a jittered exponential backoff from baseDelay capped at maxDelay.
+type s3Retryer struct {
+	client.DefaultRetryer
+	baseDelay time.Duration
+	maxDelay  time.Duration
+}
+
+// RetryRules returns the delay before the next retry of r, a random delay between half
+// and all of baseDelay doubled for every retry so far, at most maxDelay.
+func (r s3Retryer) RetryRules(req *request.Request) time.Duration {
+	delay := r.maxDelay
+	retryCount := req.RetryCount
+	if retryCount < 62 && r.baseDelay < r.maxDelay>>uint(retryCount) {
+		delay = r.baseDelay << uint(retryCount)
+	}
+
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// newS3Retryer validates the retry configuration and returns the retryer of the S3 client,
+// nil to use the SDK's default backoff when baseDelay is zero.
+// A maxRetries of aws.UseServiceDefaultRetries uses the SDK's default number of retries,
+// a zero maxDelay leaves the backoff uncapped.
+func newS3Retryer(maxRetries int, baseDelay time.Duration, maxDelay time.Duration) (request.Retryer, error) {
+	if maxRetries < aws.UseServiceDefaultRetries {
+		return nil, fmt.Errorf("S3 max retries must be at least %d, got %d", aws.UseServiceDefaultRetries, maxRetries)
+	}
+
+	if baseDelay < 0 || maxDelay < 0 {
+		return nil, fmt.Errorf("S3 retry delays must not be negative, got %v and %v", baseDelay, maxDelay)
+	}
+
+	if baseDelay == 0 {
+		if maxDelay > 0 {
+			return nil, fmt.Errorf("S3 max retry delay requires a base retry delay")
+		}
+
+		return nil, nil
+	}
+
+	if maxDelay == 0 {
+		maxDelay = maxDuration
+	}
+
+	if maxDelay < baseDelay {
+		return nil, fmt.Errorf("S3 max retry delay %v is less than the base retry delay %v", maxDelay, baseDelay)
+	}
+
+	if maxRetries == aws.UseServiceDefaultRetries {
+		maxRetries = defaultS3MaxRetries
+	}
+
+	return s3Retryer{
+		DefaultRetryer: client.DefaultRetryer{NumMaxRetries: maxRetries},
+		baseDelay:      baseDelay,
+		maxDelay:       maxDelay,
+	}, nil
+}
+
+// describeRetryer describes the effective retry configuration of retryer, for logging.
+func describeRetryer(retryer request.Retryer) string {
+	if r, ok := retryer.(s3Retryer); ok {
+		return fmt.Sprintf("max retries %d, base delay %v, max delay %v", r.MaxRetries(), r.baseDelay, r.maxDelay)
+	}
+
+	return fmt.Sprintf("max retries %d, default backoff", retryer.MaxRetries())
+}
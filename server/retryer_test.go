@@ -0,0 +1,97 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/spf13/viper"
+)
+
+func TestNewS3ClientRetryer(t *testing.T) {
+	tests := []struct {
+		name           string
+		maxRetries     int
+		baseDelayMs    int64
+		maxDelayMs     int64
+		wantErr        bool
+		wantMaxRetries int
+		wantCustom     bool
+	}{
+		{name: "sdk defaults", maxRetries: -1, wantMaxRetries: 3},
+		{name: "max retries", maxRetries: 7, wantMaxRetries: 7},
+		{name: "no retries", maxRetries: 0, wantMaxRetries: 0},
+		{name: "backoff", maxRetries: 5, baseDelayMs: 10, maxDelayMs: 100, wantMaxRetries: 5, wantCustom: true},
+		{name: "uncapped backoff", maxRetries: -1, baseDelayMs: 10, wantMaxRetries: 3, wantCustom: true},
+		{name: "invalid max retries", maxRetries: -2, wantErr: true},
+		{name: "negative delay", maxRetries: -1, baseDelayMs: -1, wantErr: true},
+		{name: "max delay without base delay", maxRetries: -1, maxDelayMs: 100, wantErr: true},
+		{name: "max delay below base delay", maxRetries: -1, baseDelayMs: 100, maxDelayMs: 10, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			for config, value := range map[string]interface{}{
+				configS3MaxRetries:     tt.maxRetries,
+				configS3RetryBaseDelay: tt.baseDelayMs,
+				configS3RetryMaxDelay:  tt.maxDelayMs,
+			} {
+				previous := viper.Get(config)
+				viper.Set(config, value)
+				defer viper.Set(config, previous)
+			}
+
+			s3Client, err := newS3Client()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newS3Client() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if got := s3Client.Retryer.MaxRetries(); got != tt.wantMaxRetries {
+				t.Errorf("newS3Client() max retries = %d, want %d", got, tt.wantMaxRetries)
+			}
+
+			retryer, custom := s3Client.Retryer.(s3Retryer)
+			if custom != tt.wantCustom {
+				t.Fatalf("newS3Client() retryer = %T, want custom %v", s3Client.Retryer, tt.wantCustom)
+			}
+
+			if custom && retryer.baseDelay != time.Duration(tt.baseDelayMs)*time.Millisecond {
+				t.Errorf("newS3Client() base delay = %v, want %dms", retryer.baseDelay, tt.baseDelayMs)
+			}
+		})
+	}
+}
+
+func TestS3Retryer_RetryRules(t *testing.T) {
+	retryer := s3Retryer{baseDelay: 10 * time.Millisecond, maxDelay: 100 * time.Millisecond}
+
+	for retryCount, wantMax := range []time.Duration{
+		10 * time.Millisecond,
+		20 * time.Millisecond,
+		40 * time.Millisecond,
+		80 * time.Millisecond,
+		100 * time.Millisecond,
+		100 * time.Millisecond,
+	} {
+		for i := 0; i < 100; i++ {
+			delay := retryer.RetryRules(&request.Request{RetryCount: retryCount})
+			if delay < wantMax/2 || delay > wantMax {
+				t.Fatalf(
+					"s3Retryer.RetryRules() of retry %d = %v, want between %v and %v",
+					retryCount, delay, wantMax/2, wantMax,
+				)
+			}
+		}
+	}
+
+	// Very high retry counts don't overflow.
+	delay := retryer.RetryRules(&request.Request{RetryCount: 100})
+	if delay < 50*time.Millisecond || delay > retryer.maxDelay {
+		t.Errorf("s3Retryer.RetryRules() of retry 100 = %v, want at most %v", delay, retryer.maxDelay)
+	}
+}
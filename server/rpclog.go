@@ -0,0 +1,162 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/logrus/ctxlogrus"
+	"github.com/meateam/download-service/download"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// rpcFinishedMessage is the message of the single log entry written per finished RPC.
+	rpcFinishedMessage = "rpc.finished"
+)
+
+// fileChunk is implemented by stream messages that carry file bytes, such as DownloadResponse.
+type fileChunk interface {
+	GetFile() []byte
+}
+
+// rpcLogger logs one "rpc.finished" entry for every RPC with its resolved status,
+// at okLevel when the RPC succeeded and at errLevel when it failed, unless methodLevels
+// disables the level for the RPC's method.
+type rpcLogger struct {
+	logger       *logrus.Logger
+	okLevel      logrus.Level
+	errLevel     logrus.Level
+	methodLevels methodLogLevels
+
+	// traceExtractors extract the trace ids of the calls, nil uses download.DefaultTraceExtractors.
+	traceExtractors []download.TraceExtractor
+}
+
+// countingServerStream is a grpc.ServerStream that counts the file bytes sent on it.
+type countingServerStream struct {
+	grpc.ServerStream
+	bytesSent int64
+}
+
+// SendMsg sends m on the underlying stream and counts its file bytes, if it has any.
+func (s *countingServerStream) SendMsg(m interface{}) error {
+	if err := s.ServerStream.SendMsg(m); err != nil {
+		return err
+	}
+
+	if chunk, ok := m.(fileChunk); ok {
+		s.bytesSent += int64(len(chunk.GetFile()))
+	}
+
+	return nil
+}
+
+// newRPCLogger creates an rpcLogger from the level names okLevel and errLevel,
+// falling back to info and error levels when they cannot be parsed.
+func newRPCLogger(
+	logger *logrus.Logger,
+	okLevel string,
+	errLevel string,
+	methodLevels methodLogLevels,
+) *rpcLogger {
+	l := &rpcLogger{
+		logger:       logger,
+		okLevel:      logrus.InfoLevel,
+		errLevel:     logrus.ErrorLevel,
+		methodLevels: methodLevels,
+	}
+
+	if level, err := logrus.ParseLevel(okLevel); err == nil {
+		l.okLevel = level
+	} else {
+		logger.Warnf("invalid rpc log level %q, using %s", okLevel, l.okLevel)
+	}
+
+	if level, err := logrus.ParseLevel(errLevel); err == nil {
+		l.errLevel = level
+	} else {
+		logger.Warnf("invalid rpc error log level %q, using %s", errLevel, l.errLevel)
+	}
+
+	return l
+}
+
+// StreamServerInterceptor returns a grpc.StreamServerInterceptor that logs the
+// finished stream, including the number of file bytes streamed to the client.
+func (l *rpcLogger) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		startTime := time.Now()
+		countingStream := &countingServerStream{ServerStream: stream}
+
+		err := handler(srv, countingStream)
+
+		l.log(stream.Context(), info.FullMethod, startTime, err, logrus.Fields{
+			"grpc.bytes_sent": countingStream.bytesSent,
+		})
+
+		return err
+	}
+}
+
+// UnaryServerInterceptor returns a grpc.UnaryServerInterceptor that logs the finished call.
+func (l *rpcLogger) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		startTime := time.Now()
+
+		resp, err := handler(ctx, req)
+
+		l.log(ctx, info.FullMethod, startTime, err, logrus.Fields{})
+
+		return resp, err
+	}
+}
+
+// log writes the "rpc.finished" entry of fullMethod with the status of err.
+func (l *rpcLogger) log(
+	ctx context.Context,
+	fullMethod string,
+	startTime time.Time,
+	err error,
+	fields logrus.Fields,
+) {
+	level := l.okLevel
+	if err != nil {
+		level = l.errLevel
+	}
+
+	if !l.methodLevels.enabled(fullMethod, level) {
+		return
+	}
+
+	rpcStatus := status.Convert(err)
+
+	// Include the fields the handler tagged the call with, such as "key.prefix".
+	for field, value := range ctxlogrus.Extract(ctx).Data {
+		if _, ok := fields[field]; !ok {
+			fields[field] = value
+		}
+	}
+
+	fields["grpc.method"] = fullMethod
+	fields["grpc.code"] = rpcStatus.Code().String()
+	fields["grpc.time_ms"] = float64(time.Since(startTime)) / float64(time.Millisecond)
+	fields["trace.id"] = download.ExtractTraceID(ctx, l.traceExtractors)
+
+	if err != nil {
+		fields["grpc.message"] = rpcStatus.Message()
+	}
+
+	l.logger.WithFields(fields).Log(level, rpcFinishedMessage)
+}
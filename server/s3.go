@@ -0,0 +1,80 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/spf13/viper"
+	"go.elastic.co/apm/module/apmhttp"
+)
+
+// newS3Client creates an S3 client from the S3 configuration variables.
+func newS3Client() (*s3.S3, error) {
+	// Configuration variables
+	s3AccessKey := viper.GetString(configS3AccessKey)
+	s3SecretKey := viper.GetString(configS3SecretKey)
+	s3Endpoint := viper.GetString(configS3Endpoint)
+	s3Token := viper.GetString(configS3Token)
+	s3Region := viper.GetString(configS3Region)
+	s3SSL := viper.GetBool(configS3SSL)
+	s3UseDualStack := viper.GetBool(configS3UseDualStack)
+	s3MaxRetries := viper.GetInt(configS3MaxRetries)
+
+	retryer, err := newS3Retryer(
+		s3MaxRetries,
+		time.Duration(viper.GetInt64(configS3RetryBaseDelay))*time.Millisecond,
+		time.Duration(viper.GetInt64(configS3RetryMaxDelay))*time.Millisecond,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Dualstack endpoints are resolved from the region, a custom endpoint would silently override them.
+	if s3UseDualStack && s3Endpoint != "" {
+		return nil, fmt.Errorf(
+			"%s requires %s to be empty",
+			strings.ToUpper(configS3UseDualStack),
+			strings.ToUpper(configS3Endpoint),
+		)
+	}
+
+	if s3UseDualStack && s3Region == "" {
+		return nil, fmt.Errorf(
+			"%s requires %s",
+			strings.ToUpper(configS3UseDualStack),
+			strings.ToUpper(configS3Region),
+		)
+	}
+
+	// Configure to use S3 Server
+	s3Config := &aws.Config{
+		Credentials:      credentials.NewStaticCredentials(s3AccessKey, s3SecretKey, s3Token),
+		Endpoint:         aws.String(s3Endpoint),
+		Region:           aws.String(s3Region),
+		DisableSSL:       aws.Bool(!s3SSL),
+		S3ForcePathStyle: aws.Bool(true),
+		UseDualStack:     aws.Bool(s3UseDualStack),
+		MaxRetries:       aws.Int(s3MaxRetries),
+		HTTPClient:       apmhttp.WrapClient(http.DefaultClient),
+	}
+
+	if retryer != nil {
+		s3Config = request.WithRetryer(s3Config, retryer)
+	}
+
+	// Open a session to s3.
+	newSession, err := session.NewSession(s3Config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a client from the s3 session.
+	return s3.New(newSession), nil
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/spf13/viper"
+)
+
+func TestNewS3Client(t *testing.T) {
+	tests := []struct {
+		name             string
+		endpoint         string
+		region           string
+		useDualStack     bool
+		wantErr          bool
+		wantEndpoint     string
+		wantUseDualStack bool
+	}{
+		{
+			name:         "custom endpoint",
+			endpoint:     "http://localhost:9000",
+			region:       "us-east-1",
+			wantEndpoint: "http://localhost:9000",
+		},
+		{
+			name:             "dualstack",
+			region:           "eu-west-1",
+			useDualStack:     true,
+			wantEndpoint:     "http://s3.dualstack.eu-west-1.amazonaws.com",
+			wantUseDualStack: true,
+		},
+		{
+			name:         "dualstack with custom endpoint",
+			endpoint:     "http://localhost:9000",
+			region:       "us-east-1",
+			useDualStack: true,
+			wantErr:      true,
+		},
+		{name: "dualstack without region", useDualStack: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			for config, value := range map[string]interface{}{
+				configS3Endpoint:     tt.endpoint,
+				configS3Region:       tt.region,
+				configS3SSL:          false,
+				configS3UseDualStack: tt.useDualStack,
+			} {
+				previous := viper.Get(config)
+				viper.Set(config, value)
+				defer viper.Set(config, previous)
+			}
+
+			s3Client, err := newS3Client()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newS3Client() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if s3Client.Endpoint != tt.wantEndpoint {
+				t.Errorf("newS3Client() endpoint = %q, want %q", s3Client.Endpoint, tt.wantEndpoint)
+			}
+
+			if useDualStack := aws.BoolValue(s3Client.Config.UseDualStack); useDualStack != tt.wantUseDualStack {
+				t.Errorf("newS3Client() UseDualStack = %v, want %v", useDualStack, tt.wantUseDualStack)
+			}
+		})
+	}
+}
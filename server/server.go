@@ -1,14 +1,16 @@
 package server
 
 import (
+	"context"
+	"io"
+	"math/rand"
 	"net"
 	"net/http"
+	"os"
 	"strings"
+	"syscall"
 	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	grpc_logrus "github.com/grpc-ecosystem/go-grpc-middleware/logging/logrus"
 	"github.com/meateam/download-service/download"
@@ -16,10 +18,10 @@ import (
 	ilogger "github.com/meateam/elasticsearch-logger"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
-	"go.elastic.co/apm/module/apmhttp"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
 )
 
 const (
@@ -32,6 +34,102 @@ const (
 	configS3SecretKey          = "s3_secret_key"
 	configS3Region             = "s3_region"
 	configS3SSL                = "s3_ssl"
+	configS3UseDualStack       = "s3_use_dualstack"
+	configS3MaxRetries         = "s3_max_retries"
+	configS3RetryBaseDelay     = "s3_retry_base_delay_ms"
+	configS3RetryMaxDelay      = "s3_retry_max_delay_ms"
+	configRPCLogLevel          = "rpc_log_level"
+	configRPCErrorLogLevel     = "rpc_error_log_level"
+	configDebug                = "debug"
+	configAuditBufferSize      = "audit_buffer_size"
+	configKeyPrefixAllowlist   = "key_prefix_allowlist"
+	configMaxBufferSize        = "download_max_buffer_size"
+	configPartSize             = "download_part_size"
+	configMaxKeyLength         = "max_key_length"
+	configMaxRequestKeys       = "max_request_keys"
+	configMaxRequestSize       = "max_request_size"
+	configJaegerEndpoint       = "jaeger_endpoint"
+	configJaegerServiceName    = "jaeger_service_name"
+	configJaegerSamplerType    = "jaeger_sampler_type"
+	configJaegerSamplerParam   = "jaeger_sampler_param"
+	configCompleteWebhook      = "download_complete_webhook"
+	configStrictOrderAssert    = "strict_order_assert"
+	configShardBuckets         = "shard_buckets"
+	configFailoverBuckets      = "failover_buckets"
+	configKeyTemplate          = "key_template"
+	configKeyTemplateVars      = "key_template_vars"
+	configHeadCacheTTL         = "head_cache_ttl"
+	configHeadCacheShards      = "head_cache_shards"
+	configHeadCacheMaxEntries  = "head_cache_max_entries"
+	configHeadCacheBucketTTLs  = "head_cache_bucket_ttls"
+	configWarmKeys             = "warm_keys"
+	configServeStaleOnError    = "serve_stale_on_error"
+	configHeadCacheMaxStale    = "head_cache_max_staleness"
+	configRequireTrace         = "require_trace"
+	configTraceExtractors      = "trace_extractors"
+	configAlignNativeParts     = "align_native_parts"
+	configRangeFallback        = "range_fallback_threshold"
+	configHeadDeniedFallback   = "head_denied_fallback"
+	configTrustClientSize      = "trust_client_size"
+	configChaosEnabled         = "chaos_enabled"
+	configChaosPartDelay       = "chaos_part_delay_ms"
+	configChaosErrorProb       = "chaos_error_probability"
+	configChaosSendDelay       = "chaos_send_delay_ms"
+	configPayloadLogMaxSize    = "payload_log_max_size"
+	configPayloadLogTruncated  = "payload_log_truncated_size"
+	configPayloadLogSampleRate = "payload_log_sample_rates"
+	configMethodLogLevels      = "method_log_levels"
+	configSpillDir             = "spill_dir"
+	configSpillMaxSize         = "spill_max_size"
+	configFetchBufferDepth     = "fetch_buffer_depth"
+	configSequentialMaxSize    = "sequential_prefetch_max_size"
+	configSequentialTTL        = "sequential_prefetch_ttl_ms"
+	configPartConcurrency      = "part_concurrency"
+	configDownloadConcurrency  = "download_concurrency"
+	configConcurrencyMinSize   = "concurrency_min_size"
+	configAutoTuneConcurrency  = "auto_tune_part_concurrency"
+	configCoalesceMaxSize      = "coalesce_max_size"
+	configCoalesceGap          = "coalesce_gap"
+	configSubjectMaxDownloads  = "subject_max_concurrent_downloads"
+	configEgressLimit          = "daily_egress_limit"
+	configEgressWindow         = "egress_limit_window_seconds"
+	configShedHighWater        = "shed_high_water"
+	configShedLowWater         = "shed_low_water"
+	configShedRetryAfter       = "shed_retry_after_ms"
+	configCacheBucket          = "cache_bucket"
+	configPerStreamMaxRate     = "per_stream_max_bytes_per_sec"
+	configDownloadRateLimit    = "download_rate_limit"
+	configPartRetries          = "download_max_retries"
+	configPartRetryBaseDelay   = "download_retry_base_delay"
+	configQoSBytesPerSec       = "qos_bytes_per_sec"
+	configQoSWeights           = "qos_weights"
+	configMaxDeltaSize         = "max_delta_size"
+	configWriteHealthBucket    = "write_health_bucket"
+	configWriteHealthInterval  = "write_health_interval_seconds"
+	configLogConsoleFormat     = "log_console_format"
+	configLogBufferSize        = "log_buffer_size"
+	configLogBufferBlock       = "log_buffer_block"
+	configRequireEncryption    = "require_encryption"
+	configVerifyChecksums      = "verify_checksums"
+	configAllowDelegatedCreds  = "allow_delegated_credentials"
+	configFollowPollInterval   = "follow_poll_interval_ms"
+	configFollowIdleTimeout    = "follow_idle_timeout_ms"
+	configAccessLogBucket      = "access_log_bucket"
+	configAccessLogPrefix      = "access_log_prefix"
+	configAccessLogInterval    = "access_log_flush_interval_seconds"
+	configShutdownGracePeriod  = "shutdown_grace_period"
+	configTLSCertFile          = "tls_cert_file"
+	configTLSKeyFile           = "tls_key_file"
+	configTLSClientCAFile      = "tls_client_ca_file"
+	configS3ProbeDegraded      = "s3_probe_degraded_threshold_ms"
+	configS3ProbeWindow        = "s3_probe_latency_window"
+	configMetricsPort          = "metrics_port"
+	configElasticsearchURL     = "elasticsearch_url"
+	configElasticsearchUser    = "elasticsearch_user"
+	configElasticsearchPass    = "elasticsearch_password"
+	configElasticsearchSkipTLS = "tls_skip_verify"
+	configElasticsearchTimeout = "elasticsearch_probe_timeout_ms"
+	configDeadLetterIndex      = "dead_letter_log_index"
 )
 
 func init() {
@@ -44,6 +142,98 @@ func init() {
 	viper.SetDefault(configS3SecretKey, "")
 	viper.SetDefault(configS3Region, "us-east-1")
 	viper.SetDefault(configS3SSL, false)
+	viper.SetDefault(configS3UseDualStack, false)
+	viper.SetDefault(configS3MaxRetries, -1)
+	viper.SetDefault(configS3RetryBaseDelay, 0)
+	viper.SetDefault(configS3RetryMaxDelay, 0)
+	viper.SetDefault(configRPCLogLevel, logrus.InfoLevel.String())
+	viper.SetDefault(configRPCErrorLogLevel, logrus.ErrorLevel.String())
+	viper.SetDefault(configDebug, false)
+	viper.SetDefault(configAuditBufferSize, download.DefaultAuditBufferSize)
+	viper.SetDefault(configKeyPrefixAllowlist, "")
+	viper.SetDefault(configMaxBufferSize, download.PartSize)
+	viper.SetDefault(configPartSize, download.PartSize)
+	viper.SetDefault(configMaxKeyLength, download.DefaultMaxKeyLength)
+	viper.SetDefault(configMaxRequestKeys, download.DefaultMaxRequestKeys)
+	viper.SetDefault(configMaxRequestSize, download.DefaultMaxRequestSize)
+	viper.SetDefault(configJaegerEndpoint, "")
+	viper.SetDefault(configJaegerServiceName, "download-service")
+	viper.SetDefault(configJaegerSamplerType, "const")
+	viper.SetDefault(configJaegerSamplerParam, 1)
+	viper.SetDefault(configCompleteWebhook, "")
+	viper.SetDefault(configStrictOrderAssert, false)
+	viper.SetDefault(configShardBuckets, "")
+	viper.SetDefault(configFailoverBuckets, "")
+	viper.SetDefault(configKeyTemplate, "")
+	viper.SetDefault(configKeyTemplateVars, "")
+	viper.SetDefault(configHeadCacheTTL, 0)
+	viper.SetDefault(configHeadCacheShards, 16)
+	viper.SetDefault(configHeadCacheMaxEntries, 10000)
+	viper.SetDefault(configHeadCacheBucketTTLs, "")
+	viper.SetDefault(configWarmKeys, "")
+	viper.SetDefault(configServeStaleOnError, false)
+	viper.SetDefault(configHeadCacheMaxStale, 300)
+	viper.SetDefault(configRequireTrace, false)
+	viper.SetDefault(configTraceExtractors, "elastic-apm")
+	viper.SetDefault(configAlignNativeParts, false)
+	viper.SetDefault(configRangeFallback, 0)
+	viper.SetDefault(configHeadDeniedFallback, false)
+	viper.SetDefault(configTrustClientSize, false)
+	viper.SetDefault(configChaosEnabled, false)
+	viper.SetDefault(configChaosPartDelay, 0)
+	viper.SetDefault(configChaosErrorProb, 0)
+	viper.SetDefault(configChaosSendDelay, 0)
+	viper.SetDefault(configPayloadLogMaxSize, 32<<10)
+	viper.SetDefault(configPayloadLogTruncated, 1<<10)
+	viper.SetDefault(configPayloadLogSampleRate, "")
+	viper.SetDefault(configMethodLogLevels, "")
+	viper.SetDefault(configSpillDir, "")
+	viper.SetDefault(configSpillMaxSize, 4*download.PartSize)
+	viper.SetDefault(configFetchBufferDepth, 0)
+	viper.SetDefault(configSequentialMaxSize, 0)
+	viper.SetDefault(configSequentialTTL, int64(download.DefaultSequentialPrefetchTTL/time.Millisecond))
+	viper.SetDefault(configPartConcurrency, 0)
+	viper.SetDefault(configDownloadConcurrency, 0)
+	viper.SetDefault(configConcurrencyMinSize, 0)
+	viper.SetDefault(configAutoTuneConcurrency, false)
+	viper.SetDefault(configCoalesceMaxSize, 0)
+	viper.SetDefault(configCoalesceGap, 0)
+	viper.SetDefault(configSubjectMaxDownloads, 0)
+	viper.SetDefault(configEgressLimit, 0)
+	viper.SetDefault(configEgressWindow, int64(download.DefaultEgressWindow/time.Second))
+	viper.SetDefault(configShedHighWater, 0)
+	viper.SetDefault(configShedLowWater, 0)
+	viper.SetDefault(configShedRetryAfter, 1000)
+	viper.SetDefault(configCacheBucket, "")
+	viper.SetDefault(configPerStreamMaxRate, 0)
+	viper.SetDefault(configDownloadRateLimit, 0)
+	viper.SetDefault(configPartRetries, 0)
+	viper.SetDefault(configPartRetryBaseDelay, download.DefaultPartRetryBaseDelay)
+	viper.SetDefault(configQoSBytesPerSec, 0)
+	viper.SetDefault(configQoSWeights, "")
+	viper.SetDefault(configMaxDeltaSize, download.DefaultMaxDeltaSize)
+	viper.SetDefault(configWriteHealthBucket, "")
+	viper.SetDefault(configWriteHealthInterval, int64(defaultWriteProbeInterval/time.Second))
+	viper.SetDefault(configLogConsoleFormat, consoleFormatJSON)
+	viper.SetDefault(configLogBufferSize, 0)
+	viper.SetDefault(configLogBufferBlock, false)
+	viper.SetDefault(configRequireEncryption, false)
+	viper.SetDefault(configVerifyChecksums, false)
+	viper.SetDefault(configAllowDelegatedCreds, false)
+	viper.SetDefault(configFollowPollInterval, int64(download.DefaultFollowPollInterval/time.Millisecond))
+	viper.SetDefault(configFollowIdleTimeout, int64(download.DefaultFollowIdleTimeout/time.Millisecond))
+	viper.SetDefault(configAccessLogBucket, "")
+	viper.SetDefault(configAccessLogPrefix, "access-logs/")
+	viper.SetDefault(configAccessLogInterval, int64(defaultAccessLogFlushInterval/time.Second))
+	viper.SetDefault(configShutdownGracePeriod, defaultShutdownGracePeriod)
+	viper.SetDefault(configTLSCertFile, "")
+	viper.SetDefault(configTLSKeyFile, "")
+	viper.SetDefault(configTLSClientCAFile, "")
+	viper.SetDefault(configS3ProbeDegraded, 0)
+	viper.SetDefault(configS3ProbeWindow, defaultProbeLatencyWindow)
+	viper.SetDefault(configMetricsPort, "")
+	viper.SetDefault(configElasticsearchTimeout, defaultElasticsearchProbeTimeout.Milliseconds())
+	viper.SetDefault(configDeadLetterIndex, "")
 	viper.AutomaticEnv()
 }
 
@@ -54,6 +244,15 @@ type DownloadServer struct {
 	tcpPort             string
 	healthCheckInterval int
 	downloadService     *download.Service
+	healthServer        *health.Server
+	tracerCloser        io.Closer
+	accessLogCloser     io.Closer
+	logBuffer           *logBufferHook
+	writeProbe          *writeProbe
+	esProbe             *elasticsearchProbe
+	s3Latency           *probeLatency
+	shutdown            *shutdownState
+	metricsServer       *http.Server
 }
 
 // GetService returns a copy of the underlying download service.
@@ -69,6 +268,7 @@ func (s *DownloadServer) GetService() download.Service {
 // If `lis` is nil then Serve creates a `net.Listener` with "tcp" network listening
 // on the configured `TCP_PORT`, which defaults to "8080".
 // Serve will return a non-nil error unless Stop or GracefulStop is called.
+// Once Shutdown is called, Serve returns after the server stopped.
 func (s DownloadServer) Serve(lis net.Listener) {
 	listener := lis
 	if lis == nil {
@@ -80,62 +280,299 @@ func (s DownloadServer) Serve(lis net.Listener) {
 		listener = l
 	}
 
+	// Serve the metrics on their own port, alongside the grpc server.
+	s.serveMetrics()
+	defer s.closeMetrics()
+
 	s.logger.Infof("listening and serving grpc server on port %s", s.tcpPort)
 	if err := s.Server.Serve(listener); err != nil {
 		s.logger.Fatalf(err.Error())
 	}
+
+	// Let the active calls complete if the server is shutting down.
+	s.shutdown.wait()
+
+	// Flush the spans that weren't reported yet.
+	if s.tracerCloser != nil {
+		if err := s.tracerCloser.Close(); err != nil {
+			s.logger.Errorf("failed to close tracer: %v", err)
+		}
+	}
+
+	// Flush the access logs that weren't shipped yet.
+	if s.accessLogCloser != nil {
+		if err := s.accessLogCloser.Close(); err != nil {
+			s.logger.Errorf("failed to close access log: %v", err)
+		}
+	}
+
+	// Ship the logs left in the buffer.
+	if s.logBuffer != nil {
+		if err := s.logBuffer.Close(); err != nil {
+			s.logger.Errorf("failed to close log buffer: %v", err)
+		}
+	}
 }
 
 // NewServer configures and creates a grpc.Server instance with the download service
 // health check service.
 // Configure using environment variables.
 // `HEALTH_CHECK_INTERVAL`: Interval to update serving state of the health check server.
+// `S3_PROBE_DEGRADED_THRESHOLD_MS`: Average latency in milliseconds of the latest S3 health probes above which
+// the "readiness" health service reports NOT_SERVING though S3 is healthy, 0 disables it.
+// `S3_PROBE_LATENCY_WINDOW`: Number of latest S3 health probes whose latency is averaged, defaults to 10.
+// `WRITE_HEALTH_BUCKET`: Bucket that a scratch object is put to and deleted from to check that S3 is writable,
+// disabled when empty.
+// `WRITE_HEALTH_INTERVAL_SECONDS`: Seconds between checks of WRITE_HEALTH_BUCKET, defaults to 300.
+// `ELASTICSEARCH_PROBE_TIMEOUT_MS`: Milliseconds to wait for the Elasticsearch cluster that logs are shipped to
+// to respond to a health probe, defaults to 2000. Probed only when logging to it.
 // `S3_ACCESS_KEY`: S3 accress key to connect with s3 backend.
 // `S3_SECRET_KEY`: S3 secret key to connect with s3 backend.
 // `S3_ENDPOINT`: S3 endpoint of s3 backend to connect to.
 // `S3_TOKEN`: S3 token of s3 backend to connect to.
 // `S3_REGION`: S3 ergion of s3 backend to connect to.
 // `S3_SSL`: Enable or Disable SSL on S3 connection.
+// `S3_USE_DUALSTACK`: Use the dualstack (IPv6) endpoint of S3_REGION, requires an empty S3_ENDPOINT,
+// defaults to false.
+// `S3_MAX_RETRIES`: Maximum retries of a failed S3 call by the SDK, -1 uses the SDK's default of 3.
+// `S3_RETRY_BASE_DELAY_MS`: Base delay of the SDK's exponential retry backoff, 0 uses the SDK's default backoff.
+// `S3_RETRY_MAX_DELAY_MS`: Maximum delay between SDK retries, 0 leaves the backoff uncapped.
+// `RPC_LOG_LEVEL`: Log level of the "rpc.finished" entry of successful calls, defaults to "info".
+// `RPC_ERROR_LOG_LEVEL`: Log level of the "rpc.finished" entry of failed calls, defaults to "error".
+// `METHOD_LOG_LEVELS`: Least severe log level of the entries of methods, formatted as
+// "/package.Service/Method=level,...", e.g. "/grpc.health.v1.Health/Check=warn".
+// `DEBUG`: Register the admin service, which exposes the download statistics and audit events.
+// `AUDIT_BUFFER_SIZE`: Audit events buffered for every subscriber of the admin service's audit feed,
+// events are dropped for subscribers that fall further behind, defaults to 100.
+// `DOWNLOAD_MAX_BUFFER_SIZE`: Maximum bytes buffered per download, defaults to the part size.
+// `DOWNLOAD_PART_SIZE`: Bytes of the parts objects are downloaded from S3 in, unless requests set their
+// chunk size, between 256KiB and 64MiB, defaults to 5MiB.
+// `MAX_KEY_LENGTH`: Maximum bytes of the keys, prefixes and URLs of requests, 0 disables the limit,
+// defaults to 1024.
+// `MAX_REQUEST_KEYS`: Maximum keys of a request, 0 disables the limit, defaults to 1000.
+// `MAX_REQUEST_SIZE`: Maximum bytes of a serialized request, 0 disables the limit, defaults to 1MiB.
+// `KEY_PREFIX_ALLOWLIST`: Comma separated top-level key prefixes to tag logs with, others are tagged "other".
+// `JAEGER_ENDPOINT`: Jaeger collector endpoint to report download spans to, tracing is disabled when empty.
+// `JAEGER_SERVICE_NAME`: Service name of the reported spans, defaults to "download-service".
+// `JAEGER_SAMPLER_TYPE`: Jaeger sampler type, "const", "probabilistic" or "ratelimiting", defaults to "const".
+// `JAEGER_SAMPLER_PARAM`: Jaeger sampler parameter, defaults to 1.
+// `DOWNLOAD_COMPLETE_WEBHOOK`: URL to POST an event to when a download finishes, disabled when empty.
+// `STRICT_ORDER_ASSERT`: Fail downloads that are about to send a chunk out of order, defaults to false.
+// `SHARD_BUCKETS`: Comma-separated buckets that keys of requests without a bucket are sharded across by hash.
+// `FAILOVER_BUCKETS`: Replicas in secondary regions that downloads fail over to, in order, when the region
+// of their bucket fails, formatted as "bucket=replica|replica,bucket=replica", disabled when empty.
+// `KEY_TEMPLATE`: Template that maps the keys of requests to the keys in S3, e.g. "{env}/{key}",
+// the keys are used as is when empty. The server doesn't start if it references undefined variables.
+// `KEY_TEMPLATE_VARS`: Variables of the key template, formatted as "name=value,name=value".
+// `HEAD_CACHE_TTL`: Seconds to cache the HeadObject results of downloaded objects for, 0 disables caching
+// except for the buckets of HEAD_CACHE_BUCKET_TTLS.
+// `HEAD_CACHE_SHARDS`: Number of independently locked shards of the head cache, defaults to 16.
+// `HEAD_CACHE_MAX_ENTRIES`: Entries of the head cache above which the least recently used are evicted,
+// 0 leaves it unbounded, defaults to 10000.
+// `HEAD_CACHE_BUCKET_TTLS`: Seconds to cache the HeadObject results of the objects of specific buckets for,
+// overriding HEAD_CACHE_TTL, formatted as "bucket=seconds,bucket=seconds", 0 disables caching a bucket.
+// `WARM_KEYS`: Comma-separated bucket/key objects whose HeadObject results are cached at startup.
+// `SERVE_STALE_ON_ERROR`: Serve expired HeadObject results of the head cache, with an "x-cache-status: stale"
+// trailer, when S3 fails with a server error or a failure to connect, defaults to false.
+// `HEAD_CACHE_MAX_STALENESS`: Seconds after their expiry that head cache entries are served by
+// SERVE_STALE_ON_ERROR, defaults to 300.
+// `ALIGN_NATIVE_PARTS`: Download multipart objects in ranges aligned to their native parts, defaults to false.
+// `RANGE_FALLBACK_THRESHOLD`: Ranged GetObject failures of a download after which the rest is downloaded
+// by a single non-ranged GetObject, 0 disables the fallback.
+// `HEAD_DENIED_FALLBACK`: Learn the size of objects by a ranged GetObject of their first byte when
+// HeadObject is denied, for stores that allow only GetObject, defaults to false.
+// `TRUST_CLIENT_SIZE`: Skip the HeadObject call of downloads whose requests set the object's known size,
+// validating it against the first part downloaded, defaults to false.
+// `CHAOS_ENABLED`: Inject faults into downloads to test clients, never enable in production, defaults to false.
+// `CHAOS_PART_DELAY_MS`: Milliseconds to delay every part by in chaos mode,
+// overridable by the request's headers.
+// `CHAOS_ERROR_PROBABILITY`: Probability to fail before every part in chaos mode,
+// overridable by the request's headers.
+// `CHAOS_SEND_DELAY_MS`: Milliseconds to delay every chunk sent by in chaos mode,
+// overridable by the request's headers.
+// `PAYLOAD_LOG_MAX_SIZE`: Bytes of logged payloads above which they're truncated, 0 disables truncation,
+// defaults to 32KiB.
+// `PAYLOAD_LOG_TRUNCATED_SIZE`: Bytes of a truncated payload that are logged, defaults to 1KiB.
+// `PAYLOAD_LOG_SAMPLE_RATES`: Rates between 0 and 1 of the payloads logged per method, formatted as
+// "/package.Service/Method=rate,...", overriding whether the method's payloads are ignored.
+// `SPILL_DIR`: Directory to prefetch the parts of downloads into, prefetching is disabled when empty.
+// `SPILL_MAX_SIZE`: Maximum bytes a single download prefetches into SPILL_DIR, defaults to 4 parts.
+// `FETCH_BUFFER_DEPTH`: Chunks a download fetches from S3 ahead of the chunk being sent,
+// 0 fetches each part only once the previous one was sent.
+// `SEQUENTIAL_PREFETCH_MAX_SIZE`: Bytes of the ranges prefetched for clients reading consecutive ranges
+// of an object, before they request them, 0 disables sequential prefetching.
+// `SEQUENTIAL_PREFETCH_TTL_MS`: Milliseconds a prefetched range is kept for its client, defaults to 30000.
+// `DOWNLOAD_CONCURRENCY`: Parts a download fetches from S3 into memory at once, 0 and 1 fetch them
+// one at a time.
+// `PART_CONCURRENCY`: Deprecated name of DOWNLOAD_CONCURRENCY, used when it's 0.
+// `CONCURRENCY_MIN_SIZE`: Bytes up to which downloads fetch their parts one at a time despite
+// DOWNLOAD_CONCURRENCY, 0 fetches downloads of every size concurrently.
+// `AUTO_TUNE_PART_CONCURRENCY`: Tune the part concurrency of every download by its throughput,
+// up to DOWNLOAD_CONCURRENCY, defaults to false.
+// `COALESCE_MAX_SIZE`: Bytes of a single S3 read that adjacent parts fetched concurrently are coalesced into,
+// 0 fetches every part by its own read.
+// `COALESCE_GAP`: Bytes between ranges that are still coalesced into a single read, defaults to 0.
+// `SUBJECT_MAX_CONCURRENT_DOWNLOADS`: Concurrent downloads allowed per authenticated subject,
+// 0 disables the limit.
+// `DAILY_EGRESS_LIMIT`: Bytes served per EGRESS_LIMIT_WINDOW_SECONDS after which new downloads are rejected
+// as resource exhausted until the window resets, 0 disables the limit.
+// `EGRESS_LIMIT_WINDOW_SECONDS`: Seconds of the window of DAILY_EGRESS_LIMIT, defaults to a day.
+// `SHED_HIGH_WATER`: Active downloads at which new downloads are rejected as unavailable,
+// 0 disables load shedding.
+// `SHED_LOW_WATER`: Active downloads below which load shedding stops, must not exceed SHED_HIGH_WATER,
+// 0 defaults to 80% of SHED_HIGH_WATER, but at least 1.
+// `SHED_RETRY_AFTER_MS`: Milliseconds that shed downloads are told to retry after, defaults to 1000.
+// `CACHE_BUCKET`: Bucket that downloaded objects are copied to and then downloaded from,
+// disabled when empty.
+// `DOWNLOAD_RATE_LIMIT`: Rate in bytes per second that every download stream is paced to, requests may
+// lower it, 0 disables pacing unless requests set their rate.
+// `PER_STREAM_MAX_BYTES_PER_SEC`: Deprecated name of DOWNLOAD_RATE_LIMIT, used when it's 0.
+// `DOWNLOAD_MAX_RETRIES`: Times the download of a part that failed by an S3 server error or a failure
+// to connect is retried, on top of the SDK's retries, 0 disables retries.
+// `DOWNLOAD_RETRY_BASE_DELAY`: Delay before the first retry of a part, e.g. "250ms", doubled for every
+// further retry and jittered, defaults to 100ms.
+// `QOS_BYTES_PER_SEC`: Bandwidth budget shared by the downloads by the weights of their QoS classes,
+// 0 disables it.
+// `QOS_WEIGHTS`: Weights of the QoS classes in the bandwidth budget, formatted as "class=weight,...",
+// defaults to "premium=4,standard=2,bulk=1".
+// `MAX_DELTA_SIZE`: Bytes of the largest target version a delta is computed for by DownloadDelta,
+// larger versions are sent whole, defaults to 64MiB.
+// `REQUIRE_ENCRYPTION`: Refuse to serve objects that aren't encrypted at rest, defaults to false.
+// `VERIFY_CHECKSUMS`: Verify downloads of whole objects against the checksum they were uploaded with,
+// failing them with DataLoss on a mismatch, defaults to false.
+// `ALLOW_DELEGATED_CREDENTIALS`: Serve requests passing their own S3 credentials in the x-s3-access-key-id,
+// x-s3-secret-access-key and x-s3-session-token headers with them, defaults to false.
+// `FOLLOW_POLL_INTERVAL_MS`: Milliseconds between checks of followed objects for appended bytes,
+// defaults to 1000.
+// `FOLLOW_IDLE_TIMEOUT_MS`: Milliseconds without appended bytes after which following an object ends,
+// defaults to 60000.
+// `ACCESS_LOG_BUCKET`: Bucket that the "rpc.finished" access logs are shipped to as gzip-compressed objects,
+// disabled when empty.
+// `ACCESS_LOG_PREFIX`: Key prefix of the shipped access log objects, defaults to "access-logs/".
+// `ACCESS_LOG_FLUSH_INTERVAL_SECONDS`: Seconds between shipping the buffered access logs, defaults to 60.
+// `LOG_CONSOLE_FORMAT`: Format of the logs written to the console, json or text,
+// logs sent to Elasticsearch are always JSON, defaults to json.
+// `LOG_BUFFER_SIZE`: Log entries buffered for shipping to Elasticsearch in the background,
+// 0 ships them synchronously.
+// `LOG_BUFFER_BLOCK`: Block logging while the log buffer is full instead of dropping entries,
+// defaults to false.
+// `DEAD_LETTER_LOG_INDEX`: Elasticsearch index that the "download.failed.permanent" entries of downloads that
+// failed after all of their retries are shipped to, the index of the rest of the logs when empty.
+// `SHUTDOWN_GRACE_PERIOD`: Time the active calls are given to complete on SIGTERM or SIGINT, e.g. "45s",
+// after which they're cancelled, defaults to 30s.
+// `REQUIRE_TRACE`: Reject requests without a valid trace context, except health checks and reflection,
+// defaults to false.
+// `TRACE_EXTRACTORS`: Comma-separated trace context formats that trace ids are extracted from, tried in order,
+// "elastic-apm", "w3c", "b3" or "b3-multi", defaults to "elastic-apm".
+// `TLS_CERT_FILE`: PEM encoded certificate the grpc server serves TLS with, plaintext when empty.
+// `TLS_KEY_FILE`: PEM encoded private key of the TLS certificate, required with TLS_CERT_FILE.
+// `TLS_CLIENT_CA_FILE`: PEM encoded CAs that clients are required to present a certificate signed by,
+// for mutual TLS, disabled when empty. Requires TLS_CERT_FILE.
+// `METRICS_PORT`: TCP port on which the Prometheus metrics are served over HTTP on /metrics,
+// disabled when empty.
 // `TCP_PORT`: TCP port on which the grpc server would serve on.
 func NewServer(logger *logrus.Logger) *DownloadServer {
-	// Configuration variables
-	s3AccessKey := viper.GetString(configS3AccessKey)
-	s3SecretKey := viper.GetString(configS3SecretKey)
-	s3Endpoint := viper.GetString(configS3Endpoint)
-	s3Token := viper.GetString(configS3Token)
-	s3Region := viper.GetString(configS3Region)
-	s3SSL := viper.GetBool(configS3SSL)
-
-	// Configure to use S3 Server
-	s3Config := &aws.Config{
-		Credentials:      credentials.NewStaticCredentials(s3AccessKey, s3SecretKey, s3Token),
-		Endpoint:         aws.String(s3Endpoint),
-		Region:           aws.String(s3Region),
-		DisableSSL:       aws.Bool(!s3SSL),
-		S3ForcePathStyle: aws.Bool(true),
-		HTTPClient:       apmhttp.WrapClient(http.DefaultClient),
-	}
+	return NewServerWithConfig(logger, Config{})
+}
 
+// NewServerWithConfig creates a DownloadServer configured by the environment like NewServer, and by config,
+// e.g. with the interceptors authenticating the requests of the deployment.
+func NewServerWithConfig(logger *logrus.Logger, config Config) *DownloadServer {
 	// If no logger is given, create a new default logger for the server.
+	var logBuffer *logBufferHook
+	var esProbe *elasticsearchProbe
+	var deadLetterLogger *logrus.Logger
 	if logger == nil {
 		logger = ilogger.NewLogger()
+
+		// Ship the permanent failures of downloads to their own index, if set.
+		deadLetterLogger = newDeadLetterLogger()
+
+		// Probe the Elasticsearch cluster the logs are shipped to, for the health checks.
+		esProbe = newElasticsearchProbe(
+			viper.GetString(configElasticsearchURL),
+			viper.GetString(configElasticsearchUser),
+			viper.GetString(configElasticsearchPass),
+			time.Duration(viper.GetInt64(configElasticsearchTimeout))*time.Millisecond,
+			viper.GetBool(configElasticsearchSkipTLS),
+		)
+
+		// Ship the logs to Elasticsearch in the background, without waiting for it.
+		if logBufferSize := viper.GetInt(configLogBufferSize); logBufferSize > 0 {
+			logBuffer = bufferLogHooks(logger, logBufferSize, viper.GetBool(configLogBufferBlock), os.Stderr)
+		}
+
+		// Log text to the console, while Elasticsearch keeps receiving JSON.
+		if viper.GetString(configLogConsoleFormat) == consoleFormatText {
+			setConsoleText(logger, os.Stderr)
+		}
 	}
 
-	// Open a session to s3.
-	newSession, err := session.NewSession(s3Config)
+	// Truncate oversized payloads before they're logged by any other hook.
+	if payloadLogMaxSize := viper.GetInt(configPayloadLogMaxSize); payloadLogMaxSize > 0 {
+		addFirstHook(logger, payloadTruncationHook{
+			maxSize:       payloadLogMaxSize,
+			truncatedSize: viper.GetInt(configPayloadLogTruncated),
+		})
+	}
+
+	// Create a client of the S3 server.
+	s3Client, err := newS3Client()
 	if err != nil {
 		logger.Fatalf(err.Error())
 	}
-	logger.Infof("connected to S3 - %s", s3Endpoint)
+	logger.Infof("connected to S3 - %s", s3Client.Endpoint)
+	logger.Infof("S3 client retries - %s", describeRetryer(s3Client.Retryer))
+
+	// Ship the access logs to S3 for environments without Elasticsearch.
+	var accessLogCloser io.Closer
+	if accessLogBucket := viper.GetString(configAccessLogBucket); accessLogBucket != "" {
+		accessLogHook := newS3AccessLogHook(
+			s3Client,
+			accessLogBucket,
+			viper.GetString(configAccessLogPrefix),
+			time.Duration(viper.GetInt64(configAccessLogInterval))*time.Second,
+			logger,
+		)
+		logger.AddHook(accessLogHook)
+		accessLogCloser = accessLogHook
+		logger.Infof("shipping access logs to s3://%s/%s", accessLogBucket, viper.GetString(configAccessLogPrefix))
+	}
 
-	// Create a client from the s3 session.
-	s3Client := s3.New(newSession)
+	// Extract the trace ids of requests from the enabled trace context formats.
+	traceExtractors := parseTraceExtractors(logger, viper.GetString(configTraceExtractors))
 
-	// Set up grpc server opts with logger interceptor.
+	// Log a single "rpc.finished" entry with the resolved status of every call.
+	methodLevels := parseMethodLogLevels(logger, viper.GetString(configMethodLogLevels))
+	rpcLogger := newRPCLogger(
+		logger,
+		viper.GetString(configRPCLogLevel),
+		viper.GetString(configRPCErrorLogLevel),
+		methodLevels,
+	)
+	rpcLogger.traceExtractors = traceExtractors
+
+	// Set up grpc server opts with logger interceptor, and the interceptors of config inside it.
 	serverOpts := append(
-		serverLoggerInterceptor(logger),
-		grpc.MaxRecvMsgSize(10<<20),
+		serverLoggerInterceptor(logger, methodLevels),
+		chainInterceptors(rpcLogger, traceExtractors, viper.GetBool(configRequireTrace), config.Interceptors)...,
+	)
+	serverOpts = append(serverOpts, grpc.MaxRecvMsgSize(10<<20))
+
+	// Serve TLS, and mutual TLS if client CAs are set, plaintext otherwise for local development.
+	tlsCredentials, err := loadTLSCredentials(
+		viper.GetString(configTLSCertFile),
+		viper.GetString(configTLSKeyFile),
+		viper.GetString(configTLSClientCAFile),
 	)
+	if err != nil {
+		logger.Fatalf(err.Error())
+	}
+
+	if tlsCredentials != nil {
+		serverOpts = append(serverOpts, grpc.Creds(tlsCredentials))
+		logger.Infof("serving TLS, client certificates required - %v", viper.GetString(configTLSClientCAFile) != "")
+	}
 
 	// Create a new grpc server.
 	grpcServer := grpc.NewServer(
@@ -143,29 +580,244 @@ func NewServer(logger *logrus.Logger) *DownloadServer {
 	)
 
 	// Create a download service and register it on the grpc server.
-	downloadService := download.NewService(s3Client, logger)
+	downloadService := newDownloadService(s3Client, logger)
+	downloadService.TraceExtractors = traceExtractors
+	downloadService.DeadLetterLogger = deadLetterLogger
+	if logBuffer != nil {
+		downloadService.LogBuffer = logBuffer
+	}
+
+	// Trace downloads only when a Jaeger collector is configured.
+	var tracerCloser io.Closer
+	if jaegerEndpoint := viper.GetString(configJaegerEndpoint); jaegerEndpoint != "" {
+		tracer, closer, err := newTracer(
+			viper.GetString(configJaegerServiceName),
+			jaegerEndpoint,
+			viper.GetString(configJaegerSamplerType),
+			viper.GetFloat64(configJaegerSamplerParam),
+			logger,
+		)
+		if err != nil {
+			logger.Fatalf(err.Error())
+		}
+
+		downloadService.Tracer = tracer
+		tracerCloser = closer
+		logger.Infof("reporting spans to jaeger - %s", jaegerEndpoint)
+	}
 	pb.RegisterDownloadServer(grpcServer, downloadService)
 
+	// Expose the admin service only for debugging.
+	if viper.GetBool(configDebug) {
+		pb.RegisterAdminServer(grpcServer, downloadService)
+		logger.Infof("registered admin service")
+	}
+
 	// Create a health server and register it on the grpc server.
 	healthServer := health.NewServer()
 	grpc_health_v1.RegisterHealthServer(grpcServer, healthServer)
 
+	// Track the latency of the S3 health probes to detect a degraded S3.
+	s3Latency := newProbeLatency(
+		time.Duration(viper.GetInt64(configS3ProbeDegraded))*time.Millisecond,
+		viper.GetInt(configS3ProbeWindow),
+	)
+	downloadService.S3Probe = s3Latency
+
+	// Export the metrics of the downloads to Prometheus, if enabled.
+	metricsServer := configureMetrics(downloadService, viper.GetString(configMetricsPort))
+
 	downloadServer := &DownloadServer{
 		Server:              grpcServer,
 		logger:              logger,
 		tcpPort:             viper.GetString(configPort),
 		healthCheckInterval: viper.GetInt(configHealthCheckInterval),
 		downloadService:     downloadService,
+		healthServer:        healthServer,
+		tracerCloser:        tracerCloser,
+		accessLogCloser:     accessLogCloser,
+		logBuffer:           logBuffer,
+		s3Latency:           s3Latency,
+		esProbe:             esProbe,
+		shutdown:            newShutdownState(viper.GetDuration(configShutdownGracePeriod)),
+		metricsServer:       metricsServer,
+	}
+
+	// Probe that S3 is writable too, if opted in.
+	if writeHealthBucket := viper.GetString(configWriteHealthBucket); writeHealthBucket != "" {
+		downloadServer.writeProbe = newWriteProbe(
+			s3Client,
+			writeHealthBucket,
+			time.Duration(viper.GetInt64(configWriteHealthInterval))*time.Second,
+		)
 	}
 
 	// Health check validation goroutine worker.
-	go downloadServer.healthCheckWorker(healthServer)
+	go downloadServer.healthCheckWorker()
+
+	// Drain the active calls before stopping when the orchestrator stops the server.
+	downloadServer.shutdownOnSignal(syscall.SIGTERM, os.Interrupt)
 
 	return downloadServer
 }
 
+// newDownloadService creates a download service of s3Client configured by the environment.
+func newDownloadService(s3Client *s3.S3, logger *logrus.Logger) *download.Service {
+	downloadService := download.NewService(s3Client, logger)
+	downloadService.MaxBufferSize = viper.GetInt64(configMaxBufferSize)
+	downloadService.PartSize = viper.GetInt64(configPartSize)
+	if downloadService.PartSize < download.MinPartSize || downloadService.PartSize > download.MaxPartSize {
+		logger.Fatalf(
+			"invalid part size %d, must be between %d and %d",
+			downloadService.PartSize, download.MinPartSize, download.MaxPartSize,
+		)
+	}
+	downloadService.RequestLimits = download.RequestLimits{
+		MaxKeyLength:   viper.GetInt(configMaxKeyLength),
+		MaxKeys:        viper.GetInt(configMaxRequestKeys),
+		MaxRequestSize: viper.GetInt(configMaxRequestSize),
+	}
+	downloadService.StrictOrderAssert = viper.GetBool(configStrictOrderAssert)
+	downloadService.AlignToNativeParts = viper.GetBool(configAlignNativeParts)
+	downloadService.RangeFallbackThreshold = viper.GetInt(configRangeFallback)
+	downloadService.HeadDeniedFallback = viper.GetBool(configHeadDeniedFallback)
+	downloadService.TrustClientSize = viper.GetBool(configTrustClientSize)
+	downloadService.SpillDir = viper.GetString(configSpillDir)
+	downloadService.SpillMaxSize = viper.GetInt64(configSpillMaxSize)
+	downloadService.FetchBufferDepth = viper.GetInt(configFetchBufferDepth)
+	if sequentialMaxSize := viper.GetInt64(configSequentialMaxSize); sequentialMaxSize > 0 {
+		sequentialTTL := time.Duration(viper.GetInt64(configSequentialTTL)) * time.Millisecond
+		downloadService.SequentialPrefetch = download.NewSequentialPrefetcher(sequentialMaxSize, sequentialTTL)
+	}
+	downloadService.PartConcurrency = viper.GetInt(configDownloadConcurrency)
+	if downloadService.PartConcurrency == 0 {
+		downloadService.PartConcurrency = viper.GetInt(configPartConcurrency)
+	}
+	downloadService.ConcurrencyMinSize = viper.GetInt64(configConcurrencyMinSize)
+	downloadService.AutoTunePartConcurrency = viper.GetBool(configAutoTuneConcurrency)
+	downloadService.CoalesceMaxSize = viper.GetInt64(configCoalesceMaxSize)
+	downloadService.CoalesceGap = viper.GetInt64(configCoalesceGap)
+	if subjectMaxDownloads := viper.GetInt(configSubjectMaxDownloads); subjectMaxDownloads > 0 {
+		downloadService.SubjectLimiter = download.NewSubjectLimiter(subjectMaxDownloads)
+	}
+	if viper.GetBool(configDebug) {
+		downloadService.AuditFeed = download.NewAuditFeed(viper.GetInt(configAuditBufferSize))
+	}
+	if egressLimit := viper.GetInt64(configEgressLimit); egressLimit > 0 {
+		egressWindow := time.Duration(viper.GetInt64(configEgressWindow)) * time.Second
+		downloadService.EgressQuota = download.NewEgressQuota(egressLimit, egressWindow)
+	}
+	downloadService.ShedHighWater = viper.GetInt64(configShedHighWater)
+	downloadService.ShedLowWater = viper.GetInt64(configShedLowWater)
+	if downloadService.ShedHighWater > 0 && downloadService.ShedLowWater > downloadService.ShedHighWater {
+		logger.Fatalf(
+			"invalid shed low water %d, must not exceed the shed high water %d",
+			downloadService.ShedLowWater, downloadService.ShedHighWater,
+		)
+	}
+	downloadService.ShedRetryAfter = time.Duration(viper.GetInt64(configShedRetryAfter)) * time.Millisecond
+	downloadService.CacheBucket = viper.GetString(configCacheBucket)
+	downloadService.PerStreamMaxBytesPerSec = viper.GetInt64(configDownloadRateLimit)
+	if downloadService.PerStreamMaxBytesPerSec == 0 {
+		downloadService.PerStreamMaxBytesPerSec = viper.GetInt64(configPerStreamMaxRate)
+	}
+	downloadService.PartRetries = viper.GetInt(configPartRetries)
+	downloadService.PartRetryBaseDelay = viper.GetDuration(configPartRetryBaseDelay)
+	downloadService.MaxDeltaSize = viper.GetInt64(configMaxDeltaSize)
+	if qosBytesPerSec := viper.GetInt64(configQoSBytesPerSec); qosBytesPerSec > 0 {
+		qosWeights := parseQoSWeights(logger, viper.GetString(configQoSWeights))
+		downloadService.QoSBudget = download.NewQoSBudget(qosBytesPerSec, qosWeights)
+	}
+	downloadService.RequireEncryption = viper.GetBool(configRequireEncryption)
+	downloadService.VerifyChecksums = viper.GetBool(configVerifyChecksums)
+	downloadService.AllowDelegatedCredentials = viper.GetBool(configAllowDelegatedCreds)
+	downloadService.FollowPollInterval = time.Duration(viper.GetInt64(configFollowPollInterval)) * time.Millisecond
+	downloadService.FollowIdleTimeout = time.Duration(viper.GetInt64(configFollowIdleTimeout)) * time.Millisecond
+	if keyPrefixAllowlist := viper.GetString(configKeyPrefixAllowlist); keyPrefixAllowlist != "" {
+		downloadService.KeyPrefixAllowlist = strings.Split(keyPrefixAllowlist, ",")
+	}
+
+	// Route the keys of requests to their objects, and fail the buckets over to their replicas.
+	configureBuckets(downloadService, logger)
+
+	// Notify the webhook of finished downloads.
+	if completeWebhook := viper.GetString(configCompleteWebhook); completeWebhook != "" {
+		downloadService.CompletionHook = download.WebhookCompletionHook(
+			completeWebhook,
+			&http.Client{Timeout: 10 * time.Second},
+		)
+	}
+
+	// Inject faults into downloads only when chaos is explicitly enabled.
+	if viper.GetBool(configChaosEnabled) {
+		downloadService.Chaos = &download.Chaos{
+			PartDelay:        time.Duration(viper.GetInt64(configChaosPartDelay)) * time.Millisecond,
+			ErrorProbability: viper.GetFloat64(configChaosErrorProb),
+			SendDelay:        time.Duration(viper.GetInt64(configChaosSendDelay)) * time.Millisecond,
+		}
+		logger.Warnf("chaos mode is enabled, downloads will be delayed and fail on purpose")
+	}
+
+	// Cache HeadObject results and warm the cache with the hot objects.
+	configureHeadCache(downloadService, logger)
+
+	return downloadService
+}
+
+// configureBuckets sets the bucket router, the failover buckets and the key template of downloadService
+// configured by the environment, if enabled.
+func configureBuckets(downloadService *download.Service, logger *logrus.Logger) {
+	if shardBuckets := viper.GetString(configShardBuckets); shardBuckets != "" {
+		downloadService.BucketRouter = download.HashBucketRouter(strings.Split(shardBuckets, ","))
+	}
+	if failoverBuckets := viper.GetString(configFailoverBuckets); failoverBuckets != "" {
+		downloadService.FailoverBuckets = parseFailoverBuckets(logger, failoverBuckets)
+	}
+	if keyTemplate := viper.GetString(configKeyTemplate); keyTemplate != "" {
+		vars := parseKeyTemplateVars(logger, viper.GetString(configKeyTemplateVars))
+		template, err := download.ParseKeyTemplate(keyTemplate, vars)
+		if err != nil {
+			logger.Fatalf("invalid key template: %v", err)
+		}
+		downloadService.KeyTemplate = template
+	}
+}
+
+// configureHeadCache sets the head cache of downloadService configured by the environment, if enabled,
+// and warms it with the hot objects.
+func configureHeadCache(downloadService *download.Service, logger *logrus.Logger) {
+	headCacheTTL := viper.GetInt(configHeadCacheTTL)
+	bucketTTLs := parseBucketTTLs(logger, viper.GetString(configHeadCacheBucketTTLs))
+	if headCacheTTL > 0 || len(bucketTTLs) > 0 {
+		downloadService.HeadCache = download.NewShardedHeadCache(
+			time.Duration(headCacheTTL)*time.Second,
+			viper.GetInt(configHeadCacheShards),
+			viper.GetInt(configHeadCacheMaxEntries),
+		)
+		downloadService.HeadCache.SetBucketTTLs(bucketTTLs)
+		if viper.GetBool(configServeStaleOnError) {
+			downloadService.HeadCache.SetMaxStaleness(time.Duration(viper.GetInt(configHeadCacheMaxStale)) * time.Second)
+			downloadService.ServeStaleOnError = true
+		}
+		if warmKeys := viper.GetString(configWarmKeys); warmKeys != "" {
+			objects := strings.Split(warmKeys, ",")
+			warmed := downloadService.WarmHeadCache(context.Background(), objects, download.DefaultWarmConcurrency)
+			logger.Infof("warmed %d/%d keys", warmed, len(objects))
+		}
+
+		return
+	}
+
+	if viper.GetString(configWarmKeys) != "" {
+		logger.Warnf("ignoring %s since the head cache is disabled", strings.ToUpper(configWarmKeys))
+	}
+	if viper.GetBool(configServeStaleOnError) {
+		logger.Warnf("ignoring %s since the head cache is disabled", strings.ToUpper(configServeStaleOnError))
+	}
+}
+
 // serverLoggerInterceptor configures the logger interceptor for the download server.
-func serverLoggerInterceptor(logger *logrus.Logger) []grpc.ServerOption {
+func serverLoggerInterceptor(logger *logrus.Logger, methodLevels methodLogLevels) []grpc.ServerOption {
 	// Create new logrus entry for logger interceptor.
 	logrusEntry := logrus.NewEntry(logger)
 
@@ -173,6 +825,9 @@ func serverLoggerInterceptor(logger *logrus.Logger) []grpc.ServerOption {
 		append(
 			strings.Split(viper.GetString(configElasticAPMIgnoreURLS), ","),
 			"/download.Download/Download",
+			"/download.Download/DownloadDelta",
+			"/download.Download/DownloadConcatenated",
+			"/download.Admin/WatchAuditEvents",
 		)...,
 	)
 
@@ -183,32 +838,107 @@ func serverLoggerInterceptor(logger *logrus.Logger) []grpc.ServerOption {
 	// Shared options for the logger, with a custom gRPC code to log level function.
 	loggerOpts := []grpc_logrus.Option{
 		grpc_logrus.WithDecider(func(fullMethodName string, err error) bool {
-			return ignorePayload(fullMethodName)
+			level := grpc_logrus.DefaultCodeToLevel(status.Code(err))
+			return ignorePayload(fullMethodName) && methodLevels.enabled(fullMethodName, level)
 		}),
 		grpc_logrus.WithLevels(grpc_logrus.DefaultCodeToLevel),
 	}
 
+	// Sample the payloads of the methods with a configured sample rate.
+	samplePayload := sampledPayloadDecider(
+		ignorePayload,
+		parsePayloadSampleRates(logger, viper.GetString(configPayloadLogSampleRate)),
+		rand.Float64,
+	)
+
+	// Payloads are logged at info, drop them for methods that log only more severe levels.
 	return ilogger.ElasticsearchLoggerServerInterceptor(
 		logrusEntry,
-		ignorePayload,
-		ignoreInitialRequest,
+		methodLevels.payloadDecider(samplePayload),
+		methodLevels.payloadDecider(ignoreInitialRequest),
 		loggerOpts...,
 	)
 }
 
+// SetDraining sets whether the server is draining ahead of a shutdown. A draining server reports
+// NOT_SERVING and rejects new downloads with Unavailable, while the active downloads complete.
+// The server reports SERVING again on the next health check after draining is unset.
+func (s DownloadServer) SetDraining(draining bool) {
+	s.downloadService.SetDraining(draining)
+	if draining {
+		s.healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+		s.healthServer.SetServingStatus(readinessService, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	}
+}
+
 // healthCheckWorker is running an infinite loop that sets the serving status once
 // in s.healthCheckInterval seconds.
-func (s DownloadServer) healthCheckWorker(healthServer *health.Server) {
-	s3Client := s.downloadService.GetS3Client()
-
+func (s DownloadServer) healthCheckWorker() {
 	for {
-		_, err := s3Client.ListBuckets(&s3.ListBucketsInput{})
-		if err != nil {
-			healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
-		} else {
-			healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
-		}
-
+		s.checkHealth()
 		time.Sleep(time.Second * time.Duration(s.healthCheckInterval))
 	}
 }
+
+// checkHealth sets the serving status by whether S3 is readable, and writable if the write path
+// is probed, whether the Elasticsearch cluster that logs are shipped to is reachable, if probed,
+// and the server isn't draining. The status of each dependency is also set as the status of its
+// own health service. The readiness status is also NOT_SERVING while the latency of reading S3
+// is degraded.
+func (s DownloadServer) checkHealth() {
+	s3Status := servingStatus(s.checkS3())
+	esStatus := servingStatus(s.checkElasticsearch())
+
+	healthStatus := grpc_health_v1.HealthCheckResponse_SERVING
+	if s3Status != healthStatus || esStatus != healthStatus || s.downloadService.Draining() {
+		healthStatus = grpc_health_v1.HealthCheckResponse_NOT_SERVING
+	}
+
+	readiness := healthStatus
+	if s.s3Latency.degraded() {
+		readiness = grpc_health_v1.HealthCheckResponse_NOT_SERVING
+	}
+
+	s.healthServer.SetServingStatus(s3HealthService, s3Status)
+	if s.esProbe != nil {
+		s.healthServer.SetServingStatus(elasticsearchHealthService, esStatus)
+	}
+	s.healthServer.SetServingStatus("", healthStatus)
+	s.healthServer.SetServingStatus(readinessService, readiness)
+}
+
+// checkS3 returns nil if S3 is readable, and writable if the write path is probed.
+func (s DownloadServer) checkS3() error {
+	start := time.Now()
+	if _, err := s.downloadService.GetS3Client().ListBuckets(&s3.ListBucketsInput{}); err != nil {
+		return err
+	}
+
+	s.s3Latency.observe(time.Since(start))
+	if err := s.writeProbe.check(context.Background()); err != nil {
+		s.logger.Errorf("write health probe failed: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// checkElasticsearch returns nil if the Elasticsearch cluster that logs are shipped to is reachable,
+// or isn't probed.
+func (s DownloadServer) checkElasticsearch() error {
+	err := s.esProbe.check(context.Background())
+	if err != nil {
+		s.logger.Errorf("elasticsearch health probe failed: %v", err)
+	}
+
+	return err
+}
+
+// servingStatus returns the serving status of a dependency whose health check returned err.
+func servingStatus(err error) grpc_health_v1.HealthCheckResponse_ServingStatus {
+	if err != nil {
+		return grpc_health_v1.HealthCheckResponse_NOT_SERVING
+	}
+
+	return grpc_health_v1.HealthCheckResponse_SERVING
+}
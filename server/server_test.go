@@ -0,0 +1,49 @@
+package server
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+func TestNewDownloadServiceConcurrency(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	tests := []struct {
+		name                string
+		downloadConcurrency int
+		partConcurrency     int
+		want                int
+	}{
+		{name: "concurrency - unset"},
+		{name: "concurrency - download concurrency", downloadConcurrency: 4, want: 4},
+		{name: "concurrency - deprecated part concurrency", partConcurrency: 3, want: 3},
+		{
+			name:                "concurrency - download concurrency takes precedence",
+			downloadConcurrency: 4,
+			partConcurrency:     3,
+			want:                4,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			for config, value := range map[string]interface{}{
+				configDownloadConcurrency: tt.downloadConcurrency,
+				configPartConcurrency:     tt.partConcurrency,
+			} {
+				previous := viper.Get(config)
+				viper.Set(config, value)
+				defer viper.Set(config, previous)
+			}
+
+			if got := newDownloadService(nil, logger).PartConcurrency; got != tt.want {
+				t.Errorf("newDownloadService() PartConcurrency = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
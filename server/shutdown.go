@@ -0,0 +1,90 @@
+package server
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultShutdownGracePeriod is the default time the active calls are given to complete on shutdown.
+	defaultShutdownGracePeriod = 30 * time.Second
+)
+
+// shutdownState is the state of shutting down a DownloadServer, shared by its copies.
+type shutdownState struct {
+	gracePeriod time.Duration
+	once        sync.Once
+	started     chan struct{}
+	done        chan struct{}
+}
+
+// newShutdownState returns the shutdown state of a server whose active calls are given gracePeriod
+// to complete on shutdown, a non-positive gracePeriod defaults to defaultShutdownGracePeriod.
+func newShutdownState(gracePeriod time.Duration) *shutdownState {
+	if gracePeriod <= 0 {
+		gracePeriod = defaultShutdownGracePeriod
+	}
+
+	return &shutdownState{gracePeriod: gracePeriod, started: make(chan struct{}), done: make(chan struct{})}
+}
+
+// wait waits for the shutdown to complete if it started. A nil shutdownState never starts.
+func (s *shutdownState) wait() {
+	if s == nil {
+		return
+	}
+
+	select {
+	case <-s.started:
+		<-s.done
+	default:
+	}
+}
+
+// Shutdown stops the server gracefully. It reports NOT_SERVING and rejects new downloads right away, so
+// load balancers stop routing to it, then stops accepting connections and waits for the active calls to
+// complete for up to the shutdown grace period, after which they're cancelled. Serve returns once the
+// server stopped. Calls after the first wait for the first to complete.
+func (s DownloadServer) Shutdown() {
+	s.shutdown.once.Do(func() {
+		close(s.shutdown.started)
+		defer close(s.shutdown.done)
+
+		s.logger.Infof("shutting down, waiting up to %v for the active calls", s.shutdown.gracePeriod)
+		s.SetDraining(true)
+		s.healthServer.Shutdown()
+
+		stopped := make(chan struct{})
+		go func() {
+			s.Server.GracefulStop()
+			close(stopped)
+		}()
+
+		timer := time.NewTimer(s.shutdown.gracePeriod)
+		defer timer.Stop()
+
+		select {
+		case <-stopped:
+		case <-timer.C:
+			s.logger.Warnf("shutdown grace period of %v passed, cancelling the active calls", s.shutdown.gracePeriod)
+			s.Server.Stop()
+			<-stopped
+		}
+	})
+
+	<-s.shutdown.done
+}
+
+// shutdownOnSignal shuts the server down once the process receives one of signals.
+func (s DownloadServer) shutdownOnSignal(signals ...os.Signal) {
+	received := make(chan os.Signal, 1)
+	signal.Notify(received, signals...)
+
+	go func() {
+		sig := <-received
+		s.logger.Infof("received %v", sig)
+		s.Shutdown()
+	}()
+}
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	pb "github.com/meateam/download-service/proto"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// blockingS3Client returns an S3 client serving object for every HeadObject and GetObject call,
+// whose GetObject calls signal fetching and block until release is closed.
+func blockingS3Client(object []byte, fetching chan<- struct{}, release <-chan struct{}) *s3.S3 {
+	client := s3.New(session.Must(session.NewSession(&aws.Config{
+		Region:           aws.String("us-east-1"),
+		Endpoint:         aws.String("http://s3.test"),
+		Credentials:      credentials.NewStaticCredentials("key", "secret", ""),
+		S3ForcePathStyle: aws.Bool(true),
+	})))
+	client.Handlers.Send.Clear()
+	client.Handlers.Send.PushBack(func(r *request.Request) {
+		if _, ok := r.Params.(*s3.GetObjectInput); ok {
+			fetching <- struct{}{}
+			<-release
+		}
+
+		header := http.Header{}
+		header.Set("Content-Length", strconv.Itoa(len(object)))
+		header.Set("ETag", `"object"`)
+		r.HTTPResponse = &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     header,
+			Body:       ioutil.NopCloser(bytes.NewReader(object)),
+		}
+	})
+
+	return client
+}
+
+// serveShutdownTest serves a DownloadServer of s3Client whose active calls are given gracePeriod
+// to complete on shutdown, and returns it with a client of it and a channel closed once Serve returns.
+func serveShutdownTest(
+	t *testing.T,
+	s3Client *s3.S3,
+	gracePeriod time.Duration,
+) (*DownloadServer, pb.DownloadClient, <-chan struct{}) {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	grpcServer := grpc.NewServer()
+	downloadService := download.NewService(s3Client, logger)
+	pb.RegisterDownloadServer(grpcServer, downloadService)
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
+	server := &DownloadServer{
+		Server:          grpcServer,
+		logger:          logger,
+		downloadService: downloadService,
+		healthServer:    healthServer,
+		shutdown:        newShutdownState(gracePeriod),
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	served := make(chan struct{})
+	go func() {
+		server.Serve(lis)
+		close(served)
+	}()
+
+	conn, err := grpc.DialContext(
+		context.Background(),
+		"bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		}),
+		grpc.WithInsecure(),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial bufnet: %v", err)
+	}
+
+	return server, pb.NewDownloadClient(conn), served
+}
+
+// recvAll receives the whole stream and returns the file's bytes,
+// the error is nil when the stream ends with io.EOF.
+func recvAll(stream pb.Download_DownloadClient) ([]byte, error) {
+	var file []byte
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			return file, nil
+		}
+
+		if err != nil {
+			return file, err
+		}
+
+		file = append(file, chunk.GetFile()...)
+	}
+}
+
+func TestDownloadServer_Shutdown(t *testing.T) {
+	object := bytes.Repeat([]byte("download-service"), 1024)
+
+	tests := []struct {
+		name        string
+		gracePeriod time.Duration
+		wantErr     bool
+	}{
+		{name: "shutdown - active download completes", gracePeriod: time.Minute},
+		{name: "shutdown - grace period passes", gracePeriod: 50 * time.Millisecond, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			fetching, release := make(chan struct{}, 1), make(chan struct{})
+			server, client, served := serveShutdownTest(t, blockingS3Client(object, fetching, release), tt.gracePeriod)
+
+			stream, err := client.Download(context.Background(), &pb.DownloadRequest{Key: "key", Bucket: "bucket"})
+			if err != nil {
+				t.Fatalf("DownloadService.Download() error = %v", err)
+			}
+
+			// Shut down while the download is fetching the object.
+			<-fetching
+			shutDown := make(chan struct{})
+			go func() {
+				server.Shutdown()
+				close(shutDown)
+			}()
+
+			// The server reports NOT_SERVING as soon as the shutdown starts.
+			for {
+				res, err := server.healthServer.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+				if err != nil {
+					t.Fatalf("Health.Check() error = %v", err)
+				}
+
+				if res.GetStatus() == grpc_health_v1.HealthCheckResponse_NOT_SERVING {
+					break
+				}
+
+				time.Sleep(time.Millisecond)
+			}
+
+			// Let the download complete only after the grace period passed, if it's expected to pass.
+			if tt.wantErr {
+				<-shutDown
+			}
+			close(release)
+
+			got, err := recvAll(stream)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DownloadService.Download() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && !bytes.Equal(got, object) {
+				t.Errorf("DownloadService.Download() file downloaded is different from the wanted file")
+			}
+
+			<-shutDown
+			<-served
+		})
+	}
+}
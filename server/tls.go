@@ -0,0 +1,55 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// loadTLSCredentials returns the transport credentials of a server serving TLS with the certificate
+// and key of certFile and keyFile, PEM encoded. If clientCAFile is set, clients are required to present
+// a certificate signed by one of its PEM encoded CAs. It returns nil credentials, for plaintext,
+// if none of the files are set, and an error if only some of them are set or they're invalid.
+func loadTLSCredentials(
+	certFile string,
+	keyFile string,
+	clientCAFile string,
+) (credentials.TransportCredentials, error) {
+	if certFile == "" && keyFile == "" && clientCAFile == "" {
+		return nil, nil
+	}
+
+	if certFile == "" || keyFile == "" {
+		return nil, fmt.Errorf("both the TLS certificate and key files are required for TLS")
+	}
+
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the TLS certificate: %v", err)
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	// Require and verify client certificates for mutual TLS.
+	if clientCAFile != "" {
+		clientCAs, err := ioutil.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the TLS client CA file: %v", err)
+		}
+
+		config.ClientCAs = x509.NewCertPool()
+		if !config.ClientCAs.AppendCertsFromPEM(clientCAs) {
+			return nil, fmt.Errorf("no certificates found in the TLS client CA file %s", clientCAFile)
+		}
+
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return credentials.NewTLS(config), nil
+}
@@ -0,0 +1,255 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// testCertificate is a certificate and its key generated for a test.
+type testCertificate struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	tlsCert tls.Certificate
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newTestCertificate returns a certificate of template signed by parent, self-signed if parent is nil.
+func newTestCertificate(t *testing.T, template *x509.Certificate, parent *testCertificate) *testCertificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key, %v", err)
+	}
+
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate, %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate, %v", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key, %v", err)
+	}
+
+	certificate := &testCertificate{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+	if certificate.tlsCert, err = tls.X509KeyPair(certificate.certPEM, certificate.keyPEM); err != nil {
+		t.Fatalf("failed to load certificate, %v", err)
+	}
+
+	return certificate
+}
+
+// newTestCA returns a self-signed CA certificate named name.
+func newTestCA(t *testing.T, name string, serial int64) *testCertificate {
+	t.Helper()
+
+	return newTestCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: name},
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}, nil)
+}
+
+// writeTestFile writes content to the file name in dir and returns its path.
+func writeTestFile(t *testing.T, dir string, name string, content []byte) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, content, 0600); err != nil {
+		t.Fatalf("failed to write %s, %v", path, err)
+	}
+
+	return path
+}
+
+// checkHealthOverBufconn serves a health server with serverCreds, plaintext if nil, and checks
+// its health with a client dialing with clientCreds, plaintext if nil.
+func checkHealthOverBufconn(
+	serverCreds credentials.TransportCredentials,
+	clientCreds credentials.TransportCredentials,
+) error {
+	var serverOpts []grpc.ServerOption
+	if serverCreds != nil {
+		serverOpts = append(serverOpts, grpc.Creds(serverCreds))
+	}
+
+	grpcServer := grpc.NewServer(serverOpts...)
+	grpc_health_v1.RegisterHealthServer(grpcServer, health.NewServer())
+	lis := bufconn.Listen(1 << 20)
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	dialOpt := grpc.WithInsecure()
+	if clientCreds != nil {
+		dialOpt = grpc.WithTransportCredentials(clientCreds)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := grpc.DialContext(
+		ctx,
+		"bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		}),
+		dialOpt,
+	)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+
+	return err
+}
+
+func TestLoadTLSCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	if err != nil {
+		t.Fatalf("failed to create temp dir, %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca, otherCA := newTestCA(t, "ca", 1), newTestCA(t, "other-ca", 2)
+	server := newTestCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "bufnet"},
+		DNSNames:     []string{"bufnet"},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca)
+	clientTemplate := func(serial int64) *x509.Certificate {
+		return &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: "client"},
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}
+	}
+	client := newTestCertificate(t, clientTemplate(4), ca)
+	otherClient := newTestCertificate(t, clientTemplate(5), otherCA)
+
+	certFile := writeTestFile(t, dir, "server.crt", server.certPEM)
+	keyFile := writeTestFile(t, dir, "server.key", server.keyPEM)
+	caFile := writeTestFile(t, dir, "ca.crt", ca.certPEM)
+	emptyFile := writeTestFile(t, dir, "empty.crt", nil)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	tests := []struct {
+		name         string
+		certFile     string
+		keyFile      string
+		clientCAFile string
+		clientCreds  credentials.TransportCredentials
+		wantLoadErr  bool
+		wantCallErr  bool
+	}{
+		{name: "tls - plaintext"},
+		{
+			name:        "tls - server certificate",
+			certFile:    certFile,
+			keyFile:     keyFile,
+			clientCreds: credentials.NewTLS(&tls.Config{RootCAs: roots}),
+		},
+		{name: "tls - plaintext client", certFile: certFile, keyFile: keyFile, wantCallErr: true},
+		{
+			name:         "tls - client certificate",
+			certFile:     certFile,
+			keyFile:      keyFile,
+			clientCAFile: caFile,
+			clientCreds: credentials.NewTLS(
+				&tls.Config{RootCAs: roots, Certificates: []tls.Certificate{client.tlsCert}},
+			),
+		},
+		{
+			name:         "tls - missing client certificate",
+			certFile:     certFile,
+			keyFile:      keyFile,
+			clientCAFile: caFile,
+			clientCreds:  credentials.NewTLS(&tls.Config{RootCAs: roots}),
+			wantCallErr:  true,
+		},
+		{
+			name:         "tls - client certificate of another ca",
+			certFile:     certFile,
+			keyFile:      keyFile,
+			clientCAFile: caFile,
+			clientCreds: credentials.NewTLS(
+				&tls.Config{RootCAs: roots, Certificates: []tls.Certificate{otherClient.tlsCert}},
+			),
+			wantCallErr: true,
+		},
+		{name: "tls - missing key", certFile: certFile, wantLoadErr: true},
+		{name: "tls - client ca without certificate", clientCAFile: caFile, wantLoadErr: true},
+		{name: "tls - invalid key", certFile: certFile, keyFile: caFile, wantLoadErr: true},
+		{
+			name:         "tls - empty client ca",
+			certFile:     certFile,
+			keyFile:      keyFile,
+			clientCAFile: emptyFile,
+			wantLoadErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			serverCreds, err := loadTLSCredentials(tt.certFile, tt.keyFile, tt.clientCAFile)
+			if (err != nil) != tt.wantLoadErr {
+				t.Fatalf("loadTLSCredentials() error = %v, wantErr %v", err, tt.wantLoadErr)
+			}
+
+			if tt.wantLoadErr {
+				return
+			}
+
+			if err := checkHealthOverBufconn(serverCreds, tt.clientCreds); (err != nil) != tt.wantCallErr {
+				t.Errorf("Health.Check() error = %v, wantErr %v", err, tt.wantCallErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/meateam/download-service/download"
+	"github.com/sirupsen/logrus"
+)
+
+// traceExtractorsByName are the trace extractors that can be enabled by name.
+var traceExtractorsByName = map[string]download.TraceExtractor{
+	"elastic-apm": download.ElasticAPMTraceExtractor,
+	"w3c":         download.W3CTraceExtractor,
+	"b3":          download.B3TraceExtractor,
+	"b3-multi":    download.B3MultiTraceExtractor,
+}
+
+// parseTraceExtractors parses the comma-separated names of trace extractors into the extractors,
+// in order. Unknown names are logged to logger and skipped.
+func parseTraceExtractors(logger *logrus.Logger, names string) []download.TraceExtractor {
+	var extractors []download.TraceExtractor
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		extractor, ok := traceExtractorsByName[name]
+		if !ok {
+			logger.Warnf("ignoring unknown trace extractor %q, want elastic-apm, w3c, b3 or b3-multi", name)
+			continue
+		}
+
+		extractors = append(extractors, extractor)
+	}
+
+	return extractors
+}
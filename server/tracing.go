@@ -0,0 +1,54 @@
+package server
+
+import (
+	"fmt"
+	"io"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/sirupsen/logrus"
+	jaegercfg "github.com/uber/jaeger-client-go/config"
+)
+
+// jaegerLogger adapts a *logrus.Logger to jaeger.Logger.
+type jaegerLogger struct {
+	logger *logrus.Logger
+}
+
+// Error logs a jaeger error message.
+func (l jaegerLogger) Error(msg string) {
+	l.logger.Errorf("jaeger: %s", msg)
+}
+
+// Infof logs a jaeger info message.
+func (l jaegerLogger) Infof(msg string, args ...interface{}) {
+	l.logger.Infof("jaeger: "+msg, args...)
+}
+
+// newTracer creates a Jaeger tracer of serviceName which reports its spans to the
+// collector endpoint, sampled by a sampler of samplerType with samplerParam.
+// The returned io.Closer flushes the reported spans and should be closed on shutdown.
+func newTracer(
+	serviceName string,
+	endpoint string,
+	samplerType string,
+	samplerParam float64,
+	logger *logrus.Logger,
+) (opentracing.Tracer, io.Closer, error) {
+	cfg := jaegercfg.Configuration{
+		ServiceName: serviceName,
+		Sampler: &jaegercfg.SamplerConfig{
+			Type:  samplerType,
+			Param: samplerParam,
+		},
+		Reporter: &jaegercfg.ReporterConfig{
+			CollectorEndpoint: endpoint,
+		},
+	}
+
+	tracer, closer, err := cfg.NewTracer(jaegercfg.Logger(jaegerLogger{logger: logger}))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create jaeger tracer: %v", err)
+	}
+
+	return tracer, closer, nil
+}
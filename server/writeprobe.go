@@ -0,0 +1,89 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const (
+	// defaultWriteProbeInterval is the default interval of probing the write path,
+	// infrequent since every probe writes to S3.
+	defaultWriteProbeInterval = 5 * time.Minute
+
+	// writeProbeKeyPrefix is the key prefix of the scratch objects written by write probes.
+	writeProbeKeyPrefix = "health/write-probe-"
+)
+
+// writeProbe probes the write path to S3 by putting and deleting a tiny scratch object in a bucket,
+// at most once per interval, and reports the result of the last probe in between.
+type writeProbe struct {
+	client   *s3.S3
+	bucket   string
+	key      string
+	interval time.Duration
+
+	mu     sync.Mutex
+	last   time.Time
+	result error
+}
+
+// newWriteProbe returns a writeProbe of bucket probing every interval with client,
+// a non-positive interval defaults to defaultWriteProbeInterval.
+// The scratch object is keyed by the host's name, so replicas don't race on it.
+func newWriteProbe(client *s3.S3, bucket string, interval time.Duration) *writeProbe {
+	if interval <= 0 {
+		interval = defaultWriteProbeInterval
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+
+	return &writeProbe{client: client, bucket: bucket, key: writeProbeKeyPrefix + host, interval: interval}
+}
+
+// check returns the error of the last probe of the write path, probing it again if the last probe
+// is older than the probe's interval. A nil writeProbe never fails.
+func (p *writeProbe) check(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if now := time.Now(); p.last.IsZero() || now.Sub(p.last) >= p.interval {
+		p.last = now
+		p.result = p.probe(ctx)
+	}
+
+	return p.result
+}
+
+// probe puts the scratch object and deletes it.
+func (p *writeProbe) probe(ctx context.Context) error {
+	if _, err := p.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(p.bucket),
+		Key:    aws.String(p.key),
+		Body:   bytes.NewReader([]byte("ok")),
+	}); err != nil {
+		return fmt.Errorf("failed to write s3://%s/%s: %v", p.bucket, p.key, err)
+	}
+
+	if _, err := p.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(p.bucket),
+		Key:    aws.String(p.key),
+	}); err != nil {
+		return fmt.Errorf("failed to delete s3://%s/%s: %v", p.bucket, p.key, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/meateam/download-service/download"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// simulatedStore returns an S3 client of a simulated store, which rejects writes if readOnly is set,
+// counting the writes to it.
+func simulatedStore(readOnly bool, writes *int64) *s3.S3 {
+	client := s3.New(session.Must(session.NewSession(&aws.Config{
+		Region:           aws.String("us-east-1"),
+		Endpoint:         aws.String("http://s3.test"),
+		Credentials:      credentials.NewStaticCredentials("id", "secret", ""),
+		S3ForcePathStyle: aws.Bool(true),
+		MaxRetries:       aws.Int(0),
+	})))
+	client.Handlers.Send.Clear()
+	client.Handlers.Send.PushBack(func(r *request.Request) {
+		r.HTTPResponse = &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody}
+
+		switch r.Params.(type) {
+		case *s3.ListBucketsInput:
+			r.HTTPResponse.Body = ioutil.NopCloser(strings.NewReader("<ListAllMyBucketsResult/>"))
+		case *s3.PutObjectInput:
+			atomic.AddInt64(writes, 1)
+			if readOnly {
+				r.HTTPResponse.StatusCode = http.StatusForbidden
+				r.Error = awserr.New("AccessDenied", "the store is read-only", nil)
+			}
+		}
+	})
+
+	return client
+}
+
+func TestDownloadServer_checkHealthWriteProbe(t *testing.T) {
+	tests := []struct {
+		name       string
+		readOnly   bool
+		probe      bool
+		wantStatus grpc_health_v1.HealthCheckResponse_ServingStatus
+		wantWrites int64
+	}{
+		{
+			name:       "write probe - writable",
+			probe:      true,
+			wantStatus: grpc_health_v1.HealthCheckResponse_SERVING,
+			wantWrites: 1,
+		},
+		{
+			name:       "write probe - read-only",
+			readOnly:   true,
+			probe:      true,
+			wantStatus: grpc_health_v1.HealthCheckResponse_NOT_SERVING,
+			wantWrites: 1,
+		},
+		{
+			name:       "write probe - disabled",
+			readOnly:   true,
+			wantStatus: grpc_health_v1.HealthCheckResponse_SERVING,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logrus.New()
+			logger.SetOutput(ioutil.Discard)
+
+			var writes int64
+			client := simulatedStore(tt.readOnly, &writes)
+			healthServer := health.NewServer()
+			server := DownloadServer{
+				logger:          logger,
+				downloadService: download.NewService(client, logger),
+				healthServer:    healthServer,
+			}
+			if tt.probe {
+				server.writeProbe = newWriteProbe(client, "health", time.Hour)
+			}
+
+			// Every check within the probe's interval reports the last probe's result.
+			for i := 0; i < 3; i++ {
+				server.checkHealth()
+			}
+
+			res, err := healthServer.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+			if err != nil {
+				t.Fatalf("Health.Check() error = %v", err)
+			}
+
+			if res.GetStatus() != tt.wantStatus {
+				t.Errorf("Health.Check() status = %v, want %v", res.GetStatus(), tt.wantStatus)
+			}
+
+			if writes != tt.wantWrites {
+				t.Errorf("DownloadServer.checkHealth() wrote %d times, want %d", writes, tt.wantWrites)
+			}
+		})
+	}
+}